@@ -191,6 +191,7 @@ func DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	accountOld := *account
 	err = account.DeleteAccount(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -200,6 +201,8 @@ func DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, account.StoreID, models.AuditActionDelete, "account", account.ID, account.Number, &accountOld, account)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 
@@ -246,6 +249,7 @@ func RestoreAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	accountOld := *account
 	err = account.RestoreAccount(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -254,6 +258,8 @@ func RestoreAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, account.StoreID, models.AuditActionRestore, "account", account.ID, account.Number, &accountOld, account)
+
 	response.Status = true
 	response.Result = "Restored successfully"
 
@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListAuditLog : handler for GET /audit
+func ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	auditLogs, criterias, err := store.SearchAuditLog(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find audit logs:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "audit_log")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of audit logs:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(auditLogs) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = auditLogs
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewAuditLog : handler function for GET /v1/audit/<id> call
+func ViewAuditLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	params := mux.Vars(r)
+
+	auditLogID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["audit_log_id"] = "Invalid Audit Log ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	auditLog, err := store.FindAuditLogByID(&auditLogID)
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = auditLog
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// recordUserAudit : users are not store documents, so a change is logged in every store the user belongs to
+func recordUserAudit(r *http.Request, tokenClaims models.TokenClaims, action string, before, after *models.User) {
+	seen := map[primitive.ObjectID]bool{}
+	for _, user := range []*models.User{before, after} {
+		if user == nil {
+			continue
+		}
+		storeIDs := append([]*primitive.ObjectID{user.StoreID}, user.StoreIDs...)
+		for _, storeID := range storeIDs {
+			if storeID == nil || seen[*storeID] {
+				continue
+			}
+			seen[*storeID] = true
+			models.RecordAudit(r, tokenClaims, storeID, action, "user", after.ID, after.Email, before, after)
+		}
+	}
+}
@@ -0,0 +1,812 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListBankStatement : handler for GET /bank-statement
+func ListBankStatement(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	statements, criterias, err := store.SearchBankStatement(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find bank statements:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "bank_statement")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of bank statements:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(statements) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = statements
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ImportBankStatement : handler for POST /bank-statement/import
+// Multipart form: file (.csv, .xlsx, MT940 or CAMT.053 .xml), format (optional, detected otherwise),
+// account_id (the bank ledger account, BANK by default), mapping (CSV/XLSX column mapping, see
+// ImportFields["bank_statement"]), opening_balance/closing_balance (when the file has none) and
+// auto_match ("false" to skip matching the lines right away).
+func ImportBankStatement(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = r.ParseMultipartForm(32 << 20)
+	if err != nil {
+		response.Status = false
+		response.Errors["file"] = "Unable to parse form: " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	file, handler, err := r.FormFile("file")
+	if err != nil {
+		response.Status = false
+		response.Errors["file"] = "File is required"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		response.Status = false
+		response.Errors["file"] = "Error reading file: " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	mapping, err := models.ParseImportMapping(r.FormValue("mapping"))
+	if err != nil {
+		response.Status = false
+		response.Errors["mapping"] = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	statement, err := models.ParseBankStatementFile(handler.Filename, r.FormValue("format"), data, mapping)
+	if err != nil {
+		response.Status = false
+		response.Errors["file"] = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	for _, key := range []string{"opening_balance", "closing_balance"} {
+		value := r.FormValue(key)
+		if value == "" {
+			continue
+		}
+		balance, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			response.Errors[key] = "Invalid amount:" + err.Error()
+			continue
+		}
+		balance = models.RoundTo2Decimals(balance)
+		if key == "opening_balance" {
+			statement.OpeningBalance = &balance
+		} else {
+			statement.ClosingBalance = &balance
+		}
+	}
+
+	toleranceDays, err := parseBankMatchToleranceDays(r.FormValue("date_tolerance_days"))
+	if err != nil {
+		response.Errors["date_tolerance_days"] = err.Error()
+	}
+
+	if len(response.Errors) > 0 {
+		response.Status = false
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	statement.StoreID = &store.ID
+	if accountID := r.FormValue("account_id"); accountID != "" {
+		statement.AccountID, err = primitive.ObjectIDFromHex(accountID)
+		if err != nil {
+			response.Status = false
+			response.Errors["account_id"] = "Invalid account id:" + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	} else {
+		bankAccount, err := store.CreateAccountIfNotExists(&store.ID, nil, nil, "Bank", nil, nil)
+		if err != nil {
+			response.Status = false
+			response.Errors["account_id"] = "Error creating bank account:" + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		statement.AccountID = bankAccount.ID
+	}
+
+	statement.ConvertDatesToUTC(models.CountryTimezoneOffset(store.CountryCode))
+
+	// Validate data
+	if errs := statement.Validate(w, r, "create"); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	statement.CreatedBy = &userID
+	statement.UpdatedBy = &userID
+	statement.CreatedAt = &now
+	statement.UpdatedAt = &now
+
+	if r.FormValue("auto_match") != "false" {
+		entries, err := statement.FindUnreconciledBankPostings(store, toleranceDays)
+		if err != nil {
+			response.Status = false
+			response.Errors["auto_match"] = "Unable to find postings:" + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		models.AutoMatchBankLines(statement.Lines, entries, statement.MatchedPostingIDs(), toleranceDays, &now)
+	}
+
+	err = statement.Insert()
+	if err != nil {
+		response.Status = false
+		response.Errors["insert"] = "Unable to insert to db:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, statement.StoreID, models.AuditActionCreate, "bank_statement", statement.ID, statement.StatementNo, nil, statement)
+
+	response.Status = true
+	response.Result = statement
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewBankStatement : handler function for GET /v1/bank-statement/<id> call
+func ViewBankStatement(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	statement, _ := findBankStatement(w, r, &response)
+	if statement == nil {
+		return
+	}
+
+	response.Status = true
+	response.Result = statement
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteBankStatement : handler function for DELETE /v1/bank-statement/<id> call
+// The matches of the statement are dropped with it, so its postings become available for matching again.
+func DeleteBankStatement(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	statement, _ := findBankStatement(w, r, &response)
+	if statement == nil {
+		return
+	}
+	statementOld := *statement
+
+	err = statement.DeleteBankStatement(tokenClaims)
+	if err != nil {
+		response.Status = false
+		response.Errors["delete"] = "Unable to delete:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, statement.StoreID, models.AuditActionDelete, "bank_statement", statement.ID, statement.StatementNo, &statementOld, statement)
+
+	response.Status = true
+	response.Result = "Deleted successfully"
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ListBankStatementCandidates : handler function for GET /v1/bank-statement/<id>/candidates call
+// Returns the postings of the bank account around the statement period that are not matched yet,
+// the choices for matching a line manually.
+func ListBankStatementCandidates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	toleranceDays, err := parseBankMatchToleranceDays(r.URL.Query().Get("date_tolerance_days"))
+	if err != nil {
+		response.Status = false
+		response.Errors["date_tolerance_days"] = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	statement, store := findBankStatement(w, r, &response)
+	if statement == nil {
+		return
+	}
+
+	entries, err := statement.FindUnreconciledBankPostings(store, toleranceDays)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find postings:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = entries
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// AutoMatchBankStatement : handler function for POST /v1/bank-statement/<id>/auto-match call
+// Body (optional): {"date_tolerance_days": 3}
+func AutoMatchBankStatement(w http.ResponseWriter, r *http.Request) {
+	changeBankStatement(w, r, func(statement *models.BankStatement, store *models.Store, userID primitive.ObjectID) (int, string, error) {
+		var input struct {
+			DateToleranceDays *int `json:"date_tolerance_days"`
+		}
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				return http.StatusBadRequest, "input", errors.New("Invalid input:" + err.Error())
+			}
+		}
+
+		toleranceDays := models.BankMatchDefaultToleranceDays
+		if input.DateToleranceDays != nil {
+			toleranceDays = *input.DateToleranceDays
+			if toleranceDays < 0 || toleranceDays > models.BankMatchMaxToleranceDays {
+				return http.StatusBadRequest, "date_tolerance_days", fmt.Errorf("date tolerance should be 0 to %d days", models.BankMatchMaxToleranceDays)
+			}
+		}
+
+		entries, err := statement.FindUnreconciledBankPostings(store, toleranceDays)
+		if err != nil {
+			return http.StatusInternalServerError, "auto_match", errors.New("Unable to find postings:" + err.Error())
+		}
+
+		now := time.Now()
+		models.AutoMatchBankLines(statement.Lines, entries, statement.MatchedPostingIDs(), toleranceDays, &now)
+		return http.StatusOK, "", nil
+	})
+}
+
+// MatchBankStatementLine : handler function for POST /v1/bank-statement/<id>/line/<line_id>/match call
+// Body: {"posting_ids": ["..."]}, postings of the bank account whose amounts add up to the line amount.
+func MatchBankStatementLine(w http.ResponseWriter, r *http.Request) {
+	changeBankStatement(w, r, func(statement *models.BankStatement, store *models.Store, userID primitive.ObjectID) (int, string, error) {
+		lineID, err := primitive.ObjectIDFromHex(mux.Vars(r)["line_id"])
+		if err != nil {
+			return http.StatusBadRequest, "line_id", errors.New("Invalid line id:" + err.Error())
+		}
+
+		var input struct {
+			PostingIDs []primitive.ObjectID `json:"posting_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			return http.StatusBadRequest, "input", errors.New("Invalid input:" + err.Error())
+		}
+		if len(input.PostingIDs) == 0 {
+			return http.StatusBadRequest, "posting_ids", errors.New("Select at least one posting")
+		}
+
+		entries, err := store.FindBankAccountPostings(statement.AccountID, nil, nil, input.PostingIDs)
+		if err != nil {
+			return http.StatusInternalServerError, "posting_ids", err
+		}
+		if len(entries) != len(input.PostingIDs) {
+			return http.StatusBadRequest, "posting_ids", errors.New("Some postings are not of the " + statement.AccountName + " account")
+		}
+
+		reconciled, err := store.FindReconciledPostingIDs(statement.AccountID, &statement.ID)
+		if err != nil {
+			return http.StatusInternalServerError, "posting_ids", err
+		}
+		for _, entry := range entries {
+			if reconciled[entry.PostingID] {
+				return http.StatusBadRequest, "posting_ids", errors.New("Posting of " + entry.ReferenceCode + " is already matched on another statement")
+			}
+		}
+
+		now := time.Now()
+		if err := statement.MatchLine(lineID, entries, userID, &now); err != nil {
+			return http.StatusBadRequest, "match", err
+		}
+		return http.StatusOK, "", nil
+	})
+}
+
+// UnmatchBankStatementLine : handler function for POST /v1/bank-statement/<id>/line/<line_id>/unmatch call
+// Unmatching a split line merges its parts back.
+func UnmatchBankStatementLine(w http.ResponseWriter, r *http.Request) {
+	changeBankStatement(w, r, func(statement *models.BankStatement, store *models.Store, userID primitive.ObjectID) (int, string, error) {
+		lineID, err := primitive.ObjectIDFromHex(mux.Vars(r)["line_id"])
+		if err != nil {
+			return http.StatusBadRequest, "line_id", errors.New("Invalid line id:" + err.Error())
+		}
+		if err := statement.UnmatchLine(lineID); err != nil {
+			return http.StatusBadRequest, "unmatch", err
+		}
+		return http.StatusOK, "", nil
+	})
+}
+
+// SplitBankStatementLine : handler function for POST /v1/bank-statement/<id>/line/<line_id>/split call
+// Body: {"amounts": [100, 250.5]}
+func SplitBankStatementLine(w http.ResponseWriter, r *http.Request) {
+	changeBankStatement(w, r, func(statement *models.BankStatement, store *models.Store, userID primitive.ObjectID) (int, string, error) {
+		lineID, err := primitive.ObjectIDFromHex(mux.Vars(r)["line_id"])
+		if err != nil {
+			return http.StatusBadRequest, "line_id", errors.New("Invalid line id:" + err.Error())
+		}
+
+		var input struct {
+			Amounts []float64 `json:"amounts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			return http.StatusBadRequest, "input", errors.New("Invalid input:" + err.Error())
+		}
+		if err := statement.SplitLine(lineID, input.Amounts); err != nil {
+			return http.StatusBadRequest, "split", err
+		}
+		return http.StatusOK, "", nil
+	})
+}
+
+// CreateBankStatementLineDocument : handler function for POST /v1/bank-statement/<id>/line/<line_id>/create-document call
+// Books an unmatched line that is missing in the books and matches it to the new document. Body:
+// {"type": "expense" | "customer_deposit", "document": {...}}. The document gets the date, amount,
+// description and bank reference of the line; the other fields (category_id, vendor_id, customer_id,
+// payment_method...) are taken from "document" and validated as by POST /v1/expense or /v1/customer-deposit.
+// Money out of the bank becomes an expense, money in a customer deposit.
+func CreateBankStatementLineDocument(w http.ResponseWriter, r *http.Request) {
+	changeBankStatement(w, r, func(statement *models.BankStatement, store *models.Store, userID primitive.ObjectID) (int, string, error) {
+		lineID, err := primitive.ObjectIDFromHex(mux.Vars(r)["line_id"])
+		if err != nil {
+			return http.StatusBadRequest, "line_id", errors.New("Invalid line id:" + err.Error())
+		}
+		index := statement.FindLineIndex(lineID)
+		if index < 0 {
+			return http.StatusBadRequest, "line_id", errors.New("Line not found")
+		}
+		line := statement.Lines[index]
+		if line.Status != models.BankLineStatusUnmatched {
+			return http.StatusBadRequest, "line_id", errors.New("Line is " + line.Status)
+		}
+		if statement.AccountName != "BANK" {
+			return http.StatusBadRequest, "type", errors.New("Documents are booked to the BANK account, match lines of " + statement.AccountName + " manually")
+		}
+
+		var input struct {
+			Type     string                 `json:"type"`
+			Document map[string]interface{} `json:"document"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			return http.StatusBadRequest, "input", errors.New("Invalid input:" + err.Error())
+		}
+
+		document := input.Document
+		if document == nil {
+			document = map[string]interface{}{}
+		}
+		document["store_id"] = store.ID.Hex()
+		document["date_str"] = line.Date.Format(time.RFC3339)
+		if _, ok := document["description"]; !ok {
+			document["description"] = line.Description
+		}
+		method, _ := document["payment_method"].(string)
+		if !slices.Contains(models.BANK_PAYMENT_METHODS, method) {
+			method = "bank_transfer"
+		}
+		document["payment_method"] = method
+
+		var handler http.HandlerFunc
+		switch input.Type {
+		case "expense":
+			if line.Amount > 0 {
+				return http.StatusBadRequest, "type", errors.New("Money into the bank cannot be booked as an expense")
+			}
+			handler = CreateExpense
+			document["amount"] = -line.Amount
+		case "customer_deposit":
+			if line.Amount < 0 {
+				return http.StatusBadRequest, "type", errors.New("Money out of the bank cannot be booked as a customer deposit")
+			}
+			handler = CreateCustomerDeposit
+			if _, ok := document["type"]; !ok {
+				document["type"] = "customer"
+			}
+			document["bank_reference_no"] = line.Reference
+			document["payments"] = []map[string]interface{}{{
+				"date_str":       line.Date.Format(time.RFC3339),
+				"amount":         line.Amount,
+				"method":         method,
+				"bank_reference": line.Reference,
+			}}
+		default:
+			return http.StatusBadRequest, "type", errors.New("Type should be expense or customer_deposit")
+		}
+
+		created, errs := callCreateHandler(r, handler, document)
+		if len(errs) > 0 {
+			return http.StatusBadRequest, "", errs
+		}
+
+		entries, err := store.FindBankAccountPostingsByReference(statement.AccountID, created)
+		if err != nil {
+			return http.StatusInternalServerError, "match", errors.New("Document created but its postings were not found:" + err.Error())
+		}
+
+		now := time.Now()
+		if err := statement.MatchLine(lineID, entries, userID, &now); err != nil {
+			return http.StatusBadRequest, "match", errors.New("Document created but not matched, match it manually:" + err.Error())
+		}
+		return http.StatusOK, "", nil
+	})
+}
+
+// GetBankReconciliation : handler function for GET /v1/bank-statement/<id>/reconciliation call
+// format=json|pdf|xlsx
+func GetBankReconciliation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "pdf" && format != "xlsx" {
+		response.Status = false
+		response.Errors["format"] = "Invalid format, allowed: json, pdf, xlsx"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	statement, store := findBankStatement(w, r, &response)
+	if statement == nil {
+		return
+	}
+
+	reconciliation, err := store.MakeBankReconciliation(statement)
+	if err != nil {
+		response.Status = false
+		response.Errors["report"] = "Unable to make bank reconciliation:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	filename := "bank_reconciliation_" + statement.ToDate.Format("2006-01-02")
+
+	switch format {
+	case "pdf":
+		model, err := json.Marshal(reconciliation)
+		if err != nil {
+			response.Status = false
+			response.Errors["report"] = "Unable to encode bank reconciliation:" + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		pdfBuf, errKey, err := renderReportPDF(printJobData{
+			Model:     model,
+			ModelName: "bank_reconciliation",
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			response.Status = false
+			response.Errors[errKey] = err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, filename))
+		w.WriteHeader(http.StatusOK)
+		w.Write(pdfBuf)
+	case "xlsx":
+		var buf bytes.Buffer
+		if err := reconciliation.WriteXLSX(&buf, models.CountryTimezoneOffset(store.CountryCode)); err != nil {
+			response.Status = false
+			response.Errors["report"] = "Unable to write bank reconciliation:" + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, filename))
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	default:
+		response.Status = true
+		response.Result = reconciliation
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// documentErrors are the validation errors of a document created for a statement line, passed on as they are.
+type documentErrors map[string]string
+
+func (errs documentErrors) Error() string {
+	return fmt.Sprint(map[string]string(errs))
+}
+
+// changeBankStatement runs action on the statement in the URL, saves it and audit-logs the change.
+// action returns the HTTP status and, on failure, the error key.
+func changeBankStatement(w http.ResponseWriter, r *http.Request, action func(statement *models.BankStatement, store *models.Store, userID primitive.ObjectID) (int, string, error)) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	statement, store := findBankStatement(w, r, &response)
+	if statement == nil {
+		return
+	}
+	if statement.Deleted {
+		response.Status = false
+		response.Errors["bank_statement"] = "Bank statement is deleted"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	statementOld := *statement
+	statementOld.Lines = append([]models.BankStatementLine{}, statement.Lines...)
+
+	status, errKey, err := action(statement, store, userID)
+	if err != nil {
+		response.Status = false
+		if errs, ok := err.(documentErrors); ok {
+			response.Errors = errs
+		} else {
+			response.Errors[errKey] = err.Error()
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	statement.UpdatedBy = &userID
+	statement.UpdatedAt = &now
+	err = statement.Update()
+	if err != nil {
+		response.Status = false
+		response.Errors["update"] = "Unable to update:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, statement.StoreID, models.AuditActionUpdate, "bank_statement", statement.ID, statement.StatementNo, &statementOld, statement)
+
+	response.Status = true
+	response.Result = statement
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func findBankStatement(w http.ResponseWriter, r *http.Request, response *models.Response) (*models.BankStatement, *models.Store) {
+	params := mux.Vars(r)
+	statementID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["bank_statement_id"] = "Invalid Bank Statement ID:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil, nil
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil, nil
+	}
+
+	statement, err := store.FindBankStatementByID(&statementID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil, nil
+	}
+
+	return statement, store
+}
+
+func parseBankMatchToleranceDays(value string) (int, error) {
+	if value == "" {
+		return models.BankMatchDefaultToleranceDays, nil
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 || days > models.BankMatchMaxToleranceDays {
+		return 0, fmt.Errorf("date tolerance should be 0 to %d days", models.BankMatchMaxToleranceDays)
+	}
+	return days, nil
+}
+
+// callCreateHandler runs a create handler (e.g. CreateExpense) for document on behalf of the caller of r,
+// so the document goes through the same validation, accounting and audit as one created from its own page.
+// It returns the ID of the created document or the validation errors.
+func callCreateHandler(r *http.Request, handler http.HandlerFunc, document map[string]interface{}) (primitive.ObjectID, documentErrors) {
+	body, err := json.Marshal(document)
+	if err != nil {
+		return primitive.NilObjectID, documentErrors{"input": err.Error()}
+	}
+
+	request := httptest.NewRequest(http.MethodPost, r.URL.Path+"?"+r.URL.RawQuery, bytes.NewReader(body))
+	request.Header = r.Header.Clone()
+	request.Header.Set("Content-Type", "application/json")
+	request.RemoteAddr = r.RemoteAddr
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+
+	var result struct {
+		Status bool              `json:"status"`
+		Errors map[string]string `json:"errors"`
+		Result struct {
+			ID primitive.ObjectID `json:"id"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		return primitive.NilObjectID, documentErrors{"response": "Invalid response:" + err.Error()}
+	}
+	if !result.Status {
+		if len(result.Errors) == 0 {
+			result.Errors = map[string]string{"create": "Unable to create document"}
+		}
+		return primitive.NilObjectID, documentErrors(result.Errors)
+	}
+	return result.Result.ID, nil
+}
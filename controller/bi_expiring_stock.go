@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/sirinibin/startpos/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetBIExpiringStock : GET /v1/bi/expiring-stock
+// Query params: search[store_id] (required), days (default 30), warehouse_id (optional), limit (default 100)
+func GetBIExpiringStock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token: " + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			days = n
+		}
+	}
+
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			limit = n
+		}
+	}
+
+	var warehouseID *primitive.ObjectID
+	if v := r.URL.Query().Get("warehouse_id"); v != "" {
+		id, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			response.Status = false
+			response.Errors["warehouse_id"] = "Invalid warehouse id: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		warehouseID = &id
+	}
+
+	results, err := store.GetExpiringProductLots(days, warehouseID, limit)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to fetch expiring stock: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = results
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirinibin/startpos/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetBIPromotionUplift : GET /v1/bi/promotion-uplift
+// Query params: search[store_id] (required), promotion_id (optional)
+func GetBIPromotionUplift(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token: " + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var promotionID *primitive.ObjectID
+	if v := r.URL.Query().Get("promotion_id"); v != "" {
+		id, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			response.Status = false
+			response.Errors["promotion_id"] = "Invalid promotion id: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		promotionID = &id
+	}
+
+	results, err := store.GetBIPromotionUplift(promotionID)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to fetch BI promotion uplift: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = results
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,410 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"github.com/sirinibin/startpos/backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListBudget : handler for GET /budget
+func ListBudget(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	budgets, criterias, err := store.SearchBudget(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find budgets:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "budget")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of budgets:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(budgets) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = budgets
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateBudget : handler for POST /budget
+func CreateBudget(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var budget *models.Budget
+	// Decode data
+	if !utils.Decode(w, r, &budget) {
+		return
+	}
+	budget.StoreID = &store.ID
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Validate data
+	if errs := budget.Validate(w, r, "create"); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	budget.Deleted = false
+	budget.CreatedBy = &userID
+	budget.UpdatedBy = &userID
+	budget.CreatedAt = &now
+	budget.UpdatedAt = &now
+
+	err = budget.Insert()
+	if err != nil {
+		response.Status = false
+		response.Errors["insert"] = "Unable to insert to db:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, budget.StoreID, models.AuditActionCreate, "budget", budget.ID, budget.Name, nil, budget)
+
+	response.Status = true
+	response.Result = budget
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateBudget : handler function for PUT /v1/budget/<id> call
+func UpdateBudget(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	budget := findBudget(w, r, &response)
+	if budget == nil {
+		return
+	}
+	budgetOld := *budget
+	budgetOld.Lines = append([]models.BudgetLine{}, budget.Lines...)
+
+	// Decode data
+	if !utils.Decode(w, r, &budget) {
+		return
+	}
+	budget.ID = budgetOld.ID
+	budget.StoreID = budgetOld.StoreID
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	budget.UpdatedBy = &userID
+	budget.UpdatedAt = &now
+
+	// Validate data
+	if errs := budget.Validate(w, r, "update"); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = budget.Update()
+	if err != nil {
+		response.Status = false
+		response.Errors["update"] = "Unable to update:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, budget.StoreID, models.AuditActionUpdate, "budget", budget.ID, budget.Name, &budgetOld, budget)
+
+	response.Status = true
+	response.Result = budget
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewBudget : handler function for GET /v1/budget/<id> call
+func ViewBudget(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	budget := findBudget(w, r, &response)
+	if budget == nil {
+		return
+	}
+
+	response.Status = true
+	response.Result = budget
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteBudget : handler function for DELETE /v1/budget/<id> call
+func DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	budget := findBudget(w, r, &response)
+	if budget == nil {
+		return
+	}
+	budgetOld := *budget
+
+	err = budget.DeleteBudget(tokenClaims)
+	if err != nil {
+		response.Status = false
+		response.Errors["delete"] = "Unable to delete:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, budget.StoreID, models.AuditActionDelete, "budget", budget.ID, budget.Name, &budgetOld, budget)
+
+	response.Status = true
+	response.Result = "Deleted successfully"
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetBudgetVariance : handler function for GET /v1/budget/<id>/variance?period_from=1&period_to=12 call
+func GetBudgetVariance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	budget := findBudget(w, r, &response)
+	if budget == nil {
+		return
+	}
+
+	store, err := models.FindStoreByID(budget.StoreID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	query := r.URL.Query()
+	periodFrom, periodTo := 0, 0
+	if value := query.Get("period_from"); value != "" {
+		if periodFrom, err = strconv.Atoi(value); err != nil {
+			response.Errors["period_from"] = "Invalid period from:" + err.Error()
+		}
+	}
+	if value := query.Get("period_to"); value != "" {
+		if periodTo, err = strconv.Atoi(value); err != nil {
+			response.Errors["period_to"] = "Invalid period to:" + err.Error()
+		}
+	}
+	if len(response.Errors) > 0 {
+		response.Status = false
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	variance, err := store.MakeBudgetVariance(budget, periodFrom, periodTo)
+	if err != nil {
+		response.Status = false
+		response.Errors["report"] = "Unable to make budget variance:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = variance
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ListBudgetAlert : handler for GET /budget-alert
+func ListBudgetAlert(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	alerts, criterias, err := store.SearchBudgetAlert(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find budget alerts:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "budget_alert")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of budget alerts:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(alerts) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = alerts
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func findBudget(w http.ResponseWriter, r *http.Request, response *models.Response) *models.Budget {
+	params := mux.Vars(r)
+	budgetID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["budget_id"] = "Invalid Budget ID:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	budget, err := store.FindBudgetByID(&budgetID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	return budget
+}
@@ -145,6 +145,8 @@ func CreateCapital(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, capital.StoreID, models.AuditActionCreate, "capital", capital.ID, capital.Code, nil, capital)
+
 	err = capital.DoAccounting()
 	if err != nil {
 		response.Status = false
@@ -249,6 +251,8 @@ func UpdateCapital(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, capital.StoreID, models.AuditActionUpdate, "capital", capital.ID, capital.Code, capitalOld, capital)
+
 	err = capital.AttributesValueChangeEvent(capitalOld)
 	if err != nil {
 		response.Status = false
@@ -444,6 +448,15 @@ func DeleteCapital(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, capital.StoreID, "capital", capital.ID, capital.Code, capital.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	capitalOld := *capital
 	err = capital.DeleteCapital(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -452,6 +465,8 @@ func DeleteCapital(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, capital.StoreID, models.AuditActionDelete, "capital", capital.ID, capital.Code, &capitalOld, capital)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 
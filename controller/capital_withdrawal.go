@@ -145,6 +145,8 @@ func CreateCapitalWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, capitalwithdrawal.StoreID, models.AuditActionCreate, "capital_withdrawal", capitalwithdrawal.ID, capitalwithdrawal.Code, nil, capitalwithdrawal)
+
 	response.Status = true
 	response.Result = capitalwithdrawal
 
@@ -248,6 +250,8 @@ func UpdateCapitalWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, capitalwithdrawal.StoreID, models.AuditActionUpdate, "capital_withdrawal", capitalwithdrawal.ID, capitalwithdrawal.Code, capitalwithdrawalOld, capitalwithdrawal)
+
 	err = capitalwithdrawal.AttributesValueChangeEvent(capitalwithdrawalOld)
 	if err != nil {
 		response.Status = false
@@ -422,6 +426,15 @@ func DeleteCapitalWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, capitalwithdrawal.StoreID, "capital_withdrawal", capitalwithdrawal.ID, capitalwithdrawal.Code, capitalwithdrawal.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	capitalwithdrawalOld := *capitalwithdrawal
 	err = capitalwithdrawal.DeleteCapitalWithdrawal(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -430,6 +443,8 @@ func DeleteCapitalWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, capitalwithdrawal.StoreID, models.AuditActionDelete, "capital_withdrawal", capitalwithdrawal.ID, capitalwithdrawal.Code, &capitalwithdrawalOld, capitalwithdrawal)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 
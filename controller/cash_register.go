@@ -0,0 +1,348 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"github.com/sirinibin/startpos/backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListCashRegister : handler for GET /cash-register
+func ListCashRegister(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	cashRegisters := []models.CashRegister{}
+
+	cashRegisters, criterias, err := store.SearchCashRegister(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find cash registers:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "cash_register")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of cash registers:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(cashRegisters) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = cashRegisters
+	}
+
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// CreateCashRegister : handler for POST /cash-register
+func CreateCashRegister(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var cashRegister *models.CashRegister
+	// Decode data
+	if !utils.Decode(w, r, &cashRegister) {
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	cashRegister.CreatedBy = &userID
+	cashRegister.UpdatedBy = &userID
+	now := time.Now()
+	cashRegister.CreatedAt = &now
+	cashRegister.UpdatedAt = &now
+
+	// Validate data
+	if errs := cashRegister.Validate(w, r, "create"); len(errs) > 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = cashRegister.Insert()
+	if err != nil {
+		response.Status = false
+		response.Errors = make(map[string]string)
+		response.Errors["insert"] = "Unable to insert to db:" + err.Error()
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, cashRegister.StoreID, models.AuditActionCreate, "cash_register", cashRegister.ID, cashRegister.Code, nil, cashRegister)
+
+	response.Status = true
+	response.Result = cashRegister
+
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// UpdateCashRegister : handler function for PUT /v1/cash-register call
+func UpdateCashRegister(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var cashRegister *models.CashRegister
+
+	params := mux.Vars(r)
+
+	cashRegisterID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["cash_register_id"] = "Invalid Cash Register ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	cashRegister, err = store.FindCashRegisterByID(&cashRegisterID, bson.M{})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	cashRegisterOld := *cashRegister
+	// Decode data
+	if !utils.Decode(w, r, &cashRegister) {
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	cashRegister.UpdatedBy = &userID
+	now := time.Now()
+	cashRegister.UpdatedAt = &now
+
+	// Validate data
+	if errs := cashRegister.Validate(w, r, "update"); len(errs) > 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = cashRegister.Update()
+	if err != nil {
+		response.Status = false
+		response.Errors = make(map[string]string)
+		response.Errors["update"] = "Unable to update:" + err.Error()
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, cashRegister.StoreID, models.AuditActionUpdate, "cash_register", cashRegister.ID, cashRegister.Code, &cashRegisterOld, cashRegister)
+
+	cashRegister, err = store.FindCashRegisterByID(&cashRegister.ID, bson.M{})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["view"] = "Unable to find cash register:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = cashRegister
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewCashRegister : handler function for GET /v1/cash-register/<id> call
+func ViewCashRegister(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	params := mux.Vars(r)
+
+	cashRegisterID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["cash_register_id"] = "Invalid Cash Register ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var cashRegister *models.CashRegister
+
+	selectFields := map[string]interface{}{}
+	keys, ok := r.URL.Query()["select"]
+	if ok && len(keys[0]) >= 1 {
+		selectFields = models.ParseSelectString(keys[0])
+	}
+
+	cashRegister, err = store.FindCashRegisterByID(&cashRegisterID, selectFields)
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = cashRegister
+
+	json.NewEncoder(w).Encode(response)
+
+}
+
+// DeleteCashRegister : handler function for DELETE /v1/cash-register/<id> call
+func DeleteCashRegister(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	params := mux.Vars(r)
+
+	cashRegisterID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["cash_register_id"] = "Invalid Cash Register ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	cashRegister, err := store.FindCashRegisterByID(&cashRegisterID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	cashRegisterOld := *cashRegister
+	err = cashRegister.DeleteCashRegister(tokenClaims)
+	if err != nil {
+		response.Status = false
+		response.Errors["delete"] = "Unable to delete:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, cashRegister.StoreID, models.AuditActionDelete, "cash_register", cashRegister.ID, cashRegister.Code, &cashRegisterOld, cashRegister)
+
+	response.Status = true
+	response.Result = "Deleted successfully"
+
+	json.NewEncoder(w).Encode(response)
+
+}
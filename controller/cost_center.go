@@ -0,0 +1,305 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"github.com/sirinibin/startpos/backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListCostCenter : handler for GET /cost-center
+func ListCostCenter(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	costCenters, criterias, err := store.SearchCostCenter(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find cost centers:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "cost_center")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of cost centers:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(costCenters) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = costCenters
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateCostCenter : handler for POST /cost-center
+func CreateCostCenter(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var costCenter *models.CostCenter
+	// Decode data
+	if !utils.Decode(w, r, &costCenter) {
+		return
+	}
+	costCenter.StoreID = &store.ID
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Validate data
+	if errs := costCenter.Validate(w, r, "create"); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	costCenter.Deleted = false
+	costCenter.CreatedBy = &userID
+	costCenter.UpdatedBy = &userID
+	costCenter.CreatedAt = &now
+	costCenter.UpdatedAt = &now
+
+	err = costCenter.Insert()
+	if err != nil {
+		response.Status = false
+		response.Errors["insert"] = "Unable to insert to db:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, costCenter.StoreID, models.AuditActionCreate, "cost_center", costCenter.ID, costCenter.Name, nil, costCenter)
+
+	response.Status = true
+	response.Result = costCenter
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateCostCenter : handler function for PUT /v1/cost-center/<id> call
+func UpdateCostCenter(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	costCenter := findCostCenter(w, r, &response)
+	if costCenter == nil {
+		return
+	}
+	costCenterOld := *costCenter
+
+	// Decode data
+	if !utils.Decode(w, r, &costCenter) {
+		return
+	}
+	costCenter.ID = costCenterOld.ID
+	costCenter.StoreID = costCenterOld.StoreID
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	costCenter.UpdatedBy = &userID
+	costCenter.UpdatedAt = &now
+
+	// Validate data
+	if errs := costCenter.Validate(w, r, "update"); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = costCenter.Update()
+	if err != nil {
+		response.Status = false
+		response.Errors["update"] = "Unable to update:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = costCenter.AttributesValueChangeEvent(&costCenterOld)
+	if err != nil {
+		response.Status = false
+		response.Errors["attributes_value_change"] = "Unable to update:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, costCenter.StoreID, models.AuditActionUpdate, "cost_center", costCenter.ID, costCenter.Name, &costCenterOld, costCenter)
+
+	response.Status = true
+	response.Result = costCenter
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewCostCenter : handler function for GET /v1/cost-center/<id> call
+func ViewCostCenter(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	costCenter := findCostCenter(w, r, &response)
+	if costCenter == nil {
+		return
+	}
+
+	response.Status = true
+	response.Result = costCenter
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteCostCenter : handler function for DELETE /v1/cost-center/<id> call
+// Documents and postings already tagged keep the cost center.
+func DeleteCostCenter(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	costCenter := findCostCenter(w, r, &response)
+	if costCenter == nil {
+		return
+	}
+	costCenterOld := *costCenter
+
+	err = costCenter.DeleteCostCenter(tokenClaims)
+	if err != nil {
+		response.Status = false
+		response.Errors["delete"] = "Unable to delete:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, costCenter.StoreID, models.AuditActionDelete, "cost_center", costCenter.ID, costCenter.Name, &costCenterOld, costCenter)
+
+	response.Status = true
+	response.Result = "Deleted successfully"
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func findCostCenter(w http.ResponseWriter, r *http.Request, response *models.Response) *models.CostCenter {
+	params := mux.Vars(r)
+	costCenterID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["cost_center_id"] = "Invalid Cost Center ID:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	costCenter, err := store.FindCostCenterByID(&costCenterID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	return costCenter
+}
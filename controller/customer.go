@@ -208,6 +208,8 @@ func CreateCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, customer.StoreID, models.AuditActionCreate, "customer", customer.ID, customer.Code, nil, customer)
+
 	if customer.OpeningBalance > 0 {
 		customerStore, err := models.FindStoreByID(customer.StoreID, bson.M{})
 		if err != nil {
@@ -301,6 +303,8 @@ func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
 	if customer.StoreID == nil {
 		customer.StoreID = customerOld.StoreID
 	}
+	// Loyalty points only change through the loyalty point history
+	customer.LoyaltyPoints = customerOld.LoyaltyPoints
 
 	// Validate data
 	if errs := customer.Validate(w, r, "update"); len(errs) > 0 {
@@ -338,6 +342,8 @@ func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, customer.StoreID, models.AuditActionUpdate, "customer", customer.ID, customer.Code, customerOld, customer)
+
 	err = customer.AttributesValueChangeEvent(customerOld)
 	if err != nil {
 		response.Status = false
@@ -505,6 +511,7 @@ func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	customerOld := *customer
 	err = customer.DeleteCustomer(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -513,6 +520,8 @@ func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, customer.StoreID, models.AuditActionDelete, "customer", customer.ID, customer.Code, &customerOld, customer)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 
@@ -560,6 +569,7 @@ func RestoreCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	customerOld := *customer
 	err = customer.RestoreCustomer(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -568,6 +578,8 @@ func RestoreCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, customer.StoreID, models.AuditActionRestore, "customer", customer.ID, customer.Code, &customerOld, customer)
+
 	response.Status = true
 	response.Result = "Restored successfully"
 
@@ -717,6 +729,66 @@ func GetCustomerHistory(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// GetCustomerLoyaltyPoints : handler for GET /v1/customer/{id}/loyalty-points
+// Returns the loyalty points balance and point history of a customer.
+func GetCustomerLoyaltyPoints(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	params := mux.Vars(r)
+	customerID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["customer_id"] = "Invalid Customer ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	balance, err := store.GetLoyaltyPointsBalance(customerID, nil)
+	if err != nil {
+		response.Status = false
+		response.Errors["loyalty_points"] = "Unable to find loyalty points balance:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	history, err := store.GetLoyaltyPointHistory(customerID)
+	if err != nil {
+		response.Status = false
+		response.Errors["loyalty_point_history"] = "Unable to fetch loyalty point history:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = map[string]interface{}{
+		"loyalty_points": balance,
+		"value":          store.Settings.Loyalty.ValueOfPoints(balance),
+		"history":        history,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
 // CustomerSummary : handler for GET /customer/summary
 func CustomerSummary(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -196,6 +196,8 @@ func CreateCustomerDeposit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, customerdeposit.StoreID, models.AuditActionCreate, "customer_deposit", customerdeposit.ID, customerdeposit.Code, nil, customerdeposit)
+
 	err = customerdeposit.DoAccounting()
 	if err != nil {
 		response.Status = false
@@ -370,6 +372,8 @@ func UpdateCustomerDeposit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, customerdeposit.StoreID, models.AuditActionUpdate, "customer_deposit", customerdeposit.ID, customerdeposit.Code, customerdepositOld, customerdeposit)
+
 	err = customerdeposit.AttributesValueChangeEvent(customerdepositOld)
 	if err != nil {
 		response.Status = false
@@ -667,6 +671,15 @@ func DeleteCustomerDeposit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, customerdeposit.StoreID, "customer_deposit", customerdeposit.ID, customerdeposit.Code, customerdeposit.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	customerdepositOld := *customerdeposit
 	err = customerdeposit.DeleteCustomerDeposit(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -675,6 +688,8 @@ func DeleteCustomerDeposit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, customerdeposit.StoreID, models.AuditActionDelete, "customer_deposit", customerdeposit.ID, customerdeposit.Code, &customerdepositOld, customerdeposit)
+
 	if customerdeposit.StoreID != nil {
 		go models.MarkDashboardDirty(*customerdeposit.StoreID, customerdeposit.Date)
 	}
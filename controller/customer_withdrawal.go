@@ -195,6 +195,8 @@ func CreateCustomerWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, customerwithdrawal.StoreID, models.AuditActionCreate, "customer_withdrawal", customerwithdrawal.ID, customerwithdrawal.Code, nil, customerwithdrawal)
+
 	err = customerwithdrawal.DoAccounting()
 	if err != nil {
 		response.Status = false
@@ -365,6 +367,8 @@ func UpdateCustomerWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, customerwithdrawal.StoreID, models.AuditActionUpdate, "customer_withdrawal", customerwithdrawal.ID, customerwithdrawal.Code, customerwithdrawalOld, customerwithdrawal)
+
 	err = customerwithdrawal.AttributesValueChangeEvent(customerwithdrawalOld)
 	if err != nil {
 		response.Status = false
@@ -670,6 +674,15 @@ func DeleteCustomerWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, customerwithdrawal.StoreID, "customer_withdrawal", customerwithdrawal.ID, customerwithdrawal.Code, customerwithdrawal.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	customerwithdrawalOld := *customerwithdrawal
 	err = customerwithdrawal.DeleteCustomerWithdrawal(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -678,6 +691,8 @@ func DeleteCustomerWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, customerwithdrawal.StoreID, models.AuditActionDelete, "customer_withdrawal", customerwithdrawal.ID, customerwithdrawal.Code, &customerwithdrawalOld, customerwithdrawal)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 
@@ -0,0 +1,433 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"github.com/sirinibin/startpos/backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListDebitNote : handler for GET /debit-note
+func ListDebitNote(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	debitNotes, criterias, err := store.SearchDebitNote(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find debit notes:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "debitnote")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of debit notes:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(debitNotes) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = debitNotes
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateDebitNote : handler for POST /debit-note
+func CreateDebitNote(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var debitNote *models.DebitNote
+	// Decode data
+	if !utils.Decode(w, r, &debitNote) {
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	debitNote.CreatedBy = &userID
+	debitNote.UpdatedBy = &userID
+	now := time.Now()
+	debitNote.CreatedAt = &now
+	debitNote.UpdatedAt = &now
+
+	// Validate data
+	if errs := debitNote.Validate(w, r, "create", nil); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	debitNote.UUID = uuid.New().String()
+	debitNote.Zatca = models.ZatcaReporting{}
+
+	err = debitNote.MakeRedisCode()
+	if err != nil {
+		response.Status = false
+		response.Errors["code"] = "Error making code: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := models.FindStoreByID(debitNote.StoreID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if store.Zatca.Phase == "2" && store.Zatca.Connected && debitNote.EnableReportToZatca {
+		zatcaQueue := GetOrCreateQueue(store.ID.Hex(), "zatca")
+		zatcaQueueToken := generateQueueToken()
+		zatcaQueue.Enqueue(Request{Token: zatcaQueueToken})
+		zatcaQueue.WaitUntilMyTurn(zatcaQueueToken)
+
+		err = debitNote.ReportToZatca()
+		if err != nil {
+			zatcaQueue.Pop()
+			CleanupQueueIfEmpty(store.ID.Hex(), "zatca")
+			debitNote.UnMakeRedisCode()
+			response.Status = false
+			response.Errors["reporting_to_zatca"] = "Error reporting to zatca: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		zatcaQueue.Pop()
+		CleanupQueueIfEmpty(store.ID.Hex(), "zatca")
+	}
+
+	err = debitNote.Insert()
+	if err != nil {
+		redisErr := debitNote.UnMakeRedisCode()
+		if redisErr != nil {
+			response.Errors["error_unmaking_code"] = "error_unmaking_code: " + redisErr.Error()
+		}
+
+		response.Status = false
+		response.Errors = make(map[string]string)
+		response.Errors["insert"] = "Unable to insert to db:" + err.Error()
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, debitNote.StoreID, models.AuditActionCreate, "debit_note", debitNote.ID, debitNote.Code, nil, debitNote)
+
+	err = debitNote.DoAccounting()
+	if err != nil {
+		response.Status = false
+		response.Errors["do_accounting"] = "Error do accounting: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	debitNote.SetCustomerCreditBalance()
+	go debitNote.SetPostBalances()
+
+	store.NotifyUsers("debit_note_updated")
+	if debitNote.StoreID != nil {
+		go models.MarkDashboardDirty(*debitNote.StoreID, debitNote.Date)
+	}
+
+	response.Status = true
+	response.Result = debitNote
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateDebitNote : handler function for PUT /v1/debit-note/<id> call
+func UpdateDebitNote(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	params := mux.Vars(r)
+
+	debitNoteID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["debit_note_id"] = "Invalid Debit Note ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	debitNoteOld, err := store.FindDebitNoteByID(&debitNoteID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["debit_note"] = "Unable to find debit note:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	debitNote, err := store.FindDebitNoteByID(&debitNoteID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["debit_note"] = "Unable to find debit note:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if !utils.Decode(w, r, &debitNote) {
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	debitNote.UpdatedBy = &userID
+	now := time.Now()
+	debitNote.UpdatedAt = &now
+
+	// Validate data
+	if errs := debitNote.Validate(w, r, "update", debitNoteOld); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = debitNote.UndoAccounting()
+	if err != nil {
+		response.Status = false
+		response.Errors["undo_accounting"] = "Error undo accounting: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = debitNote.Update()
+	if err != nil {
+		response.Status = false
+		response.Errors = make(map[string]string)
+		response.Errors["update"] = "Unable to update:" + err.Error()
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, debitNote.StoreID, models.AuditActionUpdate, "debit_note", debitNote.ID, debitNote.Code, debitNoteOld, debitNote)
+
+	err = debitNote.DoAccounting()
+	if err != nil {
+		response.Status = false
+		response.Errors["do_accounting"] = "Error do accounting: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	debitNote.SetCustomerCreditBalance()
+	debitNoteOld.SetCustomerCreditBalance()
+
+	go func() {
+		debitNote.SetPostBalances()
+		debitNoteOld.SetPostBalances()
+	}()
+
+	store.NotifyUsers("debit_note_updated")
+	if debitNote.StoreID != nil {
+		go models.MarkDashboardDirty(*debitNote.StoreID, debitNote.Date)
+	}
+
+	response.Status = true
+	response.Result = debitNote
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewDebitNote : handler function for GET /v1/debit-note/<id> call
+func ViewDebitNote(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Status = false
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	params := mux.Vars(r)
+
+	debitNoteID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Errors["debit_note_id"] = "Invalid Debit Note ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	selectFields := map[string]interface{}{}
+	keys, ok := r.URL.Query()["select"]
+	if ok && len(keys[0]) >= 1 {
+		selectFields = models.ParseSelectString(keys[0])
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	debitNote, err := store.FindDebitNoteByID(&debitNoteID, selectFields)
+	if err != nil {
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = debitNote
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteDebitNote : handler function for DELETE /v1/debit-note/<id> call
+func DeleteDebitNote(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	params := mux.Vars(r)
+
+	debitNoteID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["debit_note_id"] = "Invalid Debit Note ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	debitNote, err := store.FindDebitNoteByID(&debitNoteID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if message := models.ValidateAccountingLock(r, debitNote.StoreID, models.DebitNoteReferenceModel, debitNote.ID, debitNote.Code, debitNote.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	debitNoteOld := *debitNote
+	err = debitNote.DeleteDebitNote(tokenClaims)
+	if err != nil {
+		response.Status = false
+		response.Errors["delete"] = "Unable to delete:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, debitNote.StoreID, models.AuditActionDelete, "debit_note", debitNote.ID, debitNote.Code, &debitNoteOld, debitNote)
+
+	debitNote.SetCustomerCreditBalance()
+	go debitNote.SetPostBalances()
+
+	store.NotifyUsers("debit_note_updated")
+	if debitNote.StoreID != nil {
+		go models.MarkDashboardDirty(*debitNote.StoreID, debitNote.Date)
+	}
+
+	response.Status = true
+	response.Result = "Deleted successfully"
+
+	json.NewEncoder(w).Encode(response)
+}
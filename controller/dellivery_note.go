@@ -175,6 +175,8 @@ func CreateDeliveryNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, deliverynote.StoreID, models.AuditActionCreate, "delivery_note", deliverynote.ID, deliverynote.Code, nil, deliverynote)
+
 	go deliverynote.CreateProductsDeliveryNoteHistory()
 
 	go deliverynote.SetProductsDeliveryNoteStats()
@@ -317,6 +319,8 @@ func UpdateDeliveryNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, deliverynote.StoreID, models.AuditActionUpdate, "delivery_note", deliverynote.ID, deliverynote.Code, deliverynoteOld, deliverynote)
+
 	go deliverynote.ClearProductsDeliveryNoteHistory()
 	go deliverynote.CreateProductsDeliveryNoteHistory()
 
@@ -133,6 +133,8 @@ func CreateDivident(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, divident.StoreID, models.AuditActionCreate, "divident", divident.ID, divident.Code, nil, divident)
+
 	err = divident.DoAccounting()
 	if err != nil {
 		response.Status = false
@@ -237,6 +239,8 @@ func UpdateDivident(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, divident.StoreID, models.AuditActionUpdate, "divident", divident.ID, divident.Code, dividentOld, divident)
+
 	err = divident.AttributesValueChangeEvent(dividentOld)
 	if err != nil {
 		response.Status = false
@@ -429,6 +433,15 @@ func DeleteDivident(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, divident.StoreID, "divident", divident.ID, divident.Code, divident.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	dividentOld := *divident
 	err = divident.DeleteDivident(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -437,6 +450,8 @@ func DeleteDivident(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, divident.StoreID, models.AuditActionDelete, "divident", divident.ID, divident.Code, &dividentOld, divident)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 
@@ -151,6 +151,8 @@ func CreateEmployee(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, employee.StoreID, models.AuditActionCreate, "employee", employee.ID, employee.Code, nil, employee)
+
 	// Create the employee liability account immediately on creation.
 	store, _ := models.FindStoreByID(employee.StoreID, bson.M{})
 	if store != nil {
@@ -295,6 +297,14 @@ func UpdateEmployee(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	employeeOld, err := store.FindEmployeeByID(&id, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find employee:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	// Remember prior opening-balance values so we only re-post the ledger entry
 	// when they actually change (avoids needless ledger churn on unrelated edits).
 	oldOpeningBalance := employee.OpeningBalance
@@ -346,6 +356,8 @@ func UpdateEmployee(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, employee.StoreID, models.AuditActionUpdate, "employee", employee.ID, employee.Code, employeeOld, employee)
+
 	acc, err := employee.GetOrCreateLiabilityAccount(store)
 	if err != nil {
 		response.Status = false
@@ -442,6 +454,7 @@ func DeleteEmployee(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	employeeOld := *employee
 	if err := employee.Delete(tokenClaims); err != nil {
 		response.Status = false
 		response.Errors["delete"] = "Unable to delete employee:" + err.Error()
@@ -449,6 +462,8 @@ func DeleteEmployee(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, employee.StoreID, models.AuditActionDelete, "employee", employee.ID, employee.Code, &employeeOld, employee)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 	json.NewEncoder(w).Encode(response)
@@ -634,6 +649,8 @@ func CreateEmployeeSalaryPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, payment.StoreID, models.AuditActionCreate, "employee_salary_payment", payment.ID, payment.Code, nil, payment)
+
 	if err := payment.DoAccounting(); err != nil {
 		response.Status = false
 		response.Errors["accounting"] = "Error creating ledger entries:" + err.Error()
@@ -734,6 +751,8 @@ func UpdateEmployeeSalaryPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	paymentOld := *payment
+
 	// Remember old pay period so its accrual can be re-checked after the update.
 	oldMonth, oldYear, oldEmployeeID := payment.Month, payment.Year, payment.EmployeeID
 
@@ -797,6 +816,8 @@ func UpdateEmployeeSalaryPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, payment.StoreID, models.AuditActionUpdate, "employee_salary_payment", payment.ID, payment.Code, &paymentOld, payment)
+
 	if err := payment.DoAccounting(); err != nil {
 		response.Status = false
 		response.Errors["accounting"] = "Error creating ledger entries:" + err.Error()
@@ -874,6 +895,7 @@ func DeleteEmployeeSalaryPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	paymentOld := *payment
 	if err := payment.Delete(tokenClaims); err != nil {
 		response.Status = false
 		response.Errors["delete"] = "Unable to delete salary payment:" + err.Error()
@@ -881,6 +903,8 @@ func DeleteEmployeeSalaryPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, payment.StoreID, models.AuditActionDelete, "employee_salary_payment", payment.ID, payment.Code, &paymentOld, payment)
+
 	if err := payment.RegenerateSalaryDueIfNeeded(); err != nil {
 		response.Status = false
 		response.Errors["regenerate_salary_due"] = "Error regenerating salary due ledger:" + err.Error()
@@ -0,0 +1,306 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"github.com/sirinibin/startpos/backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListExchangeRate : handler for GET /exchange-rate
+func ListExchangeRate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	exchangeRates, criterias, err := store.SearchExchangeRate(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find exchange rates:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "exchange_rate")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of exchange rates:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(exchangeRates) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = exchangeRates
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetLatestExchangeRates : handler for GET /exchange-rate/latest
+// Returns the store's base currency and the current rate of every currency, or the rate of
+// search[currency] on search[date] when given.
+func GetLatestExchangeRates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var exchangeRates []models.ExchangeRate
+	currency := r.URL.Query().Get("search[currency]")
+	if currency != "" {
+		date := time.Now()
+		if dateStr := r.URL.Query().Get("search[date]"); dateStr != "" {
+			date, err = time.Parse("2006-01-02T15:04:05Z07:00", dateStr)
+			if err != nil {
+				response.Status = false
+				response.Errors["date"] = "Invalid date format"
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+		}
+
+		exchangeRate, err := store.FindExchangeRate(currency, date)
+		if err != nil {
+			response.Status = false
+			response.Errors["find"] = "Unable to find the exchange rate:" + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		exchangeRates = []models.ExchangeRate{}
+		if exchangeRate != nil {
+			exchangeRates = append(exchangeRates, *exchangeRate)
+		}
+	} else {
+		exchangeRates, err = store.FindLatestExchangeRates()
+		if err != nil {
+			response.Status = false
+			response.Errors["find"] = "Unable to find exchange rates:" + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	response.Status = true
+	response.Result = map[string]interface{}{
+		"base_currency": store.BaseCurrency(),
+		"rates":         exchangeRates,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateExchangeRate : handler for POST /exchange-rate
+// Body: { "currency": "USD", "rate": 3.75, "date_str": "..." }, rate is in the store's base currency.
+func CreateExchangeRate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var exchangeRate *models.ExchangeRate
+	// Decode data
+	if !utils.Decode(w, r, &exchangeRate) {
+		return
+	}
+	exchangeRate.StoreID = &store.ID
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Validate data
+	if errs := exchangeRate.Validate(w, r, "create"); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	exchangeRate.Deleted = false
+	exchangeRate.CreatedBy = &userID
+	exchangeRate.UpdatedBy = &userID
+	exchangeRate.CreatedAt = &now
+	exchangeRate.UpdatedAt = &now
+
+	err = exchangeRate.Insert()
+	if err != nil {
+		response.Status = false
+		response.Errors["insert"] = "Unable to insert to db:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, exchangeRate.StoreID, models.AuditActionCreate, "exchange_rate", exchangeRate.ID, exchangeRate.Currency, nil, exchangeRate)
+
+	response.Status = true
+	response.Result = exchangeRate
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewExchangeRate : handler function for GET /v1/exchange-rate/<id> call
+func ViewExchangeRate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	exchangeRate := findExchangeRate(w, r, &response)
+	if exchangeRate == nil {
+		return
+	}
+
+	response.Status = true
+	response.Result = exchangeRate
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteExchangeRate : handler function for DELETE /v1/exchange-rate/<id> call
+// Documents already saved keep the rate they were booked at.
+func DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	exchangeRate := findExchangeRate(w, r, &response)
+	if exchangeRate == nil {
+		return
+	}
+	exchangeRateOld := *exchangeRate
+
+	err = exchangeRate.DeleteExchangeRate(tokenClaims)
+	if err != nil {
+		response.Status = false
+		response.Errors["delete"] = "Unable to delete:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, exchangeRate.StoreID, models.AuditActionDelete, "exchange_rate", exchangeRate.ID, exchangeRate.Currency+" "+strconv.FormatFloat(exchangeRate.Rate, 'f', -1, 64), &exchangeRateOld, exchangeRate)
+
+	response.Status = true
+	response.Result = "Deleted successfully"
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func findExchangeRate(w http.ResponseWriter, r *http.Request, response *models.Response) *models.ExchangeRate {
+	params := mux.Vars(r)
+	exchangeRateID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["exchange_rate_id"] = "Invalid Exchange Rate ID:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	exchangeRate, err := store.FindExchangeRateByID(&exchangeRateID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	return exchangeRate
+}
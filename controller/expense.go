@@ -185,6 +185,8 @@ func CreateExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, expense.StoreID, models.AuditActionCreate, "expense", expense.ID, expense.Code, nil, expense)
+
 	err = expense.DoAccounting()
 	if err != nil {
 		response.Status = false
@@ -199,6 +201,9 @@ func CreateExpense(w http.ResponseWriter, r *http.Request) {
 	if expense.StoreID != nil {
 		go models.MarkDashboardDirty(*expense.StoreID, expense.Date)
 	}
+	if expense.Date != nil {
+		go store.CheckBudgetAlerts(*expense.Date)
+	}
 
 	response.Status = true
 	response.Result = expense
@@ -311,6 +316,8 @@ func UpdateExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, expense.StoreID, models.AuditActionUpdate, "expense", expense.ID, expense.Code, expenseOld, expense)
+
 	err = expense.AttributesValueChangeEvent(expenseOld)
 	if err != nil {
 		response.Status = false
@@ -357,6 +364,9 @@ func UpdateExpense(w http.ResponseWriter, r *http.Request) {
 	if expense.StoreID != nil {
 		go models.MarkDashboardDirty(*expense.StoreID, expense.Date)
 	}
+	if expense.Date != nil {
+		go store.CheckBudgetAlerts(*expense.Date)
+	}
 	response.Status = true
 	response.Result = expense
 	json.NewEncoder(w).Encode(response)
@@ -525,6 +535,15 @@ func DeleteExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, expense.StoreID, "expense", expense.ID, expense.Code, expense.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	expenseOld := *expense
 	err = expense.DeleteExpense(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -533,6 +552,8 @@ func DeleteExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, expense.StoreID, models.AuditActionDelete, "expense", expense.ID, expense.Code, &expenseOld, expense)
+
 	if expense.StoreID != nil {
 		go models.MarkDashboardDirty(*expense.StoreID, expense.Date)
 	}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/models"
+)
+
+// GetTrialBalance : handler for GET /report/trial-balance
+func GetTrialBalance(w http.ResponseWriter, r *http.Request) {
+	writeFinancialStatement(w, r, models.FinancialStatementTrialBalance)
+}
+
+// GetBalanceSheet : handler for GET /report/balance-sheet
+func GetBalanceSheet(w http.ResponseWriter, r *http.Request) {
+	writeFinancialStatement(w, r, models.FinancialStatementBalanceSheet)
+}
+
+// GetIncomeStatement : handler for GET /report/income-statement
+func GetIncomeStatement(w http.ResponseWriter, r *http.Request) {
+	writeFinancialStatement(w, r, models.FinancialStatementIncomeStatement)
+}
+
+// GetCostCenterIncomeStatement : handler for GET /report/cost-center-income-statement
+func GetCostCenterIncomeStatement(w http.ResponseWriter, r *http.Request) {
+	writeFinancialStatement(w, r, models.FinancialStatementCostCenterIncome)
+}
+
+// writeFinancialStatement builds the statement for date_from..date_to (optionally with
+// compare=previous_period|previous_year and compare_periods=n) and writes it as JSON, PDF or XLSX.
+func writeFinancialStatement(w http.ResponseWriter, r *http.Request, statementType string) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	query := r.URL.Query()
+	dateFrom, err := models.ParseStatementDate(query.Get("date_from"))
+	if err != nil {
+		response.Errors["date_from"] = err.Error()
+	}
+	dateTo, err := models.ParseStatementDate(query.Get("date_to"))
+	if err != nil {
+		response.Errors["date_to"] = err.Error()
+	}
+
+	comparePeriods := 0
+	if value := query.Get("compare_periods"); value != "" {
+		comparePeriods, err = strconv.Atoi(value)
+		if err != nil {
+			response.Errors["compare_periods"] = "Invalid compare periods:" + err.Error()
+		}
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "pdf" && format != "xlsx" {
+		response.Errors["format"] = "Invalid format, allowed: json, pdf, xlsx"
+	}
+
+	if len(response.Errors) > 0 {
+		response.Status = false
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	timeZoneOffset := models.CountryTimezoneOffset(store.CountryCode)
+	if dateTo == nil {
+		now := time.Now().UTC().Add(-time.Duration(timeZoneOffset * float64(time.Hour)))
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		dateTo = &today
+	}
+
+	periods, err := models.MakeStatementPeriods(dateFrom, dateTo, query.Get("compare"), comparePeriods, timeZoneOffset, statementType == models.FinancialStatementBalanceSheet)
+	if err != nil {
+		response.Status = false
+		response.Errors["period"] = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	statement, err := store.MakeFinancialStatement(statementType, periods)
+	if err != nil {
+		response.Status = false
+		response.Errors["report"] = "Unable to make " + statementType + ":" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	filename := statementType + "_" + dateTo.Format("2006-01-02")
+
+	switch format {
+	case "pdf":
+		model, err := json.Marshal(statement)
+		if err != nil {
+			response.Status = false
+			response.Errors["report"] = "Unable to encode " + statementType + ":" + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		pdfBuf, errKey, err := renderReportPDF(printJobData{
+			Model:     model,
+			ModelName: statementType,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			response.Status = false
+			response.Errors[errKey] = err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, filename))
+		w.WriteHeader(http.StatusOK)
+		w.Write(pdfBuf)
+	case "xlsx":
+		var buf bytes.Buffer
+		if err := statement.WriteXLSX(&buf); err != nil {
+			response.Status = false
+			response.Errors["report"] = "Unable to write " + statementType + ":" + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, filename))
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	default:
+		response.Status = true
+		response.Result = statement
+		json.NewEncoder(w).Encode(response)
+	}
+}
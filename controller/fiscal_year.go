@@ -0,0 +1,382 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"github.com/sirinibin/startpos/backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListFiscalYear : handler for GET /fiscal-year
+func ListFiscalYear(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	fiscalYears, criterias, err := store.SearchFiscalYear(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find fiscal years:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "fiscal_year")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of fiscal years:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(fiscalYears) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = fiscalYears
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateFiscalYear : handler for POST /fiscal-year
+// Body: { "name": "FY 2026", "start_date_str": "...", "end_date_str": "..." }, split into monthly periods.
+func CreateFiscalYear(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var fiscalYear *models.FiscalYear
+	// Decode data
+	if !utils.Decode(w, r, &fiscalYear) {
+		return
+	}
+	fiscalYear.StoreID = &store.ID
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Validate data
+	if errs := fiscalYear.Validate(w, r, "create"); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	fiscalYear.Status = models.FiscalStatusOpen
+	fiscalYear.NetProfit = 0
+	fiscalYear.RetainedEarningsAccountID = nil
+	fiscalYear.ClosedBy = nil
+	fiscalYear.ClosedAt = nil
+	fiscalYear.CreatedBy = &userID
+	fiscalYear.UpdatedBy = &userID
+	fiscalYear.CreatedAt = &now
+	fiscalYear.UpdatedAt = &now
+
+	err = fiscalYear.Insert()
+	if err != nil {
+		response.Status = false
+		response.Errors["insert"] = "Unable to insert to db:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, fiscalYear.StoreID, models.AuditActionCreate, "fiscal_year", fiscalYear.ID, fiscalYear.Name, nil, fiscalYear)
+
+	response.Status = true
+	response.Result = fiscalYear
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewFiscalYear : handler function for GET /v1/fiscal-year/<id> call
+func ViewFiscalYear(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	fiscalYear, _ := findFiscalYear(w, r, &response)
+	if fiscalYear == nil {
+		return
+	}
+
+	response.Status = true
+	response.Result = fiscalYear
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteFiscalYear : handler function for DELETE /v1/fiscal-year/<id> call
+func DeleteFiscalYear(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	fiscalYear, _ := findFiscalYear(w, r, &response)
+	if fiscalYear == nil {
+		return
+	}
+	fiscalYearOld := *fiscalYear
+
+	err = fiscalYear.DeleteFiscalYear(tokenClaims)
+	if err != nil {
+		response.Status = false
+		response.Errors["delete"] = "Unable to delete:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, fiscalYear.StoreID, models.AuditActionDelete, "fiscal_year", fiscalYear.ID, fiscalYear.Name, &fiscalYearOld, fiscalYear)
+
+	response.Status = true
+	response.Result = "Deleted successfully"
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// CloseFiscalPeriod : handler function for POST /v1/fiscal-year/<id>/period/<number>/close call
+// Closes the period and every earlier one, locking their documents.
+func CloseFiscalPeriod(w http.ResponseWriter, r *http.Request) {
+	changeFiscalYearStatus(w, r, false, func(fiscalYear *models.FiscalYear, userID primitive.ObjectID, number int) error {
+		return fiscalYear.ClosePeriod(number, userID)
+	})
+}
+
+// ReopenFiscalPeriod : handler function for POST /v1/fiscal-year/<id>/period/<number>/reopen call
+// Admin only. Reopens the period and every later one.
+func ReopenFiscalPeriod(w http.ResponseWriter, r *http.Request) {
+	changeFiscalYearStatus(w, r, true, func(fiscalYear *models.FiscalYear, userID primitive.ObjectID, number int) error {
+		return fiscalYear.ReopenPeriod(number, userID)
+	})
+}
+
+// CloseFiscalYear : handler function for POST /v1/fiscal-year/<id>/close call
+// Books the closing entries to retained earnings and closes every period of the year.
+func CloseFiscalYear(w http.ResponseWriter, r *http.Request) {
+	changeFiscalYearStatus(w, r, false, func(fiscalYear *models.FiscalYear, userID primitive.ObjectID, _ int) error {
+		err := fiscalYear.Close(userID)
+		if err == nil {
+			go fiscalYear.SetPostBalances()
+		}
+		return err
+	})
+}
+
+// ReopenFiscalYear : handler function for POST /v1/fiscal-year/<id>/reopen call
+// Admin only. Removes the closing entries; the periods stay closed until reopened.
+func ReopenFiscalYear(w http.ResponseWriter, r *http.Request) {
+	changeFiscalYearStatus(w, r, true, func(fiscalYear *models.FiscalYear, userID primitive.ObjectID, _ int) error {
+		return fiscalYear.Reopen(userID)
+	})
+}
+
+// GetAccountingLock : handler function for GET /v1/fiscal-year/lock call
+func GetAccountingLock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	lock, err := models.FindAccountingLock(store.ID)
+	if err != nil {
+		response.Status = false
+		response.Errors["lock"] = "Unable to find the lock date:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = lock
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// changeFiscalYearStatus runs a close/reopen action on the fiscal year in the URL and audit-logs it.
+func changeFiscalYearStatus(w http.ResponseWriter, r *http.Request, adminOnly bool, action func(fiscalYear *models.FiscalYear, userID primitive.ObjectID, number int) error) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if adminOnly {
+		accessingUser, err := models.FindUserByID(&userID, bson.M{})
+		if err != nil || accessingUser.Role != "Admin" {
+			response.Status = false
+			response.Errors["role"] = "Only Admins can reopen closed accounting periods"
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	fiscalYear, _ := findFiscalYear(w, r, &response)
+	if fiscalYear == nil {
+		return
+	}
+	fiscalYearOld := *fiscalYear
+	fiscalYearOld.Periods = append([]models.FiscalPeriod{}, fiscalYear.Periods...)
+
+	number := 0
+	if value, ok := mux.Vars(r)["number"]; ok {
+		number, err = strconv.Atoi(value)
+		if err != nil {
+			response.Status = false
+			response.Errors["number"] = "Invalid period number:" + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	err = action(fiscalYear, userID, number)
+	if err != nil {
+		response.Status = false
+		response.Errors["status"] = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, fiscalYear.StoreID, models.AuditActionUpdate, "fiscal_year", fiscalYear.ID, fiscalYear.Name, &fiscalYearOld, fiscalYear)
+
+	response.Status = true
+	response.Result = fiscalYear
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func findFiscalYear(w http.ResponseWriter, r *http.Request, response *models.Response) (*models.FiscalYear, *models.Store) {
+	params := mux.Vars(r)
+	fiscalYearID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["fiscal_year_id"] = "Invalid Fiscal Year ID:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil, nil
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil, nil
+	}
+
+	fiscalYear, err := store.FindFiscalYearByID(&fiscalYearID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil, nil
+	}
+
+	return fiscalYear, store
+}
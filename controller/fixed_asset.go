@@ -0,0 +1,502 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"github.com/sirinibin/startpos/backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListFixedAsset : handler for GET /fixed-asset
+func ListFixedAsset(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	assets, criterias, err := store.SearchFixedAsset(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find fixed assets:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "fixed_asset")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of fixed assets:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(assets) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = assets
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateFixedAsset : handler for POST /fixed-asset
+func CreateFixedAsset(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var asset *models.FixedAsset
+	// Decode data
+	if !utils.Decode(w, r, &asset) {
+		return
+	}
+	asset.StoreID = &store.ID
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Validate data
+	if errs := asset.Validate(w, r, "create", nil); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	asset.Deleted = false
+	asset.CreatedBy = &userID
+	asset.UpdatedBy = &userID
+	asset.CreatedAt = &now
+	asset.UpdatedAt = &now
+	asset.MakeSchedule(store)
+
+	err = asset.MakeCode()
+	if err != nil {
+		response.Status = false
+		response.Errors["code"] = "Error making code: " + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = asset.Insert()
+	if err != nil {
+		response.Status = false
+		response.Errors["insert"] = "Unable to insert to db:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = asset.DoAccounting()
+	if err != nil {
+		response.Status = false
+		response.Errors["do_accounting"] = "Error do accounting: " + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// An asset acquired in the past catches up with the months already ended,
+	// a month in a closed period is left for later and kept in asset.LastError
+	asset.PostDepreciation(store, now)
+
+	go asset.SetPostBalances()
+
+	models.RecordAudit(r, tokenClaims, asset.StoreID, models.AuditActionCreate, "fixed_asset", asset.ID, asset.Code, nil, asset)
+
+	response.Status = true
+	response.Result = asset
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateFixedAsset : handler function for PUT /v1/fixed-asset/<id> call
+func UpdateFixedAsset(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	asset := findFixedAsset(w, r, &response)
+	if asset == nil {
+		return
+	}
+	assetOld := *asset
+	assetOld.Schedule = append([]models.DepreciationEntry{}, asset.Schedule...)
+
+	// Decode data
+	if !utils.Decode(w, r, &asset) {
+		return
+	}
+	asset.ID = assetOld.ID
+	asset.StoreID = assetOld.StoreID
+	asset.Code = assetOld.Code
+	asset.Schedule = assetOld.Schedule
+	asset.AccumulatedDepreciation = assetOld.AccumulatedDepreciation
+	asset.BookValue = assetOld.BookValue
+	asset.Status = assetOld.Status
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	asset.UpdatedBy = &userID
+	asset.UpdatedAt = &now
+
+	// Validate data
+	if errs := asset.Validate(w, r, "update", &assetOld); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := models.FindStoreByID(asset.StoreID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	financialsChanged := asset.FinancialsChanged(&assetOld)
+	if financialsChanged {
+		err = asset.UndoAccounting()
+		if err != nil {
+			response.Status = false
+			response.Errors["undo_accounting"] = "Error undo accounting: " + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		asset.MakeSchedule(store)
+	}
+
+	err = asset.Update()
+	if err != nil {
+		response.Status = false
+		response.Errors["update"] = "Unable to update:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if financialsChanged {
+		err = asset.DoAccounting()
+		if err != nil {
+			response.Status = false
+			response.Errors["do_accounting"] = "Error do accounting: " + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		asset.PostDepreciation(store, now)
+
+		go asset.SetPostBalances()
+	}
+
+	models.RecordAudit(r, tokenClaims, asset.StoreID, models.AuditActionUpdate, "fixed_asset", asset.ID, asset.Code, &assetOld, asset)
+
+	response.Status = true
+	response.Result = asset
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewFixedAsset : handler function for GET /v1/fixed-asset/<id> call
+func ViewFixedAsset(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	asset := findFixedAsset(w, r, &response)
+	if asset == nil {
+		return
+	}
+
+	response.Status = true
+	response.Result = asset
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteFixedAsset : handler function for DELETE /v1/fixed-asset/<id> call
+func DeleteFixedAsset(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	asset := findFixedAsset(w, r, &response)
+	if asset == nil {
+		return
+	}
+	assetOld := *asset
+	assetOld.Schedule = append([]models.DepreciationEntry{}, asset.Schedule...)
+
+	if message := models.ValidateAccountingLock(r, asset.StoreID, models.FixedAssetReferenceModel, asset.ID, asset.Code, asset.AcquisitionDate); message != "" {
+		response.Status = false
+		response.Errors["acquisition_date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = asset.DeleteFixedAsset(tokenClaims)
+	if err != nil {
+		response.Status = false
+		response.Errors["delete"] = "Unable to delete:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, asset.StoreID, models.AuditActionDelete, "fixed_asset", asset.ID, asset.Code, &assetOld, asset)
+
+	response.Status = true
+	response.Result = "Deleted successfully"
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// DisposeFixedAsset : handler function for POST /v1/fixed-asset/<id>/dispose call
+func DisposeFixedAsset(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	asset := findFixedAsset(w, r, &response)
+	if asset == nil {
+		return
+	}
+	assetOld := *asset
+	assetOld.Schedule = append([]models.DepreciationEntry{}, asset.Schedule...)
+
+	var disposal *models.FixedAssetDisposal
+	// Decode data
+	if !utils.Decode(w, r, &disposal) {
+		return
+	}
+
+	// Validate data
+	if errs := disposal.Validate(w, r, asset); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := models.FindStoreByID(asset.StoreID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = asset.Dispose(store, disposal)
+	if err != nil {
+		response.Status = false
+		response.Errors["dispose"] = "Unable to dispose:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, asset.StoreID, models.AuditActionUpdate, "fixed_asset", asset.ID, asset.Code, &assetOld, asset)
+
+	response.Status = true
+	response.Result = asset
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetFixedAssetRegister : handler function for GET /v1/report/fixed-asset-register?date_from=2026-01-01&date_to=2026-12-31 call
+func GetFixedAssetRegister(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	query := r.URL.Query()
+	dateFrom, err := models.ParseStatementDate(query.Get("date_from"))
+	if err != nil {
+		response.Errors["date_from"] = err.Error()
+	}
+	dateTo, err := models.ParseStatementDate(query.Get("date_to"))
+	if err != nil {
+		response.Errors["date_to"] = err.Error()
+	}
+	if len(response.Errors) > 0 {
+		response.Status = false
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// The dates are store dates, the register runs from the start of date_from to the end of date_to
+	location := models.StoreLocation(store.CountryCode)
+	var from *time.Time
+	if dateFrom != nil {
+		start := time.Date(dateFrom.Year(), dateFrom.Month(), dateFrom.Day(), 0, 0, 0, 0, location).UTC()
+		from = &start
+	}
+	to := time.Now().In(location)
+	if dateTo != nil {
+		to = time.Date(dateTo.Year(), dateTo.Month(), dateTo.Day(), 0, 0, 0, 0, location)
+	}
+	to = time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, location).Add(-time.Nanosecond).UTC()
+
+	register, err := store.MakeFixedAssetRegister(from, to)
+	if err != nil {
+		response.Status = false
+		response.Errors["report"] = "Unable to make fixed asset register:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = register
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func findFixedAsset(w http.ResponseWriter, r *http.Request, response *models.Response) *models.FixedAsset {
+	params := mux.Vars(r)
+	assetID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["fixed_asset_id"] = "Invalid Fixed Asset ID:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	asset, err := store.FindFixedAssetByID(&assetID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	return asset
+}
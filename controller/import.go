@@ -0,0 +1,534 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ── Types ──────────────────────────────────────────────────────────────────────
+
+// ImportPreview is the dry-run result: how the columns were mapped and which rows would fail.
+type ImportPreview struct {
+	Entity      string                  `json:"entity"`
+	Headers     []string                `json:"headers"`
+	Fields      []models.ImportField    `json:"fields"`
+	Columns     map[string]string       `json:"columns"` // field key => header
+	TotalRows   int                     `json:"total_rows"`
+	ValidRows   int                     `json:"valid_rows"`
+	InvalidRows int                     `json:"invalid_rows"`
+	Errors      []models.ImportRowError `json:"errors"`
+}
+
+type ImportProgressData struct {
+	JobID     string                  `json:"job_id"`
+	Entity    string                  `json:"entity"`
+	Total     int                     `json:"total"`
+	Processed int                     `json:"processed"`
+	Created   int                     `json:"created"`
+	Failed    int                     `json:"failed"`
+	Progress  float64                 `json:"progress"`
+	Done      bool                    `json:"done"`
+	Error     string                  `json:"error,omitempty"`
+	Errors    []models.ImportRowError `json:"errors"`
+}
+
+// ── Job registry ───────────────────────────────────────────────────────────────
+
+const importJobRetention = 1 * time.Hour
+
+type ImportJob struct {
+	JobID     string
+	StoreID   string
+	Entity    string
+	total     int
+	processed int
+	created   int
+	rowErrors []models.ImportRowError
+	done      bool
+	errMsg    string
+	mu        sync.Mutex
+}
+
+var (
+	importJobs   = make(map[string]*ImportJob)
+	importJobsMu sync.Mutex
+)
+
+func newImportJob(storeID, entity string, total int) *ImportJob {
+	jobID := fmt.Sprintf("%d", time.Now().UnixNano())
+	job := &ImportJob{
+		JobID:     jobID,
+		StoreID:   storeID,
+		Entity:    entity,
+		total:     total,
+		rowErrors: []models.ImportRowError{},
+	}
+	importJobsMu.Lock()
+	importJobs[jobID] = job
+	importJobsMu.Unlock()
+	return job
+}
+
+func getImportJob(jobID string) *ImportJob {
+	importJobsMu.Lock()
+	defer importJobsMu.Unlock()
+	return importJobs[jobID]
+}
+
+func deleteImportJob(jobID string) {
+	importJobsMu.Lock()
+	delete(importJobs, jobID)
+	importJobsMu.Unlock()
+}
+
+func (j *ImportJob) rowDone(rowNumber int, errs map[string]string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.processed++
+	if len(errs) > 0 {
+		j.rowErrors = append(j.rowErrors, models.ImportRowError{Row: rowNumber, Errors: errs})
+	} else {
+		j.created++
+	}
+}
+
+// finish marks the job done and drops it from the registry once the frontend had time to read the result.
+func (j *ImportJob) finish(errMsg string) {
+	j.mu.Lock()
+	j.done = true
+	j.errMsg = errMsg
+	j.mu.Unlock()
+	time.AfterFunc(importJobRetention, func() { deleteImportJob(j.JobID) })
+}
+
+func (j *ImportJob) snapshot() ImportProgressData {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	p := ImportProgressData{
+		JobID:     j.JobID,
+		Entity:    j.Entity,
+		Total:     j.total,
+		Processed: j.processed,
+		Created:   j.created,
+		Failed:    len(j.rowErrors),
+		Progress:  100,
+		Done:      j.done,
+		Error:     j.errMsg,
+		Errors:    append([]models.ImportRowError{}, j.rowErrors...),
+	}
+	if j.total > 0 {
+		p.Progress = float64(j.processed) / float64(j.total) * 100.0
+	}
+	return p
+}
+
+// ── POST /v1/{product|customer|vendor}/import ─────────────────────────────────
+// Multipart form: file (.xlsx or .csv, first row is the header), mapping (JSON
+// object of field key => column header) and dry_run. Unless dry_run is "false"
+// every row is validated and the per-row errors are returned; otherwise valid
+// rows are inserted by a background job whose job_id is returned.
+
+// ImportProduct : handler for POST /v1/product/import
+func ImportProduct(w http.ResponseWriter, r *http.Request) {
+	handleImport(w, r, models.ImportEntityProduct)
+}
+
+// ImportCustomer : handler for POST /v1/customer/import
+func ImportCustomer(w http.ResponseWriter, r *http.Request) {
+	handleImport(w, r, models.ImportEntityCustomer)
+}
+
+// ImportVendor : handler for POST /v1/vendor/import
+func ImportVendor(w http.ResponseWriter, r *http.Request) {
+	handleImport(w, r, models.ImportEntityVendor)
+}
+
+func handleImport(w http.ResponseWriter, r *http.Request, entity string) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = r.ParseMultipartForm(32 << 20)
+	if err != nil {
+		response.Status = false
+		response.Errors["file"] = "Unable to parse form: " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	file, handler, err := r.FormFile("file")
+	if err != nil {
+		response.Status = false
+		response.Errors["file"] = "File is required"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	defer file.Close()
+
+	sheet, err := models.ParseImportFile(handler.Filename, file)
+	if err != nil {
+		response.Status = false
+		response.Errors["file"] = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	mapping, err := models.ParseImportMapping(r.FormValue("mapping"))
+	if err != nil {
+		response.Status = false
+		response.Errors["mapping"] = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	columns, errs := models.ResolveImportColumns(entity, sheet.Headers, mapping)
+	if len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	dryRun := r.FormValue("dry_run")
+	if dryRun != "false" && dryRun != "0" {
+		preview := ImportPreview{
+			Entity:  entity,
+			Headers: sheet.Headers,
+			Fields:  models.ImportFields[entity],
+			Columns: map[string]string{},
+			Errors:  []models.ImportRowError{},
+		}
+		for key, index := range columns {
+			preview.Columns[key] = sheet.Headers[index]
+		}
+
+		seen := map[string]int{}
+		for i, row := range sheet.Rows {
+			if models.IsImportRowEmpty(row) {
+				continue
+			}
+			preview.TotalRows++
+			rowErrs := importRow(r.Context(), models.NewAuditRequest(r), tokenClaims, store, entity, row, columns, userID, false)
+			if rowErrs == nil {
+				rowErrs = map[string]string{}
+			}
+			checkImportDuplicates(entity, row, columns, seen, i+2, rowErrs)
+			if len(rowErrs) > 0 {
+				preview.InvalidRows++
+				preview.Errors = append(preview.Errors, models.ImportRowError{Row: i + 2, Errors: rowErrs})
+			} else {
+				preview.ValidRows++
+			}
+		}
+
+		response.Status = true
+		response.Result = preview
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	total := 0
+	for _, row := range sheet.Rows {
+		if !models.IsImportRowEmpty(row) {
+			total++
+		}
+	}
+
+	job := newImportJob(store.ID.Hex(), entity, total)
+	// the job outlives the request: it keeps what it needs of it, not the request itself
+	go runImportJob(context.WithoutCancel(r.Context()), job, models.NewAuditRequest(r), tokenClaims, store, sheet, columns, userID)
+
+	response.Status = true
+	response.Result = map[string]string{"job_id": job.JobID}
+	json.NewEncoder(w).Encode(response)
+}
+
+// ── GET /v1/{product|customer|vendor}/import/progress?job_id=xxx ──────────────
+
+// GetImportProgress : handler for GET /v1/{product|customer|vendor}/import/progress
+func GetImportProgress(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	jobID := r.URL.Query().Get("job_id")
+	if jobID == "" {
+		response.Status = false
+		response.Errors["job_id"] = "job_id required"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	job := getImportJob(jobID)
+	if job == nil || job.StoreID != store.ID.Hex() {
+		response.Status = false
+		response.Errors["job_id"] = "Job not found or expired"
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = job.snapshot()
+	json.NewEncoder(w).Encode(response)
+}
+
+// ── Core import logic ──────────────────────────────────────────────────────────
+
+func runImportJob(
+	ctx context.Context,
+	job *ImportJob,
+	audit models.AuditRequest,
+	tokenClaims models.TokenClaims,
+	store *models.Store,
+	sheet *models.ImportSheet,
+	columns map[string]int,
+	userID primitive.ObjectID,
+) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("[import] %s job %s panicked: %v", job.Entity, job.JobID, rec)
+			job.finish(fmt.Sprintf("import stopped unexpectedly: %v", rec))
+		}
+	}()
+
+	seen := map[string]int{}
+	for i, row := range sheet.Rows {
+		if models.IsImportRowEmpty(row) {
+			continue
+		}
+		errs := map[string]string{}
+		checkImportDuplicates(job.Entity, row, columns, seen, i+2, errs)
+		if len(errs) == 0 {
+			errs = importRow(ctx, audit, tokenClaims, store, job.Entity, row, columns, userID, true)
+		}
+		job.rowDone(i+2, errs)
+	}
+
+	job.finish("")
+}
+
+// checkImportDuplicates flags values of unique fields already used by an earlier row of the same file.
+func checkImportDuplicates(entity string, row []string, columns map[string]int, seen map[string]int, rowNumber int, errs map[string]string) {
+	for _, key := range models.ImportUniqueFields[entity] {
+		index, ok := columns[key]
+		if !ok {
+			continue
+		}
+		value := strings.ToLower(models.ImportCell(row, index))
+		if value == "" {
+			continue
+		}
+		if firstRow, exists := seen[key+":"+value]; exists {
+			errs[key] = fmt.Sprintf("Duplicate of row %d", firstRow)
+			continue
+		}
+		seen[key+":"+value] = rowNumber
+	}
+}
+
+// importRow validates one row and, when commit is set, saves it the same way the create handlers do.
+func importRow(
+	ctx context.Context,
+	audit models.AuditRequest,
+	tokenClaims models.TokenClaims,
+	store *models.Store,
+	entity string,
+	row []string,
+	columns map[string]int,
+	userID primitive.ObjectID,
+	commit bool,
+) map[string]string {
+	// Validate writes status codes for the HTTP handlers; rows are validated against a throwaway writer and request.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequestWithContext(ctx, audit.Method, audit.Path, nil)
+	now := time.Now()
+
+	switch entity {
+	case models.ImportEntityProduct:
+		product, errs := store.NewImportProduct(row, columns)
+		if len(errs) > 0 {
+			return errs
+		}
+		product.CreatedBy = &userID
+		product.UpdatedBy = &userID
+		product.CreatedAt = &now
+		product.UpdatedAt = &now
+		product.FindSetTotal()
+
+		if errs := product.Validate(w, r, "create"); len(errs) > 0 || !commit {
+			return errs
+		}
+		return saveImportedProduct(audit, tokenClaims, store, product)
+	case models.ImportEntityCustomer:
+		customer, errs := store.NewImportCustomer(row, columns)
+		if len(errs) > 0 {
+			return errs
+		}
+		if errs := customer.Validate(w, r, "create"); len(errs) > 0 || !commit {
+			return errs
+		}
+		customer.CreatedBy = &userID
+		customer.UpdatedBy = &userID
+		customer.CreatedAt = &now
+		customer.UpdatedAt = &now
+		return saveImportedCustomer(audit, tokenClaims, customer)
+	case models.ImportEntityVendor:
+		vendor, errs := store.NewImportVendor(row, columns)
+		if len(errs) > 0 {
+			return errs
+		}
+		vendor.CreatedBy = &userID
+		vendor.UpdatedBy = &userID
+		vendor.CreatedAt = &now
+		vendor.UpdatedAt = &now
+		if errs := vendor.Validate(w, r, "create"); len(errs) > 0 || !commit {
+			return errs
+		}
+		return saveImportedVendor(audit, tokenClaims, vendor)
+	}
+
+	return map[string]string{"entity": "Unsupported import entity: " + entity}
+}
+
+func saveImportedProduct(audit models.AuditRequest, tokenClaims models.TokenClaims, store *models.Store, product *models.Product) map[string]string {
+	errs := map[string]string{}
+
+	if err := product.SetPartNumber(); err != nil {
+		errs["setting_part_number"] = "error setting part number:" + err.Error()
+		return errs
+	}
+	if err := product.SetBarcode(); err != nil {
+		errs["setting_bar_code"] = "error setting barcode:" + err.Error()
+		return errs
+	}
+	if err := product.UpdateForeignLabelFields(); err != nil {
+		errs["update_foreign_label_fields"] = "error updating foreign label fields:" + err.Error()
+		return errs
+	}
+
+	product.InitStoreUnitPrice()
+	product.CalculateUnitProfit()
+	product.GeneratePrefixes()
+	product.SetStock()
+	product.SetAdditionalkeywords()
+
+	if err := product.Insert(); err != nil {
+		errs["insert"] = "Unable to insert to db:" + err.Error()
+		return errs
+	}
+
+	audit.Record(tokenClaims, product.StoreID, models.AuditActionCreate, "product", product.ID, product.PartNumber, nil, product)
+
+	product.CreateStockAdjustmentHistory()
+	go store.EmitWebhookEvent(models.WebhookEventProductCreated, product)
+
+	return nil
+}
+
+func saveImportedCustomer(audit models.AuditRequest, tokenClaims models.TokenClaims, customer *models.Customer) map[string]string {
+	errs := map[string]string{}
+
+	customer.UpdateForeignLabelFields()
+	if govalidator.IsNull(strings.TrimSpace(customer.Code)) {
+		if err := customer.MakeCode(); err != nil {
+			errs["code"] = "Error making code: " + err.Error()
+			return errs
+		}
+	}
+	customer.GenerateSearchWords()
+	customer.SetAdditionalkeywords()
+	customer.SetSearchLabel()
+
+	if err := customer.Insert(); err != nil {
+		errs["insert"] = "Unable to insert to db:" + err.Error()
+		return errs
+	}
+
+	audit.Record(tokenClaims, customer.StoreID, models.AuditActionCreate, "customer", customer.ID, customer.Code, nil, customer)
+
+	return nil
+}
+
+func saveImportedVendor(audit models.AuditRequest, tokenClaims models.TokenClaims, vendor *models.Vendor) map[string]string {
+	errs := map[string]string{}
+
+	if govalidator.IsNull(strings.TrimSpace(vendor.Code)) {
+		if err := vendor.MakeCode(); err != nil {
+			errs["code"] = "Error making code: " + err.Error()
+			return errs
+		}
+	}
+	vendor.GenerateSearchWords()
+	vendor.SetSearchLabel()
+	vendor.SetAdditionalkeywords()
+
+	if err := vendor.Insert(); err != nil {
+		errs["insert"] = "Unable to insert to db:" + err.Error()
+		return errs
+	}
+
+	audit.Record(tokenClaims, vendor.StoreID, models.AuditActionCreate, "vendor", vendor.ID, vendor.Code, nil, vendor)
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -17,6 +18,8 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/sirinibin/startpos/backend/env"
 	"github.com/sirinibin/startpos/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // printJobStore temporarily holds invoice data POSTed by the React frontend.
@@ -79,12 +82,50 @@ func InvoicePrintData(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// pdfaDocumentTypes are the ZATCA document types of the print models with a
+// PDF/A-3 output.
+var pdfaDocumentTypes = map[string]string{
+	"sales":            models.ZatcaDocumentOrder,
+	"order":            models.ZatcaDocumentOrder,
+	"sales_return":     models.ZatcaDocumentSalesReturn,
+	"customer_deposit": models.ZatcaDocumentCustomerDeposit,
+	"debit_note":       models.ZatcaDocumentDebitNote,
+}
+
+// findZatcaPDFA finds the signed XML to attach to the PDF/A-3 print of the model.
+func findZatcaPDFA(modelName string, model json.RawMessage) (*models.ZatcaPDFA, error) {
+	documentType, ok := pdfaDocumentTypes[modelName]
+	if !ok {
+		return nil, errors.New("PDF/A-3 is only available for sales, sales returns, customer deposits and debit notes")
+	}
+
+	var document struct {
+		ID      primitive.ObjectID  `json:"id"`
+		StoreID *primitive.ObjectID `json:"store_id"`
+	}
+	if err := json.Unmarshal(model, &document); err != nil {
+		return nil, errors.New("invalid model: " + err.Error())
+	}
+	if document.ID.IsZero() || document.StoreID == nil || document.StoreID.IsZero() {
+		return nil, errors.New("model id and store_id are required")
+	}
+
+	store, err := models.FindStoreByID(document.StoreID, bson.M{})
+	if err != nil {
+		return nil, errors.New("error finding store: " + err.Error())
+	}
+	return store.FindZatcaPDFA(documentType, &document.ID)
+}
+
 // InvoicePDF accepts the fully-loaded invoice data from React, stores it in
 // memory under a random key, then uses headless Chrome to render the React
 // /invoice-print page (which reads the data via that key) and captures an A4 PDF.
 //
 // POST /v1/invoice/pdf
-// Body: { "model": {...}, "modelName": "sales", "fontSizes": {...} }
+// Body: { "model": {...}, "modelName": "sales", "fontSizes": {...}, "format": "pdfa3" }
+//
+// With "format": "pdfa3" the PDF is made PDF/A-3b with the signed ZATCA XML of
+// the document attached, for sales, sales returns and customer deposits.
 func InvoicePDF(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	var response models.Response
@@ -104,6 +145,7 @@ func InvoicePDF(w http.ResponseWriter, r *http.Request) {
 		ModelName string          `json:"modelName"`
 		FontSizes json.RawMessage `json:"fontSizes"`
 		Filename  string          `json:"filename"`
+		Format    string          `json:"format"` // "" | pdfa3
 	}
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
 		response.Status = false
@@ -120,6 +162,24 @@ func InvoicePDF(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var zatcaPDFA *models.ZatcaPDFA
+	if reqBody.Format == "pdfa3" {
+		zatcaPDFA, err = findZatcaPDFA(reqBody.ModelName, reqBody.Model)
+		if err != nil {
+			response.Status = false
+			response.Errors["format"] = "PDF/A-3 is not available: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	} else if reqBody.Format != "" {
+		response.Status = false
+		response.Errors["format"] = "format must be pdfa3 or empty"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	chromeBin := chromePath()
 	if chromeBin == "" {
 		response.Status = false
@@ -199,6 +259,17 @@ func InvoicePDF(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if zatcaPDFA != nil {
+		pdfBuf, err = zatcaPDFA.Convert(pdfBuf)
+		if err != nil {
+			response.Status = false
+			response.Errors["pdf"] = "PDF/A-3 conversion failed: " + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
 	// Determine save path: ~/Downloads/{filename}.pdf (local desktop app)
 	savedPath := ""
 	saveFilename := reqBody.Filename
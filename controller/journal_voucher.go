@@ -0,0 +1,473 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"github.com/sirinibin/startpos/backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListJournalVoucher : handler for GET /journal-voucher
+func ListJournalVoucher(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	vouchers, criterias, err := store.SearchJournalVoucher(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find journal vouchers:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "journal_voucher")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of journal vouchers:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(vouchers) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = vouchers
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateJournalVoucher : handler for POST /journal-voucher
+// The voucher is saved as a draft; it reaches the ledger only once approved.
+func CreateJournalVoucher(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var voucher *models.JournalVoucher
+	// Decode data
+	if !utils.Decode(w, r, &voucher) {
+		return
+	}
+	voucher.StoreID = &store.ID
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Validate data
+	if errs := voucher.Validate(w, r, "create"); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	voucher.Status = models.JournalVoucherStatusDraft
+	voucher.Attachments = []string{}
+	voucher.ApprovedBy = nil
+	voucher.ApprovedAt = nil
+	voucher.ReversalOfID = nil
+	voucher.ReversedByID = nil
+	voucher.CreatedBy = &userID
+	voucher.UpdatedBy = &userID
+	voucher.CreatedAt = &now
+	voucher.UpdatedAt = &now
+
+	err = voucher.MakeCode()
+	if err != nil {
+		response.Status = false
+		response.Errors["code"] = "Error making code: " + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = voucher.Insert()
+	if err != nil {
+		response.Status = false
+		response.Errors["insert"] = "Unable to insert to db:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, voucher.StoreID, models.AuditActionCreate, "journal_voucher", voucher.ID, voucher.Code, nil, voucher)
+
+	response.Status = true
+	response.Result = voucher
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateJournalVoucher : handler function for PUT /v1/journal-voucher/<id> call
+func UpdateJournalVoucher(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	voucher, store := findJournalVoucher(w, r, &response)
+	if voucher == nil {
+		return
+	}
+	voucherOld := *voucher
+	voucherOld.Lines = append([]models.JournalVoucherLine{}, voucher.Lines...)
+	voucherOld.Attachments = append([]string{}, voucher.Attachments...)
+
+	// Decode data
+	if !utils.Decode(w, r, &voucher) {
+		return
+	}
+	// Workflow fields only change through approve/reverse/delete
+	voucher.ID = voucherOld.ID
+	voucher.Code = voucherOld.Code
+	voucher.Status = voucherOld.Status
+	voucher.StoreID = &store.ID
+	voucher.ApprovedBy = voucherOld.ApprovedBy
+	voucher.ApprovedAt = voucherOld.ApprovedAt
+	voucher.ReversalOfID = voucherOld.ReversalOfID
+	voucher.ReversedByID = voucherOld.ReversedByID
+	voucher.Deleted = voucherOld.Deleted
+	voucher.CreatedBy = voucherOld.CreatedBy
+	voucher.CreatedAt = voucherOld.CreatedAt
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Validate data
+	if errs := voucher.Validate(w, r, "update"); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	voucher.UpdatedBy = &userID
+	voucher.UpdatedAt = &now
+
+	err = voucher.Update()
+	if err != nil {
+		response.Status = false
+		response.Errors["update"] = "Unable to update:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, voucher.StoreID, models.AuditActionUpdate, "journal_voucher", voucher.ID, voucher.Code, &voucherOld, voucher)
+
+	response.Status = true
+	response.Result = voucher
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewJournalVoucher : handler function for GET /v1/journal-voucher/<id> call
+func ViewJournalVoucher(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	voucher, _ := findJournalVoucher(w, r, &response)
+	if voucher == nil {
+		return
+	}
+
+	response.Status = true
+	response.Result = voucher
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteJournalVoucher : handler function for DELETE /v1/journal-voucher/<id> call
+// Only drafts can be deleted; approved vouchers have to be reversed.
+func DeleteJournalVoucher(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	voucher, _ := findJournalVoucher(w, r, &response)
+	if voucher == nil {
+		return
+	}
+	voucherOld := *voucher
+
+	err = voucher.DeleteJournalVoucher(tokenClaims)
+	if err != nil {
+		response.Status = false
+		response.Errors["delete"] = "Unable to delete:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, voucher.StoreID, models.AuditActionDelete, "journal_voucher", voucher.ID, voucher.Code, &voucherOld, voucher)
+
+	response.Status = true
+	response.Result = "Deleted successfully"
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ApproveJournalVoucher : handler function for POST /v1/journal-voucher/<id>/approve call
+// Posts the draft to the ledger.
+func ApproveJournalVoucher(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	voucher, _ := findJournalVoucher(w, r, &response)
+	if voucher == nil {
+		return
+	}
+	voucherOld := *voucher
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if voucher.Status != models.JournalVoucherStatusDraft {
+		response.Status = false
+		response.Errors["status"] = "Voucher is already " + voucher.Status
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if message := models.ValidateAccountingLock(r, voucher.StoreID, "journal_voucher", voucher.ID, voucher.Code, voucher.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = voucher.Approve(userID)
+	if err != nil {
+		response.Status = false
+		response.Errors["approve"] = "Unable to approve:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, voucher.StoreID, models.AuditActionUpdate, "journal_voucher", voucher.ID, voucher.Code, &voucherOld, voucher)
+
+	go voucher.SetPostBalances()
+
+	response.Status = true
+	response.Result = voucher
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReverseJournalVoucher : handler function for POST /v1/journal-voucher/<id>/reverse call
+// Body (optional): { "date_str": "2025-01-31T00:00:00Z" }, defaults to now.
+func ReverseJournalVoucher(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	voucher, _ := findJournalVoucher(w, r, &response)
+	if voucher == nil {
+		return
+	}
+	voucherOld := *voucher
+
+	var input struct {
+		DateStr string `json:"date_str"`
+	}
+	if r.ContentLength != 0 && !utils.Decode(w, r, &input) {
+		return
+	}
+
+	var date *time.Time
+	if !govalidator.IsNull(input.DateStr) {
+		parsed, err := time.Parse("2006-01-02T15:04:05Z07:00", input.DateStr)
+		if err != nil {
+			response.Status = false
+			response.Errors["date_str"] = "Invalid date format"
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		date = &parsed
+	}
+
+	// Only the reversal date matters, the original may well sit in a closed period
+	if date != nil {
+		if message := models.ValidateAccountingLock(r, voucher.StoreID, "journal_voucher", primitive.NilObjectID, voucher.Code, date); message != "" {
+			response.Status = false
+			response.Errors["date_str"] = message
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	reversal, err := voucher.Reverse(userID, date)
+	if err != nil {
+		response.Status = false
+		response.Errors["reverse"] = "Unable to reverse:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, voucher.StoreID, models.AuditActionUpdate, "journal_voucher", voucher.ID, voucher.Code, &voucherOld, voucher)
+	models.RecordAudit(r, tokenClaims, reversal.StoreID, models.AuditActionCreate, "journal_voucher", reversal.ID, reversal.Code, nil, reversal)
+
+	go reversal.SetPostBalances()
+
+	response.Status = true
+	response.Result = reversal
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func findJournalVoucher(w http.ResponseWriter, r *http.Request, response *models.Response) (*models.JournalVoucher, *models.Store) {
+	params := mux.Vars(r)
+	voucherID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["journal_voucher_id"] = "Invalid Journal Voucher ID:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil, nil
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil, nil
+	}
+
+	voucher, err := store.FindJournalVoucherByID(&voucherID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil, nil
+	}
+
+	return voucher, store
+}
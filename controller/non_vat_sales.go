@@ -173,6 +173,8 @@ func CreateNonVATSales(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, item.StoreID, models.AuditActionCreate, "non_vat_sales", item.ID, item.Code, nil, item)
+
 	queue.Pop()
 	CleanupQueueIfEmpty(store.ID.Hex(), "non_vat_sales")
 
@@ -347,6 +349,8 @@ func UpdateNonVATSales(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, item.StoreID, models.AuditActionUpdate, "non_vat_sales", item.ID, item.Code, itemOld, item)
+
 	go func() {
 		_ = item.UndoAccounting()
 		_ = item.ClearProductsNonVATSalesHistory()
@@ -401,6 +405,15 @@ func DeleteNonVATSales(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, item.StoreID, "non_vat_sales", item.ID, item.Code, item.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	itemOld := *item
 	if err = item.Delete(tokenClaims); err != nil {
 		response.Status = false
 		response.Errors["delete"] = "Unable to delete:" + err.Error()
@@ -409,6 +422,8 @@ func DeleteNonVATSales(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, item.StoreID, models.AuditActionDelete, "non_vat_sales", item.ID, item.Code, &itemOld, item)
+
 	go func() {
 		_ = item.UndoAccounting()
 		_ = item.ClearProductsNonVATSalesHistory()
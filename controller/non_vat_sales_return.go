@@ -184,6 +184,8 @@ func CreateNonVATSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, item.StoreID, models.AuditActionCreate, "non_vat_sales_return", item.ID, item.Code, nil, item)
+
 	queue.Pop()
 	CleanupQueueIfEmpty(store.ID.Hex(), "non_vat_sales_return")
 
@@ -357,6 +359,8 @@ func UpdateNonVATSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, item.StoreID, models.AuditActionUpdate, "non_vat_sales_return", item.ID, item.Code, itemOld, item)
+
 	go func() {
 		_ = item.UndoAccounting()
 		_ = item.ClearProductsNonVATSalesReturnHistory()
@@ -411,6 +415,15 @@ func DeleteNonVATSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, item.StoreID, "non_vat_sales_return", item.ID, item.Code, item.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	itemOld := *item
 	if err = item.Delete(tokenClaims); err != nil {
 		response.Status = false
 		response.Errors["delete"] = "Unable to delete:" + err.Error()
@@ -419,6 +432,8 @@ func DeleteNonVATSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, item.StoreID, models.AuditActionDelete, "non_vat_sales_return", item.ID, item.Code, &itemOld, item)
+
 	go func() {
 		_ = item.UndoAccounting()
 		_ = item.ClearProductsNonVATSalesReturnHistory()
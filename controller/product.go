@@ -280,6 +280,8 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, product.StoreID, models.AuditActionCreate, "product", product.ID, product.PartNumber, nil, product)
+
 	//Link products
 	for _, linkedProductID := range product.LinkedProductIDs {
 		linkedProduct, err := store.FindProductByID(linkedProductID, bson.M{})
@@ -326,6 +328,7 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 	go func() {
 		product.CreateStockAdjustmentHistory()
 	}()
+	go store.EmitWebhookEvent(models.WebhookEventProductCreated, product)
 
 	response.Result = product
 
@@ -433,6 +436,9 @@ func UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(response)
 		return
 	}
+
+	models.RecordAudit(r, tokenClaims, product.StoreID, models.AuditActionUpdate, "product", product.ID, product.PartNumber, productOld, product)
+
 	product.ReflectValidPurchaseUnitPrice()
 
 	err = product.AttributesValueChangeEvent(productOld)
@@ -490,6 +496,7 @@ func UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(response)
 		return
 	}
+	go store.EmitWebhookEvent(models.WebhookEventProductUpdated, product)
 
 	response.Status = true
 	response.Result = product
@@ -668,6 +675,7 @@ func DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	productOld := *product
 	err = product.DeleteProduct(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -676,6 +684,8 @@ func DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, product.StoreID, models.AuditActionDelete, "product", product.ID, product.PartNumber, &productOld, product)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 
@@ -779,6 +789,7 @@ func RestoreProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	productOld := *product
 	err = product.RestoreProduct(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -787,6 +798,8 @@ func RestoreProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, product.StoreID, models.AuditActionRestore, "product", product.ID, product.PartNumber, &productOld, product)
+
 	response.Status = true
 	response.Result = "Restored successfully"
 
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListProductSerials : handler for GET /v1/product/{id}/serials
+// Query params: search[store_id] (required), status (""|"in_stock"|"sold")
+func ListProductSerials(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	params := mux.Vars(r)
+	productID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["product_id"] = "Invalid product id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	serials, err := store.GetProductSerials(&productID, r.URL.Query().Get("status"))
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find serials:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = serials
+	if len(serials) == 0 {
+		response.Result = []models.ProductSerial{}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewSerialTrail : handler for GET /v1/product/serial/{serial}
+// Returns the purchase → sale → return trail of a serial number.
+// Query params: search[store_id] (required), product_id (optional)
+func ViewSerialTrail(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var productID *primitive.ObjectID
+	if v := r.URL.Query().Get("product_id"); v != "" {
+		id, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			response.Status = false
+			response.Errors["product_id"] = "Invalid product id:" + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		productID = &id
+	}
+
+	params := mux.Vars(r)
+	history, err := store.GetSerialHistory(params["serial"], productID)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find serial:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(history) == 0 {
+		response.Status = false
+		response.Errors["serial"] = "Serial number not found"
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = map[string]interface{}{
+		"serial_number": history[0].SerialNumber,
+		"current":       models.SerialStatusFromHistory(history),
+		"trail":         history,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
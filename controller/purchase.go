@@ -239,10 +239,29 @@ func CreatePurchase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchase.StoreID, models.AuditActionCreate, "purchase", purchase.ID, purchase.Code, nil, purchase)
+
 	queue.Pop()
 	CleanupQueueIfEmpty(store.ID.Hex(), "purchase")
 
 	purchase.CreateProductsPurchaseHistory()
+	err = purchase.SetProductsLots()
+	if err != nil {
+		response.Status = false
+		response.Errors["product_lots"] = "Error setting product lots: " + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = purchase.SetProductsSerials()
+	if err != nil {
+		response.Status = false
+		response.Errors["serial_numbers"] = "Error setting serial numbers: " + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
 
 	err = purchase.AddPayments()
 	if err != nil {
@@ -254,6 +273,7 @@ func CreatePurchase(w http.ResponseWriter, r *http.Request) {
 
 	purchase.SetPaymentStatus()
 	purchase.Update()
+	go store.EmitWebhookEvent(models.WebhookEventPurchaseCreated, purchase)
 
 	err = purchase.SetProductsStock()
 	if err != nil {
@@ -438,8 +458,27 @@ func UpdatePurchase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchase.StoreID, models.AuditActionUpdate, "purchase", purchase.ID, purchase.Code, purchaseOld, purchase)
+
 	purchase.ClearProductsPurchaseHistory()
 	purchase.CreateProductsPurchaseHistory()
+	err = purchase.SetProductsLots()
+	if err != nil {
+		response.Status = false
+		response.Errors["product_lots"] = "Error setting product lots: " + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = purchase.SetProductsSerials()
+	if err != nil {
+		response.Status = false
+		response.Errors["serial_numbers"] = "Error setting serial numbers: " + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
 
 	err = purchase.UpdatePayments()
 	if err != nil {
@@ -451,6 +490,7 @@ func UpdatePurchase(w http.ResponseWriter, r *http.Request) {
 
 	purchase.SetPaymentStatus()
 	purchase.Update()
+	go store.EmitWebhookEvent(models.WebhookEventPurchaseUpdated, purchase)
 
 	err = purchaseOld.SetProductsStock()
 	if err != nil {
@@ -690,6 +730,15 @@ func DeletePurchase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, purchase.StoreID, "purchase", purchase.ID, purchase.Code, purchase.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	purchaseOld := *purchase
 	err = purchase.DeletePurchase(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -699,6 +748,8 @@ func DeletePurchase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchase.StoreID, models.AuditActionDelete, "purchase", purchase.ID, purchase.Code, &purchaseOld, purchase)
+
 	if purchase.StoreID != nil {
 		go models.MarkDashboardDirty(*purchase.StoreID, purchase.Date)
 	}
@@ -128,6 +128,8 @@ func CreatePurchaseCashDiscount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchasecashdiscount.StoreID, models.AuditActionCreate, "purchase_cash_discount", purchasecashdiscount.ID, purchasecashdiscount.PurchaseCode, nil, purchasecashdiscount)
+
 	store, err := ParseStore(r)
 	if err != nil {
 		response.Status = false
@@ -199,6 +201,7 @@ func UpdatePurchaseCashDiscount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	purchasecashdiscountOld := *purchasecashdiscount
 	// Decode data
 	if !utils.Decode(w, r, &purchasecashdiscount) {
 		return
@@ -235,6 +238,8 @@ func UpdatePurchaseCashDiscount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchasecashdiscount.StoreID, models.AuditActionUpdate, "purchase_cash_discount", purchasecashdiscount.ID, purchasecashdiscount.PurchaseCode, &purchasecashdiscountOld, purchasecashdiscount)
+
 	purchasecashdiscount, err = store.FindPurchaseCashDiscountByID(&purchasecashdiscount.ID, bson.M{})
 	if err != nil {
 		response.Status = false
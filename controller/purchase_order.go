@@ -176,6 +176,8 @@ func CreatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, po.StoreID, models.AuditActionCreate, "purchase_order", po.ID, po.Code, nil, po)
+
 	// If this PO was created from a Purchase Request, link the PR back to the PO
 	if po.PurchaseRequestID != nil && !po.PurchaseRequestID.IsZero() {
 		pr, err := store.FindPurchaseRequestByID(po.PurchaseRequestID, nil)
@@ -462,6 +464,8 @@ func UpdatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, poUpdate.StoreID, models.AuditActionUpdate, "purchase_order", poUpdate.ID, poUpdate.Code, po, poUpdate)
+
 	store.NotifyUsers("purchase_order_updated")
 
 	response.Status = true
@@ -475,7 +479,7 @@ func DeletePurchaseOrder(w http.ResponseWriter, r *http.Request) {
 	var response models.Response
 	response.Errors = make(map[string]string)
 
-	_, err := models.AuthenticateByAccessToken(r)
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
 	if err != nil {
 		response.Status = false
 		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
@@ -517,6 +521,8 @@ func DeletePurchaseOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, po.StoreID, models.AuditActionDelete, "purchase_order", po.ID, po.Code, po, nil)
+
 	store.NotifyUsers("purchase_order_updated")
 
 	response.Status = true
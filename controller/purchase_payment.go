@@ -129,6 +129,8 @@ func CreatePurchasePayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchasepayment.StoreID, models.AuditActionCreate, "purchase_payment", purchasepayment.ID, purchasepayment.PurchaseCode, nil, purchasepayment)
+
 	store, err := ParseStore(r)
 	if err != nil {
 		response.Status = false
@@ -210,6 +212,8 @@ func UpdatePurchasePayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	purchasepaymentOld := *purchasepayment
+
 	// Decode data
 	if !utils.Decode(w, r, &purchasepayment) {
 		return
@@ -247,6 +251,8 @@ func UpdatePurchasePayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchasepayment.StoreID, models.AuditActionUpdate, "purchase_payment", purchasepayment.ID, purchasepayment.PurchaseCode, &purchasepaymentOld, purchasepayment)
+
 	//Updating purchase.payments
 	purchase, _ := store.FindPurchaseByID(purchasepayment.PurchaseID, map[string]interface{}{})
 	purchase.SetPaymentStatus()
@@ -388,6 +394,15 @@ func DeletePurchasePayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, purchasePayment.StoreID, "purchase_payment", purchasePayment.ID, purchasePayment.PurchaseCode, purchasePayment.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	purchasePaymentOld := *purchasePayment
 	purchasePayment.Deleted = true
 	purchasePayment.DeletedBy = &userID
 	now := time.Now()
@@ -401,6 +416,8 @@ func DeletePurchasePayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchasePayment.StoreID, models.AuditActionDelete, "purchase_payment", purchasePayment.ID, purchasePayment.PurchaseCode, &purchasePaymentOld, purchasePayment)
+
 	//Updating purchase.payments
 	purchase, _ := store.FindPurchaseByID(purchasePayment.PurchaseID, map[string]interface{}{})
 	purchase.SetPaymentStatus()
@@ -136,6 +136,8 @@ func CreatePurchaseRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, pr.StoreID, models.AuditActionCreate, "purchase_request", pr.ID, pr.Code, nil, pr)
+
 	// Notify assignee
 	if pr.AssignedTo != nil {
 		models.NotifyUserByID(pr.AssignedTo, "purchase_request_received", map[string]interface{}{
@@ -291,6 +293,8 @@ func UpdatePurchaseRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, prUpdate.StoreID, models.AuditActionUpdate, "purchase_request", prUpdate.ID, prUpdate.Code, pr, prUpdate)
+
 	store.NotifyUsers("purchase_request_updated")
 
 	response.Status = true
@@ -304,7 +308,7 @@ func DeletePurchaseRequest(w http.ResponseWriter, r *http.Request) {
 	var response models.Response
 	response.Errors = make(map[string]string)
 
-	_, err := models.AuthenticateByAccessToken(r)
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
 	if err != nil {
 		response.Status = false
 		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
@@ -346,6 +350,8 @@ func DeletePurchaseRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, pr.StoreID, models.AuditActionDelete, "purchase_request", pr.ID, pr.Code, pr, nil)
+
 	store.NotifyUsers("purchase_request_updated")
 
 	response.Status = true
@@ -407,6 +413,7 @@ func AcceptPurchaseRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	prOld := *pr
 	now := time.Now()
 	if body.Partial {
 		pr.Status = "partially_accepted"
@@ -424,6 +431,8 @@ func AcceptPurchaseRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, pr.StoreID, models.AuditActionUpdate, "purchase_request", pr.ID, pr.Code, &prOld, pr)
+
 	// Notify creator
 	if pr.CreatedBy != nil {
 		models.NotifyUserByID(pr.CreatedBy, "purchase_request_status_changed", map[string]interface{}{
@@ -488,6 +497,7 @@ func RejectPurchaseRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	prOld := *pr
 	now := time.Now()
 	pr.Status = "rejected"
 	pr.UpdatedBy = &userID
@@ -501,6 +511,8 @@ func RejectPurchaseRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, pr.StoreID, models.AuditActionUpdate, "purchase_request", pr.ID, pr.Code, &prOld, pr)
+
 	// Notify creator
 	if pr.CreatedBy != nil {
 		models.NotifyUserByID(pr.CreatedBy, "purchase_request_status_changed", map[string]interface{}{
@@ -209,6 +209,8 @@ func CreatePurchaseReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchasereturn.StoreID, models.AuditActionCreate, "purchase_return", purchasereturn.ID, purchasereturn.Code, nil, purchasereturn)
+
 	queue.Pop()
 	CleanupQueueIfEmpty(store.ID.Hex(), "purchase_return")
 
@@ -247,6 +249,28 @@ func CreatePurchaseReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = purchasereturn.SetProductsLots()
+	if err != nil {
+		response.Status = false
+		response.Errors = make(map[string]string)
+		response.Errors["product_lots"] = "Unable to update product lots:" + err.Error()
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = purchasereturn.SetProductsSerials()
+	if err != nil {
+		response.Status = false
+		response.Errors = make(map[string]string)
+		response.Errors["serial_numbers"] = "Unable to update serial numbers:" + err.Error()
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	err = purchasereturn.ClosePurchasePayment()
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -396,6 +420,8 @@ func UpdatePurchaseReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchasereturn.StoreID, models.AuditActionUpdate, "purchase_return", purchasereturn.ID, purchasereturn.Code, purchasereturnOld, purchasereturn)
+
 	purchasereturn.ClearProductsPurchaseReturnHistory()
 	purchasereturn.CreateProductsPurchaseReturnHistory()
 
@@ -454,6 +480,28 @@ func UpdatePurchaseReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = purchasereturn.SetProductsLots()
+	if err != nil {
+		response.Status = false
+		response.Errors = make(map[string]string)
+		response.Errors["product_lots"] = "Unable to update product lots:" + err.Error()
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = purchasereturn.SetProductsSerials()
+	if err != nil {
+		response.Status = false
+		response.Errors = make(map[string]string)
+		response.Errors["serial_numbers"] = "Unable to update serial numbers:" + err.Error()
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	err = purchasereturn.ClosePurchasePayment()
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -634,6 +682,15 @@ func DeletePurchaseReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, purchasereturn.StoreID, "purchase_return", purchasereturn.ID, purchasereturn.Code, purchasereturn.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	purchasereturnOld := *purchasereturn
 	err = purchasereturn.DeletePurchaseReturn(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -643,6 +700,26 @@ func DeletePurchaseReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchasereturn.StoreID, models.AuditActionDelete, "purchase_return", purchasereturn.ID, purchasereturn.Code, &purchasereturnOld, purchasereturn)
+
+	err = purchasereturn.SetProductsLots()
+	if err != nil {
+		response.Status = false
+		response.Errors["product_lots"] = "Unable to update product lots:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = purchasereturn.SetProductsSerials()
+	if err != nil {
+		response.Status = false
+		response.Errors["serial_numbers"] = "Unable to update serial numbers:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	if purchasereturn.StoreID != nil {
 		go models.MarkDashboardDirty(*purchasereturn.StoreID, purchasereturn.Date)
 	}
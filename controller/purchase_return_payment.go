@@ -129,6 +129,8 @@ func CreatePurchaseReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchasereturnpayment.StoreID, models.AuditActionCreate, "purchase_return_payment", purchasereturnpayment.ID, purchasereturnpayment.PurchaseReturnCode, nil, purchasereturnpayment)
+
 	store, err := ParseStore(r)
 	if err != nil {
 		response.Status = false
@@ -209,6 +211,8 @@ func UpdatePurchaseReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	purchasereturnpaymentOld := *purchasereturnpayment
+
 	// Decode data
 	if !utils.Decode(w, r, &purchasereturnpayment) {
 		return
@@ -246,6 +250,8 @@ func UpdatePurchaseReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchasereturnpayment.StoreID, models.AuditActionUpdate, "purchase_return_payment", purchasereturnpayment.ID, purchasereturnpayment.PurchaseReturnCode, &purchasereturnpaymentOld, purchasereturnpayment)
+
 	//Updating purchase.payments
 	purchaseReturn, _ := store.FindPurchaseReturnByID(purchasereturnpayment.PurchaseReturnID, map[string]interface{}{})
 	purchaseReturn.SetPaymentStatus()
@@ -386,6 +392,15 @@ func DeletePurchaseReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, purchaseReturnPayment.StoreID, "purchase_return_payment", purchaseReturnPayment.ID, purchaseReturnPayment.PurchaseReturnCode, purchaseReturnPayment.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	purchaseReturnPaymentOld := *purchaseReturnPayment
 	purchaseReturnPayment.Deleted = true
 	purchaseReturnPayment.DeletedBy = &userID
 	now := time.Now()
@@ -399,6 +414,8 @@ func DeletePurchaseReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, purchaseReturnPayment.StoreID, models.AuditActionDelete, "purchase_return_payment", purchaseReturnPayment.ID, purchaseReturnPayment.PurchaseReturnCode, &purchaseReturnPaymentOld, purchaseReturnPayment)
+
 	//Updating purchase.payments
 	purchaseReturn, _ := store.FindPurchaseReturnByID(purchaseReturnPayment.PurchaseReturnID, map[string]interface{}{})
 	purchaseReturn.SetPaymentStatus()
@@ -152,6 +152,24 @@ func CreateQuotation(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
 	quotation.CreatedAt = &now
 	quotation.UpdatedAt = &now
+	err = quotation.ApplyPriceLists()
+	if err != nil {
+		response.Status = false
+		response.Errors["price_list"] = "Error applying price lists: " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = quotation.ApplyPromotions()
+	if err != nil {
+		response.Status = false
+		response.Errors["promotion"] = "Error applying promotions: " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	quotation.FindNetTotal()
 
 	//Queue
@@ -267,6 +285,8 @@ func CreateQuotation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, quotation.StoreID, models.AuditActionCreate, "quotation", quotation.ID, quotation.Code, nil, quotation)
+
 	queue.Pop()
 	CleanupQueueIfEmpty(store.ID.Hex(), "quotation")
 
@@ -466,6 +486,28 @@ func UpdateQuotation(w http.ResponseWriter, r *http.Request) {
 	quotation.UpdatedBy = &userID
 	now := time.Now()
 	quotation.UpdatedAt = &now
+	if quotation.NeedsPricing(quotationOld) {
+		err = quotation.ApplyPriceLists()
+		if err != nil {
+			response.Status = false
+			response.Errors["price_list"] = "Error applying price lists: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	if quotation.NeedsPromotions(quotationOld) {
+		err = quotation.ApplyPromotions()
+		if err != nil {
+			response.Status = false
+			response.Errors["promotion"] = "Error applying promotions: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
 	quotation.FindNetTotal()
 
 	// Validate data
@@ -543,6 +585,8 @@ func UpdateQuotation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, quotation.StoreID, models.AuditActionUpdate, "quotation", quotation.ID, quotation.Code, quotationOld, quotation)
+
 	err = quotation.LinkOrUnLinkSales(quotationOld)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -826,6 +870,7 @@ func DeleteQuotation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	quotationOld := *quotation
 	err = quotation.DeleteQuotation(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -835,6 +880,8 @@ func DeleteQuotation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, quotation.StoreID, models.AuditActionDelete, "quotation", quotation.ID, quotation.Code, &quotationOld, quotation)
+
 	if quotation.StoreID != nil {
 		go models.MarkDashboardDirty(*quotation.StoreID, quotation.Date)
 	}
@@ -866,6 +913,37 @@ func CalculateQuotationNetTotal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	quotationOld, err := quotation.FindSaved()
+	if err != nil {
+		response.Status = false
+		response.Errors["price_list"] = "Error applying price lists: " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if quotation.NeedsPricing(quotationOld) {
+		err = quotation.ApplyPriceLists()
+		if err != nil {
+			response.Status = false
+			response.Errors["price_list"] = "Error applying price lists: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	if quotation.NeedsPromotions(quotationOld) {
+		err = quotation.ApplyPromotions()
+		if err != nil {
+			response.Status = false
+			response.Errors["promotion"] = "Error applying promotions: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
 	quotation.FindNetTotal()
 
 	response.Status = true
@@ -217,6 +217,8 @@ func CreateQuotationSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, quotationsalesreturn.StoreID, models.AuditActionCreate, "quotation_sales_return", quotationsalesreturn.ID, quotationsalesreturn.Code, nil, quotationsalesreturn)
+
 	queue.Pop()
 	CleanupQueueIfEmpty(store.ID.Hex(), "quotation_sales_return")
 
@@ -416,6 +418,8 @@ func UpdateQuotationSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, quotationsalesreturn.StoreID, models.AuditActionUpdate, "quotation_sales_return", quotationsalesreturn.ID, quotationsalesreturn.Code, quotationsalesreturnOld, quotationsalesreturn)
+
 	quotationsalesreturn.UpdateQuotationReturnCount()
 	quotationsalesreturn.UpdateQuotationReturnDiscount(quotationsalesreturnOld)
 	quotationsalesreturn.UpdateQuotationReturnCashDiscount(quotationsalesreturnOld)
@@ -651,6 +655,7 @@ func DeleteQuotationSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	quotationsalesreturnOld := *quotationsalesreturn
 	err = quotationsalesreturn.DeleteQuotationSalesReturn(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -660,6 +665,8 @@ func DeleteQuotationSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, quotationsalesreturn.StoreID, models.AuditActionDelete, "quotation_sales_return", quotationsalesreturn.ID, quotationsalesreturn.Code, &quotationsalesreturnOld, quotationsalesreturn)
+
 	if quotationsalesreturn.Status == "delivered" {
 		err = quotationsalesreturn.AddStock()
 		if err != nil {
@@ -129,6 +129,8 @@ func CreateQuotationSalesReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, quotationsalesreturnpayment.StoreID, models.AuditActionCreate, "quotation_sales_return_payment", quotationsalesreturnpayment.ID, quotationsalesreturnpayment.QuotationSalesReturnCode, nil, quotationsalesreturnpayment)
+
 	store, err := ParseStore(r)
 	if err != nil {
 		response.Status = false
@@ -214,6 +216,7 @@ func UpdateQuotationSalesReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	quotationsalesreturnpaymentOld := *quotationsalesreturnpayment
 	// Decode data
 	if !utils.Decode(w, r, &quotationsalesreturnpayment) {
 		return
@@ -251,6 +254,8 @@ func UpdateQuotationSalesReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, quotationsalesreturnpayment.StoreID, models.AuditActionUpdate, "quotation_sales_return_payment", quotationsalesreturnpayment.ID, quotationsalesreturnpayment.QuotationSalesReturnCode, &quotationsalesreturnpaymentOld, quotationsalesreturnpayment)
+
 	//Updating quotationsalesReturn.payments
 	quotationsalesReturn, _ := store.FindQuotationSalesReturnByID(quotationsalesreturnpayment.QuotationSalesReturnID, map[string]interface{}{})
 	quotationsalesReturn.SetPaymentStatus()
@@ -396,6 +401,7 @@ func DeleteQuotationSalesReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	quotationsalesReturnPaymentOld := *quotationsalesReturnPayment
 	quotationsalesReturnPayment.Deleted = true
 	quotationsalesReturnPayment.DeletedBy = &userID
 	now := time.Now()
@@ -409,6 +415,8 @@ func DeleteQuotationSalesReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, quotationsalesReturnPayment.StoreID, models.AuditActionDelete, "quotation_sales_return_payment", quotationsalesReturnPayment.ID, quotationsalesReturnPayment.QuotationSalesReturnCode, &quotationsalesReturnPaymentOld, quotationsalesReturnPayment)
+
 	//Updating quotationsalesReturn.payments
 	if quotationsalesReturnPayment.QuotationSalesReturnID != nil {
 		quotationsalesReturn, _ := store.FindQuotationSalesReturnByID(quotationsalesReturnPayment.QuotationSalesReturnID, map[string]interface{}{})
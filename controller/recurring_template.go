@@ -0,0 +1,433 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"github.com/sirinibin/startpos/backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListRecurringTemplate : handler for GET /recurring-template
+func ListRecurringTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	templates, criterias, err := store.SearchRecurringTemplate(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find recurring templates:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "recurring_template")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of recurring templates:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(templates) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = templates
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateRecurringTemplate : handler for POST /recurring-template
+func CreateRecurringTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var template *models.RecurringTemplate
+	// Decode data
+	if !utils.Decode(w, r, &template) {
+		return
+	}
+	template.StoreID = &store.ID
+	template.ID = primitive.NilObjectID
+	template.LastRunAt = nil
+	template.OccurrenceCount = 0
+	template.LastError = ""
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Validate data
+	if errs := template.Validate(w, r, "create"); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	template.Deleted = false
+	template.CreatedBy = &userID
+	template.UpdatedBy = &userID
+	template.CreatedAt = &now
+	template.UpdatedAt = &now
+
+	if template.Expense != nil {
+		template.Expense.CreatedBy = &userID
+		err = template.Expense.CreateNewVendorFromName()
+		if err != nil {
+			response.Status = false
+			response.Errors["new_vendor_from_name"] = "error creating new vendor from name: " + err.Error()
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	err = template.Insert()
+	if err != nil {
+		response.Status = false
+		response.Errors["insert"] = "Unable to insert to db:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, template.StoreID, models.AuditActionCreate, "recurring_template", template.ID, template.Name, nil, template)
+
+	response.Status = true
+	response.Result = template
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateRecurringTemplate : handler function for PUT /v1/recurring-template/<id> call
+// Occurrences already made are kept, the next run is worked out again from the last one.
+func UpdateRecurringTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	template := findRecurringTemplate(w, r, &response)
+	if template == nil {
+		return
+	}
+	templateOld := *template
+
+	// Decode data
+	if !utils.Decode(w, r, &template) {
+		return
+	}
+	template.ID = templateOld.ID
+	template.StoreID = templateOld.StoreID
+	template.LastRunAt = templateOld.LastRunAt
+	template.OccurrenceCount = templateOld.OccurrenceCount
+	template.CreatedBy = templateOld.CreatedBy
+	template.CreatedAt = templateOld.CreatedAt
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	template.UpdatedBy = &userID
+	template.UpdatedAt = &now
+
+	// Validate data
+	if errs := template.Validate(w, r, "update"); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if template.Expense != nil {
+		template.Expense.CreatedBy = &userID
+		err = template.Expense.CreateNewVendorFromName()
+		if err != nil {
+			response.Status = false
+			response.Errors["new_vendor_from_name"] = "error creating new vendor from name: " + err.Error()
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	err = template.Update()
+	if err != nil {
+		response.Status = false
+		response.Errors["update"] = "Unable to update:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, template.StoreID, models.AuditActionUpdate, "recurring_template", template.ID, template.Name, &templateOld, template)
+
+	response.Status = true
+	response.Result = template
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewRecurringTemplate : handler function for GET /v1/recurring-template/<id> call
+func ViewRecurringTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	template := findRecurringTemplate(w, r, &response)
+	if template == nil {
+		return
+	}
+
+	response.Status = true
+	response.Result = template
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteRecurringTemplate : handler function for DELETE /v1/recurring-template/<id> call
+func DeleteRecurringTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	template := findRecurringTemplate(w, r, &response)
+	if template == nil {
+		return
+	}
+	templateOld := *template
+
+	err = template.DeleteRecurringTemplate(tokenClaims)
+	if err != nil {
+		response.Status = false
+		response.Errors["delete"] = "Unable to delete:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	models.RecordAudit(r, tokenClaims, template.StoreID, models.AuditActionDelete, "recurring_template", template.ID, template.Name, &templateOld, template)
+
+	response.Status = true
+	response.Result = "Deleted successfully"
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// PreviewRecurringTemplate : handler function for GET /v1/recurring-template/<id>/preview?count=12 call
+func PreviewRecurringTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	template := findRecurringTemplate(w, r, &response)
+	if template == nil {
+		return
+	}
+
+	store, err := models.FindStoreByID(template.StoreID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	writeRecurringTemplatePreview(w, r, store, template, &response)
+}
+
+// PreviewNewRecurringTemplate : handler function for POST /v1/recurring-template/preview?count=12 call
+// Lists the occurrences of a template that is not saved yet.
+func PreviewNewRecurringTemplate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var template *models.RecurringTemplate
+	// Decode data
+	if !utils.Decode(w, r, &template) {
+		return
+	}
+	template.StoreID = &store.ID
+	template.ID = primitive.NilObjectID
+	template.LastRunAt = nil
+
+	// Validate data
+	if errs := template.Validate(w, r, "create"); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	writeRecurringTemplatePreview(w, r, store, template, &response)
+}
+
+func writeRecurringTemplatePreview(w http.ResponseWriter, r *http.Request, store *models.Store, template *models.RecurringTemplate, response *models.Response) {
+	count := 12
+	keys, ok := r.URL.Query()["count"]
+	if ok && len(keys[0]) >= 1 {
+		value, err := strconv.Atoi(keys[0])
+		if err != nil || value <= 0 {
+			response.Status = false
+			response.Errors["count"] = "Invalid count"
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		count = value
+	}
+
+	previews, err := store.PreviewRecurringTemplate(template, count)
+	if err != nil {
+		response.Status = false
+		response.Errors["preview"] = "Unable to preview:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = previews
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func findRecurringTemplate(w http.ResponseWriter, r *http.Request, response *models.Response) *models.RecurringTemplate {
+	params := mux.Vars(r)
+	templateID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["recurring_template_id"] = "Invalid Recurring Template ID:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	template, err := store.FindRecurringTemplateByID(&templateID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+
+	return template
+}
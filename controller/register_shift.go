@@ -0,0 +1,317 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"github.com/sirinibin/startpos/backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListRegisterShift : handler for GET /register-shift
+func ListRegisterShift(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	shifts, criterias, err := store.SearchRegisterShift(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find register shifts:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "register_shift")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of register shifts:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(shifts) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = shifts
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// OpenRegisterShift : handler for POST /register-shift
+func OpenRegisterShift(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var shift *models.RegisterShift
+	// Decode data
+	if !utils.Decode(w, r, &shift) {
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Validate data
+	if errs := shift.ValidateOpen(w, r, userID); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	now := time.Now()
+	shift.Status = models.RegisterShiftStatusOpen
+	shift.OpenedBy = &userID
+	shift.OpenedAt = &now
+	shift.ClosedAt = nil
+	shift.ClosedBy = nil
+	shift.CountedAmounts = []models.ShiftCountedAmount{}
+	shift.ZReport = nil
+	shift.CreatedAt = &now
+	shift.UpdatedAt = &now
+
+	err = shift.Insert()
+	if err != nil {
+		response.Status = false
+		response.Errors["insert"] = "Unable to insert to db:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = shift
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// CloseRegisterShift : handler for POST /v1/register-shift/<id>/close
+// Body: { "counted_amounts": [{ "method": "cash", "amount": 1250.50 }, ...], "remarks": "..." }
+func CloseRegisterShift(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	shift, _ := findRegisterShift(w, r, &response)
+	if shift == nil {
+		return
+	}
+
+	var input struct {
+		CountedAmounts []models.ShiftCountedAmount `json:"counted_amounts"`
+		Remarks        string                      `json:"remarks"`
+	}
+	if !utils.Decode(w, r, &input) {
+		return
+	}
+	shift.CountedAmounts = input.CountedAmounts
+	shift.Remarks = strings.TrimSpace(input.Remarks)
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Validate data
+	if errs := shift.ValidateClose(w, r); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = shift.Close(userID)
+	if err != nil {
+		response.Status = false
+		response.Errors["close"] = "Unable to close shift:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = shift
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ViewRegisterShift : handler function for GET /v1/register-shift/<id> call
+func ViewRegisterShift(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	shift, _ := findRegisterShift(w, r, &response)
+	if shift == nil {
+		return
+	}
+
+	response.Status = true
+	response.Result = shift
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetRegisterShiftReport : handler function for GET /v1/register-shift/<id>/report call
+// Returns the X report of an open shift or the Z report of a closed one.
+// Query params: format=pdf renders the report through the /report-print page.
+func GetRegisterShiftReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	shift, _ := findRegisterShift(w, r, &response)
+	if shift == nil {
+		return
+	}
+
+	report, err := shift.MakeReport()
+	if err != nil {
+		response.Status = false
+		response.Errors["report"] = "Unable to make shift report:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if r.URL.Query().Get("format") != "pdf" {
+		response.Status = true
+		response.Result = report
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	model, err := json.Marshal(report)
+	if err != nil {
+		response.Status = false
+		response.Errors["report"] = "Unable to encode shift report:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	modelName := "register_shift_" + strings.ToLower(report.Type) + "_report"
+	pdfBuf, errKey, err := renderReportPDF(printJobData{
+		Model:     model,
+		ModelName: modelName,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		response.Status = false
+		response.Errors[errKey] = err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_%s.pdf"`, modelName, shift.Code))
+	w.WriteHeader(http.StatusOK)
+	w.Write(pdfBuf)
+}
+
+func findRegisterShift(w http.ResponseWriter, r *http.Request, response *models.Response) (*models.RegisterShift, *models.Store) {
+	params := mux.Vars(r)
+	shiftID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["shift_id"] = "Invalid Shift ID:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil, nil
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil, nil
+	}
+
+	shift, err := store.FindRegisterShiftByID(&shiftID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["view"] = "Unable to view:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return nil, nil
+	}
+
+	return shift, store
+}
@@ -158,6 +158,8 @@ func CreateRepairJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, job.StoreID, models.AuditActionCreate, "repair_job", job.ID, job.JobNumber, nil, job)
+
 	job.LinkToOrderAndQuotation()
 
 	response.Status = true
@@ -250,6 +252,14 @@ func UpdateRepairJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	jobOld, err := store.FindRepairJobByID(&id, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find repair job:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	if !utils.Decode(w, r, &job) {
 		return
 	}
@@ -287,6 +297,8 @@ func UpdateRepairJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, job.StoreID, models.AuditActionUpdate, "repair_job", job.ID, job.JobNumber, jobOld, job)
+
 	job.LinkToOrderAndQuotation()
 
 	response.Status = true
@@ -334,6 +346,7 @@ func DeleteRepairJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	jobOld := *job
 	if err := job.Delete(tokenClaims); err != nil {
 		response.Status = false
 		response.Errors["delete"] = "Unable to delete repair job:" + err.Error()
@@ -341,6 +354,8 @@ func DeleteRepairJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, job.StoreID, models.AuditActionDelete, "repair_job", job.ID, job.JobNumber, &jobOld, job)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 	json.NewEncoder(w).Encode(response)
@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -75,30 +76,56 @@ func ReportPDF(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chromeBin := chromePath()
-	if chromeBin == "" {
+	pdfBuf, errKey, err := renderReportPDF(printJobData{
+		Model:     reqBody.Model,
+		ModelName: reqBody.ModelName,
+		FontSizes: reqBody.FontSizes,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
 		response.Status = false
-		response.Errors["chrome"] = "Chrome/Chromium not found. Install Google Chrome to use PDF generation."
+		response.Errors[errKey] = err.Error()
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
+	// Save to ~/Downloads/{filename}.pdf
+	savedPath := ""
+	saveFilename := reqBody.Filename
+	if saveFilename == "" {
+		saveFilename = fmt.Sprintf("report_%s_%d", reqBody.ModelName, time.Now().Unix())
+	}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		savePath := fmt.Sprintf("%s/Downloads/%s.pdf", homeDir, saveFilename)
+		if writeErr := os.WriteFile(savePath, pdfBuf, 0644); writeErr == nil {
+			savedPath = savePath
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, saveFilename))
+	if savedPath != "" {
+		w.Header().Set("X-Saved-To", savedPath)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(pdfBuf)
+}
+
+// renderReportPDF renders the React /report-print page for job with headless
+// Chrome and returns the A4 PDF. On failure errKey names the failing step.
+func renderReportPDF(job printJobData) (pdf []byte, errKey string, err error) {
+	chromeBin := chromePath()
+	if chromeBin == "" {
+		return nil, "chrome", errors.New("Chrome/Chromium not found. Install Google Chrome to use PDF generation.")
+	}
+
 	key, err := generatePrintKey()
 	if err != nil {
-		response.Status = false
-		response.Errors["key"] = "Failed to generate print key: " + err.Error()
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
-		return
+		return nil, "key", errors.New("Failed to generate print key: " + err.Error())
 	}
 
-	printJobStore.Store(key, printJobData{
-		Model:     reqBody.Model,
-		ModelName: reqBody.ModelName,
-		FontSizes: reqBody.FontSizes,
-		CreatedAt: time.Now(),
-	})
+	printJobStore.Store(key, job)
 	defer printJobStore.Delete(key)
 
 	apiPort := env.Getenv("API_PORT", "2000")
@@ -147,31 +174,8 @@ func ReportPDF(w http.ResponseWriter, r *http.Request) {
 		}),
 	)
 	if err != nil {
-		response.Status = false
-		response.Errors["pdf"] = "PDF generation failed: " + err.Error()
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
-		return
+		return nil, "pdf", errors.New("PDF generation failed: " + err.Error())
 	}
 
-	// Save to ~/Downloads/{filename}.pdf
-	savedPath := ""
-	saveFilename := reqBody.Filename
-	if saveFilename == "" {
-		saveFilename = fmt.Sprintf("report_%s_%d", reqBody.ModelName, time.Now().Unix())
-	}
-	if homeDir, err := os.UserHomeDir(); err == nil {
-		savePath := fmt.Sprintf("%s/Downloads/%s.pdf", homeDir, saveFilename)
-		if writeErr := os.WriteFile(savePath, pdfBuf, 0644); writeErr == nil {
-			savedPath = savePath
-		}
-	}
-
-	w.Header().Set("Content-Type", "application/pdf")
-	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, saveFilename))
-	if savedPath != "" {
-		w.Header().Set("X-Saved-To", savedPath)
-	}
-	w.WriteHeader(http.StatusOK)
-	w.Write(pdfBuf)
+	return pdfBuf, "", nil
 }
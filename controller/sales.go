@@ -256,6 +256,24 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 	//log.Print("order.SkipZatcaReporting:")
 	//log.Print(order.SkipZatcaReporting)
 	// Validate data
+	err = order.ApplyPriceLists()
+	if err != nil {
+		response.Status = false
+		response.Errors["price_list"] = "Error applying price lists: " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = order.ApplyPromotions()
+	if err != nil {
+		response.Status = false
+		response.Errors["promotion"] = "Error applying promotions: " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	order.FindNetTotal()
 
 	if order.EnableReportToZatca && !IsConnectedToInternet() {
@@ -416,6 +434,9 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(response)
 		return
 	}
+
+	models.RecordAudit(r, tokenClaims, order.StoreID, models.AuditActionCreate, "order", order.ID, order.Code, nil, order)
+
 	queue.Pop()
 	if zatcaQueue != nil {
 		zatcaQueue.Pop()
@@ -434,6 +455,10 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 	order.AddPayments()
 	order.SetPaymentStatus()
 	order.Update()
+	go store.EmitWebhookEvent(models.WebhookEventOrderCreated, order)
+	if order.Zatca.ReportingPassed {
+		go store.EmitWebhookEvent(models.WebhookEventOrderZatcaReported, order)
+	}
 
 	err = order.ClosePurchasePayment()
 	if err != nil {
@@ -451,6 +476,7 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 		order.SetProductsStock()
 		order.SetProductsSalesStats()
 		order.SetCustomerSalesStats()
+		order.SetLoyaltyPoints()
 		order.DoAccounting()
 		go order.CreateProductsHistory(true, nil)
 		go order.SetPostBalances()
@@ -565,6 +591,28 @@ func UpdateOrder(w http.ResponseWriter, r *http.Request) {
 	order.UpdatedBy = &userID
 	now := time.Now()
 	order.UpdatedAt = &now
+	if order.NeedsPricing(orderOld) {
+		err = order.ApplyPriceLists()
+		if err != nil {
+			response.Status = false
+			response.Errors["price_list"] = "Error applying price lists: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	if order.NeedsPromotions(orderOld) {
+		err = order.ApplyPromotions()
+		if err != nil {
+			response.Status = false
+			response.Errors["promotion"] = "Error applying promotions: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
 	order.FindNetTotal()
 
 	// Validate data
@@ -619,6 +667,8 @@ func UpdateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, order.StoreID, models.AuditActionUpdate, "order", order.ID, order.Code, orderOld, order)
+
 	err = order.UpdateSalesReturnCustomer()
 	if err != nil {
 		response.Status = false
@@ -644,6 +694,7 @@ func UpdateOrder(w http.ResponseWriter, r *http.Request) {
 
 	order.SetPaymentStatus()
 	order.Update()
+	go store.EmitWebhookEvent(models.WebhookEventOrderUpdated, order)
 
 	/*
 		err = orderOld.SetProductsStock()
@@ -688,6 +739,7 @@ func UpdateOrder(w http.ResponseWriter, r *http.Request) {
 		order.SetProductsSalesStats()
 		orderOld.SetProductsSalesStats()
 		order.SetCustomerSalesStats()
+		order.SetLoyaltyPoints()
 		order.UndoAccounting()
 		order.DoAccounting()
 		order.ClearProductsHistory()
@@ -774,6 +826,37 @@ func CalculateSalesNetTotal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	orderOld, err := order.FindSaved()
+	if err != nil {
+		response.Status = false
+		response.Errors["price_list"] = "Error applying price lists: " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if order.NeedsPricing(orderOld) {
+		err = order.ApplyPriceLists()
+		if err != nil {
+			response.Status = false
+			response.Errors["price_list"] = "Error applying price lists: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	if order.NeedsPromotions(orderOld) {
+		err = order.ApplyPromotions()
+		if err != nil {
+			response.Status = false
+			response.Errors["promotion"] = "Error applying promotions: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
 	order.FindNetTotal()
 
 	response.Status = true
@@ -1079,6 +1162,15 @@ func DeleteOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, order.StoreID, "order", order.ID, order.Code, order.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	orderOld := *order
 	err = order.DeleteOrder(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -1088,6 +1180,8 @@ func DeleteOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, order.StoreID, models.AuditActionDelete, "order", order.ID, order.Code, &orderOld, order)
+
 	if order.StoreID != nil {
 		go models.MarkDashboardDirty(*order.StoreID, order.Date)
 	}
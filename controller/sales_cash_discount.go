@@ -128,6 +128,8 @@ func CreateSalesCashDiscount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, salescashdiscount.StoreID, models.AuditActionCreate, "sales_cash_discount", salescashdiscount.ID, salescashdiscount.OrderCode, nil, salescashdiscount)
+
 	store, err := ParseStore(r)
 	if err != nil {
 		response.Status = false
@@ -231,6 +233,7 @@ func UpdateSalesCashDiscount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	salescashdiscountOld := *salescashdiscount
 	// Decode data
 	if !utils.Decode(w, r, &salescashdiscount) {
 		return
@@ -267,6 +270,8 @@ func UpdateSalesCashDiscount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, salescashdiscount.StoreID, models.AuditActionUpdate, "sales_cash_discount", salescashdiscount.ID, salescashdiscount.OrderCode, &salescashdiscountOld, salescashdiscount)
+
 	salescashdiscount, err = store.FindSalesCashDiscountByID(&salescashdiscount.ID, bson.M{})
 	if err != nil {
 		response.Status = false
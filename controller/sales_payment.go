@@ -128,6 +128,8 @@ func CreateSalesPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, salespayment.StoreID, models.AuditActionCreate, "sales_payment", salespayment.ID, salespayment.OrderCode, nil, salespayment)
+
 	store, err := ParseStore(r)
 	if err != nil {
 		response.Status = false
@@ -142,6 +144,14 @@ func CreateSalesPayment(w http.ResponseWriter, r *http.Request) {
 	order.SetCustomerSalesStats()
 	order.Update()
 
+	err = order.SetLoyaltyPoints()
+	if err != nil {
+		response.Status = false
+		response.Errors["loyalty_points"] = "Error setting loyalty points: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	err = order.UndoAccounting()
 	if err != nil {
 		response.Status = false
@@ -212,6 +222,8 @@ func UpdateSalesPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	salespaymentOld := *salespayment
+
 	// Decode data
 	if !utils.Decode(w, r, &salespayment) {
 		return
@@ -249,12 +261,22 @@ func UpdateSalesPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, salespayment.StoreID, models.AuditActionUpdate, "sales_payment", salespayment.ID, salespayment.OrderCode, &salespaymentOld, salespayment)
+
 	//Updating order.payments
 	order, _ := store.FindOrderByID(salespayment.OrderID, map[string]interface{}{})
 	order.SetPaymentStatus()
 	order.SetCustomerSalesStats()
 	order.Update()
 
+	err = order.SetLoyaltyPoints()
+	if err != nil {
+		response.Status = false
+		response.Errors["loyalty_points"] = "Error setting loyalty points: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	err = order.UndoAccounting()
 	if err != nil {
 		response.Status = false
@@ -393,6 +415,15 @@ func DeleteSalesPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, salesPayment.StoreID, "sales_payment", salesPayment.ID, salesPayment.OrderCode, salesPayment.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	salesPaymentOld := *salesPayment
 	salesPayment.Deleted = true
 	salesPayment.DeletedBy = &userID
 	now := time.Now()
@@ -406,12 +437,22 @@ func DeleteSalesPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, salesPayment.StoreID, models.AuditActionDelete, "sales_payment", salesPayment.ID, salesPayment.OrderCode, &salesPaymentOld, salesPayment)
+
 	//Updating order.payments
 	order, _ := store.FindOrderByID(salesPayment.OrderID, map[string]interface{}{})
 	order.SetPaymentStatus()
 	order.SetCustomerSalesStats()
 	order.Update()
 
+	err = order.SetLoyaltyPoints()
+	if err != nil {
+		response.Status = false
+		response.Errors["loyalty_points"] = "Error setting loyalty points: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	err = order.UndoAccounting()
 	if err != nil {
 		response.Status = false
@@ -140,6 +140,15 @@ func CreateSalesReturn(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
 	salesreturn.CreatedAt = &now
 	salesreturn.UpdatedAt = &now
+	err = salesreturn.ReversePromotionDiscounts()
+	if err != nil {
+		response.Status = false
+		response.Errors["promotion"] = "Error reversing promotion discounts: " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	salesreturn.FindNetTotal()
 
 	/*// Validate data
@@ -241,6 +250,8 @@ func CreateSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, salesreturn.StoreID, models.AuditActionCreate, "sales_return", salesreturn.ID, salesreturn.Code, nil, salesreturn)
+
 	queue.Pop()
 	if zatcaQueue != nil {
 		zatcaQueue.Pop()
@@ -269,6 +280,10 @@ func CreateSalesReturn(w http.ResponseWriter, r *http.Request) {
 
 	salesreturn.SetPaymentStatus()
 	salesreturn.Update()
+	go store.EmitWebhookEvent(models.WebhookEventSalesReturnCreated, salesreturn)
+	if salesreturn.Zatca.ReportingPassed {
+		go store.EmitWebhookEvent(models.WebhookEventSalesReturnZatcaReported, salesreturn)
+	}
 
 	err = salesreturn.SetProductsStock()
 	if err != nil {
@@ -301,6 +316,14 @@ func CreateSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = salesreturn.SetLoyaltyPoints()
+	if err != nil {
+		response.Status = false
+		response.Errors["loyalty_points"] = "Error setting loyalty points: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	err = salesreturn.DoAccounting()
 	if err != nil {
 		response.Status = false
@@ -409,6 +432,15 @@ func UpdateSalesReturn(w http.ResponseWriter, r *http.Request) {
 	salesreturn.UpdatedBy = &userID
 	now := time.Now()
 	salesreturn.UpdatedAt = &now
+	err = salesreturn.ReversePromotionDiscounts()
+	if err != nil {
+		response.Status = false
+		response.Errors["promotion"] = "Error reversing promotion discounts: " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	salesreturn.FindNetTotal()
 
 	// Validate data
@@ -448,6 +480,8 @@ func UpdateSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, salesreturn.StoreID, models.AuditActionUpdate, "sales_return", salesreturn.ID, salesreturn.Code, salesreturnOld, salesreturn)
+
 	salesreturn.UpdateOrderReturnCount()
 	salesreturn.UpdateOrderReturnDiscount(salesreturnOld)
 	salesreturn.UpdateOrderReturnCashDiscount(salesreturnOld)
@@ -474,6 +508,7 @@ func UpdateSalesReturn(w http.ResponseWriter, r *http.Request) {
 
 	salesreturn.SetPaymentStatus()
 	salesreturn.Update()
+	go store.EmitWebhookEvent(models.WebhookEventSalesReturnUpdated, salesreturn)
 
 	err = salesreturn.SetProductsStock()
 	if err != nil {
@@ -530,6 +565,15 @@ func UpdateSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = salesreturn.SetLoyaltyPoints()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		response.Status = false
+		response.Errors["loyalty_points"] = "Error setting loyalty points: " + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	err = salesreturn.UndoAccounting()
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -698,6 +742,15 @@ func DeleteSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, salesreturn.StoreID, "sales_return", salesreturn.ID, salesreturn.Code, salesreturn.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	salesreturnOld := *salesreturn
 	err = salesreturn.DeleteSalesReturn(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -707,6 +760,8 @@ func DeleteSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, salesreturn.StoreID, models.AuditActionDelete, "sales_return", salesreturn.ID, salesreturn.Code, &salesreturnOld, salesreturn)
+
 	err = salesreturn.SetProductsStock()
 	if err != nil {
 		response.Status = false
@@ -718,6 +773,15 @@ func DeleteSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = salesreturn.SetLoyaltyPoints()
+	if err != nil {
+		response.Status = false
+		response.Errors["loyalty_points"] = "Error setting loyalty points: " + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	if salesreturn.StoreID != nil {
 		go models.MarkDashboardDirty(*salesreturn.StoreID, salesreturn.Date)
 	}
@@ -771,6 +835,15 @@ func UndeleteSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, salesreturn.StoreID, "sales_return", salesreturn.ID, salesreturn.Code, salesreturn.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	salesreturnOld := *salesreturn
 	err = salesreturn.UndeleteSalesReturn(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -780,6 +853,8 @@ func UndeleteSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, salesreturn.StoreID, models.AuditActionRestore, "sales_return", salesreturn.ID, salesreturn.Code, &salesreturnOld, salesreturn)
+
 	err = salesreturn.SetProductsStock()
 	if err != nil {
 		response.Status = false
@@ -791,6 +866,15 @@ func UndeleteSalesReturn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = salesreturn.SetLoyaltyPoints()
+	if err != nil {
+		response.Status = false
+		response.Errors["loyalty_points"] = "Error setting loyalty points: " + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	if salesreturn.StoreID != nil {
 		go models.MarkDashboardDirty(*salesreturn.StoreID, salesreturn.Date)
 	}
@@ -822,6 +906,15 @@ func CalculateSalesReturnNetTotal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = salesReturn.ReversePromotionDiscounts()
+	if err != nil {
+		response.Status = false
+		response.Errors["promotion"] = "Error reversing promotion discounts: " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	salesReturn.FindNetTotal()
 
 	response.Status = true
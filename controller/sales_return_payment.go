@@ -129,6 +129,8 @@ func CreateSalesReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, salesreturnpayment.StoreID, models.AuditActionCreate, "sales_return_payment", salesreturnpayment.ID, salesreturnpayment.SalesReturnCode, nil, salesreturnpayment)
+
 	store, err := ParseStore(r)
 	if err != nil {
 		response.Status = false
@@ -214,6 +216,8 @@ func UpdateSalesReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	salesreturnpaymentOld := *salesreturnpayment
+
 	// Decode data
 	if !utils.Decode(w, r, &salesreturnpayment) {
 		return
@@ -251,6 +255,8 @@ func UpdateSalesReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, salesreturnpayment.StoreID, models.AuditActionUpdate, "sales_return_payment", salesreturnpayment.ID, salesreturnpayment.SalesReturnCode, &salesreturnpaymentOld, salesreturnpayment)
+
 	//Updating salesReturn.payments
 	salesReturn, _ := store.FindSalesReturnByID(salesreturnpayment.SalesReturnID, map[string]interface{}{})
 	salesReturn.SetPaymentStatus()
@@ -396,6 +402,15 @@ func DeleteSalesReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if message := models.ValidateAccountingLock(r, salesReturnPayment.StoreID, "sales_return_payment", salesReturnPayment.ID, salesReturnPayment.SalesReturnCode, salesReturnPayment.Date); message != "" {
+		response.Status = false
+		response.Errors["date_str"] = message
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	salesReturnPaymentOld := *salesReturnPayment
 	salesReturnPayment.Deleted = true
 	salesReturnPayment.DeletedBy = &userID
 	now := time.Now()
@@ -409,6 +424,8 @@ func DeleteSalesReturnPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, salesReturnPayment.StoreID, models.AuditActionDelete, "sales_return_payment", salesReturnPayment.ID, salesReturnPayment.SalesReturnCode, &salesReturnPaymentOld, salesReturnPayment)
+
 	//Updating salesReturn.payments
 	if salesReturnPayment.SalesReturnID != nil {
 		salesReturn, _ := store.FindSalesReturnByID(salesReturnPayment.SalesReturnID, map[string]interface{}{})
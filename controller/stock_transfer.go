@@ -179,6 +179,8 @@ func CreateStockTransfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, stocktransfer.StoreID, models.AuditActionCreate, "stock_transfer", stocktransfer.ID, stocktransfer.Code, nil, stocktransfer)
+
 	go func() {
 		stocktransfer.CreateProductsStockTransferHistory()
 		stocktransfer.SetProductsStock()
@@ -298,6 +300,8 @@ func UpdateStockTransfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, stocktransfer.StoreID, models.AuditActionUpdate, "stock_transfer", stocktransfer.ID, stocktransfer.Code, stocktransferOld, stocktransfer)
+
 	/*err = stocktransfer.SetProductsStock()
 	if err != nil {
 		response.Status = false
@@ -646,6 +650,7 @@ func DeleteStockTransfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	stocktransferOld := *stocktransfer
 	err = stocktransfer.DeleteStockTransfer(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -655,6 +660,8 @@ func DeleteStockTransfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, stocktransfer.StoreID, models.AuditActionDelete, "stock_transfer", stocktransfer.ID, stocktransfer.Code, &stocktransferOld, stocktransfer)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 
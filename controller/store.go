@@ -340,6 +340,8 @@ func UpdateStore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, &store.ID, models.AuditActionUpdate, "store", store.ID, store.Code, storeOld, store)
+
 	err = store.AttributesValueChangeEvent(storeOld)
 	if err != nil {
 		response.Status = false
@@ -471,6 +473,7 @@ func DeleteStore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	storeOld := *store
 	err = store.DeleteStore(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -479,6 +482,8 @@ func DeleteStore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, &store.ID, models.AuditActionDelete, "store", store.ID, store.Code, &storeOld, store)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 
@@ -177,6 +177,8 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordUserAudit(r, tokenClaims, models.AuditActionCreate, nil, user)
+
 	if user.OpeningBalance > 0 && user.StoreID != nil {
 		userStore, err := models.FindStoreByID(user.StoreID, bson.M{})
 		if err != nil {
@@ -241,6 +243,8 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userOld := *user
+
 	var userForm *models.UserForm
 	// Decode data
 	if !utils.Decode(w, r, &userForm) {
@@ -326,6 +330,8 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordUserAudit(r, tokenClaims, models.AuditActionUpdate, &userOld, user)
+
 	openingBalanceChanged := user.OpeningBalance != oldOpeningBalance ||
 		!models.TimesEqual(user.OpeningBalanceDate, oldOpeningBalanceDate) ||
 		user.OpeningBalanceType != oldOpeningBalanceType ||
@@ -449,6 +455,7 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userOld := *user
 	err = user.DeleteUser(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -457,6 +464,8 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordUserAudit(r, tokenClaims, models.AuditActionDelete, &userOld, user)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 
@@ -132,6 +132,8 @@ func CreateUserRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, userRole.StoreID, models.AuditActionCreate, "user_role", userRole.ID, userRole.Name, nil, userRole)
+
 	response.Status = true
 	response.Result = userRole
 	json.NewEncoder(w).Encode(response)
@@ -236,6 +238,8 @@ func UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userRoleOld := *userRole
+
 	var updates *models.UserRole
 	if !utils.Decode(w, r, &updates) {
 		return
@@ -272,6 +276,8 @@ func UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, userRole.StoreID, models.AuditActionUpdate, "user_role", userRole.ID, userRole.Name, &userRoleOld, userRole)
+
 	response.Status = true
 	response.Result = userRole
 	json.NewEncoder(w).Encode(response)
@@ -340,6 +346,7 @@ func DeleteUserRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userRoleOld := *userRole
 	err = userRole.Delete(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -349,6 +356,8 @@ func DeleteUserRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, userRole.StoreID, models.AuditActionDelete, "user_role", userRole.ID, userRole.Name, &userRoleOld, userRole)
+
 	response.Status = true
 	response.Result = "User role deleted successfully"
 	json.NewEncoder(w).Encode(response)
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/models"
+)
+
+// GetVATReturn : handler for GET /report/vat-return
+// Period is quarter=2026-Q1 or date_from..date_to. box=n limits the drill-down documents to one box,
+// corrections and credit_carried_forward fill boxes 14 and 15. format=json|pdf|xlsx.
+func GetVATReturn(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	query := r.URL.Query()
+	var from, to time.Time
+	if quarter := query.Get("quarter"); quarter != "" {
+		from, to, err = models.ParseVATQuarter(quarter)
+		if err != nil {
+			response.Errors["quarter"] = err.Error()
+		}
+	} else {
+		dateFrom, err := models.ParseStatementDate(query.Get("date_from"))
+		if err != nil {
+			response.Errors["date_from"] = err.Error()
+		} else if dateFrom == nil {
+			response.Errors["date_from"] = "date_from or quarter is required"
+		} else {
+			from = *dateFrom
+		}
+		dateTo, err := models.ParseStatementDate(query.Get("date_to"))
+		if err != nil {
+			response.Errors["date_to"] = err.Error()
+		} else if dateTo == nil {
+			response.Errors["date_to"] = "date_to or quarter is required"
+		} else {
+			to = *dateTo
+		}
+		if len(response.Errors) == 0 && from.After(to) {
+			response.Errors["date_from"] = "date_from must not be after date_to"
+		}
+	}
+
+	box := 0
+	if value := query.Get("box"); value != "" {
+		box, err = strconv.Atoi(value)
+		if err != nil || box < models.VATBoxStandardRatedSales || box > models.VATBoxTotalPurchases {
+			response.Errors["box"] = "Invalid box, allowed: 1 to 12"
+		}
+	}
+
+	amounts := map[string]float64{}
+	for _, param := range []string{"corrections", "credit_carried_forward"} {
+		if value := query.Get(param); value != "" {
+			amounts[param], err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				response.Errors[param] = "Invalid amount:" + err.Error()
+			}
+		}
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "pdf" && format != "xlsx" {
+		response.Errors["format"] = "Invalid format, allowed: json, pdf, xlsx"
+	}
+
+	if len(response.Errors) > 0 {
+		response.Status = false
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	vatReturn, err := store.MakeVATReturn(from, to, amounts["corrections"], amounts["credit_carried_forward"])
+	if err != nil {
+		response.Status = false
+		response.Errors["report"] = "Unable to make the VAT return:" + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if box > 0 {
+		vatReturn.FilterDocuments(box)
+	} else if format == "" || format == "json" {
+		vatReturn.Documents = nil
+	}
+
+	filename := "vat_return_" + from.Format("2006-01-02") + "_" + to.Format("2006-01-02")
+
+	switch format {
+	case "pdf":
+		model, err := json.Marshal(vatReturn)
+		if err != nil {
+			response.Status = false
+			response.Errors["report"] = "Unable to encode the VAT return:" + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		pdfBuf, errKey, err := renderReportPDF(printJobData{
+			Model:     model,
+			ModelName: "vat_return",
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			response.Status = false
+			response.Errors[errKey] = err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, filename))
+		w.WriteHeader(http.StatusOK)
+		w.Write(pdfBuf)
+	case "xlsx":
+		var buf bytes.Buffer
+		if err := vatReturn.WriteXLSX(&buf, models.CountryTimezoneOffset(store.CountryCode)); err != nil {
+			response.Status = false
+			response.Errors["report"] = "Unable to write the VAT return:" + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, filename))
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	default:
+		response.Status = true
+		response.Result = vatReturn
+		json.NewEncoder(w).Encode(response)
+	}
+}
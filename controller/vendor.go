@@ -173,6 +173,8 @@ func CreateVendor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, vendor.StoreID, models.AuditActionCreate, "vendor", vendor.ID, vendor.Code, nil, vendor)
+
 	if vendor.OpeningBalance > 0 {
 		vendorStore, err := models.FindStoreByID(vendor.StoreID, bson.M{})
 		if err != nil {
@@ -302,6 +304,8 @@ func UpdateVendor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, vendor.StoreID, models.AuditActionUpdate, "vendor", vendor.ID, vendor.Code, vendorOld, vendor)
+
 	err = vendor.AttributesValueChangeEvent(vendorOld)
 	if err != nil {
 		response.Status = false
@@ -553,6 +557,7 @@ func DeleteVendor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	vendorOld := *vendor
 	err = vendor.DeleteVendor(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -561,6 +566,8 @@ func DeleteVendor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, vendor.StoreID, models.AuditActionDelete, "vendor", vendor.ID, vendor.Code, &vendorOld, vendor)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 
@@ -609,6 +616,7 @@ func RestoreVendor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	vendorOld := *vendor
 	err = vendor.RestoreVendor(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -617,6 +625,8 @@ func RestoreVendor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, vendor.StoreID, models.AuditActionRestore, "vendor", vendor.ID, vendor.Code, &vendorOld, vendor)
+
 	response.Status = true
 	response.Result = "Restored successfully"
 
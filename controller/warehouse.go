@@ -134,6 +134,8 @@ func CreateWarehouse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, warehouse.StoreID, models.AuditActionCreate, "warehouse", warehouse.ID, warehouse.Code, nil, warehouse)
+
 	response.Status = true
 	response.Result = warehouse
 
@@ -235,6 +237,8 @@ func UpdateWarehouse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, warehouse.StoreID, models.AuditActionUpdate, "warehouse", warehouse.ID, warehouse.Code, warehouseOld, warehouse)
+
 	err = warehouse.AttributesValueChangeEvent(warehouseOld)
 	if err != nil {
 		response.Status = false
@@ -359,6 +363,7 @@ func DeleteWarehouse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	warehouseOld := *warehouse
 	err = warehouse.DeleteWarehouse(tokenClaims)
 	if err != nil {
 		response.Status = false
@@ -367,6 +372,8 @@ func DeleteWarehouse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	models.RecordAudit(r, tokenClaims, warehouse.StoreID, models.AuditActionDelete, "warehouse", warehouse.ID, warehouse.Code, &warehouseOld, warehouse)
+
 	response.Status = true
 	response.Result = "Deleted successfully"
 
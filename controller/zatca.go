@@ -1,12 +1,10 @@
 package controller
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
@@ -16,26 +14,12 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/sirinibin/startpos/backend/models"
 	"github.com/sirinibin/startpos/backend/utils"
+	"github.com/sirinibin/startpos/backend/zatca"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// Define a struct to hold the JSON response
-type PythonResponse struct {
-	PrivateKey               string                 `json:"private_key"`
-	Csr                      string                 `json:"csr"`
-	CcsidRequestID           int64                  `json:"ccsid_requestID"`
-	CcsidBinarySecurityToken string                 `json:"ccsid_binarySecurityToken"`
-	CcsidSecret              string                 `json:"ccsid_secret"`
-	PcsidRequestID           int64                  `json:"pcsid_requestID"`
-	PcsidBinarySecurityToken string                 `json:"pcsid_binarySecurityToken"`
-	PcsidSecret              string                 `json:"pcsid_secret"`
-	Error                    string                 `json:"error"`
-	Traceback                string                 `json:"traceback,omitempty"`
-	ComplianceCheck          models.ComplianceCheck `json:"compliance_check"`
-}
-
 // ConnectStoreToZatc : handler for POST /store/zatca/connect
 func ConnectStoreToZatca(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -123,115 +107,34 @@ func ConnectStoreToZatca(w http.ResponseWriter, r *http.Request) {
 	} else {
 		storeAddress = store.Address
 	}
-	// Create JSON payload
-	payload := map[string]interface{}{
-		//"env":               env.Getenv("ZATCA_ENV", "NonProduction"),
-		"env":               store.Zatca.Env,
-		"otp":               zatcaConnectInput.Otp,
-		"crn":               store.RegistrationNumber,
-		"serial_number":     serialNumber,
-		"vat":               store.VATNo,
-		"name":              store.Name,
-		"branch_name":       store.BranchName,
-		"country_code":      countryCode,
-		"invoice_type":      "1100",
-		"address":           storeAddress,
-		"business_category": store.BusinessCategory,
-		"invoice_code":      invoiceCode,
-	}
-
-	// Convert payload to JSON
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		fmt.Println("Error marshalling JSON:", err)
-		response.Status = false
-		response.Errors["marhsallng_json"] = "Error marshalling JSON: " + err.Error()
-		json.NewEncoder(w).Encode(response)
-		return
-	}
-
-	pythonBinary := "ZatcaPython/venv/bin/python"
-	scriptPath := "ZatcaPython/csr_and_onboarding.py"
-
-	// Create command
-	cmd := exec.Command(pythonBinary, scriptPath)
-
-	// Set up pipes — keep stdout and stderr separate so stderr noise doesn't corrupt JSON
-	cmd.Stdin = bytes.NewReader(jsonData) // Send JSON data to stdin
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &stdout // Capture stdout (JSON output from Python)
-	cmd.Stderr = &stderr // Capture stderr separately
-
-	// Run the command
-	err = cmd.Run()
-	if err != nil {
-		fmt.Println("Error running Python script:", err, "stderr:", stderr.String())
-		response.Status = false
-		// Try to parse JSON from stdout first, fall back to stderr
-		jsonBytes := stdout.Bytes()
-		if len(jsonBytes) == 0 {
-			jsonBytes = stderr.Bytes()
-		}
-		var pythonResponse PythonResponse
-		parseErr := json.Unmarshal(jsonBytes, &pythonResponse)
-		if parseErr != nil {
-			response.Errors["otp"] = "Error running zatca script: " + err.Error() + " | " + stderr.String()
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(response)
-			return
-		}
-
-		//log.Print(pythonResponse.Error)
-		if pythonResponse.Error != "" {
-			response.Status = false
-			response.Errors["otp"] = "Error connecting to zatac: " + pythonResponse.Error
-			store.Zatca.ConnectionFailedCount++
-			store.Zatca.ComplianceCheck = pythonResponse.ComplianceCheck
-			now := time.Now()
-			store.Zatca.ConnectionLastFailedAt = &now
-			store.Zatca.ConnectionErrors = append(store.Zatca.ConnectionErrors, "Connection failure1: "+pythonResponse.Error)
-			err = store.Update()
-			if err != nil {
-				fmt.Println("Error saving store: ", err)
-			}
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(response)
-			return
-		}
-		// cmd failed but no error field in JSON — return the raw output as error
-		response.Errors["otp"] = "Zatca script failed: " + err.Error() + " output: " + string(jsonBytes)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
-		return
-	}
-
-	// Parse JSON response from stdout
-	var pythonResponse PythonResponse
-	err = json.Unmarshal(stdout.Bytes(), &pythonResponse)
-	if err != nil {
-		fmt.Println("Error parsing JSON:", err, "stdout:", stdout.String(), "stderr:", stderr.String())
-		response.Status = false
-		response.Errors["otp"] = "Error parsing zatca response: " + err.Error() + " | stdout: " + stdout.String()
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
-		return
-	}
-
-	if pythonResponse.Error != "" {
-		//log.Print(pythonResponse.Error)
-		response.Status = false
-		response.Errors["otp"] = "Error connecting to zatac: " + pythonResponse.Error
+	onboarding, err := zatca.NewClient(store.Zatca.Env).Onboard(zatca.OnboardingInput{
+		Env: store.Zatca.Env,
+		OTP: zatcaConnectInput.Otp,
+		CSR: zatca.CSRConfig{
+			CommonName:       store.RegistrationNumber,
+			SerialNumber:     serialNumber,
+			VATNumber:        store.VATNo,
+			BranchName:       store.BranchName,
+			OrganizationName: store.Name,
+			CountryCode:      countryCode,
+			InvoiceType:      "1100",
+			Address:          storeAddress,
+			BusinessCategory: store.BusinessCategory,
+		},
+		InvoiceCode: invoiceCode,
+	})
+	store.Zatca.ComplianceCheck = models.NewComplianceCheck(onboarding.ComplianceCheck)
+	if err != nil {
+		response.Status = false
+		response.Errors["otp"] = "Error connecting to zatac: " + err.Error()
 		store.Zatca.ConnectionFailedCount++
-		store.Zatca.ComplianceCheck = pythonResponse.ComplianceCheck
 
 		now := time.Now()
 		store.Zatca.ConnectionLastFailedAt = &now
-		store.Zatca.ConnectionErrors = append(store.Zatca.ConnectionErrors, "Connection failure2: "+pythonResponse.Error)
+		store.Zatca.ConnectionErrors = append(store.Zatca.ConnectionErrors, "Connection failure: "+err.Error())
 		err = store.Update()
 		if err != nil {
 			fmt.Println("Error saving store: ", err)
-			return
 		}
 
 		w.WriteHeader(http.StatusInternalServerError)
@@ -239,20 +142,19 @@ func ConnectStoreToZatca(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	store.Zatca.ComplianceCheck = pythonResponse.ComplianceCheck
 	store.Zatca.Otp = zatcaConnectInput.Otp
-	store.Zatca.PrivateKey = pythonResponse.PrivateKey
-	store.Zatca.Csr = pythonResponse.Csr
+	store.Zatca.PrivateKey = onboarding.PrivateKey
+	store.Zatca.Csr = onboarding.CSR
 
 	//compliance
-	store.Zatca.ComplianceRequestID = pythonResponse.CcsidRequestID
-	store.Zatca.BinarySecurityToken = pythonResponse.CcsidBinarySecurityToken
-	store.Zatca.Secret = pythonResponse.CcsidSecret
+	store.Zatca.ComplianceRequestID = onboarding.Compliance.RequestID
+	store.Zatca.BinarySecurityToken = onboarding.Compliance.BinarySecurityToken
+	store.Zatca.Secret = onboarding.Compliance.Secret
 
 	//production
-	store.Zatca.ProductionRequestID = pythonResponse.PcsidRequestID
-	store.Zatca.ProductionBinarySecurityToken = pythonResponse.PcsidBinarySecurityToken
-	store.Zatca.ProductionSecret = pythonResponse.PcsidSecret
+	store.Zatca.ProductionRequestID = onboarding.Production.RequestID
+	store.Zatca.ProductionBinarySecurityToken = onboarding.Production.BinarySecurityToken
+	store.Zatca.ProductionSecret = onboarding.Production.Secret
 
 	if !govalidator.IsNull(store.Zatca.PrivateKey) &&
 		!govalidator.IsNull(store.Zatca.Csr) &&
@@ -279,27 +181,6 @@ func ConnectStoreToZatca(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Print parsed JSON values
-	//fmt.Println("Message from Python:")
-	//log.Print(pythonResponse)
-
-	// Print output
-	//fmt.Println(string(output))
-
-	/*
-		cmd := exec.Command("/Users/sirin/go/src/github.com/sirinibin/ZatcaPython/venv/bin/python", "csr_and_onboarding.py")
-
-		// Capture output
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			fmt.Println("Error:", err)
-			return
-		}
-
-		// Print output
-		fmt.Println(string(output))
-	*/
-
 	response.Status = true
 	//response.Result = store
 
@@ -419,7 +300,6 @@ func ReportOrderToZatca(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(response)
 			return
 		}
-
 	}
 
 	response.Status = true
@@ -548,7 +428,6 @@ func ReportSalesReturnToZatca(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(response)
 			return
 		}
-
 	}
 
 	response.Status = true
@@ -617,27 +496,6 @@ func DisconnectStoreFromZatca(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Print parsed JSON values
-	//fmt.Println("Message from Python:")
-	//log.Print(pythonResponse)
-
-	// Print output
-	//fmt.Println(string(output))
-
-	/*
-		cmd := exec.Command("/Users/sirin/go/src/github.com/sirinibin/ZatcaPython/venv/bin/python", "csr_and_onboarding.py")
-
-		// Capture output
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			fmt.Println("Error:", err)
-			return
-		}
-
-		// Print output
-		fmt.Println(string(output))
-	*/
-
 	response.Status = true
 	//response.Result = store
 
@@ -767,6 +625,119 @@ func ReportCustomerDepositToZatca(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func ReportDebitNoteToZatca(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	tokenClaims, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	params := mux.Vars(r)
+	debitNoteID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["debit_note_id"] = "Invalid Debit Note ID:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	debitNote, err := store.FindDebitNoteByID(&debitNoteID, bson.M{})
+	if err != nil {
+		response.Status = false
+		response.Errors["find_debit_note"] = "Unable to find debit note:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Old records may be missing UUID or ICV (created before ZATCA was added).
+	needsUpdate := false
+	if debitNote.UUID == "" {
+		debitNote.UUID = uuid.New().String()
+		needsUpdate = true
+	}
+	if debitNote.InvoiceCountValue == 0 {
+		if icvErr := debitNote.EnsureInvoiceCountValue(); icvErr != nil {
+			response.Status = false
+			response.Errors["invoice_count_value"] = "Failed to assign ICV: " + icvErr.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		needsUpdate = true
+	}
+	if needsUpdate {
+		_ = debitNote.Update()
+	}
+
+	_, err = primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		response.Status = false
+		response.Errors["user_id"] = "Invalid User ID:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if errs := debitNote.ValidateZatcaReporting(); len(errs) > 0 {
+		response.Status = false
+		response.Errors = errs
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if store.Zatca.Phase == "2" && store.Zatca.Connected {
+		zatcaQueue := GetOrCreateQueue(store.ID.Hex(), "zatca")
+		zatcaQueueToken := generateQueueToken()
+		zatcaQueue.Enqueue(Request{Token: zatcaQueueToken})
+		zatcaQueue.WaitUntilMyTurn(zatcaQueueToken)
+
+		err = debitNote.ReportToZatca()
+		if err != nil {
+			zatcaQueue.Pop()
+			CleanupQueueIfEmpty(store.ID.Hex(), "zatca")
+			_ = debitNote.Update() // persist failure info (reporting_failed_count, reporting_errors)
+			response.Status = false
+			response.Errors["reporting_to_zatca"] = "Error reporting to zatca: " + err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		zatcaQueue.Pop()
+		CleanupQueueIfEmpty(store.ID.Hex(), "zatca")
+
+		err = debitNote.Update()
+		if err != nil {
+			response.Status = false
+			response.Errors["update"] = "Unable to update:" + err.Error()
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	response.Status = true
+	response.Result = debitNote
+	json.NewEncoder(w).Encode(response)
+}
+
 func ReportCustomerWithdrawalToZatca(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	var response models.Response
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirinibin/startpos/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WaitForZatcaTurn waits for the store's turn in the zatca queue the reporting requests go
+// through, and returns the function that ends the turn. The background retries of
+// models.ProcessZatcaQueue take their turn here too.
+func WaitForZatcaTurn(storeID string) func() {
+	zatcaQueue := GetOrCreateQueue(storeID, "zatca")
+	zatcaQueueToken := generateQueueToken()
+	zatcaQueue.Enqueue(Request{Token: zatcaQueueToken})
+	zatcaQueue.WaitUntilMyTurn(zatcaQueueToken)
+
+	return func() {
+		zatcaQueue.Pop()
+		CleanupQueueIfEmpty(storeID, "zatca")
+	}
+}
+
+// ListZatcaQueue : handler for GET /v1/zatca-queue
+func ListZatcaQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	items, criterias, err := store.SearchZatcaQueue(w, r)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find zatca queue items:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Criterias = criterias
+	response.TotalCount, err = store.GetTotalCount(criterias.SearchBy, "zatca_queue")
+	if err != nil {
+		response.Status = false
+		response.Errors["total_count"] = "Unable to find total count of zatca queue items:" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(items) == 0 {
+		response.Result = []interface{}{}
+	} else {
+		response.Result = items
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetZatcaDeadlines : handler for GET /v1/zatca-queue/deadlines
+func GetZatcaDeadlines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	deadlines, err := store.GetZatcaDeadlines(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["deadlines"] = "Unable to find zatca deadlines:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = deadlines
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// RetryZatcaQueueItem : handler function for POST /v1/zatca-queue/<id>/retry call
+func RetryZatcaQueueItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var response models.Response
+	response.Errors = make(map[string]string)
+
+	_, err := models.AuthenticateByAccessToken(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["access_token"] = "Invalid Access token:" + err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	store, err := ParseStore(r)
+	if err != nil {
+		response.Status = false
+		response.Errors["store_id"] = "Invalid store id(parsing 2):" + err.Error()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	params := mux.Vars(r)
+
+	itemID, err := primitive.ObjectIDFromHex(params["id"])
+	if err != nil {
+		response.Status = false
+		response.Errors["item_id"] = "Invalid Item ID:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	err = store.RetryZatcaQueueItem(itemID)
+	if err != nil {
+		response.Status = false
+		response.Errors["retry"] = "Unable to retry:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Items before it in its chain are reported first
+	err = store.ProcessZatcaQueue(WaitForZatcaTurn)
+	if err != nil {
+		log.Print("ProcessZatcaQueue error:", err)
+	}
+
+	item, err := store.FindZatcaQueueItemByID(&itemID)
+	if err != nil {
+		response.Status = false
+		response.Errors["find"] = "Unable to find zatca queue item:" + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response.Status = true
+	response.Result = item
+
+	json.NewEncoder(w).Encode(response)
+}
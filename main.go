@@ -254,11 +254,136 @@ func main() {
 	router.HandleFunc("/v1/warehouse/{id}", controller.UpdateWarehouse).Methods("PUT")
 	router.HandleFunc("/v1/warehouse/{id}", controller.DeleteWarehouse).Methods("DELETE")
 
+	//Price list
+	router.HandleFunc("/v1/price-list", controller.CreatePriceList).Methods("POST")
+	router.HandleFunc("/v1/price-list", controller.ListPriceList).Methods("GET")
+	router.HandleFunc("/v1/price-list/{id}", controller.ViewPriceList).Methods("GET")
+	router.HandleFunc("/v1/price-list/{id}", controller.UpdatePriceList).Methods("PUT")
+	router.HandleFunc("/v1/price-list/{id}", controller.DeletePriceList).Methods("DELETE")
+
+	//Promotion
+	router.HandleFunc("/v1/promotion", controller.CreatePromotion).Methods("POST")
+	router.HandleFunc("/v1/promotion", controller.ListPromotion).Methods("GET")
+	router.HandleFunc("/v1/promotion/{id}", controller.ViewPromotion).Methods("GET")
+	router.HandleFunc("/v1/promotion/{id}", controller.UpdatePromotion).Methods("PUT")
+	router.HandleFunc("/v1/promotion/{id}", controller.DeletePromotion).Methods("DELETE")
+
+	//Cash Register
+	router.HandleFunc("/v1/cash-register", controller.CreateCashRegister).Methods("POST")
+	router.HandleFunc("/v1/cash-register", controller.ListCashRegister).Methods("GET")
+	router.HandleFunc("/v1/cash-register/{id}", controller.ViewCashRegister).Methods("GET")
+	router.HandleFunc("/v1/cash-register/{id}", controller.UpdateCashRegister).Methods("PUT")
+	router.HandleFunc("/v1/cash-register/{id}", controller.DeleteCashRegister).Methods("DELETE")
+
+	//Register Shift
+	router.HandleFunc("/v1/register-shift", controller.OpenRegisterShift).Methods("POST")
+	router.HandleFunc("/v1/register-shift", controller.ListRegisterShift).Methods("GET")
+	router.HandleFunc("/v1/register-shift/{id}", controller.ViewRegisterShift).Methods("GET")
+	router.HandleFunc("/v1/register-shift/{id}/close", controller.CloseRegisterShift).Methods("POST")
+	router.HandleFunc("/v1/register-shift/{id}/report", controller.GetRegisterShiftReport).Methods("GET")
+
+	//Webhook
+	router.HandleFunc("/v1/webhook", controller.CreateWebhook).Methods("POST")
+	router.HandleFunc("/v1/webhook", controller.ListWebhook).Methods("GET")
+	router.HandleFunc("/v1/webhook/{id}", controller.ViewWebhook).Methods("GET")
+	router.HandleFunc("/v1/webhook/{id}", controller.UpdateWebhook).Methods("PUT")
+	router.HandleFunc("/v1/webhook/{id}", controller.DeleteWebhook).Methods("DELETE")
+	router.HandleFunc("/v1/webhook-delivery", controller.ListWebhookDelivery).Methods("GET")
+	router.HandleFunc("/v1/webhook-delivery/{id}/retry", controller.RetryWebhookDelivery).Methods("POST")
+
+	//Audit log
+	router.HandleFunc("/v1/audit", controller.ListAuditLog).Methods("GET")
+	router.HandleFunc("/v1/audit/{id}", controller.ViewAuditLog).Methods("GET")
+
+	//Journal voucher
+	router.HandleFunc("/v1/journal-voucher", controller.CreateJournalVoucher).Methods("POST")
+	router.HandleFunc("/v1/journal-voucher", controller.ListJournalVoucher).Methods("GET")
+	router.HandleFunc("/v1/journal-voucher/{id}", controller.ViewJournalVoucher).Methods("GET")
+	router.HandleFunc("/v1/journal-voucher/{id}", controller.UpdateJournalVoucher).Methods("PUT")
+	router.HandleFunc("/v1/journal-voucher/{id}", controller.DeleteJournalVoucher).Methods("DELETE")
+	router.HandleFunc("/v1/journal-voucher/{id}/approve", controller.ApproveJournalVoucher).Methods("POST")
+	router.HandleFunc("/v1/journal-voucher/{id}/reverse", controller.ReverseJournalVoucher).Methods("POST")
+
+	//Fiscal year
+	router.HandleFunc("/v1/fiscal-year", controller.CreateFiscalYear).Methods("POST")
+	router.HandleFunc("/v1/fiscal-year", controller.ListFiscalYear).Methods("GET")
+	router.HandleFunc("/v1/fiscal-year/lock", controller.GetAccountingLock).Methods("GET")
+	router.HandleFunc("/v1/fiscal-year/{id}", controller.ViewFiscalYear).Methods("GET")
+	router.HandleFunc("/v1/fiscal-year/{id}", controller.DeleteFiscalYear).Methods("DELETE")
+	router.HandleFunc("/v1/fiscal-year/{id}/close", controller.CloseFiscalYear).Methods("POST")
+	router.HandleFunc("/v1/fiscal-year/{id}/reopen", controller.ReopenFiscalYear).Methods("POST")
+	router.HandleFunc("/v1/fiscal-year/{id}/period/{number}/close", controller.CloseFiscalPeriod).Methods("POST")
+	router.HandleFunc("/v1/fiscal-year/{id}/period/{number}/reopen", controller.ReopenFiscalPeriod).Methods("POST")
+
+	//Bank reconciliation
+	router.HandleFunc("/v1/bank-statement/import", controller.ImportBankStatement).Methods("POST")
+	router.HandleFunc("/v1/bank-statement", controller.ListBankStatement).Methods("GET")
+	router.HandleFunc("/v1/bank-statement/{id}", controller.ViewBankStatement).Methods("GET")
+	router.HandleFunc("/v1/bank-statement/{id}", controller.DeleteBankStatement).Methods("DELETE")
+	router.HandleFunc("/v1/bank-statement/{id}/candidates", controller.ListBankStatementCandidates).Methods("GET")
+	router.HandleFunc("/v1/bank-statement/{id}/auto-match", controller.AutoMatchBankStatement).Methods("POST")
+	router.HandleFunc("/v1/bank-statement/{id}/reconciliation", controller.GetBankReconciliation).Methods("GET")
+	router.HandleFunc("/v1/bank-statement/{id}/line/{line_id}/match", controller.MatchBankStatementLine).Methods("POST")
+	router.HandleFunc("/v1/bank-statement/{id}/line/{line_id}/unmatch", controller.UnmatchBankStatementLine).Methods("POST")
+	router.HandleFunc("/v1/bank-statement/{id}/line/{line_id}/split", controller.SplitBankStatementLine).Methods("POST")
+	router.HandleFunc("/v1/bank-statement/{id}/line/{line_id}/create-document", controller.CreateBankStatementLineDocument).Methods("POST")
+
+	//Exchange rate
+	router.HandleFunc("/v1/exchange-rate", controller.CreateExchangeRate).Methods("POST")
+	router.HandleFunc("/v1/exchange-rate", controller.ListExchangeRate).Methods("GET")
+	router.HandleFunc("/v1/exchange-rate/latest", controller.GetLatestExchangeRates).Methods("GET")
+	router.HandleFunc("/v1/exchange-rate/{id}", controller.ViewExchangeRate).Methods("GET")
+	router.HandleFunc("/v1/exchange-rate/{id}", controller.DeleteExchangeRate).Methods("DELETE")
+
+	//Cost center
+	router.HandleFunc("/v1/cost-center", controller.CreateCostCenter).Methods("POST")
+	router.HandleFunc("/v1/cost-center", controller.ListCostCenter).Methods("GET")
+	router.HandleFunc("/v1/cost-center/{id}", controller.ViewCostCenter).Methods("GET")
+	router.HandleFunc("/v1/cost-center/{id}", controller.UpdateCostCenter).Methods("PUT")
+	router.HandleFunc("/v1/cost-center/{id}", controller.DeleteCostCenter).Methods("DELETE")
+
+	//Recurring template
+	router.HandleFunc("/v1/recurring-template", controller.CreateRecurringTemplate).Methods("POST")
+	router.HandleFunc("/v1/recurring-template", controller.ListRecurringTemplate).Methods("GET")
+	router.HandleFunc("/v1/recurring-template/preview", controller.PreviewNewRecurringTemplate).Methods("POST")
+	router.HandleFunc("/v1/recurring-template/{id}", controller.ViewRecurringTemplate).Methods("GET")
+	router.HandleFunc("/v1/recurring-template/{id}", controller.UpdateRecurringTemplate).Methods("PUT")
+	router.HandleFunc("/v1/recurring-template/{id}", controller.DeleteRecurringTemplate).Methods("DELETE")
+	router.HandleFunc("/v1/recurring-template/{id}/preview", controller.PreviewRecurringTemplate).Methods("GET")
+
+	//Budget
+	router.HandleFunc("/v1/budget", controller.CreateBudget).Methods("POST")
+	router.HandleFunc("/v1/budget", controller.ListBudget).Methods("GET")
+	router.HandleFunc("/v1/budget/{id}", controller.ViewBudget).Methods("GET")
+	router.HandleFunc("/v1/budget/{id}", controller.UpdateBudget).Methods("PUT")
+	router.HandleFunc("/v1/budget/{id}", controller.DeleteBudget).Methods("DELETE")
+	router.HandleFunc("/v1/budget/{id}/variance", controller.GetBudgetVariance).Methods("GET")
+	router.HandleFunc("/v1/budget-alert", controller.ListBudgetAlert).Methods("GET")
+
+	//Fixed asset
+	router.HandleFunc("/v1/fixed-asset", controller.CreateFixedAsset).Methods("POST")
+	router.HandleFunc("/v1/fixed-asset", controller.ListFixedAsset).Methods("GET")
+	router.HandleFunc("/v1/fixed-asset/{id}", controller.ViewFixedAsset).Methods("GET")
+	router.HandleFunc("/v1/fixed-asset/{id}", controller.UpdateFixedAsset).Methods("PUT")
+	router.HandleFunc("/v1/fixed-asset/{id}", controller.DeleteFixedAsset).Methods("DELETE")
+	router.HandleFunc("/v1/fixed-asset/{id}/dispose", controller.DisposeFixedAsset).Methods("POST")
+	router.HandleFunc("/v1/report/fixed-asset-register", controller.GetFixedAssetRegister).Methods("GET")
+
+	//Debit note
+	router.HandleFunc("/v1/debit-note", controller.CreateDebitNote).Methods("POST")
+	router.HandleFunc("/v1/debit-note", controller.ListDebitNote).Methods("GET")
+	router.HandleFunc("/v1/debit-note/{id}", controller.ViewDebitNote).Methods("GET")
+	router.HandleFunc("/v1/debit-note/{id}", controller.UpdateDebitNote).Methods("PUT")
+	router.HandleFunc("/v1/debit-note/{id}", controller.DeleteDebitNote).Methods("DELETE")
+
 	//Customer
 	router.HandleFunc("/v1/customer/summary", controller.CustomerSummary).Methods("GET")
 	router.HandleFunc("/v1/customer", controller.CreateCustomer).Methods("POST")
+	router.HandleFunc("/v1/customer/import", controller.ImportCustomer).Methods("POST")
+	router.HandleFunc("/v1/customer/import/progress", controller.GetImportProgress).Methods("GET")
 	router.HandleFunc("/v1/customer", controller.ListCustomer).Methods("GET")
 	router.HandleFunc("/v1/customer/{id}/history", controller.GetCustomerHistory).Methods("GET")
+	router.HandleFunc("/v1/customer/{id}/loyalty-points", controller.GetCustomerLoyaltyPoints).Methods("GET")
 	router.HandleFunc("/v1/customer/{id}", controller.ViewCustomer).Methods("GET")
 	router.HandleFunc("/v1/customer/{id}", controller.UpdateCustomer).Methods("PUT")
 	router.HandleFunc("/v1/customer/{id}", controller.DeleteCustomer).Methods("DELETE")
@@ -271,9 +396,13 @@ func main() {
 	router.HandleFunc("/v1/product/summary", controller.ProductSummary).Methods("GET")
 	router.HandleFunc("/v1/product/migrate-rack", controller.MigrateProductRackToWarehouseRacks).Methods("POST")
 	router.HandleFunc("/v1/product", controller.CreateProduct).Methods("POST")
+	router.HandleFunc("/v1/product/import", controller.ImportProduct).Methods("POST")
+	router.HandleFunc("/v1/product/import/progress", controller.GetImportProgress).Methods("GET")
 	router.HandleFunc("/v1/product", controller.ListProduct).Methods("GET")
 	router.HandleFunc("/v1/product/json", controller.ListProductJson).Methods("GET")
 	router.HandleFunc("/v1/product/{id}/last-purchase-price", controller.GetProductLastPurchasePrice).Methods("GET")
+	router.HandleFunc("/v1/product/{id}/serials", controller.ListProductSerials).Methods("GET")
+	router.HandleFunc("/v1/product/serial/{serial}", controller.ViewSerialTrail).Methods("GET")
 	router.HandleFunc("/v1/product/{id}", controller.ViewProduct).Methods("GET")
 	router.HandleFunc("/v1/product/code/{code}", controller.ViewProductByItemCode).Methods("GET")
 	router.HandleFunc("/v1/product/barcode/{barcode}", controller.ViewProductByBarCode).Methods("GET")
@@ -296,6 +425,8 @@ func main() {
 	router.HandleFunc("/v1/bi/expense-summary", controller.GetBIExpenseSummary).Methods("GET")
 	router.HandleFunc("/v1/bi/outstanding", controller.GetBIOutstanding).Methods("GET")
 	router.HandleFunc("/v1/bi/stock-alerts", controller.GetBIStockAlerts).Methods("GET")
+	router.HandleFunc("/v1/bi/expiring-stock", controller.GetBIExpiringStock).Methods("GET")
+	router.HandleFunc("/v1/bi/promotion-uplift", controller.GetBIPromotionUplift).Methods("GET")
 	router.HandleFunc("/v1/bi/vendor-performance", controller.GetBIVendorPerformance).Methods("GET")
 	router.HandleFunc("/v1/bi/quotation-conversion", controller.GetBIQuotationConversion).Methods("GET")
 	router.HandleFunc("/v1/bi/product-abc-xyz", controller.GetBIProductAbcXyz).Methods("GET")
@@ -575,6 +706,8 @@ func main() {
 	//Vendor
 	router.HandleFunc("/v1/vendor/summary", controller.VendorSummary).Methods("GET")
 	router.HandleFunc("/v1/vendor", controller.CreateVendor).Methods("POST")
+	router.HandleFunc("/v1/vendor/import", controller.ImportVendor).Methods("POST")
+	router.HandleFunc("/v1/vendor/import/progress", controller.GetImportProgress).Methods("GET")
 	router.HandleFunc("/v1/vendor", controller.ListVendor).Methods("GET")
 	router.HandleFunc("/v1/vendor/{id}", controller.ViewVendor).Methods("GET")
 	router.HandleFunc("/v1/vendor/vat_no/name", controller.ViewVendorByVatNoByName).Methods("GET")
@@ -677,7 +810,11 @@ func main() {
 	router.HandleFunc("/v1/sales-return/zatca/report/{id}", controller.ReportSalesReturnToZatca).Methods("POST")
 	router.HandleFunc("/v1/customer-deposit/zatca/report/{id}", controller.ReportCustomerDepositToZatca).Methods("POST")
 	router.HandleFunc("/v1/customer-withdrawal/zatca/report/{id}", controller.ReportCustomerWithdrawalToZatca).Methods("POST")
+	router.HandleFunc("/v1/debit-note/zatca/report/{id}", controller.ReportDebitNoteToZatca).Methods("POST")
 	router.HandleFunc("/v1/store/zatca/disconnect", controller.DisconnectStoreFromZatca).Methods("POST")
+	router.HandleFunc("/v1/zatca-queue", controller.ListZatcaQueue).Methods("GET")
+	router.HandleFunc("/v1/zatca-queue/deadlines", controller.GetZatcaDeadlines).Methods("GET")
+	router.HandleFunc("/v1/zatca-queue/{id}/retry", controller.RetryZatcaQueueItem).Methods("POST")
 
 	//Ledger
 	router.HandleFunc("/v1/ledger", controller.ListLedger).Methods("GET")
@@ -785,6 +922,15 @@ func main() {
 	router.HandleFunc("/v1/report/pdf", controller.ReportPDF).Methods("POST")
 	router.HandleFunc("/v1/report/print-data/{key}", controller.ReportPrintData).Methods("GET")
 
+	//Financial statements
+	router.HandleFunc("/v1/report/trial-balance", controller.GetTrialBalance).Methods("GET")
+	router.HandleFunc("/v1/report/balance-sheet", controller.GetBalanceSheet).Methods("GET")
+	router.HandleFunc("/v1/report/income-statement", controller.GetIncomeStatement).Methods("GET")
+	router.HandleFunc("/v1/report/cost-center-income-statement", controller.GetCostCenterIncomeStatement).Methods("GET")
+
+	//VAT return
+	router.HandleFunc("/v1/report/vat-return", controller.GetVATReturn).Methods("GET")
+
 	// Desktop app: serve React build as SPA from STATIC_DIR env var (e.g. ./public)
 	// Must be registered LAST — PathPrefix("/") catches everything else
 	staticDir := env.Getenv("STATIC_DIR", "")
@@ -832,16 +978,44 @@ func main() {
 	s.Every(1).Hour().Do(func() {
 		go models.SyncWhatsAppContactsForAllStores()
 	})
+	s.Every(1).Minute().Do(func() {
+		if err := models.ProcessWebhookDeliveries(); err != nil {
+			log.Print("ProcessWebhookDeliveries error:", err)
+		}
+	})
+	s.Every(1).Minute().Do(func() {
+		if err := models.ProcessZatcaQueueForAllStores(controller.WaitForZatcaTurn); err != nil {
+			log.Print("ProcessZatcaQueueForAllStores error:", err)
+		}
+	})
 	s.Every(1).Hour().Do(func() {
 		if err := models.ProcessScheduledPermanentDeletions(); err != nil {
 			log.Printf("[store-cleanup] error: %v", err)
 		}
 	})
+	s.Every(1).Hour().Do(func() {
+		if err := models.ProcessRecurringTemplatesForAllStores(); err != nil {
+			log.Print("ProcessRecurringTemplatesForAllStores error:", err)
+		}
+	})
+	s.Every(1).Hour().Do(func() {
+		if err := models.ProcessBudgetAlertsForAllStores(); err != nil {
+			log.Print("ProcessBudgetAlertsForAllStores error:", err)
+		}
+	})
+	s.Every(1).Hour().Do(func() {
+		if err := models.ProcessFixedAssetDepreciationForAllStores(); err != nil {
+			log.Print("ProcessFixedAssetDepreciationForAllStores error:", err)
+		}
+	})
 	s.StartAsync()
 
 	// Sync WhatsApp contacts at startup so they're immediately available
 	go models.SyncWhatsAppContactsForAllStores()
 
+	// Queue the ZATCA reporting failures from before the queue existed
+	go models.QueueUnreportedZatcaDocumentsForAllStores()
+
 	// Dashboard analytics: start the dirty-month worker, drain any persisted dirty
 	// months from a previous crash, then clear old data and backfill from scratch.
 	models.StartDashboardDirtyWorker()
@@ -163,7 +163,7 @@ func (account *Account) CalculateBalance(beforeDate *time.Time, beforeID *primit
 		}
 	}
 
-	if account.Type == "asset" || account.Type == "liability" {
+	if (account.Type == "asset" || account.Type == "liability") && !account.IsContraAsset() {
 		if stats.CreditTotal > stats.DebitTotal {
 			account.Type = "liability" //creditor
 		} else if stats.CreditTotal < stats.DebitTotal {
@@ -173,14 +173,7 @@ func (account *Account) CalculateBalance(beforeDate *time.Time, beforeID *primit
 
 	// Infer type from name for system accounts that predate the type field being set.
 	if account.Type == "" && account.ReferenceModel == nil {
-		switch account.Name {
-		case "CASH", "BANK":
-			account.Type = "asset"
-		case "SALES", "NON VAT SALES", "PURCHASE RETURN", "CASH DISCOUNT RECEIVED":
-			account.Type = "revenue"
-		case "SALES RETURN", "NON VAT SALES RETURN", "PURCHASE", "CASH DISCOUNT ALLOWED", "COMMISSION ALLOWED", "SALARY EXPENSE":
-			account.Type = "expense"
-		}
+		account.Type = systemAccountType(account.Name)
 	}
 
 	if account.Type == "drawing" || account.Type == "expense" || account.Type == "asset" {
@@ -188,6 +181,9 @@ func (account *Account) CalculateBalance(beforeDate *time.Time, beforeID *primit
 	} else if account.Type == "liability" || account.Type == "capital" || account.Type == "revenue" {
 		account.DebitOrCreditBalance = "credit_balance"
 	}
+	if account.IsContraAsset() && stats.CreditTotal > stats.DebitTotal {
+		account.DebitOrCreditBalance = "credit_balance"
+	}
 
 	if account.Balance == 0 {
 		account.Open = false
@@ -591,6 +587,22 @@ func (store *Store) CreateAccountIfNotExists(
 		account.Type = "expense"
 	} else if referenceModel == nil && (name == "SALARY EXPENSE") {
 		account.Type = "expense"
+	} else if referenceModel == nil && (name == "LOYALTY POINTS LIABILITY") {
+		account.Type = "liability"
+	} else if referenceModel == nil && (name == "LOYALTY POINTS EXPENSE") {
+		account.Type = "expense"
+	} else if referenceModel == nil && (name == RetainedEarningsAccountName) {
+		account.Type = "capital"
+	} else if referenceModel == nil && (name == ExchangeGainAccountName) {
+		account.Type = "revenue"
+	} else if referenceModel == nil && (name == ExchangeLossAccountName) {
+		account.Type = "expense"
+	} else if referenceModel == nil && (name == FixedAssetsAccountName || name == AccumulatedDepreciationAccountName) {
+		account.Type = "asset"
+	} else if referenceModel == nil && (name == DepreciationExpenseAccountName || name == AssetDisposalLossAccountName) {
+		account.Type = "expense"
+	} else if referenceModel == nil && (name == AssetDisposalGainAccountName) {
+		account.Type = "revenue"
 	}
 
 	//account = &accountModel
@@ -0,0 +1,443 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/db"
+	"github.com/sirinibin/startpos/backend/env"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+
+	// An admin saved a document dated in a closed accounting period
+	AuditActionLockOverride = "lock_override"
+
+	auditMaxValueLength = 500
+)
+
+// Bookkeeping fields that change on every save and would only add noise to a diff.
+var auditIgnoredFields = map[string]bool{
+	"updated_at":          true,
+	"updated_by":          true,
+	"updated_by_name":     true,
+	"updated_by_user":     true,
+	"search_label":        true,
+	"additional_keywords": true,
+	"images_content":      true,
+	"photo_content":       true,
+	"change_log":          true,
+}
+
+// AuditLog : one mutating request against a document. Entries are only ever inserted.
+type AuditLog struct {
+	ID         primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Entity     string              `json:"entity" bson:"entity"`
+	EntityID   primitive.ObjectID  `json:"entity_id" bson:"entity_id"`
+	EntityCode string              `json:"entity_code,omitempty" bson:"entity_code,omitempty"`
+	Action     string              `json:"action" bson:"action"`
+	Changes    []AuditChange       `json:"changes" bson:"changes"`
+	ActorID    *primitive.ObjectID `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	ActorName  string              `json:"actor_name,omitempty" bson:"actor_name,omitempty"`
+	ActorEmail string              `json:"actor_email,omitempty" bson:"actor_email,omitempty"`
+	IP         string              `json:"ip" bson:"ip"`
+	UserAgent  string              `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	Method     string              `json:"method" bson:"method"`
+	Path       string              `json:"path" bson:"path"`
+	StoreID    *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	CreatedAt  *time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+}
+
+// AuditChange is the before/after value of one field, addressed by its dotted JSON path
+// (e.g. products.0.unit_price).
+type AuditChange struct {
+	Field string      `json:"field" bson:"field"`
+	Old   interface{} `json:"old" bson:"old"`
+	New   interface{} `json:"new" bson:"new"`
+}
+
+// RequestIP returns the client IP. The proxy headers are only honoured when the request
+// comes from a reverse proxy listed in TRUSTED_PROXIES (IPs or CIDRs, comma separated),
+// as anyone else can set them.
+func RequestIP(r *http.Request) string {
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+
+	trusted := trustedProxies()
+	if !isTrustedProxy(remoteIP, trusted) {
+		return remoteIP
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		// the client is the last hop not added by one of our proxies
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop != "" && (i == 0 || !isTrustedProxy(hop, trusted)) {
+				return hop
+			}
+		}
+	}
+	if realIP := r.Header.Get("X-Real-Ip"); realIP != "" {
+		return strings.TrimSpace(realIP)
+	}
+	return remoteIP
+}
+
+// trustedProxies are the networks of the reverse proxies in TRUSTED_PROXIES.
+func trustedProxies() (networks []*net.IPNet) {
+	for _, value := range strings.Split(env.Getenv("TRUSTED_PROXIES", ""), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			log.Printf("[audit] invalid TRUSTED_PROXIES entry %q: %v", value, err)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func isTrustedProxy(address string, trusted []*net.IPNet) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffAuditFields compares two documents field by field through their JSON form.
+// A nil before (create) lists every non-empty field of after; missing and zero values are treated alike.
+func DiffAuditFields(before, after interface{}) ([]AuditChange, error) {
+	oldFields, err := flattenAuditDocument(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flattenAuditDocument(after)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]bool{}
+	for field := range oldFields {
+		fields[field] = true
+	}
+	for field := range newFields {
+		fields[field] = true
+	}
+	sortedFields := make([]string, 0, len(fields))
+	for field := range fields {
+		sortedFields = append(sortedFields, field)
+	}
+	sort.Strings(sortedFields)
+
+	changes := []AuditChange{}
+	for _, field := range sortedFields {
+		oldValue, newValue := oldFields[field], newFields[field]
+		if isAuditZero(oldValue) && isAuditZero(newValue) {
+			continue
+		}
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if isAuditSecretField(field) {
+			oldValue, newValue = "[redacted]", "[redacted]"
+		}
+		changes = append(changes, AuditChange{
+			Field: field,
+			Old:   truncateAuditValue(oldValue),
+			New:   truncateAuditValue(newValue),
+		})
+	}
+
+	return changes, nil
+}
+
+func flattenAuditDocument(doc interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if doc == nil {
+		return fields, nil
+	}
+	if value := reflect.ValueOf(doc); value.Kind() == reflect.Ptr && value.IsNil() {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	flattenAuditValue("", decoded, fields)
+	return fields, nil
+}
+
+func flattenAuditValue(path string, value interface{}, fields map[string]interface{}) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			if path == "" && auditIgnoredFields[key] {
+				continue
+			}
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			flattenAuditValue(childPath, child, fields)
+		}
+	case []interface{}:
+		for i, child := range typed {
+			flattenAuditValue(path+"."+strconv.Itoa(i), child, fields)
+		}
+	default:
+		if path != "" && typed != nil {
+			fields[path] = typed
+		}
+	}
+}
+
+func isAuditZero(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case string:
+		return typed == ""
+	case float64:
+		return typed == 0
+	case bool:
+		return !typed
+	}
+	return false
+}
+
+func isAuditSecretField(field string) bool {
+	name := strings.ToLower(field[strings.LastIndex(field, ".")+1:])
+	return name == "key" || strings.Contains(name, "private_key") ||
+		strings.Contains(name, "password") || strings.Contains(name, "secret") || strings.Contains(name, "token")
+}
+
+func truncateAuditValue(value interface{}) interface{} {
+	if text, ok := value.(string); ok && len(text) > auditMaxValueLength {
+		return text[:auditMaxValueLength] + "..."
+	}
+	return value
+}
+
+// AuditRequest is what an audit entry keeps of the request that made the change.
+// Work that outlives the handler, like an import job, takes it before the handler returns.
+type AuditRequest struct {
+	IP        string
+	UserAgent string
+	Method    string
+	Path      string
+}
+
+func NewAuditRequest(r *http.Request) AuditRequest {
+	return AuditRequest{
+		IP:        RequestIP(r),
+		UserAgent: r.UserAgent(),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+	}
+}
+
+// RecordAudit writes an audit entry for a create/update/delete/restore made by the request.
+// The diff is taken immediately so later changes to before/after don't leak into it;
+// the actor lookup and insert run in the background. Updates that change nothing are not logged.
+func RecordAudit(
+	r *http.Request,
+	tokenClaims TokenClaims,
+	storeID *primitive.ObjectID,
+	action string,
+	entity string,
+	entityID primitive.ObjectID,
+	entityCode string,
+	before, after interface{},
+) {
+	NewAuditRequest(r).Record(tokenClaims, storeID, action, entity, entityID, entityCode, before, after)
+}
+
+// Record is RecordAudit for a request that has already been answered.
+func (request AuditRequest) Record(
+	tokenClaims TokenClaims,
+	storeID *primitive.ObjectID,
+	action string,
+	entity string,
+	entityID primitive.ObjectID,
+	entityCode string,
+	before, after interface{},
+) {
+	if storeID == nil || storeID.IsZero() {
+		return
+	}
+
+	changes, err := DiffAuditFields(before, after)
+	if err != nil {
+		log.Printf("[audit] %s %s %s: error diffing: %v", action, entity, entityID.Hex(), err)
+		return
+	}
+	if action == AuditActionUpdate && len(changes) == 0 {
+		return
+	}
+
+	now := time.Now()
+	auditLog := AuditLog{
+		ID:         primitive.NewObjectID(),
+		Entity:     entity,
+		EntityID:   entityID,
+		EntityCode: entityCode,
+		Action:     action,
+		Changes:    changes,
+		IP:         request.IP,
+		UserAgent:  request.UserAgent,
+		Method:     request.Method,
+		Path:       request.Path,
+		StoreID:    storeID,
+		CreatedAt:  &now,
+	}
+
+	go func() {
+		if userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID); err == nil {
+			auditLog.ActorID = &userID
+			if user, err := FindUserByID(&userID, bson.M{"id": 1, "name": 1, "email": 1}); err == nil {
+				auditLog.ActorName = user.Name
+				auditLog.ActorEmail = user.Email
+			}
+		}
+
+		if err := auditLog.Insert(); err != nil {
+			log.Printf("[audit] %s %s %s: error inserting: %v", action, entity, entityID.Hex(), err)
+		}
+	}()
+}
+
+func (auditLog *AuditLog) Insert() error {
+	collection := db.GetDB("store_" + auditLog.StoreID.Hex()).Collection("audit_log")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := collection.InsertOne(ctx, &auditLog)
+	return err
+}
+
+func (store *Store) SearchAuditLog(w http.ResponseWriter, r *http.Request) (auditLogs []AuditLog, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+	timeZoneOffset := CountryTimezoneOffset(store.CountryCode)
+
+	for _, field := range []string{"entity", "action"} {
+		keys, ok := r.URL.Query()["search["+field+"]"]
+		if ok && len(keys[0]) >= 1 {
+			criterias.SearchBy[field] = keys[0]
+		}
+	}
+
+	ParseTextSearch(r, &criterias, "search[entity_code]", "entity_code")
+	ParseTextSearch(r, &criterias, "search[actor_name]", "actor_name")
+	ParseTextSearch(r, &criterias, "search[ip]", "ip")
+	ParseTextSearch(r, &criterias, "search[field]", "changes.field")
+
+	if err = ParseObjectIDFilter(r, &criterias, "search[entity_id]", "entity_id"); err != nil {
+		return auditLogs, criterias, err
+	}
+
+	if err = ParseObjectIDListFilter(r, &criterias, "search[actor_id]", "actor_id"); err != nil {
+		return auditLogs, criterias, err
+	}
+
+	if err = ParseDateRangeFilter(r, &criterias, "search[created_at_from]", "search[created_at_to]", "created_at", timeZoneOffset); err != nil {
+		return auditLogs, criterias, err
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("audit_log")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	keys, ok := r.URL.Query()["select"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.Select = ParseSelectString(keys[0])
+	}
+
+	if criterias.Select != nil {
+		findOptions.SetProjection(criterias.Select)
+	}
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return auditLogs, criterias, errors.New("Error fetching audit logs:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return auditLogs, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		auditLog := AuditLog{}
+		err = cur.Decode(&auditLog)
+		if err != nil {
+			return auditLogs, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		auditLogs = append(auditLogs, auditLog)
+	}
+
+	return auditLogs, criterias, nil
+}
+
+func (store *Store) FindAuditLogByID(ID *primitive.ObjectID) (auditLog *AuditLog, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("audit_log")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = collection.FindOne(ctx, bson.M{"_id": ID}).Decode(&auditLog)
+	if err != nil {
+		return nil, err
+	}
+	return auditLog, nil
+}
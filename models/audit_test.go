@@ -0,0 +1,142 @@
+package models
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+type auditTestLine struct {
+	Name      string  `json:"name"`
+	UnitPrice float64 `json:"unit_price"`
+}
+
+type auditTestDoc struct {
+	Code      string          `json:"code"`
+	Total     float64         `json:"total"`
+	Remarks   string          `json:"remarks,omitempty"`
+	Deleted   bool            `json:"deleted"`
+	Password  string          `json:"password,omitempty"`
+	UpdatedAt string          `json:"updated_at,omitempty"`
+	Products  []auditTestLine `json:"products"`
+}
+
+func findAuditChange(changes []AuditChange, field string) *AuditChange {
+	for i := range changes {
+		if changes[i].Field == field {
+			return &changes[i]
+		}
+	}
+	return nil
+}
+
+// ── DiffAuditFields ──────────────────────────────────────────────────────────
+
+func TestDiffAuditFields_ReportsChangedFieldsOnly(t *testing.T) {
+	before := auditTestDoc{Code: "S-1", Total: 100, UpdatedAt: "a", Products: []auditTestLine{{Name: "Oil", UnitPrice: 50}}}
+	after := auditTestDoc{Code: "S-1", Total: 120, UpdatedAt: "b", Products: []auditTestLine{{Name: "Oil", UnitPrice: 60}}}
+
+	changes, err := DiffAuditFields(&before, &after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("changes = %+v, want total and products.0.unit_price", changes)
+	}
+	if c := findAuditChange(changes, "products.0.unit_price"); c == nil || c.Old != 50.0 || c.New != 60.0 {
+		t.Errorf("products.0.unit_price change = %+v, want 50 -> 60", c)
+	}
+	if findAuditChange(changes, "updated_at") != nil {
+		t.Error("updated_at should be ignored")
+	}
+}
+
+func TestDiffAuditFields_MissingAndZeroAreEqual(t *testing.T) {
+	changes, err := DiffAuditFields(map[string]interface{}{"remarks": ""}, auditTestDoc{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("changes = %+v, want none", changes)
+	}
+}
+
+func TestDiffAuditFields_CreateListsNonEmptyFields(t *testing.T) {
+	var before *auditTestDoc
+	changes, err := DiffAuditFields(before, auditTestDoc{Code: "S-2", Deleted: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Field != "code" || changes[0].Old != nil || changes[0].New != "S-2" {
+		t.Errorf("changes = %+v, want only code nil -> S-2", changes)
+	}
+}
+
+func TestDiffAuditFields_RedactsSecrets(t *testing.T) {
+	changes, err := DiffAuditFields(auditTestDoc{Password: "old-hash"}, auditTestDoc{Password: "new-hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := findAuditChange(changes, "password")
+	if c == nil || c.Old != "[redacted]" || c.New != "[redacted]" {
+		t.Errorf("password change = %+v, want a redacted change", c)
+	}
+}
+
+func TestIsAuditSecretField_Keys(t *testing.T) {
+	for _, field := range []string{"zatca.private_key", "key", "api.key", "webhook.secret", "zatca.binary_security_token"} {
+		if !isAuditSecretField(field) {
+			t.Errorf("isAuditSecretField(%q) = false, want true", field)
+		}
+	}
+	for _, field := range []string{"name", "products.0.item_code", "keyword"} {
+		if isAuditSecretField(field) {
+			t.Errorf("isAuditSecretField(%q) = true, want false", field)
+		}
+	}
+}
+
+func TestDiffAuditFields_RemovedArrayItem(t *testing.T) {
+	before := auditTestDoc{Products: []auditTestLine{{Name: "Oil"}, {Name: "Filter"}}}
+	after := auditTestDoc{Products: []auditTestLine{{Name: "Oil"}}}
+
+	changes, err := DiffAuditFields(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := findAuditChange(changes, "products.1.name"); c == nil || c.Old != "Filter" || c.New != nil {
+		t.Errorf("products.1.name change = %+v, want Filter -> nil", c)
+	}
+}
+
+// ── RequestIP ────────────────────────────────────────────────────────────────
+
+func TestRequestIP(t *testing.T) {
+	r := httptest.NewRequest("POST", "/v1/order", nil)
+	r.RemoteAddr = "10.0.0.5:51234"
+	if got := RequestIP(r); got != "10.0.0.5" {
+		t.Errorf("RequestIP = %q, want 10.0.0.5", got)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+	r.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.1")
+	if got := RequestIP(r); got != "203.0.113.9" {
+		t.Errorf("RequestIP with X-Forwarded-For = %q, want 203.0.113.9", got)
+	}
+
+	// a forged hop before the one our proxy saw is ignored
+	r.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.9")
+	if got := RequestIP(r); got != "203.0.113.9" {
+		t.Errorf("RequestIP with a forged hop = %q, want 203.0.113.9", got)
+	}
+}
+
+func TestRequestIP_UntrustedProxyHeadersIgnored(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1")
+	r := httptest.NewRequest("POST", "/v1/order", nil)
+	r.RemoteAddr = "203.0.113.9:51234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	r.Header.Set("X-Real-Ip", "198.51.100.1")
+	if got := RequestIP(r); got != "203.0.113.9" {
+		t.Errorf("RequestIP = %q, want the remote address 203.0.113.9", got)
+	}
+}
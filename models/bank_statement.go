@@ -0,0 +1,922 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/db"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	BankLineStatusUnmatched = "unmatched"
+	BankLineStatusMatched   = "matched"
+	BankLineStatusSplit     = "split"
+
+	BankMatchAuto   = "auto"
+	BankMatchManual = "manual"
+
+	BankMatchDefaultToleranceDays = 3
+	BankMatchMaxToleranceDays     = 31
+)
+
+// BankStatement : a statement imported from the bank for one bank ledger account (BANK by default).
+// Each line is reconciled with one or more postings of that account; a line can also be split into
+// parts that are reconciled separately.
+type BankStatement struct {
+	ID             primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	AccountID      primitive.ObjectID  `json:"account_id" bson:"account_id"`
+	AccountName    string              `json:"account_name" bson:"account_name"`
+	StatementNo    string              `json:"statement_no" bson:"statement_no"`
+	BankAccountNo  string              `json:"bank_account_no,omitempty" bson:"bank_account_no,omitempty"` // IBAN or account number given in the file
+	Currency       string              `json:"currency,omitempty" bson:"currency,omitempty"`
+	Format         string              `json:"format" bson:"format"`
+	Filename       string              `json:"filename" bson:"filename"`
+	OpeningBalance *float64            `json:"opening_balance" bson:"opening_balance"`
+	ClosingBalance *float64            `json:"closing_balance" bson:"closing_balance"`
+	FromDate       *time.Time          `json:"from_date" bson:"from_date"`
+	ToDate         *time.Time          `json:"to_date" bson:"to_date"`
+	Lines          []BankStatementLine `json:"lines" bson:"lines"`
+	TotalIn        float64             `json:"total_in" bson:"total_in"`
+	TotalOut       float64             `json:"total_out" bson:"total_out"`
+	MatchedCount   int                 `json:"matched_count" bson:"matched_count"`
+	UnmatchedCount int                 `json:"unmatched_count" bson:"unmatched_count"`
+	StoreID        *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName      string              `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted        bool                `bson:"deleted" json:"deleted"`
+	DeletedBy      *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt      *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt      *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt      *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy      *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy      *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName  string              `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName  string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+}
+
+// BankStatementLine : one transaction on the statement. Amount is signed: positive is money into the bank.
+type BankStatementLine struct {
+	ID            primitive.ObjectID   `json:"id" bson:"_id"`
+	ParentID      *primitive.ObjectID  `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	Date          *time.Time           `json:"date" bson:"date"`
+	ValueDate     *time.Time           `json:"value_date,omitempty" bson:"value_date,omitempty"`
+	Amount        float64              `json:"amount" bson:"amount"`
+	Description   string               `json:"description" bson:"description"`
+	Reference     string               `json:"reference,omitempty" bson:"reference,omitempty"`
+	Counterparty  string               `json:"counterparty,omitempty" bson:"counterparty,omitempty"`
+	Status        string               `json:"status" bson:"status"`
+	Matches       []BankStatementMatch `json:"matches,omitempty" bson:"matches,omitempty"`
+	MatchedBy     string               `json:"matched_by,omitempty" bson:"matched_by,omitempty"`
+	MatchedByUser *primitive.ObjectID  `json:"matched_by_user,omitempty" bson:"matched_by_user,omitempty"`
+	MatchedAt     *time.Time           `json:"matched_at,omitempty" bson:"matched_at,omitempty"`
+}
+
+// BankStatementMatch : a posting of the bank account. Amount is signed like the statement line (debit - credit).
+type BankStatementMatch struct {
+	PostingID      primitive.ObjectID `json:"posting_id" bson:"posting_id"`
+	ReferenceID    primitive.ObjectID `json:"reference_id" bson:"reference_id"`
+	ReferenceModel string             `json:"reference_model" bson:"reference_model"`
+	ReferenceCode  string             `json:"reference_code" bson:"reference_code"`
+	Date           *time.Time         `json:"date" bson:"date"`
+	Amount         float64            `json:"amount" bson:"amount"`
+}
+
+// IsLeaf is false for a line that was split; its parts are reconciled instead.
+func (line *BankStatementLine) IsLeaf() bool {
+	return line.Status != BankLineStatusSplit
+}
+
+// ConvertDatesToUTC moves the statement days (parsed as UTC midnight) to local midnight of the store
+// and sets the statement period from the first to the end of the last day.
+func (statement *BankStatement) ConvertDatesToUTC(timeZoneOffset float64) {
+	convert := func(date *time.Time) *time.Time {
+		if date == nil {
+			return nil
+		}
+		converted := ConvertTimeZoneToUTC(timeZoneOffset, *date)
+		return &converted
+	}
+
+	for i := range statement.Lines {
+		statement.Lines[i].Date = convert(statement.Lines[i].Date)
+		statement.Lines[i].ValueDate = convert(statement.Lines[i].ValueDate)
+	}
+	if len(statement.Lines) > 0 {
+		statement.FromDate = statement.Lines[0].Date
+		toDate := statement.Lines[len(statement.Lines)-1].Date.Add(24*time.Hour - time.Second)
+		statement.ToDate = &toDate
+	}
+}
+
+func (statement *BankStatement) FindTotals() {
+	statement.TotalIn = 0
+	statement.TotalOut = 0
+	statement.MatchedCount = 0
+	statement.UnmatchedCount = 0
+	for _, line := range statement.Lines {
+		if line.ParentID == nil {
+			if line.Amount > 0 {
+				statement.TotalIn += line.Amount
+			} else {
+				statement.TotalOut -= line.Amount
+			}
+		}
+		if !line.IsLeaf() {
+			continue
+		}
+		if line.Status == BankLineStatusMatched {
+			statement.MatchedCount++
+		} else {
+			statement.UnmatchedCount++
+		}
+	}
+	statement.TotalIn = RoundTo2Decimals(statement.TotalIn)
+	statement.TotalOut = RoundTo2Decimals(statement.TotalOut)
+}
+
+// FindLineIndex returns the index of the line or -1.
+func (statement *BankStatement) FindLineIndex(lineID primitive.ObjectID) int {
+	for i, line := range statement.Lines {
+		if line.ID == lineID {
+			return i
+		}
+	}
+	return -1
+}
+
+// MatchedPostingIDs are the postings already reconciled on this statement.
+func (statement *BankStatement) MatchedPostingIDs() map[primitive.ObjectID]bool {
+	used := map[primitive.ObjectID]bool{}
+	for _, line := range statement.Lines {
+		for _, match := range line.Matches {
+			used[match.PostingID] = true
+		}
+	}
+	return used
+}
+
+// bankMatchDayDistance is the number of local days between the posting and the nearer of the line's
+// booking and value date. Line dates are local midnight.
+func bankMatchDayDistance(line *BankStatementLine, entry *BankStatementMatch) int {
+	distance := math.MaxInt32
+	for _, date := range []*time.Time{line.Date, line.ValueDate} {
+		if date == nil || entry.Date == nil {
+			continue
+		}
+		days := int(math.Abs(math.Floor(entry.Date.Sub(*date).Hours() / 24)))
+		if days < distance {
+			distance = days
+		}
+	}
+	return distance
+}
+
+// bankMatchReferenceHit is true when the document code of the posting appears in the line's reference or description.
+func bankMatchReferenceHit(line *BankStatementLine, entry *BankStatementMatch) bool {
+	code := strings.ToLower(strings.TrimSpace(entry.ReferenceCode))
+	if len(code) < 3 {
+		return false
+	}
+	return strings.Contains(strings.ToLower(line.Reference), code) || strings.Contains(strings.ToLower(line.Description), code)
+}
+
+// pickBankMatch returns the single best candidate for the line or -1 when there is none or it is ambiguous.
+// With byReference only candidates whose code appears on the line are considered.
+func pickBankMatch(line *BankStatementLine, entries []BankStatementMatch, used map[primitive.ObjectID]bool, toleranceDays int, byReference bool) int {
+	best, bestDistance, tie := -1, math.MaxInt32, false
+	for i := range entries {
+		entry := &entries[i]
+		if used[entry.PostingID] || RoundTo2Decimals(entry.Amount) != RoundTo2Decimals(line.Amount) {
+			continue
+		}
+		if byReference && !bankMatchReferenceHit(line, entry) {
+			continue
+		}
+		distance := bankMatchDayDistance(line, entry)
+		if !byReference && distance > toleranceDays {
+			continue
+		}
+		if distance < bestDistance {
+			best, bestDistance, tie = i, distance, false
+		} else if distance == bestDistance {
+			tie = true
+		}
+	}
+	if tie {
+		return -1
+	}
+	return best
+}
+
+// AutoMatchBankLines matches unmatched lines to postings of exactly the same amount. A posting whose
+// document code appears on the line wins regardless of date; otherwise the posting nearest in date within
+// toleranceDays is taken. Lines with two equally good candidates are left for manual matching.
+// used holds the postings already reconciled and is updated. It returns the number of lines matched.
+func AutoMatchBankLines(lines []BankStatementLine, entries []BankStatementMatch, used map[primitive.ObjectID]bool, toleranceDays int, now *time.Time) (matched int) {
+	for _, byReference := range []bool{true, false} {
+		for i := range lines {
+			line := &lines[i]
+			if line.Status != BankLineStatusUnmatched {
+				continue
+			}
+			index := pickBankMatch(line, entries, used, toleranceDays, byReference)
+			if index < 0 {
+				continue
+			}
+			used[entries[index].PostingID] = true
+			line.Matches = []BankStatementMatch{entries[index]}
+			line.Status = BankLineStatusMatched
+			line.MatchedBy = BankMatchAuto
+			line.MatchedByUser = nil
+			line.MatchedAt = now
+			matched++
+		}
+	}
+	return matched
+}
+
+// MatchLine reconciles the line with the given postings manually; their amounts must add up to the line amount.
+func (statement *BankStatement) MatchLine(lineID primitive.ObjectID, entries []BankStatementMatch, userID primitive.ObjectID, now *time.Time) error {
+	index := statement.FindLineIndex(lineID)
+	if index < 0 {
+		return errors.New("line not found")
+	}
+	line := &statement.Lines[index]
+	if line.Status != BankLineStatusUnmatched {
+		return errors.New("line is " + line.Status + ", unmatch it first")
+	}
+	if len(entries) == 0 {
+		return errors.New("select at least one posting")
+	}
+
+	used := statement.MatchedPostingIDs()
+	total := 0.0
+	for _, entry := range entries {
+		if used[entry.PostingID] {
+			return errors.New("posting of " + entry.ReferenceCode + " is already matched to another line")
+		}
+		used[entry.PostingID] = true
+		total += entry.Amount
+	}
+	if RoundTo2Decimals(total) != RoundTo2Decimals(line.Amount) {
+		return fmt.Errorf("selected postings total %.2f, the line amount is %.2f", RoundTo2Decimals(total), line.Amount)
+	}
+
+	line.Matches = entries
+	line.Status = BankLineStatusMatched
+	line.MatchedBy = BankMatchManual
+	line.MatchedByUser = &userID
+	line.MatchedAt = now
+	return nil
+}
+
+// UnmatchLine clears the matches of a line. Unmatching a split line merges its parts back,
+// provided none of them is matched.
+func (statement *BankStatement) UnmatchLine(lineID primitive.ObjectID) error {
+	index := statement.FindLineIndex(lineID)
+	if index < 0 {
+		return errors.New("line not found")
+	}
+	line := &statement.Lines[index]
+
+	switch line.Status {
+	case BankLineStatusMatched:
+		line.Matches = nil
+		line.Status = BankLineStatusUnmatched
+		line.MatchedBy = ""
+		line.MatchedByUser = nil
+		line.MatchedAt = nil
+	case BankLineStatusSplit:
+		lines := []BankStatementLine{}
+		for _, part := range statement.Lines {
+			if part.ParentID != nil && *part.ParentID == lineID {
+				if part.Status != BankLineStatusUnmatched {
+					return errors.New("unmatch the parts of the split line first")
+				}
+				continue
+			}
+			lines = append(lines, part)
+		}
+		statement.Lines = lines
+		statement.Lines[statement.FindLineIndex(lineID)].Status = BankLineStatusUnmatched
+	default:
+		return errors.New("line is not matched")
+	}
+	return nil
+}
+
+// SplitLine divides an unmatched line into parts (e.g. one transfer paying several invoices).
+// The parts must have the sign of the line and add up to its amount.
+func (statement *BankStatement) SplitLine(lineID primitive.ObjectID, amounts []float64) error {
+	index := statement.FindLineIndex(lineID)
+	if index < 0 {
+		return errors.New("line not found")
+	}
+	line := statement.Lines[index]
+	if line.Status != BankLineStatusUnmatched {
+		return errors.New("line is " + line.Status + ", unmatch it first")
+	}
+	if line.ParentID != nil {
+		return errors.New("a part of a split line cannot be split again, unmatch the split line and split it anew")
+	}
+	if len(amounts) < 2 {
+		return errors.New("split into at least two parts")
+	}
+
+	parentID := line.ID
+	total := 0.0
+	parts := []BankStatementLine{}
+	for _, amount := range amounts {
+		amount = RoundTo2Decimals(amount)
+		if amount == 0 || (amount > 0) != (line.Amount > 0) {
+			return errors.New("every part must be non-zero and have the sign of the line amount")
+		}
+		total += amount
+
+		part := line
+		part.ID = primitive.NewObjectID()
+		part.ParentID = &parentID
+		part.Amount = amount
+		parts = append(parts, part)
+	}
+	if RoundTo2Decimals(total) != line.Amount {
+		return fmt.Errorf("parts total %.2f, the line amount is %.2f", RoundTo2Decimals(total), line.Amount)
+	}
+
+	statement.Lines[index].Status = BankLineStatusSplit
+	lines := append([]BankStatementLine{}, statement.Lines[:index+1]...)
+	lines = append(lines, parts...)
+	statement.Lines = append(lines, statement.Lines[index+1:]...)
+	return nil
+}
+
+func (statement *BankStatement) Validate(w http.ResponseWriter, r *http.Request, scenario string) (errs map[string]string) {
+	errs = make(map[string]string)
+
+	store, err := FindStoreByID(statement.StoreID, bson.M{})
+	if err != nil {
+		errs["store_id"] = "Invalid store id:" + err.Error()
+		return errs
+	}
+
+	account, err := store.FindAccountByID(statement.AccountID, bson.M{})
+	if err != nil || account == nil || account.Deleted {
+		errs["account_id"] = "Invalid bank account"
+	} else {
+		statement.AccountName = account.Name
+	}
+
+	if len(statement.Lines) == 0 {
+		errs["file"] = "Statement has no transactions"
+	}
+
+	if statement.StatementNo != "" && errs["account_id"] == "" {
+		exists, err := statement.IsStatementNoExists()
+		if err != nil {
+			errs["statement_no"] = err.Error()
+		} else if exists {
+			errs["statement_no"] = "Statement " + statement.StatementNo + " is already imported for " + statement.AccountName
+		}
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	return errs
+}
+
+func (statement *BankStatement) IsStatementNoExists() (exists bool, err error) {
+	collection := db.GetDB("store_" + statement.StoreID.Hex()).Collection("bank_statement")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := collection.CountDocuments(ctx, bson.M{
+		"_id":          bson.M{"$ne": statement.ID},
+		"account_id":   statement.AccountID,
+		"statement_no": statement.StatementNo,
+		"from_date":    statement.FromDate,
+		"deleted":      bson.M{"$ne": true},
+	})
+	return count > 0, err
+}
+
+func (statement *BankStatement) UpdateForeignLabelFields() error {
+	if statement.StoreID != nil {
+		store, err := FindStoreByID(statement.StoreID, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		statement.StoreName = store.Name
+	}
+
+	if statement.CreatedBy != nil {
+		createdByUser, err := FindUserByID(statement.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		statement.CreatedByName = createdByUser.Name
+	}
+
+	if statement.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(statement.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		statement.UpdatedByName = updatedByUser.Name
+	}
+
+	return nil
+}
+
+func (statement *BankStatement) Insert() error {
+	collection := db.GetDB("store_" + statement.StoreID.Hex()).Collection("bank_statement")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	statement.ID = primitive.NewObjectID()
+
+	err := statement.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	statement.FindTotals()
+	_, err = collection.InsertOne(ctx, &statement)
+	return err
+}
+
+func (statement *BankStatement) Update() error {
+	collection := db.GetDB("store_" + statement.StoreID.Hex()).Collection("bank_statement")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := statement.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	statement.FindTotals()
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": statement.ID},
+		bson.M{"$set": statement},
+		updateOptions,
+	)
+	return err
+}
+
+func (statement *BankStatement) DeleteBankStatement(tokenClaims TokenClaims) (err error) {
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	statement.Deleted = true
+	statement.DeletedBy = &userID
+	now := time.Now()
+	statement.DeletedAt = &now
+
+	return statement.Update()
+}
+
+func (store *Store) FindBankStatementByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (statement *BankStatement, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("bank_statement")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{
+			"_id":      ID,
+			"store_id": store.ID,
+		}, findOneOptions).
+		Decode(&statement)
+	if err != nil {
+		return nil, err
+	}
+
+	return statement, err
+}
+
+func (store *Store) SearchBankStatement(w http.ResponseWriter, r *http.Request) (statements []BankStatement, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+	timeZoneOffset := CountryTimezoneOffset(store.CountryCode)
+
+	ParseDeletedFilter(r, &criterias)
+	ParseTextSearch(r, &criterias, "search[statement_no]", "statement_no")
+	ParseTextSearch(r, &criterias, "search[filename]", "filename")
+
+	keys, ok := r.URL.Query()["search[format]"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.SearchBy["format"] = keys[0]
+	}
+
+	if err = ParseObjectIDFilter(r, &criterias, "search[account_id]", "account_id"); err != nil {
+		return statements, criterias, err
+	}
+
+	if err = ParseDateRangeFilter(r, &criterias, "search[date_from]", "search[date_to]", "to_date", timeZoneOffset); err != nil {
+		return statements, criterias, err
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("bank_statement")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	keys, ok = r.URL.Query()["select"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.Select = ParseSelectString(keys[0])
+	}
+
+	// Lines are only sent when asked for, statements can be long
+	if criterias.Select != nil {
+		findOptions.SetProjection(criterias.Select)
+	} else {
+		findOptions.SetProjection(bson.M{"lines": 0})
+	}
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return statements, criterias, errors.New("Error fetching bank statements:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return statements, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		statement := BankStatement{}
+		err = cur.Decode(&statement)
+		if err != nil {
+			return statements, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		statements = append(statements, statement)
+	}
+
+	return statements, criterias, nil
+}
+
+// ── Book side ────────────────────────────────────────────────────────────────
+
+// FindBankAccountPostings returns the postings of the bank account dated from..to (either may be nil),
+// or only those with the given IDs when postingIDs is not empty.
+func (store *Store) FindBankAccountPostings(accountID primitive.ObjectID, from, to *time.Time, postingIDs []primitive.ObjectID) (entries []BankStatementMatch, err error) {
+	filter := bson.M{"account_id": accountID}
+	if len(postingIDs) > 0 {
+		filter["_id"] = bson.M{"$in": postingIDs}
+	}
+	dateFilter := bson.M{}
+	if from != nil {
+		dateFilter["$gte"] = from
+	}
+	if to != nil {
+		dateFilter["$lte"] = to
+	}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+	return store.findBankAccountPostings(filter)
+}
+
+// FindBankAccountPostingsByReference returns the postings a document left on the bank account.
+func (store *Store) FindBankAccountPostingsByReference(accountID primitive.ObjectID, referenceID primitive.ObjectID) (entries []BankStatementMatch, err error) {
+	return store.findBankAccountPostings(bson.M{"account_id": accountID, "reference_id": referenceID})
+}
+
+func (store *Store) findBankAccountPostings(filter bson.M) (entries []BankStatementMatch, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("posting")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.M{"date": 1})
+	findOptions.SetProjection(bson.M{"posts": 0})
+
+	cur, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, errors.New("Error fetching postings:" + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	entries = []BankStatementMatch{}
+	for cur.Next(ctx) {
+		posting := Posting{}
+		if err := cur.Decode(&posting); err != nil {
+			return nil, errors.New("Cursor decode error:" + err.Error())
+		}
+		entries = append(entries, BankStatementMatch{
+			PostingID:      posting.ID,
+			ReferenceID:    posting.ReferenceID,
+			ReferenceModel: posting.ReferenceModel,
+			ReferenceCode:  posting.ReferenceCode,
+			Date:           posting.Date,
+			Amount:         RoundTo2Decimals(posting.DebitTotal - posting.CreditTotal),
+		})
+	}
+	return entries, cur.Err()
+}
+
+// FindReconciledPostingIDs returns the postings of the account matched on any statement except exceptID.
+func (store *Store) FindReconciledPostingIDs(accountID primitive.ObjectID, exceptID *primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("bank_statement")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"account_id":               accountID,
+		"deleted":                  bson.M{"$ne": true},
+		"lines.matches.posting_id": bson.M{"$exists": true},
+	}
+	if exceptID != nil {
+		filter["_id"] = bson.M{"$ne": exceptID}
+	}
+
+	cur, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"lines.matches.posting_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	reconciled := map[primitive.ObjectID]bool{}
+	for cur.Next(ctx) {
+		statement := BankStatement{}
+		if err := cur.Decode(&statement); err != nil {
+			return nil, err
+		}
+		for postingID := range statement.MatchedPostingIDs() {
+			reconciled[postingID] = true
+		}
+	}
+	return reconciled, cur.Err()
+}
+
+// FindUnreconciledBankPostings returns the postings of the statement's account dated within the statement
+// period widened by toleranceDays that are matched on no statement yet. These are the candidates for matching.
+func (statement *BankStatement) FindUnreconciledBankPostings(store *Store, toleranceDays int) ([]BankStatementMatch, error) {
+	from := statement.FromDate.AddDate(0, 0, -toleranceDays)
+	to := statement.ToDate.AddDate(0, 0, toleranceDays)
+	entries, err := store.FindBankAccountPostings(statement.AccountID, &from, &to, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	reconciled, err := store.FindReconciledPostingIDs(statement.AccountID, &statement.ID)
+	if err != nil {
+		return nil, err
+	}
+	for postingID := range statement.MatchedPostingIDs() {
+		reconciled[postingID] = true
+	}
+
+	unreconciled := []BankStatementMatch{}
+	for _, entry := range entries {
+		if !reconciled[entry.PostingID] {
+			unreconciled = append(unreconciled, entry)
+		}
+	}
+	return unreconciled, nil
+}
+
+// FindBankBookBalance returns debit - credit of the account up to and including date.
+func (store *Store) FindBankBookBalance(accountID primitive.ObjectID, date *time.Time) (float64, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("posting")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"account_id": accountID, "date": bson.M{"$lte": date}}},
+		{"$group": bson.M{
+			"_id":          nil,
+			"debit_total":  bson.M{"$sum": "$debit_total"},
+			"credit_total": bson.M{"$sum": "$credit_total"},
+		}},
+	}
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	stats := AccountStats{}
+	if cur.Next(ctx) {
+		if err := cur.Decode(&stats); err != nil {
+			return 0, err
+		}
+	}
+	return RoundTo2Decimals(stats.DebitTotal - stats.CreditTotal), cur.Err()
+}
+
+// ── Reconciliation report ────────────────────────────────────────────────────
+
+// BankReconciliation : statement balance vs book balance at the end of the statement, explained by the
+// statement lines not yet booked and the postings the bank has not cleared yet.
+type BankReconciliation struct {
+	StatementID           primitive.ObjectID   `json:"statement_id"`
+	StatementNo           string               `json:"statement_no"`
+	AccountID             primitive.ObjectID   `json:"account_id"`
+	AccountName           string               `json:"account_name"`
+	StoreName             string               `json:"store_name"`
+	FromDate              *time.Time           `json:"from_date"`
+	ToDate                *time.Time           `json:"to_date"`
+	OpeningBalance        *float64             `json:"opening_balance"`
+	StatementBalance      float64              `json:"statement_balance"`
+	BookBalance           float64              `json:"book_balance"`
+	UnmatchedLines        []BankStatementLine  `json:"unmatched_lines"`
+	UnmatchedLinesTotal   float64              `json:"unmatched_lines_total"`
+	UnmatchedEntries      []BankStatementMatch `json:"unmatched_entries"`
+	UnmatchedEntriesTotal float64              `json:"unmatched_entries_total"`
+	AdjustedBookBalance   float64              `json:"adjusted_book_balance"`
+	Difference            float64              `json:"difference"`
+	MatchedCount          int                  `json:"matched_count"`
+	UnmatchedCount        int                  `json:"unmatched_count"`
+	CreatedAt             time.Time            `json:"created_at"`
+}
+
+// BuildBankReconciliation reconciles the statement with the book balance at its end date.
+// unmatchedEntries are the unreconciled postings of the period. Lines matched to postings missing
+// from livePostings (documents deleted after matching) count as unmatched; a nil map skips that check.
+func BuildBankReconciliation(statement *BankStatement, bookBalance float64, unmatchedEntries []BankStatementMatch, livePostings map[primitive.ObjectID]bool) *BankReconciliation {
+	reconciliation := &BankReconciliation{
+		StatementID:      statement.ID,
+		StatementNo:      statement.StatementNo,
+		AccountID:        statement.AccountID,
+		AccountName:      statement.AccountName,
+		StoreName:        statement.StoreName,
+		FromDate:         statement.FromDate,
+		ToDate:           statement.ToDate,
+		OpeningBalance:   statement.OpeningBalance,
+		BookBalance:      bookBalance,
+		UnmatchedLines:   []BankStatementLine{},
+		UnmatchedEntries: unmatchedEntries,
+		CreatedAt:        time.Now(),
+	}
+	if reconciliation.UnmatchedEntries == nil {
+		reconciliation.UnmatchedEntries = []BankStatementMatch{}
+	}
+
+	movement := 0.0
+	for _, line := range statement.Lines {
+		if line.ParentID == nil {
+			movement += line.Amount
+		}
+		if !line.IsLeaf() {
+			continue
+		}
+
+		matched := line.Status == BankLineStatusMatched
+		if matched && livePostings != nil {
+			for _, match := range line.Matches {
+				if !livePostings[match.PostingID] {
+					matched = false
+				}
+			}
+		}
+		if matched {
+			reconciliation.MatchedCount++
+			continue
+		}
+		reconciliation.UnmatchedCount++
+		reconciliation.UnmatchedLines = append(reconciliation.UnmatchedLines, line)
+		reconciliation.UnmatchedLinesTotal += line.Amount
+	}
+
+	for _, entry := range reconciliation.UnmatchedEntries {
+		reconciliation.UnmatchedEntriesTotal += entry.Amount
+	}
+
+	if statement.ClosingBalance != nil {
+		reconciliation.StatementBalance = *statement.ClosingBalance
+	} else if statement.OpeningBalance != nil {
+		reconciliation.StatementBalance = *statement.OpeningBalance + movement
+	} else {
+		reconciliation.StatementBalance = movement
+	}
+
+	reconciliation.StatementBalance = RoundTo2Decimals(reconciliation.StatementBalance)
+	reconciliation.UnmatchedLinesTotal = RoundTo2Decimals(reconciliation.UnmatchedLinesTotal)
+	reconciliation.UnmatchedEntriesTotal = RoundTo2Decimals(reconciliation.UnmatchedEntriesTotal)
+	reconciliation.AdjustedBookBalance = RoundTo2Decimals(bookBalance - reconciliation.UnmatchedEntriesTotal + reconciliation.UnmatchedLinesTotal)
+	reconciliation.Difference = RoundTo2Decimals(reconciliation.StatementBalance - reconciliation.AdjustedBookBalance)
+	return reconciliation
+}
+
+// MakeBankReconciliation loads the book side of the statement and builds the reconciliation.
+func (store *Store) MakeBankReconciliation(statement *BankStatement) (*BankReconciliation, error) {
+	bookBalance, err := store.FindBankBookBalance(statement.AccountID, statement.ToDate)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := store.FindBankAccountPostings(statement.AccountID, statement.FromDate, statement.ToDate, nil)
+	if err != nil {
+		return nil, err
+	}
+	reconciled, err := store.FindReconciledPostingIDs(statement.AccountID, nil)
+	if err != nil {
+		return nil, err
+	}
+	unmatchedEntries := []BankStatementMatch{}
+	for _, entry := range entries {
+		if !reconciled[entry.PostingID] {
+			unmatchedEntries = append(unmatchedEntries, entry)
+		}
+	}
+
+	matchedIDs := []primitive.ObjectID{}
+	for postingID := range statement.MatchedPostingIDs() {
+		matchedIDs = append(matchedIDs, postingID)
+	}
+	livePostings := map[primitive.ObjectID]bool{}
+	if len(matchedIDs) > 0 {
+		live, err := store.FindBankAccountPostings(statement.AccountID, nil, nil, matchedIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range live {
+			livePostings[entry.PostingID] = true
+		}
+	}
+
+	return BuildBankReconciliation(statement, bookBalance, unmatchedEntries, livePostings), nil
+}
+
+func (reconciliation *BankReconciliation) WriteXLSX(w io.Writer, timeZoneOffset float64) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	row := 1
+	setRow := func(values ...interface{}) {
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		f.SetSheetRow(sheet, cell, &values)
+		row++
+	}
+	localDate := func(date *time.Time) string {
+		if date == nil {
+			return ""
+		}
+		return ConvertTimeZoneToUTC(-timeZoneOffset, *date).Format("Jan 02 2006")
+	}
+
+	setRow(reconciliation.StoreName)
+	setRow("Bank Reconciliation", reconciliation.AccountName)
+	setRow("Statement", reconciliation.StatementNo, localDate(reconciliation.FromDate)+" - "+localDate(reconciliation.ToDate))
+	row++
+
+	setRow("Balance as per statement", "", "", reconciliation.StatementBalance)
+	setRow("Balance as per books", "", "", reconciliation.BookBalance)
+	setRow("Less: postings not on the statement", "", "", -reconciliation.UnmatchedEntriesTotal)
+	setRow("Add: statement lines not in the books", "", "", reconciliation.UnmatchedLinesTotal)
+	setRow("Adjusted book balance", "", "", reconciliation.AdjustedBookBalance)
+	setRow("Difference", "", "", reconciliation.Difference)
+	row++
+
+	setRow("STATEMENT LINES NOT IN THE BOOKS")
+	setRow("Date", "Description", "Reference", "Amount")
+	for _, line := range reconciliation.UnmatchedLines {
+		setRow(localDate(line.Date), line.Description, line.Reference, line.Amount)
+	}
+	setRow("", "Total", "", reconciliation.UnmatchedLinesTotal)
+	row++
+
+	setRow("POSTINGS NOT ON THE STATEMENT")
+	setRow("Date", "Document", "Reference", "Amount")
+	for _, entry := range reconciliation.UnmatchedEntries {
+		setRow(localDate(entry.Date), entry.ReferenceModel, entry.ReferenceCode, entry.Amount)
+	}
+	setRow("", "Total", "", reconciliation.UnmatchedEntriesTotal)
+
+	f.SetColWidth(sheet, "A", "A", 36)
+	f.SetColWidth(sheet, "B", "B", 40)
+	f.SetColWidth(sheet, "C", "C", 20)
+	return f.Write(w)
+}
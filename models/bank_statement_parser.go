@@ -0,0 +1,636 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	BankStatementFormatCSV     = "csv"
+	BankStatementFormatMT940   = "mt940"
+	BankStatementFormatCAMT053 = "camt053"
+)
+
+// bankStatementDateFormats are tried in order; day-first formats win over month-first ones
+// because that is what the banks in the region export.
+var bankStatementDateFormats = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"02/01/2006",
+	"02-01-2006",
+	"02.01.2006",
+	"2006/01/02",
+	"02/01/06",
+	"Jan 02 2006",
+	"02 Jan 2006",
+	"02-Jan-2006",
+	"01/02/2006",
+}
+
+// DetectBankStatementFormat guesses the format from the file extension and content.
+func DetectBankStatementFormat(filename string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", ".xlsx":
+		return BankStatementFormatCSV
+	case ".xml":
+		return BankStatementFormatCAMT053
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		return BankStatementFormatCAMT053
+	}
+	if bytes.Contains(trimmed, []byte(":20:")) && bytes.Contains(trimmed, []byte(":61:")) {
+		return BankStatementFormatMT940
+	}
+	return ""
+}
+
+// ParseBankStatementFile parses an uploaded statement. The mapping is used by CSV/XLSX files only.
+func ParseBankStatementFile(filename string, format string, data []byte, mapping ImportMapping) (*BankStatement, error) {
+	if format == "" {
+		format = DetectBankStatementFormat(filename, data)
+	}
+
+	var statement *BankStatement
+	var err error
+	switch format {
+	case BankStatementFormatCSV:
+		statement, err = ParseBankStatementCSV(filename, data, mapping)
+	case BankStatementFormatMT940:
+		statement, err = ParseMT940(data)
+	case BankStatementFormatCAMT053:
+		statement, err = ParseCAMT053(data)
+	default:
+		return nil, errors.New("unsupported statement format, upload a .csv, .xlsx, MT940 or CAMT.053 file")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(statement.Lines) == 0 {
+		return nil, errors.New("statement has no transactions")
+	}
+	if len(statement.Lines) > ImportMaxRows {
+		return nil, errors.New("statement has more than " + strconv.Itoa(ImportMaxRows) + " transactions")
+	}
+
+	statement.Format = format
+	statement.Filename = filename
+	sort.SliceStable(statement.Lines, func(i, j int) bool {
+		return statement.Lines[i].Date.Before(*statement.Lines[j].Date)
+	})
+	statement.FromDate = statement.Lines[0].Date
+	statement.ToDate = statement.Lines[len(statement.Lines)-1].Date
+	for i := range statement.Lines {
+		statement.Lines[i].ID = primitive.NewObjectID()
+		statement.Lines[i].Amount = RoundTo2Decimals(statement.Lines[i].Amount)
+		statement.Lines[i].Status = BankLineStatusUnmatched
+	}
+	return statement, nil
+}
+
+// ParseBankStatementDate parses a statement date (a calendar day, returned as UTC midnight).
+func ParseBankStatementDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, format := range bankStatementDateFormats {
+		date, err := time.Parse(format, value)
+		if err == nil {
+			date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+			return &date, nil
+		}
+	}
+	// Excel serial date
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 20000 && serial < 80000 {
+		date := time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(serial))
+		return &date, nil
+	}
+	return nil, errors.New("invalid date: " + value)
+}
+
+// ParseBankStatementAmount parses amounts like "1,234.50", "-12.5", "(12.50)", "12.50-" or "1234,50".
+func ParseBankStatementAmount(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = strings.Trim(value, "()")
+	}
+	if strings.HasSuffix(value, "-") {
+		negative = true
+		value = strings.TrimSuffix(value, "-")
+	}
+
+	var cleaned strings.Builder
+	for _, c := range value {
+		if (c >= '0' && c <= '9') || c == '.' || c == ',' || c == '-' {
+			cleaned.WriteRune(c)
+		}
+	}
+	number := cleaned.String()
+
+	// The last of "." and "," is the decimal separator when both are present. A lone comma is a
+	// decimal separator unless it is followed by exactly three digits ("1,234").
+	lastDot := strings.LastIndex(number, ".")
+	lastComma := strings.LastIndex(number, ",")
+	decimalComma := lastComma > lastDot && (lastDot >= 0 || strings.Count(number, ",") == 1 && len(number)-lastComma-1 != 3)
+	if decimalComma {
+		number = strings.ReplaceAll(number, ".", "")
+		number = strings.Replace(number, ",", ".", 1)
+	} else {
+		number = strings.ReplaceAll(number, ",", "")
+	}
+
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, errors.New("invalid amount: " + value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// ── CSV / XLSX ───────────────────────────────────────────────────────────────
+
+// ParseBankStatementCSV reads a .csv or .xlsx export. Amounts come either from one signed
+// amount column or from separate debit (money out) and credit (money in) columns.
+func ParseBankStatementCSV(filename string, data []byte, mapping ImportMapping) (*BankStatement, error) {
+	sheet, err := ParseImportFile(filename, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	columns, errs := ResolveImportColumns(ImportEntityBankStatement, sheet.Headers, mapping)
+	if len(errs) > 0 {
+		messages := []string{}
+		for _, message := range errs {
+			messages = append(messages, message)
+		}
+		sort.Strings(messages)
+		return nil, errors.New(strings.Join(messages, ", "))
+	}
+
+	_, hasAmount := columns["amount"]
+	_, hasDebit := columns["debit"]
+	_, hasCredit := columns["credit"]
+	if !hasAmount && !hasDebit && !hasCredit {
+		return nil, errors.New("map an amount column or debit/credit columns")
+	}
+
+	cell := func(row []string, key string) string {
+		index, ok := columns[key]
+		if !ok {
+			return ""
+		}
+		return ImportCell(row, index)
+	}
+
+	statement := &BankStatement{}
+	balances := []float64{}
+	for i, row := range sheet.Rows {
+		if IsImportRowEmpty(row) {
+			continue
+		}
+		rowNumber := strconv.Itoa(i + 2)
+
+		line := BankStatementLine{
+			Description:  cell(row, "description"),
+			Reference:    cell(row, "reference"),
+			Counterparty: cell(row, "counterparty"),
+		}
+
+		line.Date, err = ParseBankStatementDate(cell(row, "date"))
+		if err != nil {
+			return nil, errors.New("row " + rowNumber + ": " + err.Error())
+		}
+		if value := cell(row, "value_date"); value != "" {
+			line.ValueDate, err = ParseBankStatementDate(value)
+			if err != nil {
+				return nil, errors.New("row " + rowNumber + ": " + err.Error())
+			}
+		}
+
+		if hasAmount && cell(row, "amount") != "" {
+			line.Amount, err = ParseBankStatementAmount(cell(row, "amount"))
+			if err != nil {
+				return nil, errors.New("row " + rowNumber + ": " + err.Error())
+			}
+		} else {
+			debit, err := ParseBankStatementAmount(cell(row, "debit"))
+			if err != nil {
+				return nil, errors.New("row " + rowNumber + ": " + err.Error())
+			}
+			credit, err := ParseBankStatementAmount(cell(row, "credit"))
+			if err != nil {
+				return nil, errors.New("row " + rowNumber + ": " + err.Error())
+			}
+			// some banks export debits as negative numbers
+			line.Amount = credit - math.Abs(debit)
+		}
+
+		if line.Amount == 0 {
+			continue
+		}
+
+		if value := cell(row, "balance"); value != "" {
+			balance, err := ParseBankStatementAmount(value)
+			if err != nil {
+				return nil, errors.New("row " + rowNumber + ": " + err.Error())
+			}
+			balances = append(balances, balance)
+		}
+		statement.Lines = append(statement.Lines, line)
+	}
+
+	// Running balance column: the first row gives the opening balance, the last one the closing balance.
+	// Statements listed newest first are detected by the running balance not adding up.
+	if len(balances) == len(statement.Lines) && len(balances) > 0 {
+		first, last := 0, len(balances)-1
+		if len(balances) > 1 && RoundTo2Decimals(balances[0]+statement.Lines[1].Amount) != RoundTo2Decimals(balances[1]) {
+			first, last = last, first
+		}
+		opening := RoundTo2Decimals(balances[first] - statement.Lines[first].Amount)
+		closing := RoundTo2Decimals(balances[last])
+		statement.OpeningBalance = &opening
+		statement.ClosingBalance = &closing
+	}
+
+	return statement, nil
+}
+
+// ── MT940 ────────────────────────────────────────────────────────────────────
+
+var (
+	mt940TagPattern     = regexp.MustCompile(`^:(\d{2}[A-Z]?):(.*)$`)
+	mt940BalancePattern = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})([\d,]+)`)
+	mt940LinePattern    = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?([\d,]+)([NFS][A-Z0-9]{3})?([^/\n]*)(?://([^\n]*))?(?:\n(.*))?`)
+	mt940SubfieldCode   = regexp.MustCompile(`\?\d{2}`)
+)
+
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+func splitMT940Fields(data []byte) []mt940Field {
+	fields := []mt940Field{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if match := mt940TagPattern.FindStringSubmatch(line); match != nil {
+			fields = append(fields, mt940Field{tag: match[1], value: match[2]})
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "-" || strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "-}") {
+			continue
+		}
+		if len(fields) > 0 {
+			fields[len(fields)-1].value += "\n" + line
+		}
+	}
+	return fields
+}
+
+func parseMT940Date(value string) (*time.Time, error) {
+	date, err := time.Parse("060102", value)
+	if err != nil {
+		return nil, errors.New("invalid MT940 date: " + value)
+	}
+	return &date, nil
+}
+
+// parseMT940Amount reads an MT940 amount, which always uses "," as the decimal separator.
+func parseMT940Amount(value string) (float64, error) {
+	amount, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		return 0, errors.New("invalid MT940 amount: " + value)
+	}
+	return amount, nil
+}
+
+func parseMT940Balance(value string) (balance float64, date *time.Time, currency string, err error) {
+	match := mt940BalancePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, nil, "", errors.New("invalid MT940 balance: " + value)
+	}
+	date, err = parseMT940Date(match[2])
+	if err != nil {
+		return 0, nil, "", err
+	}
+	balance, err = parseMT940Amount(match[4])
+	if err != nil {
+		return 0, nil, "", err
+	}
+	if match[1] == "D" {
+		balance = -balance
+	}
+	return RoundTo2Decimals(balance), date, match[3], nil
+}
+
+// ParseMT940 reads a SWIFT MT940 customer statement. Several statements in one file are merged.
+func ParseMT940(data []byte) (*BankStatement, error) {
+	statement := &BankStatement{}
+	var line *BankStatementLine
+
+	flush := func() {
+		if line != nil {
+			statement.Lines = append(statement.Lines, *line)
+			line = nil
+		}
+	}
+
+	for _, field := range splitMT940Fields(data) {
+		switch field.tag {
+		case "20":
+			if statement.StatementNo == "" {
+				statement.StatementNo = strings.TrimSpace(field.value)
+			}
+		case "25":
+			statement.BankAccountNo = strings.TrimSpace(field.value)
+		case "28C":
+			statement.StatementNo = strings.TrimSpace(field.value)
+		case "60F", "60M":
+			balance, _, currency, err := parseMT940Balance(field.value)
+			if err != nil {
+				return nil, err
+			}
+			if statement.OpeningBalance == nil {
+				statement.OpeningBalance = &balance
+			}
+			statement.Currency = currency
+		case "62F", "62M":
+			flush()
+			balance, _, _, err := parseMT940Balance(field.value)
+			if err != nil {
+				return nil, err
+			}
+			statement.ClosingBalance = &balance
+		case "61":
+			flush()
+			match := mt940LinePattern.FindStringSubmatch(field.value)
+			if match == nil {
+				return nil, errors.New("invalid MT940 transaction: " + field.value)
+			}
+
+			valueDate, err := parseMT940Date(match[1])
+			if err != nil {
+				return nil, err
+			}
+			bookingDate := valueDate
+			if match[2] != "" {
+				month, _ := strconv.Atoi(match[2][:2])
+				day, _ := strconv.Atoi(match[2][2:])
+				year := valueDate.Year()
+				if month == 12 && valueDate.Month() == time.January {
+					year--
+				} else if month == 1 && valueDate.Month() == time.December {
+					year++
+				}
+				date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+				bookingDate = &date
+			}
+
+			amount, err := parseMT940Amount(match[5])
+			if err != nil {
+				return nil, err
+			}
+			// D = debit (money out), RC = reversal of a credit
+			if match[3] == "D" || match[3] == "RC" {
+				amount = -amount
+			}
+
+			reference := strings.TrimSpace(match[7])
+			if reference == "" || strings.EqualFold(reference, "NONREF") {
+				reference = strings.TrimSpace(match[8])
+			}
+
+			line = &BankStatementLine{
+				Date:        bookingDate,
+				ValueDate:   valueDate,
+				Amount:      amount,
+				Reference:   reference,
+				Description: strings.TrimSpace(match[9]),
+			}
+		case "86":
+			if line == nil {
+				continue
+			}
+			description := mt940SubfieldCode.ReplaceAllString(field.value, " ")
+			description = strings.Join(strings.Fields(description), " ")
+			if line.Description != "" {
+				description = line.Description + " " + description
+			}
+			line.Description = description
+		}
+	}
+	flush()
+
+	return statement, nil
+}
+
+// ── CAMT.053 ─────────────────────────────────────────────────────────────────
+
+type camtDate struct {
+	Dt   string `xml:"Dt"`
+	DtTm string `xml:"DtTm"`
+}
+
+func (d camtDate) parse() (*time.Time, error) {
+	value := d.Dt
+	if value == "" && len(d.DtTm) >= 10 {
+		value = d.DtTm[:10]
+	}
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, errors.New("invalid CAMT.053 date: " + value)
+	}
+	return &date, nil
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"` // camt.053.001.08 and later
+}
+
+func (p camtParty) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.PartyName
+}
+
+// camtStatus is "BOOK" up to camt.053.001.04 and <Cd>BOOK</Cd> afterwards.
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+type camtDocument struct {
+	Statements []struct {
+		ID   string `xml:"Id"`
+		Acct struct {
+			IBAN     string `xml:"Id>IBAN"`
+			Other    string `xml:"Id>Othr>Id"`
+			Currency string `xml:"Ccy"`
+		} `xml:"Acct"`
+		Balances []struct {
+			Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+			Amount    camtAmount `xml:"Amt"`
+			Indicator string     `xml:"CdtDbtInd"`
+		} `xml:"Bal"`
+		Entries []struct {
+			Amount         camtAmount `xml:"Amt"`
+			Indicator      string     `xml:"CdtDbtInd"`
+			Status         camtStatus `xml:"Sts"`
+			BookingDate    camtDate   `xml:"BookgDt"`
+			ValueDate      camtDate   `xml:"ValDt"`
+			ServicerRef    string     `xml:"AcctSvcrRef"`
+			AdditionalInfo string     `xml:"AddtlNtryInf"`
+			Details        []struct {
+				EndToEndID   string    `xml:"Refs>EndToEndId"`
+				InstrID      string    `xml:"Refs>InstrId"`
+				Debtor       camtParty `xml:"RltdPties>Dbtr"`
+				Creditor     camtParty `xml:"RltdPties>Cdtr"`
+				Unstructured []string  `xml:"RmtInf>Ustrd"`
+				CreditorRef  string    `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+			} `xml:"NtryDtls>TxDtls"`
+		} `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+// ParseCAMT053 reads an ISO 20022 camt.053 bank-to-customer statement (any version).
+// Pending entries are skipped; several statements in one file are merged.
+func ParseCAMT053(data []byte) (*BankStatement, error) {
+	var document camtDocument
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return nil, errors.New("invalid CAMT.053 file: " + err.Error())
+	}
+	if len(document.Statements) == 0 {
+		return nil, errors.New("no statement found in CAMT.053 file")
+	}
+
+	statement := &BankStatement{}
+	for _, stmt := range document.Statements {
+		statement.StatementNo = strings.TrimSpace(stmt.ID)
+		statement.BankAccountNo = stmt.Acct.IBAN
+		if statement.BankAccountNo == "" {
+			statement.BankAccountNo = stmt.Acct.Other
+		}
+
+		for _, balance := range stmt.Balances {
+			amount, err := ParseBankStatementAmount(balance.Amount.Value)
+			if err != nil {
+				return nil, err
+			}
+			if balance.Indicator == "DBIT" {
+				amount = -amount
+			}
+			amount = RoundTo2Decimals(amount)
+			if statement.Currency == "" {
+				statement.Currency = balance.Amount.Currency
+			}
+
+			switch balance.Code {
+			case "OPBD", "PRCD":
+				if statement.OpeningBalance == nil {
+					statement.OpeningBalance = &amount
+				}
+			case "CLBD":
+				statement.ClosingBalance = &amount
+			}
+		}
+		if statement.Currency == "" {
+			statement.Currency = stmt.Acct.Currency
+		}
+
+		for _, entry := range stmt.Entries {
+			status := strings.TrimSpace(entry.Status.Code)
+			if status == "" {
+				status = strings.TrimSpace(entry.Status.Value)
+			}
+			if status != "" && status != "BOOK" {
+				continue
+			}
+
+			amount, err := ParseBankStatementAmount(entry.Amount.Value)
+			if err != nil {
+				return nil, err
+			}
+			if entry.Indicator == "DBIT" {
+				amount = -amount
+			}
+
+			line := BankStatementLine{
+				Amount:    amount,
+				Reference: strings.TrimSpace(entry.ServicerRef),
+			}
+			line.Date, err = entry.BookingDate.parse()
+			if err != nil {
+				return nil, err
+			}
+			line.ValueDate, err = entry.ValueDate.parse()
+			if err != nil {
+				return nil, err
+			}
+			if line.Date == nil {
+				line.Date = line.ValueDate
+			}
+			if line.Date == nil {
+				return nil, errors.New("CAMT.053 entry without booking date")
+			}
+
+			descriptions := []string{}
+			for _, details := range entry.Details {
+				if ref := details.EndToEndID; ref != "" && ref != "NOTPROVIDED" {
+					line.Reference = ref
+				} else if details.CreditorRef != "" {
+					line.Reference = details.CreditorRef
+				} else if line.Reference == "" {
+					line.Reference = details.InstrID
+				}
+
+				if amount >= 0 {
+					line.Counterparty = details.Debtor.name()
+				} else {
+					line.Counterparty = details.Creditor.name()
+				}
+				descriptions = append(descriptions, details.Unstructured...)
+			}
+			if len(descriptions) == 0 && entry.AdditionalInfo != "" {
+				descriptions = append(descriptions, entry.AdditionalInfo)
+			}
+			line.Description = strings.Join(strings.Fields(strings.Join(descriptions, " ")), " ")
+
+			statement.Lines = append(statement.Lines, line)
+		}
+	}
+
+	return statement, nil
+}
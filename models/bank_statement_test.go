@@ -0,0 +1,302 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func bankTestDate(year int, month time.Month, day int) *time.Time {
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &date
+}
+
+// ── Parsing ──────────────────────────────────────────────────────────────────
+
+func TestParseBankStatementAmount(t *testing.T) {
+	cases := map[string]float64{
+		"1,234.50":  1234.5,
+		"-12.5":     -12.5,
+		"(12.50)":   -12.5,
+		"12.50-":    -12.5,
+		"1234,50":   1234.5,
+		"1.234,50":  1234.5,
+		"1,234":     1234,
+		"SAR 99.00": 99,
+		"":          0,
+	}
+	for value, want := range cases {
+		got, err := ParseBankStatementAmount(value)
+		if err != nil || got != want {
+			t.Errorf("%q = %v (%v), want %v", value, got, err, want)
+		}
+	}
+}
+
+func TestParseBankStatementCSV_DebitCreditAndRunningBalance(t *testing.T) {
+	data := []byte("Txn Date,Details,Ref,Withdrawal,Deposit,Balance\n" +
+		"01/03/2026,Opening transfer,TRF1,,1000.00,1500.00\n" +
+		"05/03/2026,Rent March,EXP-0001,300.00,,1200.00\n" +
+		"\n" +
+		"07/03/2026,Bank charges,,-15.00,,1185.00\n")
+	mapping := ImportMapping{"date": "Txn Date", "description": "Details", "reference": "Ref", "debit": "Withdrawal", "credit": "Deposit"}
+
+	statement, err := ParseBankStatementFile("statement.csv", "", data, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statement.Format != BankStatementFormatCSV || len(statement.Lines) != 3 {
+		t.Fatalf("statement = %+v", statement)
+	}
+	if statement.Lines[1].Amount != -300 || statement.Lines[2].Amount != -15 || statement.Lines[0].Amount != 1000 {
+		t.Errorf("amounts = %v %v %v", statement.Lines[0].Amount, statement.Lines[1].Amount, statement.Lines[2].Amount)
+	}
+	if got := statement.Lines[1].Date.Format("2006-01-02"); got != "2026-03-05" {
+		t.Errorf("dates are day first, got %s", got)
+	}
+	if *statement.OpeningBalance != 500 || *statement.ClosingBalance != 1185 {
+		t.Errorf("balances = %v / %v", *statement.OpeningBalance, *statement.ClosingBalance)
+	}
+	if statement.Lines[0].Status != BankLineStatusUnmatched || statement.Lines[0].ID.IsZero() {
+		t.Errorf("line not initialised: %+v", statement.Lines[0])
+	}
+}
+
+func TestParseBankStatementCSV_NewestFirstAndMissingAmount(t *testing.T) {
+	data := []byte("date,amount,balance\n2026-03-02,-50,950\n2026-03-01,100,1000\n")
+	statement, err := ParseBankStatementFile("statement.csv", "", data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *statement.OpeningBalance != 900 || *statement.ClosingBalance != 950 {
+		t.Errorf("balances = %v / %v", *statement.OpeningBalance, *statement.ClosingBalance)
+	}
+	if got := statement.Lines[0].Date.Format("2006-01-02"); got != "2026-03-01" {
+		t.Errorf("lines not sorted by date: %s first", got)
+	}
+
+	if _, err := ParseBankStatementFile("statement.csv", "", []byte("date,details\n2026-03-01,x\n"), nil); err == nil {
+		t.Error("expected an error without amount columns")
+	}
+}
+
+func TestParseMT940(t *testing.T) {
+	data := []byte("{1:F01BANKSARIAXXX0000000000}{4:\r\n" +
+		":20:STMT2026-03\r\n" +
+		":25:SA0380000000608010167519\r\n" +
+		":28C:00042/001\r\n" +
+		":60F:C260228SAR1000,00\r\n" +
+		":61:2603010301D300,NTRFEXP-0001//B260301001\r\n" +
+		":86:?20Rent March?32Landlord Co\r\n" +
+		":61:2603020302C1250,5NMSCNONREF//B260302007\r\n" +
+		"CUSTOMER DEPOSIT\r\n" +
+		":86:Transfer from\r\n" +
+		"ACME TRADING\r\n" +
+		":61:2512311231RC20,00NCHGNONREF\r\n" +
+		":62F:C260331SAR1950,50\r\n" +
+		"-}\r\n")
+
+	statement, err := ParseBankStatementFile("march.sta", "", data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statement.Format != BankStatementFormatMT940 || statement.StatementNo != "00042/001" || statement.Currency != "SAR" {
+		t.Errorf("header = %+v", statement)
+	}
+	if *statement.OpeningBalance != 1000 || *statement.ClosingBalance != 1950.5 {
+		t.Errorf("balances = %v / %v", *statement.OpeningBalance, *statement.ClosingBalance)
+	}
+	if len(statement.Lines) != 3 {
+		t.Fatalf("lines = %d, want 3", len(statement.Lines))
+	}
+
+	// sorted by date: the reversal of Dec 31 comes first
+	reversal, rent, deposit := statement.Lines[0], statement.Lines[1], statement.Lines[2]
+	if reversal.Amount != -20 || reversal.Date.Year() != 2025 {
+		t.Errorf("reversal = %+v", reversal)
+	}
+	if rent.Amount != -300 || rent.Reference != "EXP-0001" || rent.Description != "Rent March Landlord Co" {
+		t.Errorf("rent = %+v", rent)
+	}
+	if deposit.Amount != 1250.5 || deposit.Reference != "B260302007" || deposit.Description != "CUSTOMER DEPOSIT Transfer from ACME TRADING" {
+		t.Errorf("deposit = %+v", deposit)
+	}
+}
+
+func TestParseCAMT053(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Id>STMT-0003</Id>
+      <Acct><Id><IBAN>SA0380000000608010167519</IBAN></Id></Acct>
+      <Bal><Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy="SAR">1000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd></Bal>
+      <Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="SAR">700.00</Amt><CdtDbtInd>CRDT</CdtDbtInd></Bal>
+      <Ntry>
+        <Amt Ccy="SAR">300.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2026-03-05</Dt></BookgDt><ValDt><Dt>2026-03-06</Dt></ValDt>
+        <AcctSvcrRef>B1</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>EXP-0001</EndToEndId></Refs>
+          <RltdPties><Cdtr><Pty><Nm>Landlord Co</Nm></Pty></Cdtr></RltdPties>
+          <RmtInf><Ustrd>Rent</Ustrd><Ustrd>March</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="SAR">99.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts><Cd>PDNG</Cd></Sts>
+        <BookgDt><Dt>2026-03-07</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`)
+
+	statement, err := ParseBankStatementFile("camt.xml", "", data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statement.StatementNo != "STMT-0003" || statement.BankAccountNo != "SA0380000000608010167519" || statement.Currency != "SAR" {
+		t.Errorf("header = %+v", statement)
+	}
+	if *statement.OpeningBalance != 1000 || *statement.ClosingBalance != 700 {
+		t.Errorf("balances = %v / %v", *statement.OpeningBalance, *statement.ClosingBalance)
+	}
+	if len(statement.Lines) != 1 {
+		t.Fatalf("lines = %d, want 1 (pending entries skipped)", len(statement.Lines))
+	}
+	line := statement.Lines[0]
+	if line.Amount != -300 || line.Reference != "EXP-0001" || line.Counterparty != "Landlord Co" || line.Description != "Rent March" {
+		t.Errorf("line = %+v", line)
+	}
+	if line.ValueDate.Format("2006-01-02") != "2026-03-06" {
+		t.Errorf("value date = %s", line.ValueDate)
+	}
+}
+
+// ── Matching ─────────────────────────────────────────────────────────────────
+
+func TestAutoMatchBankLines(t *testing.T) {
+	lines := []BankStatementLine{
+		{ID: primitive.NewObjectID(), Date: bankTestDate(2026, 3, 5), Amount: -300, Description: "Rent EXP-0002", Status: BankLineStatusUnmatched},
+		{ID: primitive.NewObjectID(), Date: bankTestDate(2026, 3, 5), Amount: -300, Status: BankLineStatusUnmatched},
+		{ID: primitive.NewObjectID(), Date: bankTestDate(2026, 3, 10), Amount: 50, Status: BankLineStatusUnmatched},
+		{ID: primitive.NewObjectID(), Date: bankTestDate(2026, 3, 20), Amount: 75, Status: BankLineStatusUnmatched},
+	}
+	entries := []BankStatementMatch{
+		{PostingID: primitive.NewObjectID(), ReferenceCode: "EXP-0001", Date: bankTestDate(2026, 3, 4), Amount: -300},
+		{PostingID: primitive.NewObjectID(), ReferenceCode: "EXP-0002", Date: bankTestDate(2026, 3, 1), Amount: -300},
+		// two deposits of 50 equally far from the line: ambiguous
+		{PostingID: primitive.NewObjectID(), ReferenceCode: "CD-1", Date: bankTestDate(2026, 3, 9), Amount: 50},
+		{PostingID: primitive.NewObjectID(), ReferenceCode: "CD-2", Date: bankTestDate(2026, 3, 11), Amount: 50},
+		// outside the date tolerance
+		{PostingID: primitive.NewObjectID(), ReferenceCode: "CD-3", Date: bankTestDate(2026, 3, 10), Amount: 75},
+	}
+
+	now := time.Now()
+	matched := AutoMatchBankLines(lines, entries, map[primitive.ObjectID]bool{}, 3, &now)
+	if matched != 2 {
+		t.Fatalf("matched = %d, want 2", matched)
+	}
+	// the reference wins over the nearer date, the other line takes the remaining posting
+	if lines[0].Matches[0].ReferenceCode != "EXP-0002" || lines[1].Matches[0].ReferenceCode != "EXP-0001" {
+		t.Errorf("matches = %+v / %+v", lines[0].Matches, lines[1].Matches)
+	}
+	if lines[0].MatchedBy != BankMatchAuto || lines[2].Status != BankLineStatusUnmatched || lines[3].Status != BankLineStatusUnmatched {
+		t.Errorf("statuses = %s %s %s", lines[0].MatchedBy, lines[2].Status, lines[3].Status)
+	}
+}
+
+func TestBankStatement_SplitMatchAndUnmatch(t *testing.T) {
+	lineID := primitive.NewObjectID()
+	statement := &BankStatement{Lines: []BankStatementLine{
+		{ID: lineID, Date: bankTestDate(2026, 3, 5), Amount: 500, Status: BankLineStatusUnmatched},
+		{ID: primitive.NewObjectID(), Date: bankTestDate(2026, 3, 6), Amount: -10, Status: BankLineStatusUnmatched},
+	}}
+	userID := primitive.NewObjectID()
+	now := time.Now()
+
+	if err := statement.SplitLine(lineID, []float64{200, 250}); err == nil {
+		t.Error("expected an error when the parts do not add up")
+	}
+	if err := statement.SplitLine(lineID, []float64{600, -100}); err == nil {
+		t.Error("expected an error for a part with the wrong sign")
+	}
+	if err := statement.SplitLine(lineID, []float64{200, 300}); err != nil {
+		t.Fatal(err)
+	}
+	if len(statement.Lines) != 4 || statement.Lines[0].Status != BankLineStatusSplit || *statement.Lines[2].ParentID != lineID {
+		t.Fatalf("lines after split = %+v", statement.Lines)
+	}
+
+	part := statement.Lines[1]
+	first := BankStatementMatch{PostingID: primitive.NewObjectID(), Amount: 120}
+	second := BankStatementMatch{PostingID: primitive.NewObjectID(), Amount: 80}
+	if err := statement.MatchLine(part.ID, []BankStatementMatch{first}, userID, &now); err == nil {
+		t.Error("expected an error when the postings do not add up")
+	}
+	if err := statement.MatchLine(part.ID, []BankStatementMatch{first, second}, userID, &now); err != nil {
+		t.Fatal(err)
+	}
+	if err := statement.MatchLine(statement.Lines[2].ID, []BankStatementMatch{first}, userID, &now); err == nil {
+		t.Error("expected an error for a posting matched twice")
+	}
+
+	statement.FindTotals()
+	if statement.MatchedCount != 1 || statement.UnmatchedCount != 2 || statement.TotalIn != 500 || statement.TotalOut != 10 {
+		t.Errorf("totals = %d matched, %d unmatched, in %v, out %v", statement.MatchedCount, statement.UnmatchedCount, statement.TotalIn, statement.TotalOut)
+	}
+
+	if err := statement.UnmatchLine(lineID); err == nil {
+		t.Error("expected an error merging a split line with a matched part")
+	}
+	if err := statement.UnmatchLine(part.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := statement.UnmatchLine(lineID); err != nil {
+		t.Fatal(err)
+	}
+	if len(statement.Lines) != 2 || statement.Lines[0].Status != BankLineStatusUnmatched {
+		t.Errorf("lines after merge = %+v", statement.Lines)
+	}
+}
+
+// ── Reconciliation ───────────────────────────────────────────────────────────
+
+func TestBuildBankReconciliation(t *testing.T) {
+	opening := 1000.0
+	closing := 685.0
+	deletedPosting := primitive.NewObjectID()
+	livePosting := primitive.NewObjectID()
+	statement := &BankStatement{
+		OpeningBalance: &opening,
+		ClosingBalance: &closing,
+		Lines: []BankStatementLine{
+			{Amount: -300, Status: BankLineStatusMatched, Matches: []BankStatementMatch{{PostingID: livePosting, Amount: -300}}},
+			// bank charges not booked yet
+			{Amount: -15, Status: BankLineStatusUnmatched},
+			// matched to an expense that was deleted afterwards
+			{Amount: -40, Status: BankLineStatusMatched, Matches: []BankStatementMatch{{PostingID: deletedPosting, Amount: -40}}},
+			{Amount: 40, Status: BankLineStatusUnmatched},
+		},
+	}
+	// books: 1000 - 300 and a cheque of 120 the bank has not cleared yet
+	unmatched := []BankStatementMatch{{PostingID: primitive.NewObjectID(), Amount: -120}}
+
+	reconciliation := BuildBankReconciliation(statement, 580, unmatched, map[primitive.ObjectID]bool{livePosting: true})
+	if reconciliation.MatchedCount != 1 || reconciliation.UnmatchedCount != 3 {
+		t.Errorf("counts = %d / %d", reconciliation.MatchedCount, reconciliation.UnmatchedCount)
+	}
+	if reconciliation.UnmatchedLinesTotal != -15 || reconciliation.UnmatchedEntriesTotal != -120 {
+		t.Errorf("unmatched totals = %v / %v", reconciliation.UnmatchedLinesTotal, reconciliation.UnmatchedEntriesTotal)
+	}
+	if reconciliation.AdjustedBookBalance != 685 || reconciliation.Difference != 0 {
+		t.Errorf("adjusted = %v, difference = %v", reconciliation.AdjustedBookBalance, reconciliation.Difference)
+	}
+
+	// without a closing balance the statement balance is the opening plus the lines
+	statement.ClosingBalance = nil
+	reconciliation = BuildBankReconciliation(statement, 580, unmatched, nil)
+	if reconciliation.StatementBalance != 685 {
+		t.Errorf("statement balance = %v, want 685", reconciliation.StatementBalance)
+	}
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BIPromotionUplift compares the sales of the promoted products while a promotion
+// ran with an equally long period right before it started.
+type BIPromotionUplift struct {
+	PromotionID          primitive.ObjectID `json:"promotion_id"`
+	PromotionName        string             `json:"promotion_name"`
+	Type                 string             `json:"type"`
+	CouponCode           string             `json:"coupon_code,omitempty"`
+	From                 time.Time          `json:"from"`
+	To                   time.Time          `json:"to"`
+	OrderCount           int64              `json:"order_count"`
+	PromotedUnits        float64            `json:"promoted_units"`
+	PromotedRevenue      float64            `json:"promoted_revenue"`
+	DiscountGiven        float64            `json:"discount_given"`
+	PeriodUnits          float64            `json:"period_units"`
+	PeriodRevenue        float64            `json:"period_revenue"`
+	BaselineUnits        float64            `json:"baseline_units"`
+	BaselineRevenue      float64            `json:"baseline_revenue"`
+	UnitsUpliftPercent   float64            `json:"units_uplift_percent"`
+	RevenueUpliftPercent float64            `json:"revenue_uplift_percent"`
+}
+
+// UpliftPercent returns the change from baseline to current in percent,
+// or 0 when there is no baseline to compare with.
+func UpliftPercent(current float64, baseline float64) float64 {
+	if baseline <= 0 {
+		return 0
+	}
+	return RoundTo2Decimals((current - baseline) / baseline * 100)
+}
+
+type promotionSalesTotals struct {
+	units      float64
+	revenue    float64
+	discount   float64
+	orders     int64
+	productIDs []primitive.ObjectID
+}
+
+func (store *Store) aggregatePromotionSales(ctx context.Context, match bson.M) (totals promotionSalesTotals, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("product_sales_history")
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         nil,
+			"units":       bson.M{"$sum": "$quantity"},
+			"revenue":     bson.M{"$sum": "$net_price"},
+			"discount":    bson.M{"$sum": "$discount"},
+			"order_ids":   bson.M{"$addToSet": "$order_id"},
+			"product_ids": bson.M{"$addToSet": "$product_id"},
+		}}},
+	}
+
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return totals, err
+	}
+	defer cur.Close(ctx)
+
+	if cur.Next(ctx) {
+		var row struct {
+			Units      float64              `bson:"units"`
+			Revenue    float64              `bson:"revenue"`
+			Discount   float64              `bson:"discount"`
+			OrderIDs   []primitive.ObjectID `bson:"order_ids"`
+			ProductIDs []primitive.ObjectID `bson:"product_ids"`
+		}
+		if err := cur.Decode(&row); err != nil {
+			return totals, err
+		}
+		totals.units = RoundTo2Decimals(row.Units)
+		totals.revenue = RoundTo2Decimals(row.Revenue)
+		totals.discount = RoundTo2Decimals(row.Discount)
+		totals.orders = int64(len(row.OrderIDs))
+		totals.productIDs = row.ProductIDs
+	}
+
+	return totals, nil
+}
+
+// GetBIPromotionUplift reports the uplift of every promotion, or of one when promotionID is set.
+func (store *Store) GetBIPromotionUplift(promotionID *primitive.ObjectID) ([]BIPromotionUplift, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("promotion")
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	filter := bson.M{"deleted": bson.M{"$ne": true}}
+	if promotionID != nil {
+		filter["_id"] = promotionID
+	}
+
+	cur, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var promotions []Promotion
+	if err := cur.All(ctx, &promotions); err != nil {
+		return nil, err
+	}
+
+	results := []BIPromotionUplift{}
+	now := time.Now()
+	for _, promotion := range promotions {
+		from := now
+		if promotion.StartAt != nil {
+			from = *promotion.StartAt
+		} else if promotion.CreatedAt != nil {
+			from = *promotion.CreatedAt
+		}
+		to := now
+		if promotion.EndAt != nil && promotion.EndAt.Before(now) {
+			to = *promotion.EndAt
+		}
+		if !to.After(from) {
+			continue
+		}
+
+		promoted, err := store.aggregatePromotionSales(ctx, bson.M{
+			"promotion_id": promotion.ID,
+			"date":         bson.M{"$gte": from, "$lte": to},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		row := BIPromotionUplift{
+			PromotionID:     promotion.ID,
+			PromotionName:   promotion.Name,
+			Type:            promotion.Type,
+			CouponCode:      promotion.CouponCode,
+			From:            from,
+			To:              to,
+			OrderCount:      promoted.orders,
+			PromotedUnits:   promoted.units,
+			PromotedRevenue: promoted.revenue,
+			DiscountGiven:   promoted.discount,
+		}
+
+		if len(promoted.productIDs) > 0 {
+			period, err := store.aggregatePromotionSales(ctx, bson.M{
+				"product_id": bson.M{"$in": promoted.productIDs},
+				"date":       bson.M{"$gte": from, "$lte": to},
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			baselineFrom := from.Add(-to.Sub(from))
+			baseline, err := store.aggregatePromotionSales(ctx, bson.M{
+				"product_id": bson.M{"$in": promoted.productIDs},
+				"date":       bson.M{"$gte": baselineFrom, "$lt": from},
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			row.PeriodUnits = period.units
+			row.PeriodRevenue = period.revenue
+			row.BaselineUnits = baseline.units
+			row.BaselineRevenue = baseline.revenue
+			row.UnitsUpliftPercent = UpliftPercent(period.units, baseline.units)
+			row.RevenueUpliftPercent = UpliftPercent(period.revenue, baseline.revenue)
+		}
+
+		results = append(results, row)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,808 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	BudgetLineExpenseCategory = "expense_category"
+	BudgetLineAccount         = "account"
+
+	BudgetDefaultAlertThreshold = 100
+)
+
+// Budget : the monthly amounts planned for expense categories and revenue/expense accounts
+// over a fiscal year, one budget per fiscal year.
+type Budget struct {
+	ID                    primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name                  string              `bson:"name" json:"name"`
+	FiscalYearID          *primitive.ObjectID `json:"fiscal_year_id" bson:"fiscal_year_id"`
+	FiscalYearName        string              `json:"fiscal_year_name,omitempty" bson:"fiscal_year_name,omitempty"`
+	StartDate             *time.Time          `bson:"start_date,omitempty" json:"start_date,omitempty"`
+	EndDate               *time.Time          `bson:"end_date,omitempty" json:"end_date,omitempty"`
+	AlertThresholdPercent float64             `bson:"alert_threshold_percent" json:"alert_threshold_percent"`
+	Lines                 []BudgetLine        `bson:"lines" json:"lines"`
+	StoreID               *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName             string              `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted               bool                `bson:"deleted" json:"deleted"`
+	DeletedBy             *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt             *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt             *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt             *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy             *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy             *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName         string              `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName         string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+}
+
+// BudgetLine : the budget of one expense category or account. Amounts has one value per fiscal
+// period, MonthlyAmount fills every period with the same value when Amounts is not given.
+type BudgetLine struct {
+	Type                  string              `bson:"type" json:"type"`
+	ExpenseCategoryID     *primitive.ObjectID `json:"expense_category_id,omitempty" bson:"expense_category_id,omitempty"`
+	AccountID             *primitive.ObjectID `json:"account_id,omitempty" bson:"account_id,omitempty"`
+	AccountNumber         string              `json:"account_number,omitempty" bson:"account_number,omitempty"`
+	AccountType           string              `json:"account_type,omitempty" bson:"account_type,omitempty"`
+	Name                  string              `bson:"name" json:"name"`
+	Amounts               []float64           `bson:"amounts" json:"amounts"`
+	MonthlyAmount         float64             `bson:"-" json:"monthly_amount,omitempty"`
+	Total                 float64             `bson:"total" json:"total"`
+	AlertThresholdPercent *float64            `bson:"alert_threshold_percent,omitempty" json:"alert_threshold_percent,omitempty"`
+}
+
+// BudgetAlert : sent once per budget line and fiscal period when the actual spending reaches the threshold
+type BudgetAlert struct {
+	ID               primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	BudgetID         *primitive.ObjectID `json:"budget_id" bson:"budget_id"`
+	BudgetName       string              `json:"budget_name" bson:"budget_name"`
+	LineType         string              `json:"line_type" bson:"line_type"`
+	ReferenceID      *primitive.ObjectID `json:"reference_id" bson:"reference_id"`
+	Name             string              `json:"name" bson:"name"`
+	PeriodNumber     int                 `json:"period_number" bson:"period_number"`
+	PeriodName       string              `json:"period_name" bson:"period_name"`
+	Budget           float64             `json:"budget" bson:"budget"`
+	Actual           float64             `json:"actual" bson:"actual"`
+	UsedPercent      float64             `json:"used_percent" bson:"used_percent"`
+	ThresholdPercent float64             `json:"threshold_percent" bson:"threshold_percent"`
+	StoreID          *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	CreatedAt        *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+}
+
+// BudgetVariance : actual vs budget of every line of a budget for a range of its fiscal periods.
+// Variance is positive when the result is favourable: spending below budget or revenue above it.
+type BudgetVariance struct {
+	BudgetID       *primitive.ObjectID  `json:"budget_id"`
+	BudgetName     string               `json:"budget_name"`
+	FiscalYearName string               `json:"fiscal_year_name"`
+	StoreName      string               `json:"store_name"`
+	Periods        []StatementPeriod    `json:"periods"`
+	Lines          []BudgetVarianceLine `json:"lines"`
+	CreatedAt      *time.Time           `json:"created_at,omitempty"`
+}
+
+type BudgetVarianceLine struct {
+	Type          string              `json:"type"`
+	ReferenceID   *primitive.ObjectID `json:"reference_id"`
+	AccountNumber string              `json:"account_number,omitempty"`
+	Name          string              `json:"name"`
+	Side          string              `json:"side"` // revenue or expense
+	Budget        []float64           `json:"budget"`
+	Actual        []float64           `json:"actual"`
+	Variance      []float64           `json:"variance"`
+	TotalBudget   float64             `json:"total_budget"`
+	TotalActual   float64             `json:"total_actual"`
+	TotalVariance float64             `json:"total_variance"`
+	UsedPercent   float64             `json:"used_percent"`
+}
+
+// ReferenceID is the expense category or account the line budgets.
+func (line *BudgetLine) ReferenceID() *primitive.ObjectID {
+	if line.Type == BudgetLineExpenseCategory {
+		return line.ExpenseCategoryID
+	}
+	return line.AccountID
+}
+
+// Side tells if the line plans revenue or spending.
+func (line *BudgetLine) Side() string {
+	if line.Type == BudgetLineAccount && line.AccountType == "revenue" {
+		return "revenue"
+	}
+	return "expense"
+}
+
+// Threshold is the percentage of the budget that raises an alert for the line.
+func (budget *Budget) Threshold(line *BudgetLine) float64 {
+	if line.AlertThresholdPercent != nil && *line.AlertThresholdPercent > 0 {
+		return *line.AlertThresholdPercent
+	}
+	if budget.AlertThresholdPercent > 0 {
+		return budget.AlertThresholdPercent
+	}
+	return BudgetDefaultAlertThreshold
+}
+
+func budgetUsedPercent(actual, budget float64) float64 {
+	if budget == 0 {
+		return 0
+	}
+	return RoundFloat(actual/budget*100, 2)
+}
+
+// BuildBudgetVariance compares the budget of periods (fiscal period numbers, 1 based) with the
+// actuals: movements of the accounts and expense totals of the categories, one entry per period.
+func BuildBudgetVariance(budget *Budget, periodNumbers []int, periods []StatementPeriod, accounts map[primitive.ObjectID]*Account, movements []AccountMovements, categoryActuals []map[primitive.ObjectID]float64) *BudgetVariance {
+	variance := &BudgetVariance{
+		BudgetID:       &budget.ID,
+		BudgetName:     budget.Name,
+		FiscalYearName: budget.FiscalYearName,
+		StoreName:      budget.StoreName,
+		Periods:        periods,
+		Lines:          []BudgetVarianceLine{},
+	}
+
+	for _, line := range budget.Lines {
+		row := BudgetVarianceLine{
+			Type:          line.Type,
+			ReferenceID:   line.ReferenceID(),
+			AccountNumber: line.AccountNumber,
+			Name:          line.Name,
+			Side:          line.Side(),
+		}
+		if line.Type == BudgetLineAccount && line.AccountID != nil {
+			if account, ok := accounts[*line.AccountID]; ok {
+				row.Name = account.Name
+				row.AccountNumber = account.Number
+			}
+		}
+
+		for i, number := range periodNumbers {
+			planned := 0.0
+			if number >= 1 && number <= len(line.Amounts) {
+				planned = line.Amounts[number-1]
+			}
+
+			actual := 0.0
+			if line.Type == BudgetLineExpenseCategory {
+				if i < len(categoryActuals) && line.ExpenseCategoryID != nil {
+					actual = categoryActuals[i][*line.ExpenseCategoryID]
+				}
+			} else if i < len(movements) && line.AccountID != nil {
+				movement := movements[i][*line.AccountID]
+				actual = movement.Debit - movement.Credit
+				if row.Side == "revenue" {
+					actual = -actual
+				}
+			}
+			actual = RoundFloat(actual, 2)
+
+			difference := planned - actual
+			if row.Side == "revenue" {
+				difference = -difference
+			}
+
+			row.Budget = append(row.Budget, RoundFloat(planned, 2))
+			row.Actual = append(row.Actual, actual)
+			row.Variance = append(row.Variance, RoundFloat(difference, 2))
+			row.TotalBudget += planned
+			row.TotalActual += actual
+			row.TotalVariance += difference
+		}
+
+		row.TotalBudget = RoundFloat(row.TotalBudget, 2)
+		row.TotalActual = RoundFloat(row.TotalActual, 2)
+		row.TotalVariance = RoundFloat(row.TotalVariance, 2)
+		row.UsedPercent = budgetUsedPercent(row.TotalActual, row.TotalBudget)
+		variance.Lines = append(variance.Lines, row)
+	}
+
+	return variance
+}
+
+// FindExpenseCategoryActuals sums the expenses of each category between from and to, net of VAT.
+// An expense with more than one category counts in full against each of them.
+func (store *Store) FindExpenseCategoryActuals(categoryIDs []primitive.ObjectID, from, to *time.Time) (map[primitive.ObjectID]float64, error) {
+	actuals := map[primitive.ObjectID]float64{}
+	for _, categoryID := range categoryIDs {
+		stats, err := store.GetExpenseStats(map[string]interface{}{
+			"store_id":    store.ID,
+			"deleted":     bson.M{"$ne": true},
+			"category_id": categoryID,
+			"date":        bson.M{"$gte": from, "$lte": to},
+		})
+		if err != nil {
+			return nil, err
+		}
+		actuals[categoryID] = RoundFloat(stats.Total-stats.Vat, 2)
+	}
+	return actuals, nil
+}
+
+// MakeBudgetVariance builds the variance report for fiscal periods from..to (1 based, inclusive).
+func (store *Store) MakeBudgetVariance(budget *Budget, periodFrom, periodTo int) (*BudgetVariance, error) {
+	fiscalYear, err := store.FindFiscalYearByID(budget.FiscalYearID, bson.M{})
+	if err != nil {
+		return nil, errors.New("unable to find fiscal year: " + err.Error())
+	}
+
+	if periodFrom <= 0 {
+		periodFrom = 1
+	}
+	if periodTo <= 0 || periodTo > len(fiscalYear.Periods) {
+		periodTo = len(fiscalYear.Periods)
+	}
+	if periodFrom > periodTo {
+		return nil, errors.New("period from should not be after period to")
+	}
+
+	accounts, err := store.FindStatementAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	categoryIDs := []primitive.ObjectID{}
+	for _, line := range budget.Lines {
+		if line.Type == BudgetLineExpenseCategory && line.ExpenseCategoryID != nil {
+			categoryIDs = append(categoryIDs, *line.ExpenseCategoryID)
+		}
+	}
+
+	periodNumbers := []int{}
+	periods := []StatementPeriod{}
+	movements := []AccountMovements{}
+	categoryActuals := []map[primitive.ObjectID]float64{}
+	for _, period := range fiscalYear.Periods[periodFrom-1 : periodTo] {
+		// Closing entries zero revenue and expenses at year end, they are not actuals
+		movement, err := store.FindAccountMovements(period.StartDate, period.EndDate, FiscalYearReferenceModel)
+		if err != nil {
+			return nil, err
+		}
+		categoryActual, err := store.FindExpenseCategoryActuals(categoryIDs, period.StartDate, period.EndDate)
+		if err != nil {
+			return nil, err
+		}
+
+		periodNumbers = append(periodNumbers, period.Number)
+		periods = append(periods, StatementPeriod{Label: period.Name, From: period.StartDate, To: period.EndDate})
+		movements = append(movements, movement)
+		categoryActuals = append(categoryActuals, categoryActual)
+	}
+
+	variance := BuildBudgetVariance(budget, periodNumbers, periods, accounts, movements, categoryActuals)
+	now := time.Now()
+	variance.StoreName = store.Name
+	variance.CreatedAt = &now
+	return variance, nil
+}
+
+func (budget *Budget) Validate(w http.ResponseWriter, r *http.Request, scenario string) (errs map[string]string) {
+	errs = make(map[string]string)
+
+	store, err := FindStoreByID(budget.StoreID, bson.M{})
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errs["store_id"] = "invalid store id"
+		return errs
+	}
+
+	if scenario == "update" {
+		if budget.ID.IsZero() {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = "ID is required"
+			return errs
+		}
+	}
+
+	budget.Name = strings.TrimSpace(budget.Name)
+	if govalidator.IsNull(budget.Name) {
+		errs["name"] = "Name is required"
+	}
+
+	if budget.AlertThresholdPercent < 0 {
+		errs["alert_threshold_percent"] = "Alert threshold should not be negative"
+	} else if budget.AlertThresholdPercent == 0 {
+		budget.AlertThresholdPercent = BudgetDefaultAlertThreshold
+	}
+
+	var fiscalYear *FiscalYear
+	if budget.FiscalYearID == nil || budget.FiscalYearID.IsZero() {
+		errs["fiscal_year_id"] = "Fiscal year is required"
+	} else {
+		fiscalYear, err = store.FindFiscalYearByID(budget.FiscalYearID, bson.M{})
+		if err != nil || fiscalYear.Deleted {
+			errs["fiscal_year_id"] = "Invalid fiscal year"
+			fiscalYear = nil
+		} else {
+			exists, err := store.IsBudgetExists(bson.M{"fiscal_year_id": budget.FiscalYearID}, &budget.ID)
+			if err != nil {
+				errs["fiscal_year_id"] = err.Error()
+			} else if exists {
+				errs["fiscal_year_id"] = "A budget already exists for this fiscal year"
+			}
+			budget.FiscalYearName = fiscalYear.Name
+			budget.StartDate = fiscalYear.StartDate
+			budget.EndDate = fiscalYear.EndDate
+		}
+	}
+
+	if len(budget.Lines) == 0 {
+		errs["lines"] = "At least one budget line is required"
+	}
+
+	seen := map[primitive.ObjectID]bool{}
+	for i := range budget.Lines {
+		line := &budget.Lines[i]
+		index := strconv.Itoa(i)
+
+		switch line.Type {
+		case BudgetLineExpenseCategory:
+			line.AccountID = nil
+			line.AccountNumber = ""
+			line.AccountType = ""
+			if line.ExpenseCategoryID == nil || line.ExpenseCategoryID.IsZero() {
+				errs["expense_category_id_"+index] = "Expense category is required"
+				break
+			}
+			category, err := store.FindExpenseCategoryByID(line.ExpenseCategoryID, bson.M{"name": 1, "deleted": 1})
+			if err != nil || category.Deleted {
+				errs["expense_category_id_"+index] = "Invalid expense category"
+				break
+			}
+			line.Name = category.Name
+		case BudgetLineAccount:
+			line.ExpenseCategoryID = nil
+			if line.AccountID == nil || line.AccountID.IsZero() {
+				errs["account_id_"+index] = "Account is required"
+				break
+			}
+			account, err := store.FindAccountByID(*line.AccountID, bson.M{})
+			if err != nil {
+				errs["account_id_"+index] = "Invalid account"
+				break
+			}
+			accountType := StatementAccountType(account, 0, 0)
+			if accountType != "revenue" && accountType != "expense" {
+				errs["account_id_"+index] = "Only revenue and expense accounts can be budgeted"
+				break
+			}
+			line.Name = account.Name
+			line.AccountNumber = account.Number
+			line.AccountType = accountType
+		default:
+			errs["type_"+index] = "Invalid line type, allowed: " + BudgetLineExpenseCategory + ", " + BudgetLineAccount
+			continue
+		}
+
+		if referenceID := line.ReferenceID(); referenceID != nil && !referenceID.IsZero() {
+			if seen[*referenceID] {
+				errs["line_"+index] = "Duplicate budget line: " + line.Name
+			}
+			seen[*referenceID] = true
+		}
+
+		if line.AlertThresholdPercent != nil && *line.AlertThresholdPercent < 0 {
+			errs["alert_threshold_percent_"+index] = "Alert threshold should not be negative"
+		}
+
+		if fiscalYear == nil {
+			continue
+		}
+		if len(line.Amounts) == 0 {
+			line.Amounts = make([]float64, len(fiscalYear.Periods))
+			for k := range line.Amounts {
+				line.Amounts[k] = line.MonthlyAmount
+			}
+		}
+		if len(line.Amounts) != len(fiscalYear.Periods) {
+			errs["amounts_"+index] = "Enter one amount for each of the " + strconv.Itoa(len(fiscalYear.Periods)) + " periods of the fiscal year"
+			continue
+		}
+		line.Total = 0
+		for k, amount := range line.Amounts {
+			if amount < 0 || math.IsNaN(amount) {
+				errs["amounts_"+index] = "Amounts should not be negative"
+				break
+			}
+			line.Amounts[k] = RoundFloat(amount, 2)
+			line.Total += line.Amounts[k]
+		}
+		line.Total = RoundFloat(line.Total, 2)
+		line.MonthlyAmount = 0
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+// IsBudgetExists tells if another budget (not deleted) matches the filter.
+func (store *Store) IsBudgetExists(filter bson.M, excludeID *primitive.ObjectID) (exists bool, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("budget")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter["store_id"] = store.ID
+	filter["deleted"] = bson.M{"$ne": true}
+	if excludeID != nil && !excludeID.IsZero() {
+		filter["_id"] = bson.M{"$ne": excludeID}
+	}
+
+	count, err := collection.CountDocuments(ctx, filter)
+	return (count > 0), err
+}
+
+func (budget *Budget) UpdateForeignLabelFields() error {
+	store, err := FindStoreByID(budget.StoreID, bson.M{"id": 1, "name": 1})
+	if err != nil {
+		return err
+	}
+	budget.StoreName = store.Name
+
+	if budget.CreatedBy != nil {
+		createdByUser, err := FindUserByID(budget.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		budget.CreatedByName = createdByUser.Name
+	}
+
+	if budget.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(budget.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		budget.UpdatedByName = updatedByUser.Name
+	}
+
+	return nil
+}
+
+func (budget *Budget) Insert() error {
+	collection := db.GetDB("store_" + budget.StoreID.Hex()).Collection("budget")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	budget.ID = primitive.NewObjectID()
+
+	err := budget.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, &budget)
+	return err
+}
+
+func (budget *Budget) Update() error {
+	collection := db.GetDB("store_" + budget.StoreID.Hex()).Collection("budget")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := budget.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": budget.ID},
+		bson.M{"$set": budget},
+		updateOptions,
+	)
+	return err
+}
+
+func (budget *Budget) DeleteBudget(tokenClaims TokenClaims) (err error) {
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	budget.Deleted = true
+	budget.DeletedBy = &userID
+	now := time.Now()
+	budget.DeletedAt = &now
+
+	return budget.Update()
+}
+
+func (store *Store) FindBudgetByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (budget *Budget, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("budget")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{
+			"_id":      ID,
+			"store_id": store.ID,
+		}, findOneOptions).
+		Decode(&budget)
+	if err != nil {
+		return nil, err
+	}
+
+	return budget, err
+}
+
+// FindBudgetAt returns the budget of the fiscal year the date falls in, nil when there is none.
+func (store *Store) FindBudgetAt(date time.Time) (*Budget, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("budget")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var budget *Budget
+	err := collection.FindOne(ctx, bson.M{
+		"store_id":   store.ID,
+		"deleted":    bson.M{"$ne": true},
+		"start_date": bson.M{"$lte": date},
+		"end_date":   bson.M{"$gte": date},
+	}).Decode(&budget)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return budget, err
+}
+
+func (store *Store) SearchBudget(w http.ResponseWriter, r *http.Request) (budgets []Budget, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+
+	ParseDeletedFilter(r, &criterias)
+
+	ParseTextSearch(r, &criterias, "search[name]", "name")
+
+	if err = ParseObjectIDListFilter(r, &criterias, "search[fiscal_year_id]", "fiscal_year_id"); err != nil {
+		return budgets, criterias, err
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("budget")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return budgets, criterias, errors.New("Error fetching budgets:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return budgets, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		budget := Budget{}
+		err = cur.Decode(&budget)
+		if err != nil {
+			return budgets, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		budgets = append(budgets, budget)
+	}
+
+	return budgets, criterias, nil
+}
+
+func (store *Store) SearchBudgetAlert(w http.ResponseWriter, r *http.Request) (alerts []BudgetAlert, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+
+	if err = ParseObjectIDListFilter(r, &criterias, "search[budget_id]", "budget_id"); err != nil {
+		return alerts, criterias, err
+	}
+
+	keys, ok := r.URL.Query()["search[period_number]"]
+	if ok && len(keys[0]) >= 1 {
+		number, err := strconv.Atoi(keys[0])
+		if err != nil {
+			return alerts, criterias, errors.New("invalid period number")
+		}
+		criterias.SearchBy["period_number"] = number
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("budget_alert")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return alerts, criterias, errors.New("Error fetching budget alerts:" + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		alert := BudgetAlert{}
+		if err := cur.Decode(&alert); err != nil {
+			return alerts, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, criterias, cur.Err()
+}
+
+// ──────────────────────────────────────────────────────────
+// Alerts
+// ──────────────────────────────────────────────────────────
+
+// BudgetAlertsDue returns the spending lines of the budget whose actual for the period reached
+// their threshold. Revenue lines never alert.
+func BudgetAlertsDue(budget *Budget, period FiscalPeriod, accounts map[primitive.ObjectID]*Account, movements AccountMovements, categoryActuals map[primitive.ObjectID]float64) []BudgetAlert {
+	variance := BuildBudgetVariance(budget, []int{period.Number}, nil, accounts, []AccountMovements{movements}, []map[primitive.ObjectID]float64{categoryActuals})
+
+	alerts := []BudgetAlert{}
+	for i, row := range variance.Lines {
+		line := &budget.Lines[i]
+		if row.Side != "expense" || row.TotalBudget <= 0 {
+			continue
+		}
+		threshold := budget.Threshold(line)
+		if row.UsedPercent < threshold {
+			continue
+		}
+		alerts = append(alerts, BudgetAlert{
+			BudgetID:         &budget.ID,
+			BudgetName:       budget.Name,
+			LineType:         line.Type,
+			ReferenceID:      line.ReferenceID(),
+			Name:             row.Name,
+			PeriodNumber:     period.Number,
+			PeriodName:       period.Name,
+			Budget:           row.TotalBudget,
+			Actual:           row.TotalActual,
+			UsedPercent:      row.UsedPercent,
+			ThresholdPercent: threshold,
+			StoreID:          budget.StoreID,
+		})
+	}
+	return alerts
+}
+
+// IsBudgetAlertSent tells if the line was already alerted for the period.
+func (store *Store) IsBudgetAlertSent(alert *BudgetAlert) (bool, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("budget_alert")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := collection.CountDocuments(ctx, bson.M{
+		"budget_id":     alert.BudgetID,
+		"reference_id":  alert.ReferenceID,
+		"period_number": alert.PeriodNumber,
+	})
+	return count > 0, err
+}
+
+func (alert *BudgetAlert) Insert() error {
+	collection := db.GetDB("store_" + alert.StoreID.Hex()).Collection("budget_alert")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	alert.ID = primitive.NewObjectID()
+	now := time.Now()
+	alert.CreatedAt = &now
+	_, err := collection.InsertOne(ctx, &alert)
+	return err
+}
+
+// CheckBudgetAlerts records an alert for every line of the budget covering date that crossed its
+// threshold in that period and notifies the users of the store when there is a new one.
+func (store *Store) CheckBudgetAlerts(date time.Time) error {
+	budget, err := store.FindBudgetAt(date)
+	if err != nil || budget == nil {
+		return err
+	}
+
+	fiscalYear, err := store.FindFiscalYearByID(budget.FiscalYearID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	var period *FiscalPeriod
+	for i := range fiscalYear.Periods {
+		p := &fiscalYear.Periods[i]
+		if !date.Before(*p.StartDate) && !date.After(*p.EndDate) {
+			period = p
+			break
+		}
+	}
+	if period == nil {
+		return nil
+	}
+
+	accounts, err := store.FindStatementAccounts()
+	if err != nil {
+		return err
+	}
+	movements, err := store.FindAccountMovements(period.StartDate, period.EndDate, FiscalYearReferenceModel)
+	if err != nil {
+		return err
+	}
+	categoryIDs := []primitive.ObjectID{}
+	for _, line := range budget.Lines {
+		if line.Type == BudgetLineExpenseCategory && line.ExpenseCategoryID != nil {
+			categoryIDs = append(categoryIDs, *line.ExpenseCategoryID)
+		}
+	}
+	categoryActuals, err := store.FindExpenseCategoryActuals(categoryIDs, period.StartDate, period.EndDate)
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	for _, alert := range BudgetAlertsDue(budget, *period, accounts, movements, categoryActuals) {
+		exists, err := store.IsBudgetAlertSent(&alert)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if err := alert.Insert(); err != nil {
+			return err
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		store.NotifyUsers("budget_alert")
+	}
+
+	return nil
+}
+
+// ProcessBudgetAlertsForAllStores runs every hour to catch spending posted by other documents
+// (purchases, journal vouchers...) than the expenses, which check the budget when saved.
+func ProcessBudgetAlertsForAllStores() error {
+	stores, err := GetAllStores()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, store := range stores {
+		if err := store.CheckBudgetAlerts(now); err != nil {
+			log.Printf("[budget-alert] store %s: %v", store.ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ── BuildBudgetVariance ──────────────────────────────────────────────────────
+
+func TestBuildBudgetVariance(t *testing.T) {
+	rent := primitive.NewObjectID()
+	sales := Account{ID: primitive.NewObjectID(), Number: "4000", Name: "SALES", Type: "revenue"}
+	accounts := map[primitive.ObjectID]*Account{sales.ID: &sales}
+
+	budget := &Budget{
+		Name: "FY 2026",
+		Lines: []BudgetLine{
+			{Type: BudgetLineExpenseCategory, ExpenseCategoryID: &rent, Name: "RENT", Amounts: []float64{1000, 1000, 1000}},
+			{Type: BudgetLineAccount, AccountID: &sales.ID, AccountType: "revenue", Amounts: []float64{5000, 5000, 6000}},
+		},
+	}
+	movements := []AccountMovements{
+		{sales.ID: {Credit: 5500, Debit: 100}},
+		{sales.ID: {Credit: 4000}},
+	}
+	categoryActuals := []map[primitive.ObjectID]float64{
+		{rent: 1200},
+		{rent: 900},
+	}
+
+	variance := BuildBudgetVariance(budget, []int{2, 3}, nil, accounts, movements, categoryActuals)
+	if len(variance.Lines) != 2 {
+		t.Fatalf("lines = %d, want 2", len(variance.Lines))
+	}
+
+	expense := variance.Lines[0]
+	if expense.Side != "expense" || expense.Budget[0] != 1000 || expense.Actual[0] != 1200 || expense.Variance[0] != -200 || expense.Variance[1] != 100 {
+		t.Errorf("expense line = %+v, want overspending as a negative variance", expense)
+	}
+	if expense.TotalBudget != 2000 || expense.TotalActual != 2100 || expense.TotalVariance != -100 || expense.UsedPercent != 105 {
+		t.Errorf("expense totals = %+v", expense)
+	}
+
+	revenue := variance.Lines[1]
+	if revenue.Name != "SALES" || revenue.AccountNumber != "4000" || revenue.Side != "revenue" {
+		t.Errorf("revenue line = %+v", revenue)
+	}
+	if revenue.Actual[0] != 5400 || revenue.Variance[0] != 400 || revenue.Budget[1] != 6000 || revenue.Variance[1] != -2000 {
+		t.Errorf("revenue line = %+v, want revenue above budget as a positive variance", revenue)
+	}
+}
+
+// ── BudgetAlertsDue ──────────────────────────────────────────────────────────
+
+func TestBudgetAlertsDue(t *testing.T) {
+	rent, fuel, repairs := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	sales := Account{ID: primitive.NewObjectID(), Name: "SALES", Type: "revenue"}
+	accounts := map[primitive.ObjectID]*Account{sales.ID: &sales}
+
+	strict := 50.0
+	budget := &Budget{
+		AlertThresholdPercent: 90,
+		Lines: []BudgetLine{
+			{Type: BudgetLineExpenseCategory, ExpenseCategoryID: &rent, Name: "RENT", Amounts: []float64{1000}},
+			{Type: BudgetLineExpenseCategory, ExpenseCategoryID: &fuel, Name: "FUEL", Amounts: []float64{1000}},
+			{Type: BudgetLineExpenseCategory, ExpenseCategoryID: &repairs, Name: "REPAIRS", Amounts: []float64{1000}, AlertThresholdPercent: &strict},
+			{Type: BudgetLineAccount, AccountID: &sales.ID, AccountType: "revenue", Amounts: []float64{1000}},
+		},
+	}
+	period := FiscalPeriod{Number: 1, Name: "January 2026"}
+	movements := AccountMovements{sales.ID: {Credit: 5000}}
+	actuals := map[primitive.ObjectID]float64{rent: 950, fuel: 800, repairs: 600}
+
+	alerts := BudgetAlertsDue(budget, period, accounts, movements, actuals)
+	if len(alerts) != 2 {
+		t.Fatalf("alerts = %+v, want rent and repairs", alerts)
+	}
+	if alerts[0].Name != "RENT" || alerts[0].UsedPercent != 95 || alerts[0].ThresholdPercent != 90 || alerts[0].PeriodName != "January 2026" {
+		t.Errorf("rent alert = %+v", alerts[0])
+	}
+	if alerts[1].Name != "REPAIRS" || alerts[1].ThresholdPercent != 50 || *alerts[1].ReferenceID != repairs {
+		t.Errorf("repairs alert = %+v", alerts[1])
+	}
+}
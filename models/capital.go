@@ -430,6 +430,12 @@ func (capital *Capital) Validate(w http.ResponseWriter, r *http.Request, scenari
 		capital.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, capital.StoreID, "capital", capital.ID, capital.Code, capital.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	for k, imageContent := range capital.ImagesContent {
 		splits := strings.Split(imageContent, ",")
 
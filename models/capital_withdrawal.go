@@ -403,6 +403,12 @@ func (capitalwithdrawal *CapitalWithdrawal) Validate(w http.ResponseWriter, r *h
 		capitalwithdrawal.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, capitalwithdrawal.StoreID, "capital_withdrawal", capitalwithdrawal.ID, capitalwithdrawal.Code, capitalwithdrawal.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	for k, imageContent := range capitalwithdrawal.ImagesContent {
 		splits := strings.Split(imageContent, ",")
 
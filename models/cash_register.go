@@ -0,0 +1,310 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CashRegister : a till on which cashiers open and close shifts
+type CashRegister struct {
+	ID            primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name          string              `bson:"name" json:"name"`
+	Code          string              `bson:"code" json:"code"`
+	Description   string              `bson:"description,omitempty" json:"description,omitempty"`
+	StoreID       *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName     string              `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted       bool                `bson:"deleted" json:"deleted"`
+	DeletedBy     *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt     *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt     *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt     *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy     *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy     *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName string              `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+	DeletedByName string              `json:"deleted_by_name,omitempty" bson:"deleted_by_name,omitempty"`
+}
+
+func (cashRegister *CashRegister) UpdateForeignLabelFields() error {
+	if cashRegister.StoreID != nil {
+		store, err := FindStoreByID(cashRegister.StoreID, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		cashRegister.StoreName = store.Name
+	}
+
+	if cashRegister.CreatedBy != nil {
+		createdByUser, err := FindUserByID(cashRegister.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		cashRegister.CreatedByName = createdByUser.Name
+	}
+
+	if cashRegister.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(cashRegister.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		cashRegister.UpdatedByName = updatedByUser.Name
+	}
+
+	if cashRegister.DeletedBy != nil && !cashRegister.DeletedBy.IsZero() {
+		deletedByUser, err := FindUserByID(cashRegister.DeletedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		cashRegister.DeletedByName = deletedByUser.Name
+	}
+
+	return nil
+}
+
+func (store *Store) SearchCashRegister(w http.ResponseWriter, r *http.Request) (cashRegisters []CashRegister, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+	ParseDeletedFilter(r, &criterias)
+
+	ParseTextSearch(r, &criterias, "search[name]", "name")
+	ParseTextSearch(r, &criterias, "search[code]", "code")
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("cash_register")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	keys, ok := r.URL.Query()["select"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.Select = ParseSelectString(keys[0])
+	}
+
+	if criterias.Select != nil {
+		findOptions.SetProjection(criterias.Select)
+	}
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return cashRegisters, criterias, errors.New("Error fetching cash registers:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return cashRegisters, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		cashRegister := CashRegister{}
+		err = cur.Decode(&cashRegister)
+		if err != nil {
+			return cashRegisters, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		cashRegisters = append(cashRegisters, cashRegister)
+	}
+
+	return cashRegisters, criterias, nil
+}
+
+func (cashRegister *CashRegister) Validate(w http.ResponseWriter, r *http.Request, scenario string) (errs map[string]string) {
+	errs = make(map[string]string)
+	cashRegister.Name = strings.TrimSpace(cashRegister.Name)
+	cashRegister.Code = strings.ToUpper(strings.TrimSpace(cashRegister.Code))
+
+	store, err := FindStoreByID(cashRegister.StoreID, bson.M{})
+	if err != nil {
+		errs["store_id"] = "invalid store id"
+		w.WriteHeader(http.StatusBadRequest)
+		return errs
+	}
+
+	if scenario == "update" {
+		if cashRegister.ID.IsZero() {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = "ID is required"
+			return errs
+		}
+		exists, err := store.IsCashRegisterExists(&cashRegister.ID)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = err.Error()
+			return errs
+		}
+
+		if !exists {
+			errs["id"] = "Invalid Cash Register:" + cashRegister.ID.Hex()
+		}
+	}
+
+	if govalidator.IsNull(cashRegister.Name) {
+		errs["name"] = "Name is required"
+	}
+
+	if govalidator.IsNull(cashRegister.Code) {
+		errs["code"] = "Code is required"
+	} else {
+		collection := db.GetDB("store_" + store.ID.Hex()).Collection("cash_register")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		count, err := collection.CountDocuments(ctx, bson.M{
+			"code":    cashRegister.Code,
+			"deleted": bson.M{"$ne": true},
+			"_id":     bson.M{"$ne": cashRegister.ID},
+		})
+		if err != nil {
+			errs["code"] = err.Error()
+		} else if count > 0 {
+			errs["code"] = "Code is already used by another cash register"
+		}
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+func (cashRegister *CashRegister) Insert() error {
+	collection := db.GetDB("store_" + cashRegister.StoreID.Hex()).Collection("cash_register")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cashRegister.ID = primitive.NewObjectID()
+
+	err := cashRegister.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, &cashRegister)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cashRegister *CashRegister) Update() error {
+	collection := db.GetDB("store_" + cashRegister.StoreID.Hex()).Collection("cash_register")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := cashRegister.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": cashRegister.ID},
+		bson.M{"$set": cashRegister},
+		updateOptions,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cashRegister *CashRegister) DeleteCashRegister(tokenClaims TokenClaims) (err error) {
+	store, err := FindStoreByID(cashRegister.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	openShift, err := store.FindOpenRegisterShift(bson.M{"register_id": cashRegister.ID})
+	if err != nil {
+		return err
+	}
+	if openShift != nil {
+		return errors.New("close the open shift " + openShift.Code + " of this cash register first")
+	}
+
+	collection := db.GetDB("store_" + cashRegister.StoreID.Hex()).Collection("cash_register")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	cashRegister.Deleted = true
+	cashRegister.DeletedBy = &userID
+	now := time.Now()
+	cashRegister.DeletedAt = &now
+
+	err = cashRegister.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": cashRegister.ID},
+		bson.M{"$set": cashRegister},
+		updateOptions,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (store *Store) FindCashRegisterByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (cashRegister *CashRegister, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("cash_register")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{"_id": ID}, findOneOptions).
+		Decode(&cashRegister)
+	if err != nil {
+		return nil, err
+	}
+
+	return cashRegister, err
+}
+
+func (store *Store) IsCashRegisterExists(ID *primitive.ObjectID) (exists bool, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("cash_register")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	count := int64(0)
+
+	count, err = collection.CountDocuments(ctx, bson.M{
+		"_id": ID,
+	})
+
+	return (count > 0), err
+}
@@ -0,0 +1,485 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const CostCenterUnassigned = "UNASSIGNED"
+
+// Collections that keep a copy of the cost center name next to cost_center_id
+var costCenterTaggedCollections = []string{"expense", "order", "purchase", "repair_job", "posting"}
+
+// CostCenter : a department or branch section (e.g. workshop, spare parts counter) that expenses,
+// sales, purchases and journal lines can be tagged with to report profit per department.
+type CostCenter struct {
+	ID            primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Code          string              `bson:"code,omitempty" json:"code,omitempty"`
+	Name          string              `bson:"name" json:"name"`
+	Description   string              `bson:"description,omitempty" json:"description,omitempty"`
+	StoreID       *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName     string              `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted       bool                `bson:"deleted" json:"deleted"`
+	DeletedBy     *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt     *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt     *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt     *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy     *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy     *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName string              `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+}
+
+// CostCenterMovements : account movements per cost center, postings without a cost center are
+// kept under primitive.NilObjectID.
+type CostCenterMovements map[primitive.ObjectID]AccountMovements
+
+// ValidateCostCenter checks the optional cost center of a document and sets its name.
+// Returns an error message when the cost center is unknown or deleted.
+func (store *Store) ValidateCostCenter(costCenterID **primitive.ObjectID, costCenterName *string) string {
+	if *costCenterID == nil || (*costCenterID).IsZero() {
+		*costCenterID = nil
+		*costCenterName = ""
+		return ""
+	}
+
+	costCenter, err := store.FindCostCenterByID(*costCenterID, bson.M{"name": 1, "deleted": 1})
+	if err != nil || costCenter.Deleted {
+		return "Invalid cost center:" + (*costCenterID).Hex()
+	}
+	*costCenterName = costCenter.Name
+	return ""
+}
+
+// TagJournalsWithCostCenter sets the cost center of the document on every journal line
+// that has none of its own.
+func TagJournalsWithCostCenter(journals []Journal, costCenterID *primitive.ObjectID, costCenterName string) []Journal {
+	if costCenterID == nil || costCenterID.IsZero() {
+		return journals
+	}
+	for i := range journals {
+		if journals[i].CostCenterID == nil || journals[i].CostCenterID.IsZero() {
+			id := *costCenterID
+			journals[i].CostCenterID = &id
+			journals[i].CostCenterName = costCenterName
+		}
+	}
+	return journals
+}
+
+// FindCostCenterMovements sums the postings of every account per cost center between from and to
+// (both inclusive, either may be nil), leaving out the postings of the given reference models.
+func (store *Store) FindCostCenterMovements(from, to *time.Time, excludeReferenceModels ...string) (CostCenterMovements, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("posting")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{"store_id": store.ID}
+	dateFilter := bson.M{}
+	if from != nil {
+		dateFilter["$gte"] = from
+	}
+	if to != nil {
+		dateFilter["$lte"] = to
+	}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+	if len(excludeReferenceModels) > 0 {
+		filter["reference_model"] = bson.M{"$nin": excludeReferenceModels}
+	}
+
+	pipeline := []bson.M{
+		bson.M{"$match": filter},
+		bson.M{
+			"$group": bson.M{
+				"_id": bson.M{
+					"account_id":     "$account_id",
+					"cost_center_id": "$cost_center_id",
+				},
+				"debit_total":  bson.M{"$sum": "$debit_total"},
+				"credit_total": bson.M{"$sum": "$credit_total"},
+			},
+		},
+	}
+
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, errors.New("error aggregating postings: " + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	movements := CostCenterMovements{}
+	for cur.Next(ctx) {
+		stats := struct {
+			ID struct {
+				AccountID    *primitive.ObjectID `bson:"account_id"`
+				CostCenterID *primitive.ObjectID `bson:"cost_center_id"`
+			} `bson:"_id"`
+			DebitTotal  float64 `bson:"debit_total"`
+			CreditTotal float64 `bson:"credit_total"`
+		}{}
+		if err := cur.Decode(&stats); err != nil {
+			return nil, errors.New("cursor decode error: " + err.Error())
+		}
+		if stats.ID.AccountID == nil {
+			continue
+		}
+
+		costCenterID := primitive.NilObjectID
+		if stats.ID.CostCenterID != nil {
+			costCenterID = *stats.ID.CostCenterID
+		}
+		if movements[costCenterID] == nil {
+			movements[costCenterID] = AccountMovements{}
+		}
+		movements[costCenterID][*stats.ID.AccountID] = AccountMovement{
+			Debit:  RoundFloat(stats.DebitTotal, 2),
+			Credit: RoundFloat(stats.CreditTotal, 2),
+		}
+	}
+
+	return movements, cur.Err()
+}
+
+// BuildCostCenterIncomeStatement shows revenue, expenses and net profit of each period with one
+// column per cost center, postings without a cost center in UNASSIGNED and a TOTAL column.
+// Only cost centers with postings in one of the periods get a column, in the order of costCenters.
+func BuildCostCenterIncomeStatement(accounts map[primitive.ObjectID]*Account, costCenters []CostCenter, periods []StatementPeriod, movements []CostCenterMovements) *FinancialStatement {
+	used := map[primitive.ObjectID]bool{}
+	for p := range periods {
+		for costCenterID := range movements[p] {
+			used[costCenterID] = true
+		}
+	}
+
+	columns := []string{}
+	columnIndex := map[primitive.ObjectID]int{}
+	for _, costCenter := range costCenters {
+		if used[costCenter.ID] {
+			columnIndex[costCenter.ID] = len(columns)
+			columns = append(columns, costCenter.Name)
+		}
+	}
+	unknown := []primitive.ObjectID{}
+	for costCenterID := range used {
+		if _, ok := columnIndex[costCenterID]; !ok && !costCenterID.IsZero() {
+			unknown = append(unknown, costCenterID)
+		}
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Hex() < unknown[j].Hex() })
+	for _, costCenterID := range unknown {
+		columnIndex[costCenterID] = len(columns)
+		columns = append(columns, "UNKNOWN COST CENTER")
+	}
+	if used[primitive.NilObjectID] {
+		columnIndex[primitive.NilObjectID] = len(columns)
+		columns = append(columns, CostCenterUnassigned)
+	}
+	total := len(columns)
+	columns = append(columns, "TOTAL")
+
+	builder := newStatementBuilder(FinancialStatementCostCenterIncome, accounts, periods, columns)
+	for p := range periods {
+		base := p * len(columns)
+		for costCenterID, accountMovements := range movements[p] {
+			for id, movement := range accountMovements {
+				value := 0.0
+				sectionType := StatementAccountType(builder.account(id), movement.Debit, movement.Credit)
+				switch sectionType {
+				case "revenue":
+					value = movement.Credit - movement.Debit
+				case "expense":
+					value = movement.Debit - movement.Credit
+				default:
+					continue
+				}
+				builder.add(sectionType, id, base+columnIndex[costCenterID], value)
+				builder.add(sectionType, id, base+total, value)
+			}
+		}
+	}
+
+	builder.build([]string{"revenue", "expense"})
+	netProfit := make([]float64, builder.width)
+	for i := range netProfit {
+		netProfit[i] = RoundTo2Decimals(builder.section("revenue").Total[i] - builder.section("expense").Total[i])
+	}
+	builder.summary("NET PROFIT", netProfit)
+	return builder.statement
+}
+
+func (costCenter *CostCenter) Validate(w http.ResponseWriter, r *http.Request, scenario string) (errs map[string]string) {
+	errs = make(map[string]string)
+
+	store, err := FindStoreByID(costCenter.StoreID, bson.M{})
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errs["store_id"] = "invalid store id"
+		return errs
+	}
+
+	if scenario == "update" {
+		if costCenter.ID.IsZero() {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = "ID is required"
+			return errs
+		}
+	}
+
+	costCenter.Name = strings.ToUpper(strings.TrimSpace(costCenter.Name))
+	costCenter.Code = strings.ToUpper(strings.TrimSpace(costCenter.Code))
+
+	if govalidator.IsNull(costCenter.Name) {
+		errs["name"] = "Name is required"
+	} else {
+		exists, err := store.IsCostCenterExists(bson.M{"name": costCenter.Name}, &costCenter.ID)
+		if err != nil {
+			errs["name"] = err.Error()
+		} else if exists {
+			errs["name"] = "Name is already in use"
+		}
+	}
+
+	if !govalidator.IsNull(costCenter.Code) {
+		exists, err := store.IsCostCenterExists(bson.M{"code": costCenter.Code}, &costCenter.ID)
+		if err != nil {
+			errs["code"] = err.Error()
+		} else if exists {
+			errs["code"] = "Code is already in use"
+		}
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+// IsCostCenterExists tells if another cost center (not deleted) matches the filter.
+func (store *Store) IsCostCenterExists(filter bson.M, excludeID *primitive.ObjectID) (exists bool, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("cost_center")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter["store_id"] = store.ID
+	filter["deleted"] = bson.M{"$ne": true}
+	if excludeID != nil && !excludeID.IsZero() {
+		filter["_id"] = bson.M{"$ne": excludeID}
+	}
+
+	count, err := collection.CountDocuments(ctx, filter)
+	return (count > 0), err
+}
+
+func (costCenter *CostCenter) UpdateForeignLabelFields() error {
+	store, err := FindStoreByID(costCenter.StoreID, bson.M{"id": 1, "name": 1})
+	if err != nil {
+		return err
+	}
+	costCenter.StoreName = store.Name
+
+	if costCenter.CreatedBy != nil {
+		createdByUser, err := FindUserByID(costCenter.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		costCenter.CreatedByName = createdByUser.Name
+	}
+
+	if costCenter.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(costCenter.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		costCenter.UpdatedByName = updatedByUser.Name
+	}
+
+	return nil
+}
+
+// AttributesValueChangeEvent keeps the cost center name copied on documents and postings in sync.
+func (costCenter *CostCenter) AttributesValueChangeEvent(costCenterOld *CostCenter) error {
+	if costCenter.Name == costCenterOld.Name {
+		return nil
+	}
+
+	store, err := FindStoreByID(costCenter.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	for _, collectionName := range costCenterTaggedCollections {
+		err := store.UpdateManyByCollectionName(
+			collectionName,
+			bson.M{"cost_center_id": costCenter.ID},
+			bson.M{"$set": bson.M{"cost_center_name": costCenter.Name}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (costCenter *CostCenter) Insert() error {
+	collection := db.GetDB("store_" + costCenter.StoreID.Hex()).Collection("cost_center")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	costCenter.ID = primitive.NewObjectID()
+
+	err := costCenter.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, &costCenter)
+	return err
+}
+
+func (costCenter *CostCenter) Update() error {
+	collection := db.GetDB("store_" + costCenter.StoreID.Hex()).Collection("cost_center")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := costCenter.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": costCenter.ID},
+		bson.M{"$set": costCenter},
+		updateOptions,
+	)
+	return err
+}
+
+// DeleteCostCenter soft deletes the cost center, documents and postings already tagged keep it.
+func (costCenter *CostCenter) DeleteCostCenter(tokenClaims TokenClaims) (err error) {
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	costCenter.Deleted = true
+	costCenter.DeletedBy = &userID
+	now := time.Now()
+	costCenter.DeletedAt = &now
+
+	return costCenter.Update()
+}
+
+func (store *Store) FindCostCenterByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (costCenter *CostCenter, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("cost_center")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{
+			"_id":      ID,
+			"store_id": store.ID,
+		}, findOneOptions).
+		Decode(&costCenter)
+	if err != nil {
+		return nil, err
+	}
+
+	return costCenter, err
+}
+
+// FindCostCenters returns every cost center of the store ordered by code and name,
+// deleted ones included because their postings still count.
+func (store *Store) FindCostCenters() ([]CostCenter, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("cost_center")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cur, err := collection.Find(ctx, bson.M{"store_id": store.ID},
+		options.Find().SetSort(bson.D{{Key: "code", Value: 1}, {Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, errors.New("error fetching cost centers: " + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	costCenters := []CostCenter{}
+	for cur.Next(ctx) {
+		costCenter := CostCenter{}
+		if err := cur.Decode(&costCenter); err != nil {
+			return nil, errors.New("cursor decode error: " + err.Error())
+		}
+		costCenters = append(costCenters, costCenter)
+	}
+
+	return costCenters, cur.Err()
+}
+
+func (store *Store) SearchCostCenter(w http.ResponseWriter, r *http.Request) (costCenters []CostCenter, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+
+	ParseDeletedFilter(r, &criterias)
+
+	ParseTextSearch(r, &criterias, "search[name]", "name")
+
+	ParseTextSearch(r, &criterias, "search[code]", "code")
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("cost_center")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return costCenters, criterias, errors.New("Error fetching cost centers:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return costCenters, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		costCenter := CostCenter{}
+		err = cur.Decode(&costCenter)
+		if err != nil {
+			return costCenters, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		costCenters = append(costCenters, costCenter)
+	}
+
+	return costCenters, criterias, nil
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ── TagJournalsWithCostCenter ────────────────────────────────────────────────
+
+func TestTagJournalsWithCostCenter(t *testing.T) {
+	workshop, counter := primitive.NewObjectID(), primitive.NewObjectID()
+	journals := []Journal{
+		{AccountName: "SALES"},
+		{AccountName: "CASH", CostCenterID: &counter, CostCenterName: "COUNTER"},
+	}
+
+	journals = TagJournalsWithCostCenter(journals, &workshop, "WORKSHOP")
+	if journals[0].CostCenterID == nil || *journals[0].CostCenterID != workshop || journals[0].CostCenterName != "WORKSHOP" {
+		t.Errorf("untagged line = %+v, want the document's cost center", journals[0])
+	}
+	if *journals[1].CostCenterID != counter || journals[1].CostCenterName != "COUNTER" {
+		t.Errorf("tagged line = %+v, want its own cost center kept", journals[1])
+	}
+
+	journals = TagJournalsWithCostCenter([]Journal{{AccountName: "SALES"}}, nil, "")
+	if journals[0].CostCenterID != nil {
+		t.Errorf("line = %+v, want no cost center", journals[0])
+	}
+}
+
+func TestBuildJournalVoucherJournals_CarriesLineCostCenter(t *testing.T) {
+	workshop := primitive.NewObjectID()
+	date := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	voucher := &JournalVoucher{
+		Date: &date,
+		Lines: []JournalVoucherLine{
+			{AccountID: primitive.NewObjectID(), AccountName: "RENT", Debit: 100, CostCenterID: &workshop, CostCenterName: "WORKSHOP"},
+			{AccountID: primitive.NewObjectID(), AccountName: "BANK", Credit: 100},
+		},
+	}
+
+	journals := BuildJournalVoucherJournals(voucher, &date)
+	if journals[0].CostCenterID == nil || *journals[0].CostCenterID != workshop || journals[0].CostCenterName != "WORKSHOP" {
+		t.Errorf("debit journal = %+v, want the line's cost center", journals[0])
+	}
+	if journals[1].CostCenterID != nil {
+		t.Errorf("credit journal = %+v, want no cost center", journals[1])
+	}
+}
+
+// ── BuildCostCenterIncomeStatement ───────────────────────────────────────────
+
+func TestBuildCostCenterIncomeStatement(t *testing.T) {
+	sales := Account{ID: primitive.NewObjectID(), Name: "SALES", Type: "revenue"}
+	rent := Account{ID: primitive.NewObjectID(), Name: "RENT EXPENSE", Type: "expense"}
+	cash := Account{ID: primitive.NewObjectID(), Name: "CASH", Type: "asset"}
+	accounts := map[primitive.ObjectID]*Account{sales.ID: &sales, rent.ID: &rent, cash.ID: &cash}
+
+	workshop := CostCenter{ID: primitive.NewObjectID(), Name: "WORKSHOP"}
+	counter := CostCenter{ID: primitive.NewObjectID(), Name: "SPARE PARTS COUNTER"}
+	idle := CostCenter{ID: primitive.NewObjectID(), Name: "IDLE"}
+
+	movements := []CostCenterMovements{{
+		workshop.ID: AccountMovements{
+			sales.ID: {Credit: 1000},
+			rent.ID:  {Debit: 300},
+			cash.ID:  {Debit: 1000},
+		},
+		counter.ID: AccountMovements{
+			sales.ID: {Credit: 500, Debit: 50},
+		},
+		primitive.NilObjectID: AccountMovements{
+			rent.ID: {Debit: 100},
+		},
+	}}
+
+	statement := BuildCostCenterIncomeStatement(accounts, []CostCenter{counter, idle, workshop}, []StatementPeriod{{Label: "March"}}, movements)
+
+	wantColumns := []string{"SPARE PARTS COUNTER", "WORKSHOP", CostCenterUnassigned, "TOTAL"}
+	if len(statement.Columns) != len(wantColumns) {
+		t.Fatalf("columns = %v, want %v", statement.Columns, wantColumns)
+	}
+	for i, column := range wantColumns {
+		if statement.Columns[i] != column {
+			t.Errorf("column %d = %s, want %s", i, statement.Columns[i], column)
+		}
+	}
+
+	if len(statement.Sections) != 2 {
+		t.Fatalf("sections = %d, want revenue and expense only", len(statement.Sections))
+	}
+	revenue, expense := statement.Sections[0], statement.Sections[1]
+	if got := revenue.Total; got[0] != 450 || got[1] != 1000 || got[2] != 0 || got[3] != 1450 {
+		t.Errorf("revenue = %v", got)
+	}
+	if got := expense.Total; got[0] != 0 || got[1] != 300 || got[2] != 100 || got[3] != 400 {
+		t.Errorf("expenses = %v", got)
+	}
+
+	netProfit := statement.Summary[0]
+	if netProfit.AccountName != "NET PROFIT" || netProfit.Values[0] != 450 || netProfit.Values[1] != 700 || netProfit.Values[2] != -100 || netProfit.Values[3] != 1050 {
+		t.Errorf("net profit = %+v", netProfit)
+	}
+}
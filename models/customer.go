@@ -111,8 +111,10 @@ type Customer struct {
 	RegistrationNumber         string                   `bson:"registration_number,omitempty" json:"registration_number,omitempty"`
 	RegistrationNumberInArabic string                   `bson:"registration_number_arabic,omitempty" json:"registration_number_in_arabic,omitempty"`
 	ContactPerson              string                   `bson:"contact_person,omitempty" json:"contact_person,omitempty"`
+	CustomerGroup              string                   `bson:"customer_group,omitempty" json:"customer_group,omitempty"`
 	CreditLimit                float64                  `bson:"credit_limit" json:"credit_limit"`
 	CreditBalance              float64                  `json:"credit_balance" bson:"credit_balance"`
+	LoyaltyPoints              float64                  `json:"loyalty_points" bson:"loyalty_points"`
 	Account                    *Account                 `json:"account" bson:"account"`
 	Deleted                    bool                     `bson:"deleted" json:"deleted"`
 	DeletedBy                  *primitive.ObjectID      `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
@@ -2015,6 +2017,8 @@ func (store *Store) SearchCustomer(w http.ResponseWriter, r *http.Request) (cust
 		//criterias.SearchBy["vat_no"] = map[string]interface{}{"$regex": keys[0], "$options": "i"}
 	}
 
+	ParseTextSearch(r, &criterias, "search[customer_group]", "customer_group")
+
 	keys, ok = r.URL.Query()["search[email]"]
 	if ok && len(keys[0]) >= 1 {
 		criterias.SearchBy["$text"] = bson.M{"$search": keys[0]}
@@ -2194,6 +2198,7 @@ func (customer *Customer) TrimSpaceFromFields() {
 	customer.Phone = strings.TrimSpace(customer.Phone)
 	customer.VATNo = strings.TrimSpace(customer.VATNo)
 	customer.RegistrationNumber = strings.TrimSpace(customer.RegistrationNumber)
+	customer.CustomerGroup = strings.TrimSpace(customer.CustomerGroup)
 	customer.Email = strings.TrimSpace(customer.Email)
 	customer.Address = strings.TrimSpace(customer.Address)
 	customer.AddressInArabic = strings.TrimSpace(customer.AddressInArabic)
@@ -4022,6 +4027,8 @@ func (store *Store) BuildCustomerCriterias(w http.ResponseWriter, r *http.Reques
 		//criterias.SearchBy["vat_no"] = map[string]interface{}{"$regex": keys[0], "$options": "i"}
 	}
 
+	ParseTextSearch(r, &criterias, "search[customer_group]", "customer_group")
+
 	keys, ok = r.URL.Query()["search[email]"]
 	if ok && len(keys[0]) >= 1 {
 		criterias.SearchBy["$text"] = bson.M{"$search": keys[0]}
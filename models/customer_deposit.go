@@ -96,6 +96,39 @@ type ReceivablePayment struct {
 	UpdatedByName string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
 	StoreID       *primitive.ObjectID `json:"store_id" bson:"store_id"`
 	StoreName     string              `json:"store_name" bson:"store_name"`
+	ShiftID       *primitive.ObjectID `json:"shift_id,omitempty" bson:"shift_id,omitempty"`
+}
+
+// LinkPaymentsToShift keeps the shift of the payments already saved and links
+// new payments to the open shift of the user who took them.
+func (customerdeposit *CustomerDeposit) LinkPaymentsToShift() error {
+	shiftIDs := map[primitive.ObjectID]*primitive.ObjectID{}
+	if !customerdeposit.ID.IsZero() {
+		store, err := FindStoreByID(customerdeposit.StoreID, bson.M{})
+		if err != nil {
+			return err
+		}
+
+		old, err := store.FindCustomerDepositByID(&customerdeposit.ID, bson.M{"payments": 1})
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+		if old != nil {
+			for _, payment := range old.Payments {
+				shiftIDs[payment.ID] = payment.ShiftID
+			}
+		}
+	}
+
+	for i, payment := range customerdeposit.Payments {
+		if shiftID, ok := shiftIDs[payment.ID]; ok {
+			customerdeposit.Payments[i].ShiftID = shiftID
+		} else if payment.ShiftID == nil {
+			customerdeposit.Payments[i].ShiftID = FindOpenShiftID(customerdeposit.StoreID, payment.CreatedBy)
+		}
+	}
+
+	return nil
 }
 
 func (model *CustomerDeposit) SetPostBalances() error {
@@ -876,6 +909,12 @@ func (customerDeposit *CustomerDeposit) Validate(w http.ResponseWriter, r *http.
 		customerDeposit.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, customerDeposit.StoreID, "customer_deposit", customerDeposit.ID, customerDeposit.Code, customerDeposit.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	for index, payment := range customerDeposit.Payments {
 		if payment.ID.IsZero() {
 			customerDeposit.Payments[index].ID = primitive.NewObjectID()
@@ -1418,6 +1457,11 @@ func (customerdeposit *CustomerDeposit) Insert() (err error) {
 		return err
 	}
 
+	err = customerdeposit.LinkPaymentsToShift()
+	if err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Second)
 	defer cancel()
 	_, err = collection.InsertOne(ctx, &customerdeposit)
@@ -1474,6 +1518,11 @@ func (customerdeposit *CustomerDeposit) Update() error {
 		return err
 	}
 
+	err = customerdeposit.LinkPaymentsToShift()
+	if err != nil {
+		return err
+	}
+
 	_, err = collection.UpdateOne(
 		ctx,
 		bson.M{"_id": customerdeposit.ID},
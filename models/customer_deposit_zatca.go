@@ -1,16 +1,14 @@
 package models
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
-	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -89,6 +87,14 @@ func (deposit *CustomerDeposit) RecordZatcaReportingFailure(errorMessage string)
 	deposit.Zatca.ReportingFailedCount++
 	deposit.Zatca.ReportingErrors = append(deposit.Zatca.ReportingErrors, errorMessage)
 	deposit.Zatca.ReportingLastFailedAt = &now
+	queueZatcaReporting(deposit.StoreID, ZatcaQueueItem{
+		DocumentType:      ZatcaDocumentCustomerDeposit,
+		DocumentID:        deposit.ID,
+		DocumentCode:      deposit.Code,
+		InvoiceCountValue: deposit.InvoiceCountValue,
+		DocumentDate:      deposit.Date,
+		IsSimplified:      deposit.Zatca.IsSimplified,
+	}, errorMessage)
 	return nil
 }
 
@@ -98,6 +104,7 @@ func (deposit *CustomerDeposit) RecordZatcaReportingSuccess(reportingResponse Za
 	deposit.Zatca.ReportedAt = &now
 	deposit.Zatca.ReportingInvoiceHash = reportingResponse.InvoiceHash
 	deposit.Hash = reportingResponse.InvoiceHash
+	resolveZatcaQueueItem(deposit.StoreID, ZatcaDocumentCustomerDeposit, deposit.ID)
 	return nil
 }
 
@@ -161,6 +168,7 @@ func (deposit *CustomerDeposit) MakeXMLContent() (string, error) {
 	} else if vendor != nil && !govalidator.IsNull(vendor.VATNo) && IsValidDigitNumber(vendor.VATNo, "15") {
 		isSimplified = false
 	}
+	deposit.Zatca.IsSimplified = isSimplified
 
 	if isSimplified {
 		invoice.InvoiceTypeCode.Name = "0200000"
@@ -448,13 +456,7 @@ func (deposit *CustomerDeposit) MakeXMLContent() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	updatedXML2 := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" + string(updatedXML)
-
-	filePath := "ZatcaPython/templates/debit_note_" + deposit.Code + ".xml"
-	if err = os.WriteFile(filePath, []byte(updatedXML2), 0644); err != nil {
-		return "", err
-	}
-	return "", nil
+	return "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" + string(updatedXML), nil
 }
 
 func (deposit *CustomerDeposit) ReportToZatca() error {
@@ -463,63 +465,12 @@ func (deposit *CustomerDeposit) ReportToZatca() error {
 		return errors.New("error finding store: " + err.Error())
 	}
 
-	isSimplified := true
-	if deposit.CustomerID != nil && !deposit.CustomerID.IsZero() {
-		customer, err := store.FindCustomerByID(deposit.CustomerID, bson.M{})
-		if err == nil && customer != nil && customer.IsB2B() {
-			isSimplified = false
-		}
-	} else if deposit.VendorID != nil && !deposit.VendorID.IsZero() {
-		vendor, err := store.FindVendorByID(deposit.VendorID, bson.M{})
-		if err == nil && vendor != nil && !govalidator.IsNull(vendor.VATNo) && IsValidDigitNumber(vendor.VATNo, "15") {
-			isSimplified = false
-		}
-	}
-
-	if _, err = deposit.MakeXMLContent(); err != nil {
-		return errors.New("error making xml: " + err.Error())
-	}
-
-	payload := map[string]interface{}{
-		"env":                              store.Zatca.Env,
-		"private_key":                      store.Zatca.PrivateKey,
-		"production_binary_security_token": store.Zatca.ProductionBinarySecurityToken,
-		"production_secret":                store.Zatca.ProductionSecret,
-		"xml_file_path":                    "ZatcaPython/templates/debit_note_" + deposit.Code + ".xml",
-		"is_simplified":                    isSimplified,
-		"store_id":                         store.ID.Hex(),
-	}
-
-	jsonData, err := json.Marshal(payload)
+	xmlContent, err := deposit.MakeXMLContent()
 	if err != nil {
-		return err
-	}
-
-	cmd := exec.Command("ZatcaPython/venv/bin/python", "ZatcaPython/reporting_and_clearance.py")
-	cmd.Stdin = bytes.NewReader(jsonData)
-	var output bytes.Buffer
-	cmd.Stdout = &output
-	cmd.Stderr = &output
-
-	reportingResponse := ZatcaReportingResponse{}
-	err = cmd.Run()
-	if err != nil {
-		if jsonErr := json.Unmarshal(output.Bytes(), &reportingResponse); jsonErr == nil && reportingResponse.Error != "" {
-			errMsg := "error running reporting script: " + reportingResponse.Error
-			deposit.RecordZatcaReportingFailure(errMsg)
-			return errors.New(errMsg)
-		}
-		errMsg := "error running reporting script: " + err.Error()
-		deposit.RecordZatcaReportingFailure(errMsg)
-		return errors.New(errMsg)
-	}
-
-	if err = json.Unmarshal(output.Bytes(), &reportingResponse); err != nil {
-		errMsg := "error unmarshal reporting response: " + err.Error()
-		deposit.RecordZatcaReportingFailure(errMsg)
-		return errors.New(errMsg)
+		return errors.New("error making xml: " + err.Error())
 	}
 
+	reportingResponse := store.SubmitToZatca(xmlContent)
 	if reportingResponse.Error != "" || !reportingResponse.ReportingPassed {
 		errMsg := "error reporting: " + reportingResponse.Error
 		deposit.RecordZatcaReportingFailure(errMsg)
@@ -539,8 +490,8 @@ func (deposit *CustomerDeposit) SaveClearedInvoiceData(reportingResponse ZatcaRe
 		return err
 	}
 
-	xmlResponseFilePath := "zatca/" + deposit.StoreID.Hex() + "/receivables/xml/" + deposit.Code + ".xml"
-	if err = os.MkdirAll("zatca/"+deposit.StoreID.Hex()+"/receivables/xml", 0755); err != nil {
+	xmlResponseFilePath := ZatcaXMLPath(deposit.StoreID, ZatcaDocumentCustomerDeposit, deposit.Code)
+	if err = os.MkdirAll(filepath.Dir(xmlResponseFilePath), 0755); err != nil {
 		return err
 	}
 	if err = os.WriteFile(xmlResponseFilePath, xmlData, 0644); err != nil {
@@ -591,12 +542,6 @@ func (deposit *CustomerDeposit) SaveClearedInvoiceData(reportingResponse ZatcaRe
 		}
 	}
 	deposit.Zatca.IsSimplified = reportingResponse.IsSimplified
-
-	// Delete temp XML
-	xmlFilePath := "ZatcaPython/templates/debit_note_" + deposit.Code + ".xml"
-	if _, err := os.Stat(xmlFilePath); err == nil {
-		os.Remove(xmlFilePath)
-	}
 	return nil
 }
 
@@ -842,6 +842,12 @@ func (customerWithdrawal *CustomerWithdrawal) Validate(w http.ResponseWriter, r
 		customerWithdrawal.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, customerWithdrawal.StoreID, "customer_withdrawal", customerWithdrawal.ID, customerWithdrawal.Code, customerWithdrawal.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	for index, payment := range customerWithdrawal.Payments {
 		if payment.ID.IsZero() {
 			customerWithdrawal.Payments[index].ID = primitive.NewObjectID()
@@ -1,16 +1,14 @@
 package models
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
-	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -69,6 +67,14 @@ func (withdrawal *CustomerWithdrawal) RecordZatcaReportingFailure(errorMessage s
 	withdrawal.Zatca.ReportingFailedCount++
 	withdrawal.Zatca.ReportingErrors = append(withdrawal.Zatca.ReportingErrors, errorMessage)
 	withdrawal.Zatca.ReportingLastFailedAt = &now
+	queueZatcaReporting(withdrawal.StoreID, ZatcaQueueItem{
+		DocumentType:      ZatcaDocumentCustomerWithdrawal,
+		DocumentID:        withdrawal.ID,
+		DocumentCode:      withdrawal.Code,
+		InvoiceCountValue: withdrawal.InvoiceCountValue,
+		DocumentDate:      withdrawal.Date,
+		IsSimplified:      withdrawal.Zatca.IsSimplified,
+	}, errorMessage)
 	return nil
 }
 
@@ -78,6 +84,7 @@ func (withdrawal *CustomerWithdrawal) RecordZatcaReportingSuccess(reportingRespo
 	withdrawal.Zatca.ReportedAt = &now
 	withdrawal.Zatca.ReportingInvoiceHash = reportingResponse.InvoiceHash
 	withdrawal.Hash = reportingResponse.InvoiceHash
+	resolveZatcaQueueItem(withdrawal.StoreID, ZatcaDocumentCustomerWithdrawal, withdrawal.ID)
 	return nil
 }
 
@@ -141,6 +148,7 @@ func (withdrawal *CustomerWithdrawal) MakeXMLContent() (string, error) {
 	} else if vendor != nil && !govalidator.IsNull(vendor.VATNo) && IsValidDigitNumber(vendor.VATNo, "15") {
 		isSimplified = false
 	}
+	withdrawal.Zatca.IsSimplified = isSimplified
 
 	if isSimplified {
 		invoice.InvoiceTypeCode.Name = "0200000"
@@ -427,13 +435,7 @@ func (withdrawal *CustomerWithdrawal) MakeXMLContent() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	updatedXML2 := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" + string(updatedXML)
-
-	filePath := "ZatcaPython/templates/credit_note_" + withdrawal.Code + ".xml"
-	if err = os.WriteFile(filePath, []byte(updatedXML2), 0644); err != nil {
-		return "", err
-	}
-	return "", nil
+	return "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" + string(updatedXML), nil
 }
 
 func (withdrawal *CustomerWithdrawal) ReportToZatca() error {
@@ -442,63 +444,12 @@ func (withdrawal *CustomerWithdrawal) ReportToZatca() error {
 		return errors.New("error finding store: " + err.Error())
 	}
 
-	isSimplified := true
-	if withdrawal.CustomerID != nil && !withdrawal.CustomerID.IsZero() {
-		customer, err := store.FindCustomerByID(withdrawal.CustomerID, bson.M{})
-		if err == nil && customer != nil && customer.IsB2B() {
-			isSimplified = false
-		}
-	} else if withdrawal.VendorID != nil && !withdrawal.VendorID.IsZero() {
-		vendor, err := store.FindVendorByID(withdrawal.VendorID, bson.M{})
-		if err == nil && vendor != nil && !govalidator.IsNull(vendor.VATNo) && IsValidDigitNumber(vendor.VATNo, "15") {
-			isSimplified = false
-		}
-	}
-
-	if _, err = withdrawal.MakeXMLContent(); err != nil {
-		return errors.New("error making xml: " + err.Error())
-	}
-
-	payload := map[string]interface{}{
-		"env":                              store.Zatca.Env,
-		"private_key":                      store.Zatca.PrivateKey,
-		"production_binary_security_token": store.Zatca.ProductionBinarySecurityToken,
-		"production_secret":                store.Zatca.ProductionSecret,
-		"xml_file_path":                    "ZatcaPython/templates/credit_note_" + withdrawal.Code + ".xml",
-		"is_simplified":                    isSimplified,
-		"store_id":                         store.ID.Hex(),
-	}
-
-	jsonData, err := json.Marshal(payload)
+	xmlContent, err := withdrawal.MakeXMLContent()
 	if err != nil {
-		return err
-	}
-
-	cmd := exec.Command("ZatcaPython/venv/bin/python", "ZatcaPython/reporting_and_clearance.py")
-	cmd.Stdin = bytes.NewReader(jsonData)
-	var output bytes.Buffer
-	cmd.Stdout = &output
-	cmd.Stderr = &output
-
-	reportingResponse := ZatcaReportingResponse{}
-	err = cmd.Run()
-	if err != nil {
-		if jsonErr := json.Unmarshal(output.Bytes(), &reportingResponse); jsonErr == nil && reportingResponse.Error != "" {
-			errMsg := "error running reporting script: " + reportingResponse.Error
-			withdrawal.RecordZatcaReportingFailure(errMsg)
-			return errors.New(errMsg)
-		}
-		errMsg := "error running reporting script: " + err.Error()
-		withdrawal.RecordZatcaReportingFailure(errMsg)
-		return errors.New(errMsg)
-	}
-
-	if err = json.Unmarshal(output.Bytes(), &reportingResponse); err != nil {
-		errMsg := "error unmarshal reporting response: " + err.Error()
-		withdrawal.RecordZatcaReportingFailure(errMsg)
-		return errors.New(errMsg)
+		return errors.New("error making xml: " + err.Error())
 	}
 
+	reportingResponse := store.SubmitToZatca(xmlContent)
 	if reportingResponse.Error != "" || !reportingResponse.ReportingPassed {
 		errMsg := "error reporting: " + reportingResponse.Error
 		withdrawal.RecordZatcaReportingFailure(errMsg)
@@ -518,8 +469,8 @@ func (withdrawal *CustomerWithdrawal) SaveClearedInvoiceData(reportingResponse Z
 		return err
 	}
 
-	xmlResponseFilePath := "zatca/" + withdrawal.StoreID.Hex() + "/payables/xml/" + withdrawal.Code + ".xml"
-	if err = os.MkdirAll("zatca/"+withdrawal.StoreID.Hex()+"/payables/xml", 0755); err != nil {
+	xmlResponseFilePath := ZatcaXMLPath(withdrawal.StoreID, ZatcaDocumentCustomerWithdrawal, withdrawal.Code)
+	if err = os.MkdirAll(filepath.Dir(xmlResponseFilePath), 0755); err != nil {
 		return err
 	}
 	if err = os.WriteFile(xmlResponseFilePath, xmlData, 0644); err != nil {
@@ -570,12 +521,6 @@ func (withdrawal *CustomerWithdrawal) SaveClearedInvoiceData(reportingResponse Z
 		}
 	}
 	withdrawal.Zatca.IsSimplified = reportingResponse.IsSimplified
-
-	// Delete temp XML
-	xmlFilePath := "ZatcaPython/templates/credit_note_" + withdrawal.Code + ".xml"
-	if _, err := os.Stat(xmlFilePath); err == nil {
-		os.Remove(xmlFilePath)
-	}
 	return nil
 }
 
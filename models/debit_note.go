@@ -0,0 +1,647 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/exp/slices"
+)
+
+const DebitNoteReferenceModel = "debit_note"
+
+// DebitNote : an extra charge billed against a sales invoice after it was issued, such as a price
+// increase, reported to ZATCA as a debit note referencing the invoice.
+type DebitNote struct {
+	ID                  primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Code                string              `bson:"code,omitempty" json:"code,omitempty"`
+	Date                *time.Time          `bson:"date,omitempty" json:"date,omitempty"`
+	DateStr             string              `json:"date_str,omitempty" bson:"-"`
+	OrderID             *primitive.ObjectID `json:"order_id" bson:"order_id"`
+	OrderCode           string              `bson:"order_code" json:"order_code"`
+	CustomerID          *primitive.ObjectID `json:"customer_id" bson:"customer_id"`
+	CustomerName        string              `json:"customer_name" bson:"customer_name"`
+	CustomerNameArabic  string              `json:"customer_name_arabic" bson:"customer_name_arabic"`
+	Reason              string              `bson:"reason" json:"reason"`
+	Products            []DebitNoteProduct  `bson:"products" json:"products"`
+	VatPercent          float64             `bson:"vat_percent" json:"vat_percent"`
+	Total               float64             `bson:"total" json:"total"`
+	VatPrice            float64             `bson:"vat_price" json:"vat_price"`
+	NetTotal            float64             `bson:"net_total" json:"net_total"`
+	PaymentMethod       string              `bson:"payment_method" json:"payment_method"` // customer_account, cash or a bank method
+	Remarks             string              `bson:"remarks,omitempty" json:"remarks,omitempty"`
+	InvoiceCountValue   int64               `bson:"invoice_count_value,omitempty" json:"invoice_count_value,omitempty"`
+	UUID                string              `bson:"uuid,omitempty" json:"uuid,omitempty"`
+	Hash                string              `bson:"hash,omitempty" json:"hash,omitempty"`
+	PrevHash            string              `bson:"prev_hash,omitempty" json:"prev_hash,omitempty"`
+	Zatca               ZatcaReporting      `bson:"zatca,omitempty" json:"zatca,omitempty"`
+	EnableReportToZatca bool                `json:"enable_report_to_zatca" bson:"-"`
+	StoreID             *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName           string              `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted             bool                `bson:"deleted" json:"deleted"`
+	DeletedBy           *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt           *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt           *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt           *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy           *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy           *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName       string              `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName       string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+}
+
+// DebitNoteProduct : a line of the charge, priced without VAT
+type DebitNoteProduct struct {
+	ProductID    *primitive.ObjectID `json:"product_id,omitempty" bson:"product_id,omitempty"`
+	Name         string              `bson:"name" json:"name"`
+	NameInArabic string              `bson:"name_in_arabic,omitempty" json:"name_in_arabic,omitempty"`
+	Unit         string              `bson:"unit,omitempty" json:"unit,omitempty"`
+	Quantity     float64             `bson:"quantity" json:"quantity"`
+	UnitPrice    float64             `bson:"unit_price" json:"unit_price"`
+	LineTotal    float64             `bson:"line_total" json:"line_total"`
+	VatPrice     float64             `bson:"vat_price" json:"vat_price"`
+}
+
+// GetZatcaUnit is the unit code of the line, a service "one" unless it is set.
+func (product DebitNoteProduct) GetZatcaUnit() string {
+	return OrderProduct{Unit: product.Unit, IsService: true}.GetZatcaUnit()
+}
+
+// FindNetTotal totals the lines. The VAT of the note is worked out on its total, as it is
+// reported, and the VAT of each line on the line.
+func (debitNote *DebitNote) FindNetTotal() {
+	total := float64(0.00)
+	for i := range debitNote.Products {
+		product := &debitNote.Products[i]
+		product.LineTotal = RoundTo2Decimals(product.UnitPrice * product.Quantity)
+		product.VatPrice = RoundTo2Decimals(product.LineTotal * debitNote.VatPercent / 100)
+		total += product.LineTotal
+	}
+	debitNote.Total = RoundTo2Decimals(total)
+	debitNote.VatPrice = RoundTo2Decimals(debitNote.Total * debitNote.VatPercent / 100)
+	debitNote.NetTotal = RoundTo2Decimals(debitNote.Total + debitNote.VatPrice)
+}
+
+func (debitNote *DebitNote) Validate(w http.ResponseWriter, r *http.Request, scenario string, debitNoteOld *DebitNote) (errs map[string]string) {
+	errs = make(map[string]string)
+
+	store, err := FindStoreByID(debitNote.StoreID, bson.M{})
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errs["store_id"] = "invalid store id"
+		return errs
+	}
+
+	if scenario == "update" {
+		if debitNote.ID.IsZero() {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = "ID is required"
+			return errs
+		}
+		if debitNoteOld.Zatca.ReportingPassed {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["zatca"] = "A debit note reported to ZATCA can not be changed, issue another debit or credit note instead"
+			return errs
+		}
+	}
+
+	var order *Order
+	if debitNote.OrderID == nil || debitNote.OrderID.IsZero() {
+		errs["order_id"] = "The sales invoice is required"
+	} else {
+		order, err = store.FindOrderByID(debitNote.OrderID, bson.M{})
+		if err != nil {
+			errs["order_id"] = "Invalid sales invoice"
+		}
+	}
+
+	if order != nil {
+		debitNote.OrderCode = order.Code
+		debitNote.CustomerID = order.CustomerID
+		debitNote.CustomerName = order.CustomerName
+		debitNote.CustomerNameArabic = order.CustomerNameArabic
+		debitNote.VatPercent = store.VatPercent
+		if order.VatPercent != nil {
+			debitNote.VatPercent = *order.VatPercent
+		}
+	}
+
+	if govalidator.IsNull(debitNote.DateStr) {
+		errs["date_str"] = "Date is required"
+	} else {
+		const shortForm = "2006-01-02T15:04:05Z07:00"
+		date, err := time.Parse(shortForm, debitNote.DateStr)
+		if err != nil {
+			errs["date_str"] = "Invalid date format"
+		} else {
+			debitNote.Date = &date
+			if order != nil && order.Date != nil && date.Before(*order.Date) {
+				errs["date_str"] = "The debit note can not be dated before the sales invoice " + order.Code
+			}
+		}
+	}
+
+	// KSA-10: the reason a credit or debit note is issued
+	debitNote.Reason = strings.TrimSpace(debitNote.Reason)
+	if govalidator.IsNull(debitNote.Reason) {
+		errs["reason"] = "Reason is required"
+	}
+
+	if len(debitNote.Products) == 0 {
+		errs["products"] = "At least one charge is required"
+	}
+	for i, product := range debitNote.Products {
+		debitNote.Products[i].Name = strings.TrimSpace(product.Name)
+		if govalidator.IsNull(debitNote.Products[i].Name) {
+			errs[fmt.Sprintf("name_%d", i)] = "Name is required"
+		}
+		if product.Quantity <= 0 {
+			errs[fmt.Sprintf("quantity_%d", i)] = "Quantity should be greater than zero"
+		}
+		if product.UnitPrice <= 0 {
+			errs[fmt.Sprintf("unit_price_%d", i)] = "Unit price should be greater than zero"
+		}
+	}
+
+	if govalidator.IsNull(debitNote.PaymentMethod) {
+		debitNote.PaymentMethod = "customer_account"
+	}
+	if debitNote.PaymentMethod != "customer_account" && debitNote.PaymentMethod != "cash" && !slices.Contains(BANK_PAYMENT_METHODS, debitNote.PaymentMethod) {
+		errs["payment_method"] = "Invalid payment method, allowed: customer_account, cash, " + strings.Join(BANK_PAYMENT_METHODS, ", ")
+	}
+
+	debitNote.FindNetTotal()
+
+	if len(errs) == 0 {
+		if message := ValidateAccountingLock(r, debitNote.StoreID, DebitNoteReferenceModel, debitNote.ID, debitNote.Code, debitNote.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+// MakeRedisCode gives the note the next number of the store's debit note series, and the
+// invoice counter value (ICV) it is reported to ZATCA with.
+func (debitNote *DebitNote) MakeRedisCode() error {
+	store, err := FindStoreByID(debitNote.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	redisKey := debitNote.StoreID.Hex() + "_debit_note_counter"
+	location := StoreLocation(store.CountryCode)
+	baseTime := debitNote.CreatedAt.In(location)
+
+	exists, err := db.RedisClient.Exists(redisKey).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		count, err := store.GetCountByCollection("debitnote")
+		if err != nil {
+			return err
+		}
+		startFrom := store.DebitNoteSerialNumber.StartFromCount
+		err = db.RedisClient.Set(redisKey, startFrom+count-1, 0).Err()
+		if err != nil {
+			return err
+		}
+	}
+
+	globalIncr, err := db.RedisClient.Incr(redisKey).Result()
+	if err != nil {
+		return err
+	}
+
+	serialNumber := globalIncr
+	if strings.Contains(store.DebitNoteSerialNumber.Prefix, "DATE") {
+		monthlyRedisKey := debitNote.StoreID.Hex() + "_debit_note_counter_" + baseTime.Format("200601")
+		monthlyExists, err := db.RedisClient.Exists(monthlyRedisKey).Result()
+		if err != nil {
+			return err
+		}
+		if monthlyExists == 0 {
+			fromDate := time.Date(baseTime.Year(), baseTime.Month(), 1, 0, 0, 0, 0, location)
+			toDate := fromDate.AddDate(0, 1, 0).Add(-time.Nanosecond)
+			monthlyCount, err := store.GetCountByCollectionInRange(fromDate, toDate, "debitnote")
+			if err != nil {
+				return err
+			}
+			err = db.RedisClient.Set(monthlyRedisKey, store.DebitNoteSerialNumber.StartFromCount+monthlyCount-1, 0).Err()
+			if err != nil {
+				return err
+			}
+		}
+
+		monthlyIncr, err := db.RedisClient.Incr(monthlyRedisKey).Result()
+		if err != nil {
+			return err
+		}
+		if store.Settings.EnableMonthlySerialNumber {
+			serialNumber = monthlyIncr
+		}
+	}
+
+	paddingCount := store.DebitNoteSerialNumber.PaddingCount
+	if store.DebitNoteSerialNumber.Prefix != "" {
+		debitNote.Code = fmt.Sprintf("%s-%0*d", store.DebitNoteSerialNumber.Prefix, paddingCount, serialNumber)
+	} else {
+		debitNote.Code = fmt.Sprintf("%0*d", paddingCount, serialNumber)
+	}
+	debitNote.Code = strings.ReplaceAll(debitNote.Code, "DATE", baseTime.Format("20060102"))
+
+	debitNote.InvoiceCountValue = globalIncr
+	return nil
+}
+
+// UnMakeRedisCode gives back the number taken by MakeRedisCode when the note is not saved.
+func (debitNote *DebitNote) UnMakeRedisCode() error {
+	store, err := FindStoreByID(debitNote.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	redisKey := debitNote.StoreID.Hex() + "_debit_note_counter"
+	if exists, err := db.RedisClient.Exists(redisKey).Result(); err == nil && exists != 0 {
+		if _, err := db.RedisClient.Decr(redisKey).Result(); err != nil {
+			return err
+		}
+	}
+
+	if strings.Contains(store.DebitNoteSerialNumber.Prefix, "DATE") {
+		baseTime := debitNote.CreatedAt.In(StoreLocation(store.CountryCode))
+		monthlyRedisKey := debitNote.StoreID.Hex() + "_debit_note_counter_" + baseTime.Format("200601")
+		if exists, err := db.RedisClient.Exists(monthlyRedisKey).Result(); err == nil && exists != 0 {
+			if _, err := db.RedisClient.Decr(monthlyRedisKey).Result(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (debitNote *DebitNote) UpdateForeignLabelFields() error {
+	store, err := FindStoreByID(debitNote.StoreID, bson.M{"id": 1, "name": 1})
+	if err != nil {
+		return err
+	}
+	debitNote.StoreName = store.Name
+
+	if debitNote.CreatedBy != nil {
+		createdByUser, err := FindUserByID(debitNote.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		debitNote.CreatedByName = createdByUser.Name
+	}
+
+	if debitNote.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(debitNote.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		debitNote.UpdatedByName = updatedByUser.Name
+	}
+
+	return nil
+}
+
+func (debitNote *DebitNote) Insert() error {
+	collection := db.GetDB("store_" + debitNote.StoreID.Hex()).Collection("debitnote")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	debitNote.ID = primitive.NewObjectID()
+
+	err := debitNote.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, &debitNote)
+	return err
+}
+
+func (debitNote *DebitNote) Update() error {
+	collection := db.GetDB("store_" + debitNote.StoreID.Hex()).Collection("debitnote")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := debitNote.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": debitNote.ID},
+		bson.M{"$set": debitNote},
+		updateOptions,
+	)
+	return err
+}
+
+// DeleteDebitNote soft deletes a note that is not reported to ZATCA and removes its ledger.
+func (debitNote *DebitNote) DeleteDebitNote(tokenClaims TokenClaims) (err error) {
+	if debitNote.Zatca.ReportingPassed {
+		return errors.New("a debit note reported to ZATCA can not be deleted, issue a credit note instead")
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := debitNote.UndoAccounting(); err != nil {
+		return err
+	}
+
+	debitNote.Deleted = true
+	debitNote.DeletedBy = &userID
+	now := time.Now()
+	debitNote.DeletedAt = &now
+
+	return debitNote.Update()
+}
+
+func (store *Store) FindDebitNoteByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (debitNote *DebitNote, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("debitnote")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{
+			"_id":      ID,
+			"store_id": store.ID,
+		}, findOneOptions).
+		Decode(&debitNote)
+	if err != nil {
+		return nil, err
+	}
+
+	return debitNote, err
+}
+
+func (store *Store) SearchDebitNote(w http.ResponseWriter, r *http.Request) (debitNotes []DebitNote, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+
+	ParseDeletedFilter(r, &criterias)
+
+	ParseTextSearch(r, &criterias, "search[code]", "code")
+
+	ParseTextSearch(r, &criterias, "search[order_code]", "order_code")
+
+	ParseTextSearch(r, &criterias, "search[customer_name]", "customer_name")
+
+	if err = ParseObjectIDFilter(r, &criterias, "search[order_id]", "order_id"); err != nil {
+		return debitNotes, criterias, err
+	}
+
+	if err = ParseObjectIDListFilter(r, &criterias, "search[customer_id]", "customer_id"); err != nil {
+		return debitNotes, criterias, err
+	}
+
+	keys, ok := r.URL.Query()["search[zatca.reporting_passed]"]
+	if ok && len(keys[0]) >= 1 {
+		if keys[0] == "1" {
+			criterias.SearchBy["zatca.reporting_passed"] = true
+		} else {
+			criterias.SearchBy["zatca.reporting_passed"] = bson.M{"$ne": true}
+		}
+	}
+
+	timeZoneOffset := CountryTimezoneOffset(store.CountryCode)
+	if err = ParseDateRangeFilter(r, &criterias, "search[from_date]", "search[to_date]", "date", timeZoneOffset); err != nil {
+		return debitNotes, criterias, err
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("debitnote")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return debitNotes, criterias, errors.New("Error fetching debit notes:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return debitNotes, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		debitNote := DebitNote{}
+		err = cur.Decode(&debitNote)
+		if err != nil {
+			return debitNotes, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		debitNotes = append(debitNotes, debitNote)
+	}
+
+	return debitNotes, criterias, nil
+}
+
+// ──────────────────────────────────────────────────────────
+// Accounting
+// ──────────────────────────────────────────────────────────
+
+// debitedAccount is the account the charge is owed or paid into: the customer's account, or
+// cash or bank when it was paid on the spot.
+func (debitNote *DebitNote) debitedAccount(store *Store) (*Account, error) {
+	switch {
+	case debitNote.PaymentMethod == "cash":
+		return store.CreateAccountIfNotExists(debitNote.StoreID, nil, nil, "Cash", nil, nil)
+	case slices.Contains(BANK_PAYMENT_METHODS, debitNote.PaymentMethod):
+		return store.CreateAccountIfNotExists(debitNote.StoreID, nil, nil, "Bank", nil, nil)
+	}
+
+	customerName := "Customer Accounts - Unknown"
+	var referenceID *primitive.ObjectID
+	customerVATNo := ""
+	customerPhone := ""
+	if debitNote.CustomerID != nil && !debitNote.CustomerID.IsZero() {
+		customer, err := store.FindCustomerByID(debitNote.CustomerID, bson.M{})
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+		if customer != nil {
+			customerName = customer.Name
+			referenceID = &customer.ID
+			customerVATNo = customer.VATNo
+			customerPhone = customer.Phone
+		}
+	}
+
+	referenceModel := "customer"
+	return store.CreateAccountIfNotExists(
+		debitNote.StoreID,
+		referenceID,
+		&referenceModel,
+		customerName,
+		&customerPhone,
+		&customerVATNo,
+	)
+}
+
+// DoAccounting books the charge as a sale: DR the customer, cash or bank / CR Sales, with the VAT
+// included as it is for the invoices.
+func (debitNote *DebitNote) DoAccounting() error {
+	store, err := FindStoreByID(debitNote.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	debitedAccount, err := debitNote.debitedAccount(store)
+	if err != nil {
+		return errors.New("error finding the account to debit: " + err.Error())
+	}
+	salesAccount, err := store.CreateAccountIfNotExists(debitNote.StoreID, nil, nil, "Sales", nil, nil)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	groupID := primitive.NewObjectID()
+	ledger := &Ledger{
+		StoreID:        debitNote.StoreID,
+		ReferenceID:    debitNote.ID,
+		ReferenceModel: DebitNoteReferenceModel,
+		ReferenceCode:  debitNote.Code,
+		Journals: []Journal{
+			{
+				Date:          debitNote.Date,
+				AccountID:     debitedAccount.ID,
+				AccountNumber: debitedAccount.Number,
+				AccountName:   debitedAccount.Name,
+				DebitOrCredit: "debit",
+				Debit:         debitNote.NetTotal,
+				GroupID:       groupID,
+				CreatedAt:     &now,
+				UpdatedAt:     &now,
+			},
+			{
+				Date:          debitNote.Date,
+				AccountID:     salesAccount.ID,
+				AccountNumber: salesAccount.Number,
+				AccountName:   salesAccount.Name,
+				DebitOrCredit: "credit",
+				Credit:        debitNote.NetTotal,
+				GroupID:       groupID,
+				CreatedAt:     &now,
+				UpdatedAt:     &now,
+			},
+		},
+		CreatedAt: &now,
+		UpdatedAt: &now,
+	}
+
+	err = ledger.Insert()
+	if err != nil {
+		return errors.New("error creating ledger: " + err.Error())
+	}
+
+	_, err = ledger.CreatePostings()
+	if err != nil {
+		return errors.New("error creating postings: " + err.Error())
+	}
+
+	return nil
+}
+
+func (debitNote *DebitNote) UndoAccounting() error {
+	store, err := FindStoreByID(debitNote.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	ledger, err := store.FindLedgerByReferenceID(debitNote.ID, *debitNote.StoreID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return errors.New("Error finding ledger by reference id: " + err.Error())
+	}
+
+	ledgerAccounts, err := ledger.GetRelatedAccounts()
+	if err != nil {
+		return err
+	}
+
+	err = store.RemoveLedgerByReferenceID(debitNote.ID)
+	if err != nil {
+		return err
+	}
+
+	err = store.RemovePostingsByReferenceID(debitNote.ID)
+	if err != nil {
+		return err
+	}
+
+	return SetAccountBalances(ledgerAccounts)
+}
+
+func (debitNote *DebitNote) SetPostBalances() error {
+	store, err := FindStoreByID(debitNote.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	ledger, err := store.FindLedgerByReferenceID(debitNote.ID, *debitNote.StoreID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return errors.New("Error finding ledger by reference id: " + err.Error())
+	}
+
+	return ledger.SetPostBalancesByLedger(debitNote.Date)
+}
+
+// SetCustomerCreditBalance refreshes the balance of the customer the charge is billed to.
+func (debitNote *DebitNote) SetCustomerCreditBalance() {
+	if debitNote.CustomerID == nil || debitNote.CustomerID.IsZero() {
+		return
+	}
+	store, err := FindStoreByID(debitNote.StoreID, bson.M{})
+	if err != nil {
+		return
+	}
+	customer, _ := store.FindCustomerByID(debitNote.CustomerID, bson.M{})
+	if customer != nil {
+		customer.SetCreditBalance()
+	}
+}
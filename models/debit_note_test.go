@@ -0,0 +1,148 @@
+package models
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// b2bCustomer is a customer with all ZATCA asks of a buyer on a standard invoice.
+func b2bCustomer() *Customer {
+	return &Customer{
+		Name:  "Acme",
+		VATNo: "310000000000003",
+		NationalAddress: NationalAddress{
+			BuildingNo:   "1234",
+			StreetName:   "King Fahd Road",
+			DistrictName: "Olaya",
+			CityName:     "Riyadh",
+			ZipCode:      "12211",
+		},
+	}
+}
+
+// ── FindNetTotal ─────────────────────────────────────────────────────────────
+
+func TestDebitNoteFindNetTotal_VATOnTheTotal(t *testing.T) {
+	debitNote := DebitNote{
+		VatPercent: 15,
+		Products: []DebitNoteProduct{
+			{Name: "Price difference", Quantity: 3, UnitPrice: 3.33},
+			{Name: "Freight", Quantity: 1, UnitPrice: 0.01},
+		},
+	}
+	debitNote.FindNetTotal()
+
+	if debitNote.Products[0].LineTotal != 9.99 || debitNote.Products[0].VatPrice != 1.5 {
+		t.Errorf("first line = %+v", debitNote.Products[0])
+	}
+	if debitNote.Total != 10 {
+		t.Errorf("Total = %v, want 10", debitNote.Total)
+	}
+	// 15% of the total, not the sum of the rounded line VAT (1.50 + 0.00)
+	if debitNote.VatPrice != 1.5 || debitNote.NetTotal != 11.5 {
+		t.Errorf("VatPrice, NetTotal = %v, %v, want 1.5, 11.5", debitNote.VatPrice, debitNote.NetTotal)
+	}
+}
+
+// ── GetZatcaUnit ─────────────────────────────────────────────────────────────
+
+func TestDebitNoteProductGetZatcaUnit_DefaultsToService(t *testing.T) {
+	if got := (DebitNoteProduct{}).GetZatcaUnit(); got != (OrderProduct{IsService: true}).GetZatcaUnit() {
+		t.Errorf("unit = %q, want the service unit", got)
+	}
+	if got, want := (DebitNoteProduct{Unit: "Kg"}).GetZatcaUnit(), (OrderProduct{Unit: "Kg"}).GetZatcaUnit(); got != want {
+		t.Errorf("unit = %q, want %q", got, want)
+	}
+}
+
+// ── fillInvoice ──────────────────────────────────────────────────────────────
+
+func TestDebitNoteFillInvoice_ReferencesTheInvoice(t *testing.T) {
+	riyadh := StoreLocation("SA")
+	invoiceDate := time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC)
+	noteDate := time.Date(2026, 3, 5, 9, 0, 0, 0, riyadh)
+	order := &Order{
+		Code:              "S-0042",
+		InvoiceCountValue: 42,
+		Date:              &invoiceDate,
+	}
+	order.Zatca.ReportingPassed = true
+	order.Zatca.IsSimplified = true
+
+	debitNote := DebitNote{
+		Code:              "DN-0001",
+		UUID:              "8d487816-70b8-4ade-a618-9d620b73814a",
+		Date:              &noteDate,
+		Reason:            "Price increase agreed after delivery",
+		PaymentMethod:     "customer_account",
+		InvoiceCountValue: 7,
+		PrevHash:          "NWZlY2ViNjZmZmM4NmYzOGQ5NTI3ODZjNmQ2OTZjNzljMmRiYzIzOWRkNGU5MWI0NjcyOWQ3M2EyN2ZiNTdlOQ==",
+		VatPercent:        15,
+		Products:          []DebitNoteProduct{{Name: "Price difference", Quantity: 2, UnitPrice: 50}},
+	}
+	debitNote.FindNetTotal()
+
+	// a B2B customer does not make the note standard when the invoice was simplified
+	customer := b2bCustomer()
+
+	var invoice Invoice
+	if err := debitNote.fillInvoice(&invoice, &Store{ID: primitive.NewObjectID(), Name: "Store", VATNo: "300000000000003"}, order, customer); err != nil {
+		t.Fatal(err)
+	}
+
+	if invoice.InvoiceTypeCode.Value != "383" || invoice.InvoiceTypeCode.Name != "0200000" {
+		t.Errorf("type code = %+v, want simplified 383", invoice.InvoiceTypeCode)
+	}
+	if !debitNote.Zatca.IsSimplified {
+		t.Error("IsSimplified = false, want the type of the invoice")
+	}
+	if invoice.BillingReference == nil {
+		t.Fatal("no billing reference")
+	}
+	// the invoice was issued on March 2nd in Riyadh
+	if got, want := invoice.BillingReference.InvoiceDocumentReference.ID, "Invoice Number: 42; Invoice Issue Date: 2026-03-02"; got != want {
+		t.Errorf("billing reference = %q, want %q", got, want)
+	}
+	if len(invoice.PaymentMeans) != 1 || invoice.PaymentMeans[0].InstructionNote == nil ||
+		invoice.PaymentMeans[0].InstructionNote.Value != debitNote.Reason {
+		t.Errorf("payment means = %+v, want the reason as instruction note", invoice.PaymentMeans)
+	}
+	if invoice.LegalMonetaryTotal.TaxExclusiveAmount.Value != 100 || invoice.LegalMonetaryTotal.PayableAmount.Value != 115 {
+		t.Errorf("monetary total = %+v", invoice.LegalMonetaryTotal)
+	}
+	if len(invoice.InvoiceLines) != 1 || invoice.InvoiceLines[0].LineExtensionAmount.Value != 100 {
+		t.Errorf("lines = %+v", invoice.InvoiceLines)
+	}
+
+	out, err := xml.Marshal(invoice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "<cbc:UUID>7</cbc:UUID>") {
+		t.Errorf("ICV missing from %s", out)
+	}
+}
+
+func TestDebitNoteFillInvoice_StandardWhenInvoiceNotReported(t *testing.T) {
+	now := time.Now()
+	debitNote := DebitNote{
+		Date:       &now,
+		Reason:     "Price increase",
+		VatPercent: 15,
+		Products:   []DebitNoteProduct{{Name: "Price difference", Quantity: 1, UnitPrice: 10}},
+	}
+	debitNote.FindNetTotal()
+
+	var invoice Invoice
+	err := debitNote.fillInvoice(&invoice, &Store{}, &Order{Date: &now}, b2bCustomer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invoice.InvoiceTypeCode.Name != "0100000" || debitNote.Zatca.IsSimplified {
+		t.Errorf("type code = %+v, want standard for a B2B customer", invoice.InvoiceTypeCode)
+	}
+}
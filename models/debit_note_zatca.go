@@ -0,0 +1,515 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DebitNoteTypeCode is the UN/CEFACT 1001 code ZATCA takes debit notes with.
+const DebitNoteTypeCode = "383"
+
+func (debitNote *DebitNote) ValidateZatcaReporting() (errs map[string]string) {
+	errs = make(map[string]string)
+
+	store, err := FindStoreByID(debitNote.StoreID, bson.M{})
+	if err != nil {
+		errs["store_id"] = "invalid store"
+		return errs
+	}
+
+	if govalidator.IsNull(store.VATNo) {
+		errs["store_vat_no"] = "Store VAT No. is required for ZATCA reporting"
+	}
+
+	if debitNote.Zatca.ReportingPassed {
+		errs["already_reported"] = "Already reported to ZATCA"
+	}
+
+	order, err := store.FindOrderByID(debitNote.OrderID, bson.M{"code": 1, "zatca.reporting_passed": 1})
+	if err != nil {
+		errs["order_id"] = "Unable to find the sales invoice: " + err.Error()
+	} else if !order.Zatca.ReportingPassed {
+		errs["order_id"] = "Report the sales invoice " + order.Code + " to ZATCA before its debit note"
+	}
+
+	return errs
+}
+
+func (debitNote *DebitNote) FindLastReportedDebitNote(selectFields map[string]interface{}) (lastReported *DebitNote, err error) {
+	collection := db.GetDB("store_" + debitNote.StoreID.Hex()).Collection("debitnote")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+	findOneOptions.SetSort(map[string]interface{}{"zatca.reporting_passed_at": -1})
+
+	err = collection.FindOne(ctx, bson.M{
+		"zatca.reporting_passed": true,
+		"store_id":               debitNote.StoreID,
+	}, findOneOptions).Decode(&lastReported)
+	if err != nil {
+		return nil, err
+	}
+	return lastReported, nil
+}
+
+func (debitNote *DebitNote) RecordZatcaReportingFailure(errorMessage string) error {
+	now := time.Now()
+	debitNote.Zatca.ReportingPassed = false
+	debitNote.Zatca.ReportingFailedCount++
+	debitNote.Zatca.ReportingErrors = append(debitNote.Zatca.ReportingErrors, errorMessage)
+	debitNote.Zatca.ReportingLastFailedAt = &now
+	queueZatcaReporting(debitNote.StoreID, ZatcaQueueItem{
+		DocumentType:      ZatcaDocumentDebitNote,
+		DocumentID:        debitNote.ID,
+		DocumentCode:      debitNote.Code,
+		InvoiceCountValue: debitNote.InvoiceCountValue,
+		DocumentDate:      debitNote.Date,
+		IsSimplified:      debitNote.Zatca.IsSimplified,
+	}, errorMessage)
+	return nil
+}
+
+func (debitNote *DebitNote) RecordZatcaReportingSuccess(reportingResponse ZatcaReportingResponse) error {
+	now := time.Now()
+	debitNote.Zatca.ReportingPassed = true
+	debitNote.Zatca.ReportedAt = &now
+	debitNote.Zatca.ReportingInvoiceHash = reportingResponse.InvoiceHash
+	debitNote.Hash = reportingResponse.InvoiceHash
+	resolveZatcaQueueItem(debitNote.StoreID, ZatcaDocumentDebitNote, debitNote.ID)
+	return nil
+}
+
+func (debitNote *DebitNote) MakeXMLContent() (string, error) {
+	store, err := FindStoreByID(debitNote.StoreID, bson.M{})
+	if err != nil {
+		return "", err
+	}
+
+	order, err := store.FindOrderByID(debitNote.OrderID, bson.M{})
+	if err != nil {
+		return "", errors.New("error finding sales invoice: " + err.Error())
+	}
+
+	var customer *Customer
+	if debitNote.CustomerID != nil && !debitNote.CustomerID.IsZero() {
+		customer, err = store.FindCustomerByID(debitNote.CustomerID, bson.M{})
+		if err != nil && err != mongo.ErrNoDocuments {
+			return "", errors.New("error finding customer: " + err.Error())
+		}
+	}
+
+	lastReported, err := debitNote.FindLastReportedDebitNote(bson.M{})
+	if err != nil && err != mongo.ErrNoDocuments {
+		return "", errors.New("error finding previous debit note: " + err.Error())
+	}
+	if lastReported != nil && lastReported.Hash != "" {
+		debitNote.PrevHash = lastReported.Hash
+	} else {
+		debitNote.PrevHash, err = GenerateInvoiceHash("0")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	xmlData, err := os.ReadFile("zatca/standard_invoice.xml")
+	if err != nil {
+		return "", err
+	}
+
+	var invoice Invoice
+	if err = xml.Unmarshal(xmlData, &invoice); err != nil {
+		return "", err
+	}
+
+	if err = debitNote.fillInvoice(&invoice, store, order, customer); err != nil {
+		return "", err
+	}
+
+	updatedXML, err := xml.MarshalIndent(invoice, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" + string(updatedXML), nil
+}
+
+// fillInvoice sets the invoice template to the debit note of order. The note is simplified when
+// the invoice was, and refers to it by its counter value and issue date as the credit notes do.
+func (debitNote *DebitNote) fillInvoice(invoice *Invoice, store *Store, order *Order, customer *Customer) error {
+	loc, err := time.LoadLocation("Asia/Riyadh")
+	if err != nil {
+		return err
+	}
+
+	invoice.ProfileID = "reporting:1.0"
+	invoice.Xmlns = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	invoice.Cac = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	invoice.Cbc = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+	invoice.Ext = "urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2"
+
+	invoice.ID = debitNote.Code
+	invoice.UUID = debitNote.UUID
+	invoice.IssueDate = debitNote.Date.In(loc).Format("2006-01-02")
+	invoice.IssueTime = debitNote.Date.In(loc).Format("15:04:05")
+
+	isSimplified := !customer.IsB2B()
+	if order.Zatca.ReportingPassed {
+		isSimplified = order.Zatca.IsSimplified
+	}
+	debitNote.Zatca.IsSimplified = isSimplified
+
+	if isSimplified {
+		invoice.InvoiceTypeCode.Name = "0200000"
+	} else {
+		invoice.InvoiceTypeCode.Name = "0100000"
+	}
+	invoice.InvoiceTypeCode.Value = DebitNoteTypeCode
+	invoice.Note = &Note{
+		LanguageID: "en",
+		Value:      debitNote.Reason,
+	}
+
+	invoice.DocumentCurrencyCode = "SAR"
+	invoice.TaxCurrencyCode = "SAR"
+
+	invoice.BillingReference = &BillingReference{
+		InvoiceDocumentReference: InvoiceDocumentReference{
+			ID: "Invoice Number: " + strconv.FormatInt(order.InvoiceCountValue, 10) + "; Invoice Issue Date: " + order.Date.In(loc).Format("2006-01-02"),
+		},
+	}
+
+	invoice.AdditionalDocumentRefs = []AdditionalDocumentRef{
+		{
+			ID:   "ICV",
+			UUID: strconv.FormatInt(debitNote.InvoiceCountValue, 10),
+		},
+		{
+			ID: "PIH",
+			Attachment: &Attachment{EmbeddedDocumentBinaryObject: BinaryObject{
+				MimeCode: "text/plain",
+				Value:    debitNote.PrevHash,
+			}},
+		},
+	}
+
+	// Supplier party (store)
+	storeStreetName := store.NationalAddress.StreetName
+	if !govalidator.IsNull(strings.TrimSpace(store.NationalAddress.StreetNameArabic)) {
+		storeStreetName = store.NationalAddress.StreetName + " | " + store.NationalAddress.StreetNameArabic
+	}
+	storeDistrictName := store.NationalAddress.DistrictName
+	if !govalidator.IsNull(strings.TrimSpace(store.NationalAddress.DistrictNameArabic)) {
+		storeDistrictName = store.NationalAddress.DistrictName + " | " + store.NationalAddress.DistrictNameArabic
+	}
+	storeCityName := store.NationalAddress.CityName
+	if !govalidator.IsNull(strings.TrimSpace(store.NationalAddress.CityNameArabic)) {
+		storeCityName = store.NationalAddress.CityName + " | " + store.NationalAddress.CityNameArabic
+	}
+	storeName := store.Name
+	if !govalidator.IsNull(strings.TrimSpace(store.NameInArabic)) {
+		storeName = store.Name + " | " + store.NameInArabic
+	}
+	storeCountryCode := store.CountryCode
+	if govalidator.IsNull(storeCountryCode) {
+		storeCountryCode = "SA"
+	}
+
+	invoice.AccountingSupplierParty = AccountingSupplierParty{
+		Party: Party{
+			PartyIdentification: &PartyIdentification{
+				ID: IdentificationID{
+					SchemeID: "CRN",
+					Value:    store.RegistrationNumber,
+				},
+			},
+			PostalAddress: Address{
+				StreetName:      storeStreetName,
+				BuildingNumber:  store.NationalAddress.BuildingNo,
+				CitySubdivision: storeDistrictName,
+				CityName:        storeCityName,
+				PostalZone:      store.NationalAddress.ZipCode,
+				CountryCode:     storeCountryCode,
+			},
+			PartyTaxScheme: PartyTaxScheme{
+				CompanyID: store.VATNo,
+				TaxScheme: TaxScheme{ID: IDField{Value: "VAT"}},
+			},
+			PartyLegalEntity: LegalEntity{RegistrationName: storeName},
+		},
+	}
+
+	// Customer party
+	customerStreetName := ""
+	customerDistrictName := ""
+	customerCityName := ""
+	customerName := debitNote.CustomerName
+	customerCountryCode := "SA"
+	customerNationalAddressBuildingNo := ""
+	customerNationalAddressZipCode := ""
+	customerVATNo := ""
+
+	if customer != nil {
+		customerNationalAddressBuildingNo = customer.NationalAddress.BuildingNo
+		customerNationalAddressZipCode = customer.NationalAddress.ZipCode
+		customerVATNo = customer.VATNo
+		if customer.CountryCode != "" {
+			customerCountryCode = customer.CountryCode
+		}
+		customerStreetName = customer.NationalAddress.StreetName
+		if !govalidator.IsNull(strings.TrimSpace(customer.NationalAddress.StreetNameArabic)) {
+			customerStreetName = customer.NationalAddress.StreetName + " | " + customer.NationalAddress.StreetNameArabic
+		}
+		customerDistrictName = customer.NationalAddress.DistrictName
+		if !govalidator.IsNull(strings.TrimSpace(customer.NationalAddress.DistrictNameArabic)) {
+			customerDistrictName = customer.NationalAddress.DistrictName + " | " + customer.NationalAddress.DistrictNameArabic
+		}
+		customerCityName = customer.NationalAddress.CityName
+		if !govalidator.IsNull(strings.TrimSpace(customer.NationalAddress.CityNameArabic)) {
+			customerCityName = customer.NationalAddress.CityName + " | " + customer.NationalAddress.CityNameArabic
+		}
+		customerName = customer.Name
+		if !govalidator.IsNull(strings.TrimSpace(customer.NameInArabic)) {
+			customerName = customer.Name + " | " + customer.NameInArabic
+		}
+	}
+	if isSimplified && customerName == "" {
+		customerName = "Cash Customer"
+	}
+
+	party := Party{
+		PostalAddress: Address{
+			StreetName:      customerStreetName,
+			BuildingNumber:  customerNationalAddressBuildingNo,
+			CitySubdivision: customerDistrictName,
+			CityName:        customerCityName,
+			PostalZone:      customerNationalAddressZipCode,
+			CountryCode:     customerCountryCode,
+		},
+		PartyTaxScheme: PartyTaxScheme{
+			CompanyID: customerVATNo,
+			TaxScheme: TaxScheme{ID: IDField{Value: "VAT"}},
+		},
+		PartyLegalEntity: LegalEntity{RegistrationName: customerName},
+	}
+	if isSimplified {
+		party.PartyIdentification = &PartyIdentification{
+			ID: IdentificationID{SchemeID: "OTH", Value: "CASH"},
+		}
+	}
+	invoice.AccountingCustomerParty = AccountingCustomerParty{Party: party}
+
+	invoice.Delivery = Delivery{
+		ActualDeliveryDate: debitNote.Date.In(loc).Format("2006-01-02"),
+	}
+
+	// KSA-10: the reason of the debit note goes in the instruction note
+	invoice.PaymentMeans = []PaymentMeans{
+		{
+			PaymentMeansCode: paymentMethodToZatcaCode(debitNote.PaymentMethod),
+			InstructionNote:  &InstructionNote{Value: debitNote.Reason},
+		},
+	}
+
+	invoice.AllowanceCharge = []AllowanceCharge{}
+
+	taxScheme := TaxScheme{
+		ID: IDField{Value: "VAT", SchemeID: "UN/ECE 5153", AgencyID: "6"},
+	}
+
+	invoice.TaxTotals = []TaxTotal{
+		{
+			TaxAmount: TaxAmount{Value: ToFixed2(debitNote.VatPrice, 2), CurrencyID: "SAR"},
+		},
+		{
+			TaxAmount: TaxAmount{Value: ToFixed2(debitNote.VatPrice, 2), CurrencyID: "SAR"},
+			TaxSubtotal: &TaxSubtotal{
+				TaxableAmount: TaxableAmount{Value: ToFixed2(debitNote.Total, 2), CurrencyID: "SAR"},
+				TaxAmount:     TaxAmount{Value: ToFixed2(debitNote.VatPrice, 2), CurrencyID: "SAR"},
+				TaxCategory: TaxCategory{
+					ID:        IDField{Value: "S", SchemeID: "UN/ECE 5305", AgencyID: "6"},
+					Percent:   TaxPercent(ToFixed2(debitNote.VatPercent, 2)),
+					TaxScheme: taxScheme,
+				},
+			},
+		},
+	}
+
+	invoice.LegalMonetaryTotal = LegalMonetaryTotal{
+		LineExtensionAmount:   MonetaryAmount{Value: ToFixed2(debitNote.Total, 2), CurrencyID: "SAR"},
+		TaxExclusiveAmount:    MonetaryAmount{Value: ToFixed2(debitNote.Total, 2), CurrencyID: "SAR"},
+		TaxInclusiveAmount:    MonetaryAmount{Value: ToFixed2(debitNote.NetTotal, 2), CurrencyID: "SAR"},
+		AllowanceTotalAmount:  MonetaryAmount{Value: 0.00, CurrencyID: "SAR"},
+		ChargeTotalAmount:     MonetaryAmount{Value: 0.00, CurrencyID: "SAR"},
+		PrepaidAmount:         MonetaryAmount{Value: 0.00, CurrencyID: "SAR"},
+		PayableRoundingAmount: MonetaryAmount{Value: 0.00, CurrencyID: "SAR"},
+		PayableAmount:         MonetaryAmount{Value: ToFixed2(debitNote.NetTotal, 2), CurrencyID: "SAR"},
+	}
+
+	invoice.InvoiceLines = []InvoiceLine{}
+	for i, product := range debitNote.Products {
+		invoice.InvoiceLines = append(invoice.InvoiceLines, InvoiceLine{
+			ID: strconv.Itoa(i + 1),
+			InvoicedQuantity: InvoicedQuantity{
+				UnitCode: product.GetZatcaUnit(),
+				Value:    ToFixed(product.Quantity, 2),
+			},
+			LineExtensionAmount: LineAmount{Value: product.LineTotal, CurrencyID: "SAR"},
+			TaxTotal: TaxTotal{
+				TaxAmount:      TaxAmount{Value: product.VatPrice, CurrencyID: "SAR"},
+				RoundingAmount: &RoundingAmount{Value: RoundTo2Decimals(product.LineTotal + product.VatPrice), CurrencyID: "SAR"},
+			},
+			Item: Item{
+				Name: product.Name,
+				ClassifiedTaxCategory: ClassifiedTaxCategory{
+					ID:        "S",
+					Percent:   TaxPercent(ToFixed2(debitNote.VatPercent, 2)),
+					TaxScheme: taxScheme,
+				},
+			},
+			Price: Price{
+				PriceAmount:  PriceAmount{Value: RoundTo8Decimals(product.UnitPrice), CurrencyID: "SAR"},
+				BaseQuantity: BaseQuantity{UnitCode: product.GetZatcaUnit(), Value: 1},
+			},
+		})
+	}
+
+	return nil
+}
+
+func (debitNote *DebitNote) ReportToZatca() error {
+	store, err := FindStoreByID(debitNote.StoreID, bson.M{})
+	if err != nil {
+		return errors.New("error finding store: " + err.Error())
+	}
+
+	// the note chains on its own hashes, the invoice it refers to has to be known to ZATCA first
+	order, err := store.FindOrderByID(debitNote.OrderID, bson.M{"code": 1, "zatca.reporting_passed": 1})
+	if err != nil {
+		return errors.New("error finding sales invoice: " + err.Error())
+	}
+	if !order.Zatca.ReportingPassed {
+		errMsg := "error reporting: the sales invoice " + order.Code + " is not reported to ZATCA yet"
+		debitNote.RecordZatcaReportingFailure(errMsg)
+		return errors.New(errMsg)
+	}
+
+	xmlContent, err := debitNote.MakeXMLContent()
+	if err != nil {
+		return errors.New("error making xml: " + err.Error())
+	}
+
+	reportingResponse := store.SubmitToZatca(xmlContent)
+	if reportingResponse.Error != "" || !reportingResponse.ReportingPassed {
+		errMsg := "error reporting: " + reportingResponse.Error
+		debitNote.RecordZatcaReportingFailure(errMsg)
+		return errors.New(errMsg)
+	}
+
+	if err = debitNote.RecordZatcaReportingSuccess(reportingResponse); err != nil {
+		return err
+	}
+	return debitNote.SaveClearedInvoiceData(reportingResponse)
+}
+
+func (debitNote *DebitNote) SaveClearedInvoiceData(reportingResponse ZatcaReportingResponse) error {
+	xmlData, err := base64.StdEncoding.DecodeString(reportingResponse.ClearedInvoice)
+	if err != nil {
+		return err
+	}
+
+	xmlResponseFilePath := ZatcaXMLPath(debitNote.StoreID, ZatcaDocumentDebitNote, debitNote.Code)
+	if err = os.MkdirAll(filepath.Dir(xmlResponseFilePath), 0755); err != nil {
+		return err
+	}
+	if err = os.WriteFile(xmlResponseFilePath, xmlData, 0644); err != nil {
+		return err
+	}
+
+	var invoice InvoiceToRead
+	if err = xml.Unmarshal(xmlData, &invoice); err != nil {
+		return err
+	}
+
+	signature := invoice.UBLExtensions.UBLExtension.ExtensionContent.UBLDocumentSignatures.SignatureInformation.Signature
+	signedProperties := signature.Object.QualifyingProperties.SignedProperties.SignedSignatureProperties
+
+	debitNote.Zatca.SigningCertificateHash = signedProperties.SigningCertificate.Cert.CertDigest.DigestValue
+	debitNote.Zatca.ReportingInvoiceHash = signature.SignedInfo.References[0].DigestValue
+	if debitNote.Zatca.ReportingInvoiceHash != debitNote.Hash {
+		return errors.New("invalid hash")
+	}
+	debitNote.Zatca.XadesSignedPropertiesHash = signature.SignedInfo.References[1].DigestValue
+	debitNote.Zatca.ECDSASignature = signature.SignatureValue
+	debitNote.Zatca.X509DigitalCertificate = signature.KeyInfo.X509Data.X509Certificate
+
+	loc, err := time.LoadLocation("Asia/Riyadh")
+	if err != nil {
+		return err
+	}
+	signingTime, err := time.ParseInLocation("2006-01-02T15:04:05", signedProperties.SigningTime, loc)
+	if err != nil {
+		return err
+	}
+	signingTime = signingTime.UTC()
+	debitNote.Zatca.SigningTime = &signingTime
+
+	debitNote.Zatca.X509DigitalCertificateIssuerName = signedProperties.SigningCertificate.Cert.IssuerSerial.X509IssuerName
+	debitNote.Zatca.X509DigitalCertificateSerialNumber = signedProperties.SigningCertificate.Cert.IssuerSerial.X509SerialNumber
+
+	for _, doc := range invoice.AdditionalDocumentRefs {
+		if doc.ID == "QR" {
+			debitNote.Zatca.QrCode = doc.Attachment.EmbeddedDocumentBinaryObject.Value
+			break
+		}
+	}
+	debitNote.Zatca.IsSimplified = reportingResponse.IsSimplified
+	return nil
+}
+
+// EnsureInvoiceCountValue assigns InvoiceCountValue from Redis if it's 0.
+func (debitNote *DebitNote) EnsureInvoiceCountValue() error {
+	if debitNote.InvoiceCountValue != 0 {
+		return nil
+	}
+	redisKey := debitNote.StoreID.Hex() + "_debit_note_counter"
+	exists, err := db.RedisClient.Exists(redisKey).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		store, err := FindStoreByID(debitNote.StoreID, bson.M{})
+		if err != nil {
+			return err
+		}
+		count, err := store.GetCountByCollection("debitnote")
+		if err != nil {
+			return err
+		}
+		startFrom := store.DebitNoteSerialNumber.StartFromCount
+		if err = db.RedisClient.Set(redisKey, startFrom+count-1, 0).Err(); err != nil {
+			return err
+		}
+	}
+	globalIncr, err := db.RedisClient.Incr(redisKey).Result()
+	if err != nil {
+		return err
+	}
+	debitNote.InvoiceCountValue = globalIncr
+	return nil
+}
@@ -428,6 +428,12 @@ func (divident *Divident) Validate(w http.ResponseWriter, r *http.Request, scena
 		divident.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, divident.StoreID, "divident", divident.ID, divident.Code, divident.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	for k, imageContent := range divident.ImagesContent {
 		splits := strings.Split(imageContent, ",")
 
@@ -0,0 +1,579 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultBaseCurrency     = "SAR"
+	ExchangeGainAccountName = "EXCHANGE GAIN"
+	ExchangeLossAccountName = "EXCHANGE LOSS"
+)
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Currency of the stores by country code, used when a store has not set its base currency.
+var countryCurrencies = map[string]string{
+	"SA": "SAR",
+	"AE": "AED",
+	"KW": "KWD",
+	"BH": "BHD",
+	"OM": "OMR",
+	"QA": "QAR",
+	"EG": "EGP",
+	"IN": "INR",
+	"US": "USD",
+}
+
+// ExchangeRate : how many units of the store's base currency one unit of Currency is worth,
+// effective from Date until the next rate of the same currency.
+type ExchangeRate struct {
+	ID            primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Currency      string              `bson:"currency" json:"currency"`
+	Rate          float64             `bson:"rate" json:"rate"`
+	Date          *time.Time          `bson:"date,omitempty" json:"date,omitempty"`
+	DateStr       string              `json:"date_str,omitempty" bson:"-"`
+	Note          string              `bson:"note,omitempty" json:"note,omitempty"`
+	StoreID       *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName     string              `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted       bool                `bson:"deleted" json:"deleted"`
+	DeletedBy     *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt     *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt     *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt     *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy     *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy     *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName string              `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+}
+
+// FXPayment : a payment of a foreign currency document, used to find the rate of the cash or bank
+// journal it was posted to.
+type FXPayment struct {
+	Date      *time.Time
+	AccountID primitive.ObjectID
+	Amount    float64
+	Rate      float64
+}
+
+// BaseCurrency returns the currency the store keeps its books in.
+func (store *Store) BaseCurrency() string {
+	if !govalidator.IsNull(store.Currency) {
+		return strings.ToUpper(strings.TrimSpace(store.Currency))
+	}
+	if currency, ok := countryCurrencies[strings.ToUpper(store.CountryCode)]; ok {
+		return currency
+	}
+	return DefaultBaseCurrency
+}
+
+// IsForeignCurrency tells whether a document in currency has to be converted before posting.
+func (store *Store) IsForeignCurrency(currency string) bool {
+	return !govalidator.IsNull(currency) && currency != store.BaseCurrency()
+}
+
+// ValidateDocumentCurrency normalises the currency and exchange rate of a document dated date:
+// the base currency always has the rate 1 and a missing rate is taken from the rate table.
+// It returns the field and message of the first problem found, empty when valid.
+func (store *Store) ValidateDocumentCurrency(currency *string, rate *float64, date *time.Time) (field string, message string) {
+	*currency = strings.ToUpper(strings.TrimSpace(*currency))
+	if govalidator.IsNull(*currency) {
+		*currency = store.BaseCurrency()
+	}
+
+	if !currencyCodePattern.MatchString(*currency) {
+		return "currency", "Currency should be a 3 letter ISO code"
+	}
+
+	if !store.IsForeignCurrency(*currency) {
+		*rate = 1
+		return "", ""
+	}
+
+	if *rate < 0 {
+		return "exchange_rate", "Exchange rate should be greater than zero"
+	}
+
+	if *rate == 0 {
+		if date == nil {
+			return "exchange_rate", "Exchange rate is required"
+		}
+		exchangeRate, err := store.FindExchangeRate(*currency, *date)
+		if err != nil {
+			return "exchange_rate", "Unable to find the exchange rate:" + err.Error()
+		} else if exchangeRate == nil {
+			return "exchange_rate", "No exchange rate of " + *currency + " on or before " + date.Format("2006-01-02") + ", add one or enter the rate"
+		}
+		*rate = exchangeRate.Rate
+	}
+
+	return "", ""
+}
+
+// ZatcaCurrency is the only currency of the e-invoices reported to ZATCA.
+const ZatcaCurrency = "SAR"
+
+// ValidateZatcaCurrency returns a message when a sale in currency can't be reported to ZATCA,
+// empty when it can or the store doesn't report.
+func (store *Store) ValidateZatcaCurrency(currency string) string {
+	if store.Zatca.Phase != "2" || !store.Zatca.Connected || currency == ZatcaCurrency {
+		return ""
+	}
+	return "Sales reported to ZATCA must be in " + ZatcaCurrency + ", not " + currency
+}
+
+// ResolvePaymentExchangeRate returns the rate a payment of a document settles at: the entered rate,
+// the document's own rate when paid along with it, else the rate table on the payment date.
+func (store *Store) ResolvePaymentExchangeRate(currency string, documentDate *time.Time, documentRate float64, rate float64, date *time.Time) float64 {
+	if !store.IsForeignCurrency(currency) {
+		return 1
+	}
+	if rate > 0 {
+		return rate
+	}
+	if date != nil && documentDate != nil && !IsDateTimesEqual(date, documentDate) {
+		exchangeRate, err := store.FindExchangeRate(currency, *date)
+		if err == nil && exchangeRate != nil {
+			return exchangeRate.Rate
+		}
+	}
+	return documentRate
+}
+
+// journalExchangeRate returns the rate of a payment posted to the journal's account at its time,
+// amount weighted when several payments share the account and time, else the document rate.
+func journalExchangeRate(journal Journal, documentRate float64, payments []FXPayment) float64 {
+	total, weighted := 0.0, 0.0
+	for _, payment := range payments {
+		if payment.AccountID != journal.AccountID || payment.Date == nil || journal.Date == nil || !IsDateTimesEqual(payment.Date, journal.Date) {
+			continue
+		}
+		rate := payment.Rate
+		if rate <= 0 {
+			rate = documentRate
+		}
+		if RoundFloat(payment.Amount, 2) == RoundFloat(journal.Debit+journal.Credit, 2) {
+			return rate
+		}
+		total += payment.Amount
+		weighted += payment.Amount * rate
+	}
+	if total > 0 {
+		return weighted / total
+	}
+	return documentRate
+}
+
+// ConvertJournalsByExchangeRate converts document currency journals into the base currency. Cash and
+// bank journals of payments use the payment's rate and everything else the document rate. Any
+// difference left in a group is first absorbed by the party account (or a document rate line) so the
+// group still balances, then moved to the exchange gain or loss account in a separate group so the
+// party account keeps its value at the document rate.
+func ConvertJournalsByExchangeRate(
+	journals []Journal,
+	documentRate float64,
+	payments []FXPayment,
+	partyAccountID *primitive.ObjectID,
+	exchangeGainAccount *Account,
+	exchangeLossAccount *Account,
+) []Journal {
+	rates := make([]float64, len(journals))
+	groups := map[primitive.ObjectID][]int{}
+	groupIDs := []primitive.ObjectID{}
+
+	converted := make([]Journal, len(journals))
+	for i, journal := range journals {
+		rates[i] = journalExchangeRate(journal, documentRate, payments)
+		journal.Debit = RoundFloat(journal.Debit*rates[i], 2)
+		journal.Credit = RoundFloat(journal.Credit*rates[i], 2)
+		converted[i] = journal
+
+		if _, ok := groups[journal.GroupID]; !ok {
+			groupIDs = append(groupIDs, journal.GroupID)
+		}
+		groups[journal.GroupID] = append(groups[journal.GroupID], i)
+	}
+
+	now := time.Now()
+	for _, groupID := range groupIDs {
+		indexes := groups[groupID]
+
+		imbalance := 0.0
+		sameRate := true
+		absorber := -1
+		var lastDate *time.Time
+		for _, i := range indexes {
+			imbalance += converted[i].Debit - converted[i].Credit
+			if rates[i] != rates[indexes[0]] {
+				sameRate = false
+			}
+			if partyAccountID != nil && converted[i].AccountID == *partyAccountID && absorber == -1 {
+				absorber = i
+			}
+			if converted[i].Date != nil && (lastDate == nil || converted[i].Date.After(*lastDate)) {
+				lastDate = converted[i].Date
+			}
+		}
+		imbalance = RoundFloat(imbalance, 2)
+		if imbalance == 0 {
+			continue
+		}
+
+		if absorber == -1 {
+			for _, i := range indexes {
+				if rates[i] == documentRate {
+					absorber = i
+					break
+				}
+			}
+		}
+		if absorber == -1 {
+			absorber = indexes[0]
+		}
+
+		if converted[absorber].DebitOrCredit == "debit" {
+			converted[absorber].Debit = RoundFloat(converted[absorber].Debit-imbalance, 2)
+		} else {
+			converted[absorber].Credit = RoundFloat(converted[absorber].Credit+imbalance, 2)
+		}
+
+		// Rounding of a single rate is not an exchange difference
+		if sameRate {
+			continue
+		}
+
+		fxGroupID := primitive.NewObjectID()
+		absorberAccount := converted[absorber]
+		amount := RoundFloat(imbalance, 2)
+		debitAccount, creditAccount := Journal{
+			AccountID:     absorberAccount.AccountID,
+			AccountNumber: absorberAccount.AccountNumber,
+			AccountName:   absorberAccount.AccountName,
+		}, Journal{
+			AccountID:     exchangeGainAccount.ID,
+			AccountNumber: exchangeGainAccount.Number,
+			AccountName:   exchangeGainAccount.Name,
+		}
+		if imbalance < 0 {
+			amount = -amount
+			debitAccount, creditAccount = Journal{
+				AccountID:     exchangeLossAccount.ID,
+				AccountNumber: exchangeLossAccount.Number,
+				AccountName:   exchangeLossAccount.Name,
+			}, debitAccount
+		}
+
+		debitAccount.Date = lastDate
+		debitAccount.DebitOrCredit = "debit"
+		debitAccount.Debit = amount
+		debitAccount.GroupID = fxGroupID
+		debitAccount.CreatedAt = &now
+		debitAccount.UpdatedAt = &now
+
+		creditAccount.Date = lastDate
+		creditAccount.DebitOrCredit = "credit"
+		creditAccount.Credit = amount
+		creditAccount.GroupID = fxGroupID
+		creditAccount.CreatedAt = &now
+		creditAccount.UpdatedAt = &now
+
+		converted = append(converted, debitAccount, creditAccount)
+	}
+
+	return converted
+}
+
+// ConvertJournalsToBaseCurrency converts the journals of a document kept in currency at rate into
+// the store's base currency, booking the realised exchange difference of its payments.
+func (store *Store) ConvertJournalsToBaseCurrency(
+	journals []Journal,
+	currency string,
+	rate float64,
+	payments []FXPayment,
+	partyAccountID *primitive.ObjectID,
+) ([]Journal, error) {
+	if !store.IsForeignCurrency(currency) || rate <= 0 {
+		return journals, nil
+	}
+
+	exchangeGainAccount, err := store.CreateAccountIfNotExists(&store.ID, nil, nil, ExchangeGainAccountName, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	exchangeLossAccount, err := store.CreateAccountIfNotExists(&store.ID, nil, nil, ExchangeLossAccountName, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return ConvertJournalsByExchangeRate(journals, rate, payments, partyAccountID, exchangeGainAccount, exchangeLossAccount), nil
+}
+
+func (exchangeRate *ExchangeRate) Validate(w http.ResponseWriter, r *http.Request, scenario string) (errs map[string]string) {
+	errs = make(map[string]string)
+
+	store, err := FindStoreByID(exchangeRate.StoreID, bson.M{})
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errs["store_id"] = "invalid store id"
+		return errs
+	}
+
+	exchangeRate.Currency = strings.ToUpper(strings.TrimSpace(exchangeRate.Currency))
+	if govalidator.IsNull(exchangeRate.Currency) {
+		errs["currency"] = "Currency is required"
+	} else if !currencyCodePattern.MatchString(exchangeRate.Currency) {
+		errs["currency"] = "Currency should be a 3 letter ISO code"
+	} else if !store.IsForeignCurrency(exchangeRate.Currency) {
+		errs["currency"] = exchangeRate.Currency + " is the base currency of the store"
+	}
+
+	if exchangeRate.Rate <= 0 {
+		errs["rate"] = "Rate should be greater than zero"
+	}
+
+	if govalidator.IsNull(exchangeRate.DateStr) {
+		errs["date_str"] = "Date is required"
+	} else {
+		const shortForm = "2006-01-02T15:04:05Z07:00"
+		date, err := time.Parse(shortForm, exchangeRate.DateStr)
+		if err != nil {
+			errs["date_str"] = "Invalid date format"
+		} else {
+			exchangeRate.Date = &date
+		}
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+func (exchangeRate *ExchangeRate) UpdateForeignLabelFields() error {
+	store, err := FindStoreByID(exchangeRate.StoreID, bson.M{"id": 1, "name": 1})
+	if err != nil {
+		return err
+	}
+	exchangeRate.StoreName = store.Name
+
+	if exchangeRate.CreatedBy != nil {
+		createdByUser, err := FindUserByID(exchangeRate.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		exchangeRate.CreatedByName = createdByUser.Name
+	}
+
+	if exchangeRate.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(exchangeRate.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		exchangeRate.UpdatedByName = updatedByUser.Name
+	}
+
+	return nil
+}
+
+func (exchangeRate *ExchangeRate) Insert() error {
+	collection := db.GetDB("store_" + exchangeRate.StoreID.Hex()).Collection("exchange_rate")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	exchangeRate.ID = primitive.NewObjectID()
+
+	err := exchangeRate.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, &exchangeRate)
+	return err
+}
+
+func (exchangeRate *ExchangeRate) Update() error {
+	collection := db.GetDB("store_" + exchangeRate.StoreID.Hex()).Collection("exchange_rate")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := exchangeRate.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": exchangeRate.ID},
+		bson.M{"$set": exchangeRate},
+		updateOptions,
+	)
+	return err
+}
+
+func (exchangeRate *ExchangeRate) DeleteExchangeRate(tokenClaims TokenClaims) (err error) {
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	exchangeRate.Deleted = true
+	exchangeRate.DeletedBy = &userID
+	now := time.Now()
+	exchangeRate.DeletedAt = &now
+
+	return exchangeRate.Update()
+}
+
+func (store *Store) FindExchangeRateByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (exchangeRate *ExchangeRate, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("exchange_rate")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{
+			"_id":      ID,
+			"store_id": store.ID,
+		}, findOneOptions).
+		Decode(&exchangeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	return exchangeRate, err
+}
+
+// FindExchangeRate returns the latest rate of currency effective on date, nil when there is none.
+func (store *Store) FindExchangeRate(currency string, date time.Time) (*ExchangeRate, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("exchange_rate")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	exchangeRate := ExchangeRate{}
+	err := collection.FindOne(ctx, bson.M{
+		"store_id": store.ID,
+		"currency": strings.ToUpper(currency),
+		"date":     bson.M{"$lte": date},
+		"deleted":  bson.M{"$ne": true},
+	}, options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "created_at", Value: -1}})).Decode(&exchangeRate)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &exchangeRate, nil
+}
+
+// FindLatestExchangeRates returns the current rate of every currency in the store's rate table.
+func (store *Store) FindLatestExchangeRates() ([]ExchangeRate, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("exchange_rate")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cur, err := collection.Find(ctx, bson.M{
+		"store_id": store.ID,
+		"deleted":  bson.M{"$ne": true},
+		"date":     bson.M{"$lte": time.Now()},
+	}, options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	latest := map[string]ExchangeRate{}
+	for cur.Next(ctx) {
+		exchangeRate := ExchangeRate{}
+		if err := cur.Decode(&exchangeRate); err != nil {
+			return nil, err
+		}
+		if _, ok := latest[exchangeRate.Currency]; !ok {
+			latest[exchangeRate.Currency] = exchangeRate
+		}
+	}
+
+	exchangeRates := []ExchangeRate{}
+	for _, exchangeRate := range latest {
+		exchangeRates = append(exchangeRates, exchangeRate)
+	}
+	sort.Slice(exchangeRates, func(i, j int) bool {
+		return exchangeRates[i].Currency < exchangeRates[j].Currency
+	})
+
+	return exchangeRates, nil
+}
+
+func (store *Store) SearchExchangeRate(w http.ResponseWriter, r *http.Request) (exchangeRates []ExchangeRate, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+
+	ParseDeletedFilter(r, &criterias)
+
+	keys, ok := r.URL.Query()["search[currency]"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.SearchBy["currency"] = strings.ToUpper(keys[0])
+	}
+
+	timeZoneOffset := CountryTimezoneOffset(store.CountryCode)
+	if err = ParseDateRangeFilter(r, &criterias, "search[from_date]", "search[to_date]", "date", timeZoneOffset); err != nil {
+		return exchangeRates, criterias, err
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("exchange_rate")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return exchangeRates, criterias, errors.New("Error fetching exchange rates:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return exchangeRates, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		exchangeRate := ExchangeRate{}
+		err = cur.Decode(&exchangeRate)
+		if err != nil {
+			return exchangeRates, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		exchangeRates = append(exchangeRates, exchangeRate)
+	}
+
+	return exchangeRates, criterias, nil
+}
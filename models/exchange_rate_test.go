@@ -0,0 +1,211 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ── BaseCurrency ─────────────────────────────────────────────────────────────
+
+func TestBaseCurrency(t *testing.T) {
+	cases := []struct {
+		store Store
+		want  string
+	}{
+		{Store{CountryCode: "SA"}, "SAR"},
+		{Store{CountryCode: "ae"}, "AED"},
+		{Store{CountryCode: "ZZ"}, DefaultBaseCurrency},
+		{Store{CountryCode: "SA", Currency: " usd "}, "USD"},
+	}
+	for _, c := range cases {
+		if got := c.store.BaseCurrency(); got != c.want {
+			t.Errorf("BaseCurrency(%+v) = %s, want %s", c.store, got, c.want)
+		}
+	}
+
+	store := Store{CountryCode: "SA"}
+	if store.IsForeignCurrency("SAR") || store.IsForeignCurrency("") || !store.IsForeignCurrency("USD") {
+		t.Error("IsForeignCurrency mismatch")
+	}
+}
+
+// ── ValidateZatcaCurrency ────────────────────────────────────────────────────
+
+func TestValidateZatcaCurrency(t *testing.T) {
+	store := Store{CountryCode: "SA"}
+	if message := store.ValidateZatcaCurrency("USD"); message != "" {
+		t.Errorf("not reporting to ZATCA: message = %q, want none", message)
+	}
+
+	store.Zatca.Phase = "2"
+	store.Zatca.Connected = true
+	if message := store.ValidateZatcaCurrency("SAR"); message != "" {
+		t.Errorf("SAR: message = %q, want none", message)
+	}
+	if message := store.ValidateZatcaCurrency("USD"); message == "" {
+		t.Error("a USD sale reported to ZATCA should be rejected")
+	}
+}
+
+// ── ConvertJournalsByExchangeRate ────────────────────────────────────────────
+
+type fxAccounts struct {
+	cash, vendor, purchase, gain, loss Account
+}
+
+func newFXAccounts() fxAccounts {
+	return fxAccounts{
+		cash:     Account{ID: primitive.NewObjectID(), Name: "BANK"},
+		vendor:   Account{ID: primitive.NewObjectID(), Name: "VENDOR"},
+		purchase: Account{ID: primitive.NewObjectID(), Name: "PURCHASE"},
+		gain:     Account{ID: primitive.NewObjectID(), Name: ExchangeGainAccountName},
+		loss:     Account{ID: primitive.NewObjectID(), Name: ExchangeLossAccountName},
+	}
+}
+
+func fxJournal(account Account, side string, amount float64, date time.Time, groupID primitive.ObjectID) Journal {
+	journal := Journal{Date: &date, AccountID: account.ID, AccountName: account.Name, DebitOrCredit: side, GroupID: groupID}
+	if side == "debit" {
+		journal.Debit = amount
+	} else {
+		journal.Credit = amount
+	}
+	return journal
+}
+
+// balanceOf returns debits - credits of an account over the journals.
+func balanceOf(journals []Journal, accountID primitive.ObjectID) float64 {
+	balance := 0.0
+	for _, journal := range journals {
+		if journal.AccountID == accountID {
+			balance += journal.Debit - journal.Credit
+		}
+	}
+	return RoundFloat(balance, 2)
+}
+
+func assertGroupsBalance(t *testing.T, journals []Journal) {
+	t.Helper()
+	totals := map[primitive.ObjectID]float64{}
+	for _, journal := range journals {
+		totals[journal.GroupID] += journal.Debit - journal.Credit
+	}
+	for groupID, total := range totals {
+		if RoundFloat(total, 2) != 0 {
+			t.Errorf("group %s is off by %.2f", groupID.Hex(), total)
+		}
+	}
+}
+
+func TestConvertJournals_PaidLaterAtHigherRateBooksLoss(t *testing.T) {
+	a := newFXAccounts()
+	purchaseDate := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	paymentDate := time.Date(2026, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	invoice, payment := primitive.NewObjectID(), primitive.NewObjectID()
+	journals := []Journal{
+		fxJournal(a.purchase, "debit", 1000, purchaseDate, invoice),
+		fxJournal(a.vendor, "credit", 1000, purchaseDate, invoice),
+		fxJournal(a.vendor, "debit", 1000, paymentDate, payment),
+		fxJournal(a.cash, "credit", 1000, paymentDate, payment),
+	}
+	payments := []FXPayment{{Date: &paymentDate, AccountID: a.cash.ID, Amount: 1000, Rate: 3.76}}
+
+	converted := ConvertJournalsByExchangeRate(journals, 3.75, payments, &a.vendor.ID, &a.gain, &a.loss)
+
+	assertGroupsBalance(t, converted)
+	if got := balanceOf(converted, a.purchase.ID); got != 3750 {
+		t.Errorf("purchase = %.2f, want 3750", got)
+	}
+	if got := balanceOf(converted, a.cash.ID); got != -3760 {
+		t.Errorf("bank = %.2f, want -3760", got)
+	}
+	if got := balanceOf(converted, a.vendor.ID); got != 0 {
+		t.Errorf("vendor = %.2f, want settled", got)
+	}
+	if got := balanceOf(converted, a.loss.ID); got != 10 {
+		t.Errorf("exchange loss = %.2f, want 10", got)
+	}
+	if len(converted) != 6 {
+		t.Errorf("journals = %d, want 6", len(converted))
+	}
+	// the difference is kept in its own one to one group
+	fx := converted[len(converted)-2:]
+	if fx[0].GroupID != fx[1].GroupID || fx[0].GroupID == payment || !fx[0].Date.Equal(paymentDate) {
+		t.Errorf("exchange difference group = %+v", fx)
+	}
+}
+
+func TestConvertJournals_PartPaidOnPurchaseDateBooksGain(t *testing.T) {
+	a := newFXAccounts()
+	date := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	group := primitive.NewObjectID()
+	journals := []Journal{
+		fxJournal(a.purchase, "debit", 100, date, group),
+		fxJournal(a.cash, "credit", 40, date, group),
+		fxJournal(a.vendor, "credit", 60, date, group),
+	}
+	payments := []FXPayment{{Date: &date, AccountID: a.cash.ID, Amount: 40, Rate: 3.70}}
+
+	converted := ConvertJournalsByExchangeRate(journals, 3.75, payments, &a.vendor.ID, &a.gain, &a.loss)
+
+	assertGroupsBalance(t, converted)
+	if got := balanceOf(converted, a.vendor.ID); got != -225 {
+		t.Errorf("vendor = %.2f, want -225", got)
+	}
+	if got := balanceOf(converted, a.cash.ID); got != -148 {
+		t.Errorf("bank = %.2f, want -148", got)
+	}
+	if got := balanceOf(converted, a.gain.ID); got != -2 {
+		t.Errorf("exchange gain = %.2f, want 2 credit", got)
+	}
+}
+
+func TestConvertJournals_SameRateOnlyAbsorbsRounding(t *testing.T) {
+	a := newFXAccounts()
+	date := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	group := primitive.NewObjectID()
+	journals := []Journal{
+		fxJournal(a.purchase, "debit", 1, date, group),
+		fxJournal(a.cash, "credit", 0.5, date, group),
+		fxJournal(a.vendor, "credit", 0.5, date, group),
+	}
+
+	converted := ConvertJournalsByExchangeRate(journals, 0.333, nil, &a.vendor.ID, &a.gain, &a.loss)
+
+	assertGroupsBalance(t, converted)
+	if len(converted) != 3 {
+		t.Errorf("journals = %d, want no exchange difference", len(converted))
+	}
+	if got := balanceOf(converted, a.purchase.ID); got != 0.33 {
+		t.Errorf("purchase = %.2f, want 0.33", got)
+	}
+}
+
+func TestJournalExchangeRate_WeightsPaymentsAtSameTime(t *testing.T) {
+	a := newFXAccounts()
+	date := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	journal := fxJournal(a.cash, "credit", 300, date, primitive.NewObjectID())
+
+	payments := []FXPayment{
+		{Date: &date, AccountID: a.cash.ID, Amount: 100, Rate: 3.70},
+		{Date: &date, AccountID: a.cash.ID, Amount: 200, Rate: 3.85},
+	}
+	if got := RoundFloat(journalExchangeRate(journal, 3.75, payments), 4); got != 3.8 {
+		t.Errorf("rate = %v, want 3.8", got)
+	}
+
+	journal.Credit = 200
+	if got := journalExchangeRate(journal, 3.75, payments); got != 3.85 {
+		t.Errorf("rate = %v, want the matching payment's 3.85", got)
+	}
+
+	journal.AccountID = a.vendor.ID
+	if got := journalExchangeRate(journal, 3.75, payments); got != 3.75 {
+		t.Errorf("rate = %v, want the document rate", got)
+	}
+}
@@ -59,6 +59,8 @@ type Expense struct {
 	VatPrice            float64               `bson:"vat_price" json:"vat_price"`
 	VendorName          string                `json:"vendor_name" bson:"vendor_name"`
 	VendorNameArabic    string                `json:"vendor_name_arabic" bson:"vendor_name_arabic"`
+	CostCenterID        *primitive.ObjectID   `json:"cost_center_id,omitempty" bson:"cost_center_id,omitempty"`
+	CostCenterName      string                `json:"cost_center_name,omitempty" bson:"cost_center_name,omitempty"`
 }
 
 func (expense *Expense) AttributesValueChangeEvent(expenseOld *Expense) error {
@@ -352,6 +354,10 @@ func (store *Store) SearchExpense(w http.ResponseWriter, r *http.Request) (expen
 		return expenses, criterias, err
 	}
 
+	if err = ParseObjectIDListFilter(r, &criterias, "search[cost_center_id]", "cost_center_id"); err != nil {
+		return expenses, criterias, err
+	}
+
 	if err = ParseExactDateFilter(r, &criterias, "search[date_str]", "date", timeZoneOffset); err != nil {
 		return expenses, criterias, err
 	}
@@ -559,6 +565,12 @@ func (expense *Expense) Validate(w http.ResponseWriter, r *http.Request, scenari
 		expense.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, expense.StoreID, "expense", expense.ID, expense.Code, expense.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	if len(expense.CategoryID) == 0 {
 		errs["category_id"] = "Atleast 1 category is required"
 	} else {
@@ -606,6 +618,10 @@ func (expense *Expense) Validate(w http.ResponseWriter, r *http.Request, scenari
 		expense.VendorID = nil
 	}
 
+	if message := store.ValidateCostCenter(&expense.CostCenterID, &expense.CostCenterName); message != "" {
+		errs["cost_center_id"] = message
+	}
+
 	if len(errs) > 0 {
 		w.WriteHeader(http.StatusBadRequest)
 	}
@@ -1502,6 +1518,8 @@ func (expense *Expense) CreateLedger() (ledger *Ledger, err error) {
 		UpdatedAt:     &now,
 	})
 
+	journals = TagJournalsWithCostCenter(journals, expense.CostCenterID, expense.CostCenterName)
+
 	ledger = &Ledger{
 		StoreID:        expense.StoreID,
 		ReferenceID:    expense.ID,
@@ -1643,6 +1661,10 @@ func (store *Store) BuildExpenseCriterias(w http.ResponseWriter, r *http.Request
 		return criterias, err
 	}
 
+	if err = ParseObjectIDListFilter(r, &criterias, "search[cost_center_id]", "cost_center_id"); err != nil {
+		return criterias, err
+	}
+
 	if err = ParseExactDateFilter(r, &criterias, "search[date_str]", "date", timeZoneOffset); err != nil {
 		return criterias, err
 	}
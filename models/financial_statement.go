@@ -0,0 +1,650 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/db"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	FinancialStatementTrialBalance     = "trial_balance"
+	FinancialStatementBalanceSheet     = "balance_sheet"
+	FinancialStatementIncomeStatement  = "income_statement"
+	FinancialStatementCostCenterIncome = "cost_center_income_statement"
+
+	StatementCompareNone           = ""
+	StatementComparePreviousPeriod = "previous_period"
+	StatementComparePreviousYear   = "previous_year"
+
+	StatementMaxComparePeriods = 12
+	statementDateFormat        = "Jan 02 2006"
+)
+
+// Order in which account types appear on the statements
+var statementTypeOrder = []string{"asset", "liability", "capital", "drawing", "revenue", "expense"}
+
+var statementTitles = map[string]string{
+	FinancialStatementTrialBalance:     "Trial Balance",
+	FinancialStatementBalanceSheet:     "Balance Sheet",
+	FinancialStatementIncomeStatement:  "Income Statement",
+	FinancialStatementCostCenterIncome: "Income Statement by Cost Center",
+}
+
+var statementTypeLabels = map[string]string{
+	"asset":     "Assets",
+	"liability": "Liabilities",
+	"capital":   "Capital",
+	"drawing":   "Drawings",
+	"revenue":   "Revenue",
+	"expense":   "Expenses",
+}
+
+// StatementPeriod : one column group of a financial statement, bounds are in UTC and inclusive
+type StatementPeriod struct {
+	Label string     `json:"label"`
+	From  *time.Time `json:"from,omitempty"`
+	To    *time.Time `json:"to,omitempty"`
+}
+
+// StatementLine : one account (or computed row) with a value per period column
+type StatementLine struct {
+	AccountID     *primitive.ObjectID `json:"account_id,omitempty"`
+	AccountNumber string              `json:"account_number,omitempty"`
+	AccountName   string              `json:"account_name"`
+	Values        []float64           `json:"values"`
+}
+
+// StatementSection : the accounts of one Account.Type with their totals
+type StatementSection struct {
+	Type  string          `json:"type"`
+	Label string          `json:"label"`
+	Lines []StatementLine `json:"lines"`
+	Total []float64       `json:"total"`
+}
+
+// FinancialStatement : trial balance, balance sheet or income statement built from postings.
+// Values of every line are laid out period by period, len(Columns) values per period.
+type FinancialStatement struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	StoreID   *primitive.ObjectID `json:"store_id,omitempty"`
+	StoreName string              `json:"store_name"`
+	Periods   []StatementPeriod   `json:"periods"`
+	Columns   []string            `json:"columns"`
+	Sections  []StatementSection  `json:"sections"`
+	Summary   []StatementLine     `json:"summary"`
+	CreatedAt *time.Time          `json:"created_at,omitempty"`
+}
+
+// AccountMovement : debit and credit posted to an account within a date range
+type AccountMovement struct {
+	Debit  float64
+	Credit float64
+}
+
+type AccountMovements map[primitive.ObjectID]AccountMovement
+
+// systemAccountType infers the type of the system accounts that predate the type field being set.
+func systemAccountType(name string) string {
+	switch name {
+	case "CASH", "BANK":
+		return "asset"
+	case "SALES", "NON VAT SALES", "PURCHASE RETURN", "CASH DISCOUNT RECEIVED":
+		return "revenue"
+	case "SALES RETURN", "NON VAT SALES RETURN", "PURCHASE", "CASH DISCOUNT ALLOWED", "COMMISSION ALLOWED", "SALARY EXPENSE", "LOYALTY POINTS EXPENSE":
+		return "expense"
+	case "LOYALTY POINTS LIABILITY":
+		return "liability"
+	}
+	return ""
+}
+
+// contraAssetAccounts are the system asset accounts that carry a credit balance
+// and are deducted from the assets they belong to.
+var contraAssetAccounts = map[string]bool{
+	AccumulatedDepreciationAccountName: true,
+}
+
+// IsContraAsset reports whether the account stays with the assets, as a deduction, when in credit.
+func (account *Account) IsContraAsset() bool {
+	return account.ReferenceModel == nil && contraAssetAccounts[account.Name]
+}
+
+// StatementAccountType classifies an account for the statements by the same rules
+// as Account.CalculateBalance: parties and asset/liability accounts follow their net balance,
+// contra-asset accounts stay assets.
+func StatementAccountType(account *Account, debit, credit float64) string {
+	if account.IsContraAsset() {
+		return "asset"
+	}
+
+	accountType := account.Type
+	isParty := account.ReferenceModel != nil && (*account.ReferenceModel == "customer" || *account.ReferenceModel == "vendor")
+
+	if accountType == "" && account.ReferenceModel == nil {
+		accountType = systemAccountType(account.Name)
+		if account.Name == "OPENING BALANCE EQUITY" {
+			accountType = "capital"
+		}
+	}
+
+	if isParty || accountType == "asset" || accountType == "liability" || accountType == "" {
+		if credit > debit {
+			return "liability" //creditor
+		} else if debit > credit {
+			return "asset" //debtor
+		}
+		if accountType == "" {
+			return "asset"
+		}
+	}
+
+	return accountType
+}
+
+// ParseStatementDate parses a "Jan 02 2006" date param, nil when empty.
+func ParseStatementDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(statementDateFormat, value)
+	if err != nil {
+		return nil, errors.New("invalid date, expected format: " + statementDateFormat)
+	}
+	return &date, nil
+}
+
+// shiftStatementRange moves a local date range back by one period. Ranges made of
+// whole calendar months move by months so that e.g. February is compared with January.
+func shiftStatementRange(from, to time.Time, compare string) (time.Time, time.Time) {
+	if compare == StatementComparePreviousYear {
+		return from.AddDate(-1, 0, 0), to.AddDate(-1, 0, 0)
+	}
+
+	if from.Day() == 1 && to.AddDate(0, 0, 1).Day() == 1 {
+		months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month()) + 1
+		newFrom := from.AddDate(0, -months, 0)
+		return newFrom, newFrom.AddDate(0, months, -1)
+	}
+
+	days := int(to.Sub(from).Hours()/24) + 1
+	return from.AddDate(0, 0, -days), to.AddDate(0, 0, -days)
+}
+
+// MakeStatementPeriods builds the current period followed by up to count comparative periods.
+// from/to are local dates as parsed by ParseStatementDate; from may be nil ("since the beginning").
+// With asOf only the end of each period is kept, from then just drives the comparison shift.
+func MakeStatementPeriods(from, to *time.Time, compare string, count int, timeZoneOffset float64, asOf bool) ([]StatementPeriod, error) {
+	if to == nil {
+		return nil, errors.New("date_to is required")
+	}
+	if from != nil && from.After(*to) {
+		return nil, errors.New("date_from must not be after date_to")
+	}
+
+	switch compare {
+	case StatementCompareNone:
+		count = 0
+	case StatementComparePreviousPeriod, StatementComparePreviousYear:
+		if compare == StatementComparePreviousPeriod && from == nil {
+			return nil, errors.New("date_from is required to compare with the previous period")
+		}
+		if count < 1 {
+			count = 1
+		}
+		if count > StatementMaxComparePeriods {
+			return nil, errors.New("at most " + strconv.Itoa(StatementMaxComparePeriods) + " comparative periods are allowed")
+		}
+	default:
+		return nil, errors.New("invalid compare, allowed: " + StatementComparePreviousPeriod + ", " + StatementComparePreviousYear)
+	}
+
+	periods := []StatementPeriod{}
+	localFrom, localTo := from, *to
+	for i := 0; i <= count; i++ {
+		if i > 0 {
+			if localFrom != nil {
+				newFrom, newTo := shiftStatementRange(*localFrom, localTo, compare)
+				localFrom, localTo = &newFrom, newTo
+			} else {
+				localTo = localTo.AddDate(-1, 0, 0)
+			}
+		}
+
+		period := StatementPeriod{}
+		if localFrom != nil && !asOf {
+			start := ConvertTimeZoneToUTC(timeZoneOffset, *localFrom)
+			period.From = &start
+			period.Label = localFrom.Format(statementDateFormat) + " - " + localTo.Format(statementDateFormat)
+		} else {
+			period.Label = "As of " + localTo.Format(statementDateFormat)
+		}
+		end := ConvertTimeZoneToUTC(timeZoneOffset, localTo).Add(24*time.Hour - time.Second)
+		period.To = &end
+		periods = append(periods, period)
+	}
+
+	return periods, nil
+}
+
+// FindAccountMovements sums the postings of every account between from and to (both inclusive, either may be nil),
+// leaving out the postings of the given reference models.
+func (store *Store) FindAccountMovements(from, to *time.Time, excludeReferenceModels ...string) (AccountMovements, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("posting")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{"store_id": store.ID}
+	dateFilter := bson.M{}
+	if from != nil {
+		dateFilter["$gte"] = from
+	}
+	if to != nil {
+		dateFilter["$lte"] = to
+	}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+	if len(excludeReferenceModels) > 0 {
+		filter["reference_model"] = bson.M{"$nin": excludeReferenceModels}
+	}
+
+	pipeline := []bson.M{
+		bson.M{"$match": filter},
+		bson.M{
+			"$group": bson.M{
+				"_id":          "$account_id",
+				"debit_total":  bson.M{"$sum": "$debit_total"},
+				"credit_total": bson.M{"$sum": "$credit_total"},
+			},
+		},
+	}
+
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, errors.New("error aggregating postings: " + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	movements := AccountMovements{}
+	for cur.Next(ctx) {
+		stats := AccountStats{}
+		if err := cur.Decode(&stats); err != nil {
+			return nil, errors.New("cursor decode error: " + err.Error())
+		}
+		if stats.ID == nil {
+			continue
+		}
+		movements[*stats.ID] = AccountMovement{
+			Debit:  RoundFloat(stats.DebitTotal, 2),
+			Credit: RoundFloat(stats.CreditTotal, 2),
+		}
+	}
+
+	return movements, cur.Err()
+}
+
+// FindStatementAccounts returns every account of the store, deleted ones included
+// because their postings still count.
+func (store *Store) FindStatementAccounts() (map[primitive.ObjectID]*Account, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("account")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cur, err := collection.Find(ctx, bson.M{"store_id": store.ID})
+	if err != nil {
+		return nil, errors.New("error fetching accounts: " + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	accounts := map[primitive.ObjectID]*Account{}
+	for cur.Next(ctx) {
+		account := Account{}
+		if err := cur.Decode(&account); err != nil {
+			return nil, errors.New("cursor decode error: " + err.Error())
+		}
+		accounts[account.ID] = &account
+	}
+
+	return accounts, cur.Err()
+}
+
+// statementBuilder collects lines per section while a statement is being built.
+type statementBuilder struct {
+	statement *FinancialStatement
+	accounts  map[primitive.ObjectID]*Account
+	lines     map[string]map[primitive.ObjectID]*StatementLine
+	width     int
+}
+
+func newStatementBuilder(statementType string, accounts map[primitive.ObjectID]*Account, periods []StatementPeriod, columns []string) *statementBuilder {
+	return &statementBuilder{
+		statement: &FinancialStatement{
+			Type:     statementType,
+			Title:    statementTitles[statementType],
+			Periods:  periods,
+			Columns:  columns,
+			Sections: []StatementSection{},
+			Summary:  []StatementLine{},
+		},
+		accounts: accounts,
+		lines:    map[string]map[primitive.ObjectID]*StatementLine{},
+		width:    len(periods) * len(columns),
+	}
+}
+
+func (builder *statementBuilder) add(sectionType string, accountID primitive.ObjectID, index int, value float64) {
+	if builder.lines[sectionType] == nil {
+		builder.lines[sectionType] = map[primitive.ObjectID]*StatementLine{}
+	}
+	line, ok := builder.lines[sectionType][accountID]
+	if !ok {
+		line = &StatementLine{Values: make([]float64, builder.width)}
+		id := accountID
+		line.AccountID = &id
+		if account, ok := builder.accounts[accountID]; ok {
+			line.AccountNumber = account.Number
+			line.AccountName = account.Name
+		} else {
+			line.AccountName = "UNKNOWN ACCOUNT"
+		}
+		builder.lines[sectionType][accountID] = line
+	}
+	line.Values[index] = RoundTo2Decimals(line.Values[index] + value)
+}
+
+func (builder *statementBuilder) account(accountID primitive.ObjectID) *Account {
+	if account, ok := builder.accounts[accountID]; ok {
+		return account
+	}
+	return &Account{ID: accountID}
+}
+
+// build turns the collected lines into sections ordered by type and account number.
+func (builder *statementBuilder) build(types []string) *FinancialStatement {
+	for _, sectionType := range types {
+		section := StatementSection{
+			Type:  sectionType,
+			Label: statementTypeLabels[sectionType],
+			Lines: []StatementLine{},
+			Total: make([]float64, builder.width),
+		}
+		for _, line := range builder.lines[sectionType] {
+			section.Lines = append(section.Lines, *line)
+			for i, value := range line.Values {
+				section.Total[i] = RoundTo2Decimals(section.Total[i] + value)
+			}
+		}
+		sort.Slice(section.Lines, func(i, j int) bool {
+			if section.Lines[i].AccountNumber != section.Lines[j].AccountNumber {
+				return section.Lines[i].AccountNumber < section.Lines[j].AccountNumber
+			}
+			return section.Lines[i].AccountName < section.Lines[j].AccountName
+		})
+		builder.statement.Sections = append(builder.statement.Sections, section)
+	}
+	return builder.statement
+}
+
+func (builder *statementBuilder) section(sectionType string) *StatementSection {
+	for i := range builder.statement.Sections {
+		if builder.statement.Sections[i].Type == sectionType {
+			return &builder.statement.Sections[i]
+		}
+	}
+	return nil
+}
+
+func (builder *statementBuilder) summary(name string, values []float64) {
+	builder.statement.Summary = append(builder.statement.Summary, StatementLine{AccountName: name, Values: values})
+}
+
+// BuildTrialBalance lists opening balance, period debit/credit and closing balance per account.
+// Balances are signed: debit balances are positive, credit balances negative.
+func BuildTrialBalance(accounts map[primitive.ObjectID]*Account, periods []StatementPeriod, openings, movements []AccountMovements) *FinancialStatement {
+	columns := []string{"Opening", "Debit", "Credit", "Closing"}
+	builder := newStatementBuilder(FinancialStatementTrialBalance, accounts, periods, columns)
+
+	for p := range periods {
+		ids := map[primitive.ObjectID]bool{}
+		for id := range openings[p] {
+			ids[id] = true
+		}
+		for id := range movements[p] {
+			ids[id] = true
+		}
+
+		for id := range ids {
+			opening, movement := openings[p][id], movements[p][id]
+			closingDebit, closingCredit := opening.Debit+movement.Debit, opening.Credit+movement.Credit
+			if opening.Debit == opening.Credit && movement.Debit == 0 && movement.Credit == 0 {
+				continue
+			}
+
+			sectionType := StatementAccountType(builder.account(id), closingDebit, closingCredit)
+			base := p * len(columns)
+			builder.add(sectionType, id, base, opening.Debit-opening.Credit)
+			builder.add(sectionType, id, base+1, movement.Debit)
+			builder.add(sectionType, id, base+2, movement.Credit)
+			builder.add(sectionType, id, base+3, closingDebit-closingCredit)
+		}
+	}
+
+	statement := builder.build(statementTypeOrder)
+	total := make([]float64, builder.width)
+	for _, section := range statement.Sections {
+		for i, value := range section.Total {
+			total[i] = RoundTo2Decimals(total[i] + value)
+		}
+	}
+	builder.summary("TOTAL", total)
+	return statement
+}
+
+// BuildIncomeStatement shows revenue and expenses of each period and the resulting net profit.
+func BuildIncomeStatement(accounts map[primitive.ObjectID]*Account, periods []StatementPeriod, movements []AccountMovements) *FinancialStatement {
+	builder := newStatementBuilder(FinancialStatementIncomeStatement, accounts, periods, []string{"Amount"})
+
+	for p := range periods {
+		for id, movement := range movements[p] {
+			switch sectionType := StatementAccountType(builder.account(id), movement.Debit, movement.Credit); sectionType {
+			case "revenue":
+				builder.add(sectionType, id, p, movement.Credit-movement.Debit)
+			case "expense":
+				builder.add(sectionType, id, p, movement.Debit-movement.Credit)
+			}
+		}
+	}
+
+	builder.build([]string{"revenue", "expense"})
+	netProfit := make([]float64, builder.width)
+	for i := range netProfit {
+		netProfit[i] = RoundTo2Decimals(builder.section("revenue").Total[i] - builder.section("expense").Total[i])
+	}
+	builder.summary("NET PROFIT", netProfit)
+	return builder.statement
+}
+
+// BuildBalanceSheet shows the position at the end of each period. Revenue and expense
+// not yet closed to capital are carried as current earnings within capital.
+func BuildBalanceSheet(accounts map[primitive.ObjectID]*Account, periods []StatementPeriod, closings []AccountMovements) *FinancialStatement {
+	builder := newStatementBuilder(FinancialStatementBalanceSheet, accounts, periods, []string{"Amount"})
+	earnings := make([]float64, builder.width)
+
+	for p := range periods {
+		for id, closing := range closings[p] {
+			if closing.Debit == closing.Credit {
+				continue
+			}
+			switch sectionType := StatementAccountType(builder.account(id), closing.Debit, closing.Credit); sectionType {
+			case "asset", "drawing":
+				builder.add(sectionType, id, p, closing.Debit-closing.Credit)
+			case "liability", "capital":
+				builder.add(sectionType, id, p, closing.Credit-closing.Debit)
+			case "revenue", "expense":
+				earnings[p] = RoundTo2Decimals(earnings[p] + closing.Credit - closing.Debit)
+			}
+		}
+	}
+
+	builder.build([]string{"asset", "liability", "capital", "drawing"})
+	capital := builder.section("capital")
+	capital.Lines = append(capital.Lines, StatementLine{AccountName: "CURRENT EARNINGS", Values: earnings})
+	for i := range capital.Total {
+		capital.Total[i] = RoundTo2Decimals(capital.Total[i] + earnings[i])
+	}
+
+	liabilitiesAndEquity := make([]float64, builder.width)
+	difference := make([]float64, builder.width)
+	for i := range liabilitiesAndEquity {
+		liabilitiesAndEquity[i] = RoundTo2Decimals(builder.section("liability").Total[i] + capital.Total[i] - builder.section("drawing").Total[i])
+		difference[i] = RoundTo2Decimals(builder.section("asset").Total[i] - liabilitiesAndEquity[i])
+	}
+	builder.summary("TOTAL ASSETS", builder.section("asset").Total)
+	builder.summary("TOTAL LIABILITIES AND EQUITY", liabilitiesAndEquity)
+	builder.summary("DIFFERENCE", difference)
+	return builder.statement
+}
+
+// MakeFinancialStatement loads the postings needed for the statement type and builds it.
+func (store *Store) MakeFinancialStatement(statementType string, periods []StatementPeriod) (*FinancialStatement, error) {
+	accounts, err := store.FindStatementAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var statement *FinancialStatement
+	switch statementType {
+	case FinancialStatementTrialBalance:
+		openings, movements := []AccountMovements{}, []AccountMovements{}
+		for _, period := range periods {
+			opening := AccountMovements{}
+			if period.From != nil {
+				before := period.From.Add(-time.Nanosecond)
+				opening, err = store.FindAccountMovements(nil, &before)
+				if err != nil {
+					return nil, err
+				}
+			}
+			movement, err := store.FindAccountMovements(period.From, period.To)
+			if err != nil {
+				return nil, err
+			}
+			openings = append(openings, opening)
+			movements = append(movements, movement)
+		}
+		statement = BuildTrialBalance(accounts, periods, openings, movements)
+	case FinancialStatementIncomeStatement:
+		// Closing entries zero revenue and expenses at year end, they are not part of the result
+		movements := []AccountMovements{}
+		for _, period := range periods {
+			movement, err := store.FindAccountMovements(period.From, period.To, FiscalYearReferenceModel)
+			if err != nil {
+				return nil, err
+			}
+			movements = append(movements, movement)
+		}
+		statement = BuildIncomeStatement(accounts, periods, movements)
+	case FinancialStatementCostCenterIncome:
+		costCenters, err := store.FindCostCenters()
+		if err != nil {
+			return nil, err
+		}
+		movements := []CostCenterMovements{}
+		for _, period := range periods {
+			movement, err := store.FindCostCenterMovements(period.From, period.To, FiscalYearReferenceModel)
+			if err != nil {
+				return nil, err
+			}
+			movements = append(movements, movement)
+		}
+		statement = BuildCostCenterIncomeStatement(accounts, costCenters, periods, movements)
+	case FinancialStatementBalanceSheet:
+		closings := []AccountMovements{}
+		for _, period := range periods {
+			closing, err := store.FindAccountMovements(nil, period.To)
+			if err != nil {
+				return nil, err
+			}
+			closings = append(closings, closing)
+		}
+		statement = BuildBalanceSheet(accounts, periods, closings)
+	default:
+		return nil, errors.New("unknown statement type: " + statementType)
+	}
+
+	now := time.Now()
+	statement.StoreID = &store.ID
+	statement.StoreName = store.Name
+	statement.CreatedAt = &now
+	return statement, nil
+}
+
+// WriteXLSX writes the statement as a single sheet workbook.
+func (statement *FinancialStatement) WriteXLSX(w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	row := 1
+	setRow := func(values ...interface{}) {
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		f.SetSheetRow(sheet, cell, &values)
+		row++
+	}
+
+	setRow(statement.StoreName)
+	setRow(statement.Title)
+	row++
+
+	header := []interface{}{"Account No.", "Account"}
+	subHeader := []interface{}{"", ""}
+	for _, period := range statement.Periods {
+		for i, column := range statement.Columns {
+			if i == 0 {
+				header = append(header, period.Label)
+			} else {
+				header = append(header, "")
+			}
+			subHeader = append(subHeader, column)
+		}
+	}
+	setRow(header...)
+	if len(statement.Columns) > 1 {
+		setRow(subHeader...)
+	}
+
+	lineRow := func(number, name string, values []float64) []interface{} {
+		cells := []interface{}{number, name}
+		for _, value := range values {
+			cells = append(cells, value)
+		}
+		return cells
+	}
+
+	for _, section := range statement.Sections {
+		setRow("", strings.ToUpper(section.Label))
+		for _, line := range section.Lines {
+			setRow(lineRow(line.AccountNumber, line.AccountName, line.Values)...)
+		}
+		setRow(lineRow("", fmt.Sprintf("Total %s", section.Label), section.Total)...)
+		row++
+	}
+	for _, line := range statement.Summary {
+		setRow(lineRow("", line.AccountName, line.Values)...)
+	}
+
+	f.SetColWidth(sheet, "B", "B", 40)
+	return f.Write(w)
+}
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func statementTestAccount(accounts map[primitive.ObjectID]*Account, name, accountType string, referenceModel *string) primitive.ObjectID {
+	account := &Account{ID: primitive.NewObjectID(), Name: name, Type: accountType, ReferenceModel: referenceModel}
+	accounts[account.ID] = account
+	return account.ID
+}
+
+func statementTestDate(t *testing.T, value string) *time.Time {
+	date, err := ParseStatementDate(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return date
+}
+
+func statementSectionTotal(statement *FinancialStatement, sectionType string) []float64 {
+	for _, section := range statement.Sections {
+		if section.Type == sectionType {
+			return section.Total
+		}
+	}
+	return nil
+}
+
+// ── StatementAccountType ─────────────────────────────────────────────────────
+
+func TestStatementAccountType(t *testing.T) {
+	customer := "customer"
+	cases := []struct {
+		account       Account
+		debit, credit float64
+		want          string
+	}{
+		{Account{Name: "SALES"}, 0, 100, "revenue"},
+		{Account{Name: "CASH"}, 0, 50, "liability"},
+		{Account{Name: "OPENING BALANCE EQUITY"}, 0, 100, "capital"},
+		{Account{Name: "ACME", ReferenceModel: &customer, Type: "asset"}, 0, 10, "liability"},
+		{Account{Name: "RENT", Type: "expense"}, 0, 10, "expense"},
+		{Account{Name: "UNKNOWN"}, 0, 0, "asset"},
+		{Account{Name: AccumulatedDepreciationAccountName, Type: "asset"}, 0, 300, "asset"},
+	}
+	for _, c := range cases {
+		if got := StatementAccountType(&c.account, c.debit, c.credit); got != c.want {
+			t.Errorf("%s: type = %s, want %s", c.account.Name, got, c.want)
+		}
+	}
+}
+
+// ── MakeStatementPeriods ─────────────────────────────────────────────────────
+
+func TestMakeStatementPeriods_PreviousPeriodByMonths(t *testing.T) {
+	periods, err := MakeStatementPeriods(statementTestDate(t, "Mar 01 2026"), statementTestDate(t, "Mar 31 2026"), StatementComparePreviousPeriod, 2, -3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 3 {
+		t.Fatalf("periods = %d, want 3", len(periods))
+	}
+	want := []string{"Mar 01 2026 - Mar 31 2026", "Feb 01 2026 - Feb 28 2026", "Jan 01 2026 - Jan 31 2026"}
+	for i, period := range periods {
+		if period.Label != want[i] {
+			t.Errorf("period %d label = %q, want %q", i, period.Label, want[i])
+		}
+	}
+	// Saudi Arabia is UTC+3: Mar 01 00:00 local is Feb 28 21:00 UTC
+	if got := periods[0].From.Format(time.RFC3339); got != "2026-02-28T21:00:00Z" {
+		t.Errorf("from = %s", got)
+	}
+	if got := periods[0].To.Format(time.RFC3339); got != "2026-03-31T20:59:59Z" {
+		t.Errorf("to = %s", got)
+	}
+}
+
+func TestMakeStatementPeriods_DaysYearsAndErrors(t *testing.T) {
+	periods, err := MakeStatementPeriods(statementTestDate(t, "Mar 05 2026"), statementTestDate(t, "Mar 14 2026"), StatementComparePreviousPeriod, 1, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if periods[1].Label != "Feb 23 2026 - Mar 04 2026" {
+		t.Errorf("label = %q", periods[1].Label)
+	}
+
+	periods, err = MakeStatementPeriods(nil, statementTestDate(t, "Dec 31 2026"), StatementComparePreviousYear, 0, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 2 || periods[1].Label != "As of Dec 31 2025" || periods[1].From != nil {
+		t.Errorf("periods = %+v", periods)
+	}
+
+	if _, err := MakeStatementPeriods(nil, statementTestDate(t, "Dec 31 2026"), StatementComparePreviousPeriod, 1, 0, false); err == nil {
+		t.Error("expected an error without date_from")
+	}
+	if _, err := MakeStatementPeriods(statementTestDate(t, "Dec 31 2026"), statementTestDate(t, "Jan 01 2026"), "", 0, 0, false); err == nil {
+		t.Error("expected an error for an inverted range")
+	}
+	if _, err := MakeStatementPeriods(nil, statementTestDate(t, "Dec 31 2026"), "last_week", 0, 0, false); err == nil {
+		t.Error("expected an error for an unknown compare mode")
+	}
+}
+
+// ── Builders ─────────────────────────────────────────────────────────────────
+
+func TestBuildStatements_BalanceAcrossTypes(t *testing.T) {
+	accounts := map[primitive.ObjectID]*Account{}
+	investor := "investor"
+	cash := statementTestAccount(accounts, "CASH", "asset", nil)
+	capital := statementTestAccount(accounts, "OWNER", "capital", &investor)
+	sales := statementTestAccount(accounts, "SALES", "revenue", nil)
+	purchase := statementTestAccount(accounts, "PURCHASE", "expense", nil)
+
+	// Capital 1000 in cash before the period, then sales 500 and purchases 200 within it.
+	opening := AccountMovements{cash: {Debit: 1000}, capital: {Credit: 1000}}
+	movement := AccountMovements{cash: {Debit: 500, Credit: 200}, sales: {Credit: 500}, purchase: {Debit: 200}}
+	closing := AccountMovements{cash: {Debit: 1500, Credit: 200}, capital: {Credit: 1000}, sales: {Credit: 500}, purchase: {Debit: 200}}
+	periods := []StatementPeriod{{Label: "current"}}
+
+	trialBalance := BuildTrialBalance(accounts, periods, []AccountMovements{opening}, []AccountMovements{movement})
+	total := trialBalance.Summary[0].Values
+	if total[0] != 0 || total[1] != 700 || total[2] != 700 || total[3] != 0 {
+		t.Errorf("trial balance totals = %v", total)
+	}
+
+	incomeStatement := BuildIncomeStatement(accounts, periods, []AccountMovements{movement})
+	if got := incomeStatement.Summary[0].Values[0]; got != 300 {
+		t.Errorf("net profit = %v, want 300", got)
+	}
+
+	balanceSheet := BuildBalanceSheet(accounts, periods, []AccountMovements{closing})
+	if got := statementSectionTotal(balanceSheet, "asset")[0]; got != 1300 {
+		t.Errorf("assets = %v, want 1300", got)
+	}
+	if got := statementSectionTotal(balanceSheet, "capital")[0]; got != 1300 {
+		t.Errorf("capital with current earnings = %v, want 1300", got)
+	}
+	if difference := balanceSheet.Summary[2].Values[0]; difference != 0 {
+		t.Errorf("difference = %v, want 0", difference)
+	}
+}
+
+func TestBuildBalanceSheet_AccumulatedDepreciationDeductedFromAssets(t *testing.T) {
+	accounts := map[primitive.ObjectID]*Account{}
+	investor := "investor"
+	fixedAssets := statementTestAccount(accounts, FixedAssetsAccountName, "asset", nil)
+	accumulated := statementTestAccount(accounts, AccumulatedDepreciationAccountName, "asset", nil)
+	depreciation := statementTestAccount(accounts, DepreciationExpenseAccountName, "expense", nil)
+	capital := statementTestAccount(accounts, "OWNER", "capital", &investor)
+
+	// A 1000 asset brought in as capital, depreciated by 300.
+	closing := AccountMovements{
+		fixedAssets:  {Debit: 1000},
+		accumulated:  {Credit: 300},
+		depreciation: {Debit: 300},
+		capital:      {Credit: 1000},
+	}
+	balanceSheet := BuildBalanceSheet(accounts, []StatementPeriod{{Label: "current"}}, []AccountMovements{closing})
+
+	if got := statementSectionTotal(balanceSheet, "asset")[0]; got != 700 {
+		t.Errorf("assets = %v, want 700 net of accumulated depreciation", got)
+	}
+	if got := statementSectionTotal(balanceSheet, "liability")[0]; got != 0 {
+		t.Errorf("liabilities = %v, want 0", got)
+	}
+	for _, section := range balanceSheet.Sections {
+		for _, line := range section.Lines {
+			if line.AccountName == AccumulatedDepreciationAccountName && (section.Type != "asset" || line.Values[0] != -300) {
+				t.Errorf("accumulated depreciation = %v under %s, want -300 under assets", line.Values, section.Type)
+			}
+		}
+	}
+	if difference := balanceSheet.Summary[2].Values[0]; difference != 0 {
+		t.Errorf("difference = %v, want 0", difference)
+	}
+}
+
+func TestFinancialStatement_WriteXLSX(t *testing.T) {
+	accounts := map[primitive.ObjectID]*Account{}
+	sales := statementTestAccount(accounts, "SALES", "revenue", nil)
+	statement := BuildIncomeStatement(accounts, []StatementPeriod{{Label: "current"}, {Label: "previous"}}, []AccountMovements{{sales: {Credit: 10}}, {sales: {Credit: 5}}})
+
+	var buf bytes.Buffer
+	if err := statement.WriteXLSX(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, row := range rows {
+		if len(row) == 4 && row[1] == "SALES" && row[2] == "10" && row[3] == "5" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("sales row not found in %q", rows)
+	}
+}
@@ -0,0 +1,751 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	FiscalStatusOpen   = "open"
+	FiscalStatusClosed = "closed"
+
+	FiscalYearMaxMonths         = 18
+	RetainedEarningsAccountName = "RETAINED EARNINGS"
+	FiscalYearReferenceModel    = "fiscal_year"
+)
+
+// Collections of the documents that post to the ledger, by audit entity name. Used to
+// find the saved date of a document being updated when checking the lock date.
+var lockedDocumentCollections = map[string]string{
+	"order":                   "order",
+	"sales_return":            "salesreturn",
+	"purchase":                "purchase",
+	"purchase_return":         "purchasereturn",
+	"expense":                 "expense",
+	"sales_payment":           "sales_payment",
+	"sales_return_payment":    "sales_return_payment",
+	"purchase_payment":        "purchase_payment",
+	"purchase_return_payment": "purchase_return_payment",
+	"capital":                 "capital",
+	"capital_withdrawal":      "capitalwithdrawal",
+	"divident":                "divident",
+	"customer_deposit":        "customerdeposit",
+	"customer_withdrawal":     "customerwithdrawal",
+	"non_vat_sales":           "non_vat_sales",
+	"non_vat_sales_return":    "non_vat_sales_return",
+	"sales_cash_discount":     "sales_cash_discount",
+	"journal_voucher":         "journal_voucher",
+	"debit_note":              "debitnote",
+}
+
+// FiscalYear : a store's accounting year split into monthly periods. Closing a period locks every
+// document dated on or before its end; closing the year also books the closing entries that move
+// the year's revenue and expenses to retained earnings.
+type FiscalYear struct {
+	ID                          primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name                        string              `bson:"name" json:"name"`
+	StartDate                   *time.Time          `bson:"start_date,omitempty" json:"start_date,omitempty"`
+	StartDateStr                string              `json:"start_date_str,omitempty" bson:"-"`
+	EndDate                     *time.Time          `bson:"end_date,omitempty" json:"end_date,omitempty"`
+	EndDateStr                  string              `json:"end_date_str,omitempty" bson:"-"`
+	Status                      string              `bson:"status" json:"status"`
+	Periods                     []FiscalPeriod      `bson:"periods" json:"periods"`
+	RetainedEarningsAccountID   *primitive.ObjectID `json:"retained_earnings_account_id,omitempty" bson:"retained_earnings_account_id,omitempty"`
+	RetainedEarningsAccountName string              `json:"retained_earnings_account_name,omitempty" bson:"retained_earnings_account_name,omitempty"`
+	NetProfit                   float64             `bson:"net_profit" json:"net_profit"`
+	ClosedBy                    *primitive.ObjectID `json:"closed_by,omitempty" bson:"closed_by,omitempty"`
+	ClosedByName                string              `json:"closed_by_name,omitempty" bson:"closed_by_name,omitempty"`
+	ClosedAt                    *time.Time          `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	StoreID                     *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName                   string              `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted                     bool                `bson:"deleted" json:"deleted"`
+	DeletedBy                   *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt                   *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt                   *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt                   *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy                   *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy                   *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName               string              `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName               string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+}
+
+// FiscalPeriod : one month of a fiscal year, bounds are in UTC and inclusive
+type FiscalPeriod struct {
+	Number    int                 `bson:"number" json:"number"`
+	Name      string              `bson:"name" json:"name"`
+	StartDate *time.Time          `bson:"start_date" json:"start_date"`
+	EndDate   *time.Time          `bson:"end_date" json:"end_date"`
+	Status    string              `bson:"status" json:"status"`
+	ClosedBy  *primitive.ObjectID `json:"closed_by,omitempty" bson:"closed_by,omitempty"`
+	ClosedAt  *time.Time          `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+}
+
+// AccountingLock : the last closed period of a store, documents dated up to its end are locked
+type AccountingLock struct {
+	LockDate       *time.Time `json:"lock_date"`
+	FiscalYearName string     `json:"fiscal_year_name"`
+	PeriodName     string     `json:"period_name"`
+}
+
+// accountingLockOverride is what gets audit-logged when an admin saves a document in a closed period.
+type accountingLockOverride struct {
+	LockDate     *time.Time `json:"lock_date"`
+	PeriodName   string     `json:"period_name"`
+	Date         *time.Time `json:"date"`
+	PreviousDate *time.Time `json:"previous_date,omitempty"`
+}
+
+// localDate returns the calendar date of t in the store's timezone as a UTC midnight.
+func localDate(t time.Time, timeZoneOffset float64) time.Time {
+	local := t.UTC().Add(-time.Duration(timeZoneOffset * float64(time.Hour)))
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// MakeFiscalPeriods splits the local dates start..end into months starting on start's day of the month.
+func MakeFiscalPeriods(start, end time.Time, timeZoneOffset float64) []FiscalPeriod {
+	periods := []FiscalPeriod{}
+	for i := 0; ; i++ {
+		periodStart := start.AddDate(0, i, 0)
+		if periodStart.After(end) {
+			break
+		}
+		periodEnd := start.AddDate(0, i+1, -1)
+		if periodEnd.After(end) {
+			periodEnd = end
+		}
+
+		from := ConvertTimeZoneToUTC(timeZoneOffset, periodStart)
+		to := ConvertTimeZoneToUTC(timeZoneOffset, periodEnd).Add(24*time.Hour - time.Second)
+		periods = append(periods, FiscalPeriod{
+			Number:    i + 1,
+			Name:      periodStart.Format("Jan 2006"),
+			StartDate: &from,
+			EndDate:   &to,
+			Status:    FiscalStatusOpen,
+		})
+	}
+	return periods
+}
+
+func (fiscalYear *FiscalYear) Validate(w http.ResponseWriter, r *http.Request, scenario string) (errs map[string]string) {
+	errs = make(map[string]string)
+
+	store, err := FindStoreByID(fiscalYear.StoreID, bson.M{})
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errs["store_id"] = "invalid store id"
+		return errs
+	}
+
+	fiscalYear.Name = strings.TrimSpace(fiscalYear.Name)
+	if govalidator.IsNull(fiscalYear.Name) {
+		errs["name"] = "Name is required"
+	}
+
+	const shortForm = "2006-01-02T15:04:05Z07:00"
+	var start, end time.Time
+	if govalidator.IsNull(fiscalYear.StartDateStr) {
+		errs["start_date_str"] = "Start date is required"
+	} else if start, err = time.Parse(shortForm, fiscalYear.StartDateStr); err != nil {
+		errs["start_date_str"] = "Invalid date format"
+	}
+	if govalidator.IsNull(fiscalYear.EndDateStr) {
+		errs["end_date_str"] = "End date is required"
+	} else if end, err = time.Parse(shortForm, fiscalYear.EndDateStr); err != nil {
+		errs["end_date_str"] = "Invalid date format"
+	}
+
+	if len(errs) == 0 {
+		timeZoneOffset := CountryTimezoneOffset(store.CountryCode)
+		start, end = localDate(start, timeZoneOffset), localDate(end, timeZoneOffset)
+		if !end.After(start) {
+			errs["end_date_str"] = "End date should be after the start date"
+		} else if end.After(start.AddDate(0, FiscalYearMaxMonths, -1)) {
+			errs["end_date_str"] = "A fiscal year can not be longer than 18 months"
+		} else {
+			fiscalYear.Periods = MakeFiscalPeriods(start, end, timeZoneOffset)
+			fiscalYear.StartDate = fiscalYear.Periods[0].StartDate
+			fiscalYear.EndDate = fiscalYear.Periods[len(fiscalYear.Periods)-1].EndDate
+
+			overlapping, err := store.FindOverlappingFiscalYear(fiscalYear)
+			if err != nil {
+				errs["start_date_str"] = "Unable to check other fiscal years:" + err.Error()
+			} else if overlapping != nil {
+				errs["start_date_str"] = "Overlaps with fiscal year " + overlapping.Name
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+func (fiscalYear *FiscalYear) UpdateForeignLabelFields() error {
+	store, err := FindStoreByID(fiscalYear.StoreID, bson.M{"id": 1, "name": 1})
+	if err != nil {
+		return err
+	}
+	fiscalYear.StoreName = store.Name
+
+	if fiscalYear.CreatedBy != nil {
+		createdByUser, err := FindUserByID(fiscalYear.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		fiscalYear.CreatedByName = createdByUser.Name
+	}
+
+	if fiscalYear.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(fiscalYear.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		fiscalYear.UpdatedByName = updatedByUser.Name
+	}
+
+	if fiscalYear.ClosedBy != nil {
+		closedByUser, err := FindUserByID(fiscalYear.ClosedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		fiscalYear.ClosedByName = closedByUser.Name
+	}
+
+	return nil
+}
+
+func (fiscalYear *FiscalYear) Insert() error {
+	collection := db.GetDB("store_" + fiscalYear.StoreID.Hex()).Collection("fiscal_year")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	fiscalYear.ID = primitive.NewObjectID()
+
+	err := fiscalYear.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, &fiscalYear)
+	return err
+}
+
+func (fiscalYear *FiscalYear) Update() error {
+	collection := db.GetDB("store_" + fiscalYear.StoreID.Hex()).Collection("fiscal_year")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := fiscalYear.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": fiscalYear.ID},
+		bson.M{"$set": fiscalYear},
+		updateOptions,
+	)
+	return err
+}
+
+func (fiscalYear *FiscalYear) DeleteFiscalYear(tokenClaims TokenClaims) (err error) {
+	for _, period := range fiscalYear.Periods {
+		if period.Status == FiscalStatusClosed {
+			return errors.New("a fiscal year with closed periods can not be deleted, reopen them first")
+		}
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	fiscalYear.Deleted = true
+	fiscalYear.DeletedBy = &userID
+	now := time.Now()
+	fiscalYear.DeletedAt = &now
+
+	return fiscalYear.Update()
+}
+
+// ClosePeriod closes the period with the given number and every open period before it.
+func (fiscalYear *FiscalYear) ClosePeriod(number int, userID primitive.ObjectID) error {
+	if number < 1 || number > len(fiscalYear.Periods) {
+		return errors.New("invalid period number")
+	}
+
+	now := time.Now()
+	for i := 0; i < number; i++ {
+		if fiscalYear.Periods[i].Status != FiscalStatusClosed {
+			fiscalYear.Periods[i].Status = FiscalStatusClosed
+			fiscalYear.Periods[i].ClosedBy = &userID
+			fiscalYear.Periods[i].ClosedAt = &now
+		}
+	}
+	fiscalYear.UpdatedBy = &userID
+	fiscalYear.UpdatedAt = &now
+
+	return fiscalYear.Update()
+}
+
+// ReopenPeriod reopens the period with the given number and every period after it,
+// so the closed periods of a year always run from its start.
+func (fiscalYear *FiscalYear) ReopenPeriod(number int, userID primitive.ObjectID) error {
+	if fiscalYear.Status == FiscalStatusClosed {
+		return errors.New("the fiscal year is closed, reopen the year first")
+	}
+	if number < 1 || number > len(fiscalYear.Periods) {
+		return errors.New("invalid period number")
+	}
+
+	for i := number - 1; i < len(fiscalYear.Periods); i++ {
+		fiscalYear.Periods[i].Status = FiscalStatusOpen
+		fiscalYear.Periods[i].ClosedBy = nil
+		fiscalYear.Periods[i].ClosedAt = nil
+	}
+	now := time.Now()
+	fiscalYear.UpdatedBy = &userID
+	fiscalYear.UpdatedAt = &now
+
+	return fiscalYear.Update()
+}
+
+// BuildClosingLines returns the voucher lines that bring every revenue and expense account
+// to zero, with the net profit (or loss) going to the retained earnings account.
+func BuildClosingLines(accounts map[primitive.ObjectID]*Account, movements AccountMovements, retainedEarnings *Account) (lines []JournalVoucherLine, netProfit float64) {
+	lines = []JournalVoucherLine{}
+	for id, movement := range movements {
+		account, ok := accounts[id]
+		if !ok {
+			continue
+		}
+		accountType := StatementAccountType(account, movement.Debit, movement.Credit)
+		if accountType != "revenue" && accountType != "expense" {
+			continue
+		}
+
+		balance := RoundTo2Decimals(movement.Debit - movement.Credit)
+		if balance == 0 {
+			continue
+		}
+		line := JournalVoucherLine{AccountID: account.ID, AccountName: account.Name, AccountNumber: account.Number}
+		if balance > 0 {
+			line.Credit = balance
+		} else {
+			line.Debit = -balance
+		}
+		lines = append(lines, line)
+		netProfit = RoundTo2Decimals(netProfit - balance)
+	}
+
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].AccountNumber < lines[j].AccountNumber
+	})
+
+	if netProfit != 0 {
+		line := JournalVoucherLine{AccountID: retainedEarnings.ID, AccountName: retainedEarnings.Name, AccountNumber: retainedEarnings.Number}
+		if netProfit > 0 {
+			line.Credit = netProfit
+		} else {
+			line.Debit = -netProfit
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, netProfit
+}
+
+// Close books the closing entries on the last day of the year and closes all its periods.
+// Earlier fiscal years have to be closed first.
+func (fiscalYear *FiscalYear) Close(userID primitive.ObjectID) error {
+	if fiscalYear.Status == FiscalStatusClosed {
+		return errors.New("fiscal year is already closed")
+	}
+
+	store, err := FindStoreByID(fiscalYear.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	openYears, err := store.GetTotalCount(bson.M{
+		"store_id": store.ID,
+		"deleted":  bson.M{"$ne": true},
+		"status":   FiscalStatusOpen,
+		"end_date": bson.M{"$lt": fiscalYear.StartDate},
+	}, "fiscal_year")
+	if err != nil {
+		return err
+	}
+	if openYears > 0 {
+		return errors.New("close the earlier fiscal years first")
+	}
+
+	accounts, err := store.FindStatementAccounts()
+	if err != nil {
+		return err
+	}
+
+	movements, err := store.FindAccountMovements(fiscalYear.StartDate, fiscalYear.EndDate, FiscalYearReferenceModel)
+	if err != nil {
+		return err
+	}
+
+	retainedEarnings, err := store.CreateAccountIfNotExists(fiscalYear.StoreID, nil, nil, RetainedEarningsAccountName, nil, nil)
+	if err != nil {
+		return errors.New("error creating retained earnings account: " + err.Error())
+	}
+
+	lines, netProfit := BuildClosingLines(accounts, movements, retainedEarnings)
+	if len(lines) > 0 {
+		now := time.Now()
+		voucher := &JournalVoucher{Date: fiscalYear.EndDate, Lines: lines}
+		ledger := &Ledger{
+			StoreID:        fiscalYear.StoreID,
+			ReferenceID:    fiscalYear.ID,
+			ReferenceModel: FiscalYearReferenceModel,
+			ReferenceCode:  fiscalYear.Name,
+			Journals:       BuildJournalVoucherJournals(voucher, &now),
+			CreatedAt:      &now,
+			UpdatedAt:      &now,
+		}
+
+		err = ledger.Insert()
+		if err != nil {
+			return err
+		}
+
+		_, err = ledger.CreatePostings()
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for i := range fiscalYear.Periods {
+		if fiscalYear.Periods[i].Status != FiscalStatusClosed {
+			fiscalYear.Periods[i].Status = FiscalStatusClosed
+			fiscalYear.Periods[i].ClosedBy = &userID
+			fiscalYear.Periods[i].ClosedAt = &now
+		}
+	}
+	fiscalYear.Status = FiscalStatusClosed
+	fiscalYear.NetProfit = netProfit
+	fiscalYear.RetainedEarningsAccountID = &retainedEarnings.ID
+	fiscalYear.RetainedEarningsAccountName = retainedEarnings.Name
+	fiscalYear.ClosedBy = &userID
+	fiscalYear.ClosedAt = &now
+	fiscalYear.UpdatedBy = &userID
+	fiscalYear.UpdatedAt = &now
+
+	return fiscalYear.Update()
+}
+
+// Reopen removes the closing entries of the year. Its periods stay closed until reopened one by one.
+func (fiscalYear *FiscalYear) Reopen(userID primitive.ObjectID) error {
+	if fiscalYear.Status != FiscalStatusClosed {
+		return errors.New("fiscal year is not closed")
+	}
+
+	store, err := FindStoreByID(fiscalYear.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	closedYears, err := store.GetTotalCount(bson.M{
+		"store_id":   store.ID,
+		"deleted":    bson.M{"$ne": true},
+		"status":     FiscalStatusClosed,
+		"start_date": bson.M{"$gt": fiscalYear.EndDate},
+	}, "fiscal_year")
+	if err != nil {
+		return err
+	}
+	if closedYears > 0 {
+		return errors.New("reopen the later fiscal years first")
+	}
+
+	err = fiscalYear.UndoAccounting()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	fiscalYear.Status = FiscalStatusOpen
+	fiscalYear.NetProfit = 0
+	fiscalYear.ClosedBy = nil
+	fiscalYear.ClosedByName = ""
+	fiscalYear.ClosedAt = nil
+	fiscalYear.UpdatedBy = &userID
+	fiscalYear.UpdatedAt = &now
+
+	return fiscalYear.Update()
+}
+
+func (fiscalYear *FiscalYear) UndoAccounting() error {
+	store, err := FindStoreByID(fiscalYear.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	ledger, err := store.FindLedgerByReferenceID(fiscalYear.ID, *fiscalYear.StoreID, bson.M{})
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	ledgerAccounts := map[string]Account{}
+
+	if ledger != nil {
+		ledgerAccounts, err = ledger.GetRelatedAccounts()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = store.RemoveLedgerByReferenceID(fiscalYear.ID)
+	if err != nil {
+		return err
+	}
+
+	err = store.RemovePostingsByReferenceID(fiscalYear.ID)
+	if err != nil {
+		return err
+	}
+
+	return SetAccountBalances(ledgerAccounts)
+}
+
+func (fiscalYear *FiscalYear) SetPostBalances() error {
+	store, err := FindStoreByID(fiscalYear.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	ledger, err := store.FindLedgerByReferenceID(fiscalYear.ID, *fiscalYear.StoreID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return errors.New("Error finding ledger by reference id: " + err.Error())
+	}
+
+	return ledger.SetPostBalancesByLedger(fiscalYear.EndDate)
+}
+
+func (store *Store) FindFiscalYearByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (fiscalYear *FiscalYear, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("fiscal_year")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{
+			"_id":      ID,
+			"store_id": store.ID,
+		}, findOneOptions).
+		Decode(&fiscalYear)
+	if err != nil {
+		return nil, err
+	}
+
+	return fiscalYear, err
+}
+
+// FindOverlappingFiscalYear returns another fiscal year of the store sharing any date with fiscalYear.
+func (store *Store) FindOverlappingFiscalYear(fiscalYear *FiscalYear) (*FiscalYear, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("fiscal_year")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var overlapping *FiscalYear
+	err := collection.FindOne(ctx, bson.M{
+		"store_id":   store.ID,
+		"_id":        bson.M{"$ne": fiscalYear.ID},
+		"deleted":    bson.M{"$ne": true},
+		"start_date": bson.M{"$lte": fiscalYear.EndDate},
+		"end_date":   bson.M{"$gte": fiscalYear.StartDate},
+	}).Decode(&overlapping)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return overlapping, err
+}
+
+func (store *Store) SearchFiscalYear(w http.ResponseWriter, r *http.Request) (fiscalYears []FiscalYear, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+
+	ParseDeletedFilter(r, &criterias)
+	ParseTextSearch(r, &criterias, "search[name]", "name")
+
+	keys, ok := r.URL.Query()["search[status]"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.SearchBy["status"] = keys[0]
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("fiscal_year")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return fiscalYears, criterias, errors.New("Error fetching fiscal years:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return fiscalYears, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		fiscalYear := FiscalYear{}
+		err = cur.Decode(&fiscalYear)
+		if err != nil {
+			return fiscalYears, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		fiscalYears = append(fiscalYears, fiscalYear)
+	}
+
+	return fiscalYears, criterias, nil
+}
+
+// FindAccountingLock returns the latest closed period of the store, nil when nothing is closed.
+func FindAccountingLock(storeID primitive.ObjectID) (*AccountingLock, error) {
+	collection := db.GetDB("store_" + storeID.Hex()).Collection("fiscal_year")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cur, err := collection.Find(ctx, bson.M{
+		"store_id":       storeID,
+		"deleted":        bson.M{"$ne": true},
+		"periods.status": FiscalStatusClosed,
+	}, options.Find().SetProjection(bson.M{"name": 1, "periods": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var lock *AccountingLock
+	for cur.Next(ctx) {
+		fiscalYear := FiscalYear{}
+		if err := cur.Decode(&fiscalYear); err != nil {
+			return nil, err
+		}
+		for _, period := range fiscalYear.Periods {
+			if period.Status != FiscalStatusClosed || period.EndDate == nil {
+				continue
+			}
+			if lock == nil || period.EndDate.After(*lock.LockDate) {
+				lock = &AccountingLock{LockDate: period.EndDate, FiscalYearName: fiscalYear.Name, PeriodName: period.Name}
+			}
+		}
+	}
+
+	return lock, cur.Err()
+}
+
+// IsAccountingLockOverride tells if the request asks to save into a closed period (override_lock=true)
+// and comes from an admin.
+func IsAccountingLockOverride(r *http.Request) bool {
+	if r == nil || r.URL.Query().Get("override_lock") != "true" {
+		return false
+	}
+
+	tokenClaims, err := AuthenticateByAccessToken(r)
+	if err != nil {
+		return false
+	}
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return false
+	}
+	user, err := FindUserByID(&userID, bson.M{"role": 1})
+	return err == nil && user.Role == "Admin"
+}
+
+// findLockedDocumentDate returns the saved date of an existing document, nil for a new one.
+func findLockedDocumentDate(storeID primitive.ObjectID, entity string, ID primitive.ObjectID) *time.Time {
+	collectionName, ok := lockedDocumentCollections[entity]
+	if !ok || ID.IsZero() {
+		return nil
+	}
+
+	collection := db.GetDB("store_" + storeID.Hex()).Collection(collectionName)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	doc := struct {
+		Date *time.Time `bson:"date"`
+	}{}
+	err := collection.FindOne(ctx, bson.M{"_id": ID}, options.FindOne().SetProjection(bson.M{"date": 1})).Decode(&doc)
+	if err != nil {
+		return nil
+	}
+	return doc.Date
+}
+
+// ValidateAccountingLock returns an error message when date, or the saved date of the document
+// being updated, falls in a closed period. Admins can save anyway with override_lock=true,
+// which is recorded in the audit log.
+func ValidateAccountingLock(r *http.Request, storeID *primitive.ObjectID, entity string, ID primitive.ObjectID, code string, date *time.Time) string {
+	if storeID == nil || date == nil {
+		return ""
+	}
+
+	lock, err := FindAccountingLock(*storeID)
+	if err != nil {
+		return "Unable to check the accounting lock date:" + err.Error()
+	}
+	if lock == nil {
+		return ""
+	}
+
+	previousDate := findLockedDocumentDate(*storeID, entity, ID)
+	if date.After(*lock.LockDate) && (previousDate == nil || previousDate.After(*lock.LockDate)) {
+		return ""
+	}
+
+	if IsAccountingLockOverride(r) {
+		tokenClaims, _ := AuthenticateByAccessToken(r)
+		RecordAudit(r, tokenClaims, storeID, AuditActionLockOverride, entity, ID, code, nil, accountingLockOverride{
+			LockDate:     lock.LockDate,
+			PeriodName:   lock.PeriodName,
+			Date:         date,
+			PreviousDate: previousDate,
+		})
+		return ""
+	}
+
+	return "The books are closed up to " + lock.PeriodName + " (" + lock.FiscalYearName + "), documents in a closed period can not be changed"
+}
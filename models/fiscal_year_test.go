@@ -0,0 +1,109 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ── MakeFiscalPeriods ────────────────────────────────────────────────────────
+
+func TestMakeFiscalPeriods_CalendarYear(t *testing.T) {
+	start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	periods := MakeFiscalPeriods(start, end, -3)
+	if len(periods) != 12 {
+		t.Fatalf("periods = %d, want 12", len(periods))
+	}
+	if periods[1].Name != "Feb 2026" || periods[1].Number != 2 || periods[1].Status != FiscalStatusOpen {
+		t.Errorf("period 2 = %+v", periods[1])
+	}
+	// Saudi Arabia is UTC+3, so local midnight is 21:00 UTC the day before
+	if got := periods[0].StartDate.Format(time.RFC3339); got != "2025-12-31T21:00:00Z" {
+		t.Errorf("start = %s", got)
+	}
+	if got := periods[1].EndDate.Format(time.RFC3339); got != "2026-02-28T20:59:59Z" {
+		t.Errorf("february end = %s", got)
+	}
+	for i := 1; i < len(periods); i++ {
+		if periods[i].StartDate.Sub(*periods[i-1].EndDate) != time.Second {
+			t.Errorf("gap between period %d and %d", i, i+1)
+		}
+	}
+}
+
+func TestMakeFiscalPeriods_ShortYearClipsLastPeriod(t *testing.T) {
+	start := time.Date(2026, time.April, 15, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+	periods := MakeFiscalPeriods(start, end, 0)
+	if len(periods) != 3 {
+		t.Fatalf("periods = %d, want 3", len(periods))
+	}
+	if got := periods[0].EndDate.Format("2006-01-02"); got != "2026-05-14" {
+		t.Errorf("first period end = %s", got)
+	}
+	if got := periods[2].EndDate.Format(time.RFC3339); got != "2026-06-30T23:59:59Z" {
+		t.Errorf("last period end = %s", got)
+	}
+}
+
+func TestLocalDate(t *testing.T) {
+	// 22:30 UTC is already the next day in Riyadh
+	date := localDate(time.Date(2026, time.March, 31, 22, 30, 0, 0, time.UTC), -3)
+	if got := date.Format("2006-01-02"); got != "2026-04-01" {
+		t.Errorf("local date = %s", got)
+	}
+}
+
+// ── BuildClosingLines ────────────────────────────────────────────────────────
+
+func TestBuildClosingLines_ProfitGoesToRetainedEarnings(t *testing.T) {
+	accounts := map[primitive.ObjectID]*Account{}
+	sales := statementTestAccount(accounts, "SALES", "revenue", nil)
+	purchase := statementTestAccount(accounts, "PURCHASE", "expense", nil)
+	cash := statementTestAccount(accounts, "CASH", "asset", nil)
+	retainedEarnings := &Account{ID: primitive.NewObjectID(), Name: RetainedEarningsAccountName}
+
+	movements := AccountMovements{
+		sales:    {Debit: 20, Credit: 520},
+		purchase: {Debit: 200},
+		cash:     {Debit: 500, Credit: 200},
+	}
+
+	lines, netProfit := BuildClosingLines(accounts, movements, retainedEarnings)
+	if netProfit != 300 {
+		t.Errorf("net profit = %v, want 300", netProfit)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("lines = %d, want 3 (balance sheet accounts stay open)", len(lines))
+	}
+
+	voucher := &JournalVoucher{Lines: lines}
+	voucher.FindTotals()
+	if voucher.TotalDebit != voucher.TotalCredit {
+		t.Errorf("closing entry is not balanced: %v / %v", voucher.TotalDebit, voucher.TotalCredit)
+	}
+	last := lines[len(lines)-1]
+	if last.AccountID != retainedEarnings.ID || last.Credit != 300 {
+		t.Errorf("retained earnings line = %+v", last)
+	}
+}
+
+func TestBuildClosingLines_LossAndNothingToClose(t *testing.T) {
+	accounts := map[primitive.ObjectID]*Account{}
+	rent := statementTestAccount(accounts, "RENT", "expense", nil)
+	retainedEarnings := &Account{ID: primitive.NewObjectID(), Name: RetainedEarningsAccountName}
+
+	lines, netProfit := BuildClosingLines(accounts, AccountMovements{rent: {Debit: 75}}, retainedEarnings)
+	if netProfit != -75 || len(lines) != 2 || lines[1].Debit != 75 {
+		t.Errorf("lines = %+v, net profit = %v", lines, netProfit)
+	}
+
+	lines, _ = BuildClosingLines(accounts, AccountMovements{}, retainedEarnings)
+	if len(lines) != 0 {
+		t.Errorf("expected no lines, got %+v", lines)
+	}
+}
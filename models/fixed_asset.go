@@ -0,0 +1,1153 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	FixedAssetMethodStraightLine       = "straight_line"
+	FixedAssetMethodDecliningBalance   = "declining_balance"
+	FixedAssetStatusActive             = "active"
+	FixedAssetStatusFullyDepreciated   = "fully_depreciated"
+	FixedAssetStatusDisposed           = "disposed"
+	FixedAssetMaxUsefulLifeMonths      = 600
+	FixedAssetsAccountName             = "FIXED ASSETS"
+	AccumulatedDepreciationAccountName = "ACCUMULATED DEPRECIATION"
+	DepreciationExpenseAccountName     = "DEPRECIATION EXPENSE"
+	AssetDisposalGainAccountName       = "GAIN ON DISPOSAL OF ASSETS"
+	AssetDisposalLossAccountName       = "LOSS ON DISPOSAL OF ASSETS"
+
+	FixedAssetReferenceModel             = "fixed_asset"
+	FixedAssetDepreciationReferenceModel = "fixed_asset_depreciation"
+	FixedAssetDisposalReferenceModel     = "fixed_asset_disposal"
+)
+
+// FixedAsset : a vehicle, lift, tool... capitalised from the purchase or expense that acquired it
+// and depreciated every month until it is fully depreciated or disposed.
+type FixedAsset struct {
+	ID                      primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Code                    string              `bson:"code,omitempty" json:"code,omitempty"`
+	Name                    string              `bson:"name" json:"name"`
+	Category                string              `bson:"category,omitempty" json:"category,omitempty"`
+	SerialNumber            string              `bson:"serial_number,omitempty" json:"serial_number,omitempty"`
+	Description             string              `bson:"description,omitempty" json:"description,omitempty"`
+	AcquisitionDate         *time.Time          `bson:"acquisition_date,omitempty" json:"acquisition_date,omitempty"`
+	AcquisitionDateStr      string              `json:"acquisition_date_str,omitempty" bson:"-"`
+	AcquiredByModel         string              `bson:"acquired_by_model,omitempty" json:"acquired_by_model,omitempty"` // purchase or expense
+	AcquiredByID            *primitive.ObjectID `json:"acquired_by_id,omitempty" bson:"acquired_by_id,omitempty"`
+	AcquiredByCode          string              `json:"acquired_by_code,omitempty" bson:"acquired_by_code,omitempty"`
+	Cost                    float64             `bson:"cost" json:"cost"`
+	SalvageValue            float64             `bson:"salvage_value" json:"salvage_value"`
+	UsefulLifeMonths        int                 `bson:"useful_life_months" json:"useful_life_months"`
+	DepreciationMethod      string              `bson:"depreciation_method" json:"depreciation_method"`
+	DecliningRatePercent    float64             `bson:"declining_rate_percent,omitempty" json:"declining_rate_percent,omitempty"` // yearly, double declining when not set
+	Schedule                []DepreciationEntry `bson:"schedule" json:"schedule"`
+	AccumulatedDepreciation float64             `bson:"accumulated_depreciation" json:"accumulated_depreciation"`
+	BookValue               float64             `bson:"book_value" json:"book_value"`
+	Status                  string              `bson:"status" json:"status"`
+	DisposalDate            *time.Time          `bson:"disposal_date,omitempty" json:"disposal_date,omitempty"`
+	DisposalProceeds        float64             `bson:"disposal_proceeds,omitempty" json:"disposal_proceeds,omitempty"`
+	DisposalPaymentMethod   string              `bson:"disposal_payment_method,omitempty" json:"disposal_payment_method,omitempty"`
+	DisposalGainLoss        float64             `bson:"disposal_gain_loss,omitempty" json:"disposal_gain_loss,omitempty"`
+	CostCenterID            *primitive.ObjectID `json:"cost_center_id,omitempty" bson:"cost_center_id,omitempty"`
+	CostCenterName          string              `json:"cost_center_name,omitempty" bson:"cost_center_name,omitempty"`
+	LastError               string              `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	StoreID                 *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName               string              `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted                 bool                `bson:"deleted" json:"deleted"`
+	DeletedBy               *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt               *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt               *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt               *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy               *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy               *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName           string              `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName           string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+}
+
+// DepreciationEntry : the depreciation of one month, dated to the last day of the month
+type DepreciationEntry struct {
+	Number                  int        `bson:"number" json:"number"`
+	Date                    *time.Time `bson:"date" json:"date"`
+	Amount                  float64    `bson:"amount" json:"amount"`
+	AccumulatedDepreciation float64    `bson:"accumulated_depreciation" json:"accumulated_depreciation"`
+	BookValue               float64    `bson:"book_value" json:"book_value"`
+	Posted                  bool       `bson:"posted" json:"posted"`
+	PostedAt                *time.Time `bson:"posted_at,omitempty" json:"posted_at,omitempty"`
+}
+
+// FixedAssetDisposal : the sale or write-off of an asset
+type FixedAssetDisposal struct {
+	DateStr       string     `json:"date_str"`
+	Date          *time.Time `json:"-"`
+	Proceeds      float64    `json:"proceeds"`
+	PaymentMethod string     `json:"payment_method"`
+}
+
+// FixedAssetRegister : cost, depreciation and book value of every asset for a period
+type FixedAssetRegister struct {
+	StoreName string                   `json:"store_name"`
+	From      *time.Time               `json:"from,omitempty"`
+	To        *time.Time               `json:"to"`
+	Lines     []FixedAssetRegisterLine `json:"lines"`
+	Total     FixedAssetRegisterLine   `json:"total"`
+	CreatedAt *time.Time               `json:"created_at,omitempty"`
+}
+
+type FixedAssetRegisterLine struct {
+	AssetID                 *primitive.ObjectID `json:"asset_id,omitempty"`
+	Code                    string              `json:"code,omitempty"`
+	Name                    string              `json:"name"`
+	Category                string              `json:"category,omitempty"`
+	AcquisitionDate         *time.Time          `json:"acquisition_date,omitempty"`
+	DepreciationMethod      string              `json:"depreciation_method,omitempty"`
+	Status                  string              `json:"status,omitempty"`
+	Cost                    float64             `json:"cost"`
+	OpeningDepreciation     float64             `json:"opening_depreciation"`
+	PeriodDepreciation      float64             `json:"period_depreciation"`
+	AccumulatedDepreciation float64             `json:"accumulated_depreciation"`
+	BookValue               float64             `json:"book_value"`
+	DisposalDate            *time.Time          `json:"disposal_date,omitempty"`
+	DisposalProceeds        float64             `json:"disposal_proceeds,omitempty"`
+	DisposalGainLoss        float64             `json:"disposal_gain_loss,omitempty"`
+}
+
+// depreciationMonthEnd is the last day of the month, months after date, at local midnight.
+func depreciationMonthEnd(date time.Time, months int, location *time.Location) time.Time {
+	local := date.In(location)
+	return time.Date(local.Year(), local.Month()+time.Month(months)+1, 0, 0, 0, 0, 0, location).UTC()
+}
+
+// BuildDepreciationSchedule lays out the monthly depreciation of an asset from the month it was
+// acquired (a full month is charged for it) until its book value reaches the salvage value.
+// Straight line charges (cost - salvage) / life every month. Declining balance charges the yearly
+// rate / 12 of the book value, twice the straight line rate when the rate is not set, and writes
+// the asset down to the salvage value in its last month.
+func BuildDepreciationSchedule(cost, salvageValue float64, lifeMonths int, method string, decliningRatePercent float64, acquisitionDate time.Time, location *time.Location) []DepreciationEntry {
+	schedule := []DepreciationEntry{}
+	depreciable := RoundTo2Decimals(cost - salvageValue)
+	if lifeMonths <= 0 || depreciable <= 0 {
+		return schedule
+	}
+
+	monthlyRate := 2 / float64(lifeMonths)
+	if decliningRatePercent > 0 {
+		monthlyRate = decliningRatePercent / 100 / 12
+	}
+
+	accumulated := 0.0
+	for month := 0; month < lifeMonths; month++ {
+		bookValue := RoundTo2Decimals(cost - accumulated)
+		remaining := RoundTo2Decimals(bookValue - salvageValue)
+		if remaining <= 0 {
+			break
+		}
+
+		var amount float64
+		if month == lifeMonths-1 {
+			amount = remaining
+		} else if method == FixedAssetMethodDecliningBalance {
+			amount = RoundTo2Decimals(bookValue * monthlyRate)
+		} else {
+			amount = RoundTo2Decimals(depreciable / float64(lifeMonths))
+		}
+		if amount > remaining {
+			amount = remaining
+		}
+		if amount <= 0 {
+			continue
+		}
+
+		accumulated = RoundTo2Decimals(accumulated + amount)
+		date := depreciationMonthEnd(acquisitionDate, month, location)
+		schedule = append(schedule, DepreciationEntry{
+			Number:                  len(schedule) + 1,
+			Date:                    &date,
+			Amount:                  amount,
+			AccumulatedDepreciation: accumulated,
+			BookValue:               RoundTo2Decimals(cost - accumulated),
+		})
+	}
+
+	return schedule
+}
+
+// PostedDepreciation is the depreciation already posted to the ledger.
+func (asset *FixedAsset) PostedDepreciation() float64 {
+	total := 0.0
+	for _, entry := range asset.Schedule {
+		if entry.Posted {
+			total += entry.Amount
+		}
+	}
+	return RoundTo2Decimals(total)
+}
+
+func (asset *FixedAsset) HasPostedDepreciation() bool {
+	for _, entry := range asset.Schedule {
+		if entry.Posted {
+			return true
+		}
+	}
+	return false
+}
+
+// DisposalGainLoss is what the proceeds bring over the book value, a loss when negative.
+func DisposalGainLoss(cost, accumulatedDepreciation, proceeds float64) float64 {
+	return RoundTo2Decimals(proceeds - (cost - accumulatedDepreciation))
+}
+
+// BuildFixedAssetRegisterLine reads the depreciation of the asset before, within and up to the end
+// of from..to from its schedule (from may be nil for everything up to to).
+func BuildFixedAssetRegisterLine(asset *FixedAsset, from *time.Time, to time.Time) FixedAssetRegisterLine {
+	line := FixedAssetRegisterLine{
+		AssetID:            &asset.ID,
+		Code:               asset.Code,
+		Name:               asset.Name,
+		Category:           asset.Category,
+		AcquisitionDate:    asset.AcquisitionDate,
+		DepreciationMethod: asset.DepreciationMethod,
+		Status:             asset.Status,
+		Cost:               asset.Cost,
+	}
+
+	for _, entry := range asset.Schedule {
+		if entry.Date == nil || entry.Date.After(to) {
+			continue
+		}
+		if from != nil && entry.Date.Before(*from) {
+			line.OpeningDepreciation += entry.Amount
+		} else {
+			line.PeriodDepreciation += entry.Amount
+		}
+	}
+	line.OpeningDepreciation = RoundTo2Decimals(line.OpeningDepreciation)
+	line.PeriodDepreciation = RoundTo2Decimals(line.PeriodDepreciation)
+	line.AccumulatedDepreciation = RoundTo2Decimals(line.OpeningDepreciation + line.PeriodDepreciation)
+	line.BookValue = RoundTo2Decimals(line.Cost - line.AccumulatedDepreciation)
+
+	if asset.Status == FixedAssetStatusDisposed && asset.DisposalDate != nil && !asset.DisposalDate.After(to) {
+		line.DisposalDate = asset.DisposalDate
+		line.DisposalProceeds = asset.DisposalProceeds
+		line.DisposalGainLoss = asset.DisposalGainLoss
+		line.BookValue = 0
+	} else if asset.Status == FixedAssetStatusDisposed {
+		line.Status = FixedAssetStatusActive
+	}
+
+	return line
+}
+
+// BuildFixedAssetRegister adds up the register lines of the assets acquired up to to.
+func BuildFixedAssetRegister(assets []FixedAsset, from *time.Time, to time.Time) *FixedAssetRegister {
+	register := &FixedAssetRegister{From: from, To: &to, Lines: []FixedAssetRegisterLine{}}
+	register.Total.Name = "TOTAL"
+
+	for i := range assets {
+		asset := &assets[i]
+		if asset.AcquisitionDate == nil || asset.AcquisitionDate.After(to) {
+			continue
+		}
+		if asset.Status == FixedAssetStatusDisposed && asset.DisposalDate != nil && from != nil && asset.DisposalDate.Before(*from) {
+			continue
+		}
+
+		line := BuildFixedAssetRegisterLine(asset, from, to)
+		register.Lines = append(register.Lines, line)
+		register.Total.Cost += line.Cost
+		register.Total.OpeningDepreciation += line.OpeningDepreciation
+		register.Total.PeriodDepreciation += line.PeriodDepreciation
+		register.Total.AccumulatedDepreciation += line.AccumulatedDepreciation
+		register.Total.BookValue += line.BookValue
+		register.Total.DisposalProceeds += line.DisposalProceeds
+		register.Total.DisposalGainLoss += line.DisposalGainLoss
+	}
+
+	register.Total.Cost = RoundTo2Decimals(register.Total.Cost)
+	register.Total.OpeningDepreciation = RoundTo2Decimals(register.Total.OpeningDepreciation)
+	register.Total.PeriodDepreciation = RoundTo2Decimals(register.Total.PeriodDepreciation)
+	register.Total.AccumulatedDepreciation = RoundTo2Decimals(register.Total.AccumulatedDepreciation)
+	register.Total.BookValue = RoundTo2Decimals(register.Total.BookValue)
+	register.Total.DisposalProceeds = RoundTo2Decimals(register.Total.DisposalProceeds)
+	register.Total.DisposalGainLoss = RoundTo2Decimals(register.Total.DisposalGainLoss)
+
+	return register
+}
+
+// MakeFixedAssetRegister builds the register of every asset of the store for from..to.
+func (store *Store) MakeFixedAssetRegister(from *time.Time, to time.Time) (*FixedAssetRegister, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("fixed_asset")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cur, err := collection.Find(ctx, bson.M{
+		"store_id":         store.ID,
+		"deleted":          bson.M{"$ne": true},
+		"acquisition_date": bson.M{"$lte": to},
+	}, options.Find().SetSort(bson.D{{Key: "acquisition_date", Value: 1}, {Key: "code", Value: 1}}))
+	if err != nil {
+		return nil, errors.New("error fetching fixed assets: " + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	assets := []FixedAsset{}
+	if err := cur.All(ctx, &assets); err != nil {
+		return nil, errors.New("cursor decode error: " + err.Error())
+	}
+
+	register := BuildFixedAssetRegister(assets, from, to)
+	now := time.Now()
+	register.StoreName = store.Name
+	register.CreatedAt = &now
+	return register, nil
+}
+
+// findAcquiringDocumentAmount returns the code and amount of the purchase or expense that
+// acquired the asset.
+func (store *Store) findAcquiringDocumentAmount(model string, ID *primitive.ObjectID) (code string, amount float64, err error) {
+	switch model {
+	case "expense":
+		expense, err := store.FindExpenseByID(ID, bson.M{"code": 1, "amount": 1, "deleted": 1})
+		if err != nil || expense.Deleted {
+			return "", 0, errors.New("invalid expense")
+		}
+		return expense.Code, expense.Amount, nil
+	case "purchase":
+		purchase, err := store.FindPurchaseByID(ID, bson.M{"code": 1, "net_total": 1})
+		if err != nil {
+			return "", 0, errors.New("invalid purchase")
+		}
+		return purchase.Code, purchase.NetTotal, nil
+	}
+	return "", 0, errors.New("invalid acquired by model")
+}
+
+// FindCapitalisedAmount sums the cost of the other assets acquired by the same document.
+func (store *Store) FindCapitalisedAmount(model string, ID *primitive.ObjectID, excludeAssetID primitive.ObjectID) (float64, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("fixed_asset")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cur, err := collection.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{
+			"store_id":          store.ID,
+			"deleted":           bson.M{"$ne": true},
+			"acquired_by_model": model,
+			"acquired_by_id":    ID,
+			"_id":               bson.M{"$ne": excludeAssetID},
+		}},
+		{"$group": bson.M{"_id": nil, "total": bson.M{"$sum": "$cost"}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	result := struct {
+		Total float64 `bson:"total"`
+	}{}
+	if cur.Next(ctx) {
+		if err := cur.Decode(&result); err != nil {
+			return 0, err
+		}
+	}
+	return RoundTo2Decimals(result.Total), cur.Err()
+}
+
+func (asset *FixedAsset) Validate(w http.ResponseWriter, r *http.Request, scenario string, assetOld *FixedAsset) (errs map[string]string) {
+	errs = make(map[string]string)
+
+	store, err := FindStoreByID(asset.StoreID, bson.M{})
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errs["store_id"] = "invalid store id"
+		return errs
+	}
+
+	if scenario == "update" {
+		if asset.ID.IsZero() {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = "ID is required"
+			return errs
+		}
+		if assetOld.Status == FixedAssetStatusDisposed {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["status"] = "A disposed asset can not be changed"
+			return errs
+		}
+	}
+
+	asset.Name = strings.TrimSpace(asset.Name)
+	asset.Category = strings.TrimSpace(asset.Category)
+	if govalidator.IsNull(asset.Name) {
+		errs["name"] = "Name is required"
+	}
+
+	if govalidator.IsNull(asset.AcquisitionDateStr) {
+		errs["acquisition_date_str"] = "Acquisition date is required"
+	} else {
+		const shortForm = "2006-01-02T15:04:05Z07:00"
+		date, err := time.Parse(shortForm, asset.AcquisitionDateStr)
+		if err != nil {
+			errs["acquisition_date_str"] = "Invalid date format"
+		} else {
+			asset.AcquisitionDate = &date
+		}
+	}
+
+	if asset.Cost <= 0 {
+		errs["cost"] = "Cost should be greater than zero"
+	}
+	if asset.SalvageValue < 0 {
+		errs["salvage_value"] = "Salvage value should not be negative"
+	} else if asset.Cost > 0 && asset.SalvageValue >= asset.Cost {
+		errs["salvage_value"] = "Salvage value should be less than the cost"
+	}
+	if asset.UsefulLifeMonths <= 0 || asset.UsefulLifeMonths > FixedAssetMaxUsefulLifeMonths {
+		errs["useful_life_months"] = fmt.Sprintf("Useful life should be between 1 and %d months", FixedAssetMaxUsefulLifeMonths)
+	}
+
+	switch asset.DepreciationMethod {
+	case FixedAssetMethodStraightLine:
+		asset.DecliningRatePercent = 0
+	case FixedAssetMethodDecliningBalance:
+		if asset.DecliningRatePercent < 0 || asset.DecliningRatePercent > 100 {
+			errs["declining_rate_percent"] = "Yearly rate should be between 0 and 100"
+		}
+	default:
+		errs["depreciation_method"] = "Invalid depreciation method, allowed: " + FixedAssetMethodStraightLine + ", " + FixedAssetMethodDecliningBalance
+	}
+
+	if govalidator.IsNull(asset.AcquiredByModel) {
+		asset.AcquiredByID = nil
+		asset.AcquiredByCode = ""
+	} else if asset.AcquiredByModel != "purchase" && asset.AcquiredByModel != "expense" {
+		errs["acquired_by_model"] = "Invalid acquired by, allowed: purchase, expense"
+	} else if asset.AcquiredByID == nil || asset.AcquiredByID.IsZero() {
+		errs["acquired_by_id"] = "The " + asset.AcquiredByModel + " that acquired the asset is required"
+	} else {
+		code, amount, err := store.findAcquiringDocumentAmount(asset.AcquiredByModel, asset.AcquiredByID)
+		if err != nil {
+			errs["acquired_by_id"] = err.Error()
+		} else {
+			asset.AcquiredByCode = code
+			capitalised, err := store.FindCapitalisedAmount(asset.AcquiredByModel, asset.AcquiredByID, asset.ID)
+			if err != nil {
+				errs["acquired_by_id"] = "Unable to check other assets of the " + asset.AcquiredByModel + ":" + err.Error()
+			} else if RoundTo2Decimals(capitalised+asset.Cost) > RoundTo2Decimals(amount) {
+				errs["cost"] = fmt.Sprintf("Cost is more than what is left to capitalise from %s (%.2f)", code, RoundTo2Decimals(amount-capitalised))
+			}
+		}
+	}
+
+	if message := store.ValidateCostCenter(&asset.CostCenterID, &asset.CostCenterName); message != "" {
+		errs["cost_center_id"] = message
+	}
+
+	if len(errs) == 0 && scenario == "update" && assetOld.HasPostedDepreciation() && asset.FinancialsChanged(assetOld) {
+		errs["cost"] = "Depreciation has been posted for this asset, its cost, cost center and depreciation details can not be changed"
+	}
+
+	if len(errs) == 0 {
+		// moving an asset out of a closed period changes that period as well
+		date := asset.AcquisitionDate
+		if assetOld != nil && assetOld.AcquisitionDate.Before(*date) {
+			date = assetOld.AcquisitionDate
+		}
+		if message := ValidateAccountingLock(r, asset.StoreID, FixedAssetReferenceModel, asset.ID, asset.Code, date); message != "" {
+			errs["acquisition_date_str"] = message
+		}
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+// FinancialsChanged tells if the capitalisation and schedule of the asset have to be made again.
+func (asset *FixedAsset) FinancialsChanged(assetOld *FixedAsset) bool {
+	return !asset.AcquisitionDate.Equal(*assetOld.AcquisitionDate) ||
+		asset.Cost != assetOld.Cost ||
+		asset.SalvageValue != assetOld.SalvageValue ||
+		asset.UsefulLifeMonths != assetOld.UsefulLifeMonths ||
+		asset.DepreciationMethod != assetOld.DepreciationMethod ||
+		asset.DecliningRatePercent != assetOld.DecliningRatePercent ||
+		asset.AcquiredByModel != assetOld.AcquiredByModel ||
+		asset.AcquiredByCode != assetOld.AcquiredByCode ||
+		asset.CostCenterName != assetOld.CostCenterName
+}
+
+// MakeSchedule lays out the depreciation of the asset again, nothing must be posted yet.
+func (asset *FixedAsset) MakeSchedule(store *Store) {
+	asset.Schedule = BuildDepreciationSchedule(asset.Cost, asset.SalvageValue, asset.UsefulLifeMonths, asset.DepreciationMethod, asset.DecliningRatePercent, *asset.AcquisitionDate, StoreLocation(store.CountryCode))
+	asset.AccumulatedDepreciation = 0
+	asset.BookValue = asset.Cost
+	asset.Status = FixedAssetStatusActive
+}
+
+func (asset *FixedAsset) MakeCode() error {
+	store, err := FindStoreByID(asset.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	count, err := store.GetCountByCollectionByDeletedIncluded("fixed_asset")
+	if err != nil {
+		return err
+	}
+
+	for {
+		count++
+		asset.Code = fmt.Sprintf("FA-%06d", count)
+		exists, err := asset.IsCodeExists()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return nil
+		}
+	}
+}
+
+func (asset *FixedAsset) IsCodeExists() (exists bool, err error) {
+	collection := db.GetDB("store_" + asset.StoreID.Hex()).Collection("fixed_asset")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := collection.CountDocuments(ctx, bson.M{
+		"code": asset.Code,
+		"_id":  bson.M{"$ne": asset.ID},
+	})
+	return count > 0, err
+}
+
+func (asset *FixedAsset) UpdateForeignLabelFields() error {
+	store, err := FindStoreByID(asset.StoreID, bson.M{"id": 1, "name": 1})
+	if err != nil {
+		return err
+	}
+	asset.StoreName = store.Name
+
+	if asset.CreatedBy != nil {
+		createdByUser, err := FindUserByID(asset.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		asset.CreatedByName = createdByUser.Name
+	}
+
+	if asset.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(asset.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		asset.UpdatedByName = updatedByUser.Name
+	}
+
+	return nil
+}
+
+func (asset *FixedAsset) Insert() error {
+	collection := db.GetDB("store_" + asset.StoreID.Hex()).Collection("fixed_asset")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	asset.ID = primitive.NewObjectID()
+
+	err := asset.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, &asset)
+	return err
+}
+
+func (asset *FixedAsset) Update() error {
+	collection := db.GetDB("store_" + asset.StoreID.Hex()).Collection("fixed_asset")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := asset.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": asset.ID},
+		bson.M{"$set": asset},
+		updateOptions,
+	)
+	return err
+}
+
+// DeleteFixedAsset soft deletes the asset and removes its capitalisation, depreciation and disposal entries.
+func (asset *FixedAsset) DeleteFixedAsset(tokenClaims TokenClaims) (err error) {
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := asset.UndoAccounting(); err != nil {
+		return err
+	}
+
+	asset.Deleted = true
+	asset.DeletedBy = &userID
+	now := time.Now()
+	asset.DeletedAt = &now
+
+	return asset.Update()
+}
+
+func (store *Store) FindFixedAssetByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (asset *FixedAsset, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("fixed_asset")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{
+			"_id":      ID,
+			"store_id": store.ID,
+		}, findOneOptions).
+		Decode(&asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return asset, err
+}
+
+func (store *Store) SearchFixedAsset(w http.ResponseWriter, r *http.Request) (assets []FixedAsset, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+
+	ParseDeletedFilter(r, &criterias)
+
+	ParseTextSearch(r, &criterias, "search[name]", "name")
+
+	ParseTextSearch(r, &criterias, "search[code]", "code")
+
+	ParseTextSearch(r, &criterias, "search[category]", "category")
+
+	keys, ok := r.URL.Query()["search[status]"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.SearchBy["status"] = bson.M{"$in": strings.Split(keys[0], ",")}
+	}
+
+	keys, ok = r.URL.Query()["search[acquired_by_id]"]
+	if ok && len(keys[0]) >= 1 {
+		acquiredByID, err := primitive.ObjectIDFromHex(keys[0])
+		if err != nil {
+			return assets, criterias, err
+		}
+		criterias.SearchBy["acquired_by_id"] = acquiredByID
+	}
+
+	if err = ParseObjectIDListFilter(r, &criterias, "search[cost_center_id]", "cost_center_id"); err != nil {
+		return assets, criterias, err
+	}
+
+	timeZoneOffset := CountryTimezoneOffset(store.CountryCode)
+	if err = ParseDateRangeFilter(r, &criterias, "search[acquisition_date_from]", "search[acquisition_date_to]", "acquisition_date", timeZoneOffset); err != nil {
+		return assets, criterias, err
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("fixed_asset")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return assets, criterias, errors.New("Error fetching fixed assets:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return assets, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		asset := FixedAsset{}
+		err = cur.Decode(&asset)
+		if err != nil {
+			return assets, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		assets = append(assets, asset)
+	}
+
+	return assets, criterias, nil
+}
+
+// ──────────────────────────────────────────────────────────
+// Accounting
+// ──────────────────────────────────────────────────────────
+
+// capitalisedFromAccount is the account the cost is moved out of: the expense account of the
+// expense or the purchase account that booked it, or opening balance equity for an asset owned
+// before the books were kept here.
+func (asset *FixedAsset) capitalisedFromAccount(store *Store) (*Account, error) {
+	switch asset.AcquiredByModel {
+	case "expense":
+		expense, err := store.FindExpenseByID(asset.AcquiredByID, bson.M{"category_id": 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(expense.CategoryID) == 0 {
+			return nil, errors.New("expense has no category")
+		}
+		expenseCategory, err := store.FindExpenseCategoryByID(expense.CategoryID[0], bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		referenceModel := "expense_category"
+		return store.CreateAccountIfNotExists(asset.StoreID, &expenseCategory.ID, &referenceModel, expenseCategory.Name+" Expense", nil, nil)
+	case "purchase":
+		return store.CreateAccountIfNotExists(asset.StoreID, nil, nil, "Purchase", nil, nil)
+	}
+	return store.CreateAccountIfNotExists(asset.StoreID, nil, nil, "OPENING BALANCE EQUITY", nil, nil)
+}
+
+func fixedAssetJournal(date *time.Time, account *Account, debit, credit float64, groupID primitive.ObjectID, now *time.Time) Journal {
+	journal := Journal{
+		Date:          date,
+		AccountID:     account.ID,
+		AccountNumber: account.Number,
+		AccountName:   account.Name,
+		GroupID:       groupID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if debit > 0 {
+		journal.DebitOrCredit = "debit"
+		journal.Debit = debit
+	} else {
+		journal.DebitOrCredit = "credit"
+		journal.Credit = credit
+	}
+	return journal
+}
+
+func (asset *FixedAsset) insertLedger(store *Store, referenceModel, referenceCode string, journals []Journal) (*Ledger, error) {
+	now := time.Now()
+	ledger := &Ledger{
+		StoreID:        asset.StoreID,
+		ReferenceID:    asset.ID,
+		ReferenceModel: referenceModel,
+		ReferenceCode:  referenceCode,
+		Journals:       journals,
+		CreatedAt:      &now,
+		UpdatedAt:      &now,
+	}
+	if err := ledger.Insert(); err != nil {
+		return nil, err
+	}
+	if _, err := ledger.CreatePostings(); err != nil {
+		return nil, err
+	}
+	return ledger, nil
+}
+
+// DoAccounting capitalises the asset: DR FIXED ASSETS / CR the account that booked its cost.
+func (asset *FixedAsset) DoAccounting() error {
+	store, err := FindStoreByID(asset.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	assetAccount, err := store.CreateAccountIfNotExists(asset.StoreID, nil, nil, FixedAssetsAccountName, nil, nil)
+	if err != nil {
+		return err
+	}
+	fromAccount, err := asset.capitalisedFromAccount(store)
+	if err != nil {
+		return errors.New("error finding the account to capitalise from: " + err.Error())
+	}
+
+	now := time.Now()
+	groupID := primitive.NewObjectID()
+	journals := []Journal{
+		fixedAssetJournal(asset.AcquisitionDate, assetAccount, asset.Cost, 0, groupID, &now),
+		fixedAssetJournal(asset.AcquisitionDate, fromAccount, 0, asset.Cost, groupID, &now),
+	}
+	journals = TagJournalsWithCostCenter(journals, asset.CostCenterID, asset.CostCenterName)
+
+	_, err = asset.insertLedger(store, FixedAssetReferenceModel, asset.Code, journals)
+	return err
+}
+
+// UndoAccounting removes every ledger of the asset: capitalisation, depreciation and disposal.
+func (asset *FixedAsset) UndoAccounting() error {
+	store, err := FindStoreByID(asset.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	ledgers, err := store.FindLedgersByReferenceID(asset.ID, *asset.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	ledgerAccounts := map[string]Account{}
+	for _, ledger := range ledgers {
+		accounts, err := ledger.GetRelatedAccounts()
+		if err != nil {
+			return err
+		}
+		for key, account := range accounts {
+			ledgerAccounts[key] = account
+		}
+	}
+
+	err = store.RemoveLedgerByReferenceID(asset.ID)
+	if err != nil {
+		return err
+	}
+
+	err = store.RemovePostingsByReferenceID(asset.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range asset.Schedule {
+		asset.Schedule[i].Posted = false
+		asset.Schedule[i].PostedAt = nil
+	}
+	asset.AccumulatedDepreciation = 0
+	asset.BookValue = asset.Cost
+
+	return SetAccountBalances(ledgerAccounts)
+}
+
+func (asset *FixedAsset) SetPostBalances() error {
+	store, err := FindStoreByID(asset.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	ledgers, err := store.FindLedgersByReferenceID(asset.ID, *asset.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	for i := range ledgers {
+		if err := ledgers[i].SetPostBalancesByLedger(asset.AcquisitionDate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func depreciationReferenceCode(assetCode string, entry DepreciationEntry) string {
+	return fmt.Sprintf("%s-DEP-%03d", assetCode, entry.Number)
+}
+
+// HasDepreciationLedger checks whether the depreciation of the entry was posted already.
+func (store *Store) HasDepreciationLedger(assetID primitive.ObjectID, referenceCode string) (bool, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("ledger")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := collection.CountDocuments(ctx, bson.M{
+		"store_id":        store.ID,
+		"reference_id":    assetID,
+		"reference_model": FixedAssetDepreciationReferenceModel,
+		"reference_code":  referenceCode,
+	})
+	return count > 0, err
+}
+
+// PostDepreciation posts the depreciation of every month up to date that is not posted yet:
+// DR DEPRECIATION EXPENSE / CR ACCUMULATED DEPRECIATION. A month found in the ledger already is
+// only marked as posted, so a run that stopped half way never posts twice. Stops at the first
+// month in a closed period.
+func (asset *FixedAsset) PostDepreciation(store *Store, date time.Time) (posted int, err error) {
+	if asset.Status == FixedAssetStatusDisposed {
+		return 0, nil
+	}
+
+	var expenseAccount, accumulatedAccount *Account
+	firstDate := (*time.Time)(nil)
+	for i := range asset.Schedule {
+		entry := &asset.Schedule[i]
+		if entry.Posted || entry.Date == nil || entry.Date.After(date) {
+			continue
+		}
+
+		if message := ValidateAccountingLock(nil, asset.StoreID, FixedAssetDepreciationReferenceModel, primitive.NilObjectID, asset.Code, entry.Date); message != "" {
+			err = errors.New(message)
+			break
+		}
+
+		referenceCode := depreciationReferenceCode(asset.Code, *entry)
+		exists, err := store.HasDepreciationLedger(asset.ID, referenceCode)
+		if err != nil {
+			return posted, err
+		}
+		if !exists {
+			if expenseAccount == nil {
+				if expenseAccount, err = store.CreateAccountIfNotExists(asset.StoreID, nil, nil, DepreciationExpenseAccountName, nil, nil); err != nil {
+					return posted, err
+				}
+				if accumulatedAccount, err = store.CreateAccountIfNotExists(asset.StoreID, nil, nil, AccumulatedDepreciationAccountName, nil, nil); err != nil {
+					return posted, err
+				}
+			}
+
+			now := time.Now()
+			groupID := primitive.NewObjectID()
+			journals := []Journal{
+				fixedAssetJournal(entry.Date, expenseAccount, entry.Amount, 0, groupID, &now),
+				fixedAssetJournal(entry.Date, accumulatedAccount, 0, entry.Amount, groupID, &now),
+			}
+			journals = TagJournalsWithCostCenter(journals, asset.CostCenterID, asset.CostCenterName)
+			if _, err := asset.insertLedger(store, FixedAssetDepreciationReferenceModel, referenceCode, journals); err != nil {
+				return posted, errors.New("error posting depreciation " + referenceCode + ": " + err.Error())
+			}
+			if firstDate == nil {
+				firstDate = entry.Date
+			}
+		}
+
+		now := time.Now()
+		entry.Posted = true
+		entry.PostedAt = &now
+		posted++
+	}
+
+	asset.AccumulatedDepreciation = asset.PostedDepreciation()
+	asset.BookValue = RoundTo2Decimals(asset.Cost - asset.AccumulatedDepreciation)
+	if len(asset.Schedule) > 0 && asset.Schedule[len(asset.Schedule)-1].Posted {
+		asset.Status = FixedAssetStatusFullyDepreciated
+	}
+	asset.LastError = ""
+	if err != nil {
+		asset.LastError = err.Error()
+	}
+
+	if updateErr := asset.Update(); updateErr != nil {
+		return posted, updateErr
+	}
+
+	// Months posted late sit before postings already on the accounts
+	if firstDate != nil {
+		for _, account := range []*Account{expenseAccount, accumulatedAccount} {
+			if rebuildErr := RebuildAccountPostingBalances(store, account.ID); rebuildErr != nil {
+				return posted, rebuildErr
+			}
+		}
+	}
+
+	return posted, err
+}
+
+// Dispose sells or writes off the asset on the disposal date. Depreciation is posted up to the
+// disposal, the months after are dropped, and the book value is taken off against the proceeds:
+// DR CASH/BANK proceeds, DR ACCUMULATED DEPRECIATION, CR FIXED ASSETS cost and the difference to
+// GAIN or LOSS ON DISPOSAL OF ASSETS.
+func (asset *FixedAsset) Dispose(store *Store, disposal *FixedAssetDisposal) error {
+	if _, err := asset.PostDepreciation(store, *disposal.Date); err != nil {
+		return err
+	}
+
+	schedule := []DepreciationEntry{}
+	for _, entry := range asset.Schedule {
+		if entry.Posted {
+			schedule = append(schedule, entry)
+		}
+	}
+	asset.Schedule = schedule
+	asset.AccumulatedDepreciation = asset.PostedDepreciation()
+	gainLoss := DisposalGainLoss(asset.Cost, asset.AccumulatedDepreciation, disposal.Proceeds)
+
+	assetAccount, err := store.CreateAccountIfNotExists(asset.StoreID, nil, nil, FixedAssetsAccountName, nil, nil)
+	if err != nil {
+		return err
+	}
+	accumulatedAccount, err := store.CreateAccountIfNotExists(asset.StoreID, nil, nil, AccumulatedDepreciationAccountName, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	groupID := primitive.NewObjectID()
+	journals := []Journal{}
+
+	if disposal.Proceeds > 0 {
+		receivingAccountName := "Cash"
+		if slices.Contains(BANK_PAYMENT_METHODS, disposal.PaymentMethod) {
+			receivingAccountName = "Bank"
+		}
+		receivingAccount, err := store.CreateAccountIfNotExists(asset.StoreID, nil, nil, receivingAccountName, nil, nil)
+		if err != nil {
+			return err
+		}
+		journals = append(journals, fixedAssetJournal(disposal.Date, receivingAccount, disposal.Proceeds, 0, groupID, &now))
+	}
+	if asset.AccumulatedDepreciation > 0 {
+		journals = append(journals, fixedAssetJournal(disposal.Date, accumulatedAccount, asset.AccumulatedDepreciation, 0, groupID, &now))
+	}
+	if gainLoss < 0 {
+		lossAccount, err := store.CreateAccountIfNotExists(asset.StoreID, nil, nil, AssetDisposalLossAccountName, nil, nil)
+		if err != nil {
+			return err
+		}
+		journals = append(journals, fixedAssetJournal(disposal.Date, lossAccount, -gainLoss, 0, groupID, &now))
+	}
+	journals = append(journals, fixedAssetJournal(disposal.Date, assetAccount, 0, asset.Cost, groupID, &now))
+	if gainLoss > 0 {
+		gainAccount, err := store.CreateAccountIfNotExists(asset.StoreID, nil, nil, AssetDisposalGainAccountName, nil, nil)
+		if err != nil {
+			return err
+		}
+		journals = append(journals, fixedAssetJournal(disposal.Date, gainAccount, 0, gainLoss, groupID, &now))
+	}
+	journals = TagJournalsWithCostCenter(journals, asset.CostCenterID, asset.CostCenterName)
+
+	ledger, err := asset.insertLedger(store, FixedAssetDisposalReferenceModel, asset.Code+"-DISPOSAL", journals)
+	if err != nil {
+		return err
+	}
+
+	asset.Status = FixedAssetStatusDisposed
+	asset.DisposalDate = disposal.Date
+	asset.DisposalProceeds = RoundTo2Decimals(disposal.Proceeds)
+	asset.DisposalPaymentMethod = disposal.PaymentMethod
+	asset.DisposalGainLoss = gainLoss
+	asset.BookValue = 0
+	if err := asset.Update(); err != nil {
+		return err
+	}
+
+	go ledger.SetPostBalancesByLedger(disposal.Date)
+	return nil
+}
+
+// Validate checks the disposal of asset.
+func (disposal *FixedAssetDisposal) Validate(w http.ResponseWriter, r *http.Request, asset *FixedAsset) (errs map[string]string) {
+	errs = make(map[string]string)
+
+	if asset.Status == FixedAssetStatusDisposed {
+		errs["status"] = "The asset is disposed already"
+		w.WriteHeader(http.StatusBadRequest)
+		return errs
+	}
+
+	if govalidator.IsNull(disposal.DateStr) {
+		errs["date_str"] = "Date is required"
+	} else {
+		const shortForm = "2006-01-02T15:04:05Z07:00"
+		date, err := time.Parse(shortForm, disposal.DateStr)
+		if err != nil {
+			errs["date_str"] = "Invalid date format"
+		} else if date.Before(*asset.AcquisitionDate) {
+			errs["date_str"] = "Disposal date should not be before the acquisition date"
+		} else {
+			disposal.Date = &date
+		}
+	}
+
+	if disposal.Proceeds < 0 {
+		errs["proceeds"] = "Proceeds should not be negative"
+	} else if disposal.Proceeds > 0 && disposal.PaymentMethod != "cash" && !slices.Contains(BANK_PAYMENT_METHODS, disposal.PaymentMethod) {
+		errs["payment_method"] = "Invalid payment method"
+	}
+
+	if len(errs) == 0 {
+		if message := ValidateAccountingLock(r, asset.StoreID, FixedAssetDisposalReferenceModel, asset.ID, asset.Code, disposal.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+// ──────────────────────────────────────────────────────────
+// Background cron: monthly depreciation
+// ──────────────────────────────────────────────────────────
+
+// ProcessFixedAssetDepreciationForAllStores runs every hour and posts the depreciation of the
+// months that have ended.
+func ProcessFixedAssetDepreciationForAllStores() error {
+	stores, err := GetAllStores()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, store := range stores {
+		if err := store.ProcessFixedAssetDepreciation(now); err != nil {
+			log.Printf("[fixed-asset-cron] store %s: %v", store.ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
+
+func (store *Store) ProcessFixedAssetDepreciation(now time.Time) error {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("fixed_asset")
+	ctx := context.Background()
+
+	cur, err := collection.Find(ctx, bson.M{
+		"store_id": store.ID,
+		"deleted":  bson.M{"$ne": true},
+		"status":   FixedAssetStatusActive,
+		"schedule": bson.M{"$elemMatch": bson.M{"posted": false, "date": bson.M{"$lte": now}}},
+	}, options.Find().SetNoCursorTimeout(true))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		asset := FixedAsset{}
+		if err := cur.Decode(&asset); err != nil {
+			continue
+		}
+		if _, err := asset.PostDepreciation(store, now); err != nil {
+			log.Printf("[fixed-asset-cron] asset %s: %v", asset.Code, err)
+		}
+	}
+
+	return cur.Err()
+}
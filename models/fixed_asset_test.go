@@ -0,0 +1,120 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// ── BuildDepreciationSchedule ────────────────────────────────────────────────
+
+func TestBuildDepreciationSchedule_StraightLine(t *testing.T) {
+	riyadh := StoreLocation("SA")
+	acquired := time.Date(2026, 1, 20, 10, 0, 0, 0, riyadh)
+
+	schedule := BuildDepreciationSchedule(1000, 0, 3, FixedAssetMethodStraightLine, 0, acquired, riyadh)
+	if len(schedule) != 3 {
+		t.Fatalf("schedule = %+v, want 3 months", schedule)
+	}
+
+	// the last month takes the rounding
+	if schedule[0].Amount != 333.33 || schedule[1].Amount != 333.33 || schedule[2].Amount != 333.34 {
+		t.Errorf("amounts = %v, %v, %v", schedule[0].Amount, schedule[1].Amount, schedule[2].Amount)
+	}
+	if schedule[2].AccumulatedDepreciation != 1000 || schedule[2].BookValue != 0 {
+		t.Errorf("last entry = %+v, want fully depreciated", schedule[2])
+	}
+
+	// entries fall on the last day of the month, store time
+	if want := time.Date(2026, 1, 31, 0, 0, 0, 0, riyadh); !schedule[0].Date.Equal(want) {
+		t.Errorf("first date = %s, want %s", schedule[0].Date.In(riyadh), want)
+	}
+	if want := time.Date(2026, 2, 28, 0, 0, 0, 0, riyadh); !schedule[1].Date.Equal(want) {
+		t.Errorf("second date = %s, want %s", schedule[1].Date.In(riyadh), want)
+	}
+	if schedule[0].Number != 1 || schedule[2].Number != 3 {
+		t.Errorf("numbers = %d..%d, want 1..3", schedule[0].Number, schedule[2].Number)
+	}
+}
+
+func TestBuildDepreciationSchedule_DecliningBalanceStopsAtSalvage(t *testing.T) {
+	acquired := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// double declining: 2 / 4 months = 50% of the book value every month
+	schedule := BuildDepreciationSchedule(1000, 100, 4, FixedAssetMethodDecliningBalance, 0, acquired, time.UTC)
+	want := []float64{500, 250, 125, 25}
+	if len(schedule) != len(want) {
+		t.Fatalf("schedule = %+v, want %v", schedule, want)
+	}
+	for i := range want {
+		if schedule[i].Amount != want[i] {
+			t.Errorf("month %d = %v, want %v", i+1, schedule[i].Amount, want[i])
+		}
+	}
+	if last := schedule[len(schedule)-1]; last.BookValue != 100 {
+		t.Errorf("book value = %v, want the salvage value", last.BookValue)
+	}
+
+	// 60% a year is 5% a month, the last month writes down to the salvage value
+	schedule = BuildDepreciationSchedule(1200, 0, 2, FixedAssetMethodDecliningBalance, 60, acquired, time.UTC)
+	if len(schedule) != 2 || schedule[0].Amount != 60 || schedule[1].Amount != 1140 {
+		t.Errorf("schedule = %+v", schedule)
+	}
+
+	if schedule := BuildDepreciationSchedule(500, 500, 12, FixedAssetMethodStraightLine, 0, acquired, time.UTC); len(schedule) != 0 {
+		t.Errorf("schedule = %+v, want nothing to depreciate", schedule)
+	}
+}
+
+// ── DisposalGainLoss ─────────────────────────────────────────────────────────
+
+func TestDisposalGainLoss(t *testing.T) {
+	if got := DisposalGainLoss(10000, 6000, 5000); got != 1000 {
+		t.Errorf("gain = %v, want 1000", got)
+	}
+	if got := DisposalGainLoss(10000, 6000, 0); got != -4000 {
+		t.Errorf("write off = %v, want -4000", got)
+	}
+}
+
+// ── BuildFixedAssetRegister ──────────────────────────────────────────────────
+
+func TestBuildFixedAssetRegister(t *testing.T) {
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	lift := FixedAsset{Code: "FA-000001", Name: "LIFT", Cost: 1200, AcquisitionDate: &jan, Status: FixedAssetStatusActive}
+	lift.Schedule = BuildDepreciationSchedule(1200, 0, 12, FixedAssetMethodStraightLine, 0, jan, time.UTC)
+
+	disposedAt := time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)
+	van := FixedAsset{
+		Code: "FA-000002", Name: "VAN", Cost: 600, AcquisitionDate: &jan,
+		Status: FixedAssetStatusDisposed, DisposalDate: &disposedAt, DisposalProceeds: 550, DisposalGainLoss: 50,
+	}
+	van.Schedule = BuildDepreciationSchedule(600, 0, 6, FixedAssetMethodStraightLine, 0, jan, time.UTC)[:1]
+
+	later := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	tools := FixedAsset{Code: "FA-000003", Name: "TOOLS", Cost: 300, AcquisitionDate: &later, Status: FixedAssetStatusActive}
+
+	from := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 31, 23, 59, 59, 0, time.UTC)
+	register := BuildFixedAssetRegister([]FixedAsset{lift, van, tools}, &from, to)
+
+	if len(register.Lines) != 2 {
+		t.Fatalf("lines = %+v, want the lift and the van", register.Lines)
+	}
+	line := register.Lines[0]
+	if line.OpeningDepreciation != 100 || line.PeriodDepreciation != 200 || line.AccumulatedDepreciation != 300 || line.BookValue != 900 {
+		t.Errorf("lift = %+v", line)
+	}
+	line = register.Lines[1]
+	if line.DisposalDate == nil || line.BookValue != 0 || line.DisposalGainLoss != 50 {
+		t.Errorf("van = %+v, want disposed in the period", line)
+	}
+	if register.Total.Cost != 1800 || register.Total.BookValue != 900 || register.Total.PeriodDepreciation != 200 {
+		t.Errorf("total = %+v", register.Total)
+	}
+
+	// before the disposal the van is still held
+	register = BuildFixedAssetRegister([]FixedAsset{van}, nil, time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC))
+	if line := register.Lines[0]; line.Status != FixedAssetStatusActive || line.BookValue != 500 || line.DisposalDate != nil {
+		t.Errorf("van in January = %+v", line)
+	}
+}
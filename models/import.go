@@ -0,0 +1,391 @@
+package models
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	ImportEntityProduct  = "product"
+	ImportEntityCustomer = "customer"
+	ImportEntityVendor   = "vendor"
+
+	// ImportEntityBankStatement is only used for column mapping of bank statement files, see ParseBankStatementCSV
+	ImportEntityBankStatement = "bank_statement"
+
+	ImportFieldString = "string"
+	ImportFieldNumber = "number"
+	ImportFieldBool   = "bool"
+
+	ImportMaxRows = 20000
+)
+
+// ImportField : a document field a file column can be mapped to. Key is the JSON path on the document.
+type ImportField struct {
+	Key      string `json:"key"`
+	Label    string `json:"label"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	// StoreScoped fields are stored under product_stores.<store_id>
+	StoreScoped bool `json:"-"`
+}
+
+var nationalAddressImportFields = []ImportField{
+	{Key: "national_address.building_no", Label: "Building No", Type: ImportFieldString},
+	{Key: "national_address.street_name", Label: "Street Name", Type: ImportFieldString},
+	{Key: "national_address.district_name", Label: "District", Type: ImportFieldString},
+	{Key: "national_address.city_name", Label: "City", Type: ImportFieldString},
+	{Key: "national_address.zipcode", Label: "Zip Code", Type: ImportFieldString},
+	{Key: "national_address.additional_no", Label: "Additional No", Type: ImportFieldString},
+	{Key: "national_address.unit_no", Label: "Unit No", Type: ImportFieldString},
+}
+
+var partyImportFields = append([]ImportField{
+	{Key: "code", Label: "ID", Type: ImportFieldString},
+	{Key: "name", Label: "Name", Type: ImportFieldString, Required: true},
+	{Key: "name_in_arabic", Label: "Name In Arabic", Type: ImportFieldString},
+	{Key: "vat_no", Label: "VAT No", Type: ImportFieldString},
+	{Key: "registration_number", Label: "CR No", Type: ImportFieldString},
+	{Key: "phone", Label: "Phone", Type: ImportFieldString},
+	{Key: "phone2", Label: "Phone 2", Type: ImportFieldString},
+	{Key: "email", Label: "Email", Type: ImportFieldString},
+	{Key: "address", Label: "Address", Type: ImportFieldString},
+	{Key: "address_in_arabic", Label: "Address In Arabic", Type: ImportFieldString},
+	{Key: "country_code", Label: "Country Code", Type: ImportFieldString},
+	{Key: "contact_person", Label: "Contact Person", Type: ImportFieldString},
+	{Key: "credit_limit", Label: "Credit Limit", Type: ImportFieldNumber},
+	{Key: "remarks", Label: "Remarks", Type: ImportFieldString},
+}, nationalAddressImportFields...)
+
+// ImportFields : the mappable fields of each importable entity.
+var ImportFields = map[string][]ImportField{
+	ImportEntityProduct: {
+		{Key: "name", Label: "Name", Type: ImportFieldString, Required: true},
+		{Key: "name_in_arabic", Label: "Name In Arabic", Type: ImportFieldString},
+		{Key: "part_number", Label: "Part Number", Type: ImportFieldString},
+		{Key: "item_code", Label: "Item Code", Type: ImportFieldString},
+		{Key: "ean_12", Label: "Barcode", Type: ImportFieldString},
+		{Key: "rack", Label: "Rack", Type: ImportFieldString},
+		{Key: "unit", Label: "Unit", Type: ImportFieldString},
+		{Key: "country_code", Label: "Country Code", Type: ImportFieldString},
+		{Key: "note", Label: "Note", Type: ImportFieldString},
+		{Key: "is_service", Label: "Is Service", Type: ImportFieldBool},
+		{Key: "purchase_unit_price", Label: "Purchase Unit Price", Type: ImportFieldNumber, StoreScoped: true},
+		{Key: "wholesale_unit_price", Label: "Wholesale Unit Price", Type: ImportFieldNumber, StoreScoped: true},
+		{Key: "retail_unit_price", Label: "Retail Unit Price", Type: ImportFieldNumber, StoreScoped: true},
+		{Key: "with_vat", Label: "Prices Include VAT", Type: ImportFieldBool, StoreScoped: true},
+		{Key: "stock", Label: "Opening Stock", Type: ImportFieldNumber},
+	},
+	ImportEntityCustomer: partyImportFields,
+	ImportEntityVendor:   partyImportFields,
+	ImportEntityBankStatement: {
+		{Key: "date", Label: "Date", Type: ImportFieldString, Required: true},
+		{Key: "value_date", Label: "Value Date", Type: ImportFieldString},
+		{Key: "description", Label: "Description", Type: ImportFieldString},
+		{Key: "reference", Label: "Reference", Type: ImportFieldString},
+		{Key: "counterparty", Label: "Counterparty", Type: ImportFieldString},
+		{Key: "amount", Label: "Amount", Type: ImportFieldNumber},
+		{Key: "debit", Label: "Debit", Type: ImportFieldNumber},
+		{Key: "credit", Label: "Credit", Type: ImportFieldNumber},
+		{Key: "balance", Label: "Balance", Type: ImportFieldNumber},
+	},
+}
+
+// ImportUniqueFields : fields that must not repeat within one file.
+var ImportUniqueFields = map[string][]string{
+	ImportEntityProduct:  {"part_number", "ean_12"},
+	ImportEntityCustomer: {"code"},
+	ImportEntityVendor:   {"code"},
+}
+
+// ImportSheet : the header row and data rows of an uploaded file
+type ImportSheet struct {
+	Headers []string
+	Rows    [][]string
+}
+
+// ImportRowError : validation errors of one data row. Row is the line number in the file (the header is row 1).
+type ImportRowError struct {
+	Row    int               `json:"row"`
+	Errors map[string]string `json:"errors"`
+}
+
+// ImportMapping : field key => column header in the file
+type ImportMapping map[string]string
+
+// ParseImportFile reads the first sheet of an .xlsx file or a .csv file.
+func ParseImportFile(filename string, reader io.Reader) (*ImportSheet, error) {
+	var rows [][]string
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, errors.New("error reading file: " + err.Error())
+		}
+		csvReader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+		csvReader.FieldsPerRecord = -1
+		csvReader.LazyQuotes = true
+		rows, err = csvReader.ReadAll()
+		if err != nil {
+			return nil, errors.New("invalid csv file: " + err.Error())
+		}
+	case ".xlsx":
+		f, err := excelize.OpenReader(reader)
+		if err != nil {
+			return nil, errors.New("invalid xlsx file: " + err.Error())
+		}
+		defer f.Close()
+
+		rows, err = f.GetRows(f.GetSheetName(0))
+		if err != nil {
+			return nil, errors.New("error reading sheet: " + err.Error())
+		}
+	default:
+		return nil, errors.New("unsupported file type, upload a .xlsx or .csv file")
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	sheet := &ImportSheet{}
+	for _, header := range rows[0] {
+		sheet.Headers = append(sheet.Headers, strings.TrimSpace(header))
+	}
+	sheet.Rows = rows[1:]
+
+	if len(sheet.Rows) > ImportMaxRows {
+		return nil, errors.New("file has more than " + strconv.Itoa(ImportMaxRows) + " rows")
+	}
+
+	return sheet, nil
+}
+
+// ParseImportMapping decodes the mapping form value, e.g. {"name":"Item Name","retail_unit_price":"Price"}
+func ParseImportMapping(raw string) (ImportMapping, error) {
+	mapping := ImportMapping{}
+	if strings.TrimSpace(raw) == "" {
+		return mapping, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+		return nil, errors.New("invalid mapping: " + err.Error())
+	}
+	return mapping, nil
+}
+
+// ResolveImportColumns returns the column index of every mapped field.
+// Fields left out of the mapping are matched to a header equal to their key or label.
+func ResolveImportColumns(entity string, headers []string, mapping ImportMapping) (columns map[string]int, errs map[string]string) {
+	columns = map[string]int{}
+	errs = map[string]string{}
+
+	fields, ok := ImportFields[entity]
+	if !ok {
+		errs["entity"] = "Unsupported import entity: " + entity
+		return columns, errs
+	}
+
+	headerIndex := map[string]int{}
+	for i, header := range headers {
+		key := strings.ToLower(header)
+		if _, exists := headerIndex[key]; !exists && key != "" {
+			headerIndex[key] = i
+		}
+	}
+
+	known := map[string]bool{}
+	for _, field := range fields {
+		known[field.Key] = true
+	}
+	for key := range mapping {
+		if !known[key] {
+			errs["mapping_"+key] = "Unknown field: " + key
+		}
+	}
+
+	for _, field := range fields {
+		if header, mapped := mapping[field.Key]; mapped {
+			if strings.TrimSpace(header) == "" {
+				continue
+			}
+			index, found := headerIndex[strings.ToLower(strings.TrimSpace(header))]
+			if !found {
+				errs["mapping_"+field.Key] = "Column not found: " + header
+				continue
+			}
+			columns[field.Key] = index
+			continue
+		}
+
+		if index, found := headerIndex[strings.ToLower(field.Key)]; found {
+			columns[field.Key] = index
+		} else if index, found := headerIndex[strings.ToLower(field.Label)]; found {
+			columns[field.Key] = index
+		}
+	}
+
+	for _, field := range fields {
+		if _, mapped := columns[field.Key]; field.Required && !mapped {
+			if _, hasErr := errs["mapping_"+field.Key]; !hasErr {
+				errs["mapping_"+field.Key] = field.Label + " column is required"
+			}
+		}
+	}
+
+	return columns, errs
+}
+
+// ImportCell returns the trimmed cell at index, or "" for short rows.
+func ImportCell(row []string, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[index])
+}
+
+// IsImportRowEmpty is true for spacer rows with no values at all.
+func IsImportRowEmpty(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// BuildImportDocument converts a row into a JSON-shaped document using the resolved columns.
+// Store-scoped fields are nested under product_stores.<storeID>.
+func BuildImportDocument(entity string, storeID string, row []string, columns map[string]int) (doc map[string]interface{}, errs map[string]string) {
+	doc = map[string]interface{}{}
+	errs = map[string]string{}
+
+	for _, field := range ImportFields[entity] {
+		index, ok := columns[field.Key]
+		if !ok {
+			continue
+		}
+		value := ImportCell(row, index)
+		if value == "" {
+			continue
+		}
+
+		var typedValue interface{}
+		switch field.Type {
+		case ImportFieldNumber:
+			number, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+			if err != nil {
+				errs[field.Key] = "Invalid number: " + value
+				continue
+			}
+			typedValue = number
+		case ImportFieldBool:
+			switch strings.ToLower(value) {
+			case "1", "true", "yes", "y":
+				typedValue = true
+			case "0", "false", "no", "n":
+				typedValue = false
+			default:
+				errs[field.Key] = "Invalid yes/no value: " + value
+				continue
+			}
+		default:
+			typedValue = value
+		}
+
+		path := strings.Split(field.Key, ".")
+		if field.StoreScoped {
+			path = append([]string{"product_stores", storeID}, path...)
+		}
+		setImportValue(doc, path, typedValue)
+	}
+
+	return doc, errs
+}
+
+func setImportValue(doc map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := doc[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			doc[key] = child
+		}
+		doc = child
+	}
+	doc[path[len(path)-1]] = value
+}
+
+func decodeImportDocument(doc map[string]interface{}, out interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// NewImportProduct builds an unsaved product from a row. An opening stock becomes an "adding" stock adjustment.
+func (store *Store) NewImportProduct(row []string, columns map[string]int) (product *Product, errs map[string]string) {
+	doc, errs := BuildImportDocument(ImportEntityProduct, store.ID.Hex(), row, columns)
+	stock, _ := doc["stock"].(float64)
+	delete(doc, "stock")
+
+	product = &Product{}
+	if err := decodeImportDocument(doc, product); err != nil {
+		errs["row"] = "Invalid row: " + err.Error()
+		return product, errs
+	}
+	product.StoreID = &store.ID
+
+	if stock != 0 {
+		if product.ProductStores == nil {
+			product.ProductStores = map[string]ProductStore{}
+		}
+		productStore := product.ProductStores[store.ID.Hex()]
+		productStore.StockAdjustments = append(productStore.StockAdjustments, StockAdjustment{
+			DateStr:  time.Now().Format(time.RFC3339),
+			Type:     "adding",
+			Quantity: stock,
+			Reason:   "Opening stock (import)",
+		})
+		product.ProductStores[store.ID.Hex()] = productStore
+	}
+
+	return product, errs
+}
+
+// NewImportCustomer builds an unsaved customer from a row
+func (store *Store) NewImportCustomer(row []string, columns map[string]int) (customer *Customer, errs map[string]string) {
+	doc, errs := BuildImportDocument(ImportEntityCustomer, store.ID.Hex(), row, columns)
+
+	customer = &Customer{}
+	if err := decodeImportDocument(doc, customer); err != nil {
+		errs["row"] = "Invalid row: " + err.Error()
+		return customer, errs
+	}
+	customer.StoreID = &store.ID
+	customer.Name = strings.ToUpper(customer.Name)
+	return customer, errs
+}
+
+// NewImportVendor builds an unsaved vendor from a row
+func (store *Store) NewImportVendor(row []string, columns map[string]int) (vendor *Vendor, errs map[string]string) {
+	doc, errs := BuildImportDocument(ImportEntityVendor, store.ID.Hex(), row, columns)
+
+	vendor = &Vendor{}
+	if err := decodeImportDocument(doc, vendor); err != nil {
+		errs["row"] = "Invalid row: " + err.Error()
+		return vendor, errs
+	}
+	vendor.StoreID = &store.ID
+	vendor.Name = strings.ToUpper(vendor.Name)
+	return vendor, errs
+}
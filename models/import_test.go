@@ -0,0 +1,165 @@
+package models
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ── ParseImportFile ──────────────────────────────────────────────────────────
+
+func TestParseImportFile_CSV(t *testing.T) {
+	data := "\xef\xbb\xbfItem Name , Price\nOil Filter,12.50\nBrake Pad\n"
+
+	sheet, err := ParseImportFile("products.CSV", strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Headers) != 2 || sheet.Headers[0] != "Item Name" || sheet.Headers[1] != "Price" {
+		t.Fatalf("headers = %q", sheet.Headers)
+	}
+	if len(sheet.Rows) != 2 {
+		t.Fatalf("rows = %d, want 2", len(sheet.Rows))
+	}
+	if len(sheet.Rows[1]) != 1 {
+		t.Errorf("short rows must be kept as-is, got %q", sheet.Rows[1])
+	}
+}
+
+func TestParseImportFile_XLSX(t *testing.T) {
+	f := excelize.NewFile()
+	sheetName := f.GetSheetName(0)
+	f.SetCellValue(sheetName, "A1", "name")
+	f.SetCellValue(sheetName, "B1", "vat_no")
+	f.SetCellValue(sheetName, "A2", "Acme")
+	f.SetCellValue(sheetName, "B2", "300000000000003")
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	sheet, err := ParseImportFile("customers.xlsx", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Rows) != 1 || sheet.Rows[0][1] != "300000000000003" {
+		t.Fatalf("rows = %q", sheet.Rows)
+	}
+}
+
+func TestParseImportFile_RejectsUnsupportedAndEmptyFiles(t *testing.T) {
+	if _, err := ParseImportFile("products.xls", strings.NewReader("x")); err == nil {
+		t.Error("expected an error for .xls")
+	}
+	if _, err := ParseImportFile("products.csv", strings.NewReader("")); err == nil {
+		t.Error("expected an error for an empty file")
+	}
+}
+
+// ── ResolveImportColumns ─────────────────────────────────────────────────────
+
+func TestResolveImportColumns_MappingAndAutoMatch(t *testing.T) {
+	headers := []string{"Item", "Retail Unit Price", "part_number", "Cost"}
+	mapping := ImportMapping{"name": "item", "purchase_unit_price": "COST"}
+
+	columns, errs := ResolveImportColumns(ImportEntityProduct, headers, mapping)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	want := map[string]int{"name": 0, "retail_unit_price": 1, "part_number": 2, "purchase_unit_price": 3}
+	for key, index := range want {
+		if got, ok := columns[key]; !ok || got != index {
+			t.Errorf("columns[%s] = %d, %v; want %d", key, got, ok, index)
+		}
+	}
+	if len(columns) != len(want) {
+		t.Errorf("columns = %v", columns)
+	}
+}
+
+func TestResolveImportColumns_Errors(t *testing.T) {
+	headers := []string{"Phone"}
+
+	_, errs := ResolveImportColumns(ImportEntityCustomer, headers, ImportMapping{"nickname": "Phone", "email": "E-mail"})
+	if errs["mapping_nickname"] == "" {
+		t.Error("expected an unknown field error")
+	}
+	if errs["mapping_email"] == "" {
+		t.Error("expected a missing column error")
+	}
+	if errs["mapping_name"] == "" {
+		t.Error("expected a required column error for name")
+	}
+
+	if _, errs := ResolveImportColumns("invoice", headers, nil); errs["entity"] == "" {
+		t.Error("expected an unsupported entity error")
+	}
+}
+
+// ── BuildImportDocument ──────────────────────────────────────────────────────
+
+func TestBuildImportDocument_TypesAndNesting(t *testing.T) {
+	storeID := primitive.NewObjectID().Hex()
+	columns := map[string]int{"name": 0, "retail_unit_price": 1, "with_vat": 2, "is_service": 3}
+
+	doc, errs := BuildImportDocument(ImportEntityProduct, storeID, []string{" Oil ", "1,250.5", "yes"}, columns)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if doc["name"] != "Oil" {
+		t.Errorf("name = %v", doc["name"])
+	}
+	if _, ok := doc["is_service"]; ok {
+		t.Error("missing cells must be left out")
+	}
+	productStore := doc["product_stores"].(map[string]interface{})[storeID].(map[string]interface{})
+	if productStore["retail_unit_price"] != 1250.5 || productStore["with_vat"] != true {
+		t.Errorf("product_stores = %v", productStore)
+	}
+
+	_, errs = BuildImportDocument(ImportEntityProduct, storeID, []string{"Oil", "abc", "maybe"}, columns)
+	if errs["retail_unit_price"] == "" || errs["with_vat"] == "" {
+		t.Errorf("expected type errors, got %v", errs)
+	}
+}
+
+func TestNewImportProduct_OpeningStockAndAddress(t *testing.T) {
+	store := &Store{ID: primitive.NewObjectID()}
+
+	product, errs := store.NewImportProduct([]string{"Oil Filter", "5", "10"}, map[string]int{"name": 0, "stock": 1, "retail_unit_price": 2})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if product.StoreID == nil || *product.StoreID != store.ID {
+		t.Error("store id not set")
+	}
+	productStore := product.ProductStores[store.ID.Hex()]
+	if productStore.RetailUnitPrice != 10 {
+		t.Errorf("retail unit price = %v", productStore.RetailUnitPrice)
+	}
+	if len(productStore.StockAdjustments) != 1 || productStore.StockAdjustments[0].Quantity != 5 || productStore.StockAdjustments[0].Type != "adding" {
+		t.Errorf("stock adjustments = %+v", productStore.StockAdjustments)
+	}
+
+	vendor, errs := store.NewImportVendor([]string{"acme", "12345"}, map[string]int{"name": 0, "national_address.zipcode": 1})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if vendor.Name != "ACME" || vendor.NationalAddress.ZipCode != "12345" {
+		t.Errorf("vendor = %q %q", vendor.Name, vendor.NationalAddress.ZipCode)
+	}
+}
+
+func TestImportCell(t *testing.T) {
+	row := []string{" a ", "b"}
+	if ImportCell(row, 0) != "a" || ImportCell(row, 5) != "" || ImportCell(row, -1) != "" {
+		t.Error("unexpected cell values")
+	}
+	if !IsImportRowEmpty([]string{"", "  "}) || IsImportRowEmpty(row) {
+		t.Error("unexpected empty row detection")
+	}
+}
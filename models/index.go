@@ -44,10 +44,15 @@ func (store *Store) CreateAllIndexes() error {
 		}
 	}
 	cidx := func(coll string, fields bson.D) {
-		if err := store.CreateCompoundIndex(coll, fields); err != nil {
+		if err := store.CreateCompoundIndex(coll, fields, false); err != nil {
 			errs = append(errs, fmt.Sprintf("%s compound%v: %v", coll, fields, err))
 		}
 	}
+	ucidx := func(coll string, fields bson.D) {
+		if err := store.CreateCompoundIndex(coll, fields, true); err != nil {
+			errs = append(errs, fmt.Sprintf("%s unique compound%v: %v", coll, fields, err))
+		}
+	}
 
 	// product
 	tidx("product", bson.D{
@@ -262,6 +267,102 @@ func (store *Store) CreateAllIndexes() error {
 	idx("product_sales_return_history", bson.M{"created_at": -1})
 	idx("product_sales_return_history", bson.M{"updated_at": -1})
 
+	// product_lot_history
+	cidx("product_lot_history", bson.D{{Key: "product_id", Value: 1}, {Key: "warehouse_id", Value: 1}})
+	idx("product_lot_history", bson.M{"reference_id": 1})
+	idx("product_lot_history", bson.M{"order_id": 1})
+	idx("product_lot_history", bson.M{"expiry_date": 1})
+
+	// product_serial_history
+	cidx("product_serial_history", bson.D{{Key: "product_id", Value: 1}, {Key: "serial_number", Value: 1}})
+	idx("product_serial_history", bson.M{"serial_number": 1})
+	idx("product_serial_history", bson.M{"reference_id": 1})
+
+	// price_list
+	idx("price_list", bson.M{"type": 1})
+	idx("price_list", bson.M{"customer_ids": 1})
+	idx("price_list", bson.M{"items.product_id": 1})
+
+	// promotion
+	idx("promotion", bson.M{"coupon_code": 1})
+	idx("promotion", bson.M{"start_at": 1})
+	idx("promotion", bson.M{"end_at": 1})
+	idx("order", bson.M{"products.promotion_id": 1})
+	idx("product_sales_history", bson.M{"promotion_id": 1})
+
+	// loyalty_point_history
+	cidx("loyalty_point_history", bson.D{{Key: "customer_id", Value: 1}, {Key: "date", Value: -1}})
+	idx("loyalty_point_history", bson.M{"order_id": 1})
+	idx("loyalty_point_history", bson.M{"reference_id": 1})
+
+	// cash_register / register_shift
+	idx("cash_register", bson.M{"code": 1})
+	cidx("register_shift", bson.D{{Key: "register_id", Value: 1}, {Key: "status", Value: 1}})
+	cidx("register_shift", bson.D{{Key: "opened_by", Value: 1}, {Key: "status", Value: 1}})
+	idx("register_shift", bson.M{"opened_at": -1})
+	idx("sales_payment", bson.M{"shift_id": 1})
+	idx("sales_return_payment", bson.M{"shift_id": 1})
+	idx("customerdeposit", bson.M{"payments.shift_id": 1})
+
+	// webhook / webhook_delivery
+	idx("webhook", bson.M{"events": 1})
+	cidx("webhook_delivery", bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}})
+	cidx("webhook_delivery", bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}})
+	idx("webhook_delivery", bson.M{"event_id": 1})
+
+	// zatca_queue
+	cidx("zatca_queue", bson.D{{Key: "document_type", Value: 1}, {Key: "status", Value: 1}, {Key: "invoice_count_value", Value: 1}})
+	cidx("zatca_queue", bson.D{{Key: "document_type", Value: 1}, {Key: "document_id", Value: 1}})
+	cidx("zatca_queue", bson.D{{Key: "status", Value: 1}, {Key: "deadline", Value: 1}})
+
+	// audit_log
+	cidx("audit_log", bson.D{{Key: "entity", Value: 1}, {Key: "entity_id", Value: 1}, {Key: "created_at", Value: -1}})
+	cidx("audit_log", bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}})
+	idx("audit_log", bson.M{"created_at": -1})
+	idx("audit_log", bson.M{"changes.field": 1})
+
+	// journal_voucher
+	idx("journal_voucher", bson.M{"code": 1})
+	cidx("journal_voucher", bson.D{{Key: "status", Value: 1}, {Key: "date", Value: -1}})
+	idx("journal_voucher", bson.M{"lines.account_id": 1})
+
+	// fiscal_year
+	cidx("fiscal_year", bson.D{{Key: "start_date", Value: 1}, {Key: "end_date", Value: 1}})
+	idx("fiscal_year", bson.M{"periods.status": 1})
+
+	// bank_statement
+	cidx("bank_statement", bson.D{{Key: "account_id", Value: 1}, {Key: "to_date", Value: -1}})
+	idx("bank_statement", bson.M{"statement_no": 1})
+	idx("bank_statement", bson.M{"lines.matches.posting_id": 1})
+
+	// exchange_rate
+	cidx("exchange_rate", bson.D{{Key: "currency", Value: 1}, {Key: "date", Value: -1}})
+
+	// cost_center
+	idx("cost_center", bson.M{"name": 1})
+	idx("posting", bson.M{"cost_center_id": 1})
+
+	// recurring_template
+	idx("recurring_template", bson.M{"next_run_at": 1})
+	// one occurrence per template and date, so two runs can't both make the document
+	ucidx("recurring_occurrence", bson.D{{Key: "template_id", Value: 1}, {Key: "date", Value: 1}})
+
+	// budget
+	idx("budget", bson.M{"fiscal_year_id": 1})
+	cidx("budget", bson.D{{Key: "start_date", Value: 1}, {Key: "end_date", Value: 1}})
+	cidx("budget_alert", bson.D{{Key: "budget_id", Value: 1}, {Key: "reference_id", Value: 1}, {Key: "period_number", Value: 1}})
+
+	// fixed asset
+	idx("fixed_asset", bson.M{"code": 1})
+	idx("fixed_asset", bson.M{"status": 1})
+	cidx("fixed_asset", bson.D{{Key: "acquired_by_model", Value: 1}, {Key: "acquired_by_id", Value: 1}})
+
+	// debit note
+	idx("debitnote", bson.M{"code": 1})
+	idx("debitnote", bson.M{"order_id": 1})
+	idx("debitnote", bson.M{"customer_id": 1})
+	cidx("debitnote", bson.D{{Key: "zatca.reporting_passed", Value: 1}, {Key: "zatca.reporting_passed_at", Value: -1}})
+
 	if len(errs) > 0 {
 		return fmt.Errorf("store %s: %d index error(s): %s", store.ID.Hex(), len(errs), strings.Join(errs, " | "))
 	}
@@ -324,6 +425,72 @@ func (store *Store) RemoveAllIndexes() {
 	collection = db.GetDB("store_" + store.ID.Hex()).Collection("product_sales_return_history")
 	collection.Indexes().DropAll(context.Background())
 
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("product_lot_history")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("product_serial_history")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("price_list")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("promotion")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("loyalty_point_history")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("cash_register")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("register_shift")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("webhook")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("webhook_delivery")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("zatca_queue")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("audit_log")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("journal_voucher")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("fiscal_year")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("bank_statement")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("exchange_rate")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("cost_center")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("recurring_template")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("recurring_occurrence")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("budget")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("budget_alert")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("fixed_asset")
+	collection.Indexes().DropAll(context.Background())
+
+	collection = db.GetDB("store_" + store.ID.Hex()).Collection("debitnote")
+	collection.Indexes().DropAll(context.Background())
+
 }
 
 // CreateIndex - creates an index for a specific field in a collection
@@ -391,12 +558,12 @@ func (store *Store) CreateTextIndex(collectionName string, fields bson.D, indexN
 	return nil
 }
 
-func (store *Store) CreateCompoundIndex(collectionName string, fields bson.D) error {
+func (store *Store) CreateCompoundIndex(collectionName string, fields bson.D, unique bool) error {
 	collection := db.GetDB("store_" + store.ID.Hex()).Collection(collectionName)
 
 	mod := mongo.IndexModel{
 		Keys:    fields,
-		Options: options.Index(),
+		Options: options.Index().SetUnique(unique),
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
@@ -8,7 +8,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/sirinibin/startpos/backend/db"
+	"github.com/sirinibin/startpos/backend/zatca"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -244,3 +246,231 @@ func TestProduct_CreateUpdateDelete(t *testing.T) {
 		t.Error("FindProductByID after delete: expected error (document gone), got nil")
 	}
 }
+
+// ─── ZATCA sandbox ────────────────────────────────────────────────────────────
+
+// makeZatcaSandboxStore builds a store onboarded on the in-process ZATCA
+// sandbox, the way ConnectStoreToZatca onboards one.  MakeXMLContent reads its
+// templates from zatca/ of the backend directory, so the test runs from there.
+func makeZatcaSandboxStore(t *testing.T) *Store {
+	t.Helper()
+	t.Setenv("ZATCA_SANDBOX", "true")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	store := makeTestStore(t)
+	t.Cleanup(func() {
+		if err := store.PermanentlyDelete(); err != nil {
+			t.Logf("cleanup: store.PermanentlyDelete: %v", err)
+		}
+	})
+
+	store.RegistrationNumber = "1010010000"
+	store.VATNo = "399999999900003"
+	store.VatPercent = 15
+	store.BranchName = "Riyadh Branch"
+	store.CountryCode = "SA"
+	store.NationalAddress = NationalAddress{
+		BuildingNo:   "1234",
+		StreetName:   "King Fahd Road",
+		DistrictName: "Al Olaya",
+		CityName:     "Riyadh",
+		ZipCode:      "12345",
+	}
+
+	onboarding, err := zatca.NewClient(zatca.EnvSandbox).Onboard(zatca.OnboardingInput{
+		Env: zatca.EnvSandbox,
+		OTP: "123456",
+		CSR: zatca.CSRConfig{
+			CommonName:       store.RegistrationNumber,
+			SerialNumber:     "1-StartPOS|2-1.0|3-" + uuid.New().String(),
+			VATNumber:        store.VATNo,
+			BranchName:       store.BranchName,
+			OrganizationName: store.Name,
+			CountryCode:      store.CountryCode,
+			InvoiceType:      "1100",
+			Address:          "RRRD2929",
+			BusinessCategory: "Supply activities",
+		},
+		InvoiceCode: store.Code + "-1",
+	})
+	if err != nil {
+		t.Fatalf("Onboard: %v", err)
+	}
+
+	store.Zatca.Phase = "2"
+	store.Zatca.Env = zatca.EnvSandbox
+	store.Zatca.PrivateKey = onboarding.PrivateKey
+	store.Zatca.Csr = onboarding.CSR
+	store.Zatca.ComplianceCheck = NewComplianceCheck(onboarding.ComplianceCheck)
+	store.Zatca.ComplianceRequestID = onboarding.Compliance.RequestID
+	store.Zatca.BinarySecurityToken = onboarding.Compliance.BinarySecurityToken
+	store.Zatca.Secret = onboarding.Compliance.Secret
+	store.Zatca.ProductionRequestID = onboarding.Production.RequestID
+	store.Zatca.ProductionBinarySecurityToken = onboarding.Production.BinarySecurityToken
+	store.Zatca.ProductionSecret = onboarding.Production.Secret
+	store.Zatca.Connected = true
+	if err := store.Update(); err != nil {
+		t.Fatalf("store.Update: %v", err)
+	}
+	return store
+}
+
+// TestZatcaSandbox_ReportOrderAndSalesReturn reports a simplified invoice and
+// its credit note to the ZATCA sandbox and checks that the credit note chains
+// on its own previous invoice hash and the reporting is recorded.
+func TestZatcaSandbox_ReportOrderAndSalesReturn(t *testing.T) {
+	store := makeZatcaSandboxStore(t)
+
+	vatPct := 15.0
+	now := time.Now().Truncate(time.Second)
+	order := &Order{
+		StoreID:           &store.ID,
+		Code:              store.Code + "-1",
+		UUID:              uuid.New().String(),
+		InvoiceCountValue: 1,
+		Date:              &now,
+		VatPercent:        &vatPct,
+		Products: []OrderProduct{
+			{Name: "Widget A", Quantity: 2, UnitPrice: 50.00},
+		},
+	}
+	order.FindNetTotal()
+	if err := order.Insert(); err != nil {
+		t.Fatalf("Order.Insert: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := order.HardDelete(); err != nil {
+			t.Logf("cleanup: order.HardDelete: %v", err)
+		}
+	})
+
+	if err := order.ReportToZatca(); err != nil {
+		t.Fatalf("Order.ReportToZatca: %v", err)
+	}
+	found, err := store.FindOrderByID(&order.ID, bson.M{})
+	if err != nil {
+		t.Fatalf("FindOrderByID: %v", err)
+	}
+	if !found.Zatca.ReportingPassed || !found.Zatca.IsSimplified || found.Hash == "" {
+		t.Errorf("order zatca = %+v, want a reported simplified invoice", found.Zatca)
+	}
+
+	salesReturn := &SalesReturn{
+		StoreID:           &store.ID,
+		OrderID:           &order.ID,
+		OrderCode:         order.Code,
+		Code:              store.Code + "-R1",
+		UUID:              uuid.New().String(),
+		InvoiceCountValue: 1,
+		Date:              &now,
+		VatPercent:        &vatPct,
+		Products: []SalesReturnProduct{
+			{Name: "Widget A", Quantity: 1, UnitPrice: 50.00, Selected: true},
+		},
+	}
+	salesReturn.FindNetTotal()
+	if err := salesReturn.Insert(); err != nil {
+		t.Fatalf("SalesReturn.Insert: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := salesReturn.HardDelete(); err != nil {
+			t.Logf("cleanup: salesReturn.HardDelete: %v", err)
+		}
+	})
+
+	if err := salesReturn.ReportToZatca(); err != nil {
+		t.Fatalf("SalesReturn.ReportToZatca: %v", err)
+	}
+	foundReturn, err := store.FindSalesReturnByID(&salesReturn.ID, bson.M{})
+	if err != nil {
+		t.Fatalf("FindSalesReturnByID: %v", err)
+	}
+	if !foundReturn.Zatca.ReportingPassed || foundReturn.Hash == "" || foundReturn.Hash == found.Hash {
+		t.Errorf("sales return zatca = %+v, want a reported credit note", foundReturn.Zatca)
+	}
+}
+
+func TestZatcaSandbox_ReportDebitNote(t *testing.T) {
+	store := makeZatcaSandboxStore(t)
+
+	vatPct := 15.0
+	now := time.Now().Truncate(time.Second)
+	order := &Order{
+		StoreID:           &store.ID,
+		Code:              store.Code + "-1",
+		UUID:              uuid.New().String(),
+		InvoiceCountValue: 1,
+		Date:              &now,
+		VatPercent:        &vatPct,
+		Products: []OrderProduct{
+			{Name: "Widget A", Quantity: 2, UnitPrice: 50.00},
+		},
+	}
+	order.FindNetTotal()
+	if err := order.Insert(); err != nil {
+		t.Fatalf("Order.Insert: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := order.HardDelete(); err != nil {
+			t.Logf("cleanup: order.HardDelete: %v", err)
+		}
+	})
+
+	debitNote := &DebitNote{
+		StoreID:           &store.ID,
+		OrderID:           &order.ID,
+		OrderCode:         order.Code,
+		Code:              store.Code + "-D1",
+		UUID:              uuid.New().String(),
+		InvoiceCountValue: 1,
+		Date:              &now,
+		Reason:            "Price increase after invoicing",
+		PaymentMethod:     "customer_account",
+		VatPercent:        vatPct,
+		Products: []DebitNoteProduct{
+			{Name: "Widget A price difference", Quantity: 2, UnitPrice: 5.00},
+		},
+	}
+	debitNote.FindNetTotal()
+	if err := debitNote.Insert(); err != nil {
+		t.Fatalf("DebitNote.Insert: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		collection := db.GetDB("store_" + store.ID.Hex()).Collection("debitnote")
+		if _, err := collection.DeleteOne(ctx, bson.M{"_id": debitNote.ID}); err != nil {
+			t.Logf("cleanup: debit note: %v", err)
+		}
+	})
+
+	// the invoice the note refers to has to be reported first
+	if err := debitNote.ReportToZatca(); err == nil {
+		t.Fatal("DebitNote.ReportToZatca before its invoice: want an error")
+	}
+
+	if err := order.ReportToZatca(); err != nil {
+		t.Fatalf("Order.ReportToZatca: %v", err)
+	}
+	if err := debitNote.ReportToZatca(); err != nil {
+		t.Fatalf("DebitNote.ReportToZatca: %v", err)
+	}
+	if err := debitNote.Update(); err != nil {
+		t.Fatalf("DebitNote.Update: %v", err)
+	}
+
+	found, err := store.FindDebitNoteByID(&debitNote.ID, bson.M{})
+	if err != nil {
+		t.Fatalf("FindDebitNoteByID: %v", err)
+	}
+	if !found.Zatca.ReportingPassed || !found.Zatca.IsSimplified || found.Hash == "" {
+		t.Errorf("debit note zatca = %+v, want a reported simplified debit note", found.Zatca)
+	}
+}
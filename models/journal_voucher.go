@@ -0,0 +1,681 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	JournalVoucherStatusDraft    = "draft"
+	JournalVoucherStatusApproved = "approved"
+	JournalVoucherStatusReversed = "reversed"
+)
+
+// JournalVoucher : a manual journal entry booked by the accountant (accruals, depreciation, corrections).
+// Drafts can be edited freely; approving posts the lines to the ledger, after which the voucher
+// can only be undone by a reversing voucher.
+type JournalVoucher struct {
+	ID                 primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	Code               string               `bson:"code,omitempty" json:"code,omitempty"`
+	Date               *time.Time           `bson:"date,omitempty" json:"date,omitempty"`
+	DateStr            string               `json:"date_str,omitempty" bson:"-"`
+	Narration          string               `bson:"narration" json:"narration"`
+	Reference          string               `bson:"reference,omitempty" json:"reference,omitempty"`
+	Lines              []JournalVoucherLine `bson:"lines" json:"lines"`
+	TotalDebit         float64              `bson:"total_debit" json:"total_debit"`
+	TotalCredit        float64              `bson:"total_credit" json:"total_credit"`
+	Status             string               `bson:"status" json:"status"`
+	Attachments        []string             `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	AttachmentsContent []string             `json:"attachments_content,omitempty" bson:"-"`
+	ApprovedBy         *primitive.ObjectID  `json:"approved_by,omitempty" bson:"approved_by,omitempty"`
+	ApprovedByName     string               `json:"approved_by_name,omitempty" bson:"approved_by_name,omitempty"`
+	ApprovedAt         *time.Time           `bson:"approved_at,omitempty" json:"approved_at,omitempty"`
+	ReversalOfID       *primitive.ObjectID  `json:"reversal_of_id,omitempty" bson:"reversal_of_id,omitempty"`
+	ReversalOfCode     string               `json:"reversal_of_code,omitempty" bson:"reversal_of_code,omitempty"`
+	ReversedByID       *primitive.ObjectID  `json:"reversed_by_id,omitempty" bson:"reversed_by_id,omitempty"`
+	ReversedByCode     string               `json:"reversed_by_code,omitempty" bson:"reversed_by_code,omitempty"`
+	StoreID            *primitive.ObjectID  `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName          string               `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted            bool                 `bson:"deleted" json:"deleted"`
+	DeletedBy          *primitive.ObjectID  `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt          *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt          *time.Time           `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt          *time.Time           `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy          *primitive.ObjectID  `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy          *primitive.ObjectID  `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName      string               `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName      string               `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+}
+
+// JournalVoucherLine : one debit or credit against an account
+type JournalVoucherLine struct {
+	AccountID      primitive.ObjectID  `json:"account_id" bson:"account_id"`
+	AccountName    string              `json:"account_name" bson:"account_name"`
+	AccountNumber  string              `json:"account_number" bson:"account_number"`
+	Description    string              `json:"description,omitempty" bson:"description,omitempty"`
+	Debit          float64             `json:"debit" bson:"debit"`
+	Credit         float64             `json:"credit" bson:"credit"`
+	CostCenterID   *primitive.ObjectID `json:"cost_center_id,omitempty" bson:"cost_center_id,omitempty"`
+	CostCenterName string              `json:"cost_center_name,omitempty" bson:"cost_center_name,omitempty"`
+}
+
+func (voucher *JournalVoucher) FindTotals() {
+	voucher.TotalDebit = 0
+	voucher.TotalCredit = 0
+	for i, line := range voucher.Lines {
+		voucher.Lines[i].Debit = RoundTo2Decimals(line.Debit)
+		voucher.Lines[i].Credit = RoundTo2Decimals(line.Credit)
+		voucher.TotalDebit += voucher.Lines[i].Debit
+		voucher.TotalCredit += voucher.Lines[i].Credit
+	}
+	voucher.TotalDebit = RoundTo2Decimals(voucher.TotalDebit)
+	voucher.TotalCredit = RoundTo2Decimals(voucher.TotalCredit)
+}
+
+// BuildJournalVoucherJournals turns the voucher lines into ledger journals.
+// CreatePostings pairs every debit with every credit of the same group, which is only correct when
+// one side of the group has a single line. So a voucher with one debit (or one credit) line stays one
+// group, and a many-to-many voucher is split into debit/credit pairs of matching amounts.
+func BuildJournalVoucherJournals(voucher *JournalVoucher, now *time.Time) []Journal {
+	journal := func(line JournalVoucherLine, debit, credit float64, groupID primitive.ObjectID) Journal {
+		j := Journal{
+			Date:           voucher.Date,
+			AccountID:      line.AccountID,
+			AccountName:    line.AccountName,
+			AccountNumber:  line.AccountNumber,
+			Debit:          debit,
+			Credit:         credit,
+			GroupID:        groupID,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			CostCenterID:   line.CostCenterID,
+			CostCenterName: line.CostCenterName,
+		}
+		if debit > 0 {
+			j.DebitOrCredit = "debit"
+		} else {
+			j.DebitOrCredit = "credit"
+		}
+		return j
+	}
+
+	debits := []JournalVoucherLine{}
+	credits := []JournalVoucherLine{}
+	for _, line := range voucher.Lines {
+		if line.Debit > 0 {
+			debits = append(debits, line)
+		} else if line.Credit > 0 {
+			credits = append(credits, line)
+		}
+	}
+
+	journals := []Journal{}
+
+	if len(debits) == 1 || len(credits) == 1 {
+		groupID := primitive.NewObjectID()
+		for _, line := range debits {
+			journals = append(journals, journal(line, line.Debit, 0, groupID))
+		}
+		for _, line := range credits {
+			journals = append(journals, journal(line, 0, line.Credit, groupID))
+		}
+		return journals
+	}
+
+	d, c := 0, 0
+	debitLeft, creditLeft := 0.0, 0.0
+	if len(debits) > 0 && len(credits) > 0 {
+		debitLeft, creditLeft = debits[0].Debit, credits[0].Credit
+	}
+	for d < len(debits) && c < len(credits) {
+		amount := RoundTo2Decimals(math.Min(debitLeft, creditLeft))
+		groupID := primitive.NewObjectID()
+		journals = append(journals, journal(debits[d], amount, 0, groupID))
+		journals = append(journals, journal(credits[c], 0, amount, groupID))
+
+		debitLeft = RoundTo2Decimals(debitLeft - amount)
+		creditLeft = RoundTo2Decimals(creditLeft - amount)
+		if debitLeft <= 0 {
+			d++
+			if d < len(debits) {
+				debitLeft = debits[d].Debit
+			}
+		}
+		if creditLeft <= 0 {
+			c++
+			if c < len(credits) {
+				creditLeft = credits[c].Credit
+			}
+		}
+	}
+
+	return journals
+}
+
+// ReversedLines swaps the debit and credit of every line.
+func (voucher *JournalVoucher) ReversedLines() []JournalVoucherLine {
+	lines := make([]JournalVoucherLine, len(voucher.Lines))
+	for i, line := range voucher.Lines {
+		lines[i] = line
+		lines[i].Debit, lines[i].Credit = line.Credit, line.Debit
+	}
+	return lines
+}
+
+func (voucher *JournalVoucher) Validate(w http.ResponseWriter, r *http.Request, scenario string) (errs map[string]string) {
+	errs = make(map[string]string)
+
+	store, err := FindStoreByID(voucher.StoreID, bson.M{})
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errs["store_id"] = "invalid store id"
+		return errs
+	}
+
+	if scenario == "update" {
+		if voucher.ID.IsZero() {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = "ID is required"
+			return errs
+		}
+		oldVoucher, err := store.FindJournalVoucherByID(&voucher.ID, bson.M{"status": 1})
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = "Invalid Journal Voucher:" + voucher.ID.Hex()
+			return errs
+		}
+		if oldVoucher.Status != JournalVoucherStatusDraft {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["status"] = "Only draft vouchers can be edited, reverse an approved voucher instead"
+			return errs
+		}
+	}
+
+	if govalidator.IsNull(voucher.DateStr) {
+		errs["date_str"] = "Date is required"
+	} else {
+		const shortForm = "2006-01-02T15:04:05Z07:00"
+		date, err := time.Parse(shortForm, voucher.DateStr)
+		if err != nil {
+			errs["date_str"] = "Invalid date format"
+		}
+		voucher.Date = &date
+	}
+
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, voucher.StoreID, "journal_voucher", voucher.ID, voucher.Code, voucher.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
+	voucher.Narration = strings.TrimSpace(voucher.Narration)
+	if govalidator.IsNull(voucher.Narration) {
+		errs["narration"] = "Narration is required"
+	}
+
+	if len(voucher.Lines) < 2 {
+		errs["lines"] = "Atleast 2 lines are required"
+	}
+
+	for i, line := range voucher.Lines {
+		index := strconv.Itoa(i)
+		if line.AccountID.IsZero() {
+			errs["account_id_"+index] = "Account is required"
+		} else {
+			account, err := store.FindAccountByID(line.AccountID, bson.M{})
+			if err != nil || account.Deleted {
+				errs["account_id_"+index] = "Invalid account:" + line.AccountID.Hex()
+			} else {
+				voucher.Lines[i].AccountName = account.Name
+				voucher.Lines[i].AccountNumber = account.Number
+			}
+		}
+
+		if message := store.ValidateCostCenter(&voucher.Lines[i].CostCenterID, &voucher.Lines[i].CostCenterName); message != "" {
+			errs["cost_center_id_"+index] = message
+		}
+
+		if line.Debit < 0 || line.Credit < 0 {
+			errs["amount_"+index] = "Amount should not be negative"
+		} else if line.Debit > 0 && line.Credit > 0 {
+			errs["amount_"+index] = "A line can have either a debit or a credit, not both"
+		} else if line.Debit == 0 && line.Credit == 0 {
+			errs["amount_"+index] = "Debit or credit is required"
+		}
+	}
+
+	voucher.FindTotals()
+	if len(voucher.Lines) >= 2 && voucher.TotalDebit != voucher.TotalCredit {
+		errs["lines"] = fmt.Sprintf("Entry is not balanced: debit %.2f, credit %.2f", voucher.TotalDebit, voucher.TotalCredit)
+	}
+
+	for k, content := range voucher.AttachmentsContent {
+		splits := strings.Split(content, ",")
+		voucher.AttachmentsContent[k] = splits[len(splits)-1]
+
+		valid, err := IsStringBase64(voucher.AttachmentsContent[k])
+		if err != nil {
+			errs["attachments_content"] = err.Error()
+		}
+
+		if !valid {
+			errs["attachments_"+strconv.Itoa(k)] = "Invalid base64 string"
+		}
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+func (voucher *JournalVoucher) UpdateForeignLabelFields() error {
+	if voucher.StoreID != nil {
+		store, err := FindStoreByID(voucher.StoreID, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		voucher.StoreName = store.Name
+	}
+
+	if voucher.CreatedBy != nil {
+		createdByUser, err := FindUserByID(voucher.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		voucher.CreatedByName = createdByUser.Name
+	}
+
+	if voucher.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(voucher.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		voucher.UpdatedByName = updatedByUser.Name
+	}
+
+	if voucher.ApprovedBy != nil {
+		approvedByUser, err := FindUserByID(voucher.ApprovedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		voucher.ApprovedByName = approvedByUser.Name
+	}
+
+	return nil
+}
+
+func (voucher *JournalVoucher) MakeCode() error {
+	store, err := FindStoreByID(voucher.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	count, err := store.GetCountByCollectionByDeletedIncluded("journal_voucher")
+	if err != nil {
+		return err
+	}
+
+	for {
+		count++
+		voucher.Code = fmt.Sprintf("JV-%06d", count)
+		exists, err := voucher.IsCodeExists()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return nil
+		}
+	}
+}
+
+func (voucher *JournalVoucher) IsCodeExists() (exists bool, err error) {
+	collection := db.GetDB("store_" + voucher.StoreID.Hex()).Collection("journal_voucher")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := collection.CountDocuments(ctx, bson.M{
+		"code": voucher.Code,
+		"_id":  bson.M{"$ne": voucher.ID},
+	})
+	return count > 0, err
+}
+
+func (voucher *JournalVoucher) SaveAttachments() error {
+	for _, content := range voucher.AttachmentsContent {
+		data, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return err
+		}
+
+		extension, err := GetFileExtensionFromBase64(data)
+		if err != nil {
+			return err
+		}
+
+		baseFilename := GenerateFileName("journal_voucher_", extension)
+		diskPath := "images/" + voucher.StoreID.Hex() + "/journal_vouchers/" + baseFilename
+		err = SaveBase64File(diskPath, data)
+		if err != nil {
+			return err
+		}
+		voucher.Attachments = append(voucher.Attachments, baseFilename)
+	}
+
+	voucher.AttachmentsContent = []string{}
+
+	return nil
+}
+
+func (voucher *JournalVoucher) Insert() error {
+	collection := db.GetDB("store_" + voucher.StoreID.Hex()).Collection("journal_voucher")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	voucher.ID = primitive.NewObjectID()
+
+	err := voucher.SaveAttachments()
+	if err != nil {
+		return err
+	}
+
+	err = voucher.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, &voucher)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (voucher *JournalVoucher) Update() error {
+	collection := db.GetDB("store_" + voucher.StoreID.Hex()).Collection("journal_voucher")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := voucher.SaveAttachments()
+	if err != nil {
+		return err
+	}
+
+	err = voucher.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": voucher.ID},
+		bson.M{"$set": voucher},
+		updateOptions,
+	)
+	return err
+}
+
+func (voucher *JournalVoucher) DeleteJournalVoucher(tokenClaims TokenClaims) (err error) {
+	if voucher.Status != JournalVoucherStatusDraft {
+		return errors.New("only draft vouchers can be deleted, reverse an approved voucher instead")
+	}
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	voucher.Deleted = true
+	voucher.DeletedBy = &userID
+	now := time.Now()
+	voucher.DeletedAt = &now
+
+	return voucher.Update()
+}
+
+// Approve posts a draft voucher to the ledger.
+func (voucher *JournalVoucher) Approve(userID primitive.ObjectID) error {
+	if voucher.Status != JournalVoucherStatusDraft {
+		return errors.New("voucher is already " + voucher.Status)
+	}
+
+	now := time.Now()
+	voucher.Status = JournalVoucherStatusApproved
+	voucher.ApprovedBy = &userID
+	voucher.ApprovedAt = &now
+	voucher.UpdatedBy = &userID
+	voucher.UpdatedAt = &now
+
+	err := voucher.Update()
+	if err != nil {
+		return err
+	}
+
+	return voucher.DoAccounting()
+}
+
+// Reverse books an approved voucher's lines with debit and credit swapped on date and
+// marks the original as reversed. The reversing voucher is approved straight away.
+func (voucher *JournalVoucher) Reverse(userID primitive.ObjectID, date *time.Time) (reversal *JournalVoucher, err error) {
+	if voucher.Status != JournalVoucherStatusApproved {
+		return nil, errors.New("only approved vouchers can be reversed")
+	}
+	if voucher.ReversalOfID != nil {
+		return nil, errors.New("a reversing voucher cannot be reversed")
+	}
+
+	now := time.Now()
+	if date == nil {
+		date = &now
+	}
+
+	reversal = &JournalVoucher{
+		Date:           date,
+		Narration:      "Reversal of " + voucher.Code + ": " + voucher.Narration,
+		Reference:      voucher.Reference,
+		Lines:          voucher.ReversedLines(),
+		Status:         JournalVoucherStatusApproved,
+		ApprovedBy:     &userID,
+		ApprovedAt:     &now,
+		ReversalOfID:   &voucher.ID,
+		ReversalOfCode: voucher.Code,
+		StoreID:        voucher.StoreID,
+		CreatedBy:      &userID,
+		UpdatedBy:      &userID,
+		CreatedAt:      &now,
+		UpdatedAt:      &now,
+	}
+	reversal.FindTotals()
+
+	err = reversal.MakeCode()
+	if err != nil {
+		return nil, err
+	}
+
+	err = reversal.Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	err = reversal.DoAccounting()
+	if err != nil {
+		return nil, err
+	}
+
+	voucher.Status = JournalVoucherStatusReversed
+	voucher.ReversedByID = &reversal.ID
+	voucher.ReversedByCode = reversal.Code
+	voucher.UpdatedBy = &userID
+	voucher.UpdatedAt = &now
+
+	err = voucher.Update()
+	if err != nil {
+		return nil, err
+	}
+
+	return reversal, nil
+}
+
+func (voucher *JournalVoucher) CreateLedger() (ledger *Ledger, err error) {
+	now := time.Now()
+
+	ledger = &Ledger{
+		StoreID:        voucher.StoreID,
+		ReferenceID:    voucher.ID,
+		ReferenceModel: "journal_voucher",
+		ReferenceCode:  voucher.Code,
+		Journals:       BuildJournalVoucherJournals(voucher, &now),
+		CreatedAt:      &now,
+		UpdatedAt:      &now,
+	}
+
+	err = ledger.Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return ledger, nil
+}
+
+func (voucher *JournalVoucher) DoAccounting() error {
+	ledger, err := voucher.CreateLedger()
+	if err != nil {
+		return err
+	}
+
+	_, err = ledger.CreatePostings()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (voucher *JournalVoucher) SetPostBalances() error {
+	store, err := FindStoreByID(voucher.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	ledger, err := store.FindLedgerByReferenceID(voucher.ID, *voucher.StoreID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return errors.New("Error finding ledger by reference id: " + err.Error())
+	}
+
+	return ledger.SetPostBalancesByLedger(voucher.Date)
+}
+
+func (store *Store) FindJournalVoucherByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (voucher *JournalVoucher, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("journal_voucher")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{
+			"_id":      ID,
+			"store_id": store.ID,
+		}, findOneOptions).
+		Decode(&voucher)
+	if err != nil {
+		return nil, err
+	}
+
+	return voucher, err
+}
+
+func (store *Store) SearchJournalVoucher(w http.ResponseWriter, r *http.Request) (vouchers []JournalVoucher, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+	timeZoneOffset := CountryTimezoneOffset(store.CountryCode)
+
+	ParseDeletedFilter(r, &criterias)
+	ParseTextSearch(r, &criterias, "search[code]", "code")
+	ParseTextSearch(r, &criterias, "search[narration]", "narration")
+	ParseTextSearch(r, &criterias, "search[reference]", "reference")
+
+	keys, ok := r.URL.Query()["search[status]"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.SearchBy["status"] = keys[0]
+	}
+
+	if err = ParseObjectIDFilter(r, &criterias, "search[account_id]", "lines.account_id"); err != nil {
+		return vouchers, criterias, err
+	}
+
+	if err = ParseObjectIDListFilter(r, &criterias, "search[created_by]", "created_by"); err != nil {
+		return vouchers, criterias, err
+	}
+
+	if err = ParseDateRangeFilter(r, &criterias, "search[date_from]", "search[date_to]", "date", timeZoneOffset); err != nil {
+		return vouchers, criterias, err
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("journal_voucher")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	keys, ok = r.URL.Query()["select"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.Select = ParseSelectString(keys[0])
+	}
+
+	if criterias.Select != nil {
+		findOptions.SetProjection(criterias.Select)
+	}
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return vouchers, criterias, errors.New("Error fetching journal vouchers:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return vouchers, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		voucher := JournalVoucher{}
+		err = cur.Decode(&voucher)
+		if err != nil {
+			return vouchers, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		vouchers = append(vouchers, voucher)
+	}
+
+	return vouchers, criterias, nil
+}
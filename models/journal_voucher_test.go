@@ -0,0 +1,94 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func journalVoucherTestLines(amounts ...float64) []JournalVoucherLine {
+	lines := []JournalVoucherLine{}
+	for _, amount := range amounts {
+		line := JournalVoucherLine{AccountID: primitive.NewObjectID()}
+		if amount > 0 {
+			line.Debit = amount
+		} else {
+			line.Credit = -amount
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// journalAccountTotals sums the journals per account as debit minus credit.
+func journalAccountTotals(journals []Journal) map[primitive.ObjectID]float64 {
+	totals := map[primitive.ObjectID]float64{}
+	for _, journal := range journals {
+		totals[journal.AccountID] = RoundTo2Decimals(totals[journal.AccountID] + journal.Debit - journal.Credit)
+	}
+	return totals
+}
+
+// ── BuildJournalVoucherJournals ──────────────────────────────────────────────
+
+func TestBuildJournalVoucherJournals_OneToManyIsOneGroup(t *testing.T) {
+	now := time.Now()
+	voucher := &JournalVoucher{Date: &now, Lines: journalVoucherTestLines(100, -60, -40)}
+
+	journals := BuildJournalVoucherJournals(voucher, &now)
+	if len(journals) != 3 {
+		t.Fatalf("journals = %d, want 3", len(journals))
+	}
+	for _, journal := range journals {
+		if journal.GroupID != journals[0].GroupID {
+			t.Fatal("expected a single group")
+		}
+	}
+	if journals[0].DebitOrCredit != "debit" || journals[1].DebitOrCredit != "credit" {
+		t.Errorf("debit_or_credit = %s, %s", journals[0].DebitOrCredit, journals[1].DebitOrCredit)
+	}
+}
+
+func TestBuildJournalVoucherJournals_ManyToManyIsSplitIntoPairs(t *testing.T) {
+	now := time.Now()
+	voucher := &JournalVoucher{Date: &now, Lines: journalVoucherTestLines(50, 50, -70, -30)}
+
+	journals := BuildJournalVoucherJournals(voucher, &now)
+
+	groups := map[primitive.ObjectID][]Journal{}
+	for _, journal := range journals {
+		groups[journal.GroupID] = append(groups[journal.GroupID], journal)
+	}
+	for _, group := range groups {
+		if len(group) != 2 || group[0].Debit != group[1].Credit {
+			t.Fatalf("expected a balanced debit/credit pair, got %+v", group)
+		}
+	}
+	if len(groups) != 3 {
+		t.Errorf("groups = %d, want 3 (50/50, 20/20, 30/30)", len(groups))
+	}
+
+	totals := journalAccountTotals(journals)
+	for _, line := range voucher.Lines {
+		if want := line.Debit - line.Credit; totals[line.AccountID] != want {
+			t.Errorf("account total = %v, want %v", totals[line.AccountID], want)
+		}
+	}
+}
+
+func TestJournalVoucher_FindTotalsAndReversedLines(t *testing.T) {
+	voucher := &JournalVoucher{Lines: journalVoucherTestLines(10.005, -10.005)}
+	voucher.FindTotals()
+	if voucher.TotalDebit != voucher.TotalCredit {
+		t.Errorf("totals = %v / %v", voucher.TotalDebit, voucher.TotalCredit)
+	}
+
+	reversed := voucher.ReversedLines()
+	if reversed[0].Credit != voucher.Lines[0].Debit || reversed[0].Debit != 0 || reversed[1].Debit != voucher.Lines[1].Credit {
+		t.Errorf("reversed = %+v", reversed)
+	}
+	if voucher.Lines[0].Debit == 0 {
+		t.Error("reversing must not modify the original lines")
+	}
+}
@@ -45,6 +45,8 @@ type Journal struct {
 	ReferenceID    *primitive.ObjectID `json:"reference_id" bson:"reference_id"`
 	ReferenceModel *string             `bson:"reference_model" json:"reference_model"`
 	ReferenceCode  *string             `bson:"reference_code" json:"reference_code"`
+	CostCenterID   *primitive.ObjectID `json:"cost_center_id,omitempty" bson:"cost_center_id,omitempty"`
+	CostCenterName string              `json:"cost_center_name,omitempty" bson:"cost_center_name,omitempty"`
 }
 
 func (store *Store) SearchLedger(w http.ResponseWriter, r *http.Request) (models []Ledger, criterias SearchCriterias, err error) {
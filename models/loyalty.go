@@ -0,0 +1,497 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LoyaltyPaymentMethod is the sales payment method used to pay with loyalty points.
+const LoyaltyPaymentMethod = "loyalty_points"
+
+const (
+	LoyaltyPointTypeEarn    = "earn"
+	LoyaltyPointTypeRedeem  = "redeem"
+	LoyaltyPointTypeReverse = "reverse"
+)
+
+type LoyaltySettings struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Points earned for every 1.00 of sales net total.
+	PointsPerCurrencyUnit float64 `bson:"points_per_currency_unit" json:"points_per_currency_unit"`
+	// Money value of 1 point when redeemed.
+	PointValue      float64 `bson:"point_value" json:"point_value"`
+	MinRedeemPoints float64 `bson:"min_redeem_points" json:"min_redeem_points"`
+}
+
+// LoyaltyPointHistory is one entry of a customer's loyalty ledger.
+// Points are signed: earned points are positive, redeemed and reversed points are negative.
+type LoyaltyPointHistory struct {
+	ID            primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	StoreID       *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	CustomerID    *primitive.ObjectID `json:"customer_id,omitempty" bson:"customer_id,omitempty"`
+	CustomerName  string              `json:"customer_name" bson:"customer_name"`
+	Date          *time.Time          `bson:"date,omitempty" json:"date,omitempty"`
+	Type          string              `json:"type" bson:"type"`
+	Points        float64             `json:"points" bson:"points"`
+	Value         float64             `json:"value" bson:"value"`
+	BaseAmount    float64             `json:"base_amount" bson:"base_amount"`
+	OrderID       *primitive.ObjectID `json:"order_id,omitempty" bson:"order_id,omitempty"`
+	OrderCode     string              `json:"order_code,omitempty" bson:"order_code,omitempty"`
+	ReferenceType string              `json:"reference_type" bson:"reference_type"`
+	ReferenceID   *primitive.ObjectID `json:"reference_id,omitempty" bson:"reference_id,omitempty"`
+	ReferenceCode string              `json:"reference_code" bson:"reference_code"`
+	CreatedAt     *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+}
+
+// PointsEarned returns the whole points earned on amount.
+func (settings LoyaltySettings) PointsEarned(amount float64) float64 {
+	if !settings.Enabled || settings.PointsPerCurrencyUnit <= 0 || amount <= 0 {
+		return 0
+	}
+	return math.Floor(RoundTo4Decimals(amount * settings.PointsPerCurrencyUnit))
+}
+
+// PointsForAmount returns the points needed to pay amount.
+func (settings LoyaltySettings) PointsForAmount(amount float64) float64 {
+	if settings.PointValue <= 0 {
+		return 0
+	}
+	return RoundTo2Decimals(amount / settings.PointValue)
+}
+
+// ValueOfPoints returns the money value of points.
+func (settings LoyaltySettings) ValueOfPoints(points float64) float64 {
+	return RoundTo2Decimals(points * settings.PointValue)
+}
+
+// ValidateRedemption checks that amount can be paid with the available points.
+func (settings LoyaltySettings) ValidateRedemption(amount float64, availablePoints float64) error {
+	if !settings.Enabled || settings.PointValue <= 0 {
+		return errors.New("loyalty points are not enabled for this store")
+	}
+
+	points := settings.PointsForAmount(amount)
+	if settings.MinRedeemPoints > 0 && points < settings.MinRedeemPoints {
+		return errors.New("minimum " + fmt.Sprintf("%.02f", settings.MinRedeemPoints) + " points should be redeemed at a time")
+	}
+
+	if points > RoundTo2Decimals(availablePoints) {
+		return errors.New("customer has only " + fmt.Sprintf("%.02f", availablePoints) + " loyalty points (worth " + fmt.Sprintf("%.02f", settings.ValueOfPoints(availablePoints)) + ")")
+	}
+
+	return nil
+}
+
+// LoyaltyPointsToReverse returns the share of earned points to take back for a return of
+// returnedAmount out of the earnedOn amount, never more than what is still left.
+func LoyaltyPointsToReverse(earned float64, earnedOn float64, returnedAmount float64, alreadyReversed float64) float64 {
+	if earned <= 0 || earnedOn <= 0 || returnedAmount <= 0 {
+		return 0
+	}
+
+	ratio := returnedAmount / earnedOn
+	if ratio > 1 {
+		ratio = 1
+	}
+
+	points := RoundTo2Decimals(earned * ratio)
+	if left := RoundTo2Decimals(earned - alreadyReversed); points > left {
+		points = left
+	}
+	if points < 0 {
+		return 0
+	}
+	return points
+}
+
+func (store *Store) ClearLoyaltyPointHistory(filter bson.M) (customerIDs []primitive.ObjectID, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("loyalty_point_history")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	values, err := collection.Distinct(ctx, "customer_id", filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			customerIDs = append(customerIDs, id)
+		}
+	}
+
+	_, err = collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return customerIDs, nil
+}
+
+func (store *Store) InsertLoyaltyPointHistory(history *LoyaltyPointHistory) error {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("loyalty_point_history")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	history.ID = primitive.NewObjectID()
+	history.StoreID = &store.ID
+	history.CreatedAt = &now
+
+	_, err := collection.InsertOne(ctx, history)
+	return err
+}
+
+// GetLoyaltyPointsBalance sums the points of a customer, leaving out the entries matching exclude.
+func (store *Store) GetLoyaltyPointsBalance(customerID primitive.ObjectID, exclude bson.M) (float64, error) {
+	match := bson.M{"customer_id": customerID}
+	if len(exclude) > 0 {
+		match["$nor"] = []bson.M{exclude}
+	}
+
+	return store.sumLoyaltyPoints(match)
+}
+
+func (store *Store) sumLoyaltyPoints(match bson.M) (float64, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("loyalty_point_history")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cur, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "points": bson.M{"$sum": "$points"}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var row struct {
+		Points float64 `bson:"points"`
+	}
+	if cur.Next(ctx) {
+		if err := cur.Decode(&row); err != nil {
+			return 0, err
+		}
+	}
+
+	return RoundTo2Decimals(row.Points), nil
+}
+
+func (store *Store) SetCustomerLoyaltyPoints(customerIDs ...primitive.ObjectID) error {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("customer")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, customerID := range customerIDs {
+		points, err := store.GetLoyaltyPointsBalance(customerID, nil)
+		if err != nil {
+			return err
+		}
+
+		_, err = collection.UpdateOne(ctx,
+			bson.M{"_id": customerID},
+			bson.M{"$set": bson.M{"loyalty_points": points}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *Store) GetLoyaltyPointHistory(customerID primitive.ObjectID) ([]LoyaltyPointHistory, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("loyalty_point_history")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}}).SetLimit(500)
+	cur, err := collection.Find(ctx, bson.M{"customer_id": customerID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	results := []LoyaltyPointHistory{}
+	if err = cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ValidateLoyaltyRedemption checks a loyalty points payment of amount against the customer's
+// balance. Entries matching exclude are given back first, e.g. the ones of the payment being edited.
+func (store *Store) ValidateLoyaltyRedemption(customer *Customer, amount float64, exclude bson.M) error {
+	if customer == nil {
+		return errors.New("customer is required to pay with loyalty points")
+	}
+
+	available, err := store.GetLoyaltyPointsBalance(customer.ID, exclude)
+	if err != nil {
+		return errors.New("error finding loyalty points balance: " + err.Error())
+	}
+
+	return store.Settings.Loyalty.ValidateRedemption(amount, available)
+}
+
+// SetLoyaltyPoints re-books the points redeemed with the payments of the order and
+// the points earned on what was not paid with points.
+func (order *Order) SetLoyaltyPoints() error {
+	store, err := FindStoreByID(order.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	customerIDs, err := store.ClearLoyaltyPointHistory(bson.M{
+		"order_id":       order.ID,
+		"reference_type": bson.M{"$in": []string{"sales", "sales_payment"}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var customer *Customer
+	if order.CustomerID != nil && !order.CustomerID.IsZero() {
+		customer, err = store.FindCustomerByID(order.CustomerID, bson.M{})
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+	}
+
+	var payments []SalesPayment
+	if customer != nil {
+		customerIDs = append(customerIDs, customer.ID)
+
+		payments, err = order.GetPayments()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, history := range order.loyaltyPointEntries(customer, payments, store.Settings.Loyalty) {
+		err = store.InsertLoyaltyPointHistory(&history)
+		if err != nil {
+			return err
+		}
+	}
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("order")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = collection.UpdateOne(ctx,
+		bson.M{"_id": order.ID},
+		bson.M{"$set": bson.M{
+			"loyalty_points_earned": order.LoyaltyPointsEarned,
+			"loyalty_points_value":  order.LoyaltyPointsValue,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+
+	return store.SetCustomerLoyaltyPoints(customerIDs...)
+}
+
+// loyaltyPointEntries sets the points earned on the order and returns the loyalty
+// ledger entries of the order and its loyalty payments.
+func (order *Order) loyaltyPointEntries(customer *Customer, payments []SalesPayment, settings LoyaltySettings) (entries []LoyaltyPointHistory) {
+	order.LoyaltyPointsEarned = 0
+	order.LoyaltyPointsValue = 0
+
+	if customer == nil {
+		return nil
+	}
+
+	redeemedAmount := 0.00
+	for i := range payments {
+		payment := payments[i]
+		if payment.Method != LoyaltyPaymentMethod {
+			continue
+		}
+		redeemedAmount += payment.Amount
+
+		entries = append(entries, LoyaltyPointHistory{
+			CustomerID:    &customer.ID,
+			CustomerName:  customer.Name,
+			Date:          payment.Date,
+			Type:          LoyaltyPointTypeRedeem,
+			Points:        settings.PointsForAmount(payment.Amount) * -1,
+			Value:         RoundTo2Decimals(payment.Amount * -1),
+			OrderID:       &order.ID,
+			OrderCode:     order.Code,
+			ReferenceType: "sales_payment",
+			ReferenceID:   &payment.ID,
+			ReferenceCode: order.Code,
+		})
+	}
+
+	earnedOn := RoundTo2Decimals(order.NetTotal - order.CashDiscount - redeemedAmount)
+	points := settings.PointsEarned(earnedOn)
+	if points > 0 {
+		order.LoyaltyPointsEarned = points
+		order.LoyaltyPointsValue = settings.ValueOfPoints(points)
+
+		entries = append(entries, LoyaltyPointHistory{
+			CustomerID:    &customer.ID,
+			CustomerName:  customer.Name,
+			Date:          order.Date,
+			Type:          LoyaltyPointTypeEarn,
+			Points:        points,
+			Value:         order.LoyaltyPointsValue,
+			BaseAmount:    earnedOn,
+			OrderID:       &order.ID,
+			OrderCode:     order.Code,
+			ReferenceType: "sales",
+			ReferenceID:   &order.ID,
+			ReferenceCode: order.Code,
+		})
+	}
+
+	return entries
+}
+
+// SetLoyaltyPoints takes back the share of the points earned on the order that belongs to the returned amount.
+func (salesReturn *SalesReturn) SetLoyaltyPoints() error {
+	store, err := FindStoreByID(salesReturn.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	customerIDs, err := store.ClearLoyaltyPointHistory(bson.M{
+		"reference_type": "sales_return",
+		"reference_id":   salesReturn.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	salesReturn.LoyaltyPointsReversed = 0
+	salesReturn.LoyaltyReversedValue = 0
+
+	if !salesReturn.Deleted && salesReturn.OrderID != nil {
+		collection := db.GetDB("store_" + store.ID.Hex()).Collection("loyalty_point_history")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		earned := LoyaltyPointHistory{}
+		err = collection.FindOne(ctx, bson.M{
+			"order_id": salesReturn.OrderID,
+			"type":     LoyaltyPointTypeEarn,
+		}).Decode(&earned)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		if err == nil && earned.CustomerID != nil {
+			alreadyReversed, err := store.sumLoyaltyPoints(bson.M{
+				"order_id": salesReturn.OrderID,
+				"type":     LoyaltyPointTypeReverse,
+			})
+			if err != nil {
+				return err
+			}
+
+			points := LoyaltyPointsToReverse(earned.Points, earned.BaseAmount, salesReturn.NetTotal-salesReturn.CashDiscount, alreadyReversed*-1)
+			if points > 0 {
+				salesReturn.LoyaltyPointsReversed = points
+				salesReturn.LoyaltyReversedValue = RoundTo2Decimals(earned.Value * points / earned.Points)
+				customerIDs = append(customerIDs, *earned.CustomerID)
+
+				err = store.InsertLoyaltyPointHistory(&LoyaltyPointHistory{
+					CustomerID:    earned.CustomerID,
+					CustomerName:  earned.CustomerName,
+					Date:          salesReturn.Date,
+					Type:          LoyaltyPointTypeReverse,
+					Points:        points * -1,
+					Value:         salesReturn.LoyaltyReversedValue * -1,
+					BaseAmount:    RoundTo2Decimals(salesReturn.NetTotal - salesReturn.CashDiscount),
+					OrderID:       salesReturn.OrderID,
+					OrderCode:     salesReturn.OrderCode,
+					ReferenceType: "sales_return",
+					ReferenceID:   &salesReturn.ID,
+					ReferenceCode: salesReturn.Code,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("salesreturn")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = collection.UpdateOne(ctx,
+		bson.M{"_id": salesReturn.ID},
+		bson.M{"$set": bson.M{
+			"loyalty_points_reversed": salesReturn.LoyaltyPointsReversed,
+			"loyalty_reversed_value":  salesReturn.LoyaltyReversedValue,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+
+	return store.SetCustomerLoyaltyPoints(customerIDs...)
+}
+
+// makeLoyaltyPointsJournals moves value between the loyalty expense and liability accounts.
+// Earned points debit the expense, reversed points debit the liability.
+func (store *Store) makeLoyaltyPointsJournals(date *time.Time, value float64, reverse bool) ([]Journal, error) {
+	if value <= 0 {
+		return nil, nil
+	}
+
+	liabilityAccount, err := store.CreateAccountIfNotExists(&store.ID, nil, nil, "Loyalty points liability", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	expenseAccount, err := store.CreateAccountIfNotExists(&store.ID, nil, nil, "Loyalty points expense", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	debitAccount, creditAccount := expenseAccount, liabilityAccount
+	if reverse {
+		debitAccount, creditAccount = liabilityAccount, expenseAccount
+	}
+
+	now := time.Now()
+	groupID := primitive.NewObjectID()
+	return []Journal{
+		{
+			Date:          date,
+			AccountID:     debitAccount.ID,
+			AccountNumber: debitAccount.Number,
+			AccountName:   debitAccount.Name,
+			DebitOrCredit: "debit",
+			Debit:         value,
+			GroupID:       groupID,
+			CreatedAt:     &now,
+			UpdatedAt:     &now,
+		},
+		{
+			Date:          date,
+			AccountID:     creditAccount.ID,
+			AccountNumber: creditAccount.Number,
+			AccountName:   creditAccount.Name,
+			DebitOrCredit: "credit",
+			Credit:        value,
+			GroupID:       groupID,
+			CreatedAt:     &now,
+			UpdatedAt:     &now,
+		},
+	}, nil
+}
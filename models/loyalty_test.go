@@ -0,0 +1,94 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func makeLoyaltySettings() LoyaltySettings {
+	return LoyaltySettings{Enabled: true, PointsPerCurrencyUnit: 1, PointValue: 0.05, MinRedeemPoints: 100}
+}
+
+// ── PointsEarned ─────────────────────────────────────────────────────────────
+
+func TestLoyaltyPointsEarned_WholePoints(t *testing.T) {
+	settings := makeLoyaltySettings()
+	if got := settings.PointsEarned(99.99); got != 99 {
+		t.Errorf("PointsEarned(99.99) = %v, want 99", got)
+	}
+	settings.PointsPerCurrencyUnit = 0.1
+	if got := settings.PointsEarned(250); got != 25 {
+		t.Errorf("PointsEarned(250) = %v, want 25", got)
+	}
+}
+
+func TestLoyaltyPointsEarned_Disabled(t *testing.T) {
+	settings := makeLoyaltySettings()
+	settings.Enabled = false
+	if got := settings.PointsEarned(500); got != 0 {
+		t.Errorf("PointsEarned with loyalty disabled = %v, want 0", got)
+	}
+}
+
+// ── ValidateRedemption ───────────────────────────────────────────────────────
+
+func TestLoyaltyValidateRedemption(t *testing.T) {
+	settings := makeLoyaltySettings()
+
+	// 10.00 needs 200 points
+	if err := settings.ValidateRedemption(10, 200); err != nil {
+		t.Errorf("ValidateRedemption(10, 200) = %v, want nil", err)
+	}
+	if err := settings.ValidateRedemption(10, 150); err == nil {
+		t.Error("redeeming more points than available should fail")
+	}
+	if err := settings.ValidateRedemption(2, 1000); err == nil {
+		t.Error("redeeming less than the minimum points should fail")
+	}
+
+	settings.Enabled = false
+	if err := settings.ValidateRedemption(10, 1000); err == nil {
+		t.Error("redeeming with loyalty disabled should fail")
+	}
+}
+
+// ── LoyaltyPointsToReverse ───────────────────────────────────────────────────
+
+func TestLoyaltyPointsToReverse_Proportional(t *testing.T) {
+	if got := LoyaltyPointsToReverse(100, 200, 50, 0); got != 25 {
+		t.Errorf("LoyaltyPointsToReverse = %v, want 25", got)
+	}
+}
+
+func TestLoyaltyPointsToReverse_CappedByWhatIsLeft(t *testing.T) {
+	if got := LoyaltyPointsToReverse(100, 200, 150, 60); got != 40 {
+		t.Errorf("LoyaltyPointsToReverse = %v, want 40", got)
+	}
+	if got := LoyaltyPointsToReverse(100, 200, 500, 0); got != 100 {
+		t.Errorf("LoyaltyPointsToReverse for a full return = %v, want 100", got)
+	}
+}
+
+// ── loyaltyPointEntries ──────────────────────────────────────────────────────
+
+func TestLoyaltyPointEntries_RedeemedAndEarned(t *testing.T) {
+	customer := &Customer{ID: primitive.NewObjectID(), Name: "Acme"}
+	order := Order{ID: primitive.NewObjectID(), Code: "S-0001", NetTotal: 150}
+	payments := []SalesPayment{
+		{ID: primitive.NewObjectID(), Method: LoyaltyPaymentMethod, Amount: 10},
+		{ID: primitive.NewObjectID(), Method: "cash", Amount: 140},
+	}
+
+	entries := order.loyaltyPointEntries(customer, payments, makeLoyaltySettings())
+	if len(entries) != 2 {
+		t.Fatalf("entries = %+v, want a redeem and an earn entry", entries)
+	}
+	if entries[0].Type != LoyaltyPointTypeRedeem || entries[0].Points != -200 {
+		t.Errorf("redeem entry = %+v, want -200 points", entries[0])
+	}
+	// points are earned on the 140.00 not paid with points
+	if entries[1].Type != LoyaltyPointTypeEarn || entries[1].Points != 140 || order.LoyaltyPointsEarned != 140 {
+		t.Errorf("earn entry = %+v, LoyaltyPointsEarned = %v, want 140", entries[1], order.LoyaltyPointsEarned)
+	}
+}
@@ -297,6 +297,12 @@ func (s *NonVATSales) Validate(w http.ResponseWriter, r *http.Request, scenario
 		s.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, s.StoreID, "non_vat_sales", s.ID, s.Code, s.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	if !govalidator.IsNull(strings.TrimSpace(s.Phone)) && !ValidateSaudiPhone(strings.TrimSpace(s.Phone)) {
 		errs["phone"] = "Invalid phone no."
 		return
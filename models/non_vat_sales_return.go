@@ -247,6 +247,12 @@ func (ret *NonVATSalesReturn) Validate(w http.ResponseWriter, r *http.Request, s
 		ret.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, ret.StoreID, "non_vat_sales_return", ret.ID, ret.Code, ret.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	if scenario == "update" {
 		if ret.ID.IsZero() {
 			w.WriteHeader(http.StatusBadRequest)
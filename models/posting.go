@@ -48,6 +48,8 @@ type Posting struct {
 	Reference2ID    primitive.ObjectID  `json:"reference2_id,omitempty" bson:"reference2_id,omitempty"`
 	Reference2Model string              `bson:"reference2_model,omitempty" json:"reference2_model,omitempty"`
 	Reference2Code  string              `bson:"reference2_code,omitempty" json:"reference2_code,omitempty"`
+	CostCenterID    *primitive.ObjectID `json:"cost_center_id,omitempty" bson:"cost_center_id,omitempty"`
+	CostCenterName  string              `json:"cost_center_name,omitempty" bson:"cost_center_name,omitempty"`
 	Posts           []Post              `json:"posts,omitempty" bson:"posts,omitempty"`
 	DebitTotal      float64             `bson:"debit_total" json:"debit_total"`
 	CreditTotal     float64             `bson:"credit_total" json:"credit_total"`
@@ -1004,6 +1006,8 @@ func (ledger *Ledger) CreatePostings() (postings []Posting, err error) {
 			ReferenceID:    ledger.ReferenceID,
 			ReferenceModel: ledger.ReferenceModel,
 			ReferenceCode:  ledger.ReferenceCode,
+			CostCenterID:   journal.CostCenterID,
+			CostCenterName: journal.CostCenterName,
 			Posts:          posts,
 			DebitTotal:     debitTotal,
 			CreditTotal:    creditTotal,
@@ -0,0 +1,798 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	PriceListTypeCustomer      = "customer"
+	PriceListTypeCustomerGroup = "customer_group"
+	PriceListTypeQuantityBreak = "quantity_break"
+)
+
+// PriceListItem : special price of a product in a price list.
+// UnitPrice is per base unit and excludes VAT; MinQuantity is in base units.
+type PriceListItem struct {
+	ProductID        primitive.ObjectID `json:"product_id" bson:"product_id"`
+	ProductName      string             `json:"product_name,omitempty" bson:"product_name,omitempty"`
+	PartNumber       string             `json:"part_number,omitempty" bson:"part_number,omitempty"`
+	MinQuantity      float64            `json:"min_quantity" bson:"min_quantity"`
+	UnitPrice        float64            `json:"unit_price" bson:"unit_price"`
+	UnitPriceWithVAT float64            `json:"unit_price_with_vat" bson:"unit_price_with_vat"`
+}
+
+// PriceList : PriceList structure
+type PriceList struct {
+	ID            primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	Name          string               `bson:"name" json:"name"`
+	Type          string               `bson:"type" json:"type"`
+	CustomerIDs   []primitive.ObjectID `bson:"customer_ids,omitempty" json:"customer_ids,omitempty"`
+	CustomerGroup string               `bson:"customer_group,omitempty" json:"customer_group,omitempty"`
+	Priority      int                  `bson:"priority" json:"priority"`
+	ValidFrom     *time.Time           `bson:"valid_from,omitempty" json:"valid_from,omitempty"`
+	ValidFromStr  string               `json:"valid_from_str,omitempty" bson:"-"`
+	ValidTo       *time.Time           `bson:"valid_to,omitempty" json:"valid_to,omitempty"`
+	ValidToStr    string               `json:"valid_to_str,omitempty" bson:"-"`
+	Items         []PriceListItem      `bson:"items" json:"items"`
+	StoreID       *primitive.ObjectID  `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName     string               `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted       bool                 `bson:"deleted,omitempty" json:"deleted,omitempty"`
+	DeletedBy     *primitive.ObjectID  `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt     *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt     *time.Time           `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt     *time.Time           `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy     *primitive.ObjectID  `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy     *primitive.ObjectID  `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName string               `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName string               `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+	DeletedByName string               `json:"deleted_by_name,omitempty" bson:"deleted_by_name,omitempty"`
+}
+
+// IsValidOn tells if the price list is in effect at the given time.
+func (priceList *PriceList) IsValidOn(at time.Time) bool {
+	if priceList.Deleted {
+		return false
+	}
+	if priceList.ValidFrom != nil && at.Before(*priceList.ValidFrom) {
+		return false
+	}
+	if priceList.ValidTo != nil && at.After(*priceList.ValidTo) {
+		return false
+	}
+	return true
+}
+
+// AppliesToCustomer tells if the price list covers the given customer.
+// Quantity break lists apply to every customer.
+func (priceList *PriceList) AppliesToCustomer(customer *Customer) bool {
+	switch priceList.Type {
+	case PriceListTypeCustomer:
+		if customer == nil {
+			return false
+		}
+		for _, customerID := range priceList.CustomerIDs {
+			if customerID == customer.ID {
+				return true
+			}
+		}
+		return false
+	case PriceListTypeCustomerGroup:
+		return customer != nil && customer.CustomerGroup != "" &&
+			strings.EqualFold(strings.TrimSpace(priceList.CustomerGroup), strings.TrimSpace(customer.CustomerGroup))
+	case PriceListTypeQuantityBreak:
+		return true
+	}
+	return false
+}
+
+// FindItem returns the price break of a product for the given base quantity,
+// i.e. the item with the highest minimum quantity not above it.
+func (priceList *PriceList) FindItem(productID primitive.ObjectID, baseQuantity float64) *PriceListItem {
+	var found *PriceListItem
+	for i := range priceList.Items {
+		item := &priceList.Items[i]
+		if item.ProductID != productID || baseQuantity < item.MinQuantity {
+			continue
+		}
+		if found == nil || item.MinQuantity > found.MinQuantity {
+			found = item
+		}
+	}
+	return found
+}
+
+func priceListTypeRank(listType string) int {
+	switch listType {
+	case PriceListTypeCustomer:
+		return 3
+	case PriceListTypeCustomerGroup:
+		return 2
+	case PriceListTypeQuantityBreak:
+		return 1
+	}
+	return 0
+}
+
+// SelectPriceListItem picks the price list item to use for a sales line.
+// A customer specific list wins over a customer group list, which wins over a
+// quantity break list; then the higher priority and finally the lower price wins.
+func SelectPriceListItem(
+	priceLists []PriceList,
+	customer *Customer,
+	productID primitive.ObjectID,
+	baseQuantity float64,
+	at time.Time,
+) (*PriceList, *PriceListItem) {
+	var selectedList *PriceList
+	var selectedItem *PriceListItem
+
+	for i := range priceLists {
+		priceList := &priceLists[i]
+		if !priceList.IsValidOn(at) || !priceList.AppliesToCustomer(customer) {
+			continue
+		}
+
+		item := priceList.FindItem(productID, baseQuantity)
+		if item == nil {
+			continue
+		}
+
+		if selectedList != nil {
+			rank, selectedRank := priceListTypeRank(priceList.Type), priceListTypeRank(selectedList.Type)
+			if rank < selectedRank {
+				continue
+			}
+			if rank == selectedRank {
+				if priceList.Priority < selectedList.Priority {
+					continue
+				}
+				if priceList.Priority == selectedList.Priority && item.UnitPrice >= selectedItem.UnitPrice {
+					continue
+				}
+			}
+		}
+
+		selectedList = priceList
+		selectedItem = item
+	}
+
+	return selectedList, selectedItem
+}
+
+// LineUnitPrices returns the price of one line unit, without and with VAT.
+func (item *PriceListItem) LineUnitPrices(conversionFactor float64, vatPercent float64) (unitPrice float64, unitPriceWithVAT float64) {
+	if conversionFactor <= 0 {
+		conversionFactor = 1
+	}
+	unitPrice = RoundTo2Decimals(item.UnitPrice * conversionFactor)
+	unitPriceWithVAT = RoundTo2Decimals(unitPrice * (1 + (vatPercent / 100)))
+	return unitPrice, unitPriceWithVAT
+}
+
+// GetPriceListsForCustomer returns the price lists that may apply to the customer.
+func (store *Store) GetPriceListsForCustomer(customer *Customer) (priceLists []PriceList, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("price_list")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"deleted": bson.M{"$ne": true},
+		"$or": []bson.M{
+			bson.M{"type": PriceListTypeQuantityBreak},
+			bson.M{"type": PriceListTypeCustomer, "customer_ids": customer.ID},
+			bson.M{"type": PriceListTypeCustomerGroup},
+		},
+	}
+
+	cur, err := collection.Find(ctx, filter)
+	if err != nil {
+		return priceLists, errors.New("Error fetching price lists:" + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		priceList := PriceList{}
+		err = cur.Decode(&priceList)
+		if err != nil {
+			return priceLists, errors.New("Cursor decode error:" + err.Error())
+		}
+		priceLists = append(priceLists, priceList)
+	}
+
+	return priceLists, nil
+}
+
+// priceListPricer prices sales lines of one document from the customer's price lists.
+type priceListPricer struct {
+	store      *Store
+	customer   *Customer
+	priceLists []PriceList
+	at         time.Time
+	vatPercent float64
+}
+
+func (store *Store) newPriceListPricer(customerID *primitive.ObjectID, date *time.Time, vatPercent *float64) (*priceListPricer, error) {
+	customer, err := store.FindCustomerByID(customerID, bson.M{"_id": 1, "customer_group": 1})
+	if err != nil {
+		return nil, errors.New("Error finding customer: " + err.Error())
+	}
+
+	priceLists, err := store.GetPriceListsForCustomer(customer)
+	if err != nil {
+		return nil, err
+	}
+
+	pricer := &priceListPricer{
+		store:      store,
+		customer:   customer,
+		priceLists: priceLists,
+		at:         time.Now(),
+		vatPercent: store.VatPercent,
+	}
+	if date != nil {
+		pricer.at = *date
+	}
+	if vatPercent != nil {
+		pricer.vatPercent = *vatPercent
+	}
+
+	return pricer, nil
+}
+
+// priceLine resolves the unit prices of a line. A line that no list covers keeps its
+// price, unless it was priced by a list before: then it goes back to the retail price.
+func (pricer *priceListPricer) priceLine(
+	productID primitive.ObjectID,
+	unit string,
+	quantity float64,
+	priceListID **primitive.ObjectID,
+	priceListName *string,
+	unitPrice *float64,
+	unitPriceWithVAT *float64,
+) {
+	if productID.IsZero() {
+		return
+	}
+
+	product, err := pricer.store.FindProductByID(&productID, bson.M{"id": 1, "name": 1, "unit": 1, "units": 1, "product_stores": 1})
+	if err != nil {
+		return
+	}
+
+	conversionFactor, err := product.GetUnitConversionFactor(unit)
+	if err != nil {
+		return
+	}
+
+	priceList, item := SelectPriceListItem(pricer.priceLists, pricer.customer, productID, ToBaseQuantity(quantity, conversionFactor), pricer.at)
+	if item != nil {
+		*unitPrice, *unitPriceWithVAT = item.LineUnitPrices(conversionFactor, pricer.vatPercent)
+		*priceListID = &priceList.ID
+		*priceListName = priceList.Name
+		return
+	}
+
+	if *priceListID != nil {
+		retailUnitPrice := product.ProductStores[pricer.store.ID.Hex()].RetailUnitPrice * conversionFactor
+		if productUnit := product.FindUnit(unit); productUnit != nil && productUnit.RetailUnitPrice > 0 {
+			retailUnitPrice = productUnit.RetailUnitPrice
+		}
+		*unitPrice = RoundTo2Decimals(retailUnitPrice)
+		*unitPriceWithVAT = RoundTo2Decimals(*unitPrice * (1 + (pricer.vatPercent / 100)))
+	}
+	*priceListID = nil
+	*priceListName = ""
+}
+
+// pricingLine is what the price of a sales line depends on.
+type pricingLine struct {
+	productID primitive.ObjectID
+	unit      string
+	quantity  float64
+}
+
+// needsPricing tells if a document is priced again on save: when it is new, or its
+// customer or lines changed. Otherwise the prices entered on it are kept.
+func needsPricing(customerID, oldCustomerID *primitive.ObjectID, lines, oldLines []pricingLine) bool {
+	if (customerID == nil) != (oldCustomerID == nil) || (customerID != nil && *customerID != *oldCustomerID) {
+		return true
+	}
+	if len(lines) != len(oldLines) {
+		return true
+	}
+	for i := range lines {
+		if lines[i] != oldLines[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsPricing tells if the order is priced again on save. An invoice reported to
+// ZATCA never is.
+func (order *Order) NeedsPricing(orderOld *Order) bool {
+	if orderOld == nil {
+		return true
+	}
+	if orderOld.Zatca.ReportingPassed {
+		return false
+	}
+	return needsPricing(order.CustomerID, orderOld.CustomerID, order.pricingLines(), orderOld.pricingLines())
+}
+
+// FindSaved returns the saved version of an order being edited, nil for a new order.
+func (order *Order) FindSaved() (*Order, error) {
+	if order.ID.IsZero() || order.StoreID == nil {
+		return nil, nil
+	}
+
+	store, err := FindStoreByID(order.StoreID, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	orderOld, err := store.FindOrderByID(&order.ID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return orderOld, err
+}
+
+func (order *Order) pricingLines() (lines []pricingLine) {
+	for _, product := range order.Products {
+		lines = append(lines, pricingLine{productID: product.ProductID, unit: product.Unit, quantity: product.Quantity})
+	}
+	return lines
+}
+
+// NeedsPricing tells if the quotation is priced again on save. Once the invoice made
+// from it was reported to ZATCA it never is.
+func (quotation *Quotation) NeedsPricing(quotationOld *Quotation) bool {
+	if quotationOld == nil {
+		return true
+	}
+	if quotationOld.ReportedToZatca {
+		return false
+	}
+	return needsPricing(quotation.CustomerID, quotationOld.CustomerID, quotation.pricingLines(), quotationOld.pricingLines())
+}
+
+// FindSaved returns the saved version of a quotation being edited, nil for a new quotation.
+func (quotation *Quotation) FindSaved() (*Quotation, error) {
+	if quotation.ID.IsZero() || quotation.StoreID == nil {
+		return nil, nil
+	}
+
+	store, err := FindStoreByID(quotation.StoreID, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	quotationOld, err := store.FindQuotationByID(&quotation.ID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return quotationOld, err
+}
+
+func (quotation *Quotation) pricingLines() (lines []pricingLine) {
+	for _, product := range quotation.Products {
+		lines = append(lines, pricingLine{productID: product.ProductID, unit: product.Unit, quantity: product.Quantity})
+	}
+	return lines
+}
+
+// ApplyPriceLists prices the order lines from the customer's price lists.
+func (order *Order) ApplyPriceLists() error {
+	if order.CustomerID == nil || order.CustomerID.IsZero() || order.StoreID == nil {
+		return nil
+	}
+
+	store, err := FindStoreByID(order.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	pricer, err := store.newPriceListPricer(order.CustomerID, order.Date, order.VatPercent)
+	if err != nil {
+		return err
+	}
+
+	for i := range order.Products {
+		line := &order.Products[i]
+		pricer.priceLine(line.ProductID, line.Unit, line.Quantity, &line.PriceListID, &line.PriceListName, &line.UnitPrice, &line.UnitPriceWithVAT)
+	}
+
+	return nil
+}
+
+// ApplyPriceLists prices the quotation lines from the customer's price lists.
+func (quotation *Quotation) ApplyPriceLists() error {
+	if quotation.CustomerID == nil || quotation.CustomerID.IsZero() || quotation.StoreID == nil {
+		return nil
+	}
+
+	store, err := FindStoreByID(quotation.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	pricer, err := store.newPriceListPricer(quotation.CustomerID, quotation.Date, quotation.VatPercent)
+	if err != nil {
+		return err
+	}
+
+	for i := range quotation.Products {
+		line := &quotation.Products[i]
+		pricer.priceLine(line.ProductID, line.Unit, line.Quantity, &line.PriceListID, &line.PriceListName, &line.UnitPrice, &line.UnitPriceWithVAT)
+	}
+
+	return nil
+}
+
+func (priceList *PriceList) UpdateForeignLabelFields() error {
+	if priceList.StoreID != nil {
+		store, err := FindStoreByID(priceList.StoreID, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		priceList.StoreName = store.Name
+	}
+
+	if priceList.CreatedBy != nil {
+		createdByUser, err := FindUserByID(priceList.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		priceList.CreatedByName = createdByUser.Name
+	}
+
+	if priceList.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(priceList.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		priceList.UpdatedByName = updatedByUser.Name
+	}
+
+	if priceList.DeletedBy != nil && !priceList.DeletedBy.IsZero() {
+		deletedByUser, err := FindUserByID(priceList.DeletedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		priceList.DeletedByName = deletedByUser.Name
+	}
+
+	return nil
+}
+
+func (store *Store) SearchPriceList(w http.ResponseWriter, r *http.Request) (priceLists []PriceList, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+	ParseDeletedFilter(r, &criterias)
+
+	ParseTextSearch(r, &criterias, "search[name]", "name")
+	ParseTextSearch(r, &criterias, "search[customer_group]", "customer_group")
+
+	keys, ok := r.URL.Query()["search[type]"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.SearchBy["type"] = keys[0]
+	}
+
+	if err = ParseObjectIDFilter(r, &criterias, "search[customer_id]", "customer_ids"); err != nil {
+		return priceLists, criterias, err
+	}
+
+	if err = ParseObjectIDFilter(r, &criterias, "search[product_id]", "items.product_id"); err != nil {
+		return priceLists, criterias, err
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("price_list")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	keys, ok = r.URL.Query()["select"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.Select = ParseSelectString(keys[0])
+	}
+
+	if criterias.Select != nil {
+		findOptions.SetProjection(criterias.Select)
+	}
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return priceLists, criterias, errors.New("Error fetching price lists:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return priceLists, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		priceList := PriceList{}
+		err = cur.Decode(&priceList)
+		if err != nil {
+			return priceLists, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		priceLists = append(priceLists, priceList)
+	}
+
+	return priceLists, criterias, nil
+}
+
+func (priceList *PriceList) Validate(w http.ResponseWriter, r *http.Request, scenario string) (errs map[string]string) {
+	errs = make(map[string]string)
+	priceList.Name = strings.TrimSpace(priceList.Name)
+	priceList.CustomerGroup = strings.TrimSpace(priceList.CustomerGroup)
+
+	store, err := FindStoreByID(priceList.StoreID, bson.M{})
+	if err != nil {
+		errs["store_id"] = "invalid store id"
+		w.WriteHeader(http.StatusBadRequest)
+		return errs
+	}
+
+	if scenario == "update" {
+		if priceList.ID.IsZero() {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = "ID is required"
+			return errs
+		}
+		exists, err := store.IsPriceListExists(&priceList.ID)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = err.Error()
+			return errs
+		}
+
+		if !exists {
+			errs["id"] = "Invalid Price List:" + priceList.ID.Hex()
+		}
+	}
+
+	if govalidator.IsNull(priceList.Name) {
+		errs["name"] = "Name is required"
+	}
+
+	switch priceList.Type {
+	case PriceListTypeCustomer:
+		priceList.CustomerGroup = ""
+		if len(priceList.CustomerIDs) == 0 {
+			errs["customer_ids"] = "At least 1 customer is required"
+		}
+		for i, customerID := range priceList.CustomerIDs {
+			exists, err := store.IsCustomerExists(&customerID)
+			if err != nil || !exists {
+				errs["customer_id_"+strconv.Itoa(i)] = "Invalid customer:" + customerID.Hex()
+			}
+		}
+	case PriceListTypeCustomerGroup:
+		priceList.CustomerIDs = nil
+		if govalidator.IsNull(priceList.CustomerGroup) {
+			errs["customer_group"] = "Customer group is required"
+		}
+	case PriceListTypeQuantityBreak:
+		priceList.CustomerIDs = nil
+		priceList.CustomerGroup = ""
+	default:
+		errs["type"] = "Type should be customer, customer_group or quantity_break"
+	}
+
+	if !govalidator.IsNull(priceList.ValidFromStr) {
+		const shortForm = "2006-01-02T15:04:05Z07:00"
+		validFrom, err := time.Parse(shortForm, priceList.ValidFromStr)
+		if err != nil {
+			errs["valid_from_str"] = "Invalid date format"
+		} else {
+			priceList.ValidFrom = &validFrom
+		}
+	}
+
+	if !govalidator.IsNull(priceList.ValidToStr) {
+		const shortForm = "2006-01-02T15:04:05Z07:00"
+		validTo, err := time.Parse(shortForm, priceList.ValidToStr)
+		if err != nil {
+			errs["valid_to_str"] = "Invalid date format"
+		} else {
+			priceList.ValidTo = &validTo
+		}
+	}
+
+	if priceList.ValidFrom != nil && priceList.ValidTo != nil && priceList.ValidTo.Before(*priceList.ValidFrom) {
+		errs["valid_to_str"] = "Valid to date should be after the valid from date"
+	}
+
+	if len(priceList.Items) == 0 {
+		errs["items"] = "At least 1 item is required"
+	}
+
+	breaks := map[string]bool{}
+	for i := range priceList.Items {
+		item := &priceList.Items[i]
+		if item.ProductID.IsZero() {
+			errs["product_id_"+strconv.Itoa(i)] = "Product is required"
+			continue
+		}
+
+		product, err := store.FindProductByID(&item.ProductID, bson.M{"id": 1, "name": 1, "part_number": 1})
+		if err != nil {
+			errs["product_id_"+strconv.Itoa(i)] = "Invalid product:" + item.ProductID.Hex()
+			continue
+		}
+		item.ProductName = product.Name
+		item.PartNumber = product.PartNumber
+
+		if item.MinQuantity < 0 {
+			errs["min_quantity_"+strconv.Itoa(i)] = "Minimum quantity should not be negative"
+		}
+
+		key := item.ProductID.Hex() + "_" + strconv.FormatFloat(item.MinQuantity, 'f', -1, 64)
+		if breaks[key] {
+			errs["min_quantity_"+strconv.Itoa(i)] = "Duplicate minimum quantity for " + product.Name
+		}
+		breaks[key] = true
+
+		if item.UnitPrice <= 0 {
+			errs["unit_price_"+strconv.Itoa(i)] = "Unit price should be greater than zero"
+		}
+		item.UnitPrice = RoundTo2Decimals(item.UnitPrice)
+		item.UnitPriceWithVAT = RoundTo2Decimals(item.UnitPrice * (1 + (store.VatPercent / 100)))
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+func (priceList *PriceList) Insert() error {
+	collection := db.GetDB("store_" + priceList.StoreID.Hex()).Collection("price_list")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	priceList.ID = primitive.NewObjectID()
+
+	err := priceList.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	sortPriceListItems(priceList.Items)
+
+	_, err = collection.InsertOne(ctx, &priceList)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (priceList *PriceList) Update() error {
+	collection := db.GetDB("store_" + priceList.StoreID.Hex()).Collection("price_list")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := priceList.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	sortPriceListItems(priceList.Items)
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": priceList.ID},
+		bson.M{"$set": priceList},
+		updateOptions,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func sortPriceListItems(items []PriceListItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].ProductName != items[j].ProductName {
+			return items[i].ProductName < items[j].ProductName
+		}
+		return items[i].MinQuantity < items[j].MinQuantity
+	})
+}
+
+func (priceList *PriceList) DeletePriceList(tokenClaims TokenClaims) (err error) {
+	collection := db.GetDB("store_" + priceList.StoreID.Hex()).Collection("price_list")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	priceList.Deleted = true
+	priceList.DeletedBy = &userID
+	now := time.Now()
+	priceList.DeletedAt = &now
+
+	err = priceList.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": priceList.ID},
+		bson.M{"$set": priceList},
+		updateOptions,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (store *Store) FindPriceListByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (priceList *PriceList, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("price_list")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{"_id": ID}, findOneOptions).
+		Decode(&priceList)
+	if err != nil {
+		return nil, err
+	}
+
+	return priceList, err
+}
+
+func (store *Store) IsPriceListExists(ID *primitive.ObjectID) (exists bool, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("price_list")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	count := int64(0)
+
+	count, err = collection.CountDocuments(ctx, bson.M{
+		"_id": ID,
+	})
+
+	return (count > 0), err
+}
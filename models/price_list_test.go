@@ -0,0 +1,184 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	priceListProductID  = primitive.NewObjectID()
+	priceListCustomerID = primitive.NewObjectID()
+)
+
+func makePriceListCustomer() *Customer {
+	return &Customer{ID: priceListCustomerID, CustomerGroup: "Wholesale"}
+}
+
+func makeQuantityBreakList() PriceList {
+	return PriceList{
+		ID:   primitive.NewObjectID(),
+		Name: "Bulk",
+		Type: PriceListTypeQuantityBreak,
+		Items: []PriceListItem{
+			{ProductID: priceListProductID, MinQuantity: 10, UnitPrice: 9},
+			{ProductID: priceListProductID, MinQuantity: 50, UnitPrice: 8},
+		},
+	}
+}
+
+// ── FindItem ─────────────────────────────────────────────────────────────────
+
+func TestPriceListFindItem_HighestBreakBelowQuantity(t *testing.T) {
+	list := makeQuantityBreakList()
+	item := list.FindItem(priceListProductID, 60)
+	if item == nil || item.UnitPrice != 8 {
+		t.Errorf("item = %+v, want the 50+ break", item)
+	}
+	item = list.FindItem(priceListProductID, 10)
+	if item == nil || item.UnitPrice != 9 {
+		t.Errorf("item = %+v, want the 10+ break", item)
+	}
+}
+
+func TestPriceListFindItem_BelowFirstBreak(t *testing.T) {
+	list := makeQuantityBreakList()
+	if item := list.FindItem(priceListProductID, 5); item != nil {
+		t.Errorf("item = %+v, want nil", item)
+	}
+}
+
+// ── AppliesToCustomer / IsValidOn ───────────────────────────────────────────
+
+func TestPriceListAppliesToCustomer(t *testing.T) {
+	customer := makePriceListCustomer()
+
+	byCustomer := PriceList{Type: PriceListTypeCustomer, CustomerIDs: []primitive.ObjectID{priceListCustomerID}}
+	if !byCustomer.AppliesToCustomer(customer) {
+		t.Error("customer list should apply")
+	}
+
+	byGroup := PriceList{Type: PriceListTypeCustomerGroup, CustomerGroup: "wholesale"}
+	if !byGroup.AppliesToCustomer(customer) {
+		t.Error("group list should apply regardless of case")
+	}
+
+	otherGroup := PriceList{Type: PriceListTypeCustomerGroup, CustomerGroup: "Retail"}
+	if otherGroup.AppliesToCustomer(customer) {
+		t.Error("list of another group should not apply")
+	}
+}
+
+func TestPriceListIsValidOn(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	list := PriceList{ValidFrom: &from, ValidTo: &to}
+
+	if !list.IsValidOn(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Error("list should be valid within its window")
+	}
+	if list.IsValidOn(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("list should be expired after valid_to")
+	}
+	list.ValidTo = nil
+	list.Deleted = true
+	if list.IsValidOn(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Error("deleted list should never be valid")
+	}
+}
+
+// ── SelectPriceListItem ─────────────────────────────────────────────────────
+
+func TestSelectPriceListItem_CustomerListWins(t *testing.T) {
+	customerList := PriceList{
+		ID:          primitive.NewObjectID(),
+		Type:        PriceListTypeCustomer,
+		CustomerIDs: []primitive.ObjectID{priceListCustomerID},
+		Items:       []PriceListItem{{ProductID: priceListProductID, UnitPrice: 9.5}},
+	}
+	lists := []PriceList{makeQuantityBreakList(), customerList}
+
+	list, item := SelectPriceListItem(lists, makePriceListCustomer(), priceListProductID, 60, time.Now())
+	if list == nil || list.ID != customerList.ID || item.UnitPrice != 9.5 {
+		t.Errorf("list = %+v, item = %+v, want the customer list", list, item)
+	}
+}
+
+func TestSelectPriceListItem_PriorityThenLowestPrice(t *testing.T) {
+	low := PriceList{ID: primitive.NewObjectID(), Type: PriceListTypeCustomerGroup, CustomerGroup: "Wholesale", Priority: 1,
+		Items: []PriceListItem{{ProductID: priceListProductID, UnitPrice: 7}}}
+	high := PriceList{ID: primitive.NewObjectID(), Type: PriceListTypeCustomerGroup, CustomerGroup: "Wholesale", Priority: 2,
+		Items: []PriceListItem{{ProductID: priceListProductID, UnitPrice: 9}}}
+	cheaper := PriceList{ID: primitive.NewObjectID(), Type: PriceListTypeCustomerGroup, CustomerGroup: "Wholesale", Priority: 2,
+		Items: []PriceListItem{{ProductID: priceListProductID, UnitPrice: 8.5}}}
+
+	list, _ := SelectPriceListItem([]PriceList{low, high, cheaper}, makePriceListCustomer(), priceListProductID, 1, time.Now())
+	if list == nil || list.ID != cheaper.ID {
+		t.Errorf("list = %+v, want the cheaper list of the highest priority", list)
+	}
+}
+
+func TestSelectPriceListItem_NoMatch(t *testing.T) {
+	list, item := SelectPriceListItem([]PriceList{makeQuantityBreakList()}, makePriceListCustomer(), priceListProductID, 2, time.Now())
+	if list != nil || item != nil {
+		t.Errorf("list = %+v, item = %+v, want none", list, item)
+	}
+}
+
+// ── LineUnitPrices ───────────────────────────────────────────────────────────
+
+func TestPriceListItemLineUnitPrices_AlternativeUnit(t *testing.T) {
+	item := PriceListItem{UnitPrice: 2.5}
+	unitPrice, unitPriceWithVAT := item.LineUnitPrices(12, 15)
+	if unitPrice != 30 || unitPriceWithVAT != 34.5 {
+		t.Errorf("prices = %v / %v, want 30 / 34.5", unitPrice, unitPriceWithVAT)
+	}
+}
+
+// ── NeedsPricing ─────────────────────────────────────────────────────────────
+
+func makePricedOrder() Order {
+	customerID := priceListCustomerID
+	return Order{
+		CustomerID: &customerID,
+		Products:   []OrderProduct{{ProductID: priceListProductID, Unit: "PCS", Quantity: 2, UnitPrice: 7}},
+	}
+}
+
+func TestOrderNeedsPricing_OnlyWhenCustomerOrLinesChange(t *testing.T) {
+	orderOld := makePricedOrder()
+	if order := makePricedOrder(); !order.NeedsPricing(nil) {
+		t.Error("a new order should be priced")
+	}
+
+	// a price entered by hand is kept
+	order := makePricedOrder()
+	order.Products[0].UnitPrice = 6.5
+	if order.NeedsPricing(&orderOld) {
+		t.Error("an order whose customer and lines did not change should keep its prices")
+	}
+
+	order.Products[0].Quantity = 3
+	if !order.NeedsPricing(&orderOld) {
+		t.Error("an order whose quantity changed should be priced again")
+	}
+
+	order = makePricedOrder()
+	otherCustomerID := primitive.NewObjectID()
+	order.CustomerID = &otherCustomerID
+	if !order.NeedsPricing(&orderOld) {
+		t.Error("an order whose customer changed should be priced again")
+	}
+}
+
+func TestOrderNeedsPricing_NeverOnceReportedToZatca(t *testing.T) {
+	orderOld := makePricedOrder()
+	orderOld.Zatca.ReportingPassed = true
+
+	order := makePricedOrder()
+	order.Products[0].Quantity = 3
+	if order.NeedsPricing(&orderOld) {
+		t.Error("an invoice reported to ZATCA should never be priced again")
+	}
+}
@@ -173,6 +173,8 @@ type Product struct {
 	CountryCode          string                  `bson:"country_code" json:"country_code"`
 	ProductStores        map[string]ProductStore `bson:"product_stores,omitempty" json:"product_stores,omitempty"`
 	Unit                 string                  `bson:"unit" json:"unit"`
+	Units                []ProductUnit           `bson:"units,omitempty" json:"units,omitempty"`
+	MatchedUnit          *ProductUnit            `bson:"-" json:"matched_unit,omitempty"`
 	Images               []string                `bson:"images" json:"images"`
 	ImagesContent        []string                `json:"images_content,omitempty" bson:"-"`
 	Deleted              bool                    `bson:"deleted" json:"deleted"`
@@ -198,6 +200,7 @@ type Product struct {
 	AllowDuplicates      bool                    `bson:"allow_duplicates" json:"allow_duplicates"`
 	Note                 string                  `bson:"note,omitempty" json:"note,omitempty"`
 	IsService            bool                    `bson:"is_service" json:"is_service"`
+	IsSerialised         bool                    `bson:"is_serialised" json:"is_serialised"`
 	DurationMinutes      int                     `bson:"duration_minutes,omitempty" json:"duration_minutes,omitempty"`
 	DurationUnit         string                  `bson:"duration_unit,omitempty" json:"duration_unit,omitempty"`
 	BookingRequired      bool                    `bson:"booking_required" json:"booking_required"`
@@ -2816,6 +2819,8 @@ func (product *Product) Validate(w http.ResponseWriter, r *http.Request, scenari
 
 	} //end for
 
+	product.ValidateUnits(errs)
+
 	if product.ProductStores == nil {
 		product.ProductStores = make(map[string]ProductStore)
 	}
@@ -3502,6 +3507,7 @@ func (store *Store) FindProductByBarCode(
 	criteria["$or"] = []bson.M{
 		{"bar_code": barCode},
 		{"ean_12": barCode},
+		{"units.bar_code": barCode},
 	}
 
 	err = collection.FindOne(ctx, criteria, findOneOptions).
@@ -3510,6 +3516,15 @@ func (store *Store) FindProductByBarCode(
 		return nil, err
 	}
 
+	if product.BarCode != barCode && product.Ean12 != barCode {
+		for i := range product.Units {
+			if product.Units[i].BarCode == barCode {
+				product.MatchedUnit = &product.Units[i]
+				break
+			}
+		}
+	}
+
 	product.SearchLabel = product.Name + " (Part #" + product.PartNumber + ", Arabic: " + product.NameInArabic + ")"
 
 	return product, err
@@ -4698,6 +4713,8 @@ func (product *Product) SetStock() error {
 		return err
 	}
 
+	oldStock := product.ProductStores[product.StoreID.Hex()].Stock
+
 	err = product.SetProductSalesQuantityByStoreID(*product.StoreID)
 	if err != nil {
 		return err
@@ -4798,6 +4815,16 @@ func (product *Product) SetStock() error {
 
 		productStoreTemp.Stock = RoundTo4Decimals(newStock)
 		product.ProductStores[product.StoreID.Hex()] = productStoreTemp
+
+		if productStoreTemp.Stock != oldStock && !product.ID.IsZero() {
+			go store.EmitWebhookEvent(WebhookEventProductStockChanged, ProductStockChange{
+				ProductID:  product.ID,
+				PartNumber: product.PartNumber,
+				Name:       product.Name,
+				OldStock:   oldStock,
+				Stock:      productStoreTemp.Stock,
+			})
+		}
 	}
 
 	err = product.SetWarehouseStock()
@@ -0,0 +1,629 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ProductLotQuantity : quantity of a line taken from (or returned to) a single lot
+type ProductLotQuantity struct {
+	LotNumber  string     `bson:"lot_number" json:"lot_number"`
+	ExpiryDate *time.Time `bson:"expiry_date,omitempty" json:"expiry_date,omitempty"`
+	Quantity   float64    `bson:"quantity" json:"quantity"`
+}
+
+// ProductLotHistory : a single lot movement of a product in a warehouse.
+// Quantity is positive for stock coming in (purchase, transfer in, sales return)
+// and negative for stock going out (sales, transfer out).
+// Collection: product_lot_history
+type ProductLotHistory struct {
+	ID            primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Date          *time.Time          `bson:"date,omitempty" json:"date,omitempty"`
+	StoreID       *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	ProductID     primitive.ObjectID  `json:"product_id,omitempty" bson:"product_id,omitempty"`
+	WarehouseID   *primitive.ObjectID `json:"warehouse_id" bson:"warehouse_id"`
+	WarehouseCode *string             `json:"warehouse_code" bson:"warehouse_code"`
+	LotNumber     string              `bson:"lot_number" json:"lot_number"`
+	ExpiryDate    *time.Time          `bson:"expiry_date,omitempty" json:"expiry_date,omitempty"`
+	Quantity      float64             `bson:"quantity" json:"quantity"`
+	ReferenceType string              `json:"reference_type" bson:"reference_type"`
+	ReferenceID   primitive.ObjectID  `json:"reference_id" bson:"reference_id"`
+	ReferenceCode string              `json:"reference_code" bson:"reference_code"`
+	OrderID       *primitive.ObjectID `json:"order_id,omitempty" bson:"order_id,omitempty"`
+	CreatedAt     *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+}
+
+// ProductLot : available balance of a lot in a warehouse
+type ProductLot struct {
+	ProductID     primitive.ObjectID  `json:"product_id" bson:"product_id"`
+	ProductName   string              `json:"product_name,omitempty" bson:"product_name,omitempty"`
+	ItemCode      string              `json:"item_code,omitempty" bson:"item_code,omitempty"`
+	PartNumber    string              `json:"part_number,omitempty" bson:"part_number,omitempty"`
+	WarehouseID   *primitive.ObjectID `json:"warehouse_id" bson:"warehouse_id"`
+	WarehouseCode *string             `json:"warehouse_code" bson:"warehouse_code"`
+	LotNumber     string              `json:"lot_number" bson:"lot_number"`
+	ExpiryDate    *time.Time          `json:"expiry_date,omitempty" bson:"expiry_date,omitempty"`
+	Quantity      float64             `json:"quantity" bson:"quantity"`
+	DaysToExpiry  *int                `json:"days_to_expiry,omitempty" bson:"-"`
+	Expired       bool                `json:"expired" bson:"-"`
+}
+
+// AllocateLotsFEFO picks quantity from the given lots, first-expiry-first-out.
+// Lots without an expiry date are consumed last. Any quantity that cannot be
+// covered by the lots is left unallocated.
+func AllocateLotsFEFO(lots []ProductLot, quantity float64) (allocations []ProductLotQuantity) {
+	sorted := make([]ProductLot, len(lots))
+	copy(sorted, lots)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].ExpiryDate, sorted[j].ExpiryDate
+		if a == nil && b == nil {
+			return sorted[i].LotNumber < sorted[j].LotNumber
+		} else if a == nil {
+			return false
+		} else if b == nil {
+			return true
+		} else if !a.Equal(*b) {
+			return a.Before(*b)
+		}
+		return sorted[i].LotNumber < sorted[j].LotNumber
+	})
+
+	remaining := RoundTo8Decimals(quantity)
+	for _, lot := range sorted {
+		if remaining <= 0 {
+			break
+		}
+
+		if lot.Quantity <= 0 {
+			continue
+		}
+
+		take := lot.Quantity
+		if take > remaining {
+			take = remaining
+		}
+
+		allocations = append(allocations, ProductLotQuantity{
+			LotNumber:  lot.LotNumber,
+			ExpiryDate: lot.ExpiryDate,
+			Quantity:   RoundTo8Decimals(take),
+		})
+		remaining = RoundTo8Decimals(remaining - take)
+	}
+
+	return allocations
+}
+
+func (store *Store) ClearProductLotHistory(referenceID *primitive.ObjectID) error {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("product_lot_history")
+	ctx := context.Background()
+	_, err := collection.DeleteMany(ctx, bson.M{"reference_id": referenceID})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (store *Store) InsertProductLotHistory(history *ProductLotHistory) error {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("product_lot_history")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	history.ID = primitive.NewObjectID()
+	history.StoreID = &store.ID
+	if history.WarehouseID == nil || history.WarehouseID.IsZero() {
+		history.WarehouseID = nil
+		mainStore := "main_store"
+		history.WarehouseCode = &mainStore
+	}
+
+	now := time.Now()
+	history.CreatedAt = &now
+
+	_, err := collection.InsertOne(ctx, history)
+	return err
+}
+
+// GetProductLots returns the lots of a product having a positive balance in the given warehouse (nil = main store).
+func (store *Store) GetProductLots(productID *primitive.ObjectID, warehouseID *primitive.ObjectID) (lots []ProductLot, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("product_lot_history")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"product_id":   productID,
+		"warehouse_id": warehouseID,
+	}
+
+	if warehouseID == nil || warehouseID.IsZero() {
+		filter["warehouse_id"] = bson.M{"$eq": nil}
+	}
+
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"lot_number":  "$lot_number",
+				"expiry_date": "$expiry_date",
+			},
+			"warehouse_id":   bson.M{"$first": "$warehouse_id"},
+			"warehouse_code": bson.M{"$first": "$warehouse_code"},
+			"quantity":       bson.M{"$sum": "$quantity"},
+		}},
+		{"$match": bson.M{"quantity": bson.M{"$gt": 0}}},
+		{"$project": bson.M{
+			"_id":            0,
+			"product_id":     productID,
+			"lot_number":     "$_id.lot_number",
+			"expiry_date":    "$_id.expiry_date",
+			"warehouse_id":   1,
+			"warehouse_code": 1,
+			"quantity":       1,
+		}},
+	}
+
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return lots, errors.New("Error fetching product lots: " + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		lot := ProductLot{}
+		err = cur.Decode(&lot)
+		if err != nil {
+			return lots, errors.New("Cursor decode error:" + err.Error())
+		}
+		lot.Quantity = RoundTo8Decimals(lot.Quantity)
+		if lot.Quantity > 0 {
+			lots = append(lots, lot)
+		}
+	}
+
+	return lots, nil
+}
+
+// GetExpiringProductLots returns lots with stock on hand expiring within the next `days` days,
+// including lots that have already expired. Sorted by expiry date.
+func (store *Store) GetExpiringProductLots(days int, warehouseID *primitive.ObjectID, limit int) (lots []ProductLot, err error) {
+	if days <= 0 {
+		days = 30
+	}
+
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("product_lot_history")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, days)
+
+	filter := bson.M{
+		"expiry_date": bson.M{"$ne": nil, "$lte": cutoff},
+	}
+
+	if warehouseID != nil && !warehouseID.IsZero() {
+		filter["warehouse_id"] = warehouseID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"product_id":   "$product_id",
+				"warehouse_id": "$warehouse_id",
+				"lot_number":   "$lot_number",
+				"expiry_date":  "$expiry_date",
+			},
+			"warehouse_code": bson.M{"$first": "$warehouse_code"},
+			"quantity":       bson.M{"$sum": "$quantity"},
+		}}},
+		{{Key: "$match", Value: bson.M{"quantity": bson.M{"$gt": 0}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.expiry_date", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "product",
+			"localField":   "_id.product_id",
+			"foreignField": "_id",
+			"as":           "product",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$product", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":            0,
+			"product_id":     "$_id.product_id",
+			"warehouse_id":   "$_id.warehouse_id",
+			"lot_number":     "$_id.lot_number",
+			"expiry_date":    "$_id.expiry_date",
+			"warehouse_code": 1,
+			"quantity":       1,
+			"product_name":   "$product.name",
+			"item_code":      "$product.item_code",
+			"part_number":    "$product.part_number",
+		}}},
+	}
+
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return lots, errors.New("Error fetching expiring lots: " + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		lot := ProductLot{}
+		err = cur.Decode(&lot)
+		if err != nil {
+			return lots, errors.New("Cursor decode error:" + err.Error())
+		}
+
+		lot.Quantity = RoundTo8Decimals(lot.Quantity)
+		if lot.ExpiryDate != nil {
+			daysToExpiry := int(lot.ExpiryDate.Sub(now).Hours() / 24)
+			lot.DaysToExpiry = &daysToExpiry
+			lot.Expired = lot.ExpiryDate.Before(now)
+		}
+		lots = append(lots, lot)
+	}
+
+	return lots, nil
+}
+
+// SetProductsLots records the lots received on this purchase.
+func (purchase *Purchase) SetProductsLots() error {
+	store, err := FindStoreByID(purchase.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	err = store.ClearProductLotHistory(&purchase.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, purchaseProduct := range purchase.Products {
+		if purchaseProduct.IsService || purchaseProduct.LotNumber == "" {
+			continue
+		}
+
+		quantity := ToBaseQuantity(purchaseProduct.Quantity-purchaseProduct.QuantityReturned, purchaseProduct.UnitConversionFactor)
+		if quantity <= 0 {
+			continue
+		}
+
+		err = store.InsertProductLotHistory(&ProductLotHistory{
+			Date:          purchase.Date,
+			ProductID:     purchaseProduct.ProductID,
+			WarehouseID:   purchaseProduct.WarehouseID,
+			WarehouseCode: purchaseProduct.WarehouseCode,
+			LotNumber:     purchaseProduct.LotNumber,
+			ExpiryDate:    purchaseProduct.ExpiryDate,
+			Quantity:      quantity,
+			ReferenceType: "purchase",
+			ReferenceID:   purchase.ID,
+			ReferenceCode: purchase.Code,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetProductsLots refreshes the lots of the purchase the stock is returned from, as the
+// lots of a purchase only hold the quantity not returned to the vendor.
+func (purchaseReturn *PurchaseReturn) SetProductsLots() error {
+	store, err := FindStoreByID(purchaseReturn.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	purchase, err := store.FindPurchaseByID(purchaseReturn.PurchaseID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return purchase.SetProductsLots()
+}
+
+// SetProductsLots consumes lots first-expiry-first-out for each order line and
+// stores the picked lots on the order. The persisted order is used so that the
+// call is safe to repeat for both the new and the old copy of an updated order.
+func (order *Order) SetProductsLots() error {
+	store, err := FindStoreByID(order.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	err = store.ClearProductLotHistory(&order.ID)
+	if err != nil {
+		return err
+	}
+
+	savedOrder, err := store.FindOrderByID(&order.ID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	updates := bson.M{}
+	for i, orderProduct := range savedOrder.Products {
+		var allocations []ProductLotQuantity
+
+		if !orderProduct.IsService && orderProduct.Quantity > 0 {
+			lots, err := store.GetProductLots(&orderProduct.ProductID, orderProduct.WarehouseID)
+			if err != nil {
+				return err
+			}
+
+			allocations = AllocateLotsFEFO(lots, ToBaseQuantity(orderProduct.Quantity, orderProduct.UnitConversionFactor))
+			for _, allocation := range allocations {
+				err = store.InsertProductLotHistory(&ProductLotHistory{
+					Date:          savedOrder.Date,
+					ProductID:     orderProduct.ProductID,
+					WarehouseID:   orderProduct.WarehouseID,
+					WarehouseCode: orderProduct.WarehouseCode,
+					LotNumber:     allocation.LotNumber,
+					ExpiryDate:    allocation.ExpiryDate,
+					Quantity:      -allocation.Quantity,
+					ReferenceType: "sales",
+					ReferenceID:   savedOrder.ID,
+					ReferenceCode: savedOrder.Code,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(allocations) > 0 || len(orderProduct.Lots) > 0 {
+			updates["products."+strconv.Itoa(i)+".lots"] = allocations
+		}
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("order")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": savedOrder.ID}, bson.M{"$set": updates})
+	return err
+}
+
+// SetProductsLots moves lots first-expiry-first-out from the source warehouse to the destination warehouse.
+func (stocktransfer *StockTransfer) SetProductsLots() error {
+	store, err := FindStoreByID(stocktransfer.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	err = store.ClearProductLotHistory(&stocktransfer.ID)
+	if err != nil {
+		return err
+	}
+
+	savedStockTransfer, err := store.FindStockTransferByID(&stocktransfer.ID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	updates := bson.M{}
+	for i, stocktransferProduct := range savedStockTransfer.Products {
+		var allocations []ProductLotQuantity
+
+		if stocktransferProduct.Quantity > 0 {
+			lots, err := store.GetProductLots(&stocktransferProduct.ProductID, savedStockTransfer.FromWarehouseID)
+			if err != nil {
+				return err
+			}
+
+			allocations = AllocateLotsFEFO(lots, ToBaseQuantity(stocktransferProduct.Quantity, stocktransferProduct.UnitConversionFactor))
+			for _, allocation := range allocations {
+				err = store.InsertProductLotHistory(&ProductLotHistory{
+					Date:          savedStockTransfer.Date,
+					ProductID:     stocktransferProduct.ProductID,
+					WarehouseID:   savedStockTransfer.FromWarehouseID,
+					WarehouseCode: savedStockTransfer.FromWarehouseCode,
+					LotNumber:     allocation.LotNumber,
+					ExpiryDate:    allocation.ExpiryDate,
+					Quantity:      -allocation.Quantity,
+					ReferenceType: "stock_transfer",
+					ReferenceID:   savedStockTransfer.ID,
+					ReferenceCode: savedStockTransfer.Code,
+				})
+				if err != nil {
+					return err
+				}
+
+				err = store.InsertProductLotHistory(&ProductLotHistory{
+					Date:          savedStockTransfer.Date,
+					ProductID:     stocktransferProduct.ProductID,
+					WarehouseID:   savedStockTransfer.ToWarehouseID,
+					WarehouseCode: savedStockTransfer.ToWarehouseCode,
+					LotNumber:     allocation.LotNumber,
+					ExpiryDate:    allocation.ExpiryDate,
+					Quantity:      allocation.Quantity,
+					ReferenceType: "stock_transfer",
+					ReferenceID:   savedStockTransfer.ID,
+					ReferenceCode: savedStockTransfer.Code,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(allocations) > 0 || len(stocktransferProduct.Lots) > 0 {
+			updates["products."+strconv.Itoa(i)+".lots"] = allocations
+		}
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("stocktransfer")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": savedStockTransfer.ID}, bson.M{"$set": updates})
+	return err
+}
+
+// SetProductsLots puts returned quantities back into the lots they were sold from,
+// skipping quantities already restored by earlier returns of the same order.
+func (salesreturn *SalesReturn) SetProductsLots() error {
+	store, err := FindStoreByID(salesreturn.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	err = store.ClearProductLotHistory(&salesreturn.ID)
+	if err != nil {
+		return err
+	}
+
+	savedSalesReturn, err := store.FindSalesReturnByID(&salesreturn.ID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if savedSalesReturn.Deleted || savedSalesReturn.OrderID == nil {
+		return nil
+	}
+
+	order, err := store.FindOrderByID(savedSalesReturn.OrderID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	updates := bson.M{}
+	for i, salesreturnProduct := range savedSalesReturn.Products {
+		var restored []ProductLotQuantity
+
+		if salesreturnProduct.Selected && !salesreturnProduct.IsService && salesreturnProduct.Quantity > 0 {
+			soldLots := []ProductLot{}
+			for _, orderProduct := range order.Products {
+				if orderProduct.ProductID != salesreturnProduct.ProductID {
+					continue
+				}
+
+				for _, lot := range orderProduct.Lots {
+					soldLots = append(soldLots, ProductLot{
+						LotNumber:  lot.LotNumber,
+						ExpiryDate: lot.ExpiryDate,
+						Quantity:   lot.Quantity,
+					})
+				}
+			}
+
+			alreadyRestored, err := store.getRestoredLotQuantities(order.ID, salesreturnProduct.ProductID)
+			if err != nil {
+				return err
+			}
+
+			for j := range soldLots {
+				soldLots[j].Quantity = RoundTo8Decimals(soldLots[j].Quantity - alreadyRestored[soldLots[j].LotNumber])
+			}
+
+			restored = AllocateLotsFEFO(soldLots, ToBaseQuantity(salesreturnProduct.Quantity, salesreturnProduct.UnitConversionFactor))
+			for _, lot := range restored {
+				err = store.InsertProductLotHistory(&ProductLotHistory{
+					Date:          savedSalesReturn.Date,
+					ProductID:     salesreturnProduct.ProductID,
+					WarehouseID:   salesreturnProduct.WarehouseID,
+					WarehouseCode: salesreturnProduct.WarehouseCode,
+					LotNumber:     lot.LotNumber,
+					ExpiryDate:    lot.ExpiryDate,
+					Quantity:      lot.Quantity,
+					ReferenceType: "sales_return",
+					ReferenceID:   savedSalesReturn.ID,
+					ReferenceCode: savedSalesReturn.Code,
+					OrderID:       &order.ID,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(restored) > 0 || len(salesreturnProduct.Lots) > 0 {
+			updates["products."+strconv.Itoa(i)+".lots"] = restored
+		}
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("salesreturn")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": savedSalesReturn.ID}, bson.M{"$set": updates})
+	return err
+}
+
+// getRestoredLotQuantities returns the quantity already returned per lot for a product of an order.
+func (store *Store) getRestoredLotQuantities(orderID primitive.ObjectID, productID primitive.ObjectID) (map[string]float64, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("product_lot_history")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	restored := map[string]float64{}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"order_id":       orderID,
+			"product_id":     productID,
+			"reference_type": "sales_return",
+		}},
+		{"$group": bson.M{
+			"_id":      "$lot_number",
+			"quantity": bson.M{"$sum": "$quantity"},
+		}},
+	}
+
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return restored, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var result struct {
+			LotNumber string  `bson:"_id"`
+			Quantity  float64 `bson:"quantity"`
+		}
+		err = cur.Decode(&result)
+		if err != nil {
+			return restored, err
+		}
+		restored[result.LotNumber] = result.Quantity
+	}
+
+	return restored, nil
+}
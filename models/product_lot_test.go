@@ -0,0 +1,77 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func makeLot(lotNumber string, expiry *time.Time, qty float64) ProductLot {
+	return ProductLot{
+		LotNumber:  lotNumber,
+		ExpiryDate: expiry,
+		Quantity:   qty,
+	}
+}
+
+func lotDate(year int, month time.Month, day int) *time.Time {
+	d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &d
+}
+
+// ── AllocateLotsFEFO ─────────────────────────────────────────────────────────
+
+func TestAllocateLotsFEFO_EarliestExpiryFirst(t *testing.T) {
+	lots := []ProductLot{
+		makeLot("B", lotDate(2026, 12, 1), 10),
+		makeLot("A", lotDate(2026, 6, 1), 4),
+	}
+	allocations := AllocateLotsFEFO(lots, 6)
+	if len(allocations) != 2 {
+		t.Fatalf("len(allocations) = %v, want 2", len(allocations))
+	}
+	if allocations[0].LotNumber != "A" || allocations[0].Quantity != 4 {
+		t.Errorf("allocations[0] = %v/%v, want A/4", allocations[0].LotNumber, allocations[0].Quantity)
+	}
+	if allocations[1].LotNumber != "B" || allocations[1].Quantity != 2 {
+		t.Errorf("allocations[1] = %v/%v, want B/2", allocations[1].LotNumber, allocations[1].Quantity)
+	}
+}
+
+func TestAllocateLotsFEFO_NoExpiryConsumedLast(t *testing.T) {
+	lots := []ProductLot{
+		makeLot("NOEXP", nil, 5),
+		makeLot("EXP", lotDate(2027, 1, 1), 5),
+	}
+	allocations := AllocateLotsFEFO(lots, 3)
+	if len(allocations) != 1 || allocations[0].LotNumber != "EXP" {
+		t.Errorf("allocations = %v, want only lot EXP", allocations)
+	}
+}
+
+func TestAllocateLotsFEFO_InsufficientStock(t *testing.T) {
+	lots := []ProductLot{makeLot("A", lotDate(2026, 6, 1), 2)}
+	allocations := AllocateLotsFEFO(lots, 5)
+	if len(allocations) != 1 || allocations[0].Quantity != 2 {
+		t.Errorf("allocations = %v, want A/2", allocations)
+	}
+}
+
+func TestAllocateLotsFEFO_SkipsEmptyLots(t *testing.T) {
+	lots := []ProductLot{
+		makeLot("A", lotDate(2026, 6, 1), 0),
+		makeLot("B", lotDate(2026, 7, 1), -1),
+		makeLot("C", lotDate(2026, 8, 1), 3),
+	}
+	allocations := AllocateLotsFEFO(lots, 1)
+	if len(allocations) != 1 || allocations[0].LotNumber != "C" {
+		t.Errorf("allocations = %v, want only lot C", allocations)
+	}
+}
+
+func TestAllocateLotsFEFO_ZeroQuantity(t *testing.T) {
+	lots := []ProductLot{makeLot("A", lotDate(2026, 6, 1), 2)}
+	allocations := AllocateLotsFEFO(lots, 0)
+	if len(allocations) != 0 {
+		t.Errorf("allocations = %v, want none", allocations)
+	}
+}
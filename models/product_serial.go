@@ -0,0 +1,831 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ProductSerialHistory : a single movement of a serialised unit.
+// Quantity is +1 when the unit comes into a warehouse (purchase, transfer in, sales return)
+// and -1 when it leaves (sales, transfer out, purchase return).
+// Collection: product_serial_history
+type ProductSerialHistory struct {
+	ID            primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Date          *time.Time          `bson:"date,omitempty" json:"date,omitempty"`
+	StoreID       *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	ProductID     primitive.ObjectID  `json:"product_id,omitempty" bson:"product_id,omitempty"`
+	ProductName   string              `json:"product_name,omitempty" bson:"product_name,omitempty"`
+	SerialNumber  string              `json:"serial_number" bson:"serial_number"`
+	WarehouseID   *primitive.ObjectID `json:"warehouse_id" bson:"warehouse_id"`
+	WarehouseCode *string             `json:"warehouse_code" bson:"warehouse_code"`
+	Quantity      float64             `json:"quantity" bson:"quantity"`
+	ReferenceType string              `json:"reference_type" bson:"reference_type"`
+	ReferenceID   primitive.ObjectID  `json:"reference_id" bson:"reference_id"`
+	ReferenceCode string              `json:"reference_code" bson:"reference_code"`
+	OrderID       *primitive.ObjectID `json:"order_id,omitempty" bson:"order_id,omitempty"`
+	PartyID       *primitive.ObjectID `json:"party_id,omitempty" bson:"party_id,omitempty"`
+	PartyName     string              `json:"party_name,omitempty" bson:"party_name,omitempty"`
+	CreatedAt     *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+}
+
+// ProductSerial : current state of a serialised unit
+type ProductSerial struct {
+	ProductID         primitive.ObjectID  `json:"product_id"`
+	SerialNumber      string              `json:"serial_number"`
+	Status            string              `json:"status"` // in_stock | sold | returned
+	WarehouseID       *primitive.ObjectID `json:"warehouse_id"`
+	WarehouseCode     *string             `json:"warehouse_code"`
+	LastReferenceType string              `json:"last_reference_type"`
+	LastReferenceID   primitive.ObjectID  `json:"last_reference_id"`
+	LastReferenceCode string              `json:"last_reference_code"`
+	LastPartyName     string              `json:"last_party_name,omitempty"`
+	LastDate          *time.Time          `json:"last_date,omitempty"`
+}
+
+// ValidateSerialNumbers checks that a serialised line has one unique, non-empty serial per unit.
+// Returns an empty string when the serials are valid.
+func ValidateSerialNumbers(serials []string, quantity float64) string {
+	if quantity != math.Trunc(quantity) {
+		return "Quantity should be a whole number for serialised products"
+	}
+
+	if len(serials) != int(quantity) {
+		return "Enter " + strconv.Itoa(int(quantity)) + " serial number(s), one per unit"
+	}
+
+	seen := map[string]bool{}
+	for _, serial := range serials {
+		serial = strings.TrimSpace(serial)
+		if serial == "" {
+			return "Serial number cannot be empty"
+		}
+
+		if seen[serial] {
+			return "Duplicate serial number: " + serial
+		}
+		seen[serial] = true
+	}
+
+	return ""
+}
+
+// SerialStatusFromHistory folds the movements of a product's units into their current state.
+func SerialStatusFromHistory(history []ProductSerialHistory) (serials []ProductSerial) {
+	sorted := make([]ProductSerialHistory, len(history))
+	copy(sorted, history)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Date, sorted[j].Date
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.Before(*b)
+	})
+
+	balances := map[string]map[string]float64{}
+	warehouses := map[string]map[string]ProductSerialHistory{}
+	last := map[string]ProductSerialHistory{}
+	order := []string{}
+
+	for _, movement := range sorted {
+		if _, ok := balances[movement.SerialNumber]; !ok {
+			balances[movement.SerialNumber] = map[string]float64{}
+			warehouses[movement.SerialNumber] = map[string]ProductSerialHistory{}
+			order = append(order, movement.SerialNumber)
+		}
+
+		key := serialWarehouseKey(movement.WarehouseID)
+		balances[movement.SerialNumber][key] += movement.Quantity
+		warehouses[movement.SerialNumber][key] = movement
+		last[movement.SerialNumber] = movement
+	}
+
+	for _, serialNumber := range order {
+		lastMovement := last[serialNumber]
+		status := "sold"
+		if lastMovement.ReferenceType == "purchase_return" {
+			status = "returned"
+		}
+
+		serial := ProductSerial{
+			ProductID:         lastMovement.ProductID,
+			SerialNumber:      serialNumber,
+			Status:            status,
+			LastReferenceType: lastMovement.ReferenceType,
+			LastReferenceID:   lastMovement.ReferenceID,
+			LastReferenceCode: lastMovement.ReferenceCode,
+			LastPartyName:     lastMovement.PartyName,
+			LastDate:          lastMovement.Date,
+		}
+
+		for key, balance := range balances[serialNumber] {
+			if balance > 0 {
+				serial.Status = "in_stock"
+				serial.WarehouseID = warehouses[serialNumber][key].WarehouseID
+				serial.WarehouseCode = warehouses[serialNumber][key].WarehouseCode
+				break
+			}
+		}
+
+		serials = append(serials, serial)
+	}
+
+	return serials
+}
+
+// TrimSerialNumbers trims surrounding spaces from every serial number.
+func TrimSerialNumbers(serials []string) []string {
+	trimmed := []string{}
+	for _, serial := range serials {
+		trimmed = append(trimmed, strings.TrimSpace(serial))
+	}
+	return trimmed
+}
+
+func serialWarehouseKey(warehouseID *primitive.ObjectID) string {
+	if warehouseID == nil || warehouseID.IsZero() {
+		return "main_store"
+	}
+	return warehouseID.Hex()
+}
+
+func (store *Store) ClearProductSerialHistory(referenceID *primitive.ObjectID) error {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("product_serial_history")
+	ctx := context.Background()
+	_, err := collection.DeleteMany(ctx, bson.M{"reference_id": referenceID})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (store *Store) InsertProductSerialHistory(history *ProductSerialHistory) error {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("product_serial_history")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	history.ID = primitive.NewObjectID()
+	history.StoreID = &store.ID
+	history.SerialNumber = strings.TrimSpace(history.SerialNumber)
+	if history.WarehouseID == nil || history.WarehouseID.IsZero() {
+		history.WarehouseID = nil
+		mainStore := "main_store"
+		history.WarehouseCode = &mainStore
+	}
+
+	now := time.Now()
+	history.CreatedAt = &now
+
+	_, err := collection.InsertOne(ctx, history)
+	return err
+}
+
+// GetSerialBalances returns the stock of a serial per warehouse ("main_store" or warehouse id hex),
+// ignoring the movements of excludeReferenceID (the document being edited).
+func (store *Store) GetSerialBalances(productID *primitive.ObjectID, serialNumber string, excludeReferenceID *primitive.ObjectID) (balances map[string]float64, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("product_serial_history")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	balances = map[string]float64{}
+
+	filter := bson.M{
+		"product_id":    productID,
+		"serial_number": strings.TrimSpace(serialNumber),
+	}
+
+	if excludeReferenceID != nil && !excludeReferenceID.IsZero() {
+		filter["reference_id"] = bson.M{"$ne": excludeReferenceID}
+	}
+
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$group": bson.M{
+			"_id":      "$warehouse_id",
+			"quantity": bson.M{"$sum": "$quantity"},
+		}},
+	}
+
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return balances, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var result struct {
+			WarehouseID *primitive.ObjectID `bson:"_id"`
+			Quantity    float64             `bson:"quantity"`
+		}
+		err = cur.Decode(&result)
+		if err != nil {
+			return balances, err
+		}
+		balances[serialWarehouseKey(result.WarehouseID)] += result.Quantity
+	}
+
+	return balances, nil
+}
+
+// ValidateSerialsInWarehouse returns an error message if any serial is not in stock in the given warehouse.
+func (store *Store) ValidateSerialsInWarehouse(productID *primitive.ObjectID, serials []string, warehouseID *primitive.ObjectID, excludeReferenceID *primitive.ObjectID) (string, error) {
+	warehouseName := "Main Store"
+	if warehouseID != nil && !warehouseID.IsZero() {
+		warehouse, err := store.FindWarehouseByID(warehouseID, bson.M{"name": 1, "code": 1})
+		if err == nil && warehouse != nil {
+			warehouseName = warehouse.Name
+		}
+	}
+
+	for _, serial := range serials {
+		balances, err := store.GetSerialBalances(productID, serial, excludeReferenceID)
+		if err != nil {
+			return "", err
+		}
+
+		if balances[serialWarehouseKey(warehouseID)] <= 0 {
+			return "Serial number " + strings.TrimSpace(serial) + " is not in stock in " + warehouseName, nil
+		}
+	}
+
+	return "", nil
+}
+
+// ValidateSerialsNotInStock returns an error message if any serial is already in stock in any warehouse.
+func (store *Store) ValidateSerialsNotInStock(productID *primitive.ObjectID, serials []string, excludeReferenceID *primitive.ObjectID) (string, error) {
+	for _, serial := range serials {
+		balances, err := store.GetSerialBalances(productID, serial, excludeReferenceID)
+		if err != nil {
+			return "", err
+		}
+
+		for _, balance := range balances {
+			if balance > 0 {
+				return "Serial number " + strings.TrimSpace(serial) + " is already in stock", nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// GetSerialHistory returns the full trail of a serial number, oldest first.
+func (store *Store) GetSerialHistory(serialNumber string, productID *primitive.ObjectID) (models []ProductSerialHistory, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("product_serial_history")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"serial_number": strings.TrimSpace(serialNumber)}
+	if productID != nil && !productID.IsZero() {
+		filter["product_id"] = productID
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "date", Value: 1}, {Key: "created_at", Value: 1}})
+
+	cur, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return models, errors.New("Error fetching serial history: " + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		history := ProductSerialHistory{}
+		err = cur.Decode(&history)
+		if err != nil {
+			return models, errors.New("Cursor decode error:" + err.Error())
+		}
+		models = append(models, history)
+	}
+
+	return models, nil
+}
+
+// GetProductSerials returns the current state of every unit of a product. status: "" | "in_stock" | "sold" | "returned"
+func (store *Store) GetProductSerials(productID *primitive.ObjectID, status string) (serials []ProductSerial, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("product_serial_history")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cur, err := collection.Find(ctx, bson.M{"product_id": productID})
+	if err != nil {
+		return serials, errors.New("Error fetching product serials: " + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	history := []ProductSerialHistory{}
+	err = cur.All(ctx, &history)
+	if err != nil {
+		return serials, errors.New("Cursor decode error:" + err.Error())
+	}
+
+	for _, serial := range SerialStatusFromHistory(history) {
+		if status != "" && serial.Status != status {
+			continue
+		}
+		serials = append(serials, serial)
+	}
+
+	return serials, nil
+}
+
+// isSerialisedProduct reports whether the product requires serial numbers.
+func (store *Store) isSerialisedProduct(productID *primitive.ObjectID) (bool, error) {
+	product, err := store.FindProductByID(productID, bson.M{"id": 1, "is_serialised": 1})
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return product.IsSerialised, nil
+}
+
+// ValidateSerialNumbers validates serials on purchase lines: one per unit and not already in stock.
+func (purchase *Purchase) ValidateSerialNumbers(store *Store, errs map[string]string) error {
+	for i, purchaseProduct := range purchase.Products {
+		if purchaseProduct.ProductID.IsZero() {
+			continue
+		}
+
+		serialised, err := store.isSerialisedProduct(&purchaseProduct.ProductID)
+		if err != nil {
+			return err
+		}
+
+		if !serialised {
+			purchase.Products[i].SerialNumbers = nil
+			continue
+		}
+
+		purchase.Products[i].SerialNumbers = TrimSerialNumbers(purchaseProduct.SerialNumbers)
+		purchaseProduct.SerialNumbers = purchase.Products[i].SerialNumbers
+
+		if msg := ValidateSerialNumbers(purchaseProduct.SerialNumbers, ToBaseQuantity(purchaseProduct.Quantity, purchaseProduct.UnitConversionFactor)); msg != "" {
+			errs["serial_numbers_"+strconv.Itoa(i)] = msg
+			continue
+		}
+
+		msg, err := store.ValidateSerialsNotInStock(&purchaseProduct.ProductID, purchaseProduct.SerialNumbers, &purchase.ID)
+		if err != nil {
+			return err
+		}
+
+		if msg != "" {
+			errs["serial_numbers_"+strconv.Itoa(i)] = msg
+		}
+	}
+
+	return nil
+}
+
+// ValidateSerialNumbers validates serials on order lines: one per unit and in stock in the line's warehouse.
+func (order *Order) ValidateSerialNumbers(store *Store, errs map[string]string) error {
+	for i, orderProduct := range order.Products {
+		if orderProduct.ProductID.IsZero() || orderProduct.IsService {
+			continue
+		}
+
+		serialised, err := store.isSerialisedProduct(&orderProduct.ProductID)
+		if err != nil {
+			return err
+		}
+
+		if !serialised {
+			order.Products[i].SerialNumbers = nil
+			continue
+		}
+
+		order.Products[i].SerialNumbers = TrimSerialNumbers(orderProduct.SerialNumbers)
+		orderProduct.SerialNumbers = order.Products[i].SerialNumbers
+
+		if msg := ValidateSerialNumbers(orderProduct.SerialNumbers, ToBaseQuantity(orderProduct.Quantity, orderProduct.UnitConversionFactor)); msg != "" {
+			errs["serial_numbers_"+strconv.Itoa(i)] = msg
+			continue
+		}
+
+		msg, err := store.ValidateSerialsInWarehouse(&orderProduct.ProductID, orderProduct.SerialNumbers, orderProduct.WarehouseID, &order.ID)
+		if err != nil {
+			return err
+		}
+
+		if msg != "" {
+			errs["serial_numbers_"+strconv.Itoa(i)] = msg
+		}
+	}
+
+	return nil
+}
+
+// ValidateSerialNumbers validates serials on stock transfer lines: one per unit and in stock in the source warehouse.
+func (stocktransfer *StockTransfer) ValidateSerialNumbers(store *Store, errs map[string]string) error {
+	for i, stocktransferProduct := range stocktransfer.Products {
+		if stocktransferProduct.ProductID.IsZero() {
+			continue
+		}
+
+		serialised, err := store.isSerialisedProduct(&stocktransferProduct.ProductID)
+		if err != nil {
+			return err
+		}
+
+		if !serialised {
+			stocktransfer.Products[i].SerialNumbers = nil
+			continue
+		}
+
+		stocktransfer.Products[i].SerialNumbers = TrimSerialNumbers(stocktransferProduct.SerialNumbers)
+		stocktransferProduct.SerialNumbers = stocktransfer.Products[i].SerialNumbers
+
+		if msg := ValidateSerialNumbers(stocktransferProduct.SerialNumbers, ToBaseQuantity(stocktransferProduct.Quantity, stocktransferProduct.UnitConversionFactor)); msg != "" {
+			errs["serial_numbers_"+strconv.Itoa(i)] = msg
+			continue
+		}
+
+		msg, err := store.ValidateSerialsInWarehouse(&stocktransferProduct.ProductID, stocktransferProduct.SerialNumbers, stocktransfer.FromWarehouseID, &stocktransfer.ID)
+		if err != nil {
+			return err
+		}
+
+		if msg != "" {
+			errs["serial_numbers_"+strconv.Itoa(i)] = msg
+		}
+	}
+
+	return nil
+}
+
+// ValidateSerialNumbers validates serials on sales return lines: one per unit,
+// sold on the original order and not already returned.
+func (salesreturn *SalesReturn) ValidateSerialNumbers(store *Store, order *Order, errs map[string]string) error {
+	for i, salesreturnProduct := range salesreturn.Products {
+		if !salesreturnProduct.Selected || salesreturnProduct.ProductID.IsZero() || salesreturnProduct.IsService {
+			continue
+		}
+
+		serialised, err := store.isSerialisedProduct(&salesreturnProduct.ProductID)
+		if err != nil {
+			return err
+		}
+
+		if !serialised {
+			salesreturn.Products[i].SerialNumbers = nil
+			continue
+		}
+
+		salesreturn.Products[i].SerialNumbers = TrimSerialNumbers(salesreturnProduct.SerialNumbers)
+		salesreturnProduct.SerialNumbers = salesreturn.Products[i].SerialNumbers
+
+		if msg := ValidateSerialNumbers(salesreturnProduct.SerialNumbers, ToBaseQuantity(salesreturnProduct.Quantity, salesreturnProduct.UnitConversionFactor)); msg != "" {
+			errs["serial_numbers_"+strconv.Itoa(i)] = msg
+			continue
+		}
+
+		soldSerials := map[string]bool{}
+		for _, orderProduct := range order.Products {
+			if orderProduct.ProductID != salesreturnProduct.ProductID {
+				continue
+			}
+
+			for _, serial := range orderProduct.SerialNumbers {
+				soldSerials[strings.TrimSpace(serial)] = true
+			}
+		}
+
+		for _, serial := range salesreturnProduct.SerialNumbers {
+			if !soldSerials[strings.TrimSpace(serial)] {
+				errs["serial_numbers_"+strconv.Itoa(i)] = "Serial number " + strings.TrimSpace(serial) + " was not sold on order " + order.Code
+				break
+			}
+
+			balances, err := store.GetSerialBalances(&salesreturnProduct.ProductID, serial, &salesreturn.ID)
+			if err != nil {
+				return err
+			}
+
+			inStock := float64(0)
+			for _, balance := range balances {
+				inStock += balance
+			}
+
+			if inStock > 0 {
+				errs["serial_numbers_"+strconv.Itoa(i)] = "Serial number " + strings.TrimSpace(serial) + " is already returned"
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateSerialNumbers validates serials on purchase return lines: one per unit,
+// received on the original purchase and still in stock in the line's warehouse.
+func (purchaseReturn *PurchaseReturn) ValidateSerialNumbers(store *Store, purchase *Purchase, errs map[string]string) error {
+	for i, purchaseReturnProduct := range purchaseReturn.Products {
+		if !purchaseReturnProduct.Selected || purchaseReturnProduct.ProductID.IsZero() || purchaseReturnProduct.IsService {
+			continue
+		}
+
+		serialised, err := store.isSerialisedProduct(&purchaseReturnProduct.ProductID)
+		if err != nil {
+			return err
+		}
+
+		if !serialised {
+			purchaseReturn.Products[i].SerialNumbers = nil
+			continue
+		}
+
+		purchaseReturn.Products[i].SerialNumbers = TrimSerialNumbers(purchaseReturnProduct.SerialNumbers)
+		purchaseReturnProduct.SerialNumbers = purchaseReturn.Products[i].SerialNumbers
+
+		if msg := ValidateSerialNumbers(purchaseReturnProduct.SerialNumbers, ToBaseQuantity(purchaseReturnProduct.Quantity, purchaseReturnProduct.UnitConversionFactor)); msg != "" {
+			errs["serial_numbers_"+strconv.Itoa(i)] = msg
+			continue
+		}
+
+		receivedSerials := map[string]bool{}
+		for _, purchaseProduct := range purchase.Products {
+			if purchaseProduct.ProductID != purchaseReturnProduct.ProductID {
+				continue
+			}
+
+			for _, serial := range purchaseProduct.SerialNumbers {
+				receivedSerials[strings.TrimSpace(serial)] = true
+			}
+		}
+
+		for _, serial := range purchaseReturnProduct.SerialNumbers {
+			if !receivedSerials[serial] {
+				errs["serial_numbers_"+strconv.Itoa(i)] = "Serial number " + serial + " was not received on purchase " + purchase.Code
+				break
+			}
+		}
+
+		if errs["serial_numbers_"+strconv.Itoa(i)] != "" {
+			continue
+		}
+
+		msg, err := store.ValidateSerialsInWarehouse(&purchaseReturnProduct.ProductID, purchaseReturnProduct.SerialNumbers, purchaseReturnProduct.WarehouseID, &purchaseReturn.ID)
+		if err != nil {
+			return err
+		}
+
+		if msg != "" {
+			errs["serial_numbers_"+strconv.Itoa(i)] = msg
+		}
+	}
+
+	return nil
+}
+
+// SetProductsSerials records the serials received on this purchase.
+func (purchase *Purchase) SetProductsSerials() error {
+	store, err := FindStoreByID(purchase.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	err = store.ClearProductSerialHistory(&purchase.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, purchaseProduct := range purchase.Products {
+		for _, serial := range purchaseProduct.SerialNumbers {
+			err = store.InsertProductSerialHistory(&ProductSerialHistory{
+				Date:          purchase.Date,
+				ProductID:     purchaseProduct.ProductID,
+				ProductName:   purchaseProduct.Name,
+				SerialNumber:  serial,
+				WarehouseID:   purchaseProduct.WarehouseID,
+				WarehouseCode: purchaseProduct.WarehouseCode,
+				Quantity:      1,
+				ReferenceType: "purchase",
+				ReferenceID:   purchase.ID,
+				ReferenceCode: purchase.Code,
+				PartyID:       purchase.VendorID,
+				PartyName:     purchase.VendorName,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetProductsSerials records the serials sold on this order. The persisted order is used
+// so that the call is safe to repeat for both the new and the old copy of an updated order.
+func (order *Order) SetProductsSerials() error {
+	store, err := FindStoreByID(order.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	err = store.ClearProductSerialHistory(&order.ID)
+	if err != nil {
+		return err
+	}
+
+	savedOrder, err := store.FindOrderByID(&order.ID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, orderProduct := range savedOrder.Products {
+		for _, serial := range orderProduct.SerialNumbers {
+			err = store.InsertProductSerialHistory(&ProductSerialHistory{
+				Date:          savedOrder.Date,
+				ProductID:     orderProduct.ProductID,
+				ProductName:   orderProduct.Name,
+				SerialNumber:  serial,
+				WarehouseID:   orderProduct.WarehouseID,
+				WarehouseCode: orderProduct.WarehouseCode,
+				Quantity:      -1,
+				ReferenceType: "sales",
+				ReferenceID:   savedOrder.ID,
+				ReferenceCode: savedOrder.Code,
+				PartyID:       savedOrder.CustomerID,
+				PartyName:     savedOrder.CustomerName,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetProductsSerials moves the transferred serials from the source warehouse to the destination warehouse.
+func (stocktransfer *StockTransfer) SetProductsSerials() error {
+	store, err := FindStoreByID(stocktransfer.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	err = store.ClearProductSerialHistory(&stocktransfer.ID)
+	if err != nil {
+		return err
+	}
+
+	savedStockTransfer, err := store.FindStockTransferByID(&stocktransfer.ID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, stocktransferProduct := range savedStockTransfer.Products {
+		for _, serial := range stocktransferProduct.SerialNumbers {
+			err = store.InsertProductSerialHistory(&ProductSerialHistory{
+				Date:          savedStockTransfer.Date,
+				ProductID:     stocktransferProduct.ProductID,
+				ProductName:   stocktransferProduct.Name,
+				SerialNumber:  serial,
+				WarehouseID:   savedStockTransfer.FromWarehouseID,
+				WarehouseCode: savedStockTransfer.FromWarehouseCode,
+				Quantity:      -1,
+				ReferenceType: "stock_transfer",
+				ReferenceID:   savedStockTransfer.ID,
+				ReferenceCode: savedStockTransfer.Code,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = store.InsertProductSerialHistory(&ProductSerialHistory{
+				Date:          savedStockTransfer.Date,
+				ProductID:     stocktransferProduct.ProductID,
+				ProductName:   stocktransferProduct.Name,
+				SerialNumber:  serial,
+				WarehouseID:   savedStockTransfer.ToWarehouseID,
+				WarehouseCode: savedStockTransfer.ToWarehouseCode,
+				Quantity:      1,
+				ReferenceType: "stock_transfer",
+				ReferenceID:   savedStockTransfer.ID,
+				ReferenceCode: savedStockTransfer.Code,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetProductsSerials takes the serials returned to the vendor out of stock.
+func (purchaseReturn *PurchaseReturn) SetProductsSerials() error {
+	store, err := FindStoreByID(purchaseReturn.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	err = store.ClearProductSerialHistory(&purchaseReturn.ID)
+	if err != nil {
+		return err
+	}
+
+	savedPurchaseReturn, err := store.FindPurchaseReturnByID(&purchaseReturn.ID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, purchaseReturnProduct := range savedPurchaseReturn.Products {
+		if !purchaseReturnProduct.Selected {
+			continue
+		}
+
+		for _, serial := range purchaseReturnProduct.SerialNumbers {
+			err = store.InsertProductSerialHistory(&ProductSerialHistory{
+				Date:          savedPurchaseReturn.Date,
+				ProductID:     purchaseReturnProduct.ProductID,
+				ProductName:   purchaseReturnProduct.Name,
+				SerialNumber:  serial,
+				WarehouseID:   purchaseReturnProduct.WarehouseID,
+				WarehouseCode: purchaseReturnProduct.WarehouseCode,
+				Quantity:      -1,
+				ReferenceType: "purchase_return",
+				ReferenceID:   savedPurchaseReturn.ID,
+				ReferenceCode: savedPurchaseReturn.Code,
+				PartyID:       savedPurchaseReturn.VendorID,
+				PartyName:     savedPurchaseReturn.VendorName,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetProductsSerials puts the returned serials back into stock.
+func (salesreturn *SalesReturn) SetProductsSerials() error {
+	store, err := FindStoreByID(salesreturn.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	err = store.ClearProductSerialHistory(&salesreturn.ID)
+	if err != nil {
+		return err
+	}
+
+	savedSalesReturn, err := store.FindSalesReturnByID(&salesreturn.ID, bson.M{})
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if savedSalesReturn.Deleted {
+		return nil
+	}
+
+	for _, salesreturnProduct := range savedSalesReturn.Products {
+		if !salesreturnProduct.Selected {
+			continue
+		}
+
+		for _, serial := range salesreturnProduct.SerialNumbers {
+			err = store.InsertProductSerialHistory(&ProductSerialHistory{
+				Date:          savedSalesReturn.Date,
+				ProductID:     salesreturnProduct.ProductID,
+				ProductName:   salesreturnProduct.Name,
+				SerialNumber:  serial,
+				WarehouseID:   salesreturnProduct.WarehouseID,
+				WarehouseCode: salesreturnProduct.WarehouseCode,
+				Quantity:      1,
+				ReferenceType: "sales_return",
+				ReferenceID:   savedSalesReturn.ID,
+				ReferenceCode: savedSalesReturn.Code,
+				OrderID:       savedSalesReturn.OrderID,
+				PartyID:       savedSalesReturn.CustomerID,
+				PartyName:     savedSalesReturn.CustomerName,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
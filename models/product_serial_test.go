@@ -0,0 +1,116 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func makeSerialMovement(serial string, warehouseID *primitive.ObjectID, qty float64, refType string, day int) ProductSerialHistory {
+	d := time.Date(2026, 1, day, 0, 0, 0, 0, time.UTC)
+	return ProductSerialHistory{
+		SerialNumber:  serial,
+		WarehouseID:   warehouseID,
+		Quantity:      qty,
+		ReferenceType: refType,
+		Date:          &d,
+	}
+}
+
+// ── ValidateSerialNumbers ────────────────────────────────────────────────────
+
+func TestValidateSerialNumbers_Valid(t *testing.T) {
+	if msg := ValidateSerialNumbers([]string{"IMEI1", "IMEI2"}, 2); msg != "" {
+		t.Errorf("msg = %q, want empty", msg)
+	}
+}
+
+func TestValidateSerialNumbers_CountMismatch(t *testing.T) {
+	if msg := ValidateSerialNumbers([]string{"IMEI1"}, 2); msg == "" {
+		t.Errorf("expected error for 1 serial on quantity 2")
+	}
+}
+
+func TestValidateSerialNumbers_FractionalQuantity(t *testing.T) {
+	if msg := ValidateSerialNumbers([]string{"IMEI1"}, 1.5); msg == "" {
+		t.Errorf("expected error for fractional quantity")
+	}
+}
+
+func TestValidateSerialNumbers_Duplicate(t *testing.T) {
+	if msg := ValidateSerialNumbers([]string{"IMEI1", " IMEI1"}, 2); msg == "" {
+		t.Errorf("expected error for duplicate serial")
+	}
+}
+
+func TestValidateSerialNumbers_Empty(t *testing.T) {
+	if msg := ValidateSerialNumbers([]string{"IMEI1", "  "}, 2); msg == "" {
+		t.Errorf("expected error for blank serial")
+	}
+}
+
+// ── SerialStatusFromHistory ──────────────────────────────────────────────────
+
+func TestSerialStatusFromHistory_PurchasedThenSold(t *testing.T) {
+	history := []ProductSerialHistory{
+		makeSerialMovement("S1", nil, -1, "sales", 2),
+		makeSerialMovement("S1", nil, 1, "purchase", 1),
+	}
+	serials := SerialStatusFromHistory(history)
+	if len(serials) != 1 {
+		t.Fatalf("len(serials) = %v, want 1", len(serials))
+	}
+	if serials[0].Status != "sold" {
+		t.Errorf("Status = %v, want sold", serials[0].Status)
+	}
+	if serials[0].LastReferenceType != "sales" {
+		t.Errorf("LastReferenceType = %v, want sales", serials[0].LastReferenceType)
+	}
+}
+
+func TestSerialStatusFromHistory_ReturnedIsInStock(t *testing.T) {
+	history := []ProductSerialHistory{
+		makeSerialMovement("S1", nil, 1, "purchase", 1),
+		makeSerialMovement("S1", nil, -1, "sales", 2),
+		makeSerialMovement("S1", nil, 1, "sales_return", 3),
+	}
+	serials := SerialStatusFromHistory(history)
+	if len(serials) != 1 || serials[0].Status != "in_stock" {
+		t.Errorf("serials = %v, want S1 in_stock", serials)
+	}
+}
+
+func TestSerialStatusFromHistory_ReturnedToVendor(t *testing.T) {
+	history := []ProductSerialHistory{
+		makeSerialMovement("S1", nil, 1, "purchase", 1),
+		makeSerialMovement("S1", nil, -1, "purchase_return", 2),
+		makeSerialMovement("S2", nil, 1, "purchase", 1),
+	}
+	serials := SerialStatusFromHistory(history)
+	if len(serials) != 2 {
+		t.Fatalf("len(serials) = %v, want 2", len(serials))
+	}
+	if serials[0].SerialNumber != "S1" || serials[0].Status != "returned" {
+		t.Errorf("serials[0] = %+v, want S1 returned", serials[0])
+	}
+	if serials[1].Status != "in_stock" {
+		t.Errorf("serials[1] = %+v, want S2 in_stock", serials[1])
+	}
+}
+
+func TestSerialStatusFromHistory_TransferredToWarehouse(t *testing.T) {
+	warehouseID := primitive.NewObjectID()
+	history := []ProductSerialHistory{
+		makeSerialMovement("S1", nil, 1, "purchase", 1),
+		makeSerialMovement("S1", nil, -1, "stock_transfer", 2),
+		makeSerialMovement("S1", &warehouseID, 1, "stock_transfer", 2),
+	}
+	serials := SerialStatusFromHistory(history)
+	if len(serials) != 1 || serials[0].Status != "in_stock" {
+		t.Fatalf("serials = %v, want S1 in_stock", serials)
+	}
+	if serials[0].WarehouseID == nil || *serials[0].WarehouseID != warehouseID {
+		t.Errorf("WarehouseID = %v, want %v", serials[0].WarehouseID, warehouseID)
+	}
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProductUnit : an alternative unit of measure of a product (e.g. carton of 12 pieces).
+// ConversionFactor is the number of base units (Product.Unit) in one of this unit.
+type ProductUnit struct {
+	Name                      string  `bson:"name" json:"name"`
+	ConversionFactor          float64 `bson:"conversion_factor" json:"conversion_factor"`
+	BarCode                   string  `bson:"bar_code,omitempty" json:"bar_code,omitempty"`
+	RetailUnitPrice           float64 `bson:"retail_unit_price,omitempty" json:"retail_unit_price,omitempty"`
+	RetailUnitPriceWithVAT    float64 `bson:"retail_unit_price_with_vat,omitempty" json:"retail_unit_price_with_vat,omitempty"`
+	WholesaleUnitPrice        float64 `bson:"wholesale_unit_price,omitempty" json:"wholesale_unit_price,omitempty"`
+	WholesaleUnitPriceWithVAT float64 `bson:"wholesale_unit_price_with_vat,omitempty" json:"wholesale_unit_price_with_vat,omitempty"`
+	PurchaseUnitPrice         float64 `bson:"purchase_unit_price,omitempty" json:"purchase_unit_price,omitempty"`
+	PurchaseUnitPriceWithVAT  float64 `bson:"purchase_unit_price_with_vat,omitempty" json:"purchase_unit_price_with_vat,omitempty"`
+}
+
+// FindUnit returns the unit of measure with the given name, or nil.
+func (product *Product) FindUnit(name string) *ProductUnit {
+	name = strings.TrimSpace(name)
+	for i := range product.Units {
+		if strings.EqualFold(product.Units[i].Name, name) {
+			return &product.Units[i]
+		}
+	}
+	return nil
+}
+
+// GetUnitConversionFactor returns how many base units are in one of the given unit.
+// An empty unit or the product's base unit converts 1:1.
+func (product *Product) GetUnitConversionFactor(unitName string) (float64, error) {
+	unitName = strings.TrimSpace(unitName)
+	if unitName == "" || strings.EqualFold(unitName, strings.TrimSpace(product.Unit)) {
+		return 1, nil
+	}
+
+	unit := product.FindUnit(unitName)
+	if unit == nil {
+		if len(product.Units) == 0 {
+			// Products without a unit table keep the legacy free-text unit.
+			return 1, nil
+		}
+		return 0, errors.New("Invalid unit \"" + unitName + "\" for product " + product.Name)
+	}
+
+	return unit.ConversionFactor, nil
+}
+
+// ToBaseQuantity converts a line quantity to base units.
+func ToBaseQuantity(quantity float64, conversionFactor float64) float64 {
+	if conversionFactor <= 0 {
+		conversionFactor = 1
+	}
+	return RoundTo8Decimals(quantity * conversionFactor)
+}
+
+// ToBaseUnitPrice converts a per-line-unit price to a per-base-unit price.
+func ToBaseUnitPrice(unitPrice float64, conversionFactor float64) float64 {
+	if conversionFactor <= 0 {
+		conversionFactor = 1
+	}
+	return RoundTo8Decimals(unitPrice / conversionFactor)
+}
+
+// ValidateUnits checks the unit-of-measure table of a product.
+func (product *Product) ValidateUnits(errs map[string]string) {
+	names := map[string]bool{}
+	barCodes := map[string]bool{}
+
+	for i := range product.Units {
+		product.Units[i].Name = strings.TrimSpace(product.Units[i].Name)
+		product.Units[i].BarCode = strings.TrimSpace(product.Units[i].BarCode)
+		unit := product.Units[i]
+		key := strings.ToLower(unit.Name)
+
+		if unit.Name == "" {
+			errs["units_name_"+strconv.Itoa(i)] = "Unit name is required"
+		} else if strings.EqualFold(unit.Name, strings.TrimSpace(product.Unit)) {
+			errs["units_name_"+strconv.Itoa(i)] = "Unit should be different from the base unit"
+		} else if names[key] {
+			errs["units_name_"+strconv.Itoa(i)] = "Duplicate unit: " + unit.Name
+		}
+		names[key] = true
+
+		if unit.ConversionFactor <= 0 {
+			errs["units_conversion_factor_"+strconv.Itoa(i)] = "Conversion factor should be greater than zero"
+		}
+
+		if unit.BarCode != "" {
+			if barCodes[unit.BarCode] || unit.BarCode == product.BarCode {
+				errs["units_bar_code_"+strconv.Itoa(i)] = "Duplicate barcode: " + unit.BarCode
+			}
+			barCodes[unit.BarCode] = true
+		}
+	}
+}
+
+// resolveUnitConversionFactor looks up the conversion factor of a line unit.
+func (store *Store) resolveUnitConversionFactor(productID *primitive.ObjectID, unitName string) (float64, error) {
+	product, err := store.FindProductByID(productID, bson.M{"id": 1, "name": 1, "unit": 1, "units": 1})
+	if err != nil {
+		return 0, err
+	}
+	return product.GetUnitConversionFactor(unitName)
+}
+
+// convertToBaseUnit rewrites a history line booked in an alternative unit so that
+// quantity, unit and unit prices are expressed in the product's base unit.
+func (store *Store) convertToBaseUnit(productID *primitive.ObjectID, conversionFactor float64, quantity *float64, unit *string, unitPrices ...*float64) error {
+	if conversionFactor <= 0 || conversionFactor == 1 {
+		return nil
+	}
+
+	product, err := store.FindProductByID(productID, bson.M{"id": 1, "unit": 1})
+	if err != nil {
+		return err
+	}
+
+	*quantity = ToBaseQuantity(*quantity, conversionFactor)
+	*unit = product.Unit
+	for _, unitPrice := range unitPrices {
+		*unitPrice = ToBaseUnitPrice(*unitPrice, conversionFactor)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"testing"
+)
+
+func makeUnitProduct() Product {
+	return Product{
+		Name: "Water Bottle",
+		Unit: "Piece",
+		Units: []ProductUnit{
+			{Name: "Carton", ConversionFactor: 12, BarCode: "CARTON-1"},
+			{Name: "Pack", ConversionFactor: 6},
+		},
+	}
+}
+
+// ── GetUnitConversionFactor ──────────────────────────────────────────────────
+
+func TestGetUnitConversionFactor_BaseUnit(t *testing.T) {
+	p := makeUnitProduct()
+	factor, err := p.GetUnitConversionFactor("piece")
+	if err != nil || factor != 1 {
+		t.Errorf("factor = %v, err = %v, want 1", factor, err)
+	}
+}
+
+func TestGetUnitConversionFactor_EmptyUnit(t *testing.T) {
+	p := makeUnitProduct()
+	factor, err := p.GetUnitConversionFactor("")
+	if err != nil || factor != 1 {
+		t.Errorf("factor = %v, err = %v, want 1", factor, err)
+	}
+}
+
+func TestGetUnitConversionFactor_AlternativeUnit(t *testing.T) {
+	p := makeUnitProduct()
+	factor, err := p.GetUnitConversionFactor("Carton")
+	if err != nil || factor != 12 {
+		t.Errorf("factor = %v, err = %v, want 12", factor, err)
+	}
+}
+
+func TestGetUnitConversionFactor_UnknownUnit(t *testing.T) {
+	p := makeUnitProduct()
+	if _, err := p.GetUnitConversionFactor("Pallet"); err == nil {
+		t.Errorf("expected error for unknown unit")
+	}
+}
+
+func TestGetUnitConversionFactor_LegacyFreeTextUnit(t *testing.T) {
+	p := Product{Name: "Bolt", Unit: "PCS"}
+	factor, err := p.GetUnitConversionFactor("Nos")
+	if err != nil || factor != 1 {
+		t.Errorf("factor = %v, err = %v, want 1", factor, err)
+	}
+}
+
+// ── ToBaseQuantity / ToBaseUnitPrice ─────────────────────────────────────────
+
+func TestToBaseQuantity(t *testing.T) {
+	if got := ToBaseQuantity(2, 12); got != 24 {
+		t.Errorf("ToBaseQuantity = %v, want 24", got)
+	}
+	if got := ToBaseQuantity(3, 0); got != 3 {
+		t.Errorf("ToBaseQuantity with no factor = %v, want 3", got)
+	}
+}
+
+func TestToBaseUnitPrice(t *testing.T) {
+	if got := ToBaseUnitPrice(120, 12); got != 10 {
+		t.Errorf("ToBaseUnitPrice = %v, want 10", got)
+	}
+}
+
+// ── ValidateUnits ────────────────────────────────────────────────────────────
+
+func TestValidateUnits_Valid(t *testing.T) {
+	p := makeUnitProduct()
+	errs := map[string]string{}
+	p.ValidateUnits(errs)
+	if len(errs) != 0 {
+		t.Errorf("errs = %v, want none", errs)
+	}
+}
+
+func TestValidateUnits_Invalid(t *testing.T) {
+	p := Product{
+		Unit:    "Piece",
+		BarCode: "BASE-1",
+		Units: []ProductUnit{
+			{Name: "piece", ConversionFactor: 1},
+			{Name: "Carton", ConversionFactor: 0, BarCode: "BASE-1"},
+			{Name: "carton", ConversionFactor: 12},
+		},
+	}
+	errs := map[string]string{}
+	p.ValidateUnits(errs)
+	for _, key := range []string{"units_name_0", "units_conversion_factor_1", "units_bar_code_1", "units_name_2"} {
+		if _, ok := errs[key]; !ok {
+			t.Errorf("errs[%q] missing, got %v", key, errs)
+		}
+	}
+}
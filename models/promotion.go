@@ -0,0 +1,869 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	PromotionTypeBuyXGetY   = "buy_x_get_y"
+	PromotionTypePercentage = "percentage"
+	PromotionTypeFixed      = "fixed"
+)
+
+// Promotion : a discount rule applied to sales and quotation lines.
+// buy_x_get_y gives GetQuantity units free for every BuyQuantity units bought,
+// percentage takes DiscountPercent off the unit price and fixed spreads
+// DiscountAmount over the eligible lines. A promotion with a CouponCode only
+// applies when the document carries that code.
+type Promotion struct {
+	ID                    primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	Name                  string               `bson:"name" json:"name"`
+	Type                  string               `bson:"type" json:"type"`
+	CouponCode            string               `bson:"coupon_code,omitempty" json:"coupon_code,omitempty"`
+	ProductIDs            []primitive.ObjectID `bson:"product_ids,omitempty" json:"product_ids,omitempty"`
+	CategoryIDs           []primitive.ObjectID `bson:"category_ids,omitempty" json:"category_ids,omitempty"`
+	BuyQuantity           float64              `bson:"buy_quantity,omitempty" json:"buy_quantity,omitempty"`
+	GetQuantity           float64              `bson:"get_quantity,omitempty" json:"get_quantity,omitempty"`
+	DiscountPercent       float64              `bson:"discount_percent,omitempty" json:"discount_percent,omitempty"`
+	DiscountAmount        float64              `bson:"discount_amount,omitempty" json:"discount_amount,omitempty"`
+	MinOrderAmount        float64              `bson:"min_order_amount,omitempty" json:"min_order_amount,omitempty"`
+	StartAt               *time.Time           `bson:"start_at,omitempty" json:"start_at,omitempty"`
+	StartAtStr            string               `json:"start_at_str,omitempty" bson:"-"`
+	EndAt                 *time.Time           `bson:"end_at,omitempty" json:"end_at,omitempty"`
+	EndAtStr              string               `json:"end_at_str,omitempty" bson:"-"`
+	UsageLimit            int64                `bson:"usage_limit" json:"usage_limit"`
+	UsageLimitPerCustomer int64                `bson:"usage_limit_per_customer" json:"usage_limit_per_customer"`
+	UsageCount            int64                `bson:"-" json:"usage_count"`
+	StoreID               *primitive.ObjectID  `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName             string               `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted               bool                 `bson:"deleted,omitempty" json:"deleted,omitempty"`
+	DeletedBy             *primitive.ObjectID  `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt             *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt             *time.Time           `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt             *time.Time           `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy             *primitive.ObjectID  `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy             *primitive.ObjectID  `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName         string               `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName         string               `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+	DeletedByName         string               `json:"deleted_by_name,omitempty" bson:"deleted_by_name,omitempty"`
+}
+
+// PromotionLine : the part of a sales line the promotion engine looks at.
+type PromotionLine struct {
+	ProductID   primitive.ObjectID
+	CategoryIDs []primitive.ObjectID
+	Quantity    float64
+	UnitPrice   float64
+}
+
+// PromotionLineDiscount : the promotion picked for a line and its unit discount.
+type PromotionLineDiscount struct {
+	Promotion    *Promotion
+	UnitDiscount float64
+}
+
+// IsActiveOn tells if the promotion runs at the given time for the given coupon code.
+func (promotion *Promotion) IsActiveOn(at time.Time, couponCode string) bool {
+	if promotion.Deleted {
+		return false
+	}
+	if promotion.StartAt != nil && at.Before(*promotion.StartAt) {
+		return false
+	}
+	if promotion.EndAt != nil && at.After(*promotion.EndAt) {
+		return false
+	}
+	if promotion.CouponCode != "" && !strings.EqualFold(promotion.CouponCode, strings.TrimSpace(couponCode)) {
+		return false
+	}
+	return true
+}
+
+// IsEligible tells if the promotion covers the line's product.
+// A promotion without products and categories covers every product.
+func (promotion *Promotion) IsEligible(line PromotionLine) bool {
+	if len(promotion.ProductIDs) == 0 && len(promotion.CategoryIDs) == 0 {
+		return true
+	}
+	for _, productID := range promotion.ProductIDs {
+		if productID == line.ProductID {
+			return true
+		}
+	}
+	for _, categoryID := range promotion.CategoryIDs {
+		for _, lineCategoryID := range line.CategoryIDs {
+			if categoryID == lineCategoryID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unitDiscounts returns the unit discount the promotion gives to each line.
+func (promotion *Promotion) unitDiscounts(lines []PromotionLine) []float64 {
+	discounts := make([]float64, len(lines))
+
+	eligibleTotal := 0.0
+	for _, line := range lines {
+		if promotion.IsEligible(line) && line.Quantity > 0 {
+			eligibleTotal += line.Quantity * line.UnitPrice
+		}
+	}
+
+	for i, line := range lines {
+		if !promotion.IsEligible(line) || line.Quantity <= 0 || line.UnitPrice <= 0 {
+			continue
+		}
+
+		switch promotion.Type {
+		case PromotionTypePercentage:
+			discounts[i] = line.UnitPrice * (promotion.DiscountPercent / 100)
+		case PromotionTypeBuyXGetY:
+			group := promotion.BuyQuantity + promotion.GetQuantity
+			if promotion.BuyQuantity <= 0 || promotion.GetQuantity <= 0 {
+				continue
+			}
+			freeQuantity := math.Floor(line.Quantity/group) * promotion.GetQuantity
+			discounts[i] = (freeQuantity * line.UnitPrice) / line.Quantity
+		case PromotionTypeFixed:
+			if eligibleTotal <= 0 {
+				continue
+			}
+			amount := math.Min(promotion.DiscountAmount, eligibleTotal)
+			lineShare := amount * (line.Quantity * line.UnitPrice) / eligibleTotal
+			discounts[i] = lineShare / line.Quantity
+		}
+
+		discounts[i] = RoundTo4Decimals(math.Min(discounts[i], line.UnitPrice))
+	}
+
+	return discounts
+}
+
+// EvaluatePromotions picks the best promotion for every line. Promotions don't stack:
+// each line gets the one promotion with the highest unit discount.
+func EvaluatePromotions(promotions []Promotion, lines []PromotionLine, couponCode string, at time.Time) []PromotionLineDiscount {
+	results := make([]PromotionLineDiscount, len(lines))
+
+	orderTotal := 0.0
+	for _, line := range lines {
+		orderTotal += line.Quantity * line.UnitPrice
+	}
+
+	for i := range promotions {
+		promotion := &promotions[i]
+		if !promotion.IsActiveOn(at, couponCode) {
+			continue
+		}
+		if promotion.MinOrderAmount > 0 && orderTotal < promotion.MinOrderAmount {
+			continue
+		}
+
+		for lineIndex, unitDiscount := range promotion.unitDiscounts(lines) {
+			if unitDiscount > results[lineIndex].UnitDiscount {
+				results[lineIndex] = PromotionLineDiscount{Promotion: promotion, UnitDiscount: unitDiscount}
+			}
+		}
+	}
+
+	return results
+}
+
+// promotionLineFields points to the discount fields of a sales or quotation line.
+type promotionLineFields struct {
+	promotionID                *(*primitive.ObjectID)
+	promotionName              *string
+	unitPrice                  float64
+	unitDiscount               *float64
+	unitDiscountWithVAT        *float64
+	unitDiscountPercent        *float64
+	unitDiscountPercentWithVAT *float64
+}
+
+// set writes the promotion result to the line. A line that lost its promotion
+// gets its discount cleared; lines without promotions keep manual discounts.
+func (fields promotionLineFields) set(result PromotionLineDiscount, vatPercent float64) {
+	if result.Promotion == nil {
+		if *fields.promotionID != nil {
+			*fields.promotionID = nil
+			*fields.promotionName = ""
+			*fields.unitDiscount = 0
+			*fields.unitDiscountWithVAT = 0
+			*fields.unitDiscountPercent = 0
+			*fields.unitDiscountPercentWithVAT = 0
+		}
+		return
+	}
+
+	*fields.promotionID = &result.Promotion.ID
+	*fields.promotionName = result.Promotion.Name
+	*fields.unitDiscount = result.UnitDiscount
+	*fields.unitDiscountWithVAT = RoundTo4Decimals(result.UnitDiscount * (1 + (vatPercent / 100)))
+	*fields.unitDiscountPercent = 0
+	if fields.unitPrice > 0 {
+		*fields.unitDiscountPercent = RoundTo2Decimals((result.UnitDiscount / fields.unitPrice) * 100)
+	}
+	*fields.unitDiscountPercentWithVAT = *fields.unitDiscountPercent
+}
+
+// GetActivePromotions returns the promotions that run at the given time and are
+// still under their usage limits for the customer.
+// excludeOrderID leaves the order being edited out of the usage counts.
+func (store *Store) GetActivePromotions(at time.Time, customerID *primitive.ObjectID, excludeOrderID *primitive.ObjectID) (promotions []Promotion, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("promotion")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"deleted": bson.M{"$ne": true},
+		"$and": []bson.M{
+			{"$or": []bson.M{{"start_at": nil}, {"start_at": bson.M{"$lte": at}}}},
+			{"$or": []bson.M{{"end_at": nil}, {"end_at": bson.M{"$gte": at}}}},
+		},
+	}
+
+	cur, err := collection.Find(ctx, filter)
+	if err != nil {
+		return promotions, errors.New("Error fetching promotions:" + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		promotion := Promotion{}
+		err = cur.Decode(&promotion)
+		if err != nil {
+			return promotions, errors.New("Cursor decode error:" + err.Error())
+		}
+
+		withinLimits, err := store.IsPromotionWithinUsageLimits(&promotion, customerID, excludeOrderID)
+		if err != nil {
+			return promotions, err
+		}
+		if withinLimits {
+			promotions = append(promotions, promotion)
+		}
+	}
+
+	return promotions, nil
+}
+
+// GetPromotionUsageCount counts the sales that used the promotion.
+func (store *Store) GetPromotionUsageCount(promotionID *primitive.ObjectID, customerID *primitive.ObjectID, excludeOrderID *primitive.ObjectID) (count int64, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("order")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"products.promotion_id": promotionID,
+		"deleted":               bson.M{"$ne": true},
+	}
+	if customerID != nil && !customerID.IsZero() {
+		filter["customer_id"] = customerID
+	}
+	if excludeOrderID != nil && !excludeOrderID.IsZero() {
+		filter["_id"] = bson.M{"$ne": excludeOrderID}
+	}
+
+	return collection.CountDocuments(ctx, filter)
+}
+
+// IsPromotionWithinUsageLimits tells if one more sale may use the promotion.
+func (store *Store) IsPromotionWithinUsageLimits(promotion *Promotion, customerID *primitive.ObjectID, excludeOrderID *primitive.ObjectID) (bool, error) {
+	if promotion.UsageLimit > 0 {
+		count, err := store.GetPromotionUsageCount(&promotion.ID, nil, excludeOrderID)
+		if err != nil {
+			return false, err
+		}
+		if count >= promotion.UsageLimit {
+			return false, nil
+		}
+	}
+
+	if promotion.UsageLimitPerCustomer > 0 && customerID != nil && !customerID.IsZero() {
+		count, err := store.GetPromotionUsageCount(&promotion.ID, customerID, excludeOrderID)
+		if err != nil {
+			return false, err
+		}
+		if count >= promotion.UsageLimitPerCustomer {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// promotionLines loads the product categories the engine needs for each line.
+func (store *Store) promotionLines(productIDs []primitive.ObjectID, quantities []float64, unitPrices []float64) []PromotionLine {
+	lines := make([]PromotionLine, len(productIDs))
+	for i, productID := range productIDs {
+		lines[i] = PromotionLine{ProductID: productID, Quantity: quantities[i], UnitPrice: unitPrices[i]}
+		if productID.IsZero() {
+			continue
+		}
+		product, err := store.FindProductByID(&productID, bson.M{"id": 1, "category_id": 1})
+		if err != nil {
+			continue
+		}
+		for _, categoryID := range product.CategoryID {
+			if categoryID != nil {
+				lines[i].CategoryIDs = append(lines[i].CategoryIDs, *categoryID)
+			}
+		}
+	}
+	return lines
+}
+
+func promotionDate(date *time.Time) time.Time {
+	if date != nil {
+		return *date
+	}
+	return time.Now()
+}
+
+// NeedsPromotions tells if the promotions of the order are evaluated again on save:
+// when it is priced again or its coupon changed. An invoice reported to ZATCA never is.
+func (order *Order) NeedsPromotions(orderOld *Order) bool {
+	if order.NeedsPricing(orderOld) {
+		return true
+	}
+	return !orderOld.Zatca.ReportingPassed && order.CouponCode != orderOld.CouponCode
+}
+
+// NeedsPromotions tells if the promotions of the quotation are evaluated again on save.
+func (quotation *Quotation) NeedsPromotions(quotationOld *Quotation) bool {
+	if quotation.NeedsPricing(quotationOld) {
+		return true
+	}
+	return !quotationOld.ReportedToZatca && quotation.CouponCode != quotationOld.CouponCode
+}
+
+// ApplyPromotions evaluates the store's promotions on the order lines and records
+// the promotion used on each line.
+func (order *Order) ApplyPromotions() error {
+	if order.StoreID == nil {
+		return nil
+	}
+
+	store, err := FindStoreByID(order.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	at := promotionDate(order.Date)
+	promotions, err := store.GetActivePromotions(at, order.CustomerID, &order.ID)
+	if err != nil {
+		return err
+	}
+
+	productIDs := []primitive.ObjectID{}
+	quantities := []float64{}
+	unitPrices := []float64{}
+	for _, product := range order.Products {
+		productIDs = append(productIDs, product.ProductID)
+		quantities = append(quantities, product.Quantity)
+		unitPrices = append(unitPrices, product.UnitPrice)
+	}
+
+	vatPercent := store.VatPercent
+	if order.VatPercent != nil {
+		vatPercent = *order.VatPercent
+	}
+
+	results := EvaluatePromotions(promotions, store.promotionLines(productIDs, quantities, unitPrices), order.CouponCode, at)
+	for i := range order.Products {
+		line := &order.Products[i]
+		promotionLineFields{
+			promotionID:                &line.PromotionID,
+			promotionName:              &line.PromotionName,
+			unitPrice:                  line.UnitPrice,
+			unitDiscount:               &line.UnitDiscount,
+			unitDiscountWithVAT:        &line.UnitDiscountWithVAT,
+			unitDiscountPercent:        &line.UnitDiscountPercent,
+			unitDiscountPercentWithVAT: &line.UnitDiscountPercentWithVAT,
+		}.set(results[i], vatPercent)
+	}
+
+	return nil
+}
+
+// ApplyPromotions evaluates the store's promotions on the quotation lines.
+func (quotation *Quotation) ApplyPromotions() error {
+	if quotation.StoreID == nil {
+		return nil
+	}
+
+	store, err := FindStoreByID(quotation.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	at := promotionDate(quotation.Date)
+	promotions, err := store.GetActivePromotions(at, quotation.CustomerID, nil)
+	if err != nil {
+		return err
+	}
+
+	productIDs := []primitive.ObjectID{}
+	quantities := []float64{}
+	unitPrices := []float64{}
+	for _, product := range quotation.Products {
+		productIDs = append(productIDs, product.ProductID)
+		quantities = append(quantities, product.Quantity)
+		unitPrices = append(unitPrices, product.UnitPrice)
+	}
+
+	vatPercent := store.VatPercent
+	if quotation.VatPercent != nil {
+		vatPercent = *quotation.VatPercent
+	}
+
+	results := EvaluatePromotions(promotions, store.promotionLines(productIDs, quantities, unitPrices), quotation.CouponCode, at)
+	for i := range quotation.Products {
+		line := &quotation.Products[i]
+		promotionLineFields{
+			promotionID:                &line.PromotionID,
+			promotionName:              &line.PromotionName,
+			unitPrice:                  line.UnitPrice,
+			unitDiscount:               &line.UnitDiscount,
+			unitDiscountWithVAT:        &line.UnitDiscountWithVAT,
+			unitDiscountPercent:        &line.UnitDiscountPercent,
+			unitDiscountPercentWithVAT: &line.UnitDiscountPercentWithVAT,
+		}.set(results[i], vatPercent)
+	}
+
+	return nil
+}
+
+// ValidatePromotions checks that the promotions on the order lines still run and
+// are under their usage limits.
+func (order *Order) ValidatePromotions(store *Store, errs map[string]string) error {
+	at := promotionDate(order.Date)
+	checked := map[primitive.ObjectID]bool{}
+
+	for i, product := range order.Products {
+		if product.PromotionID == nil || checked[*product.PromotionID] {
+			continue
+		}
+		checked[*product.PromotionID] = true
+
+		promotion, err := store.FindPromotionByID(product.PromotionID, bson.M{})
+		if err != nil {
+			errs["promotion_"+strconv.Itoa(i)] = "Invalid promotion:" + product.PromotionID.Hex()
+			continue
+		}
+
+		if !promotion.IsActiveOn(at, order.CouponCode) {
+			errs["promotion_"+strconv.Itoa(i)] = "Promotion " + promotion.Name + " is not active"
+			continue
+		}
+
+		withinLimits, err := store.IsPromotionWithinUsageLimits(promotion, order.CustomerID, &order.ID)
+		if err != nil {
+			return err
+		}
+		if !withinLimits {
+			errs["promotion_"+strconv.Itoa(i)] = "Promotion " + promotion.Name + " reached its usage limit"
+		}
+	}
+
+	return nil
+}
+
+// ReversePromotionDiscounts gives returned lines the same unit discount their
+// order line got from a promotion, so the discount is reversed in proportion
+// to the quantity returned.
+func (salesReturn *SalesReturn) ReversePromotionDiscounts() error {
+	if salesReturn.OrderID == nil || salesReturn.StoreID == nil {
+		return nil
+	}
+
+	store, err := FindStoreByID(salesReturn.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	order, err := store.FindOrderByID(salesReturn.OrderID, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	for i := range salesReturn.Products {
+		returnProduct := &salesReturn.Products[i]
+		for j := len(order.Products) - 1; j >= 0; j-- {
+			orderProduct := order.Products[j]
+			if orderProduct.ProductID != returnProduct.ProductID {
+				continue
+			}
+
+			returnProduct.PromotionID = orderProduct.PromotionID
+			returnProduct.PromotionName = orderProduct.PromotionName
+			if orderProduct.PromotionID != nil {
+				returnProduct.UnitDiscount = orderProduct.UnitDiscount
+				returnProduct.UnitDiscountWithVAT = orderProduct.UnitDiscountWithVAT
+				returnProduct.UnitDiscountPercent = orderProduct.UnitDiscountPercent
+				returnProduct.UnitDiscountPercentWithVAT = orderProduct.UnitDiscountPercentWithVAT
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+func (promotion *Promotion) UpdateForeignLabelFields() error {
+	if promotion.StoreID != nil {
+		store, err := FindStoreByID(promotion.StoreID, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		promotion.StoreName = store.Name
+	}
+
+	if promotion.CreatedBy != nil {
+		createdByUser, err := FindUserByID(promotion.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		promotion.CreatedByName = createdByUser.Name
+	}
+
+	if promotion.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(promotion.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		promotion.UpdatedByName = updatedByUser.Name
+	}
+
+	if promotion.DeletedBy != nil && !promotion.DeletedBy.IsZero() {
+		deletedByUser, err := FindUserByID(promotion.DeletedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		promotion.DeletedByName = deletedByUser.Name
+	}
+
+	return nil
+}
+
+func (store *Store) SearchPromotion(w http.ResponseWriter, r *http.Request) (promotions []Promotion, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+	ParseDeletedFilter(r, &criterias)
+
+	ParseTextSearch(r, &criterias, "search[name]", "name")
+	ParseTextSearch(r, &criterias, "search[coupon_code]", "coupon_code")
+
+	keys, ok := r.URL.Query()["search[type]"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.SearchBy["type"] = keys[0]
+	}
+
+	keys, ok = r.URL.Query()["search[active]"]
+	if ok && keys[0] == "1" {
+		now := time.Now()
+		criterias.SearchBy["$and"] = []bson.M{
+			{"$or": []bson.M{{"start_at": nil}, {"start_at": bson.M{"$lte": now}}}},
+			{"$or": []bson.M{{"end_at": nil}, {"end_at": bson.M{"$gte": now}}}},
+		}
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("promotion")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	keys, ok = r.URL.Query()["select"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.Select = ParseSelectString(keys[0])
+	}
+
+	if criterias.Select != nil {
+		findOptions.SetProjection(criterias.Select)
+	}
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return promotions, criterias, errors.New("Error fetching promotions:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return promotions, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		promotion := Promotion{}
+		err = cur.Decode(&promotion)
+		if err != nil {
+			return promotions, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		promotion.UsageCount, _ = store.GetPromotionUsageCount(&promotion.ID, nil, nil)
+		promotions = append(promotions, promotion)
+	}
+
+	return promotions, criterias, nil
+}
+
+func (promotion *Promotion) Validate(w http.ResponseWriter, r *http.Request, scenario string) (errs map[string]string) {
+	errs = make(map[string]string)
+	promotion.Name = strings.TrimSpace(promotion.Name)
+	promotion.CouponCode = strings.ToUpper(strings.TrimSpace(promotion.CouponCode))
+
+	store, err := FindStoreByID(promotion.StoreID, bson.M{})
+	if err != nil {
+		errs["store_id"] = "invalid store id"
+		w.WriteHeader(http.StatusBadRequest)
+		return errs
+	}
+
+	if scenario == "update" {
+		if promotion.ID.IsZero() {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = "ID is required"
+			return errs
+		}
+		exists, err := store.IsPromotionExists(&promotion.ID)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = err.Error()
+			return errs
+		}
+
+		if !exists {
+			errs["id"] = "Invalid Promotion:" + promotion.ID.Hex()
+		}
+	}
+
+	if govalidator.IsNull(promotion.Name) {
+		errs["name"] = "Name is required"
+	}
+
+	switch promotion.Type {
+	case PromotionTypeBuyXGetY:
+		if promotion.BuyQuantity <= 0 {
+			errs["buy_quantity"] = "Buy quantity should be greater than zero"
+		}
+		if promotion.GetQuantity <= 0 {
+			errs["get_quantity"] = "Get quantity should be greater than zero"
+		}
+	case PromotionTypePercentage:
+		if promotion.DiscountPercent <= 0 || promotion.DiscountPercent > 100 {
+			errs["discount_percent"] = "Discount percent should be between 0 and 100"
+		}
+	case PromotionTypeFixed:
+		if promotion.DiscountAmount <= 0 {
+			errs["discount_amount"] = "Discount amount should be greater than zero"
+		}
+	default:
+		errs["type"] = "Type should be buy_x_get_y, percentage or fixed"
+	}
+
+	if promotion.UsageLimit < 0 {
+		errs["usage_limit"] = "Usage limit should not be negative"
+	}
+
+	if promotion.UsageLimitPerCustomer < 0 {
+		errs["usage_limit_per_customer"] = "Usage limit per customer should not be negative"
+	}
+
+	if !govalidator.IsNull(promotion.StartAtStr) {
+		const shortForm = "2006-01-02T15:04:05Z07:00"
+		startAt, err := time.Parse(shortForm, promotion.StartAtStr)
+		if err != nil {
+			errs["start_at_str"] = "Invalid date format"
+		} else {
+			promotion.StartAt = &startAt
+		}
+	}
+
+	if !govalidator.IsNull(promotion.EndAtStr) {
+		const shortForm = "2006-01-02T15:04:05Z07:00"
+		endAt, err := time.Parse(shortForm, promotion.EndAtStr)
+		if err != nil {
+			errs["end_at_str"] = "Invalid date format"
+		} else {
+			promotion.EndAt = &endAt
+		}
+	}
+
+	if promotion.StartAt != nil && promotion.EndAt != nil && promotion.EndAt.Before(*promotion.StartAt) {
+		errs["end_at_str"] = "End date should be after the start date"
+	}
+
+	for i, productID := range promotion.ProductIDs {
+		exists, err := store.IsProductExists(&productID)
+		if err != nil || !exists {
+			errs["product_id_"+strconv.Itoa(i)] = "Invalid product:" + productID.Hex()
+		}
+	}
+
+	if promotion.CouponCode != "" {
+		exists, err := promotion.IsCouponCodeExists()
+		if err != nil {
+			errs["coupon_code"] = err.Error()
+		} else if exists {
+			errs["coupon_code"] = "Coupon code is already in use"
+		}
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+func (promotion *Promotion) IsCouponCodeExists() (exists bool, err error) {
+	collection := db.GetDB("store_" + promotion.StoreID.Hex()).Collection("promotion")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"coupon_code": promotion.CouponCode,
+		"deleted":     bson.M{"$ne": true},
+	}
+	if !promotion.ID.IsZero() {
+		filter["_id"] = bson.M{"$ne": promotion.ID}
+	}
+
+	count, err := collection.CountDocuments(ctx, filter)
+	return (count > 0), err
+}
+
+func (promotion *Promotion) Insert() error {
+	collection := db.GetDB("store_" + promotion.StoreID.Hex()).Collection("promotion")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	promotion.ID = primitive.NewObjectID()
+
+	err := promotion.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, &promotion)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (promotion *Promotion) Update() error {
+	collection := db.GetDB("store_" + promotion.StoreID.Hex()).Collection("promotion")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := promotion.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": promotion.ID},
+		bson.M{"$set": promotion},
+		updateOptions,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (promotion *Promotion) DeletePromotion(tokenClaims TokenClaims) (err error) {
+	collection := db.GetDB("store_" + promotion.StoreID.Hex()).Collection("promotion")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	promotion.Deleted = true
+	promotion.DeletedBy = &userID
+	now := time.Now()
+	promotion.DeletedAt = &now
+
+	err = promotion.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": promotion.ID},
+		bson.M{"$set": promotion},
+		updateOptions,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (store *Store) FindPromotionByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (promotion *Promotion, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("promotion")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{"_id": ID}, findOneOptions).
+		Decode(&promotion)
+	if err != nil {
+		return nil, err
+	}
+
+	promotion.UsageCount, _ = store.GetPromotionUsageCount(&promotion.ID, nil, nil)
+
+	return promotion, err
+}
+
+func (store *Store) IsPromotionExists(ID *primitive.ObjectID) (exists bool, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("promotion")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	count := int64(0)
+
+	count, err = collection.CountDocuments(ctx, bson.M{
+		"_id": ID,
+	})
+
+	return (count > 0), err
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	promoProductA  = primitive.NewObjectID()
+	promoProductB  = primitive.NewObjectID()
+	promoCategoryX = primitive.NewObjectID()
+)
+
+func makePromotionLines() []PromotionLine {
+	return []PromotionLine{
+		{ProductID: promoProductA, CategoryIDs: []primitive.ObjectID{promoCategoryX}, Quantity: 3, UnitPrice: 10},
+		{ProductID: promoProductB, Quantity: 1, UnitPrice: 30},
+	}
+}
+
+// ── Promotion types ─────────────────────────────────────────────────────────
+
+func TestEvaluatePromotions_BuyTwoGetOne(t *testing.T) {
+	promotions := []Promotion{{
+		ID: primitive.NewObjectID(), Type: PromotionTypeBuyXGetY,
+		ProductIDs: []primitive.ObjectID{promoProductA}, BuyQuantity: 2, GetQuantity: 1,
+	}}
+
+	results := EvaluatePromotions(promotions, makePromotionLines(), "", time.Now())
+	if results[0].Promotion == nil || RoundTo2Decimals(results[0].UnitDiscount*3) != 10 {
+		t.Errorf("line 0 = %+v, want one unit free", results[0])
+	}
+	if results[1].Promotion != nil {
+		t.Errorf("line 1 = %+v, want no promotion", results[1])
+	}
+}
+
+func TestEvaluatePromotions_CategoryPercentage(t *testing.T) {
+	promotions := []Promotion{{
+		ID: primitive.NewObjectID(), Type: PromotionTypePercentage,
+		CategoryIDs: []primitive.ObjectID{promoCategoryX}, DiscountPercent: 20,
+	}}
+
+	results := EvaluatePromotions(promotions, makePromotionLines(), "", time.Now())
+	if results[0].UnitDiscount != 2 {
+		t.Errorf("unit discount = %v, want 2", results[0].UnitDiscount)
+	}
+	if results[1].Promotion != nil {
+		t.Errorf("line outside the category got %+v", results[1])
+	}
+}
+
+func TestEvaluatePromotions_FixedCouponSpreadByLineTotal(t *testing.T) {
+	promotions := []Promotion{{
+		ID: primitive.NewObjectID(), Type: PromotionTypeFixed, CouponCode: "SAVE12", DiscountAmount: 12,
+	}}
+
+	if results := EvaluatePromotions(promotions, makePromotionLines(), "", time.Now()); results[0].Promotion != nil {
+		t.Error("coupon promotion applied without the coupon code")
+	}
+
+	results := EvaluatePromotions(promotions, makePromotionLines(), "save12", time.Now())
+	// Line totals are 30 and 30, so each line gets 6 off.
+	if results[0].UnitDiscount != 2 || results[1].UnitDiscount != 6 {
+		t.Errorf("unit discounts = %v / %v, want 2 / 6", results[0].UnitDiscount, results[1].UnitDiscount)
+	}
+}
+
+// ── Rules ────────────────────────────────────────────────────────────────────
+
+func TestEvaluatePromotions_BestPromotionWins(t *testing.T) {
+	small := Promotion{ID: primitive.NewObjectID(), Type: PromotionTypePercentage, DiscountPercent: 5}
+	big := Promotion{ID: primitive.NewObjectID(), Type: PromotionTypePercentage, DiscountPercent: 15}
+
+	results := EvaluatePromotions([]Promotion{small, big}, makePromotionLines(), "", time.Now())
+	if results[0].Promotion == nil || results[0].Promotion.ID != big.ID {
+		t.Errorf("line 0 promotion = %+v, want the 15%% one", results[0].Promotion)
+	}
+}
+
+func TestEvaluatePromotions_DateWindowAndMinOrder(t *testing.T) {
+	end := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	expired := Promotion{ID: primitive.NewObjectID(), Type: PromotionTypePercentage, DiscountPercent: 10, EndAt: &end}
+	minOrder := Promotion{ID: primitive.NewObjectID(), Type: PromotionTypePercentage, DiscountPercent: 10, MinOrderAmount: 100}
+
+	results := EvaluatePromotions([]Promotion{expired, minOrder}, makePromotionLines(), "", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	for i, result := range results {
+		if result.Promotion != nil {
+			t.Errorf("line %d got %+v, want none", i, result.Promotion)
+		}
+	}
+}
+
+// ── UpliftPercent ────────────────────────────────────────────────────────────
+
+func TestUpliftPercent(t *testing.T) {
+	if got := UpliftPercent(150, 100); got != 50 {
+		t.Errorf("UpliftPercent(150, 100) = %v, want 50", got)
+	}
+	if got := UpliftPercent(10, 0); got != 0 {
+		t.Errorf("UpliftPercent(10, 0) = %v, want 0", got)
+	}
+}
+
+// ── NeedsPromotions ──────────────────────────────────────────────────────────
+
+func TestOrderNeedsPromotions_CouponChange(t *testing.T) {
+	orderOld := makePricedOrder()
+	order := makePricedOrder()
+	if order.NeedsPromotions(&orderOld) {
+		t.Error("an unchanged order should keep its discounts")
+	}
+
+	order.CouponCode = "EID10"
+	if !order.NeedsPromotions(&orderOld) {
+		t.Error("an order whose coupon changed should be evaluated again")
+	}
+
+	orderOld.Zatca.ReportingPassed = true
+	if order.NeedsPromotions(&orderOld) {
+		t.Error("an invoice reported to ZATCA should never be evaluated again")
+	}
+}
@@ -33,6 +33,7 @@ type PurchaseProduct struct {
 	Quantity                   float64             `json:"quantity" bson:"quantity"`
 	QuantityReturned           float64             `json:"quantity_returned" bson:"quantity_returned"`
 	Unit                       string              `bson:"unit" json:"unit"`
+	UnitConversionFactor       float64             `bson:"unit_conversion_factor,omitempty" json:"unit_conversion_factor,omitempty"`
 	PurchaseUnitPrice          float64             `bson:"purchase_unit_price" json:"purchase_unit_price"`
 	PurchaseUnitPriceWithVAT   float64             `bson:"purchase_unit_price_with_vat" json:"purchase_unit_price_with_vat"`
 	RetailUnitPrice            float64             `bson:"retail_unit_price,omitempty" json:"retail_unit_price,omitempty"`
@@ -48,6 +49,10 @@ type PurchaseProduct struct {
 	ExpectedWholesaleLoss      float64             `bson:"wholesale_loss" json:"wholesale_loss"`
 	ExpectedRetailLoss         float64             `bson:"retail_loss" json:"retail_loss"`
 	IsService                  bool                `bson:"is_service" json:"is_service"`
+	LotNumber                  string              `bson:"lot_number,omitempty" json:"lot_number,omitempty"`
+	ExpiryDate                 *time.Time          `bson:"expiry_date,omitempty" json:"expiry_date,omitempty"`
+	ExpiryDateStr              string              `bson:"-" json:"expiry_date_str,omitempty"`
+	SerialNumbers              []string            `bson:"serial_numbers,omitempty" json:"serial_numbers,omitempty"`
 }
 
 // Purchase : Purchase structure
@@ -130,6 +135,10 @@ type Purchase struct {
 	PaymentsInput     []PurchasePayment   `bson:"-" json:"payments_input"`
 	PaymentsCount     int64               `bson:"payments_count" json:"payments_count"`
 	PaymentMethods    []string            `json:"payment_methods" bson:"payment_methods"`
+	Currency          string              `bson:"currency,omitempty" json:"currency,omitempty"`
+	ExchangeRate      float64             `bson:"exchange_rate,omitempty" json:"exchange_rate,omitempty"`
+	CostCenterID      *primitive.ObjectID `json:"cost_center_id,omitempty" bson:"cost_center_id,omitempty"`
+	CostCenterName    string              `json:"cost_center_name,omitempty" bson:"cost_center_name,omitempty"`
 	Remarks           string              `bson:"remarks" json:"remarks"`
 	Phone             string              `bson:"phone" json:"phone"`
 	VatNo             string              `bson:"vat_no" json:"vat_no"`
@@ -342,12 +351,19 @@ func (store *Store) GetReturnedAmountByPurchaseID(purchaseID primitive.ObjectID)
 }
 
 func (model *Purchase) AddPayments() error {
+	store, err := FindStoreByID(model.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
 	for _, payment := range model.PaymentsInput {
 		purchasePayment := PurchasePayment{
 			PurchaseID:    &model.ID,
 			PurchaseCode:  model.Code,
 			Amount:        payment.Amount,
 			Method:        payment.Method,
+			Currency:      model.Currency,
+			ExchangeRate:  store.ResolvePaymentExchangeRate(model.Currency, model.Date, model.ExchangeRate, payment.ExchangeRate, payment.Date),
 			Description:   payment.Description,
 			Date:          payment.Date,
 			CreatedAt:     model.CreatedAt,
@@ -384,6 +400,8 @@ func (model *Purchase) UpdatePayments() error {
 				PurchaseCode:  model.Code,
 				Amount:        payment.Amount,
 				Method:        payment.Method,
+				Currency:      model.Currency,
+				ExchangeRate:  store.ResolvePaymentExchangeRate(model.Currency, model.Date, model.ExchangeRate, payment.ExchangeRate, payment.Date),
 				Description:   payment.Description,
 				Date:          payment.Date,
 				CreatedAt:     &now,
@@ -410,6 +428,8 @@ func (model *Purchase) UpdatePayments() error {
 			purchasePayment.Date = payment.Date
 			purchasePayment.Amount = payment.Amount
 			purchasePayment.Method = payment.Method
+			purchasePayment.Currency = model.Currency
+			purchasePayment.ExchangeRate = store.ResolvePaymentExchangeRate(model.Currency, model.Date, model.ExchangeRate, payment.ExchangeRate, payment.Date)
 			purchasePayment.Description = payment.Description
 			purchasePayment.UpdatedAt = &now
 			purchasePayment.UpdatedBy = model.UpdatedBy
@@ -1268,6 +1288,10 @@ func (store *Store) SearchPurchase(w http.ResponseWriter, r *http.Request) (purc
 		return purchases, criterias, err
 	}
 
+	if err = ParseObjectIDListFilter(r, &criterias, "search[cost_center_id]", "cost_center_id"); err != nil {
+		return purchases, criterias, err
+	}
+
 	keys, ok = r.URL.Query()["search[status]"]
 	if ok && len(keys[0]) >= 1 {
 		statusList := strings.Split(keys[0], ",")
@@ -1444,6 +1468,19 @@ func (purchase *Purchase) Validate(
 		purchase.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, purchase.StoreID, "purchase", purchase.ID, purchase.Code, purchase.Date); message != "" {
+			errs["date_str"] = message
+		}
+
+		if field, message := store.ValidateDocumentCurrency(&purchase.Currency, &purchase.ExchangeRate, purchase.Date); message != "" {
+			errs[field] = message
+		}
+	}
+
+	if message := store.ValidateCostCenter(&purchase.CostCenterID, &purchase.CostCenterName); message != "" {
+		errs["cost_center_id"] = message
+	}
 
 	if !govalidator.IsNull(strings.TrimSpace(purchase.VatNo)) && !IsValidDigitNumber(strings.TrimSpace(purchase.VatNo), "15") {
 		errs["vat_no"] = "VAT No. should be 15 digits"
@@ -1515,6 +1552,10 @@ func (purchase *Purchase) Validate(
 			errs["payment_method_"+strconv.Itoa(index)] = "Payment method is required"
 		}
 
+		if payment.ExchangeRate < 0 {
+			errs["payment_exchange_rate_"+strconv.Itoa(index)] = "Exchange rate should be greater than zero"
+		}
+
 	} //end for
 
 	if len(purchase.Products) == 0 {
@@ -1546,6 +1587,13 @@ func (purchase *Purchase) Validate(
 				if err == nil && productObj.IsService {
 					errs["product_id_"+strconv.Itoa(i)] = "\"" + productObj.Name + "\" is a service and cannot be added to a purchase order"
 				}
+
+				conversionFactor, err := store.resolveUnitConversionFactor(&product.ProductID, product.Unit)
+				if err != nil {
+					errs["unit_"+strconv.Itoa(i)] = err.Error()
+				} else {
+					purchase.Products[i].UnitConversionFactor = conversionFactor
+				}
 			}
 		}
 
@@ -1573,6 +1621,25 @@ func (purchase *Purchase) Validate(
 			errs["purchase_unit_price_"+strconv.Itoa(i)] = "Purchase Unit Price is required"
 		}
 
+		if !govalidator.IsNull(strings.TrimSpace(product.ExpiryDateStr)) {
+			const shortForm = "2006-01-02T15:04:05Z07:00"
+			expiryDate, err := time.Parse(shortForm, product.ExpiryDateStr)
+			if err != nil {
+				expiryDate, err = time.Parse("2006-01-02", product.ExpiryDateStr)
+			}
+
+			if err != nil {
+				errs["expiry_date_str_"+strconv.Itoa(i)] = "Invalid date format"
+			} else {
+				purchase.Products[i].ExpiryDate = &expiryDate
+			}
+		}
+
+		purchase.Products[i].LotNumber = strings.TrimSpace(product.LotNumber)
+		if purchase.Products[i].ExpiryDate != nil && purchase.Products[i].LotNumber == "" {
+			errs["lot_number_"+strconv.Itoa(i)] = "Lot number is required when expiry date is given"
+		}
+
 		/*
 			if product.RetailUnitPrice == 0 {
 				errs["retail_unit_price_"+strconv.Itoa(i)] = "Retail Unit Price is required"
@@ -1584,6 +1651,13 @@ func (purchase *Purchase) Validate(
 
 	} //end for
 
+	if store != nil {
+		err = purchase.ValidateSerialNumbers(store, errs)
+		if err != nil {
+			errs["serial_numbers"] = err.Error()
+		}
+	}
+
 	if totalPayment > RoundTo2Decimals(purchase.NetTotal-purchase.CashDiscount) {
 		errs["total_payment"] = "Total payment should not exceed: " + fmt.Sprintf("%.02f", RoundTo2Decimals(purchase.NetTotal-purchase.CashDiscount)) + " (Net Total - Cash Discount)"
 		return
@@ -3689,6 +3763,12 @@ func (purchase *Purchase) CreateLedger() (ledger *Ledger, err error) {
 
 	}
 
+	journals, err = purchase.convertJournalsToBaseCurrency(store, journals, vendor, cashAccount, bankAccount)
+	if err != nil {
+		return nil, err
+	}
+	journals = TagJournalsWithCostCenter(journals, purchase.CostCenterID, purchase.CostCenterName)
+
 	ledger = &Ledger{
 		StoreID:        purchase.StoreID,
 		ReferenceID:    purchase.ID,
@@ -3707,6 +3787,57 @@ func (purchase *Purchase) CreateLedger() (ledger *Ledger, err error) {
 	return ledger, nil
 }
 
+// convertJournalsToBaseCurrency books a purchase kept in a foreign currency at its exchange rate, and
+// its cash and bank payments at theirs with the difference going to exchange gain or loss.
+func (purchase *Purchase) convertJournalsToBaseCurrency(
+	store *Store,
+	journals []Journal,
+	vendor *Vendor,
+	cashAccount *Account,
+	bankAccount *Account,
+) ([]Journal, error) {
+	if !store.IsForeignCurrency(purchase.Currency) {
+		return journals, nil
+	}
+
+	payments := []FXPayment{}
+	for _, payment := range purchase.Payments {
+		fxPayment := FXPayment{Date: payment.Date, Amount: payment.Amount, Rate: payment.ExchangeRate}
+		if payment.Method == "cash" {
+			fxPayment.AccountID = cashAccount.ID
+		} else if slices.Contains(BANK_PAYMENT_METHODS, payment.Method) {
+			fxPayment.AccountID = bankAccount.ID
+		} else {
+			continue
+		}
+		payments = append(payments, fxPayment)
+	}
+
+	referenceModel := "vendor"
+	var referenceID *primitive.ObjectID
+	var vendorVATNo *string
+	var vendorPhone *string
+	if vendor != nil {
+		referenceID = &vendor.ID
+		vendorVATNo = &vendor.VATNo
+		vendorPhone = &vendor.Phone
+	}
+
+	vendorAccount, err := store.CreateAccountIfNotExists(
+		purchase.StoreID,
+		referenceID,
+		&referenceModel,
+		resolveVendorAccountName(vendor),
+		vendorPhone,
+		vendorVATNo,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ConvertJournalsToBaseCurrency(journals, purchase.Currency, purchase.ExchangeRate, payments, &vendorAccount.ID)
+}
+
 func (purchase *Purchase) GetPayments() (models []PurchasePayment, err error) {
 	collection := db.GetDB("store_" + purchase.StoreID.Hex()).Collection("purchase_payment")
 	ctx := context.Background()
@@ -4226,6 +4357,10 @@ func (store *Store) BuildPurchaseCriterias(w http.ResponseWriter, r *http.Reques
 		return criterias, err
 	}
 
+	if err = ParseObjectIDListFilter(r, &criterias, "search[cost_center_id]", "cost_center_id"); err != nil {
+		return criterias, err
+	}
+
 	keys, ok = r.URL.Query()["search[status]"]
 	if ok && len(keys[0]) >= 1 {
 		statusList := strings.Split(keys[0], ",")
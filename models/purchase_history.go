@@ -464,6 +464,11 @@ func (purchase *Purchase) CreateProductsPurchaseHistory() error {
 
 		history.ID = primitive.NewObjectID()
 
+		err = store.convertToBaseUnit(&purchaseProduct.ProductID, purchaseProduct.UnitConversionFactor, &history.Quantity, &history.Unit, &history.UnitPrice, &history.UnitPriceWithVAT, &history.Discount)
+		if err != nil {
+			return err
+		}
+
 		_, err := collection.InsertOne(ctx, &history)
 		if err != nil {
 			return err
@@ -24,6 +24,8 @@ type PurchasePayment struct {
 	PurchaseCode     string              `json:"purchase_code" bson:"purchase_code"`
 	Amount           float64             `json:"amount" bson:"amount"`
 	Method           string              `json:"method" bson:"method"`
+	Currency         string              `json:"currency,omitempty" bson:"currency,omitempty"`
+	ExchangeRate     float64             `json:"exchange_rate,omitempty" bson:"exchange_rate,omitempty"`
 	Description      *string             `json:"description,omitempty" bson:"description,omitempty"`
 	ReferenceType    string              `json:"reference_type" bson:"reference_type"`
 	ReferenceCode    string              `json:"reference_code" bson:"reference_code"`
@@ -245,6 +247,12 @@ func (purchasePayment *PurchasePayment) Validate(w http.ResponseWriter, r *http.
 		purchasePayment.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, purchasePayment.StoreID, "purchase_payment", purchasePayment.ID, purchasePayment.PurchaseCode, purchasePayment.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	if scenario == "update" {
 		if purchasePayment.ID.IsZero() {
 			w.WriteHeader(http.StatusBadRequest)
@@ -281,6 +289,13 @@ func (purchasePayment *PurchasePayment) Validate(w http.ResponseWriter, r *http.
 		errs["sales_return"] = "error finding sales return" + err.Error()
 	}
 
+	if purchasePayment.ExchangeRate < 0 {
+		errs["exchange_rate"] = "Exchange rate should be greater than zero"
+	} else if purchase != nil {
+		purchasePayment.Currency = purchase.Currency
+		purchasePayment.ExchangeRate = store.ResolvePaymentExchangeRate(purchase.Currency, purchase.Date, purchase.ExchangeRate, purchasePayment.ExchangeRate, purchasePayment.Date)
+	}
+
 	if purchasePayment.Amount > RoundTo2Decimals(purchase.NetTotal-purchase.CashDiscount) {
 		errs["amount"] = "Amount should not exceed: " + fmt.Sprintf("%.02f", RoundTo2Decimals(purchase.NetTotal-purchase.CashDiscount)) + " (Net Total - Cash Discount)"
 		return
@@ -33,6 +33,7 @@ type PurchaseReturnProduct struct {
 	PartNumber                     string              `bson:"part_number,omitempty" json:"part_number,omitempty"`
 	Quantity                       float64             `json:"quantity" bson:"quantity"`
 	Unit                           string              `bson:"unit,omitempty" json:"unit,omitempty"`
+	UnitConversionFactor           float64             `bson:"unit_conversion_factor,omitempty" json:"unit_conversion_factor,omitempty"`
 	PurchaseReturnUnitPrice        float64             `bson:"purchasereturn_unit_price,omitempty" json:"purchasereturn_unit_price,omitempty"`
 	PurchaseReturnUnitPriceWithVAT float64             `bson:"purchasereturn_unit_price_with_vat,omitempty" json:"purchasereturn_unit_price_with_vat,omitempty"`
 	UnitDiscount                   float64             `bson:"unit_discount" json:"unit_discount"`
@@ -41,6 +42,7 @@ type PurchaseReturnProduct struct {
 	UnitDiscountPercentWithVAT     float64             `bson:"unit_discount_percent_with_vat" json:"unit_discount_percent_with_vat"`
 	Selected                       bool                `bson:"selected" json:"selected"`
 	IsService                      bool                `bson:"is_service" json:"is_service"`
+	SerialNumbers                  []string            `bson:"serial_numbers,omitempty" json:"serial_numbers,omitempty"`
 }
 
 // PurchaseReturn : PurchaseReturn structure
@@ -113,6 +115,8 @@ type PurchaseReturn struct {
 	PaymentsInput          []PurchasePayment       `bson:"-" json:"payments_input"`
 	PaymentsCount          int64                   `bson:"payments_count" json:"payments_count"`
 	PaymentMethods         []string                `json:"payment_methods" bson:"payment_methods"`
+	Currency               string                  `bson:"currency,omitempty" json:"currency,omitempty"`
+	ExchangeRate           float64                 `bson:"exchange_rate,omitempty" json:"exchange_rate,omitempty"`
 	Remarks                string                  `bson:"remarks" json:"remarks"`
 	Phone                  string                  `bson:"phone" json:"phone"`
 	VatNo                  string                  `bson:"vat_no" json:"vat_no"`
@@ -325,6 +329,11 @@ func (purchaseReturn *PurchaseReturn) UpdatePaymentFromReceivablePayment(
 }
 
 func (model *PurchaseReturn) AddPayments() error {
+	store, err := FindStoreByID(model.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
 	for _, payment := range model.PaymentsInput {
 		purchaseReturnPayment := PurchaseReturnPayment{
 			PurchaseReturnID:   &model.ID,
@@ -333,6 +342,8 @@ func (model *PurchaseReturn) AddPayments() error {
 			PurchaseCode:       model.PurchaseCode,
 			Amount:             payment.Amount,
 			Method:             payment.Method,
+			Currency:           model.Currency,
+			ExchangeRate:       store.ResolvePaymentExchangeRate(model.Currency, model.Date, model.ExchangeRate, payment.ExchangeRate, payment.Date),
 			Description:        payment.Description,
 			Date:               payment.Date,
 			CreatedAt:          model.CreatedAt,
@@ -370,6 +381,8 @@ func (model *PurchaseReturn) UpdatePayments() error {
 				PurchaseCode:       model.PurchaseCode,
 				Amount:             payment.Amount,
 				Method:             payment.Method,
+				Currency:           model.Currency,
+				ExchangeRate:       store.ResolvePaymentExchangeRate(model.Currency, model.Date, model.ExchangeRate, payment.ExchangeRate, payment.Date),
 				Description:        payment.Description,
 				Date:               payment.Date,
 				CreatedAt:          &now,
@@ -395,6 +408,8 @@ func (model *PurchaseReturn) UpdatePayments() error {
 			purchaseReturnPayment.Date = payment.Date
 			purchaseReturnPayment.Amount = payment.Amount
 			purchaseReturnPayment.Method = payment.Method
+			purchaseReturnPayment.Currency = model.Currency
+			purchaseReturnPayment.ExchangeRate = store.ResolvePaymentExchangeRate(model.Currency, model.Date, model.ExchangeRate, payment.ExchangeRate, payment.Date)
 			purchaseReturnPayment.Description = payment.Description
 			purchaseReturnPayment.UpdatedAt = &now
 			purchaseReturnPayment.UpdatedBy = model.UpdatedBy
@@ -1330,9 +1345,14 @@ func (purchasereturn *PurchaseReturn) Validate(
 			errs["payment_method_"+strconv.Itoa(index)] = "Payment method is required"
 		}
 
+		if payment.ExchangeRate < 0 {
+			errs["payment_exchange_rate_"+strconv.Itoa(index)] = "Exchange rate should be greater than zero"
+		}
+
 	} //end for
 
 	purchasereturn.PurchaseCode = purchase.Code
+	purchasereturn.SetCurrencyFromPurchase(purchase)
 
 	if govalidator.IsNull(purchasereturn.DateStr) {
 		errs["date_str"] = "Date is required"
@@ -1345,6 +1365,12 @@ func (purchasereturn *PurchaseReturn) Validate(
 		purchasereturn.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, purchasereturn.StoreID, "purchase_return", purchasereturn.ID, purchasereturn.Code, purchasereturn.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	/*
 		if !govalidator.IsNull(purchasereturn.SignatureDateStr) {
 			const shortForm = "Jan 02 2006"
@@ -1468,6 +1494,7 @@ func (purchasereturn *PurchaseReturn) Validate(
 		//for _, purchaseProduct := range purchase.Products {
 		for i := len(purchase.Products) - 1; i >= 0; i-- {
 			if purchase.Products[i].ProductID == purchaseReturnProduct.ProductID {
+				purchasereturn.Products[index].UnitConversionFactor = purchase.Products[i].UnitConversionFactor
 
 				maxAllowedQuantity := 0.00
 				if scenario == "update" {
@@ -1508,6 +1535,11 @@ func (purchasereturn *PurchaseReturn) Validate(
 		}
 	}
 
+	err = purchasereturn.ValidateSerialNumbers(store, purchase, errs)
+	if err != nil {
+		errs["serial_numbers"] = err.Error()
+	}
+
 	if purchasereturn.VatPercent == nil {
 		errs["vat_percent"] = "VAT Percentage is required"
 	}
@@ -3535,6 +3567,22 @@ func (purchaseReturn *PurchaseReturn) CreateLedger() (ledger *Ledger, err error)
 
 	}
 
+	// a return belongs to the cost center of the purchase it reverses
+	if purchaseReturn.PurchaseID != nil && !purchaseReturn.PurchaseID.IsZero() {
+		purchase, err := store.FindPurchaseByID(purchaseReturn.PurchaseID, bson.M{"cost_center_id": 1, "cost_center_name": 1})
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+		if purchase != nil {
+			journals = TagJournalsWithCostCenter(journals, purchase.CostCenterID, purchase.CostCenterName)
+		}
+	}
+
+	journals, err = purchaseReturn.convertJournalsToBaseCurrency(store, journals, vendor, cashAccount, bankAccount)
+	if err != nil {
+		return nil, err
+	}
+
 	ledger = &Ledger{
 		StoreID:        purchaseReturn.StoreID,
 		ReferenceID:    purchaseReturn.ID,
@@ -3640,6 +3688,63 @@ func (purchaseReturn *PurchaseReturn) AdjustPayments() error {
 	return nil
 }
 
+// SetCurrencyFromPurchase keeps a return in the currency of the purchase it reverses, at the purchase's rate.
+func (purchaseReturn *PurchaseReturn) SetCurrencyFromPurchase(purchase *Purchase) {
+	purchaseReturn.Currency = purchase.Currency
+	purchaseReturn.ExchangeRate = purchase.ExchangeRate
+}
+
+// convertJournalsToBaseCurrency books a return of a purchase kept in a foreign currency at the purchase's
+// exchange rate, and its cash and bank refunds at theirs with the difference going to exchange gain or loss.
+func (purchaseReturn *PurchaseReturn) convertJournalsToBaseCurrency(
+	store *Store,
+	journals []Journal,
+	vendor *Vendor,
+	cashAccount *Account,
+	bankAccount *Account,
+) ([]Journal, error) {
+	if !store.IsForeignCurrency(purchaseReturn.Currency) {
+		return journals, nil
+	}
+
+	payments := []FXPayment{}
+	for _, payment := range purchaseReturn.Payments {
+		fxPayment := FXPayment{Date: payment.Date, Amount: payment.Amount, Rate: payment.ExchangeRate}
+		if payment.Method == "cash" {
+			fxPayment.AccountID = cashAccount.ID
+		} else if slices.Contains(BANK_PAYMENT_METHODS, payment.Method) {
+			fxPayment.AccountID = bankAccount.ID
+		} else {
+			continue
+		}
+		payments = append(payments, fxPayment)
+	}
+
+	referenceModel := "vendor"
+	var referenceID *primitive.ObjectID
+	var vendorVATNo *string
+	var vendorPhone *string
+	if vendor != nil {
+		referenceID = &vendor.ID
+		vendorVATNo = &vendor.VATNo
+		vendorPhone = &vendor.Phone
+	}
+
+	vendorAccount, err := store.CreateAccountIfNotExists(
+		purchaseReturn.StoreID,
+		referenceID,
+		&referenceModel,
+		resolveVendorAccountName(vendor),
+		vendorPhone,
+		vendorVATNo,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ConvertJournalsToBaseCurrency(journals, purchaseReturn.Currency, purchaseReturn.ExchangeRate, payments, &vendorAccount.ID)
+}
+
 func (purchaseReturn *PurchaseReturn) DoAccounting() error {
 	err := purchaseReturn.AdjustPayments()
 	if err != nil {
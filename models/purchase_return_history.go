@@ -454,6 +454,11 @@ func (purchaseReturn *PurchaseReturn) CreateProductsPurchaseReturnHistory() erro
 
 		history.ID = primitive.NewObjectID()
 
+		err = store.convertToBaseUnit(&purchaseReturnProduct.ProductID, purchaseReturnProduct.UnitConversionFactor, &history.Quantity, &history.Unit, &history.UnitPrice, &history.UnitPriceWithVAT, &history.Discount)
+		if err != nil {
+			return err
+		}
+
 		_, err := collection.InsertOne(ctx, &history)
 		if err != nil {
 			return err
@@ -28,6 +28,8 @@ type PurchaseReturnPayment struct {
 	PurchaseCode        string              `json:"purchase_code" bson:"purchase_code"`
 	Amount              float64             `json:"amount" bson:"amount"`
 	Method              string              `json:"method" bson:"method"`
+	Currency            string              `json:"currency,omitempty" bson:"currency,omitempty"`
+	ExchangeRate        float64             `json:"exchange_rate,omitempty" bson:"exchange_rate,omitempty"`
 	Description         *string             `json:"description,omitempty" bson:"description,omitempty"`
 	ReferenceType       string              `json:"reference_type" bson:"reference_type"`
 	ReferenceCode       string              `json:"reference_code" bson:"reference_code"`
@@ -282,6 +284,12 @@ func (purchasereturnPayment *PurchaseReturnPayment) Validate(w http.ResponseWrit
 		purchasereturnPayment.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, purchasereturnPayment.StoreID, "purchase_return_payment", purchasereturnPayment.ID, purchasereturnPayment.PurchaseReturnCode, purchasereturnPayment.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	if scenario == "update" {
 		if purchasereturnPayment.ID.IsZero() {
 			w.WriteHeader(http.StatusBadRequest)
@@ -318,6 +326,13 @@ func (purchasereturnPayment *PurchaseReturnPayment) Validate(w http.ResponseWrit
 		errs["sales_return"] = "error finding sales return" + err.Error()
 	}
 
+	if purchasereturnPayment.ExchangeRate < 0 {
+		errs["exchange_rate"] = "Exchange rate should be greater than zero"
+	} else if purchaseReturn != nil {
+		purchasereturnPayment.Currency = purchaseReturn.Currency
+		purchasereturnPayment.ExchangeRate = store.ResolvePaymentExchangeRate(purchaseReturn.Currency, purchaseReturn.Date, purchaseReturn.ExchangeRate, purchasereturnPayment.ExchangeRate, purchasereturnPayment.Date)
+	}
+
 	if purchasereturnPayment.Amount > RoundTo2Decimals(purchaseReturn.NetTotal-purchaseReturn.CashDiscount) {
 		errs["amount"] = "Amount should not exceed: " + fmt.Sprintf("%.02f", RoundTo2Decimals(purchaseReturn.NetTotal-purchaseReturn.CashDiscount)) + " (Net Total - Cash Discount)"
 		return
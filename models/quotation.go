@@ -40,101 +40,106 @@ type QuotationProduct struct {
 	PurchaseUnitPriceWithVAT float64             `bson:"purchase_unit_price_with_vat,omitempty" json:"purchase_unit_price_with_vat,omitempty"`
 	//Discount                 float64            `bson:"discount" json:"discount"`
 	//DiscountPercent          float64            `bson:"discount_percent" json:"discount_percent"`
-	UnitDiscount               float64 `bson:"unit_discount" json:"unit_discount"`
-	UnitDiscountWithVAT        float64 `bson:"unit_discount_with_vat" json:"unit_discount_with_vat"`
-	UnitDiscountPercent        float64 `bson:"unit_discount_percent" json:"unit_discount_percent"`
-	UnitDiscountPercentWithVAT float64 `bson:"unit_discount_percent_with_vat" json:"unit_discount_percent_with_vat"`
-	Profit                     float64 `bson:"profit" json:"profit"`
-	Loss                       float64 `bson:"loss" json:"loss"`
-	IsService                  bool    `bson:"is_service" json:"is_service"`
+	UnitDiscount               float64             `bson:"unit_discount" json:"unit_discount"`
+	UnitDiscountWithVAT        float64             `bson:"unit_discount_with_vat" json:"unit_discount_with_vat"`
+	UnitDiscountPercent        float64             `bson:"unit_discount_percent" json:"unit_discount_percent"`
+	UnitDiscountPercentWithVAT float64             `bson:"unit_discount_percent_with_vat" json:"unit_discount_percent_with_vat"`
+	Profit                     float64             `bson:"profit" json:"profit"`
+	Loss                       float64             `bson:"loss" json:"loss"`
+	IsService                  bool                `bson:"is_service" json:"is_service"`
+	PriceListID                *primitive.ObjectID `bson:"price_list_id,omitempty" json:"price_list_id,omitempty"`
+	PriceListName              string              `bson:"price_list_name,omitempty" json:"price_list_name,omitempty"`
+	PromotionID                *primitive.ObjectID `bson:"promotion_id,omitempty" json:"promotion_id,omitempty"`
+	PromotionName              string              `bson:"promotion_name,omitempty" json:"promotion_name,omitempty"`
 }
 
 // Quotation : Quotation structure
 type Quotation struct {
-	ID                       primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
-	Code                     string              `bson:"code,omitempty" json:"code,omitempty"`
-	Date                     *time.Time          `bson:"date,omitempty" json:"date,omitempty"`
-	DateStr                  string              `json:"date_str,omitempty" bson:"-"`
-	StoreID                  *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
-	Store                    *Store              `json:"store,omitempty"`
-	CustomerID               *primitive.ObjectID `json:"customer_id" bson:"customer_id"`
-	Customer                 *Customer           `json:"customer"  bson:"-" `
-	CustomerName             string              `json:"customer_name" bson:"customer_name"`
-	CustomerNameArabic       string              `json:"customer_name_arabic" bson:"customer_name_arabic"`
-	Products                 []QuotationProduct  `bson:"products,omitempty" json:"products,omitempty"`
-	DeliveredBy              *primitive.ObjectID `json:"delivered_by,omitempty" bson:"delivered_by,omitempty"`
-	DeliveredBySignatureID   *primitive.ObjectID `json:"delivered_by_signature_id,omitempty" bson:"delivered_by_signature_id,omitempty"`
-	DeliveredBySignatureName string              `json:"delivered_by_signature_name,omitempty" bson:"delivered_by_signature_name,omitempty"`
-	SignatureDate            *time.Time          `bson:"signature_date,omitempty" json:"signature_date,omitempty"`
-	SignatureDateStr         string              `json:"signature_date_str,omitempty"`
-	DeliveredByUser          *User               `json:"delivered_by_user,omitempty"`
-	DeliveredBySignature     *UserSignature      `json:"delivered_by_signature,omitempty"`
-	VatPercent               *float64            `bson:"vat_percent" json:"vat_percent"`
-	Discount                 float64             `bson:"discount" json:"discount"`
-	DiscountPercent          float64             `bson:"discount_percent" json:"discount_percent"`
-	DiscountWithVAT          float64             `bson:"discount_with_vat" json:"discount_with_vat"`
-	DiscountPercentWithVAT   float64             `bson:"discount_percent_with_vat" json:"discount_percent_with_vat"`
-	ReturnDiscountWithVAT    float64             `bson:"return_discount_with_vat" json:"return_discount_vat"`
-	ReturnDiscount           float64             `bson:"return_discount" json:"return_discount"`
-	ReturnCashDiscount       float64             `bson:"return_cash_discount" json:"return_cash_discount"`
-	ReturnCount              int64               `bson:"return_count" json:"return_count"`
-	Status                   string              `bson:"status,omitempty" json:"status,omitempty"`
-	TotalQuantity            float64             `bson:"total_quantity" json:"total_quantity"`
-	VatPrice                 float64             `bson:"vat_price" json:"vat_price"`
-	Total                    float64             `bson:"total" json:"total"`
-	TotalWithVAT             float64             `bson:"total_with_vat" json:"total_with_vat"`
-	NetTotal                 float64             `bson:"net_total" json:"net_total"`
-	ActualVatPrice           float64             `bson:"actual_vat_price" json:"actual_vat_price"`
-	ActualTotal              float64             `bson:"actual_total" json:"actual_total"`
-	ActualTotalWithVAT       float64             `bson:"actual_total_with_vat" json:"actual_total_with_vat"`
-	ActualNetTotal           float64             `bson:"actual_net_total" json:"actual_net_total"`
-	RoundingAmount           float64             `bson:"rounding_amount" json:"rounding_amount"`
-	AutoRoundingAmount       bool                `bson:"auto_rounding_amount" json:"auto_rounding_amount"`
-	Payments                 []QuotationPayment  `bson:"payments" json:"payments"`
-	PaymentsInput            []QuotationPayment  `bson:"-" json:"payments_input"`
-	PaymentsCount            int64               `bson:"payments_count" json:"payments_count"`
-	PaymentStatus            string              `bson:"payment_status" json:"payment_status"`
-	PaymentMethods           []string            `json:"payment_methods" bson:"payment_methods"`
-	CashDiscount             float64             `bson:"cash_discount" json:"cash_discount"`
-	TotalPaymentReceived     float64             `bson:"total_payment_received" json:"total_payment_received"`
-	BalanceAmount            float64             `bson:"balance_amount" json:"balance_amount"`
-	ShippingOrHandlingFees   float64             `bson:"shipping_handling_fees" json:"shipping_handling_fees"`
-	Profit                   float64             `bson:"profit" json:"profit"`
-	NetProfit                float64             `bson:"net_profit" json:"net_profit"`
-	Loss                     float64             `bson:"loss" json:"loss"`
-	NetLoss                  float64             `bson:"net_loss" json:"net_loss"`
-	Deleted                  bool                `bson:"deleted,omitempty" json:"deleted,omitempty"`
-	DeletedBy                *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
-	DeletedByUser            *User               `json:"deleted_by_user,omitempty"`
-	DeletedAt                *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
-	CreatedAt                *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
-	UpdatedAt                *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
-	CreatedBy                *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
-	UpdatedBy                *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
-	CreatedByUser            *User               `json:"created_by_user,omitempty"`
-	UpdatedByUser            *User               `json:"updated_by_user,omitempty"`
-	StoreName                string              `json:"store_name,omitempty" bson:"store_name,omitempty"`
-	DeliveredByName          string              `json:"delivered_by_name,omitempty" bson:"delivered_by_name,omitempty"`
-	CreatedByName            string              `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
-	UpdatedByName            string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
-	DeletedByName            string              `json:"deleted_by_name,omitempty" bson:"deleted_by_name,omitempty"`
-	ValidityDays             *int64              `bson:"validity_days,omitempty" json:"validity_days,omitempty"`
-	DeliveryDays             *int64              `bson:"delivery_days,omitempty" json:"delivery_days,omitempty"`
-	Remarks                  string              `bson:"remarks" json:"remarks"`
-	Type                     string              `json:"type" bson:"type"`
-	Phone                    string              `bson:"phone" json:"phone"`
-	VatNo                    string              `bson:"vat_no" json:"vat_no"`
-	Address                  string              `bson:"address" json:"address"`
-	OrderID                  *primitive.ObjectID `json:"order_id" bson:"order_id"`
-	OrderCode                *string             `json:"order_code" bson:"order_code"`
-	ReportedToZatca          bool                `bson:"reported_to_zatca" json:"reported_to_zatca"`
-	ReportedToZatcaAt        *time.Time          `bson:"reported_to_zatca_at" json:"reported_to_zatca_at"`
-	ReturnAmount             float64             `bson:"return_amount" json:"return_amount"`
-	Commission               float64             `bson:"commission" json:"commission"`
-	CommissionPaymentMethod  string              `bson:"commission_payment_method" json:"commission_payment_method"`
-	VehicleID                *primitive.ObjectID `json:"vehicle_id,omitempty" bson:"vehicle_id,omitempty"`
-	VehicleSnapshot          *VehicleSnapshot    `json:"vehicle_snapshot,omitempty" bson:"vehicle_snapshot,omitempty"`
-	KmDriven                 float64             `json:"km_driven" bson:"km_driven"`
+	ID                       primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	Code                     string               `bson:"code,omitempty" json:"code,omitempty"`
+	Date                     *time.Time           `bson:"date,omitempty" json:"date,omitempty"`
+	DateStr                  string               `json:"date_str,omitempty" bson:"-"`
+	StoreID                  *primitive.ObjectID  `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	Store                    *Store               `json:"store,omitempty"`
+	CustomerID               *primitive.ObjectID  `json:"customer_id" bson:"customer_id"`
+	Customer                 *Customer            `json:"customer"  bson:"-" `
+	CustomerName             string               `json:"customer_name" bson:"customer_name"`
+	CustomerNameArabic       string               `json:"customer_name_arabic" bson:"customer_name_arabic"`
+	Products                 []QuotationProduct   `bson:"products,omitempty" json:"products,omitempty"`
+	DeliveredBy              *primitive.ObjectID  `json:"delivered_by,omitempty" bson:"delivered_by,omitempty"`
+	DeliveredBySignatureID   *primitive.ObjectID  `json:"delivered_by_signature_id,omitempty" bson:"delivered_by_signature_id,omitempty"`
+	DeliveredBySignatureName string               `json:"delivered_by_signature_name,omitempty" bson:"delivered_by_signature_name,omitempty"`
+	SignatureDate            *time.Time           `bson:"signature_date,omitempty" json:"signature_date,omitempty"`
+	SignatureDateStr         string               `json:"signature_date_str,omitempty"`
+	DeliveredByUser          *User                `json:"delivered_by_user,omitempty"`
+	DeliveredBySignature     *UserSignature       `json:"delivered_by_signature,omitempty"`
+	VatPercent               *float64             `bson:"vat_percent" json:"vat_percent"`
+	Discount                 float64              `bson:"discount" json:"discount"`
+	DiscountPercent          float64              `bson:"discount_percent" json:"discount_percent"`
+	DiscountWithVAT          float64              `bson:"discount_with_vat" json:"discount_with_vat"`
+	DiscountPercentWithVAT   float64              `bson:"discount_percent_with_vat" json:"discount_percent_with_vat"`
+	CouponCode               string               `bson:"coupon_code,omitempty" json:"coupon_code,omitempty"`
+	ReturnDiscountWithVAT    float64              `bson:"return_discount_with_vat" json:"return_discount_vat"`
+	ReturnDiscount           float64              `bson:"return_discount" json:"return_discount"`
+	ReturnCashDiscount       float64              `bson:"return_cash_discount" json:"return_cash_discount"`
+	ReturnCount              int64                `bson:"return_count" json:"return_count"`
+	Status                   string               `bson:"status,omitempty" json:"status,omitempty"`
+	TotalQuantity            float64              `bson:"total_quantity" json:"total_quantity"`
+	VatPrice                 float64              `bson:"vat_price" json:"vat_price"`
+	Total                    float64              `bson:"total" json:"total"`
+	TotalWithVAT             float64              `bson:"total_with_vat" json:"total_with_vat"`
+	NetTotal                 float64              `bson:"net_total" json:"net_total"`
+	ActualVatPrice           float64              `bson:"actual_vat_price" json:"actual_vat_price"`
+	ActualTotal              float64              `bson:"actual_total" json:"actual_total"`
+	ActualTotalWithVAT       float64              `bson:"actual_total_with_vat" json:"actual_total_with_vat"`
+	ActualNetTotal           float64              `bson:"actual_net_total" json:"actual_net_total"`
+	RoundingAmount           float64              `bson:"rounding_amount" json:"rounding_amount"`
+	AutoRoundingAmount       bool                 `bson:"auto_rounding_amount" json:"auto_rounding_amount"`
+	Payments                 []QuotationPayment   `bson:"payments" json:"payments"`
+	PaymentsInput            []QuotationPayment   `bson:"-" json:"payments_input"`
+	PaymentsCount            int64                `bson:"payments_count" json:"payments_count"`
+	PaymentStatus            string               `bson:"payment_status" json:"payment_status"`
+	PaymentMethods           []string             `json:"payment_methods" bson:"payment_methods"`
+	CashDiscount             float64              `bson:"cash_discount" json:"cash_discount"`
+	TotalPaymentReceived     float64              `bson:"total_payment_received" json:"total_payment_received"`
+	BalanceAmount            float64              `bson:"balance_amount" json:"balance_amount"`
+	ShippingOrHandlingFees   float64              `bson:"shipping_handling_fees" json:"shipping_handling_fees"`
+	Profit                   float64              `bson:"profit" json:"profit"`
+	NetProfit                float64              `bson:"net_profit" json:"net_profit"`
+	Loss                     float64              `bson:"loss" json:"loss"`
+	NetLoss                  float64              `bson:"net_loss" json:"net_loss"`
+	Deleted                  bool                 `bson:"deleted,omitempty" json:"deleted,omitempty"`
+	DeletedBy                *primitive.ObjectID  `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedByUser            *User                `json:"deleted_by_user,omitempty"`
+	DeletedAt                *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt                *time.Time           `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt                *time.Time           `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy                *primitive.ObjectID  `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy                *primitive.ObjectID  `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByUser            *User                `json:"created_by_user,omitempty"`
+	UpdatedByUser            *User                `json:"updated_by_user,omitempty"`
+	StoreName                string               `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	DeliveredByName          string               `json:"delivered_by_name,omitempty" bson:"delivered_by_name,omitempty"`
+	CreatedByName            string               `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName            string               `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+	DeletedByName            string               `json:"deleted_by_name,omitempty" bson:"deleted_by_name,omitempty"`
+	ValidityDays             *int64               `bson:"validity_days,omitempty" json:"validity_days,omitempty"`
+	DeliveryDays             *int64               `bson:"delivery_days,omitempty" json:"delivery_days,omitempty"`
+	Remarks                  string               `bson:"remarks" json:"remarks"`
+	Type                     string               `json:"type" bson:"type"`
+	Phone                    string               `bson:"phone" json:"phone"`
+	VatNo                    string               `bson:"vat_no" json:"vat_no"`
+	Address                  string               `bson:"address" json:"address"`
+	OrderID                  *primitive.ObjectID  `json:"order_id" bson:"order_id"`
+	OrderCode                *string              `json:"order_code" bson:"order_code"`
+	ReportedToZatca          bool                 `bson:"reported_to_zatca" json:"reported_to_zatca"`
+	ReportedToZatcaAt        *time.Time           `bson:"reported_to_zatca_at" json:"reported_to_zatca_at"`
+	ReturnAmount             float64              `bson:"return_amount" json:"return_amount"`
+	Commission               float64              `bson:"commission" json:"commission"`
+	CommissionPaymentMethod  string               `bson:"commission_payment_method" json:"commission_payment_method"`
+	VehicleID                *primitive.ObjectID  `json:"vehicle_id,omitempty" bson:"vehicle_id,omitempty"`
+	VehicleSnapshot          *VehicleSnapshot     `json:"vehicle_snapshot,omitempty" bson:"vehicle_snapshot,omitempty"`
+	KmDriven                 float64              `json:"km_driven" bson:"km_driven"`
 	RepairJobID              *primitive.ObjectID  `json:"repair_job_id,omitempty" bson:"repair_job_id,omitempty"`
 	RepairJobIDs             []primitive.ObjectID `json:"repair_job_ids,omitempty" bson:"repair_job_ids,omitempty"`
 }
@@ -0,0 +1,1034 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	RecurringDocumentExpense            = "expense"
+	RecurringDocumentCustomerDeposit    = "customer_deposit"
+	RecurringDocumentCustomerWithdrawal = "customer_withdrawal"
+	RecurringDocumentJournalVoucher     = "journal_voucher"
+
+	RecurringFrequencyMonthly   = "monthly"
+	RecurringFrequencyQuarterly = "quarterly"
+	RecurringFrequencyCron      = "cron"
+
+	RecurringOccurrenceCreated = "created"
+	RecurringOccurrenceFailed  = "failed"
+
+	// Occurrences created per template in one cron run, a template that was paused for long
+	// catches up over the next runs.
+	recurringMaxCatchUp       = 12
+	RecurringMaxPreviewCount  = 60
+	recurringMaxScheduleSteps = 12 * 200
+)
+
+// RecurringTemplate : a document (rent expense, monthly receipt, accrual voucher...) that is created
+// automatically on a schedule. Like the salary due accruals, every occurrence is recorded in
+// recurring_occurrence before the document is made so that it is never created twice.
+type RecurringTemplate struct {
+	ID                 primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name               string              `bson:"name" json:"name"`
+	DocumentType       string              `bson:"document_type" json:"document_type"`
+	Frequency          string              `bson:"frequency" json:"frequency"`
+	DayOfMonth         int                 `bson:"day_of_month,omitempty" json:"day_of_month,omitempty"`
+	CronExpression     string              `bson:"cron_expression,omitempty" json:"cron_expression,omitempty"`
+	StartDate          *time.Time          `bson:"start_date,omitempty" json:"start_date,omitempty"`
+	StartDateStr       string              `json:"start_date_str,omitempty" bson:"-"`
+	EndDate            *time.Time          `bson:"end_date,omitempty" json:"end_date,omitempty"`
+	EndDateStr         string              `json:"end_date_str,omitempty" bson:"-"`
+	AutoApprove        bool                `bson:"auto_approve" json:"auto_approve"`
+	Active             bool                `bson:"active" json:"active"`
+	Amount             float64             `bson:"amount" json:"amount"`
+	Expense            *Expense            `bson:"expense,omitempty" json:"expense,omitempty"`
+	CustomerDeposit    *CustomerDeposit    `bson:"customer_deposit,omitempty" json:"customer_deposit,omitempty"`
+	CustomerWithdrawal *CustomerWithdrawal `bson:"customer_withdrawal,omitempty" json:"customer_withdrawal,omitempty"`
+	JournalVoucher     *JournalVoucher     `bson:"journal_voucher,omitempty" json:"journal_voucher,omitempty"`
+	NextRunAt          *time.Time          `bson:"next_run_at,omitempty" json:"next_run_at,omitempty"`
+	LastRunAt          *time.Time          `bson:"last_run_at,omitempty" json:"last_run_at,omitempty"`
+	OccurrenceCount    int64               `bson:"occurrence_count" json:"occurrence_count"`
+	LastError          string              `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	StoreID            *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName          string              `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted            bool                `bson:"deleted" json:"deleted"`
+	DeletedBy          *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt          *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt          *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt          *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy          *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy          *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName      string              `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName      string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+}
+
+// RecurringOccurrence : idempotency record of one scheduled run of a template.
+type RecurringOccurrence struct {
+	ID           primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	TemplateID   *primitive.ObjectID `json:"template_id" bson:"template_id"`
+	StoreID      *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	Date         *time.Time          `bson:"date" json:"date"`
+	DocumentType string              `bson:"document_type" json:"document_type"`
+	DocumentID   *primitive.ObjectID `json:"document_id,omitempty" bson:"document_id,omitempty"`
+	DocumentCode string              `json:"document_code,omitempty" bson:"document_code,omitempty"`
+	Status       string              `bson:"status" json:"status"`
+	Error        string              `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt    *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt    *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// RecurringOccurrencePreview : an upcoming (or already made) occurrence of a template
+type RecurringOccurrencePreview struct {
+	Date         *time.Time          `json:"date"`
+	Amount       float64             `json:"amount"`
+	Status       string              `json:"status"`
+	DocumentID   *primitive.ObjectID `json:"document_id,omitempty"`
+	DocumentCode string              `json:"document_code,omitempty"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// StoreLocation is the fixed time zone of the store, schedules are evaluated in local time.
+func StoreLocation(countryCode string) *time.Location {
+	offset := CountryTimezoneOffset(countryCode)
+	return time.FixedZone(strings.ToUpper(countryCode), int(-offset*3600))
+}
+
+// addMonthsClamped returns the given day of the month that is months after date, moved back to
+// the last day of that month when it is shorter. The clock time of date is kept.
+func addMonthsClamped(date time.Time, months int, day int) time.Time {
+	first := time.Date(date.Year(), date.Month()+time.Month(months), 1, date.Hour(), date.Minute(), date.Second(), 0, date.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// NextOccurrence returns the first scheduled time strictly after the given time, nil when the
+// schedule has ended. Monthly and quarterly schedules repeat on DayOfMonth (the day of the start
+// date when not set) at the clock time of the start date; cron schedules use the standard 5 field
+// syntax. Both are evaluated in location.
+func (template *RecurringTemplate) NextOccurrence(after time.Time, location *time.Location) (*time.Time, error) {
+	if template.StartDate == nil {
+		return nil, errors.New("start date is required")
+	}
+	start := template.StartDate.In(location)
+
+	var next time.Time
+	switch template.Frequency {
+	case RecurringFrequencyMonthly, RecurringFrequencyQuarterly:
+		step := 1
+		if template.Frequency == RecurringFrequencyQuarterly {
+			step = 3
+		}
+		day := template.DayOfMonth
+		if day <= 0 {
+			day = start.Day()
+		}
+
+		// Skip the months that are certainly before after
+		months := 0
+		if after.After(start) {
+			local := after.In(location)
+			months = ((local.Year()-start.Year())*12 + int(local.Month()) - int(start.Month()) - 1) / step * step
+			if months < 0 {
+				months = 0
+			}
+		}
+		for i := 0; ; i++ {
+			if i > recurringMaxScheduleSteps {
+				return nil, errors.New("no occurrence found")
+			}
+			next = addMonthsClamped(start, months, day)
+			months += step
+			if !next.Before(start) && next.After(after) {
+				break
+			}
+		}
+	case RecurringFrequencyCron:
+		schedule, err := cron.ParseStandard(template.CronExpression)
+		if err != nil {
+			return nil, errors.New("invalid cron expression: " + err.Error())
+		}
+		from := after.In(location)
+		if !from.After(start) {
+			from = start.Add(-time.Second)
+		}
+		next = schedule.Next(from)
+		if next.IsZero() {
+			return nil, nil
+		}
+	default:
+		return nil, errors.New("invalid frequency: " + template.Frequency)
+	}
+
+	if template.EndDate != nil && next.After(*template.EndDate) {
+		return nil, nil
+	}
+	next = next.UTC()
+	return &next, nil
+}
+
+// Occurrences returns up to count scheduled times after the given time.
+func (template *RecurringTemplate) Occurrences(after time.Time, count int, location *time.Location) ([]time.Time, error) {
+	occurrences := []time.Time{}
+	for len(occurrences) < count {
+		next, err := template.NextOccurrence(after, location)
+		if err != nil {
+			return occurrences, err
+		}
+		if next == nil {
+			break
+		}
+		occurrences = append(occurrences, *next)
+		after = *next
+	}
+	return occurrences, nil
+}
+
+// scheduleStart is the time occurrences are looked for after: the last run, or just before the start date.
+func (template *RecurringTemplate) scheduleStart() time.Time {
+	if template.LastRunAt != nil {
+		return *template.LastRunAt
+	}
+	if template.StartDate != nil {
+		return template.StartDate.Add(-time.Nanosecond)
+	}
+	return time.Now()
+}
+
+// FindAmount sets Amount to the total of the document the template makes.
+func (template *RecurringTemplate) FindAmount() {
+	switch template.DocumentType {
+	case RecurringDocumentExpense:
+		if template.Expense != nil {
+			template.Amount = template.Expense.Amount
+		}
+	case RecurringDocumentCustomerDeposit:
+		if template.CustomerDeposit != nil {
+			template.CustomerDeposit.FindNetTotal()
+			template.Amount = template.CustomerDeposit.NetTotal
+		}
+	case RecurringDocumentCustomerWithdrawal:
+		if template.CustomerWithdrawal != nil {
+			template.CustomerWithdrawal.FindNetTotal()
+			template.Amount = template.CustomerWithdrawal.NetTotal
+		}
+	case RecurringDocumentJournalVoucher:
+		if template.JournalVoucher != nil {
+			template.JournalVoucher.FindTotals()
+			template.Amount = template.JournalVoucher.TotalDebit
+		}
+	}
+}
+
+func (template *RecurringTemplate) Validate(w http.ResponseWriter, r *http.Request, scenario string) (errs map[string]string) {
+	errs = make(map[string]string)
+
+	store, err := FindStoreByID(template.StoreID, bson.M{})
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errs["store_id"] = "invalid store id"
+		return errs
+	}
+
+	if scenario == "update" && template.ID.IsZero() {
+		w.WriteHeader(http.StatusBadRequest)
+		errs["id"] = "ID is required"
+		return errs
+	}
+
+	template.Name = strings.TrimSpace(template.Name)
+	if govalidator.IsNull(template.Name) {
+		errs["name"] = "Name is required"
+	}
+
+	switch template.Frequency {
+	case RecurringFrequencyMonthly, RecurringFrequencyQuarterly:
+		template.CronExpression = ""
+		if template.DayOfMonth < 0 || template.DayOfMonth > 31 {
+			errs["day_of_month"] = "Day of month should be between 1 and 31"
+		}
+	case RecurringFrequencyCron:
+		template.DayOfMonth = 0
+		template.CronExpression = strings.TrimSpace(template.CronExpression)
+		if govalidator.IsNull(template.CronExpression) {
+			errs["cron_expression"] = "Cron expression is required"
+		} else if _, err := cron.ParseStandard(template.CronExpression); err != nil {
+			errs["cron_expression"] = "Invalid cron expression:" + err.Error()
+		}
+	default:
+		errs["frequency"] = "Invalid frequency, allowed: " + RecurringFrequencyMonthly + ", " + RecurringFrequencyQuarterly + ", " + RecurringFrequencyCron
+	}
+
+	const shortForm = "2006-01-02T15:04:05Z07:00"
+	if govalidator.IsNull(template.StartDateStr) {
+		errs["start_date_str"] = "Start date is required"
+	} else {
+		date, err := time.Parse(shortForm, template.StartDateStr)
+		if err != nil {
+			errs["start_date_str"] = "Invalid date format"
+		} else {
+			template.StartDate = &date
+		}
+	}
+
+	template.EndDate = nil
+	if !govalidator.IsNull(template.EndDateStr) {
+		date, err := time.Parse(shortForm, template.EndDateStr)
+		if err != nil {
+			errs["end_date_str"] = "Invalid date format"
+		} else if template.StartDate != nil && date.Before(*template.StartDate) {
+			errs["end_date_str"] = "End date should not be before the start date"
+		} else {
+			template.EndDate = &date
+		}
+	}
+
+	payloads := 0
+	for _, set := range []bool{template.Expense != nil, template.CustomerDeposit != nil, template.CustomerWithdrawal != nil, template.JournalVoucher != nil} {
+		if set {
+			payloads++
+		}
+	}
+
+	var payload interface{}
+	switch template.DocumentType {
+	case RecurringDocumentExpense:
+		payload = template.Expense
+	case RecurringDocumentCustomerDeposit:
+		payload = template.CustomerDeposit
+	case RecurringDocumentCustomerWithdrawal:
+		payload = template.CustomerWithdrawal
+	case RecurringDocumentJournalVoucher:
+		payload = template.JournalVoucher
+	default:
+		errs["document_type"] = "Invalid document type, allowed: " + RecurringDocumentExpense + ", " + RecurringDocumentCustomerDeposit + ", " + RecurringDocumentCustomerWithdrawal + ", " + RecurringDocumentJournalVoucher
+	}
+	if errs["document_type"] == "" && (payloads != 1 || isNilPayload(payload)) {
+		errs[template.DocumentType] = "The " + strings.ReplaceAll(template.DocumentType, "_", " ") + " to repeat is required"
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return errs
+	}
+
+	// The document is validated as if it was made on the start date
+	var payloadErrs map[string]string
+	switch template.DocumentType {
+	case RecurringDocumentExpense:
+		template.Expense.StoreID = template.StoreID
+		template.Expense.DateStr = template.StartDateStr
+		payloadErrs = template.Expense.Validate(w, r, "create")
+	case RecurringDocumentCustomerDeposit:
+		template.CustomerDeposit.StoreID = template.StoreID
+		template.CustomerDeposit.DateStr = template.StartDateStr
+		for i, payment := range template.CustomerDeposit.Payments {
+			template.CustomerDeposit.Payments[i].DateStr = template.StartDateStr
+			if payment.InvoiceID != nil && !payment.InvoiceID.IsZero() {
+				errs["customer_receivable_payment_invoice_"+strconv.Itoa(i)] = "Recurring payments can not be linked to an invoice"
+			}
+		}
+		payloadErrs = template.CustomerDeposit.Validate(w, r, "create", nil)
+	case RecurringDocumentCustomerWithdrawal:
+		template.CustomerWithdrawal.StoreID = template.StoreID
+		template.CustomerWithdrawal.DateStr = template.StartDateStr
+		for i, payment := range template.CustomerWithdrawal.Payments {
+			template.CustomerWithdrawal.Payments[i].DateStr = template.StartDateStr
+			if payment.InvoiceID != nil && !payment.InvoiceID.IsZero() {
+				errs["customer_payable_payment_invoice_"+strconv.Itoa(i)] = "Recurring payments can not be linked to an invoice"
+			}
+		}
+		payloadErrs = template.CustomerWithdrawal.Validate(w, r, "create", nil)
+	case RecurringDocumentJournalVoucher:
+		template.JournalVoucher.StoreID = template.StoreID
+		template.JournalVoucher.DateStr = template.StartDateStr
+		payloadErrs = template.JournalVoucher.Validate(w, r, "create")
+	}
+
+	for key, message := range payloadErrs {
+		errs[key] = message
+	}
+	if len(errs) > 0 && len(payloadErrs) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	template.FindAmount()
+	template.NextRunAt, err = template.NextOccurrence(template.scheduleStart(), StoreLocation(store.CountryCode))
+	if err != nil {
+		errs["start_date_str"] = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+func isNilPayload(payload interface{}) bool {
+	switch document := payload.(type) {
+	case *Expense:
+		return document == nil
+	case *CustomerDeposit:
+		return document == nil
+	case *CustomerWithdrawal:
+		return document == nil
+	case *JournalVoucher:
+		return document == nil
+	}
+	return payload == nil
+}
+
+func (template *RecurringTemplate) UpdateForeignLabelFields() error {
+	store, err := FindStoreByID(template.StoreID, bson.M{"id": 1, "name": 1})
+	if err != nil {
+		return err
+	}
+	template.StoreName = store.Name
+
+	if template.CreatedBy != nil {
+		createdByUser, err := FindUserByID(template.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		template.CreatedByName = createdByUser.Name
+	}
+
+	if template.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(template.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		template.UpdatedByName = updatedByUser.Name
+	}
+
+	return nil
+}
+
+func (template *RecurringTemplate) Insert() error {
+	collection := db.GetDB("store_" + template.StoreID.Hex()).Collection("recurring_template")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	template.ID = primitive.NewObjectID()
+
+	err := template.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, &template)
+	return err
+}
+
+func (template *RecurringTemplate) Update() error {
+	collection := db.GetDB("store_" + template.StoreID.Hex()).Collection("recurring_template")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := template.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": template.ID},
+		bson.M{"$set": template},
+		updateOptions,
+	)
+	return err
+}
+
+// DeleteRecurringTemplate soft deletes the template, documents already made are kept.
+func (template *RecurringTemplate) DeleteRecurringTemplate(tokenClaims TokenClaims) (err error) {
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	template.Deleted = true
+	template.Active = false
+	template.DeletedBy = &userID
+	now := time.Now()
+	template.DeletedAt = &now
+
+	return template.Update()
+}
+
+func (store *Store) FindRecurringTemplateByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (template *RecurringTemplate, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("recurring_template")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{
+			"_id":      ID,
+			"store_id": store.ID,
+		}, findOneOptions).
+		Decode(&template)
+	if err != nil {
+		return nil, err
+	}
+
+	return template, err
+}
+
+func (store *Store) SearchRecurringTemplate(w http.ResponseWriter, r *http.Request) (templates []RecurringTemplate, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+
+	ParseDeletedFilter(r, &criterias)
+
+	ParseTextSearch(r, &criterias, "search[name]", "name")
+
+	keys, ok := r.URL.Query()["search[document_type]"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.SearchBy["document_type"] = keys[0]
+	}
+
+	keys, ok = r.URL.Query()["search[active]"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.SearchBy["active"] = keys[0] == "1" || keys[0] == "true"
+	}
+
+	timeZoneOffset := CountryTimezoneOffset(store.CountryCode)
+	if err = ParseDateRangeFilter(r, &criterias, "search[next_run_at_from]", "search[next_run_at_to]", "next_run_at", timeZoneOffset); err != nil {
+		return templates, criterias, err
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("recurring_template")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return templates, criterias, errors.New("Error fetching recurring templates:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return templates, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		template := RecurringTemplate{}
+		err = cur.Decode(&template)
+		if err != nil {
+			return templates, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, criterias, nil
+}
+
+// FindRecurringOccurrence returns the occurrence record of the template at date, nil when there is none.
+func (store *Store) FindRecurringOccurrence(templateID *primitive.ObjectID, date time.Time) (*RecurringOccurrence, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("recurring_occurrence")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	occurrence := RecurringOccurrence{}
+	err := collection.FindOne(ctx, bson.M{
+		"template_id": templateID,
+		"date":        date,
+	}).Decode(&occurrence)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &occurrence, nil
+}
+
+func (occurrence *RecurringOccurrence) Insert() error {
+	collection := db.GetDB("store_" + occurrence.StoreID.Hex()).Collection("recurring_occurrence")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	occurrence.ID = primitive.NewObjectID()
+	_, err := collection.InsertOne(ctx, &occurrence)
+	return err
+}
+
+func (occurrence *RecurringOccurrence) Update() error {
+	collection := db.GetDB("store_" + occurrence.StoreID.Hex()).Collection("recurring_occurrence")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	occurrence.UpdatedAt = &now
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": occurrence.ID}, bson.M{"$set": occurrence})
+	return err
+}
+
+// PreviewRecurringTemplate lists the next count occurrences the cron will make, starting with
+// the ones that are due but not made yet, along with any that were already recorded.
+func (store *Store) PreviewRecurringTemplate(template *RecurringTemplate, count int) ([]RecurringOccurrencePreview, error) {
+	if count <= 0 {
+		count = 12
+	}
+	if count > RecurringMaxPreviewCount {
+		count = RecurringMaxPreviewCount
+	}
+
+	template.FindAmount()
+	dates, err := template.Occurrences(template.scheduleStart(), count, StoreLocation(store.CountryCode))
+	if err != nil {
+		return nil, err
+	}
+
+	previews := []RecurringOccurrencePreview{}
+	for i := range dates {
+		preview := RecurringOccurrencePreview{Date: &dates[i], Amount: template.Amount, Status: "scheduled"}
+		if !template.ID.IsZero() {
+			occurrence, err := store.FindRecurringOccurrence(&template.ID, dates[i])
+			if err != nil {
+				return nil, err
+			}
+			if occurrence != nil {
+				preview.Status = occurrence.Status
+				preview.DocumentID = occurrence.DocumentID
+				preview.DocumentCode = occurrence.DocumentCode
+				preview.Error = occurrence.Error
+			}
+		}
+		previews = append(previews, preview)
+	}
+
+	return previews, nil
+}
+
+// ──────────────────────────────────────────────────────────
+// Background cron: create the documents of due templates
+// ──────────────────────────────────────────────────────────
+
+// ProcessRecurringTemplatesForAllStores runs every hour and makes the documents of every
+// active template whose next run is due.
+func ProcessRecurringTemplatesForAllStores() error {
+	stores, err := GetAllStores()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, store := range stores {
+		if err := store.ProcessRecurringTemplates(now); err != nil {
+			log.Printf("[recurring-cron] store %s: %v", store.ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
+
+func (store *Store) ProcessRecurringTemplates(now time.Time) error {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("recurring_template")
+	ctx := context.Background()
+
+	cur, err := collection.Find(ctx, bson.M{
+		"deleted":     bson.M{"$ne": true},
+		"active":      true,
+		"next_run_at": bson.M{"$lte": now},
+	}, options.Find().SetNoCursorTimeout(true))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		template := RecurringTemplate{}
+		if err := cur.Decode(&template); err != nil {
+			continue
+		}
+		if err := template.Run(store, now); err != nil {
+			log.Printf("[recurring-cron] template %s: %v", template.ID.Hex(), err)
+		}
+	}
+
+	return cur.Err()
+}
+
+// Run makes the documents of every occurrence due up to now and moves NextRunAt forward.
+// An occurrence that failed is retried on the next run before the schedule moves past it.
+func (template *RecurringTemplate) Run(store *Store, now time.Time) error {
+	location := StoreLocation(store.CountryCode)
+
+	for i := 0; i < recurringMaxCatchUp && template.NextRunAt != nil && !template.NextRunAt.After(now); i++ {
+		date := *template.NextRunAt
+
+		err := template.runOccurrence(store, date)
+		if err != nil {
+			template.LastError = date.Format(time.RFC3339) + ": " + err.Error()
+			if updateErr := template.Update(); updateErr != nil {
+				return updateErr
+			}
+			return err
+		}
+
+		template.LastError = ""
+		template.LastRunAt = &date
+		template.OccurrenceCount++
+		template.NextRunAt, err = template.NextOccurrence(date, location)
+		if err != nil {
+			return err
+		}
+		if err := template.Update(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runOccurrence records the occurrence first and then makes its document (two-phase idempotency,
+// as for the salary due accruals): a recorded occurrence with a document is never made again.
+func (template *RecurringTemplate) runOccurrence(store *Store, date time.Time) error {
+	occurrence, err := store.FindRecurringOccurrence(&template.ID, date)
+	if err != nil {
+		return err
+	}
+	if occurrence != nil && occurrence.DocumentID != nil && !occurrence.DocumentID.IsZero() {
+		return nil
+	}
+
+	now := time.Now()
+	if occurrence == nil {
+		occurrence = &RecurringOccurrence{
+			TemplateID:   &template.ID,
+			StoreID:      &store.ID,
+			Date:         &date,
+			DocumentType: template.DocumentType,
+			Status:       RecurringOccurrenceFailed,
+			CreatedAt:    &now,
+			UpdatedAt:    &now,
+		}
+		if err := occurrence.Insert(); err != nil {
+			return errors.New("error recording occurrence: " + err.Error())
+		}
+	}
+
+	documentID, documentCode, err := template.makeDocument(store, date)
+	if !documentID.IsZero() {
+		// the document is saved even when a later step failed; keep it so the next run doesn't make it again
+		occurrence.DocumentID = &documentID
+		occurrence.DocumentCode = documentCode
+	}
+	if err != nil {
+		occurrence.Status = RecurringOccurrenceFailed
+		occurrence.Error = err.Error()
+		if updateErr := occurrence.Update(); updateErr != nil {
+			log.Printf("[recurring-cron] occurrence %s: %v", occurrence.ID.Hex(), updateErr)
+		}
+		return err
+	}
+
+	occurrence.Status = RecurringOccurrenceCreated
+	occurrence.Error = ""
+	occurrence.DocumentID = &documentID
+	occurrence.DocumentCode = documentCode
+	return occurrence.Update()
+}
+
+func (template *RecurringTemplate) makeDocument(store *Store, date time.Time) (primitive.ObjectID, string, error) {
+	switch template.DocumentType {
+	case RecurringDocumentExpense:
+		return template.makeExpense(store, date)
+	case RecurringDocumentCustomerDeposit:
+		return template.makeCustomerDeposit(store, date)
+	case RecurringDocumentCustomerWithdrawal:
+		return template.makeCustomerWithdrawal(store, date)
+	case RecurringDocumentJournalVoucher:
+		return template.makeJournalVoucher(store, date)
+	}
+	return primitive.NilObjectID, "", errors.New("invalid document type: " + template.DocumentType)
+}
+
+func (template *RecurringTemplate) checkAccountingLock(entity string, date *time.Time) error {
+	if message := ValidateAccountingLock(nil, template.StoreID, entity, primitive.NilObjectID, "", date); message != "" {
+		return errors.New(message)
+	}
+	return nil
+}
+
+func (template *RecurringTemplate) makeExpense(store *Store, date time.Time) (primitive.ObjectID, string, error) {
+	if template.Expense == nil {
+		return primitive.NilObjectID, "", errors.New("expense is missing in the template")
+	}
+	if err := template.checkAccountingLock("expense", &date); err != nil {
+		return primitive.NilObjectID, "", err
+	}
+
+	now := time.Now()
+	expense := *template.Expense
+	expense.ID = primitive.NilObjectID
+	expense.Code = ""
+	expense.StoreID = &store.ID
+	expense.Date = &date
+	expense.Deleted = false
+	expense.CreatedBy = template.CreatedBy
+	expense.UpdatedBy = template.CreatedBy
+	expense.CreatedAt = &now
+	expense.UpdatedAt = &now
+
+	if expense.VendorID != nil && !expense.VendorID.IsZero() {
+		vatPercent := store.VatPercent
+		expense.VatPercent = &vatPercent
+		baseAmount := RoundTo2Decimals(expense.Amount / (1 + (vatPercent / 100)))
+		expense.VatPrice = RoundTo2Decimals(baseAmount * (vatPercent / 100))
+	} else {
+		expense.VatPercent = nil
+		expense.VatPrice = 0
+	}
+
+	if err := expense.MakeRedisCode(); err != nil {
+		return primitive.NilObjectID, "", errors.New("error making code: " + err.Error())
+	}
+
+	if err := expense.Insert(); err != nil {
+		expense.UnMakeRedisCode()
+		return primitive.NilObjectID, "", errors.New("unable to insert to db: " + err.Error())
+	}
+
+	if err := expense.DoAccounting(); err != nil {
+		return expense.ID, expense.Code, errors.New("error do accounting: " + err.Error())
+	}
+
+	go expense.SetPostBalances()
+	store.NotifyUsers("expense_updated")
+	go MarkDashboardDirty(store.ID, expense.Date)
+	go store.CheckBudgetAlerts(date)
+
+	return expense.ID, expense.Code, nil
+}
+
+func (template *RecurringTemplate) makeCustomerDeposit(store *Store, date time.Time) (primitive.ObjectID, string, error) {
+	if template.CustomerDeposit == nil {
+		return primitive.NilObjectID, "", errors.New("customer deposit is missing in the template")
+	}
+	if err := template.checkAccountingLock("customer_deposit", &date); err != nil {
+		return primitive.NilObjectID, "", err
+	}
+
+	now := time.Now()
+	customerDeposit := *template.CustomerDeposit
+	customerDeposit.ID = primitive.NilObjectID
+	customerDeposit.Code = ""
+	customerDeposit.StoreID = &store.ID
+	customerDeposit.Date = &date
+	customerDeposit.Deleted = false
+	customerDeposit.CreatedBy = template.CreatedBy
+	customerDeposit.UpdatedBy = template.CreatedBy
+	customerDeposit.CreatedAt = &now
+	customerDeposit.UpdatedAt = &now
+	customerDeposit.Payments = make([]ReceivablePayment, len(template.CustomerDeposit.Payments))
+	for i, payment := range template.CustomerDeposit.Payments {
+		payment.ID = primitive.NewObjectID()
+		payment.Date = &date
+		payment.CreatedAt = &now
+		payment.CreatedBy = template.CreatedBy
+		payment.UpdatedAt = &now
+		payment.UpdatedBy = template.CreatedBy
+		customerDeposit.Payments[i] = payment
+	}
+	customerDeposit.FindNetTotal()
+	customerDeposit.UUID = uuid.New().String()
+	customerDeposit.Zatca = ZatcaReporting{}
+
+	if err := customerDeposit.MakeRedisCode(); err != nil {
+		return primitive.NilObjectID, "", errors.New("error making code: " + err.Error())
+	}
+
+	if err := customerDeposit.Insert(); err != nil {
+		customerDeposit.UnMakeRedisCode()
+		return primitive.NilObjectID, "", errors.New("unable to insert to db: " + err.Error())
+	}
+
+	if err := customerDeposit.DoAccounting(); err != nil {
+		return customerDeposit.ID, customerDeposit.Code, errors.New("error do accounting: " + err.Error())
+	}
+
+	if customerDeposit.CustomerID != nil && !customerDeposit.CustomerID.IsZero() {
+		if customer, _ := store.FindCustomerByID(customerDeposit.CustomerID, bson.M{}); customer != nil {
+			customer.SetCreditBalance()
+		}
+	}
+	if customerDeposit.VendorID != nil && !customerDeposit.VendorID.IsZero() {
+		if vendor, _ := store.FindVendorByID(customerDeposit.VendorID, bson.M{}); vendor != nil {
+			vendor.SetCreditBalance()
+		}
+	}
+	if customerDeposit.EmployeeID != nil && !customerDeposit.EmployeeID.IsZero() {
+		if employee, _ := store.FindEmployeeByID(customerDeposit.EmployeeID, bson.M{}); employee != nil {
+			if account, _ := employee.GetOrCreateLiabilityAccount(store); account != nil {
+				account.CalculateBalance(nil, nil)
+			}
+		}
+	}
+
+	go customerDeposit.SetPostBalances()
+	store.NotifyUsers("receivable_updated")
+	go MarkDashboardDirty(store.ID, customerDeposit.Date)
+
+	return customerDeposit.ID, customerDeposit.Code, nil
+}
+
+func (template *RecurringTemplate) makeCustomerWithdrawal(store *Store, date time.Time) (primitive.ObjectID, string, error) {
+	if template.CustomerWithdrawal == nil {
+		return primitive.NilObjectID, "", errors.New("customer withdrawal is missing in the template")
+	}
+	if err := template.checkAccountingLock("customer_withdrawal", &date); err != nil {
+		return primitive.NilObjectID, "", err
+	}
+
+	now := time.Now()
+	customerWithdrawal := *template.CustomerWithdrawal
+	customerWithdrawal.ID = primitive.NilObjectID
+	customerWithdrawal.Code = ""
+	customerWithdrawal.StoreID = &store.ID
+	customerWithdrawal.Date = &date
+	customerWithdrawal.Deleted = false
+	customerWithdrawal.CreatedBy = template.CreatedBy
+	customerWithdrawal.UpdatedBy = template.CreatedBy
+	customerWithdrawal.CreatedAt = &now
+	customerWithdrawal.UpdatedAt = &now
+	customerWithdrawal.Payments = make([]PayablePayment, len(template.CustomerWithdrawal.Payments))
+	for i, payment := range template.CustomerWithdrawal.Payments {
+		payment.ID = primitive.NewObjectID()
+		payment.Date = &date
+		payment.CreatedAt = &now
+		payment.CreatedBy = template.CreatedBy
+		payment.UpdatedAt = &now
+		payment.UpdatedBy = template.CreatedBy
+		customerWithdrawal.Payments[i] = payment
+	}
+	customerWithdrawal.FindNetTotal()
+	customerWithdrawal.UUID = uuid.New().String()
+	customerWithdrawal.Zatca = ZatcaReporting{}
+
+	if err := customerWithdrawal.MakeRedisCode(); err != nil {
+		return primitive.NilObjectID, "", errors.New("error making code: " + err.Error())
+	}
+
+	if err := customerWithdrawal.Insert(); err != nil {
+		customerWithdrawal.UnMakeRedisCode()
+		return primitive.NilObjectID, "", errors.New("unable to insert to db: " + err.Error())
+	}
+
+	if err := customerWithdrawal.DoAccounting(); err != nil {
+		return customerWithdrawal.ID, customerWithdrawal.Code, errors.New("error do accounting: " + err.Error())
+	}
+
+	if customerWithdrawal.CustomerID != nil && !customerWithdrawal.CustomerID.IsZero() {
+		if customer, _ := store.FindCustomerByID(customerWithdrawal.CustomerID, bson.M{}); customer != nil {
+			customer.SetCreditBalance()
+		}
+	}
+	if customerWithdrawal.VendorID != nil && !customerWithdrawal.VendorID.IsZero() {
+		if vendor, _ := store.FindVendorByID(customerWithdrawal.VendorID, bson.M{}); vendor != nil {
+			vendor.SetCreditBalance()
+		}
+	}
+	if customerWithdrawal.EmployeeID != nil && !customerWithdrawal.EmployeeID.IsZero() {
+		if employee, _ := store.FindEmployeeByID(customerWithdrawal.EmployeeID, bson.M{}); employee != nil {
+			if account, _ := employee.GetOrCreateLiabilityAccount(store); account != nil {
+				account.CalculateBalance(nil, nil)
+			}
+		}
+	}
+
+	go customerWithdrawal.SetPostBalances()
+	store.NotifyUsers("payable_updated")
+	go MarkDashboardDirty(store.ID, customerWithdrawal.Date)
+
+	return customerWithdrawal.ID, customerWithdrawal.Code, nil
+}
+
+// makeJournalVoucher saves the voucher as a draft for the accountant to approve, or approves and
+// posts it right away when the template is set to AutoApprove.
+func (template *RecurringTemplate) makeJournalVoucher(store *Store, date time.Time) (primitive.ObjectID, string, error) {
+	if template.JournalVoucher == nil {
+		return primitive.NilObjectID, "", errors.New("journal voucher is missing in the template")
+	}
+	if err := template.checkAccountingLock("journal_voucher", &date); err != nil {
+		return primitive.NilObjectID, "", err
+	}
+
+	now := time.Now()
+	voucher := *template.JournalVoucher
+	voucher.ID = primitive.NilObjectID
+	voucher.Code = ""
+	voucher.StoreID = &store.ID
+	voucher.Date = &date
+	voucher.Lines = append([]JournalVoucherLine{}, template.JournalVoucher.Lines...)
+	voucher.Status = JournalVoucherStatusDraft
+	voucher.Attachments = []string{}
+	voucher.AttachmentsContent = nil
+	voucher.ApprovedBy = nil
+	voucher.ApprovedAt = nil
+	voucher.ReversalOfID = nil
+	voucher.ReversedByID = nil
+	voucher.Deleted = false
+	voucher.CreatedBy = template.CreatedBy
+	voucher.UpdatedBy = template.CreatedBy
+	voucher.CreatedAt = &now
+	voucher.UpdatedAt = &now
+	voucher.FindTotals()
+	if voucher.Reference == "" {
+		voucher.Reference = template.Name
+	}
+
+	if err := voucher.MakeCode(); err != nil {
+		return primitive.NilObjectID, "", errors.New("error making code: " + err.Error())
+	}
+
+	if err := voucher.Insert(); err != nil {
+		return primitive.NilObjectID, "", errors.New("unable to insert to db: " + err.Error())
+	}
+
+	if template.AutoApprove && template.CreatedBy != nil {
+		if err := voucher.Approve(*template.CreatedBy); err != nil {
+			return voucher.ID, voucher.Code, errors.New("error approving: " + err.Error())
+		}
+		go voucher.SetPostBalances()
+	}
+
+	return voucher.ID, voucher.Code, nil
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// ── NextOccurrence / Occurrences ─────────────────────────────────────────────
+
+func TestRecurringTemplateOccurrences_MonthlyClampsToMonthEnd(t *testing.T) {
+	riyadh := StoreLocation("SA")
+	start := time.Date(2026, 1, 31, 9, 0, 0, 0, riyadh)
+	template := &RecurringTemplate{Frequency: RecurringFrequencyMonthly, StartDate: &start}
+
+	dates, err := template.Occurrences(start.Add(-time.Nanosecond), 4, riyadh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Time{
+		time.Date(2026, 1, 31, 9, 0, 0, 0, riyadh),
+		time.Date(2026, 2, 28, 9, 0, 0, 0, riyadh),
+		time.Date(2026, 3, 31, 9, 0, 0, 0, riyadh),
+		time.Date(2026, 4, 30, 9, 0, 0, 0, riyadh),
+	}
+	if len(dates) != len(want) {
+		t.Fatalf("occurrences = %v, want %v", dates, want)
+	}
+	for i := range want {
+		if !dates[i].Equal(want[i]) {
+			t.Errorf("occurrence %d = %s, want %s", i, dates[i].In(riyadh), want[i])
+		}
+	}
+}
+
+func TestRecurringTemplateNextOccurrence_QuarterlyOnDayOfMonth(t *testing.T) {
+	riyadh := StoreLocation("SA")
+	start := time.Date(2026, 1, 10, 0, 0, 0, 0, riyadh)
+	template := &RecurringTemplate{Frequency: RecurringFrequencyQuarterly, DayOfMonth: 5, StartDate: &start}
+
+	// the 5th of January is before the start date
+	next, err := template.NextOccurrence(start.Add(-time.Nanosecond), riyadh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 4, 5, 0, 0, 0, 0, riyadh); next == nil || !next.Equal(want) {
+		t.Errorf("next = %v, want %s", next, want)
+	}
+
+	// far after the start, no earlier quarter is skipped
+	next, _ = template.NextOccurrence(time.Date(2027, 8, 20, 0, 0, 0, 0, riyadh), riyadh)
+	if want := time.Date(2027, 10, 5, 0, 0, 0, 0, riyadh); next == nil || !next.Equal(want) {
+		t.Errorf("next = %v, want %s", next, want)
+	}
+}
+
+func TestRecurringTemplateNextOccurrence_Cron(t *testing.T) {
+	riyadh := StoreLocation("SA")
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, riyadh)
+	template := &RecurringTemplate{Frequency: RecurringFrequencyCron, CronExpression: "30 8 * * 0", StartDate: &start}
+
+	// every Sunday 08:30 store time, 1 March 2026 is a Sunday
+	dates, err := template.Occurrences(start.Add(-time.Nanosecond), 2, riyadh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dates) != 2 || !dates[0].Equal(time.Date(2026, 3, 1, 8, 30, 0, 0, riyadh)) || !dates[1].Equal(time.Date(2026, 3, 8, 8, 30, 0, 0, riyadh)) {
+		t.Errorf("occurrences = %v", dates)
+	}
+	if dates[0].Location() != time.UTC {
+		t.Errorf("occurrence location = %s, want UTC", dates[0].Location())
+	}
+
+	template.CronExpression = "61 * * * *"
+	if _, err := template.NextOccurrence(start, riyadh); err == nil {
+		t.Error("expected an error for an invalid cron expression")
+	}
+}
+
+func TestRecurringTemplateOccurrences_StopsAtEndDate(t *testing.T) {
+	start := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)
+	template := &RecurringTemplate{Frequency: RecurringFrequencyMonthly, StartDate: &start, EndDate: &end}
+
+	dates, err := template.Occurrences(start.Add(-time.Nanosecond), 12, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dates) != 3 {
+		t.Errorf("occurrences = %v, want January to March", dates)
+	}
+
+	// once run, the schedule continues after the last run
+	template.LastRunAt = &dates[0]
+	next, _ := template.NextOccurrence(template.scheduleStart(), time.UTC)
+	if next == nil || !next.Equal(dates[1]) {
+		t.Errorf("next = %v, want %s", next, dates[1])
+	}
+}
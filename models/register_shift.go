@@ -0,0 +1,583 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	RegisterShiftStatusOpen   = "open"
+	RegisterShiftStatusClosed = "closed"
+)
+
+// RegisterShift : a cashier's session on a cash register, from opening float to closing count
+type RegisterShift struct {
+	ID             primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	Code           string               `bson:"code" json:"code"`
+	RegisterID     *primitive.ObjectID  `json:"register_id" bson:"register_id"`
+	RegisterName   string               `json:"register_name" bson:"register_name"`
+	Status         string               `bson:"status" json:"status"`
+	OpeningFloat   float64              `bson:"opening_float" json:"opening_float"`
+	OpenedAt       *time.Time           `bson:"opened_at,omitempty" json:"opened_at,omitempty"`
+	OpenedBy       *primitive.ObjectID  `json:"opened_by,omitempty" bson:"opened_by,omitempty"`
+	OpenedByName   string               `json:"opened_by_name,omitempty" bson:"opened_by_name,omitempty"`
+	ClosedAt       *time.Time           `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	ClosedBy       *primitive.ObjectID  `json:"closed_by,omitempty" bson:"closed_by,omitempty"`
+	ClosedByName   string               `json:"closed_by_name,omitempty" bson:"closed_by_name,omitempty"`
+	CountedAmounts []ShiftCountedAmount `bson:"counted_amounts" json:"counted_amounts"`
+	ZReport        *RegisterShiftReport `bson:"z_report,omitempty" json:"z_report,omitempty"`
+	Remarks        string               `bson:"remarks,omitempty" json:"remarks,omitempty"`
+	StoreID        *primitive.ObjectID  `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName      string               `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	CreatedAt      *time.Time           `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt      *time.Time           `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// ShiftCountedAmount is the amount counted in the drawer for one payment method at closing.
+type ShiftCountedAmount struct {
+	Method string  `bson:"method" json:"method"`
+	Amount float64 `bson:"amount" json:"amount"`
+}
+
+// ShiftPaymentMovement is the total of one kind of payment taken with one method during a shift.
+type ShiftPaymentMovement struct {
+	Source string  `bson:"source" json:"source"` // sales, sales_return or customer_deposit
+	Method string  `bson:"method" json:"method"`
+	Amount float64 `bson:"amount" json:"amount"`
+	Count  int64   `bson:"count" json:"count"`
+}
+
+// ShiftTender is one payment method row of an X/Z report.
+// Only cash and bank methods are countable; other methods have no variance.
+type ShiftTender struct {
+	Method           string  `bson:"method" json:"method"`
+	Countable        bool    `bson:"countable" json:"countable"`
+	OpeningFloat     float64 `bson:"opening_float" json:"opening_float"`
+	Sales            float64 `bson:"sales" json:"sales"`
+	SalesReturns     float64 `bson:"sales_returns" json:"sales_returns"`
+	CustomerDeposits float64 `bson:"customer_deposits" json:"customer_deposits"`
+	Expected         float64 `bson:"expected" json:"expected"`
+	Counted          float64 `bson:"counted" json:"counted"`
+	Variance         float64 `bson:"variance" json:"variance"`
+}
+
+// RegisterShiftReport : X (mid-shift) or Z (closing) report of a shift
+type RegisterShiftReport struct {
+	Type                  string             `bson:"type" json:"type"`
+	ShiftID               primitive.ObjectID `bson:"shift_id" json:"shift_id"`
+	ShiftCode             string             `bson:"shift_code" json:"shift_code"`
+	RegisterName          string             `bson:"register_name" json:"register_name"`
+	StoreName             string             `bson:"store_name" json:"store_name"`
+	OpenedAt              *time.Time         `bson:"opened_at,omitempty" json:"opened_at,omitempty"`
+	OpenedByName          string             `bson:"opened_by_name" json:"opened_by_name"`
+	ClosedAt              *time.Time         `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	ClosedByName          string             `bson:"closed_by_name,omitempty" json:"closed_by_name,omitempty"`
+	GeneratedAt           *time.Time         `bson:"generated_at,omitempty" json:"generated_at,omitempty"`
+	OpeningFloat          float64            `bson:"opening_float" json:"opening_float"`
+	Tenders               []ShiftTender      `bson:"tenders" json:"tenders"`
+	SalesCount            int64              `bson:"sales_count" json:"sales_count"`
+	SalesReturnCount      int64              `bson:"sales_return_count" json:"sales_return_count"`
+	CustomerDepositCount  int64              `bson:"customer_deposit_count" json:"customer_deposit_count"`
+	TotalSales            float64            `bson:"total_sales" json:"total_sales"`
+	TotalSalesReturns     float64            `bson:"total_sales_returns" json:"total_sales_returns"`
+	TotalCustomerDeposits float64            `bson:"total_customer_deposits" json:"total_customer_deposits"`
+	ExpectedCash          float64            `bson:"expected_cash" json:"expected_cash"`
+	CountedCash           float64            `bson:"counted_cash" json:"counted_cash"`
+	CashVariance          float64            `bson:"cash_variance" json:"cash_variance"`
+	TotalVariance         float64            `bson:"total_variance" json:"total_variance"`
+}
+
+func isCountablePaymentMethod(method string) bool {
+	return method == "cash" || slices.Contains(BANK_PAYMENT_METHODS, method)
+}
+
+// BuildShiftTenders totals the payment movements of a shift per method.
+// The opening float is expected back in cash. When counted is nil (X report) no variance is worked out.
+func BuildShiftTenders(openingFloat float64, movements []ShiftPaymentMovement, counted []ShiftCountedAmount) []ShiftTender {
+	tenders := map[string]*ShiftTender{}
+	tender := func(method string) *ShiftTender {
+		if tenders[method] == nil {
+			tenders[method] = &ShiftTender{Method: method, Countable: isCountablePaymentMethod(method)}
+		}
+		return tenders[method]
+	}
+
+	tender("cash").OpeningFloat = openingFloat
+
+	for _, movement := range movements {
+		t := tender(movement.Method)
+		switch movement.Source {
+		case "sales":
+			t.Sales += movement.Amount
+		case "sales_return":
+			t.SalesReturns += movement.Amount
+		case "customer_deposit":
+			t.CustomerDeposits += movement.Amount
+		}
+	}
+
+	for _, count := range counted {
+		tender(count.Method).Counted += count.Amount
+	}
+
+	results := []ShiftTender{}
+	for _, t := range tenders {
+		t.Sales = RoundTo2Decimals(t.Sales)
+		t.SalesReturns = RoundTo2Decimals(t.SalesReturns)
+		t.CustomerDeposits = RoundTo2Decimals(t.CustomerDeposits)
+		t.Expected = RoundTo2Decimals(t.OpeningFloat + t.Sales - t.SalesReturns + t.CustomerDeposits)
+		t.Counted = RoundTo2Decimals(t.Counted)
+		if counted != nil && t.Countable {
+			t.Variance = RoundTo2Decimals(t.Counted - t.Expected)
+		}
+		results = append(results, *t)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if (results[i].Method == "cash") != (results[j].Method == "cash") {
+			return results[i].Method == "cash"
+		}
+		return results[i].Method < results[j].Method
+	})
+
+	return results
+}
+
+func (report *RegisterShiftReport) setTotals(movements []ShiftPaymentMovement) {
+	for _, movement := range movements {
+		switch movement.Source {
+		case "sales":
+			report.SalesCount += movement.Count
+			report.TotalSales += movement.Amount
+		case "sales_return":
+			report.SalesReturnCount += movement.Count
+			report.TotalSalesReturns += movement.Amount
+		case "customer_deposit":
+			report.CustomerDepositCount += movement.Count
+			report.TotalCustomerDeposits += movement.Amount
+		}
+	}
+	report.TotalSales = RoundTo2Decimals(report.TotalSales)
+	report.TotalSalesReturns = RoundTo2Decimals(report.TotalSalesReturns)
+	report.TotalCustomerDeposits = RoundTo2Decimals(report.TotalCustomerDeposits)
+
+	for _, tender := range report.Tenders {
+		if tender.Method == "cash" {
+			report.ExpectedCash = tender.Expected
+			report.CountedCash = tender.Counted
+			report.CashVariance = tender.Variance
+		}
+		report.TotalVariance += tender.Variance
+	}
+	report.TotalVariance = RoundTo2Decimals(report.TotalVariance)
+}
+
+// FindOpenShiftID returns the open shift of userID in the store, if any.
+// Payments are linked to the shift of the user who takes them.
+func FindOpenShiftID(storeID *primitive.ObjectID, userID *primitive.ObjectID) *primitive.ObjectID {
+	if storeID == nil || userID == nil || userID.IsZero() {
+		return nil
+	}
+
+	store, err := FindStoreByID(storeID, bson.M{})
+	if err != nil {
+		return nil
+	}
+
+	shift, err := store.FindOpenRegisterShift(bson.M{"opened_by": userID})
+	if err != nil || shift == nil {
+		return nil
+	}
+	return &shift.ID
+}
+
+// FindOpenRegisterShift returns the open shift matching filter, or nil when there is none.
+func (store *Store) FindOpenRegisterShift(filter bson.M) (*RegisterShift, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("register_shift")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter["status"] = RegisterShiftStatusOpen
+
+	shift := RegisterShift{}
+	err := collection.FindOne(ctx, filter).Decode(&shift)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+func (store *Store) SearchRegisterShift(w http.ResponseWriter, r *http.Request) (shifts []RegisterShift, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+
+	ParseTextSearch(r, &criterias, "search[code]", "code")
+
+	keys, ok := r.URL.Query()["search[status]"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.SearchBy["status"] = keys[0]
+	}
+
+	if err = ParseObjectIDFilter(r, &criterias, "search[register_id]", "register_id"); err != nil {
+		return shifts, criterias, err
+	}
+
+	if err = ParseObjectIDFilter(r, &criterias, "search[opened_by]", "opened_by"); err != nil {
+		return shifts, criterias, err
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("register_shift")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+	findOptions.SetProjection(bson.M{"z_report": 0})
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return shifts, criterias, errors.New("Error fetching register shifts:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return shifts, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		shift := RegisterShift{}
+		err = cur.Decode(&shift)
+		if err != nil {
+			return shifts, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		shifts = append(shifts, shift)
+	}
+
+	return shifts, criterias, nil
+}
+
+// ValidateOpen checks a shift about to be opened by userID.
+func (shift *RegisterShift) ValidateOpen(w http.ResponseWriter, r *http.Request, userID primitive.ObjectID) (errs map[string]string) {
+	errs = make(map[string]string)
+
+	store, err := FindStoreByID(shift.StoreID, bson.M{})
+	if err != nil {
+		errs["store_id"] = "invalid store id"
+		w.WriteHeader(http.StatusBadRequest)
+		return errs
+	}
+
+	if shift.RegisterID == nil || shift.RegisterID.IsZero() {
+		errs["register_id"] = "Cash register is required"
+	} else {
+		register, err := store.FindCashRegisterByID(shift.RegisterID, bson.M{})
+		if err != nil || register.Deleted {
+			errs["register_id"] = "Invalid cash register:" + shift.RegisterID.Hex()
+		} else {
+			shift.RegisterName = register.Name
+			shift.Code = register.Code + "-" + time.Now().Format("20060102150405")
+
+			openShift, err := store.FindOpenRegisterShift(bson.M{"register_id": register.ID})
+			if err != nil {
+				errs["register_id"] = err.Error()
+			} else if openShift != nil {
+				errs["register_id"] = "Shift " + openShift.Code + " is already open on " + register.Name + " by " + openShift.OpenedByName
+			}
+		}
+	}
+
+	openShift, err := store.FindOpenRegisterShift(bson.M{"opened_by": userID})
+	if err != nil {
+		errs["opened_by"] = err.Error()
+	} else if openShift != nil {
+		errs["opened_by"] = "You already have an open shift " + openShift.Code + " on " + openShift.RegisterName
+	}
+
+	if shift.OpeningFloat < 0 {
+		errs["opening_float"] = "Opening float should not be negative"
+	}
+	shift.OpeningFloat = RoundTo2Decimals(shift.OpeningFloat)
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+// ValidateClose checks the counted amounts given to close the shift.
+func (shift *RegisterShift) ValidateClose(w http.ResponseWriter, r *http.Request) (errs map[string]string) {
+	errs = make(map[string]string)
+
+	if shift.Status != RegisterShiftStatusOpen {
+		errs["status"] = "Shift is already closed"
+		w.WriteHeader(http.StatusBadRequest)
+		return errs
+	}
+
+	methods := map[string]bool{}
+	for i, count := range shift.CountedAmounts {
+		if !isCountablePaymentMethod(count.Method) {
+			errs["counted_method_"+strconv.Itoa(i)] = "Only cash and bank payment methods can be counted"
+		} else if methods[count.Method] {
+			errs["counted_method_"+strconv.Itoa(i)] = "Duplicate payment method " + count.Method
+		}
+		methods[count.Method] = true
+
+		if count.Amount < 0 {
+			errs["counted_amount_"+strconv.Itoa(i)] = "Counted amount should not be negative"
+		}
+		shift.CountedAmounts[i].Amount = RoundTo2Decimals(count.Amount)
+	}
+
+	if !methods["cash"] {
+		errs["counted_amounts"] = "Counted cash is required"
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+func (shift *RegisterShift) UpdateForeignLabelFields() error {
+	if shift.StoreID != nil {
+		store, err := FindStoreByID(shift.StoreID, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		shift.StoreName = store.Name
+	}
+
+	if shift.OpenedBy != nil {
+		openedByUser, err := FindUserByID(shift.OpenedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		shift.OpenedByName = openedByUser.Name
+	}
+
+	if shift.ClosedBy != nil {
+		closedByUser, err := FindUserByID(shift.ClosedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		shift.ClosedByName = closedByUser.Name
+	}
+
+	return nil
+}
+
+func (shift *RegisterShift) Insert() error {
+	collection := db.GetDB("store_" + shift.StoreID.Hex()).Collection("register_shift")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shift.ID = primitive.NewObjectID()
+
+	err := shift.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, &shift)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (shift *RegisterShift) Update() error {
+	collection := db.GetDB("store_" + shift.StoreID.Hex()).Collection("register_shift")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := shift.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": shift.ID},
+		bson.M{"$set": shift},
+		updateOptions,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (store *Store) FindRegisterShiftByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (shift *RegisterShift, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("register_shift")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{"_id": ID}, findOneOptions).
+		Decode(&shift)
+	if err != nil {
+		return nil, err
+	}
+
+	return shift, err
+}
+
+// GetShiftPaymentMovements totals the payments linked to a shift by source and method.
+// Payments settled from another document (e.g. a customer deposit applied to a sale) are not cash movements and are skipped.
+func (store *Store) GetShiftPaymentMovements(shiftID primitive.ObjectID) (movements []ShiftPaymentMovement, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ownPayment := bson.M{"$in": []interface{}{"", nil}}
+	sources := []struct {
+		source     string
+		collection string
+		pipeline   mongo.Pipeline
+	}{
+		{"sales", "sales_payment", mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"shift_id": shiftID, "deleted": bson.M{"$ne": true}, "reference_type": ownPayment}}},
+			{{Key: "$group", Value: bson.M{"_id": "$method", "amount": bson.M{"$sum": "$amount"}, "count": bson.M{"$sum": 1}}}},
+		}},
+		{"sales_return", "sales_return_payment", mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"shift_id": shiftID, "deleted": bson.M{"$ne": true}, "reference_type": ownPayment}}},
+			{{Key: "$group", Value: bson.M{"_id": "$method", "amount": bson.M{"$sum": "$amount"}, "count": bson.M{"$sum": 1}}}},
+		}},
+		{"customer_deposit", "customerdeposit", mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"payments.shift_id": shiftID, "deleted": bson.M{"$ne": true}}}},
+			{{Key: "$unwind", Value: "$payments"}},
+			{{Key: "$match", Value: bson.M{"payments.shift_id": shiftID}}},
+			{{Key: "$group", Value: bson.M{"_id": "$payments.method", "amount": bson.M{"$sum": "$payments.amount"}, "count": bson.M{"$sum": 1}}}},
+		}},
+	}
+
+	for _, source := range sources {
+		collection := db.GetDB("store_" + store.ID.Hex()).Collection(source.collection)
+		cur, err := collection.Aggregate(ctx, source.pipeline)
+		if err != nil {
+			return nil, err
+		}
+
+		var rows []struct {
+			Method string  `bson:"_id"`
+			Amount float64 `bson:"amount"`
+			Count  int64   `bson:"count"`
+		}
+		err = cur.All(ctx, &rows)
+		cur.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			movements = append(movements, ShiftPaymentMovement{
+				Source: source.source,
+				Method: row.Method,
+				Amount: RoundTo2Decimals(row.Amount),
+				Count:  row.Count,
+			})
+		}
+	}
+
+	return movements, nil
+}
+
+// MakeReport builds the X report of an open shift from its linked payments.
+// A closed shift returns the Z report saved when it was closed.
+func (shift *RegisterShift) MakeReport() (*RegisterShiftReport, error) {
+	if shift.Status == RegisterShiftStatusClosed && shift.ZReport != nil {
+		return shift.ZReport, nil
+	}
+
+	store, err := FindStoreByID(shift.StoreID, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	movements, err := store.GetShiftPaymentMovements(shift.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	report := &RegisterShiftReport{
+		Type:         "X",
+		ShiftID:      shift.ID,
+		ShiftCode:    shift.Code,
+		RegisterName: shift.RegisterName,
+		StoreName:    shift.StoreName,
+		OpenedAt:     shift.OpenedAt,
+		OpenedByName: shift.OpenedByName,
+		GeneratedAt:  &now,
+		OpeningFloat: shift.OpeningFloat,
+	}
+
+	var counted []ShiftCountedAmount
+	if shift.Status == RegisterShiftStatusClosed {
+		report.Type = "Z"
+		report.ClosedAt = shift.ClosedAt
+		report.ClosedByName = shift.ClosedByName
+		counted = shift.CountedAmounts
+		if counted == nil {
+			counted = []ShiftCountedAmount{}
+		}
+	}
+
+	report.Tenders = BuildShiftTenders(shift.OpeningFloat, movements, counted)
+	report.setTotals(movements)
+
+	return report, nil
+}
+
+// Close closes the shift with the counted amounts and saves its Z report.
+func (shift *RegisterShift) Close(userID primitive.ObjectID) error {
+	now := time.Now()
+	shift.Status = RegisterShiftStatusClosed
+	shift.ClosedAt = &now
+	shift.ClosedBy = &userID
+	shift.UpdatedAt = &now
+	shift.ZReport = nil
+
+	err := shift.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	shift.ZReport, err = shift.MakeReport()
+	if err != nil {
+		return err
+	}
+
+	return shift.Update()
+}
@@ -0,0 +1,89 @@
+package models
+
+import "testing"
+
+func makeShiftMovements() []ShiftPaymentMovement {
+	return []ShiftPaymentMovement{
+		{Source: "sales", Method: "cash", Amount: 500, Count: 4},
+		{Source: "sales", Method: "debit_card", Amount: 300, Count: 2},
+		{Source: "sales", Method: "customer_account", Amount: 120, Count: 1},
+		{Source: "sales_return", Method: "cash", Amount: 50, Count: 1},
+		{Source: "customer_deposit", Method: "cash", Amount: 100, Count: 1},
+	}
+}
+
+func findShiftTender(tenders []ShiftTender, method string) *ShiftTender {
+	for i := range tenders {
+		if tenders[i].Method == method {
+			return &tenders[i]
+		}
+	}
+	return nil
+}
+
+// ── BuildShiftTenders ────────────────────────────────────────────────────────
+
+func TestBuildShiftTenders_ExpectedCashIncludesFloat(t *testing.T) {
+	tenders := BuildShiftTenders(200, makeShiftMovements(), nil)
+
+	if tenders[0].Method != "cash" {
+		t.Fatalf("first tender = %q, want cash", tenders[0].Method)
+	}
+	// 200 float + 500 sales - 50 returns + 100 deposits
+	if got := tenders[0].Expected; got != 750 {
+		t.Errorf("expected cash = %v, want 750", got)
+	}
+	if got := tenders[0].Variance; got != 0 {
+		t.Errorf("X report variance = %v, want 0", got)
+	}
+}
+
+func TestBuildShiftTenders_VarianceOnlyForCountableMethods(t *testing.T) {
+	counted := []ShiftCountedAmount{
+		{Method: "cash", Amount: 740},
+		{Method: "debit_card", Amount: 300},
+	}
+	tenders := BuildShiftTenders(200, makeShiftMovements(), counted)
+
+	if got := findShiftTender(tenders, "cash").Variance; got != -10 {
+		t.Errorf("cash variance = %v, want -10", got)
+	}
+	if got := findShiftTender(tenders, "debit_card").Variance; got != 0 {
+		t.Errorf("debit_card variance = %v, want 0", got)
+	}
+	account := findShiftTender(tenders, "customer_account")
+	if account == nil || account.Countable || account.Variance != 0 {
+		t.Errorf("customer_account tender = %+v, want non-countable without variance", account)
+	}
+}
+
+func TestBuildShiftTenders_NoMovements(t *testing.T) {
+	tenders := BuildShiftTenders(150, nil, []ShiftCountedAmount{{Method: "cash", Amount: 150}})
+	if len(tenders) != 1 || tenders[0].Expected != 150 || tenders[0].Variance != 0 {
+		t.Errorf("tenders = %+v, want a single balanced cash row", tenders)
+	}
+}
+
+// ── setTotals ────────────────────────────────────────────────────────────────
+
+func TestRegisterShiftReportSetTotals(t *testing.T) {
+	movements := makeShiftMovements()
+	report := RegisterShiftReport{
+		Tenders: BuildShiftTenders(200, movements, []ShiftCountedAmount{{Method: "cash", Amount: 760}}),
+	}
+	report.setTotals(movements)
+
+	if report.SalesCount != 7 || report.TotalSales != 920 {
+		t.Errorf("sales = %d / %v, want 7 / 920", report.SalesCount, report.TotalSales)
+	}
+	if report.SalesReturnCount != 1 || report.TotalSalesReturns != 50 {
+		t.Errorf("sales returns = %d / %v, want 1 / 50", report.SalesReturnCount, report.TotalSalesReturns)
+	}
+	if report.ExpectedCash != 750 || report.CountedCash != 760 || report.CashVariance != 10 {
+		t.Errorf("cash = %v / %v / %v, want 750 / 760 / 10", report.ExpectedCash, report.CountedCash, report.CashVariance)
+	}
+	// debit_card was not counted, so it is 300 short
+	if report.TotalVariance != -290 {
+		t.Errorf("total variance = %v, want -290", report.TotalVariance)
+	}
+}
@@ -34,55 +34,57 @@ type RepairJobPart struct {
 
 // RepairJob : structure for AutoMobile Workshop repair job
 type RepairJob struct {
-	ID                primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
-	StoreID           *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
-	JobNumber         string              `json:"job_number" bson:"job_number"`
-	Title             string              `json:"title" bson:"title,omitempty"`
-	Date              *time.Time          `json:"date" bson:"date"`
-	VehicleID         *primitive.ObjectID `json:"vehicle_id,omitempty" bson:"vehicle_id"`
-	CustomerID        *primitive.ObjectID `json:"customer_id,omitempty" bson:"customer_id"`
-	CustomerName      string              `json:"customer_name,omitempty" bson:"customer_name,omitempty"`
-	VehicleNumber     string              `json:"vehicle_number" bson:"vehicle_number"`
-	Brand             string              `json:"brand" bson:"brand"`
-	Model             string              `json:"model" bson:"model"`
-	KM                float64             `json:"km" bson:"km"`
-	Complaint         string              `json:"complaint" bson:"complaint"`
-	Inspection        string              `json:"inspection" bson:"inspection"`
-	WorkDone          string              `json:"work_done" bson:"work_done"`
-	TechnicianID      *primitive.ObjectID   `json:"technician_id,omitempty" bson:"technician_id,omitempty"`
-	TechnicianName    string                `json:"technician_name" bson:"technician_name"`
-	TechnicianIDs     []primitive.ObjectID  `json:"technician_ids,omitempty" bson:"technician_ids,omitempty"`
-	TechnicianNames   []string              `json:"technician_names,omitempty" bson:"technician_names,omitempty"`
-	LabourCharge      float64             `json:"labour_charge" bson:"labour_charge"`
-	VatPercent        float64             `json:"vat_percent" bson:"vat_percent"`
-	Parts             []RepairJobPart     `json:"parts" bson:"parts"`
-	PartsTotal        float64             `json:"parts_total" bson:"parts_total"`
-	PartsTotalWithVat float64             `json:"parts_total_with_vat" bson:"parts_total_with_vat"`
-	Total             float64             `json:"total" bson:"total"`
-	TotalWithVat      float64             `json:"total_with_vat" bson:"total_with_vat"`
-	EstimatedDelivery *time.Time          `json:"estimated_delivery,omitempty" bson:"estimated_delivery,omitempty"`
-	Status               string              `json:"status" bson:"status"` // open, in_progress, completed, delivered, cancelled, closed
-	OrderID              *primitive.ObjectID `json:"order_id,omitempty" bson:"order_id,omitempty"`
-	OrderCode            string              `json:"order_code,omitempty" bson:"order_code,omitempty"`
-	OrderNetTotal        float64             `json:"order_net_total,omitempty" bson:"order_net_total,omitempty"`
-	QuotationID          *primitive.ObjectID `json:"quotation_id,omitempty" bson:"quotation_id,omitempty"`
-	QuotationCode        string              `json:"quotation_code,omitempty" bson:"quotation_code,omitempty"`
-	QuotationNetTotal    float64             `json:"quotation_net_total,omitempty" bson:"quotation_net_total,omitempty"`
-	QuotationType        string              `json:"quotation_type,omitempty" bson:"quotation_type,omitempty"`
-	NonVATSalesID        *primitive.ObjectID `json:"non_vat_sales_id,omitempty" bson:"non_vat_sales_id,omitempty"`
-	NonVATSalesCode      string              `json:"non_vat_sales_code,omitempty" bson:"non_vat_sales_code,omitempty"`
-	NonVATSalesNetTotal  float64             `json:"non_vat_sales_net_total,omitempty" bson:"non_vat_sales_net_total,omitempty"`
-	Archived          bool                `bson:"archived" json:"archived"`
-	Deleted           bool                `bson:"deleted" json:"deleted"`
-	DeletedBy         *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
-	DeletedAt         *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
-	CreatedAt         *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
-	UpdatedAt         *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
-	CreatedBy         *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
-	UpdatedBy         *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
-	CreatedByName     string              `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
-	UpdatedByName     string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
-	DeletedByName     string              `json:"deleted_by_name,omitempty" bson:"deleted_by_name,omitempty"`
+	ID                  primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	StoreID             *primitive.ObjectID  `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	JobNumber           string               `json:"job_number" bson:"job_number"`
+	Title               string               `json:"title" bson:"title,omitempty"`
+	Date                *time.Time           `json:"date" bson:"date"`
+	VehicleID           *primitive.ObjectID  `json:"vehicle_id,omitempty" bson:"vehicle_id"`
+	CustomerID          *primitive.ObjectID  `json:"customer_id,omitempty" bson:"customer_id"`
+	CustomerName        string               `json:"customer_name,omitempty" bson:"customer_name,omitempty"`
+	VehicleNumber       string               `json:"vehicle_number" bson:"vehicle_number"`
+	Brand               string               `json:"brand" bson:"brand"`
+	Model               string               `json:"model" bson:"model"`
+	KM                  float64              `json:"km" bson:"km"`
+	Complaint           string               `json:"complaint" bson:"complaint"`
+	Inspection          string               `json:"inspection" bson:"inspection"`
+	WorkDone            string               `json:"work_done" bson:"work_done"`
+	TechnicianID        *primitive.ObjectID  `json:"technician_id,omitempty" bson:"technician_id,omitempty"`
+	TechnicianName      string               `json:"technician_name" bson:"technician_name"`
+	TechnicianIDs       []primitive.ObjectID `json:"technician_ids,omitempty" bson:"technician_ids,omitempty"`
+	TechnicianNames     []string             `json:"technician_names,omitempty" bson:"technician_names,omitempty"`
+	LabourCharge        float64              `json:"labour_charge" bson:"labour_charge"`
+	VatPercent          float64              `json:"vat_percent" bson:"vat_percent"`
+	Parts               []RepairJobPart      `json:"parts" bson:"parts"`
+	PartsTotal          float64              `json:"parts_total" bson:"parts_total"`
+	PartsTotalWithVat   float64              `json:"parts_total_with_vat" bson:"parts_total_with_vat"`
+	Total               float64              `json:"total" bson:"total"`
+	TotalWithVat        float64              `json:"total_with_vat" bson:"total_with_vat"`
+	EstimatedDelivery   *time.Time           `json:"estimated_delivery,omitempty" bson:"estimated_delivery,omitempty"`
+	Status              string               `json:"status" bson:"status"` // open, in_progress, completed, delivered, cancelled, closed
+	OrderID             *primitive.ObjectID  `json:"order_id,omitempty" bson:"order_id,omitempty"`
+	OrderCode           string               `json:"order_code,omitempty" bson:"order_code,omitempty"`
+	OrderNetTotal       float64              `json:"order_net_total,omitempty" bson:"order_net_total,omitempty"`
+	QuotationID         *primitive.ObjectID  `json:"quotation_id,omitempty" bson:"quotation_id,omitempty"`
+	QuotationCode       string               `json:"quotation_code,omitempty" bson:"quotation_code,omitempty"`
+	QuotationNetTotal   float64              `json:"quotation_net_total,omitempty" bson:"quotation_net_total,omitempty"`
+	QuotationType       string               `json:"quotation_type,omitempty" bson:"quotation_type,omitempty"`
+	NonVATSalesID       *primitive.ObjectID  `json:"non_vat_sales_id,omitempty" bson:"non_vat_sales_id,omitempty"`
+	NonVATSalesCode     string               `json:"non_vat_sales_code,omitempty" bson:"non_vat_sales_code,omitempty"`
+	NonVATSalesNetTotal float64              `json:"non_vat_sales_net_total,omitempty" bson:"non_vat_sales_net_total,omitempty"`
+	CostCenterID        *primitive.ObjectID  `json:"cost_center_id,omitempty" bson:"cost_center_id,omitempty"`
+	CostCenterName      string               `json:"cost_center_name,omitempty" bson:"cost_center_name,omitempty"`
+	Archived            bool                 `bson:"archived" json:"archived"`
+	Deleted             bool                 `bson:"deleted" json:"deleted"`
+	DeletedBy           *primitive.ObjectID  `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt           *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt           *time.Time           `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt           *time.Time           `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy           *primitive.ObjectID  `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy           *primitive.ObjectID  `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName       string               `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName       string               `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+	DeletedByName       string               `json:"deleted_by_name,omitempty" bson:"deleted_by_name,omitempty"`
 }
 
 func (job *RepairJob) CalculateTotals() {
@@ -179,6 +181,15 @@ func (job *RepairJob) Validate(w http.ResponseWriter, r *http.Request, scenario
 		errs["title"] = "Title is required"
 	}
 
+	if len(errs) == 0 {
+		store, err := FindStoreByID(job.StoreID, bson.M{})
+		if err != nil {
+			errs["store_id"] = "invalid store id"
+		} else if message := store.ValidateCostCenter(&job.CostCenterID, &job.CostCenterName); message != "" {
+			errs["cost_center_id"] = message
+		}
+	}
+
 	return errs
 }
 
@@ -321,6 +332,10 @@ func (store *Store) SearchRepairJob(w http.ResponseWriter, r *http.Request) (job
 		criterias.SearchBy["customer_id"] = customerID
 	}
 
+	if err = ParseObjectIDListFilter(r, &criterias, "search[cost_center_id]", "cost_center_id"); err != nil {
+		return jobs, criterias, err
+	}
+
 	keys, ok = r.URL.Query()["search[vehicle_id]"]
 	if ok && len(keys[0]) >= 1 {
 		vehicleID, err := primitive.ObjectIDFromHex(keys[0])
@@ -38,6 +38,7 @@ type OrderProduct struct {
 	PurchaseUnitPrice          float64             `bson:"purchase_unit_price,omitempty" json:"purchase_unit_price,omitempty"`
 	PurchaseUnitPriceWithVAT   float64             `bson:"purchase_unit_price_with_vat,omitempty" json:"purchase_unit_price_with_vat,omitempty"`
 	Unit                       string              `bson:"unit" json:"unit"`
+	UnitConversionFactor       float64             `bson:"unit_conversion_factor,omitempty" json:"unit_conversion_factor,omitempty"`
 	UnitDiscount               float64             `bson:"unit_discount" json:"unit_discount"`
 	UnitDiscountWithVAT        float64             `bson:"unit_discount_with_vat" json:"unit_discount_with_vat"`
 	UnitDiscountPercent        float64             `bson:"unit_discount_percent" json:"unit_discount_percent"`
@@ -47,10 +48,16 @@ type OrderProduct struct {
 	ActualLineTotal            float64            `bson:"actual_line_total" json:"actual_line_total"`
 	ActualLineTotalWithVAT     float64            `bson:"actual_line_total_with_vat" json:"actual_line_total_with_vat"`
 	*/
-	Profit              float64 `bson:"profit" json:"profit"`
-	Loss                float64 `bson:"loss" json:"loss"`
-	IsService           bool    `bson:"is_service" json:"is_service"`
-	ServiceCategoryName string  `bson:"service_category_name,omitempty" json:"service_category_name,omitempty"`
+	Profit              float64              `bson:"profit" json:"profit"`
+	Loss                float64              `bson:"loss" json:"loss"`
+	IsService           bool                 `bson:"is_service" json:"is_service"`
+	ServiceCategoryName string               `bson:"service_category_name,omitempty" json:"service_category_name,omitempty"`
+	Lots                []ProductLotQuantity `bson:"lots,omitempty" json:"lots,omitempty"`
+	SerialNumbers       []string             `bson:"serial_numbers,omitempty" json:"serial_numbers,omitempty"`
+	PriceListID         *primitive.ObjectID  `bson:"price_list_id,omitempty" json:"price_list_id,omitempty"`
+	PriceListName       string               `bson:"price_list_name,omitempty" json:"price_list_name,omitempty"`
+	PromotionID         *primitive.ObjectID  `bson:"promotion_id,omitempty" json:"promotion_id,omitempty"`
+	PromotionName       string               `bson:"promotion_name,omitempty" json:"promotion_name,omitempty"`
 }
 
 // Order : Order structure
@@ -75,6 +82,9 @@ type Order struct {
 	DiscountWithVAT         float64             `bson:"discount_with_vat" json:"discount_with_vat"`
 	DiscountPercentWithVAT  float64             `bson:"discount_percent_with_vat" json:"discount_percent_with_vat"`
 	DiscountPercent         float64             `bson:"discount_percent" json:"discount_percent"`
+	CouponCode              string              `bson:"coupon_code,omitempty" json:"coupon_code,omitempty"`
+	LoyaltyPointsEarned     float64             `bson:"loyalty_points_earned" json:"loyalty_points_earned"`
+	LoyaltyPointsValue      float64             `bson:"loyalty_points_value" json:"loyalty_points_value"`
 	ReturnDiscount          float64             `bson:"return_discount" json:"return_discount"`
 	ReturnDiscountWithVAT   float64             `bson:"return_discount_with_vat" json:"return_discount_vat"`
 	Status                  string              `bson:"status,omitempty" json:"status,omitempty"`
@@ -99,6 +109,10 @@ type Order struct {
 	PaymentsCount           int64               `bson:"payments_count" json:"payments_count"`
 	PaymentStatus           string              `bson:"payment_status" json:"payment_status"`
 	PaymentMethods          []string            `json:"payment_methods" bson:"payment_methods"`
+	Currency                string              `bson:"currency,omitempty" json:"currency,omitempty"`
+	ExchangeRate            float64             `bson:"exchange_rate,omitempty" json:"exchange_rate,omitempty"`
+	CostCenterID            *primitive.ObjectID `json:"cost_center_id,omitempty" bson:"cost_center_id,omitempty"`
+	CostCenterName          string              `json:"cost_center_name,omitempty" bson:"cost_center_name,omitempty"`
 	Profit                  float64             `bson:"profit" json:"profit"`
 	NetProfit               float64             `bson:"net_profit" json:"net_profit"`
 	Loss                    float64             `bson:"loss" json:"loss"`
@@ -1411,6 +1425,10 @@ func (store *Store) SearchOrder(w http.ResponseWriter, r *http.Request) (orders
 		return orders, criterias, err
 	}
 
+	if err = ParseObjectIDListFilter(r, &criterias, "search[cost_center_id]", "cost_center_id"); err != nil {
+		return orders, criterias, err
+	}
+
 	keys, ok = r.URL.Query()["search[status]"]
 	if ok && len(keys[0]) >= 1 {
 		statusList := strings.Split(keys[0], ",")
@@ -1911,6 +1929,10 @@ func (store *Store) BuildSalesCriterias(w http.ResponseWriter, r *http.Request)
 		return criterias, err
 	}
 
+	if err = ParseObjectIDListFilter(r, &criterias, "search[cost_center_id]", "cost_center_id"); err != nil {
+		return criterias, err
+	}
+
 	keys, ok = r.URL.Query()["search[status]"]
 	if ok && len(keys[0]) >= 1 {
 		statusList := strings.Split(keys[0], ",")
@@ -2006,6 +2028,30 @@ func (order *Order) Validate(w http.ResponseWriter, r *http.Request, scenario st
 		order.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, order.StoreID, "order", order.ID, order.Code, order.Date); message != "" {
+			errs["date_str"] = message
+		}
+
+		if field, message := store.ValidateDocumentCurrency(&order.Currency, &order.ExchangeRate, order.Date); message != "" {
+			errs[field] = message
+		} else if message := store.ValidateZatcaCurrency(order.Currency); message != "" {
+			errs["currency"] = message
+		}
+	}
+
+	// A sale made from a repair job belongs to the job's cost center unless one is chosen
+	if (order.CostCenterID == nil || order.CostCenterID.IsZero()) && order.RepairJobID != nil && !order.RepairJobID.IsZero() {
+		repairJob, err := store.FindRepairJobByID(order.RepairJobID, bson.M{"cost_center_id": 1})
+		if err == nil {
+			order.CostCenterID = repairJob.CostCenterID
+		}
+	}
+
+	if message := store.ValidateCostCenter(&order.CostCenterID, &order.CostCenterName); message != "" {
+		errs["cost_center_id"] = message
+	}
+
 	if order.Commission > 0 {
 		if govalidator.IsNull(order.CommissionPaymentMethod) {
 			errs["commission_payment_method"] = "Commission payment method is required"
@@ -2189,8 +2235,26 @@ func (order *Order) Validate(w http.ResponseWriter, r *http.Request, scenario st
 		if payment.Method == "" {
 			errs["payment_method_"+strconv.Itoa(index)] = "Payment method is required"
 		}
+
+		if payment.ExchangeRate < 0 {
+			errs["payment_exchange_rate_"+strconv.Itoa(index)] = "Exchange rate should be greater than zero"
+		}
 	} //end for
 
+	loyaltyAmount := 0.00
+	for index, payment := range order.PaymentsInput {
+		if payment.Method != LoyaltyPaymentMethod || payment.Amount <= 0 {
+			continue
+		}
+
+		// Points already redeemed by this order are available again on update
+		loyaltyAmount += payment.Amount
+		err = store.ValidateLoyaltyRedemption(customer, loyaltyAmount, bson.M{"order_id": order.ID, "type": LoyaltyPointTypeRedeem})
+		if err != nil {
+			errs["payment_method_"+strconv.Itoa(index)] = err.Error()
+		}
+	}
+
 	if scenario == "update" {
 		if order.ID.IsZero() {
 			w.WriteHeader(http.StatusBadRequest)
@@ -2269,6 +2333,13 @@ func (order *Order) Validate(w http.ResponseWriter, r *http.Request, scenario st
 
 			if !exists {
 				errs["product_id_"+strconv.Itoa(index)] = "Invalid product_id:" + product.ProductID.Hex() + " in products"
+			} else {
+				conversionFactor, err := store.resolveUnitConversionFactor(&product.ProductID, product.Unit)
+				if err != nil {
+					errs["unit_"+strconv.Itoa(index)] = err.Error()
+				} else {
+					order.Products[index].UnitConversionFactor = conversionFactor
+				}
 			}
 		}
 
@@ -2321,6 +2392,16 @@ func (order *Order) Validate(w http.ResponseWriter, r *http.Request, scenario st
 		*/
 	}
 
+	err = order.ValidateSerialNumbers(store, errs)
+	if err != nil {
+		errs["serial_numbers"] = err.Error()
+	}
+
+	err = order.ValidatePromotions(store, errs)
+	if err != nil {
+		errs["promotion"] = err.Error()
+	}
+
 	if order.VatPercent == nil {
 		errs["vat_percent"] = "VAT Percentage is required"
 	}
@@ -2666,6 +2747,16 @@ func (order *Order) SetProductsStock() (err error) {
 		return nil
 	}
 
+	err = order.SetProductsLots()
+	if err != nil {
+		return err
+	}
+
+	err = order.SetProductsSerials()
+	if err != nil {
+		return err
+	}
+
 	for _, orderProduct := range order.Products {
 		product, err := store.FindProductByID(&orderProduct.ProductID, bson.M{})
 		if err != nil {
@@ -2898,6 +2989,8 @@ func (order *Order) AddPayments() error {
 			OrderCode:     order.Code,
 			Amount:        payment.Amount,
 			Method:        payment.Method,
+			Currency:      order.Currency,
+			ExchangeRate:  store.ResolvePaymentExchangeRate(order.Currency, order.Date, order.ExchangeRate, payment.ExchangeRate, payment.Date),
 			Description:   payment.Description,
 			Date:          payment.Date,
 			CreatedAt:     order.CreatedAt,
@@ -2942,6 +3035,8 @@ func (order *Order) UpdatePayments() error {
 				OrderCode:     order.Code,
 				Amount:        payment.Amount,
 				Method:        payment.Method,
+				Currency:      order.Currency,
+				ExchangeRate:  store.ResolvePaymentExchangeRate(order.Currency, order.Date, order.ExchangeRate, payment.ExchangeRate, payment.Date),
 				Description:   payment.Description,
 				Date:          payment.Date,
 				CreatedAt:     &now,
@@ -2975,6 +3070,8 @@ func (order *Order) UpdatePayments() error {
 			salesPayment.Date = payment.Date
 			salesPayment.Amount = payment.Amount
 			salesPayment.Method = payment.Method
+			salesPayment.Currency = order.Currency
+			salesPayment.ExchangeRate = store.ResolvePaymentExchangeRate(order.Currency, order.Date, order.ExchangeRate, payment.ExchangeRate, payment.Date)
 			salesPayment.Description = payment.Description
 			salesPayment.UpdatedAt = &now
 			salesPayment.UpdatedBy = order.UpdatedBy
@@ -4629,6 +4726,13 @@ func MakeJournalsForSalesPaymentsByDatetime(
 			cashReceivingAccount = *cashAccount
 		} else if slices.Contains(BANK_PAYMENT_METHODS, payment.Method) {
 			cashReceivingAccount = *bankAccount
+		} else if payment.Method == LoyaltyPaymentMethod {
+			// Paying with points settles the points liability
+			loyaltyAccount, err := store.CreateAccountIfNotExists(order.StoreID, nil, nil, "Loyalty points liability", nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			cashReceivingAccount = *loyaltyAccount
 		} else if payment.Method == "customer_account" && customer != nil {
 			continue
 			/*
@@ -4810,6 +4914,13 @@ func MakeJournalsForSalesExtraPayments(
 			cashReceivingAccount = *cashAccount
 		} else if slices.Contains(BANK_PAYMENT_METHODS, payment.Method) {
 			cashReceivingAccount = *bankAccount
+		} else if payment.Method == LoyaltyPaymentMethod {
+			// Paying with points settles the points liability
+			loyaltyAccount, err := store.CreateAccountIfNotExists(order.StoreID, nil, nil, "Loyalty points liability", nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			cashReceivingAccount = *loyaltyAccount
 		} else if payment.Method == "customer_account" && customer != nil {
 			continue
 			/*
@@ -5201,6 +5312,18 @@ func (order *Order) CreateLedger() (ledger *Ledger, err error) {
 		})
 	}
 
+	loyaltyJournals, err := store.makeLoyaltyPointsJournals(order.Date, order.LoyaltyPointsValue, false)
+	if err != nil {
+		return nil, err
+	}
+	journals = append(journals, loyaltyJournals...)
+
+	journals, err = order.convertJournalsToBaseCurrency(store, journals, customer, cashAccount, bankAccount)
+	if err != nil {
+		return nil, err
+	}
+	journals = TagJournalsWithCostCenter(journals, order.CostCenterID, order.CostCenterName)
+
 	ledger = &Ledger{
 		StoreID:        order.StoreID,
 		ReferenceID:    order.ID,
@@ -5219,6 +5342,59 @@ func (order *Order) CreateLedger() (ledger *Ledger, err error) {
 	return ledger, nil
 }
 
+// convertJournalsToBaseCurrency books a sale kept in a foreign currency at its exchange rate, and its
+// cash and bank receipts at theirs with the difference going to exchange gain or loss.
+func (order *Order) convertJournalsToBaseCurrency(
+	store *Store,
+	journals []Journal,
+	customer *Customer,
+	cashAccount *Account,
+	bankAccount *Account,
+) ([]Journal, error) {
+	if !store.IsForeignCurrency(order.Currency) {
+		return journals, nil
+	}
+
+	payments := []FXPayment{}
+	for _, payment := range order.Payments {
+		fxPayment := FXPayment{Date: payment.Date, Amount: payment.Amount, Rate: payment.ExchangeRate}
+		if payment.Method == "cash" {
+			fxPayment.AccountID = cashAccount.ID
+		} else if slices.Contains(BANK_PAYMENT_METHODS, payment.Method) {
+			fxPayment.AccountID = bankAccount.ID
+		} else {
+			continue
+		}
+		payments = append(payments, fxPayment)
+	}
+
+	customerName := "Customer Accounts - Unknown"
+	var referenceID *primitive.ObjectID
+	customerVATNo := ""
+	customerPhone := ""
+	if customer != nil {
+		customerName = customer.Name
+		referenceID = &customer.ID
+		customerVATNo = customer.VATNo
+		customerPhone = customer.Phone
+	}
+
+	referenceModel := "customer"
+	customerAccount, err := store.CreateAccountIfNotExists(
+		order.StoreID,
+		referenceID,
+		&referenceModel,
+		customerName,
+		&customerPhone,
+		&customerVATNo,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ConvertJournalsToBaseCurrency(journals, order.Currency, order.ExchangeRate, payments, &customerAccount.ID)
+}
+
 func (order *Order) GetCashDiscounts() (models []SalesCashDiscount, err error) {
 	collection := db.GetDB("store_" + order.StoreID.Hex()).Collection("sales_cash_discount")
 	ctx := context.Background()
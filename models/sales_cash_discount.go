@@ -233,6 +233,12 @@ func (salesCashDiscount *SalesCashDiscount) Validate(w http.ResponseWriter, r *h
 		salesCashDiscount.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, salesCashDiscount.StoreID, "sales_cash_discount", salesCashDiscount.ID, salesCashDiscount.OrderCode, salesCashDiscount.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	if scenario == "update" {
 		if salesCashDiscount.ID.IsZero() {
 			w.WriteHeader(http.StatusBadRequest)
@@ -48,6 +48,7 @@ type ProductSalesHistory struct {
 	UpdatedAt          *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
 	WarehouseID        *primitive.ObjectID `json:"warehouse_id" bson:"warehouse_id"`
 	WarehouseCode      *string             `json:"warehouse_code" bson:"warehouse_code"`
+	PromotionID        *primitive.ObjectID `json:"promotion_id,omitempty" bson:"promotion_id,omitempty"`
 }
 
 type SalesHistoryStats struct {
@@ -478,6 +479,7 @@ func (order *Order) CreateProductsSalesHistory() error {
 			UpdatedAt:          order.UpdatedAt,
 			WarehouseID:        orderProduct.WarehouseID,
 			WarehouseCode:      &warehouseCode,
+			PromotionID:        orderProduct.PromotionID,
 		}
 
 		history.UnitPrice = RoundTo8Decimals(orderProduct.UnitPrice)
@@ -491,6 +493,11 @@ func (order *Order) CreateProductsSalesHistory() error {
 		history.NetPrice = RoundTo2Decimals((history.Price + history.VatPrice))
 		history.ID = primitive.NewObjectID()
 
+		err = store.convertToBaseUnit(&orderProduct.ProductID, orderProduct.UnitConversionFactor, &history.Quantity, &history.Unit, &history.UnitPrice, &history.UnitPriceWithVAT, &history.PurchaseUnitPrice, &history.UnitDiscount)
+		if err != nil {
+			return err
+		}
+
 		_, err := collection.InsertOne(ctx, &history)
 		if err != nil {
 			return err
@@ -25,6 +25,8 @@ type SalesPayment struct {
 	OrderCode           string              `json:"order_code" bson:"order_code"`
 	Amount              float64             `json:"amount" bson:"amount"`
 	Method              string              `json:"method" bson:"method"`
+	Currency            string              `json:"currency,omitempty" bson:"currency,omitempty"`
+	ExchangeRate        float64             `json:"exchange_rate,omitempty" bson:"exchange_rate,omitempty"`
 	BankReference       *string             `json:"bank_reference" bson:"bank_reference"`
 	Description         *string             `json:"description" bson:"description"`
 	ReferenceType       string              `json:"reference_type" bson:"reference_type"`
@@ -44,6 +46,7 @@ type SalesPayment struct {
 	DeletedAt           *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
 	ReceivableID        *primitive.ObjectID `json:"receivable_id" bson:"receivable_id"`
 	ReceivablePaymentID *primitive.ObjectID `json:"receivable_payment_id" bson:"receivable_payment_id"`
+	ShiftID             *primitive.ObjectID `json:"shift_id,omitempty" bson:"shift_id,omitempty"`
 }
 
 /*
@@ -250,6 +253,12 @@ func (salesPayment *SalesPayment) Validate(w http.ResponseWriter, r *http.Reques
 		salesPayment.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, salesPayment.StoreID, "sales_payment", salesPayment.ID, salesPayment.OrderCode, salesPayment.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	if scenario == "update" {
 		if salesPayment.ID.IsZero() {
 			w.WriteHeader(http.StatusBadRequest)
@@ -287,6 +296,13 @@ func (salesPayment *SalesPayment) Validate(w http.ResponseWriter, r *http.Reques
 		errs["order"] = "error finding order" + err.Error()
 	}
 
+	if salesPayment.ExchangeRate < 0 {
+		errs["exchange_rate"] = "Exchange rate should be greater than zero"
+	} else if order != nil {
+		salesPayment.Currency = order.Currency
+		salesPayment.ExchangeRate = store.ResolvePaymentExchangeRate(order.Currency, order.Date, order.ExchangeRate, salesPayment.ExchangeRate, salesPayment.Date)
+	}
+
 	if salesPayment.Amount > RoundTo2Decimals(order.NetTotal-order.CashDiscount) {
 		errs["amount"] = "Amount should not exceed: " + fmt.Sprintf("%.02f", RoundTo2Decimals(order.NetTotal-order.CashDiscount)) + " (Net Total - Cash Discount)"
 		return
@@ -333,6 +349,17 @@ func (salesPayment *SalesPayment) Validate(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
+	if salesPayment.Method == LoyaltyPaymentMethod {
+		exclude := bson.M{}
+		if oldSalesPayment != nil {
+			exclude["reference_id"] = oldSalesPayment.ID
+		}
+		err = store.ValidateLoyaltyRedemption(customer, salesPayment.Amount, exclude)
+		if err != nil {
+			errs["payment_method"] = err.Error()
+		}
+	}
+
 	if customer != nil {
 		customerAccount, err := store.FindAccountByReferenceID(customer.ID, *order.StoreID, bson.M{})
 		if err != nil && err != mongo.ErrNoDocuments {
@@ -393,6 +420,10 @@ func (salesPayment *SalesPayment) Insert() error {
 		return err
 	}
 
+	if salesPayment.ShiftID == nil {
+		salesPayment.ShiftID = FindOpenShiftID(salesPayment.StoreID, salesPayment.CreatedBy)
+	}
+
 	salesPayment.ID = primitive.NewObjectID()
 	_, err = collection.InsertOne(ctx, &salesPayment)
 	if err != nil {
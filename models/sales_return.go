@@ -23,28 +23,33 @@ import (
 )
 
 type SalesReturnProduct struct {
-	ProductID                  primitive.ObjectID  `json:"product_id,omitempty" bson:"product_id,omitempty"`
-	WarehouseID                *primitive.ObjectID `json:"warehouse_id" bson:"warehouse_id"`
-	WarehouseCode              *string             `json:"warehouse_code" bson:"warehouse_code"`
-	Name                       string              `bson:"name,omitempty" json:"name,omitempty"`
-	NameInArabic               string              `bson:"name_in_arabic,omitempty" json:"name_in_arabic,omitempty"`
-	ItemCode                   string              `bson:"item_code,omitempty" json:"item_code,omitempty"`
-	PrefixPartNumber           string              `bson:"prefix_part_number" json:"prefix_part_number"`
-	PartNumber                 string              `bson:"part_number,omitempty" json:"part_number,omitempty"`
-	Quantity                   float64             `json:"quantity,omitempty" bson:"quantity,omitempty"`
-	Unit                       string              `bson:"unit,omitempty" json:"unit,omitempty"`
-	UnitPrice                  float64             `bson:"unit_price,omitempty" json:"unit_price,omitempty"`
-	UnitPriceWithVAT           float64             `bson:"unit_price_with_vat,omitempty" json:"unit_price_with_vat,omitempty"`
-	PurchaseUnitPrice          float64             `bson:"purchase_unit_price,omitempty" json:"purchase_unit_price,omitempty"`
-	PurchaseUnitPriceWithVAT   float64             `bson:"purchase_unit_price_with_vat,omitempty" json:"purchase_unit_price_with_vat,omitempty"`
-	UnitDiscount               float64             `bson:"unit_discount" json:"unit_discount"`
-	UnitDiscountPercent        float64             `bson:"unit_discount_percent" json:"unit_discount_percent"`
-	UnitDiscountWithVAT        float64             `bson:"unit_discount_with_vat" json:"unit_discount_with_vat"`
-	UnitDiscountPercentWithVAT float64             `bson:"unit_discount_percent_with_vat" json:"unit_discount_percent_with_vat"`
-	Profit                     float64             `bson:"profit" json:"profit"`
-	Loss                       float64             `bson:"loss" json:"loss"`
-	Selected                   bool                `bson:"selected" json:"selected"`
-	IsService                  bool                `bson:"is_service" json:"is_service"`
+	ProductID                  primitive.ObjectID   `json:"product_id,omitempty" bson:"product_id,omitempty"`
+	WarehouseID                *primitive.ObjectID  `json:"warehouse_id" bson:"warehouse_id"`
+	WarehouseCode              *string              `json:"warehouse_code" bson:"warehouse_code"`
+	Name                       string               `bson:"name,omitempty" json:"name,omitempty"`
+	NameInArabic               string               `bson:"name_in_arabic,omitempty" json:"name_in_arabic,omitempty"`
+	ItemCode                   string               `bson:"item_code,omitempty" json:"item_code,omitempty"`
+	PrefixPartNumber           string               `bson:"prefix_part_number" json:"prefix_part_number"`
+	PartNumber                 string               `bson:"part_number,omitempty" json:"part_number,omitempty"`
+	Quantity                   float64              `json:"quantity,omitempty" bson:"quantity,omitempty"`
+	Unit                       string               `bson:"unit,omitempty" json:"unit,omitempty"`
+	UnitConversionFactor       float64              `bson:"unit_conversion_factor,omitempty" json:"unit_conversion_factor,omitempty"`
+	UnitPrice                  float64              `bson:"unit_price,omitempty" json:"unit_price,omitempty"`
+	UnitPriceWithVAT           float64              `bson:"unit_price_with_vat,omitempty" json:"unit_price_with_vat,omitempty"`
+	PurchaseUnitPrice          float64              `bson:"purchase_unit_price,omitempty" json:"purchase_unit_price,omitempty"`
+	PurchaseUnitPriceWithVAT   float64              `bson:"purchase_unit_price_with_vat,omitempty" json:"purchase_unit_price_with_vat,omitempty"`
+	UnitDiscount               float64              `bson:"unit_discount" json:"unit_discount"`
+	UnitDiscountPercent        float64              `bson:"unit_discount_percent" json:"unit_discount_percent"`
+	UnitDiscountWithVAT        float64              `bson:"unit_discount_with_vat" json:"unit_discount_with_vat"`
+	UnitDiscountPercentWithVAT float64              `bson:"unit_discount_percent_with_vat" json:"unit_discount_percent_with_vat"`
+	Profit                     float64              `bson:"profit" json:"profit"`
+	Loss                       float64              `bson:"loss" json:"loss"`
+	Selected                   bool                 `bson:"selected" json:"selected"`
+	IsService                  bool                 `bson:"is_service" json:"is_service"`
+	Lots                       []ProductLotQuantity `bson:"lots,omitempty" json:"lots,omitempty"`
+	SerialNumbers              []string             `bson:"serial_numbers,omitempty" json:"serial_numbers,omitempty"`
+	PromotionID                *primitive.ObjectID  `bson:"promotion_id,omitempty" json:"promotion_id,omitempty"`
+	PromotionName              string               `bson:"promotion_name,omitempty" json:"promotion_name,omitempty"`
 }
 
 // SalesReturn : SalesReturn structure
@@ -92,8 +97,12 @@ type SalesReturn struct {
 	NetTotal               float64             `bson:"net_total" json:"net_total"`
 	ActualNetTotal         float64             `bson:"actual_net_total" json:"actual_net_total"`
 	CashDiscount           float64             `bson:"cash_discount" json:"cash_discount"`
+	LoyaltyPointsReversed  float64             `bson:"loyalty_points_reversed" json:"loyalty_points_reversed"`
+	LoyaltyReversedValue   float64             `bson:"loyalty_reversed_value" json:"loyalty_reversed_value"`
 	PaymentMethods         []string            `json:"payment_methods" bson:"payment_methods"`
 	PaymentStatus          string              `bson:"payment_status" json:"payment_status"`
+	Currency               string              `bson:"currency,omitempty" json:"currency,omitempty"`
+	ExchangeRate           float64             `bson:"exchange_rate,omitempty" json:"exchange_rate,omitempty"`
 	Deleted                bool                `bson:"deleted" json:"deleted"`
 	DeletedBy              *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
 	//DeletedByUser     *User                `json:"deleted_by_user,omitempty"`
@@ -229,6 +238,11 @@ func (salesReturn *SalesReturn) UpdatePaymentFromPayablePayment(
 }
 
 func (salesReturn *SalesReturn) AddPayments() error {
+	store, err := FindStoreByID(salesReturn.StoreID, bson.M{})
+	if err != nil {
+		return err
+	}
+
 	for _, payment := range salesReturn.PaymentsInput {
 		salesReturnPayment := SalesReturnPayment{
 			SalesReturnID:   &salesReturn.ID,
@@ -237,6 +251,8 @@ func (salesReturn *SalesReturn) AddPayments() error {
 			OrderCode:       salesReturn.OrderCode,
 			Amount:          payment.Amount,
 			Method:          payment.Method,
+			Currency:        salesReturn.Currency,
+			ExchangeRate:    store.ResolvePaymentExchangeRate(salesReturn.Currency, salesReturn.Date, salesReturn.ExchangeRate, payment.ExchangeRate, payment.Date),
 			Description:     payment.Description,
 			Date:            payment.Date,
 			CreatedAt:       salesReturn.CreatedAt,
@@ -289,6 +305,8 @@ func (salesReturn *SalesReturn) UpdatePayments() error {
 				OrderCode:       salesReturn.OrderCode,
 				Amount:          payment.Amount,
 				Method:          payment.Method,
+				Currency:        salesReturn.Currency,
+				ExchangeRate:    store.ResolvePaymentExchangeRate(salesReturn.Currency, salesReturn.Date, salesReturn.ExchangeRate, payment.ExchangeRate, payment.Date),
 				Description:     payment.Description,
 				Date:            payment.Date,
 				CreatedAt:       &now,
@@ -315,6 +333,8 @@ func (salesReturn *SalesReturn) UpdatePayments() error {
 			salesReturnPayment.Date = payment.Date
 			salesReturnPayment.Amount = payment.Amount
 			salesReturnPayment.Method = payment.Method
+			salesReturnPayment.Currency = salesReturn.Currency
+			salesReturnPayment.ExchangeRate = store.ResolvePaymentExchangeRate(salesReturn.Currency, salesReturn.Date, salesReturn.ExchangeRate, payment.ExchangeRate, payment.Date)
 			salesReturnPayment.Description = payment.Description
 			salesReturnPayment.UpdatedAt = &now
 			salesReturnPayment.UpdatedBy = salesReturn.UpdatedBy
@@ -1458,6 +1478,8 @@ func (salesreturn *SalesReturn) Validate(w http.ResponseWriter, r *http.Request,
 	order, err := store.FindOrderByID(salesreturn.OrderID, bson.M{})
 	if err != nil {
 		errs["order_id"] = "Order is invalid"
+	} else {
+		salesreturn.SetCurrencyFromOrder(order)
 	}
 
 	customer, err := store.FindCustomerByID(salesreturn.CustomerID, bson.M{})
@@ -1546,6 +1568,12 @@ func (salesreturn *SalesReturn) Validate(w http.ResponseWriter, r *http.Request,
 		salesreturn.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, salesreturn.StoreID, "sales_return", salesreturn.ID, salesreturn.Code, salesreturn.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	totalPayment := float64(0.00)
 	for _, payment := range salesreturn.PaymentsInput {
 		if payment.Amount > 0 {
@@ -1586,6 +1614,10 @@ func (salesreturn *SalesReturn) Validate(w http.ResponseWriter, r *http.Request,
 			errs["payment_method_"+strconv.Itoa(index)] = "Payment method is required"
 		}
 
+		if payment.ExchangeRate < 0 {
+			errs["payment_exchange_rate_"+strconv.Itoa(index)] = "Exchange rate should be greater than zero"
+		}
+
 	} //end for
 
 	if salesreturn.OrderID == nil || salesreturn.OrderID.IsZero() {
@@ -1718,6 +1750,7 @@ func (salesreturn *SalesReturn) Validate(w http.ResponseWriter, r *http.Request,
 		//for i := 0; i < len(order.Products); i++ {
 		for i := len(order.Products) - 1; i >= 0; i-- {
 			if order.Products[i].ProductID == salesReturnProduct.ProductID {
+				salesreturn.Products[index].UnitConversionFactor = order.Products[i].UnitConversionFactor
 				//soldQty := RoundFloat((orderProduct.Quantity - orderProduct.QuantityReturned), 2)
 				maxAllowedQuantity := 0.00
 				if scenario == "update" {
@@ -1771,6 +1804,13 @@ func (salesreturn *SalesReturn) Validate(w http.ResponseWriter, r *http.Request,
 		*/
 	}
 
+	if order != nil {
+		err = salesreturn.ValidateSerialNumbers(store, order, errs)
+		if err != nil {
+			errs["serial_numbers"] = err.Error()
+		}
+	}
+
 	if salesreturn.VatPercent == nil {
 		errs["vat_percent"] = "VAT Percentage is required"
 	}
@@ -1970,6 +2010,16 @@ func (salesreturn *SalesReturn) SetProductsStock() (err error) {
 		return nil
 	}
 
+	err = salesreturn.SetProductsLots()
+	if err != nil {
+		return err
+	}
+
+	err = salesreturn.SetProductsSerials()
+	if err != nil {
+		return err
+	}
+
 	for _, salesreturnProduct := range salesreturn.Products {
 		if !salesreturnProduct.Selected {
 			continue
@@ -4656,6 +4706,28 @@ func (salesReturn *SalesReturn) CreateLedger() (ledger *Ledger, err error) {
 		})
 	}
 
+	loyaltyJournals, err := store.makeLoyaltyPointsJournals(salesReturn.Date, salesReturn.LoyaltyReversedValue, true)
+	if err != nil {
+		return nil, err
+	}
+	journals = append(journals, loyaltyJournals...)
+
+	// a return belongs to the cost center of the sale it reverses
+	if salesReturn.OrderID != nil && !salesReturn.OrderID.IsZero() {
+		order, err := store.FindOrderByID(salesReturn.OrderID, bson.M{"cost_center_id": 1, "cost_center_name": 1})
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+		if order != nil {
+			journals = TagJournalsWithCostCenter(journals, order.CostCenterID, order.CostCenterName)
+		}
+	}
+
+	journals, err = salesReturn.convertJournalsToBaseCurrency(store, journals, customer, cashAccount, bankAccount)
+	if err != nil {
+		return nil, err
+	}
+
 	ledger = &Ledger{
 		StoreID:        salesReturn.StoreID,
 		ReferenceID:    salesReturn.ID,
@@ -4674,6 +4746,65 @@ func (salesReturn *SalesReturn) CreateLedger() (ledger *Ledger, err error) {
 	return ledger, nil
 }
 
+// SetCurrencyFromOrder keeps a return in the currency of the sale it reverses, at the sale's rate.
+func (salesReturn *SalesReturn) SetCurrencyFromOrder(order *Order) {
+	salesReturn.Currency = order.Currency
+	salesReturn.ExchangeRate = order.ExchangeRate
+}
+
+// convertJournalsToBaseCurrency books a return of a sale kept in a foreign currency at the sale's
+// exchange rate, and its cash and bank refunds at theirs with the difference going to exchange gain or loss.
+func (salesReturn *SalesReturn) convertJournalsToBaseCurrency(
+	store *Store,
+	journals []Journal,
+	customer *Customer,
+	cashAccount *Account,
+	bankAccount *Account,
+) ([]Journal, error) {
+	if !store.IsForeignCurrency(salesReturn.Currency) {
+		return journals, nil
+	}
+
+	payments := []FXPayment{}
+	for _, payment := range salesReturn.Payments {
+		fxPayment := FXPayment{Date: payment.Date, Amount: payment.Amount, Rate: payment.ExchangeRate}
+		if payment.Method == "cash" {
+			fxPayment.AccountID = cashAccount.ID
+		} else if slices.Contains(BANK_PAYMENT_METHODS, payment.Method) {
+			fxPayment.AccountID = bankAccount.ID
+		} else {
+			continue
+		}
+		payments = append(payments, fxPayment)
+	}
+
+	customerName := "Customer Accounts - Unknown"
+	var referenceID *primitive.ObjectID
+	customerVATNo := ""
+	customerPhone := ""
+	if customer != nil {
+		customerName = customer.Name
+		referenceID = &customer.ID
+		customerVATNo = customer.VATNo
+		customerPhone = customer.Phone
+	}
+
+	referenceModel := "customer"
+	customerAccount, err := store.CreateAccountIfNotExists(
+		salesReturn.StoreID,
+		referenceID,
+		&referenceModel,
+		customerName,
+		&customerPhone,
+		&customerVATNo,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ConvertJournalsToBaseCurrency(journals, salesReturn.Currency, salesReturn.ExchangeRate, payments, &customerAccount.ID)
+}
+
 func (salesReturn *SalesReturn) DoAccounting() error {
 	err := salesReturn.AdjustPayments()
 	if err != nil {
@@ -472,6 +472,11 @@ func (salesReturn *SalesReturn) CreateProductsSalesReturnHistory() error {
 
 		history.ID = primitive.NewObjectID()
 
+		err = store.convertToBaseUnit(&salesReturnProduct.ProductID, salesReturnProduct.UnitConversionFactor, &history.Quantity, &history.Unit, &history.UnitPrice, &history.UnitPriceWithVAT, &history.Discount)
+		if err != nil {
+			return err
+		}
+
 		_, err := collection.InsertOne(ctx, &history)
 		if err != nil {
 			return err
@@ -26,6 +26,8 @@ type SalesReturnPayment struct {
 	OrderCode        string              `json:"order_code" bson:"order_code"`
 	Amount           float64             `json:"amount" bson:"amount"`
 	Method           string              `json:"method" bson:"method"`
+	Currency         string              `json:"currency,omitempty" bson:"currency,omitempty"`
+	ExchangeRate     float64             `json:"exchange_rate,omitempty" bson:"exchange_rate,omitempty"`
 	Description      *string             `json:"description,omitempty" bson:"description,omitempty"`
 	ReferenceType    string              `json:"reference_type" bson:"reference_type"`
 	ReferenceCode    string              `json:"reference_code" bson:"reference_code"`
@@ -44,6 +46,7 @@ type SalesReturnPayment struct {
 	DeletedAt        *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
 	PayableID        *primitive.ObjectID `json:"payable_id" bson:"payable_id"`
 	PayablePaymentID *primitive.ObjectID `json:"payable_payment_id" bson:"payable_payment_id"`
+	ShiftID          *primitive.ObjectID `json:"shift_id,omitempty" bson:"shift_id,omitempty"`
 }
 
 /*
@@ -266,6 +269,12 @@ func (salesReturnPayment *SalesReturnPayment) Validate(w http.ResponseWriter, r
 		salesReturnPayment.Date = &date
 	}
 
+	if errs["date_str"] == "" {
+		if message := ValidateAccountingLock(r, salesReturnPayment.StoreID, "sales_return_payment", salesReturnPayment.ID, salesReturnPayment.SalesReturnCode, salesReturnPayment.Date); message != "" {
+			errs["date_str"] = message
+		}
+	}
+
 	var oldSalesReturnPayment *SalesReturnPayment
 
 	if scenario == "update" {
@@ -306,6 +315,13 @@ func (salesReturnPayment *SalesReturnPayment) Validate(w http.ResponseWriter, r
 		errs["sales_return"] = "error finding sales return" + err.Error()
 	}
 
+	if salesReturnPayment.ExchangeRate < 0 {
+		errs["exchange_rate"] = "Exchange rate should be greater than zero"
+	} else if salesReturn != nil {
+		salesReturnPayment.Currency = salesReturn.Currency
+		salesReturnPayment.ExchangeRate = store.ResolvePaymentExchangeRate(salesReturn.Currency, salesReturn.Date, salesReturn.ExchangeRate, salesReturnPayment.ExchangeRate, salesReturnPayment.Date)
+	}
+
 	if salesReturnPayment.Amount > RoundTo2Decimals(salesReturn.NetTotal-salesReturn.CashDiscount) {
 		errs["amount"] = "Amount should not exceed: " + fmt.Sprintf("%.02f", RoundTo2Decimals(salesReturn.NetTotal-salesReturn.CashDiscount)) + " (Net Total - Cash Discount)"
 		return
@@ -415,6 +431,10 @@ func (salesreturnPayment *SalesReturnPayment) Insert() error {
 		return err
 	}
 
+	if salesreturnPayment.ShiftID == nil {
+		salesreturnPayment.ShiftID = FindOpenShiftID(salesreturnPayment.StoreID, salesreturnPayment.CreatedBy)
+	}
+
 	salesreturnPayment.ID = primitive.NewObjectID()
 	_, err = collection.InsertOne(ctx, &salesreturnPayment)
 	if err != nil {
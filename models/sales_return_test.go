@@ -2,6 +2,9 @@ package models
 
 import (
 	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // ── helpers ───────────────────────────────────────────────────────────────────
@@ -215,3 +218,47 @@ func TestSalesReturn_FindTotalQuantity_EmptyProducts(t *testing.T) {
 		t.Errorf("TotalQuantity = %v, want 0", sr.TotalQuantity)
 	}
 }
+
+// ── Currency — a return is kept in the currency of its sale ──────────────────
+
+func TestSalesReturn_OfUSDOrderIsBookedAtTheOrderRate(t *testing.T) {
+	order := &Order{Currency: "USD", ExchangeRate: 3.75}
+	sr := SalesReturn{}
+	sr.SetCurrencyFromOrder(order)
+	if sr.Currency != "USD" || sr.ExchangeRate != 3.75 {
+		t.Fatalf("currency = %s @ %v, want USD @ 3.75", sr.Currency, sr.ExchangeRate)
+	}
+
+	salesReturnAccount := Account{ID: primitive.NewObjectID(), Name: "Sales Return"}
+	customer := Account{ID: primitive.NewObjectID(), Name: "CUSTOMER"}
+	cash := Account{ID: primitive.NewObjectID(), Name: "Cash"}
+	gain := Account{ID: primitive.NewObjectID(), Name: ExchangeGainAccountName}
+	loss := Account{ID: primitive.NewObjectID(), Name: ExchangeLossAccountName}
+
+	returnDate := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	refundDate := time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC)
+	returned, refund := primitive.NewObjectID(), primitive.NewObjectID()
+	journals := []Journal{
+		fxJournal(salesReturnAccount, "debit", 100, returnDate, returned),
+		fxJournal(customer, "credit", 100, returnDate, returned),
+		fxJournal(customer, "debit", 100, refundDate, refund),
+		fxJournal(cash, "credit", 100, refundDate, refund),
+	}
+	payments := []FXPayment{{Date: &refundDate, AccountID: cash.ID, Amount: 100, Rate: 3.80}}
+
+	converted := ConvertJournalsByExchangeRate(journals, sr.ExchangeRate, payments, &customer.ID, &gain, &loss)
+
+	assertGroupsBalance(t, converted)
+	if got := balanceOf(converted, salesReturnAccount.ID); got != 375 {
+		t.Errorf("sales return = %.2f, want 375 at the order rate", got)
+	}
+	if got := balanceOf(converted, cash.ID); got != -380 {
+		t.Errorf("cash = %.2f, want -380 at the refund rate", got)
+	}
+	if got := balanceOf(converted, customer.ID); got != 0 {
+		t.Errorf("customer = %.2f, want settled", got)
+	}
+	if got := balanceOf(converted, loss.ID); got != 5 {
+		t.Errorf("exchange loss = %.2f, want 5", got)
+	}
+}
@@ -1,16 +1,13 @@
 package models
 
 import (
-	"bytes"
 	"encoding/base64"
-	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -116,6 +113,7 @@ func (salesReturn *SalesReturn) MakeXMLContent() (string, error) {
 	invoice.IssueTime = salesReturn.Date.In(loc).Format("15:04:05")
 
 	isSimplified := !customer.IsB2B()
+	salesReturn.Zatca.IsSimplified = isSimplified
 
 	if isSimplified {
 		invoice.InvoiceTypeCode.Name = "0200000" //simplified invoice
@@ -648,24 +646,7 @@ func (salesReturn *SalesReturn) MakeXMLContent() (string, error) {
 		return xmlContent, err
 	}
 
-	updatedXML2 := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" + string(updatedXML)
-
-	filePath := "ZatcaPython/templates/return_invoice_" + salesReturn.Code + ".xml"
-	// **Save Updated XML**
-	err = os.WriteFile(filePath, []byte(updatedXML2), 0644)
-	if err != nil {
-		log.Print("Error writing file:")
-		return xmlContent, err
-	}
-	//log.Print("Going to write file8:")
-
-	// Verify file exists
-
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		fmt.Println("File not created:", err)
-	} else {
-		//fmt.Println("File successfully written:", filePath)
-	}
+	xmlContent = "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" + string(updatedXML)
 
 	return xmlContent, nil
 }
@@ -708,6 +689,15 @@ func (salesReturn *SalesReturn) RecordZatcaReportingFailure(errorMessage string)
 		if err != nil {
 			return err
 		}*/
+
+	queueZatcaReporting(salesReturn.StoreID, ZatcaQueueItem{
+		DocumentType:      ZatcaDocumentSalesReturn,
+		DocumentID:        salesReturn.ID,
+		DocumentCode:      salesReturn.Code,
+		InvoiceCountValue: salesReturn.InvoiceCountValue,
+		DocumentDate:      salesReturn.Date,
+		IsSimplified:      salesReturn.Zatca.IsSimplified,
+	}, errorMessage)
 	return nil
 }
 
@@ -723,10 +713,18 @@ func (salesReturn *SalesReturn) RecordZatcaReportingSuccess(reportingResponse Za
 			return err
 		}*/
 
+	resolveZatcaQueueItem(salesReturn.StoreID, ZatcaDocumentSalesReturn, salesReturn.ID)
 	return nil
 }
 
+// ReportToZatca reports the sales return to ZATCA and emits the reported or failed webhook event.
 func (salesReturn *SalesReturn) ReportToZatca() error {
+	err := salesReturn.reportToZatca()
+	emitZatcaWebhookEvent(salesReturn.StoreID, salesReturn.ID, WebhookEventSalesReturnZatcaReported, WebhookEventSalesReturnZatcaFailed, salesReturn, err)
+	return err
+}
+
+func (salesReturn *SalesReturn) reportToZatca() error {
 	var err error
 
 	store, err := FindStoreByID(salesReturn.StoreID, bson.M{})
@@ -734,170 +732,12 @@ func (salesReturn *SalesReturn) ReportToZatca() error {
 		return errors.New("error finding store: " + err.Error())
 	}
 
-	customer, err := store.FindCustomerByID(salesReturn.CustomerID, bson.M{})
-	if err != nil && err != mongo.ErrNoDocuments {
-		return errors.New("error finding customer: " + err.Error())
-	}
-
-	_, err = salesReturn.MakeXMLContent()
+	xmlContent, err := salesReturn.MakeXMLContent()
 	if err != nil {
 		return errors.New("error making xml: " + err.Error())
 	}
 
-	isSimplified := !customer.IsB2B()
-
-	/*
-		// Create JSON payload
-		payload := map[string]interface{}{
-			"env":                   store.Zatca.Env,
-			"private_key":           store.Zatca.PrivateKey,
-			"binary_security_token": store.Zatca.BinarySecurityToken,
-			"secret":                store.Zatca.Secret,
-			"xml_file_path":         "ZatcaPython/templates/return_invoice_" + salesReturn.Code + ".xml",
-			"is_simplified":         isSimplified,
-		}
-
-		// Convert payload to JSON
-		jsonData, err := json.Marshal(payload)
-		if err != nil {
-			return errors.New("error marshal payload to compliance check: " + err.Error())
-		}
-
-		// Create command
-		cmd := exec.Command("ZatcaPython/compliance_check")
-
-		// Set up pipes
-		cmd.Stdin = bytes.NewReader(jsonData) // Send JSON data to stdin
-		var output bytes.Buffer
-		cmd.Stdout = &output // Capture stdout
-		cmd.Stderr = &output // Capture stderr
-
-		complianceCheckResponse := ZatcaComplianceCheckResponse{}
-
-		// Run the command
-		err = cmd.Run()
-		if err != nil {
-			fmt.Println("Error running Python script1:", err)
-			// Parse JSON response
-
-			err = json.Unmarshal(output.Bytes(), &complianceCheckResponse)
-			if err != nil {
-				errorMessage := "error unmarshaling compliance check response: " + complianceCheckResponse.Error + ", " + err.Error()
-				err = salesReturn.RecordZatcaComplianceCheckFailure(errorMessage)
-				if err != nil {
-					return err
-				}
-				return errors.New(errorMessage)
-			}
-
-			if complianceCheckResponse.Error != "" {
-				errorMessage := "compliance check error: " + complianceCheckResponse.Error
-				err = salesReturn.RecordZatcaComplianceCheckFailure(errorMessage)
-				if err != nil {
-					return err
-				}
-				return errors.New(errorMessage)
-			}
-		}
-
-		// Parse JSON response
-
-		err = json.Unmarshal(output.Bytes(), &complianceCheckResponse)
-		if err != nil {
-			errorMessage := "error unmarshal compliance check response: " + err.Error()
-			err = salesReturn.RecordZatcaComplianceCheckFailure(errorMessage)
-			if err != nil {
-				return err
-			}
-			return errors.New(errorMessage)
-		}
-
-		//log.Print("pythonResponse:")
-		//log.Print(pythonResponse)
-
-		if complianceCheckResponse.Error != "" || !complianceCheckResponse.CompliancePassed {
-			errorMessage := "compliance check error: " + complianceCheckResponse.Error
-			err = salesReturn.RecordZatcaComplianceCheckFailure(errorMessage)
-			if err != nil {
-				return err
-			}
-			return errors.New(errorMessage)
-		}
-
-		if complianceCheckResponse.CompliancePassed {
-			err = salesReturn.RecordZatcaComplianceCheckSuccess(complianceCheckResponse)
-			if err != nil {
-				return err
-			}
-		}*/
-
-	//	if complianceCheckResponse.CompliancePassed {
-	// Create JSON payload
-	payload := map[string]interface{}{
-		"env":                              store.Zatca.Env,
-		"private_key":                      store.Zatca.PrivateKey,
-		"production_binary_security_token": store.Zatca.ProductionBinarySecurityToken,
-		"production_secret":                store.Zatca.ProductionSecret,
-		"xml_file_path":                    "ZatcaPython/templates/return_invoice_" + salesReturn.Code + ".xml",
-		"is_simplified":                    isSimplified,
-		"store_id":                         store.ID.Hex(),
-	}
-
-	// Convert payload to JSON
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
-	pythonBinary := "ZatcaPython/venv/bin/python"
-	scriptPath := "ZatcaPython/reporting_and_clearance.py"
-	cmd := exec.Command(pythonBinary, scriptPath)
-
-	// Create command
-	//cmd := exec.Command("ZatcaPython/reporting_and_clearance")
-
-	// Set up pipes
-	cmd.Stdin = bytes.NewReader(jsonData) // Send JSON data to stdin
-	var output bytes.Buffer
-	cmd.Stdout = &output // Capture stdout
-	cmd.Stderr = &output // Capture stderr
-
-	reportingResponse := ZatcaReportingResponse{}
-
-	// Run the command
-	err = cmd.Run()
-	if err != nil {
-		err = json.Unmarshal(output.Bytes(), &reportingResponse)
-		if err != nil {
-			errorMessage := "error running reporting script &  unmarshal reporting response : " + err.Error()
-			err = salesReturn.RecordZatcaReportingFailure(errorMessage)
-			if err != nil {
-				return err
-			}
-			return errors.New(errorMessage)
-		}
-
-		if reportingResponse.Error != "" {
-			errorMessage := "error running reporting script: " + reportingResponse.Error
-			err = salesReturn.RecordZatcaReportingFailure(errorMessage)
-			if err != nil {
-				return err
-			}
-			return errors.New(errorMessage)
-		}
-	}
-
-	// Parse JSON response
-	err = json.Unmarshal(output.Bytes(), &reportingResponse)
-	if err != nil {
-		errorMessage := "error unmarshal reporting response: " + err.Error()
-		err = salesReturn.RecordZatcaReportingFailure(errorMessage)
-		if err != nil {
-			return err
-		}
-		return errors.New(errorMessage)
-	}
-
+	reportingResponse := store.SubmitToZatca(xmlContent)
 	if reportingResponse.Error != "" || !reportingResponse.ReportingPassed {
 		errorMessage := "error reporting: " + reportingResponse.Error
 		err = salesReturn.RecordZatcaReportingFailure(errorMessage)
@@ -912,14 +752,7 @@ func (salesReturn *SalesReturn) ReportToZatca() error {
 		return err
 	}
 
-	err = salesReturn.SaveClearedInvoiceData(reportingResponse)
-	if err != nil {
-		return err
-	}
-
-	//}
-
-	return nil
+	return salesReturn.SaveClearedInvoiceData(reportingResponse)
 }
 
 func (salesReturn *SalesReturn) SaveClearedInvoiceData(reportingResponse ZatcaReportingResponse) error {
@@ -933,9 +766,8 @@ func (salesReturn *SalesReturn) SaveClearedInvoiceData(reportingResponse ZatcaRe
 
 	// Step 2: Save to an XML file
 	//fileName := "output.xml"
-	//xmlResponseFilePath := "ZatcaPython/templates/invoice_" + order.Code + "_response.xml"
-	xmlResponseFilePath := "zatca/" + salesReturn.StoreID.Hex() + "/sales-returns/xml/" + salesReturn.Code + ".xml"
-	if err = os.MkdirAll("zatca/"+salesReturn.StoreID.Hex()+"/sales-returns/xml", 0755); err != nil {
+	xmlResponseFilePath := ZatcaXMLPath(salesReturn.StoreID, ZatcaDocumentSalesReturn, salesReturn.Code)
+	if err = os.MkdirAll(filepath.Dir(xmlResponseFilePath), 0755); err != nil {
 		fmt.Println("Error creating directory:", err)
 		return err
 	}
@@ -1012,23 +844,5 @@ func (salesReturn *SalesReturn) SaveClearedInvoiceData(reportingResponse ZatcaRe
 			return err
 		}*/
 
-	// Delete xml files
-	xmlFilePath := "ZatcaPython/templates/return_invoice_" + salesReturn.Code + ".xml"
-	if _, err := os.Stat(xmlFilePath); err == nil {
-		err = os.Remove(xmlFilePath)
-		if err != nil {
-			return err
-		}
-	}
-
-	/*
-		if _, err := os.Stat(xmlResponseFilePath); err == nil {
-			err = os.Remove(xmlResponseFilePath)
-			if err != nil {
-				return err
-			}
-		}
-	*/
-
 	return nil
 }
@@ -1,16 +1,13 @@
 package models
 
 import (
-	"bytes"
 	"encoding/base64"
-	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -114,6 +111,7 @@ func (order *Order) MakeXMLContent() (string, error) {
 	invoice.IssueDate = order.Date.In(loc).Format("2006-01-02")
 	invoice.IssueTime = order.Date.In(loc).Format("15:04:05")
 	isSimplified := !customer.IsB2B()
+	order.Zatca.IsSimplified = isSimplified
 
 	if isSimplified {
 		invoice.InvoiceTypeCode.Name = "0200000" //simplified invoice
@@ -698,24 +696,7 @@ func (order *Order) MakeXMLContent() (string, error) {
 		return xmlContent, err
 	}
 
-	updatedXML2 := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" + string(updatedXML)
-
-	filePath := "ZatcaPython/templates/invoice_" + order.Code + ".xml"
-	// **Save Updated XML**
-	err = os.WriteFile(filePath, []byte(updatedXML2), 0644)
-	if err != nil {
-		log.Print("Error writing file:")
-		return xmlContent, err
-	}
-	//log.Print("Going to write file8:")
-
-	// Verify file exists
-
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		fmt.Println("File not created:", err)
-	} else {
-		//fmt.Println("File successfully written:", filePath)
-	}
+	xmlContent = "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" + string(updatedXML)
 
 	return xmlContent, nil
 }
@@ -776,6 +757,15 @@ func (order *Order) RecordZatcaReportingFailure(errorMessage string) error {
 		if err != nil {
 			return err
 		}*/
+
+	queueZatcaReporting(order.StoreID, ZatcaQueueItem{
+		DocumentType:      ZatcaDocumentOrder,
+		DocumentID:        order.ID,
+		DocumentCode:      order.Code,
+		InvoiceCountValue: order.InvoiceCountValue,
+		DocumentDate:      order.Date,
+		IsSimplified:      order.Zatca.IsSimplified,
+	}, errorMessage)
 	return nil
 }
 
@@ -791,10 +781,18 @@ func (order *Order) RecordZatcaReportingSuccess(reportingResponse ZatcaReporting
 			return err
 		}*/
 
+	resolveZatcaQueueItem(order.StoreID, ZatcaDocumentOrder, order.ID)
 	return nil
 }
 
+// ReportToZatca reports the order to ZATCA and emits the reported or failed webhook event.
 func (order *Order) ReportToZatca() error {
+	err := order.reportToZatca()
+	emitZatcaWebhookEvent(order.StoreID, order.ID, WebhookEventOrderZatcaReported, WebhookEventOrderZatcaFailed, order, err)
+	return err
+}
+
+func (order *Order) reportToZatca() error {
 	var err error
 
 	store, err := FindStoreByID(order.StoreID, bson.M{})
@@ -802,112 +800,27 @@ func (order *Order) ReportToZatca() error {
 		return errors.New("error finding store: " + err.Error())
 	}
 
-	customer, err := store.FindCustomerByID(order.CustomerID, bson.M{})
-	if err != nil && err != mongo.ErrNoDocuments {
-		return errors.New("error finding customer: " + err.Error())
-	}
-
-	_, err = order.MakeXMLContent()
+	xmlContent, err := order.MakeXMLContent()
 	if err != nil {
 		return errors.New("error making xml: " + err.Error())
 	}
-	isSimplified := !customer.IsB2B()
 
-	// Create JSON payload for reporting/clearance
-	{
-		payload := map[string]interface{}{
-			"env":                              store.Zatca.Env,
-			"private_key":                      store.Zatca.PrivateKey,
-			"production_binary_security_token": store.Zatca.ProductionBinarySecurityToken,
-			"production_secret":                store.Zatca.ProductionSecret,
-			"xml_file_path":                    "ZatcaPython/templates/invoice_" + order.Code + ".xml",
-			"is_simplified":                    isSimplified,
-			"store_id":                         store.ID.Hex(),
-		}
-
-		// Convert payload to JSON
-		jsonData, err := json.Marshal(payload)
-		if err != nil {
-			return err
-		}
-
-		pythonBinary := "ZatcaPython/venv/bin/python"
-		scriptPath := "ZatcaPython/reporting_and_clearance.py"
-
-		// Create command
-		cmd := exec.Command(pythonBinary, scriptPath)
-
-		// Set up pipes
-		cmd.Stdin = bytes.NewReader(jsonData) // Send JSON data to stdin
-		var output bytes.Buffer
-		var stderrOutput bytes.Buffer
-		cmd.Stdout = &output
-		cmd.Stderr = &stderrOutput
-
-		reportingResponse := ZatcaReportingResponse{}
-
-		// Run the command
-		err = cmd.Run()
-		if err != nil {
-			if stderrOutput.Len() > 0 {
-				log.Printf("[ZATCA reporting] Python stderr: %s", stderrOutput.String())
-			}
-			log.Printf("[ZATCA reporting] Python stdout: %s", output.String())
-			err = json.Unmarshal(output.Bytes(), &reportingResponse)
-			if err != nil {
-				errorMessage := "error running reporting script &  unmarshal reporting response : " + err.Error()
-				err = order.RecordZatcaReportingFailure(errorMessage)
-				if err != nil {
-					return err
-				}
-				return errors.New(errorMessage)
-			}
-
-			if reportingResponse.Error != "" {
-				errorMessage := "error running reporting script: " + reportingResponse.Error
-				err = order.RecordZatcaReportingFailure(errorMessage)
-				if err != nil {
-					return err
-				}
-				return errors.New(errorMessage)
-			}
-		}
-
-		// Parse JSON response
-		err = json.Unmarshal(output.Bytes(), &reportingResponse)
-		if err != nil {
-			errorMessage := "error unmarshal reporting response: " + err.Error()
-			err = order.RecordZatcaReportingFailure(errorMessage)
-			if err != nil {
-				return err
-			}
-			return errors.New(errorMessage)
-		}
-
-		//log.Printf("[ZATCA reporting] response: %s", output.String())
-
-		if reportingResponse.Error != "" || !reportingResponse.ReportingPassed {
-			errorMessage := "error reporting: " + reportingResponse.Error
-			err = order.RecordZatcaReportingFailure(errorMessage)
-			if err != nil {
-				return err
-			}
-			return errors.New(errorMessage)
-		}
-
-		err = order.RecordZatcaReportingSuccess(reportingResponse)
-		if err != nil {
-			return err
-		}
-
-		err = order.SaveClearedInvoiceData(reportingResponse)
+	reportingResponse := store.SubmitToZatca(xmlContent)
+	if reportingResponse.Error != "" || !reportingResponse.ReportingPassed {
+		errorMessage := "error reporting: " + reportingResponse.Error
+		err = order.RecordZatcaReportingFailure(errorMessage)
 		if err != nil {
 			return err
 		}
+		return errors.New(errorMessage)
+	}
 
+	err = order.RecordZatcaReportingSuccess(reportingResponse)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return order.SaveClearedInvoiceData(reportingResponse)
 }
 
 func (order *Order) SaveClearedInvoiceData(reportingResponse ZatcaReportingResponse) error {
@@ -922,9 +835,8 @@ func (order *Order) SaveClearedInvoiceData(reportingResponse ZatcaReportingRespo
 
 	// Step 2: Save to an XML file
 	//fileName := "output.xml"
-	//xmlResponseFilePath := "ZatcaPython/templates/invoice_" + order.Code + "_response.xml"
-	xmlResponseFilePath := "zatca/" + order.StoreID.Hex() + "/sales/xml/" + order.Code + ".xml"
-	if err = os.MkdirAll("zatca/"+order.StoreID.Hex()+"/sales/xml", 0755); err != nil {
+	xmlResponseFilePath := ZatcaXMLPath(order.StoreID, ZatcaDocumentOrder, order.Code)
+	if err = os.MkdirAll(filepath.Dir(xmlResponseFilePath), 0755); err != nil {
 		fmt.Println("Error creating directory:", err)
 		return err
 	}
@@ -1002,24 +914,5 @@ func (order *Order) SaveClearedInvoiceData(reportingResponse ZatcaReportingRespo
 			return err
 		}*/
 
-	// Delete xml files
-
-	xmlFilePath := "ZatcaPython/templates/invoice_" + order.Code + ".xml"
-	if _, err := os.Stat(xmlFilePath); err == nil {
-		err = os.Remove(xmlFilePath)
-		if err != nil {
-			return err
-		}
-	}
-
-	/*
-		if _, err := os.Stat(xmlResponseFilePath); err == nil {
-			err = os.Remove(xmlResponseFilePath)
-			if err != nil {
-				return err
-			}
-		}
-	*/
-
 	return nil
 }
@@ -32,6 +32,7 @@ type StockTransferProduct struct {
 	PurchaseUnitPrice          float64            `bson:"purchase_unit_price,omitempty" json:"purchase_unit_price,omitempty"`
 	PurchaseUnitPriceWithVAT   float64            `bson:"purchase_unit_price_with_vat,omitempty" json:"purchase_unit_price_with_vat,omitempty"`
 	Unit                       string             `bson:"unit,omitempty" json:"unit,omitempty"`
+	UnitConversionFactor       float64            `bson:"unit_conversion_factor,omitempty" json:"unit_conversion_factor,omitempty"`
 	UnitDiscount               float64            `bson:"unit_discount" json:"unit_discount"`
 	UnitDiscountWithVAT        float64            `bson:"unit_discount_with_vat" json:"unit_discount_with_vat"`
 	UnitDiscountPercent        float64            `bson:"unit_discount_percent" json:"unit_discount_percent"`
@@ -41,8 +42,10 @@ type StockTransferProduct struct {
 	ActualLineTotal            float64            `bson:"actual_line_total" json:"actual_line_total"`
 	ActualLineTotalWithVAT     float64            `bson:"actual_line_total_with_vat" json:"actual_line_total_with_vat"`
 	*/
-	Profit float64 `bson:"profit" json:"profit"`
-	Loss   float64 `bson:"loss" json:"loss"`
+	Profit        float64              `bson:"profit" json:"profit"`
+	Loss          float64              `bson:"loss" json:"loss"`
+	Lots          []ProductLotQuantity `bson:"lots,omitempty" json:"lots,omitempty"`
+	SerialNumbers []string             `bson:"serial_numbers,omitempty" json:"serial_numbers,omitempty"`
 }
 
 // StockTransfer : StockTransfer structure
@@ -621,6 +624,13 @@ func (stocktransfer *StockTransfer) Validate(w http.ResponseWriter, r *http.Requ
 
 			if !exists {
 				errs["product_id_"+strconv.Itoa(index)] = "Invalid product_id:" + product.ProductID.Hex() + " in products"
+			} else {
+				conversionFactor, err := store.resolveUnitConversionFactor(&product.ProductID, product.Unit)
+				if err != nil {
+					errs["unit_"+strconv.Itoa(index)] = err.Error()
+				} else {
+					stocktransfer.Products[index].UnitConversionFactor = conversionFactor
+				}
 			}
 		}
 
@@ -643,6 +653,13 @@ func (stocktransfer *StockTransfer) Validate(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
+	if store != nil {
+		err = stocktransfer.ValidateSerialNumbers(store, errs)
+		if err != nil {
+			errs["serial_numbers"] = err.Error()
+		}
+	}
+
 	if stocktransfer.VatPercent == nil {
 		errs["vat_percent"] = "VAT Percentage is required"
 	}
@@ -663,6 +680,16 @@ func (stocktransfer *StockTransfer) SetProductsStock() (err error) {
 		return nil
 	}
 
+	err = stocktransfer.SetProductsLots()
+	if err != nil {
+		return err
+	}
+
+	err = stocktransfer.SetProductsSerials()
+	if err != nil {
+		return err
+	}
+
 	for _, stocktransferProduct := range stocktransfer.Products {
 		product, err := store.FindProductByID(&stocktransferProduct.ProductID, bson.M{})
 		if err != nil {
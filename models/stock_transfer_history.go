@@ -400,7 +400,12 @@ func (stocktransfer *StockTransfer) CreateProductsStockTransferHistory() error {
 		history.NetPrice = RoundTo2Decimals((history.Price + history.VatPrice))
 		history.ID = primitive.NewObjectID()
 
-		_, err := collection.InsertOne(ctx, &history)
+		err := store.convertToBaseUnit(&stocktransferProduct.ProductID, stocktransferProduct.UnitConversionFactor, &history.Quantity, &history.Unit, &history.UnitPrice, &history.UnitPriceWithVAT, &history.PurchaseUnitPrice, &history.UnitDiscount)
+		if err != nil {
+			return err
+		}
+
+		_, err = collection.InsertOne(ctx, &history)
 		if err != nil {
 			return err
 		}
@@ -44,6 +44,7 @@ type Store struct {
 	ZipCodeInArabic                        string                `bson:"zipcode_in_arabic,omitempty" json:"zipcode_in_arabic,omitempty"`
 	CountryName                            string                `bson:"country_name" json:"country_name"`
 	CountryCode                            string                `bson:"country_code" json:"country_code"`
+	Currency                               string                `bson:"currency,omitempty" json:"currency,omitempty"`
 	VATNo                                  string                `bson:"vat_no" json:"vat_no"`
 	VATNoInArabic                          string                `bson:"vat_no_in_arabic,omitempty" json:"vat_no_in_arabic,omitempty"`
 	VatPercent                             float64               `bson:"vat_percent,omitempty" json:"vat_percent,omitempty"`
@@ -89,6 +90,7 @@ type Store struct {
 	StockTransferSerialNumber              SerialNumber          `bson:"stock_transfer_serial_number" json:"stock_transfer_serial_number"`
 	NonVATSalesSerialNumber                SerialNumber          `bson:"non_vat_sales_serial_number" json:"non_vat_sales_serial_number"`
 	NonVATSalesReturnSerialNumber          SerialNumber          `bson:"non_vat_sales_return_serial_number" json:"non_vat_sales_return_serial_number"`
+	DebitNoteSerialNumber                  SerialNumber          `bson:"debit_note_serial_number" json:"debit_note_serial_number"`
 	ShowAddressInInvoiceFooter             bool                  `bson:"show_address_in_invoice_footer" json:"show_address_in_invoice_footer,omitempty"`
 	DefaultQuotationValidityDays           *int64                `bson:"default_quotation_validity_days" json:"default_quotation_validity_days"`
 	DefaultQuotationDeliveryDays           *int64                `bson:"default_quotation_delivery_days" json:"default_quotation_delivery_days"`
@@ -193,6 +195,7 @@ type StoreSettings struct {
 	CashOpeningBalanceDate                      *time.Time      `bson:"cash_opening_balance_date,omitempty" json:"cash_opening_balance_date,omitempty"`
 	BankOpeningBalance                          float64         `bson:"bank_opening_balance" json:"bank_opening_balance"`
 	BankOpeningBalanceDate                      *time.Time      `bson:"bank_opening_balance_date,omitempty" json:"bank_opening_balance_date,omitempty"`
+	Loyalty                                     LoyaltySettings `bson:"loyalty" json:"loyalty"`
 }
 
 type InvoiceSettings struct {
@@ -247,7 +250,7 @@ type SerialNumber struct {
 
 type Zatca struct {
 	Phase                         string              `bson:"phase,omitempty" json:"phase"` //1 or 2
-	Env                           string              `bson:"env,omitempty" json:"env"`     //NonProduction | Simulation | Production
+	Env                           string              `bson:"env,omitempty" json:"env"`     //NonProduction | Simulation | Production | Sandbox
 	Otp                           string              `bson:"otp,omitempty" json:"otp"`     //Need to obtain from zatca when going to production level
 	PrivateKey                    string              `bson:"private_key,omitempty" json:"private_key"`
 	Csr                           string              `bson:"csr,omitempty" json:"csr"` //Need to generate from store details, update it whenever the store details updates
@@ -621,6 +624,7 @@ func (store *Store) TrimSpaceFromFields() {
 	store.DeliveryNoteSerialNumber.Prefix = strings.TrimSpace(store.DeliveryNoteSerialNumber.Prefix)
 	store.NonVATSalesSerialNumber.Prefix = strings.TrimSpace(store.NonVATSalesSerialNumber.Prefix)
 	store.NonVATSalesReturnSerialNumber.Prefix = strings.TrimSpace(store.NonVATSalesReturnSerialNumber.Prefix)
+	store.DebitNoteSerialNumber.Prefix = strings.TrimSpace(store.DebitNoteSerialNumber.Prefix)
 }
 
 func (store *Store) Validate(w http.ResponseWriter, r *http.Request, scenario string) (errs map[string]string) {
@@ -675,6 +679,11 @@ func (store *Store) Validate(w http.ResponseWriter, r *http.Request, scenario st
 		errs["country_code"] = "Country is required"
 	}
 
+	store.Currency = strings.ToUpper(strings.TrimSpace(store.Currency))
+	if !govalidator.IsNull(store.Currency) && !currencyCodePattern.MatchString(store.Currency) {
+		errs["currency"] = "Currency should be a 3 letter ISO code"
+	}
+
 	if govalidator.IsNull(store.Code) {
 		errs["code"] = "Branch code is required"
 	}
@@ -897,6 +906,10 @@ func (store *Store) Validate(w http.ResponseWriter, r *http.Request, scenario st
 		}
 	}
 
+	if message := store.ValidateZatcaEnv(oldStore); message != "" {
+		errs["zatca_env"] = message
+	}
+
 	/*if !store.ID.IsZero() && oldStore != nil && !govalidator.IsNull(oldStore.Zatca.Env) {
 		if store.Zatca.Env != oldStore.Zatca.Env {
 			salesCount, err := oldStore.GetCountByCollection("order")
@@ -1487,11 +1500,11 @@ func ProcessStores() error {
 	return nil
 }
 
-func (store *Store) ImportVendorsFromExcel(filename string) {
+func (store *Store) ImportVendorsFromExcel(filename string) error {
 	// Open the Excel file
 	f, err := excelize.OpenFile(filename)
 	if err != nil {
-		log.Fatal(err)
+		return errors.New("error opening " + filename + ": " + err.Error())
 	}
 	defer f.Close()
 
@@ -1501,7 +1514,10 @@ func (store *Store) ImportVendorsFromExcel(filename string) {
 	// Read all rows from the sheet
 	rows, err := f.GetRows(sheetName)
 	if err != nil {
-		log.Fatal(err)
+		return errors.New("error reading " + filename + ": " + err.Error())
+	}
+	if len(rows) == 0 {
+		return errors.New(filename + " is empty")
 	}
 
 	// Print each row
@@ -1511,9 +1527,9 @@ func (store *Store) ImportVendorsFromExcel(filename string) {
 		//fmt.Println()
 		if i > 0 {
 			now := time.Now()
-			name := strings.ToUpper(row[1])
-			vatNo := row[16]
-			phones := ExtractSaudiPhoneNumbers(row[5] + " " + row[6] + " " + row[9])
+			name := strings.ToUpper(ImportCell(row, 1))
+			vatNo := ImportCell(row, 16)
+			phones := ExtractSaudiPhoneNumbers(ImportCell(row, 5) + " " + ImportCell(row, 6) + " " + ImportCell(row, 9))
 
 			var vendor *Vendor
 			if len(phones) > 0 {
@@ -1548,22 +1564,22 @@ func (store *Store) ImportVendorsFromExcel(filename string) {
 			vendor.UpdatedAt = &now
 			vendor.Name = name
 
-			vendor.NationalAddress.CityName = row[8]
-			vendor.NationalAddress.ZipCode = row[19]
-			vendor.NationalAddress.ZipCodeArabic = ConvertToArabicNumerals(row[19])
-			vendor.NationalAddress.StreetName = row[20]
-			vendor.NationalAddress.AdditionalNo = row[21]
-			vendor.NationalAddress.AdditionalNoArabic = ConvertToArabicNumerals(row[21])
-			vendor.NationalAddress.BuildingNo = row[22]
-			vendor.NationalAddress.BuildingNoArabic = ConvertToArabicNumerals(row[22])
-			vendor.NationalAddress.DistrictName = row[23]
-
-			vendor.Remarks = row[9]
-			vendor.Sponsor = row[10]
+			vendor.NationalAddress.CityName = ImportCell(row, 8)
+			vendor.NationalAddress.ZipCode = ImportCell(row, 19)
+			vendor.NationalAddress.ZipCodeArabic = ConvertToArabicNumerals(ImportCell(row, 19))
+			vendor.NationalAddress.StreetName = ImportCell(row, 20)
+			vendor.NationalAddress.AdditionalNo = ImportCell(row, 21)
+			vendor.NationalAddress.AdditionalNoArabic = ConvertToArabicNumerals(ImportCell(row, 21))
+			vendor.NationalAddress.BuildingNo = ImportCell(row, 22)
+			vendor.NationalAddress.BuildingNoArabic = ConvertToArabicNumerals(ImportCell(row, 22))
+			vendor.NationalAddress.DistrictName = ImportCell(row, 23)
+
+			vendor.Remarks = ImportCell(row, 9)
+			vendor.Sponsor = ImportCell(row, 10)
 			vendor.VATNo = vatNo
-			vendor.VATNoInArabic = ConvertToArabicNumerals(row[16])
-			vendor.RegistrationNumber = row[17]
-			vendor.RegistrationNumberInArabic = ConvertToArabicNumerals(row[17])
+			vendor.VATNoInArabic = ConvertToArabicNumerals(ImportCell(row, 16))
+			vendor.RegistrationNumber = ImportCell(row, 17)
+			vendor.RegistrationNumberInArabic = ConvertToArabicNumerals(ImportCell(row, 17))
 
 			if len(phones) > 0 {
 				vendor.Phone = phones[0]
@@ -1605,13 +1621,15 @@ func (store *Store) ImportVendorsFromExcel(filename string) {
 			bar.Add(1) // 1 product added
 		}
 	}
+
+	return nil
 }
 
-func (store *Store) ImportCustomersFromExcel(filename string) {
+func (store *Store) ImportCustomersFromExcel(filename string) error {
 	// Open the Excel file
 	f, err := excelize.OpenFile(filename)
 	if err != nil {
-		log.Fatal(err)
+		return errors.New("error opening " + filename + ": " + err.Error())
 	}
 	defer f.Close()
 
@@ -1621,7 +1639,10 @@ func (store *Store) ImportCustomersFromExcel(filename string) {
 	// Read all rows from the sheet
 	rows, err := f.GetRows(sheetName)
 	if err != nil {
-		log.Fatal(err)
+		return errors.New("error reading " + filename + ": " + err.Error())
+	}
+	if len(rows) == 0 {
+		return errors.New(filename + " is empty")
 	}
 
 	// Print each row
@@ -1632,9 +1653,9 @@ func (store *Store) ImportCustomersFromExcel(filename string) {
 		if i > 0 {
 			now := time.Now()
 
-			name := strings.ToUpper(row[1])
-			vatNo := row[16]
-			phones := ExtractSaudiPhoneNumbers(row[5] + " " + row[6])
+			name := strings.ToUpper(ImportCell(row, 1))
+			vatNo := ImportCell(row, 16)
+			phones := ExtractSaudiPhoneNumbers(ImportCell(row, 5) + " " + ImportCell(row, 6))
 
 			var customer *Customer
 			if len(phones) > 0 {
@@ -1669,22 +1690,22 @@ func (store *Store) ImportCustomersFromExcel(filename string) {
 			customer.UpdatedAt = &now
 			customer.Name = name
 
-			customer.NationalAddress.CityName = row[8]
-			customer.NationalAddress.ZipCode = row[19]
-			customer.NationalAddress.ZipCodeArabic = ConvertToArabicNumerals(row[19])
-			customer.NationalAddress.StreetName = row[20]
-			customer.NationalAddress.AdditionalNo = row[21]
-			customer.NationalAddress.AdditionalNoArabic = ConvertToArabicNumerals(row[21])
-			customer.NationalAddress.BuildingNo = row[22]
-			customer.NationalAddress.BuildingNoArabic = ConvertToArabicNumerals(row[22])
-			customer.NationalAddress.DistrictName = row[23]
-
-			customer.Remarks = row[9]
-			customer.Sponsor = row[10]
+			customer.NationalAddress.CityName = ImportCell(row, 8)
+			customer.NationalAddress.ZipCode = ImportCell(row, 19)
+			customer.NationalAddress.ZipCodeArabic = ConvertToArabicNumerals(ImportCell(row, 19))
+			customer.NationalAddress.StreetName = ImportCell(row, 20)
+			customer.NationalAddress.AdditionalNo = ImportCell(row, 21)
+			customer.NationalAddress.AdditionalNoArabic = ConvertToArabicNumerals(ImportCell(row, 21))
+			customer.NationalAddress.BuildingNo = ImportCell(row, 22)
+			customer.NationalAddress.BuildingNoArabic = ConvertToArabicNumerals(ImportCell(row, 22))
+			customer.NationalAddress.DistrictName = ImportCell(row, 23)
+
+			customer.Remarks = ImportCell(row, 9)
+			customer.Sponsor = ImportCell(row, 10)
 			customer.VATNo = vatNo
-			customer.VATNoInArabic = ConvertToArabicNumerals(row[16])
-			customer.RegistrationNumber = row[17]
-			customer.RegistrationNumberInArabic = ConvertToArabicNumerals(row[17])
+			customer.VATNoInArabic = ConvertToArabicNumerals(ImportCell(row, 16))
+			customer.RegistrationNumber = ImportCell(row, 17)
+			customer.RegistrationNumberInArabic = ConvertToArabicNumerals(ImportCell(row, 17))
 
 			if len(phones) > 0 {
 				customer.Phone = phones[0]
@@ -1726,6 +1747,8 @@ func (store *Store) ImportCustomersFromExcel(filename string) {
 			bar.Add(1) // 1 product added
 		}
 	}
+
+	return nil
 }
 
 func ExtractSaudiPhoneNumbers(input string) []string {
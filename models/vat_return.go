@@ -0,0 +1,597 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/db"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Boxes of the ZATCA VAT return form
+const (
+	VATBoxStandardRatedSales     = 1
+	VATBoxSpecialSales           = 2
+	VATBoxZeroRatedSales         = 3
+	VATBoxExports                = 4
+	VATBoxExemptSales            = 5
+	VATBoxTotalSales             = 6
+	VATBoxStandardRatedPurchases = 7
+	VATBoxImportsPaidAtCustoms   = 8
+	VATBoxImportsReverseCharge   = 9
+	VATBoxZeroRatedPurchases     = 10
+	VATBoxExemptPurchases        = 11
+	VATBoxTotalPurchases         = 12
+	VATBoxTotalVATDue            = 13
+	VATBoxCorrections            = 14
+	VATBoxCreditCarriedForward   = 15
+	VATBoxNetVATDue              = 16
+
+	VATReasonInvoice      = "invoice"
+	VATReasonReturn       = "return"
+	VATReasonDebitNote    = "debit_note"
+	VATReasonCashDiscount = "cash_discount"
+)
+
+var vatBoxLabels = map[int]string{
+	VATBoxStandardRatedSales:     "Standard rated sales",
+	VATBoxSpecialSales:           "Private healthcare / private education / first house sales to citizens",
+	VATBoxZeroRatedSales:         "Zero rated domestic sales",
+	VATBoxExports:                "Exports",
+	VATBoxExemptSales:            "Exempt sales",
+	VATBoxTotalSales:             "Total sales",
+	VATBoxStandardRatedPurchases: "Standard rated domestic purchases",
+	VATBoxImportsPaidAtCustoms:   "Imports subject to VAT paid at customs",
+	VATBoxImportsReverseCharge:   "Imports subject to VAT accounted for through reverse charge mechanism",
+	VATBoxZeroRatedPurchases:     "Zero rated purchases",
+	VATBoxExemptPurchases:        "Exempt purchases",
+	VATBoxTotalPurchases:         "Total purchases",
+	VATBoxTotalVATDue:            "Total VAT due for current period",
+	VATBoxCorrections:            "Corrections from previous period (between SAR ±5,000)",
+	VATBoxCreditCarriedForward:   "VAT credit carried forward from previous period(s)",
+	VATBoxNetVATDue:              "Net VAT due (or claimed)",
+}
+
+var vatQuarterPattern = regexp.MustCompile(`^(\d{4})-Q([1-4])$`)
+
+// VATReturnBox : one line of the VAT return, amounts are in the store's base currency
+type VATReturnBox struct {
+	Number        int     `json:"number"`
+	Label         string  `json:"label"`
+	Amount        float64 `json:"amount"`
+	Adjustment    float64 `json:"adjustment"`
+	VAT           float64 `json:"vat"`
+	DocumentCount int     `json:"document_count"`
+}
+
+// VATReturnDocument : what one document contributes to a box. Invoices add to Amount, returns and
+// cash discounts to Adjustment (negative); VAT is the net of both.
+type VATReturnDocument struct {
+	Box            int                `json:"box"`
+	Reason         string             `json:"reason"`
+	ReferenceID    primitive.ObjectID `json:"reference_id"`
+	ReferenceModel string             `json:"reference_model"`
+	ReferenceCode  string             `json:"reference_code"`
+	Date           *time.Time         `json:"date"`
+	PartyName      string             `json:"party_name,omitempty"`
+	PartyVATNo     string             `json:"party_vat_no,omitempty"`
+	Currency       string             `json:"currency,omitempty"`
+	ExchangeRate   float64            `json:"exchange_rate,omitempty"`
+	Amount         float64            `json:"amount"`
+	Adjustment     float64            `json:"adjustment"`
+	VAT            float64            `json:"vat"`
+}
+
+// VATReturn : the ZATCA VAT return of a store for a period
+type VATReturn struct {
+	StoreName    string              `json:"store_name"`
+	VATNo        string              `json:"vat_no"`
+	BaseCurrency string              `json:"base_currency"`
+	Label        string              `json:"label"`
+	From         *time.Time          `json:"from"`
+	To           *time.Time          `json:"to"`
+	Boxes        []VATReturnBox      `json:"boxes"`
+	Documents    []VATReturnDocument `json:"documents,omitempty"`
+}
+
+// vatSourceDocument : the fields the VAT return reads, common to every collection it scans
+type vatSourceDocument struct {
+	ID             primitive.ObjectID  `bson:"_id"`
+	Code           string              `bson:"code"`
+	Date           *time.Time          `bson:"date"`
+	CustomerID     *primitive.ObjectID `bson:"customer_id"`
+	CustomerName   string              `bson:"customer_name"`
+	VendorID       *primitive.ObjectID `bson:"vendor_id"`
+	VendorName     string              `bson:"vendor_name"`
+	VatNo          string              `bson:"vat_no"`
+	VatPrice       float64             `bson:"vat_price"`
+	NetTotal       float64             `bson:"net_total"`
+	RoundingAmount float64             `bson:"rounding_amount"`
+	CashDiscount   float64             `bson:"cash_discount"`
+	Amount         float64             `bson:"amount"`
+	Taxable        bool                `bson:"taxable"`
+	Currency       string              `bson:"currency"`
+	ExchangeRate   float64             `bson:"exchange_rate"`
+	OrderID        *primitive.ObjectID `bson:"order_id"`
+	PurchaseID     *primitive.ObjectID `bson:"purchase_id"`
+}
+
+// vatParty : country and VAT registration of a customer or vendor
+type vatParty struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	Name        string             `bson:"name"`
+	VATNo       string             `bson:"vat_no"`
+	CountryCode string             `bson:"country_code"`
+}
+
+func isForeignCountry(countryCode string) bool {
+	countryCode = strings.ToUpper(strings.TrimSpace(countryCode))
+	return countryCode != "" && countryCode != "SA"
+}
+
+// VATSalesBox returns the box of a sale: standard rated when VAT was charged, else an export
+// when the customer is abroad and zero rated otherwise.
+func VATSalesBox(vatPrice float64, customerCountryCode string) int {
+	if vatPrice != 0 {
+		return VATBoxStandardRatedSales
+	} else if isForeignCountry(customerCountryCode) {
+		return VATBoxExports
+	}
+	return VATBoxZeroRatedSales
+}
+
+// VATPurchaseBox returns the box of a purchase. Imports are purchases from a vendor abroad: with VAT
+// on the invoice it was paid at customs, without it the store accounts for it by reverse charge.
+// Domestic purchases without VAT are zero rated from a VAT registered vendor, else exempt.
+func VATPurchaseBox(vatPrice float64, vendorCountryCode string, vendorVATNo string) int {
+	if isForeignCountry(vendorCountryCode) {
+		if vatPrice != 0 {
+			return VATBoxImportsPaidAtCustoms
+		}
+		return VATBoxImportsReverseCharge
+	}
+	if vatPrice != 0 {
+		return VATBoxStandardRatedPurchases
+	} else if strings.TrimSpace(vendorVATNo) != "" {
+		return VATBoxZeroRatedPurchases
+	}
+	return VATBoxExemptPurchases
+}
+
+// MakeVATReturnDocuments splits a document into its contribution to box: the invoice, return or
+// debit note itself and, for invoices, the cash discount given on it. Returns adjust the box down
+// and debit notes up. Amounts are converted at rate.
+func MakeVATReturnDocuments(base VATReturnDocument, taxable, vat, cashDiscount, rate float64, reason string) []VATReturnDocument {
+	if rate <= 0 {
+		rate = 1
+	}
+	taxable, vat, cashDiscount = taxable*rate, vat*rate, cashDiscount*rate
+
+	document := base
+	document.Reason = reason
+	switch reason {
+	case VATReasonReturn:
+		document.Adjustment = RoundTo2Decimals(-taxable)
+		document.VAT = RoundTo2Decimals(-vat)
+	case VATReasonDebitNote:
+		document.Adjustment = RoundTo2Decimals(taxable)
+		document.VAT = RoundTo2Decimals(vat)
+	default:
+		document.Reason = VATReasonInvoice
+		document.Amount = RoundTo2Decimals(taxable)
+		document.VAT = RoundTo2Decimals(vat)
+	}
+	documents := []VATReturnDocument{document}
+
+	if document.Reason == VATReasonInvoice && cashDiscount > 0 && taxable+vat > 0 {
+		discount := base
+		discount.Reason = VATReasonCashDiscount
+		discountTaxable := RoundTo2Decimals(cashDiscount * taxable / (taxable + vat))
+		discount.Adjustment = -discountTaxable
+		discount.VAT = RoundTo2Decimals(-(cashDiscount - discountTaxable))
+		documents = append(documents, discount)
+	}
+
+	return documents
+}
+
+// BuildVATReturn sums the documents into the 16 boxes of the return. Reverse charge VAT on imports
+// (box 9) is both output and input tax, so it is added to the VAT due as well as to the purchases.
+func BuildVATReturn(documents []VATReturnDocument, reverseChargeVatPercent, corrections, creditCarriedForward float64) []VATReturnBox {
+	boxes := make([]VATReturnBox, VATBoxNetVATDue)
+	for i := range boxes {
+		boxes[i] = VATReturnBox{Number: i + 1, Label: vatBoxLabels[i+1]}
+	}
+
+	for i := range documents {
+		if documents[i].Box == VATBoxImportsReverseCharge {
+			documents[i].VAT = RoundTo2Decimals((documents[i].Amount + documents[i].Adjustment) * reverseChargeVatPercent / 100)
+		}
+		box := &boxes[documents[i].Box-1]
+		box.Amount += documents[i].Amount
+		box.Adjustment += documents[i].Adjustment
+		box.VAT += documents[i].VAT
+		box.DocumentCount++
+	}
+
+	sum := func(total int, from, to int) {
+		for number := from; number <= to; number++ {
+			boxes[total-1].Amount += boxes[number-1].Amount
+			boxes[total-1].Adjustment += boxes[number-1].Adjustment
+			boxes[total-1].VAT += boxes[number-1].VAT
+			boxes[total-1].DocumentCount += boxes[number-1].DocumentCount
+		}
+	}
+	sum(VATBoxTotalSales, VATBoxStandardRatedSales, VATBoxExemptSales)
+	sum(VATBoxTotalPurchases, VATBoxStandardRatedPurchases, VATBoxExemptPurchases)
+
+	for i := range boxes {
+		boxes[i].Amount = RoundTo2Decimals(boxes[i].Amount)
+		boxes[i].Adjustment = RoundTo2Decimals(boxes[i].Adjustment)
+		boxes[i].VAT = RoundTo2Decimals(boxes[i].VAT)
+	}
+
+	boxes[VATBoxTotalVATDue-1].VAT = RoundTo2Decimals(boxes[VATBoxTotalSales-1].VAT + boxes[VATBoxImportsReverseCharge-1].VAT - boxes[VATBoxTotalPurchases-1].VAT)
+	boxes[VATBoxCorrections-1].VAT = RoundTo2Decimals(corrections)
+	boxes[VATBoxCreditCarriedForward-1].VAT = RoundTo2Decimals(creditCarriedForward)
+	boxes[VATBoxNetVATDue-1].VAT = RoundTo2Decimals(boxes[VATBoxTotalVATDue-1].VAT + corrections - creditCarriedForward)
+
+	return boxes
+}
+
+// ParseVATQuarter returns the first and last local day of a quarter given as 2026-Q1.
+func ParseVATQuarter(value string) (from time.Time, to time.Time, err error) {
+	matches := vatQuarterPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(value)))
+	if matches == nil {
+		return from, to, errors.New("invalid quarter, expected format: 2026-Q1")
+	}
+	year, _ := strconv.Atoi(matches[1])
+	quarter, _ := strconv.Atoi(matches[2])
+	from = time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, time.UTC)
+	to = from.AddDate(0, 3, -1)
+	return from, to, nil
+}
+
+func (store *Store) findVATSourceDocuments(collectionName string, filter bson.M) ([]vatSourceDocument, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection(collectionName)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cur, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"date": 1}).SetProjection(bson.M{
+		"_id": 1, "code": 1, "date": 1, "customer_id": 1, "customer_name": 1, "vendor_id": 1, "vendor_name": 1,
+		"vat_no": 1, "vat_price": 1, "net_total": 1, "rounding_amount": 1, "cash_discount": 1, "amount": 1,
+		"taxable": 1, "currency": 1, "exchange_rate": 1, "order_id": 1, "purchase_id": 1,
+	}))
+	if err != nil {
+		return nil, errors.New("error fetching " + collectionName + ":" + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	documents := []vatSourceDocument{}
+	for cur.Next(ctx) {
+		document := vatSourceDocument{}
+		if err := cur.Decode(&document); err != nil {
+			return nil, errors.New("cursor decode error:" + err.Error())
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+// setReturnCurrencies gives returns saved without a currency the currency and rate of the sale
+// or purchase they reverse, which returns are always kept in.
+func setReturnCurrencies(returns []vatSourceDocument, originals []vatSourceDocument) {
+	byID := map[primitive.ObjectID]vatSourceDocument{}
+	for _, original := range originals {
+		byID[original.ID] = original
+	}
+
+	for i := range returns {
+		if returns[i].Currency != "" {
+			continue
+		}
+		originalID := returns[i].OrderID
+		if originalID == nil {
+			originalID = returns[i].PurchaseID
+		}
+		if originalID == nil {
+			continue
+		}
+		if original, ok := byID[*originalID]; ok {
+			returns[i].Currency = original.Currency
+			returns[i].ExchangeRate = original.ExchangeRate
+		}
+	}
+}
+
+// findVATReturnOriginals loads the currency of the sales or purchases reversed by returns saved without one.
+func (store *Store) findVATReturnOriginals(collectionName string, returns []vatSourceDocument) ([]vatSourceDocument, error) {
+	IDs := []primitive.ObjectID{}
+	for _, document := range returns {
+		if document.Currency != "" {
+			continue
+		}
+		if document.OrderID != nil && !document.OrderID.IsZero() {
+			IDs = append(IDs, *document.OrderID)
+		} else if document.PurchaseID != nil && !document.PurchaseID.IsZero() {
+			IDs = append(IDs, *document.PurchaseID)
+		}
+	}
+	if len(IDs) == 0 {
+		return nil, nil
+	}
+	return store.findVATSourceDocuments(collectionName, bson.M{"_id": bson.M{"$in": IDs}})
+}
+
+func (store *Store) findVATParties(collectionName string, IDs []primitive.ObjectID) (map[primitive.ObjectID]vatParty, error) {
+	parties := map[primitive.ObjectID]vatParty{}
+	if len(IDs) == 0 {
+		return parties, nil
+	}
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection(collectionName)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cur, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": IDs}}, options.Find().SetProjection(bson.M{"name": 1, "vat_no": 1, "country_code": 1}))
+	if err != nil {
+		return nil, errors.New("error fetching " + collectionName + ":" + err.Error())
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		party := vatParty{}
+		if err := cur.Decode(&party); err != nil {
+			return nil, errors.New("cursor decode error:" + err.Error())
+		}
+		parties[party.ID] = party
+	}
+	return parties, nil
+}
+
+// FindVATReturnDocuments classifies every sale, debit note, purchase, return and taxable expense dated from..to.
+func (store *Store) FindVATReturnDocuments(from, to time.Time) ([]VATReturnDocument, error) {
+	filter := bson.M{
+		"store_id": store.ID,
+		"date":     bson.M{"$gte": from, "$lte": to},
+		"deleted":  bson.M{"$ne": true},
+	}
+
+	sources := []struct {
+		collection     string
+		referenceModel string
+		sales          bool
+		reason         string
+		exempt         bool
+		original       string
+	}{
+		{"order", "sales", true, VATReasonInvoice, false, ""},
+		{"salesreturn", "sales_return", true, VATReasonReturn, false, "order"},
+		{"debitnote", DebitNoteReferenceModel, true, VATReasonDebitNote, false, ""},
+		{"non_vat_sales", "non_vat_sales", true, VATReasonInvoice, true, ""},
+		{"non_vat_sales_return", "non_vat_sales_return", true, VATReasonReturn, true, ""},
+		{"purchase", "purchase", false, VATReasonInvoice, false, ""},
+		{"purchasereturn", "purchase_return", false, VATReasonReturn, false, "purchase"},
+		{"expense", "expense", false, VATReasonInvoice, false, ""},
+	}
+
+	loaded := map[string][]vatSourceDocument{}
+	customerIDs, vendorIDs := []primitive.ObjectID{}, []primitive.ObjectID{}
+	for _, source := range sources {
+		sourceFilter := filter
+		if source.collection == "expense" {
+			sourceFilter = bson.M{"taxable": true}
+			for key, value := range filter {
+				sourceFilter[key] = value
+			}
+		}
+		documents, err := store.findVATSourceDocuments(source.collection, sourceFilter)
+		if err != nil {
+			return nil, err
+		}
+		if source.original != "" {
+			originals, err := store.findVATReturnOriginals(source.original, documents)
+			if err != nil {
+				return nil, err
+			}
+			setReturnCurrencies(documents, originals)
+		}
+		loaded[source.collection] = documents
+		for _, document := range documents {
+			if document.CustomerID != nil && !document.CustomerID.IsZero() {
+				customerIDs = append(customerIDs, *document.CustomerID)
+			}
+			if document.VendorID != nil && !document.VendorID.IsZero() {
+				vendorIDs = append(vendorIDs, *document.VendorID)
+			}
+		}
+	}
+
+	customers, err := store.findVATParties("customer", customerIDs)
+	if err != nil {
+		return nil, err
+	}
+	vendors, err := store.findVATParties("vendor", vendorIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := []VATReturnDocument{}
+	for _, source := range sources {
+		for _, model := range loaded[source.collection] {
+			base := VATReturnDocument{
+				ReferenceID:    model.ID,
+				ReferenceModel: source.referenceModel,
+				ReferenceCode:  model.Code,
+				Date:           model.Date,
+				PartyVATNo:     model.VatNo,
+			}
+
+			rate := 1.0
+			if store.IsForeignCurrency(model.Currency) && model.ExchangeRate > 0 {
+				rate = model.ExchangeRate
+				base.Currency = model.Currency
+				base.ExchangeRate = model.ExchangeRate
+			}
+
+			taxable := RoundTo2Decimals(model.NetTotal - model.RoundingAmount - model.VatPrice)
+			if source.collection == "expense" {
+				taxable = RoundTo2Decimals(model.Amount - model.VatPrice)
+			}
+
+			if source.sales {
+				base.PartyName = model.CustomerName
+				country := ""
+				if model.CustomerID != nil {
+					if customer, ok := customers[*model.CustomerID]; ok {
+						country = customer.CountryCode
+						if base.PartyName == "" {
+							base.PartyName = customer.Name
+						}
+						if base.PartyVATNo == "" {
+							base.PartyVATNo = customer.VATNo
+						}
+					}
+				}
+				if source.exempt {
+					base.Box = VATBoxExemptSales
+				} else {
+					base.Box = VATSalesBox(model.VatPrice, country)
+				}
+			} else {
+				base.PartyName = model.VendorName
+				country := ""
+				if model.VendorID != nil {
+					if vendor, ok := vendors[*model.VendorID]; ok {
+						country = vendor.CountryCode
+						if base.PartyName == "" {
+							base.PartyName = vendor.Name
+						}
+						if base.PartyVATNo == "" {
+							base.PartyVATNo = vendor.VATNo
+						}
+					}
+				}
+				base.Box = VATPurchaseBox(model.VatPrice, country, base.PartyVATNo)
+			}
+
+			documents = append(documents, MakeVATReturnDocuments(base, taxable, model.VatPrice, model.CashDiscount, rate, source.reason)...)
+		}
+	}
+
+	sort.SliceStable(documents, func(i, j int) bool {
+		if documents[i].Box != documents[j].Box {
+			return documents[i].Box < documents[j].Box
+		}
+		return documents[i].Date != nil && documents[j].Date != nil && documents[i].Date.Before(*documents[j].Date)
+	})
+
+	return documents, nil
+}
+
+// MakeVATReturn builds the VAT return of the local dates from..to.
+func (store *Store) MakeVATReturn(from, to time.Time, corrections, creditCarriedForward float64) (*VATReturn, error) {
+	timeZoneOffset := CountryTimezoneOffset(store.CountryCode)
+	start := ConvertTimeZoneToUTC(timeZoneOffset, from)
+	end := ConvertTimeZoneToUTC(timeZoneOffset, to).Add(24*time.Hour - time.Second)
+
+	documents, err := store.FindVATReturnDocuments(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	vatPercent := store.VatPercent
+	if vatPercent == 0 {
+		vatPercent = 15
+	}
+
+	return &VATReturn{
+		StoreName:    store.Name,
+		VATNo:        store.VATNo,
+		BaseCurrency: store.BaseCurrency(),
+		Label:        from.Format(statementDateFormat) + " - " + to.Format(statementDateFormat),
+		From:         &start,
+		To:           &end,
+		Boxes:        BuildVATReturn(documents, vatPercent, corrections, creditCarriedForward),
+		Documents:    documents,
+	}, nil
+}
+
+// FilterDocuments keeps only the documents of box, totals keep their contributing boxes.
+func (vatReturn *VATReturn) FilterDocuments(box int) {
+	from, to := box, box
+	if box == VATBoxTotalSales {
+		from, to = VATBoxStandardRatedSales, VATBoxExemptSales
+	} else if box == VATBoxTotalPurchases {
+		from, to = VATBoxStandardRatedPurchases, VATBoxExemptPurchases
+	}
+
+	documents := []VATReturnDocument{}
+	for _, document := range vatReturn.Documents {
+		if document.Box >= from && document.Box <= to {
+			documents = append(documents, document)
+		}
+	}
+	vatReturn.Documents = documents
+}
+
+// WriteXLSX writes the boxes on the first sheet and the contributing documents on a second one.
+func (vatReturn *VATReturn) WriteXLSX(w io.Writer, timeZoneOffset float64) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	f.SetSheetName(sheet, "VAT Return")
+	sheet = "VAT Return"
+	row := 1
+	setRow := func(values ...interface{}) {
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		f.SetSheetRow(sheet, cell, &values)
+		row++
+	}
+
+	setRow(vatReturn.StoreName)
+	setRow("VAT Return", vatReturn.Label)
+	setRow("VAT No.", vatReturn.VATNo)
+	row++
+
+	setRow("Box", "Description", "Amount ("+vatReturn.BaseCurrency+")", "Adjustment ("+vatReturn.BaseCurrency+")", "VAT ("+vatReturn.BaseCurrency+")")
+	for _, box := range vatReturn.Boxes {
+		if box.Number >= VATBoxTotalVATDue {
+			setRow(box.Number, box.Label, "", "", box.VAT)
+		} else {
+			setRow(box.Number, box.Label, box.Amount, box.Adjustment, box.VAT)
+		}
+	}
+	f.SetColWidth(sheet, "B", "B", 70)
+	f.SetColWidth(sheet, "C", "E", 18)
+
+	sheet = "Documents"
+	f.NewSheet(sheet)
+	row = 1
+	setRow("Box", "Type", "Reason", "No.", "Date", "Party", "VAT No.", "Currency", "Rate", "Amount", "Adjustment", "VAT")
+	for _, document := range vatReturn.Documents {
+		date := ""
+		if document.Date != nil {
+			date = document.Date.Add(-time.Duration(timeZoneOffset * float64(time.Hour))).Format("2006-01-02 15:04")
+		}
+		rate := ""
+		if document.ExchangeRate > 0 {
+			rate = fmt.Sprintf("%g", document.ExchangeRate)
+		}
+		setRow(document.Box, document.ReferenceModel, document.Reason, document.ReferenceCode, date, document.PartyName, document.PartyVATNo, document.Currency, rate, document.Amount, document.Adjustment, document.VAT)
+	}
+	f.SetColWidth(sheet, "F", "F", 40)
+
+	return f.Write(w)
+}
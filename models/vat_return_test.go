@@ -0,0 +1,187 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ── Classification ───────────────────────────────────────────────────────────
+
+func TestVATSalesBox(t *testing.T) {
+	cases := []struct {
+		vat     float64
+		country string
+		want    int
+	}{
+		{15, "SA", VATBoxStandardRatedSales},
+		{15, "AE", VATBoxStandardRatedSales},
+		{0, "", VATBoxZeroRatedSales},
+		{0, "sa", VATBoxZeroRatedSales},
+		{0, "AE", VATBoxExports},
+	}
+	for _, c := range cases {
+		if got := VATSalesBox(c.vat, c.country); got != c.want {
+			t.Errorf("VATSalesBox(%v, %q) = %d, want %d", c.vat, c.country, got, c.want)
+		}
+	}
+}
+
+func TestVATPurchaseBox(t *testing.T) {
+	cases := []struct {
+		vat     float64
+		country string
+		vatNo   string
+		want    int
+	}{
+		{15, "SA", "300000000000003", VATBoxStandardRatedPurchases},
+		{0, "SA", "300000000000003", VATBoxZeroRatedPurchases},
+		{0, "", "", VATBoxExemptPurchases},
+		{150, "CN", "", VATBoxImportsPaidAtCustoms},
+		{0, "CN", "", VATBoxImportsReverseCharge},
+	}
+	for _, c := range cases {
+		if got := VATPurchaseBox(c.vat, c.country, c.vatNo); got != c.want {
+			t.Errorf("VATPurchaseBox(%v, %q, %q) = %d, want %d", c.vat, c.country, c.vatNo, got, c.want)
+		}
+	}
+}
+
+// ── MakeVATReturnDocuments ───────────────────────────────────────────────────
+
+func TestMakeVATReturnDocuments_InvoiceWithCashDiscount(t *testing.T) {
+	base := VATReturnDocument{Box: VATBoxStandardRatedSales, ReferenceCode: "S-1"}
+
+	documents := MakeVATReturnDocuments(base, 1000, 150, 115, 1, VATReasonInvoice)
+	if len(documents) != 2 {
+		t.Fatalf("documents = %d, want invoice and cash discount", len(documents))
+	}
+	if documents[0].Reason != VATReasonInvoice || documents[0].Amount != 1000 || documents[0].VAT != 150 {
+		t.Errorf("invoice = %+v", documents[0])
+	}
+	if documents[1].Reason != VATReasonCashDiscount || documents[1].Adjustment != -100 || documents[1].VAT != -15 {
+		t.Errorf("cash discount = %+v", documents[1])
+	}
+}
+
+func TestMakeVATReturnDocuments_ReturnInForeignCurrency(t *testing.T) {
+	base := VATReturnDocument{Box: VATBoxStandardRatedSales}
+
+	documents := MakeVATReturnDocuments(base, 100, 15, 10, 3.75, VATReasonReturn)
+	if len(documents) != 1 {
+		t.Fatalf("documents = %d, want only the return", len(documents))
+	}
+	if documents[0].Reason != VATReasonReturn || documents[0].Amount != 0 || documents[0].Adjustment != -375 || documents[0].VAT != -56.25 {
+		t.Errorf("return = %+v", documents[0])
+	}
+}
+
+func TestMakeVATReturnDocuments_DebitNoteAdjustsSalesUp(t *testing.T) {
+	base := VATReturnDocument{Box: VATBoxStandardRatedSales, ReferenceModel: DebitNoteReferenceModel}
+
+	documents := MakeVATReturnDocuments(base, 200, 30, 0, 1, VATReasonDebitNote)
+	if len(documents) != 1 {
+		t.Fatalf("documents = %d, want only the debit note", len(documents))
+	}
+	if documents[0].Reason != VATReasonDebitNote || documents[0].Amount != 0 || documents[0].Adjustment != 200 || documents[0].VAT != 30 {
+		t.Errorf("debit note = %+v", documents[0])
+	}
+}
+
+func TestSetReturnCurrencies_InheritsTheRateOfTheOriginal(t *testing.T) {
+	order := vatSourceDocument{ID: primitive.NewObjectID(), Currency: "USD", ExchangeRate: 3.75}
+	purchase := vatSourceDocument{ID: primitive.NewObjectID(), Currency: "EUR", ExchangeRate: 4.1}
+	returns := []vatSourceDocument{
+		{OrderID: &order.ID},
+		{PurchaseID: &purchase.ID},
+		{OrderID: &order.ID, Currency: "USD", ExchangeRate: 3.7},
+		{},
+	}
+
+	setReturnCurrencies(returns, []vatSourceDocument{order, purchase})
+
+	if returns[0].Currency != "USD" || returns[0].ExchangeRate != 3.75 {
+		t.Errorf("sales return = %s @ %v, want USD @ 3.75", returns[0].Currency, returns[0].ExchangeRate)
+	}
+	if returns[1].Currency != "EUR" || returns[1].ExchangeRate != 4.1 {
+		t.Errorf("purchase return = %s @ %v, want EUR @ 4.1", returns[1].Currency, returns[1].ExchangeRate)
+	}
+	if returns[2].ExchangeRate != 3.7 {
+		t.Errorf("saved rate = %v, want 3.7 kept", returns[2].ExchangeRate)
+	}
+	if returns[3].Currency != "" {
+		t.Errorf("return without an original = %q, want none", returns[3].Currency)
+	}
+}
+
+// ── BuildVATReturn ───────────────────────────────────────────────────────────
+
+func TestBuildVATReturn(t *testing.T) {
+	documents := []VATReturnDocument{
+		{Box: VATBoxStandardRatedSales, Amount: 10000, VAT: 1500},
+		{Box: VATBoxStandardRatedSales, Adjustment: -1000, VAT: -150},
+		{Box: VATBoxExports, Amount: 2000},
+		{Box: VATBoxExemptSales, Amount: 500},
+		{Box: VATBoxStandardRatedPurchases, Amount: 4000, VAT: 600},
+		{Box: VATBoxImportsReverseCharge, Amount: 1000},
+	}
+
+	boxes := BuildVATReturn(documents, 15, 20, 100)
+	if len(boxes) != 16 {
+		t.Fatalf("boxes = %d, want 16", len(boxes))
+	}
+
+	box := func(number int) VATReturnBox { return boxes[number-1] }
+	if b := box(VATBoxStandardRatedSales); b.Amount != 10000 || b.Adjustment != -1000 || b.VAT != 1350 || b.DocumentCount != 2 {
+		t.Errorf("box 1 = %+v", b)
+	}
+	if b := box(VATBoxTotalSales); b.Amount != 12500 || b.Adjustment != -1000 || b.VAT != 1350 || b.DocumentCount != 4 {
+		t.Errorf("box 6 = %+v", b)
+	}
+	if b := box(VATBoxImportsReverseCharge); b.VAT != 150 {
+		t.Errorf("box 9 = %+v, want VAT at 15%%", b)
+	}
+	if b := box(VATBoxTotalPurchases); b.Amount != 5000 || b.VAT != 750 {
+		t.Errorf("box 12 = %+v", b)
+	}
+	// reverse charge VAT is due as well as recoverable
+	if got := box(VATBoxTotalVATDue).VAT; got != 750 {
+		t.Errorf("box 13 = %.2f, want 750", got)
+	}
+	if got := box(VATBoxNetVATDue).VAT; got != 670 {
+		t.Errorf("box 16 = %.2f, want 670", got)
+	}
+	if documents[5].VAT != 150 {
+		t.Errorf("reverse charge document VAT = %.2f, want 150", documents[5].VAT)
+	}
+}
+
+func TestBuildVATReturn_DebitNotesAndReturnsAdjustSales(t *testing.T) {
+	base := VATReturnDocument{Box: VATBoxStandardRatedSales}
+	documents := MakeVATReturnDocuments(base, 1000, 150, 0, 1, VATReasonInvoice)
+	documents = append(documents, MakeVATReturnDocuments(base, 200, 30, 0, 1, VATReasonDebitNote)...)
+	documents = append(documents, MakeVATReturnDocuments(base, 100, 15, 0, 1, VATReasonReturn)...)
+
+	boxes := BuildVATReturn(documents, 15, 0, 0)
+
+	if b := boxes[VATBoxStandardRatedSales-1]; b.Amount != 1000 || b.Adjustment != 100 || b.VAT != 165 || b.DocumentCount != 3 {
+		t.Errorf("box 1 = %+v", b)
+	}
+	if got := boxes[VATBoxNetVATDue-1].VAT; got != 165 {
+		t.Errorf("box 16 = %.2f, want 165", got)
+	}
+}
+
+func TestParseVATQuarter(t *testing.T) {
+	from, to, err := ParseVATQuarter("2026-q4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !from.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("quarter = %s..%s", from, to)
+	}
+	if _, _, err := ParseVATQuarter("2026-Q5"); err == nil {
+		t.Error("expected an error for Q5")
+	}
+}
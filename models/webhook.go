@@ -0,0 +1,756 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	WebhookEventOrderCreated             = "order.created"
+	WebhookEventOrderUpdated             = "order.updated"
+	WebhookEventOrderZatcaReported       = "order.zatca_reported"
+	WebhookEventOrderZatcaFailed         = "order.zatca_failed"
+	WebhookEventSalesReturnCreated       = "sales_return.created"
+	WebhookEventSalesReturnUpdated       = "sales_return.updated"
+	WebhookEventSalesReturnZatcaReported = "sales_return.zatca_reported"
+	WebhookEventSalesReturnZatcaFailed   = "sales_return.zatca_failed"
+	WebhookEventPurchaseCreated          = "purchase.created"
+	WebhookEventPurchaseUpdated          = "purchase.updated"
+	WebhookEventProductCreated           = "product.created"
+	WebhookEventProductUpdated           = "product.updated"
+	WebhookEventProductStockChanged      = "product.stock_changed"
+
+	// WebhookEventAll subscribes a webhook to every event
+	WebhookEventAll = "*"
+)
+
+var WebhookEvents = []string{
+	WebhookEventOrderCreated,
+	WebhookEventOrderUpdated,
+	WebhookEventOrderZatcaReported,
+	WebhookEventOrderZatcaFailed,
+	WebhookEventSalesReturnCreated,
+	WebhookEventSalesReturnUpdated,
+	WebhookEventSalesReturnZatcaReported,
+	WebhookEventSalesReturnZatcaFailed,
+	WebhookEventPurchaseCreated,
+	WebhookEventPurchaseUpdated,
+	WebhookEventProductCreated,
+	WebhookEventProductUpdated,
+	WebhookEventProductStockChanged,
+}
+
+const (
+	WebhookDeliveryStatusPending = "pending"
+	WebhookDeliveryStatusSuccess = "success"
+	WebhookDeliveryStatusFailed  = "failed"
+
+	WebhookMaxAttempts = 8
+	// webhookClaimLease keeps a delivery claimed by one sender while its request is in flight
+	webhookClaimLease = 2 * time.Minute
+)
+
+// Webhook : an outbound subscription of a store to document lifecycle events
+type Webhook struct {
+	ID            primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name          string              `bson:"name" json:"name"`
+	URL           string              `bson:"url" json:"url"`
+	Secret        string              `bson:"secret" json:"secret,omitempty"`
+	Events        []string            `bson:"events" json:"events"`
+	Active        bool                `bson:"active" json:"active"`
+	StoreID       *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	StoreName     string              `json:"store_name,omitempty" bson:"store_name,omitempty"`
+	Deleted       bool                `bson:"deleted" json:"deleted"`
+	DeletedBy     *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	DeletedAt     *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	CreatedAt     *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt     *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	CreatedBy     *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy     *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedByName string              `json:"created_by_name,omitempty" bson:"created_by_name,omitempty"`
+	UpdatedByName string              `json:"updated_by_name,omitempty" bson:"updated_by_name,omitempty"`
+	DeletedByName string              `json:"deleted_by_name,omitempty" bson:"deleted_by_name,omitempty"`
+}
+
+// WebhookDelivery : one event sent to one webhook, kept as the delivery log and retry queue
+type WebhookDelivery struct {
+	ID            primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	WebhookID     primitive.ObjectID  `json:"webhook_id" bson:"webhook_id"`
+	WebhookName   string              `json:"webhook_name" bson:"webhook_name"`
+	URL           string              `json:"url" bson:"url"`
+	Event         string              `json:"event" bson:"event"`
+	EventID       string              `json:"event_id" bson:"event_id"`
+	Payload       string              `json:"payload" bson:"payload"`
+	Status        string              `json:"status" bson:"status"`
+	Attempts      int                 `json:"attempts" bson:"attempts"`
+	NextAttemptAt *time.Time          `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	LastAttemptAt *time.Time          `json:"last_attempt_at,omitempty" bson:"last_attempt_at,omitempty"`
+	DeliveredAt   *time.Time          `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+	ResponseCode  int                 `json:"response_code,omitempty" bson:"response_code,omitempty"`
+	ResponseBody  string              `json:"response_body,omitempty" bson:"response_body,omitempty"`
+	Error         string              `json:"error,omitempty" bson:"error,omitempty"`
+	StoreID       *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	CreatedAt     *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt     *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// WebhookPayload is the JSON body posted to subscribers.
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	StoreID   string      `json:"store_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// ProductStockChange is the data of a product.stock_changed event.
+type ProductStockChange struct {
+	ProductID  primitive.ObjectID `json:"product_id"`
+	PartNumber string             `json:"part_number"`
+	Name       string             `json:"name"`
+	OldStock   float64            `json:"old_stock"`
+	Stock      float64            `json:"stock"`
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "<timestamp>.<body>" with the webhook secret.
+// Subscribers recompute it to verify the X-Webhook-Signature header.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookRetryDelay is the wait before the next attempt after `attempts` failed ones:
+// 30s doubling each time, capped at 6 hours.
+func WebhookRetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := 30 * time.Second
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= 6*time.Hour {
+			return 6 * time.Hour
+		}
+	}
+	return delay
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+func (webhook *Webhook) UpdateForeignLabelFields() error {
+	if webhook.StoreID != nil {
+		store, err := FindStoreByID(webhook.StoreID, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		webhook.StoreName = store.Name
+	}
+
+	if webhook.CreatedBy != nil {
+		createdByUser, err := FindUserByID(webhook.CreatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		webhook.CreatedByName = createdByUser.Name
+	}
+
+	if webhook.UpdatedBy != nil {
+		updatedByUser, err := FindUserByID(webhook.UpdatedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		webhook.UpdatedByName = updatedByUser.Name
+	}
+
+	if webhook.DeletedBy != nil && !webhook.DeletedBy.IsZero() {
+		deletedByUser, err := FindUserByID(webhook.DeletedBy, bson.M{"id": 1, "name": 1})
+		if err != nil {
+			return err
+		}
+		webhook.DeletedByName = deletedByUser.Name
+	}
+
+	return nil
+}
+
+func (store *Store) SearchWebhook(w http.ResponseWriter, r *http.Request) (webhooks []Webhook, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+	ParseDeletedFilter(r, &criterias)
+
+	ParseTextSearch(r, &criterias, "search[name]", "name")
+	ParseTextSearch(r, &criterias, "search[url]", "url")
+
+	keys, ok := r.URL.Query()["search[event]"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.SearchBy["events"] = keys[0]
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("webhook")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+	findOptions.SetProjection(bson.M{"secret": 0})
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return webhooks, criterias, errors.New("Error fetching webhooks:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return webhooks, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		webhook := Webhook{}
+		err = cur.Decode(&webhook)
+		if err != nil {
+			return webhooks, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, criterias, nil
+}
+
+func (webhook *Webhook) Validate(w http.ResponseWriter, r *http.Request, scenario string) (errs map[string]string) {
+	errs = make(map[string]string)
+	webhook.Name = strings.TrimSpace(webhook.Name)
+	webhook.URL = strings.TrimSpace(webhook.URL)
+	webhook.Secret = strings.TrimSpace(webhook.Secret)
+
+	store, err := FindStoreByID(webhook.StoreID, bson.M{})
+	if err != nil {
+		errs["store_id"] = "invalid store id"
+		w.WriteHeader(http.StatusBadRequest)
+		return errs
+	}
+
+	if scenario == "update" {
+		if webhook.ID.IsZero() {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = "ID is required"
+			return errs
+		}
+		exists, err := store.IsWebhookExists(&webhook.ID)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			errs["id"] = err.Error()
+			return errs
+		}
+
+		if !exists {
+			errs["id"] = "Invalid Webhook:" + webhook.ID.Hex()
+		}
+	}
+
+	if govalidator.IsNull(webhook.Name) {
+		errs["name"] = "Name is required"
+	}
+
+	if govalidator.IsNull(webhook.URL) {
+		errs["url"] = "URL is required"
+	} else if u, err := url.ParseRequestURI(webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs["url"] = "URL must be a valid http(s) URL"
+	}
+
+	if webhook.Secret == "" {
+		webhook.Secret, err = generateWebhookSecret()
+		if err != nil {
+			errs["secret"] = "Unable to generate secret:" + err.Error()
+		}
+	} else if len(webhook.Secret) < 16 {
+		errs["secret"] = "Secret must be at least 16 characters"
+	}
+
+	if len(webhook.Events) == 0 {
+		errs["events"] = "Select at least one event"
+	}
+	for i, event := range webhook.Events {
+		if event != WebhookEventAll && !slices.Contains(WebhookEvents, event) {
+			errs["events_"+strconv.Itoa(i)] = "Invalid event: " + event
+		}
+	}
+
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	return errs
+}
+
+func (webhook *Webhook) Insert() error {
+	collection := db.GetDB("store_" + webhook.StoreID.Hex()).Collection("webhook")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	webhook.ID = primitive.NewObjectID()
+
+	err := webhook.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, &webhook)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (webhook *Webhook) Update() error {
+	collection := db.GetDB("store_" + webhook.StoreID.Hex()).Collection("webhook")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	err := webhook.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": webhook.ID},
+		bson.M{"$set": webhook},
+		updateOptions,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (webhook *Webhook) DeleteWebhook(tokenClaims TokenClaims) (err error) {
+	collection := db.GetDB("store_" + webhook.StoreID.Hex()).Collection("webhook")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	updateOptions := options.Update()
+	updateOptions.SetUpsert(false)
+	defer cancel()
+
+	userID, err := primitive.ObjectIDFromHex(tokenClaims.UserID)
+	if err != nil {
+		return err
+	}
+
+	webhook.Deleted = true
+	webhook.Active = false
+	webhook.DeletedBy = &userID
+	now := time.Now()
+	webhook.DeletedAt = &now
+
+	err = webhook.UpdateForeignLabelFields()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		ctx,
+		bson.M{"_id": webhook.ID},
+		bson.M{"$set": webhook},
+		updateOptions,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (store *Store) FindWebhookByID(
+	ID *primitive.ObjectID,
+	selectFields map[string]interface{},
+) (webhook *Webhook, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("webhook")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOneOptions := options.FindOne()
+	if len(selectFields) > 0 {
+		findOneOptions.SetProjection(selectFields)
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{"_id": ID}, findOneOptions).
+		Decode(&webhook)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook, err
+}
+
+func (store *Store) IsWebhookExists(ID *primitive.ObjectID) (exists bool, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("webhook")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	count := int64(0)
+
+	count, err = collection.CountDocuments(ctx, bson.M{
+		"_id": ID,
+	})
+
+	return (count > 0), err
+}
+
+// EmitWebhookEvent queues event with data for every active webhook of the store subscribed to it
+// and makes a first delivery attempt. Failed deliveries are retried by ProcessWebhookDeliveries.
+// Callers run it in a goroutine so a slow subscriber never holds up the request.
+// emitZatcaWebhookEvent emits the event of a report of a saved document to ZATCA:
+// reported, or failed when err is not nil. A document not saved yet has none, its
+// created handler emits the reported event once it is inserted.
+func emitZatcaWebhookEvent(storeID *primitive.ObjectID, documentID primitive.ObjectID, reported, failed string, document interface{}, err error) {
+	if storeID == nil || documentID.IsZero() {
+		return
+	}
+
+	store, findErr := FindStoreByID(storeID, bson.M{})
+	if findErr != nil {
+		log.Printf("[webhook] store=%s: error finding store: %v", storeID.Hex(), findErr)
+		return
+	}
+
+	event := reported
+	if err != nil {
+		event = failed
+	}
+	go store.EmitWebhookEvent(event, document)
+}
+
+func (store *Store) EmitWebhookEvent(event string, data interface{}) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("webhook")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cur, err := collection.Find(ctx, bson.M{
+		"active":  true,
+		"deleted": bson.M{"$ne": true},
+		"events":  bson.M{"$in": []string{event, WebhookEventAll}},
+	})
+	if err != nil {
+		log.Printf("[webhook] store=%s event=%s: error finding webhooks: %v", store.ID.Hex(), event, err)
+		return
+	}
+	webhooks := []Webhook{}
+	if err := cur.All(ctx, &webhooks); err != nil {
+		log.Printf("[webhook] store=%s event=%s: error decoding webhooks: %v", store.ID.Hex(), event, err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	now := time.Now()
+	eventID := primitive.NewObjectID().Hex()
+	payload, err := json.Marshal(WebhookPayload{
+		ID:        eventID,
+		Event:     event,
+		StoreID:   store.ID.Hex(),
+		CreatedAt: now,
+		Data:      data,
+	})
+	if err != nil {
+		log.Printf("[webhook] store=%s event=%s: error encoding payload: %v", store.ID.Hex(), event, err)
+		return
+	}
+
+	deliveryCollection := db.GetDB("store_" + store.ID.Hex()).Collection("webhook_delivery")
+	for _, webhook := range webhooks {
+		delivery := WebhookDelivery{
+			ID:            primitive.NewObjectID(),
+			WebhookID:     webhook.ID,
+			WebhookName:   webhook.Name,
+			URL:           webhook.URL,
+			Event:         event,
+			EventID:       eventID,
+			Payload:       string(payload),
+			Status:        WebhookDeliveryStatusPending,
+			NextAttemptAt: &now,
+			StoreID:       &store.ID,
+			CreatedAt:     &now,
+			UpdatedAt:     &now,
+		}
+		if _, err := deliveryCollection.InsertOne(ctx, &delivery); err != nil {
+			log.Printf("[webhook] store=%s event=%s: error queueing delivery: %v", store.ID.Hex(), event, err)
+			continue
+		}
+		go store.attemptWebhookDelivery(delivery.ID)
+	}
+}
+
+// claimWebhookDelivery leases a due pending delivery so that only one sender posts it at a time.
+func (store *Store) claimWebhookDelivery(filter bson.M) (*WebhookDelivery, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("webhook_delivery")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter["status"] = WebhookDeliveryStatusPending
+	filter["next_attempt_at"] = bson.M{"$lte": now}
+
+	var delivery *WebhookDelivery
+	err := collection.FindOneAndUpdate(ctx, filter,
+		bson.M{"$set": bson.M{"next_attempt_at": now.Add(webhookClaimLease)}},
+		options.FindOneAndUpdate().SetSort(bson.M{"next_attempt_at": 1}).SetReturnDocument(options.After),
+	).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return delivery, err
+}
+
+func (store *Store) attemptWebhookDelivery(deliveryID primitive.ObjectID) {
+	delivery, err := store.claimWebhookDelivery(bson.M{"_id": deliveryID})
+	if err != nil {
+		log.Printf("[webhook] store=%s delivery=%s: %v", store.ID.Hex(), deliveryID.Hex(), err)
+		return
+	}
+	if delivery != nil {
+		store.sendWebhookDelivery(delivery)
+	}
+}
+
+var webhookHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// sendWebhookDelivery posts a claimed delivery and records the outcome.
+func (store *Store) sendWebhookDelivery(delivery *WebhookDelivery) {
+	webhook, err := store.FindWebhookByID(&delivery.WebhookID, bson.M{})
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.UpdatedAt = &now
+	delivery.ResponseCode = 0
+	delivery.ResponseBody = ""
+	delivery.Error = ""
+
+	if err != nil || webhook.Deleted || !webhook.Active {
+		delivery.Status = WebhookDeliveryStatusFailed
+		delivery.NextAttemptAt = nil
+		delivery.Error = "webhook is deleted or inactive"
+		store.saveWebhookDelivery(delivery)
+		return
+	}
+
+	body := []byte(delivery.Payload)
+	timestamp := now.Unix()
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "StartPOS-Webhook/1.0")
+		req.Header.Set("X-Webhook-Event", delivery.Event)
+		req.Header.Set("X-Webhook-Id", delivery.EventID)
+		req.Header.Set("X-Webhook-Delivery", delivery.ID.Hex())
+		req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+		req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhookPayload(webhook.Secret, timestamp, body))
+
+		var resp *http.Response
+		resp, err = webhookHTTPClient.Do(req)
+		if err == nil {
+			respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			resp.Body.Close()
+			delivery.ResponseCode = resp.StatusCode
+			delivery.ResponseBody = string(respBody)
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				err = errors.New("subscriber responded with HTTP " + strconv.Itoa(resp.StatusCode))
+			}
+		}
+	}
+
+	if err == nil {
+		delivery.Status = WebhookDeliveryStatusSuccess
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	} else {
+		delivery.Error = err.Error()
+		if delivery.Attempts >= WebhookMaxAttempts {
+			delivery.Status = WebhookDeliveryStatusFailed
+			delivery.NextAttemptAt = nil
+		} else {
+			delivery.Status = WebhookDeliveryStatusPending
+			next := now.Add(WebhookRetryDelay(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+		}
+	}
+
+	store.saveWebhookDelivery(delivery)
+}
+
+func (store *Store) saveWebhookDelivery(delivery *WebhookDelivery) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("webhook_delivery")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": delivery}
+	if delivery.NextAttemptAt == nil {
+		update["$unset"] = bson.M{"next_attempt_at": ""}
+	}
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": delivery.ID}, update)
+	if err != nil {
+		log.Printf("[webhook] store=%s delivery=%s: error saving: %v", store.ID.Hex(), delivery.ID.Hex(), err)
+	}
+}
+
+// ProcessWebhookDeliveries sends the due pending deliveries of every store.
+// Called every minute by the gocron scheduler in main.go.
+func ProcessWebhookDeliveries() error {
+	stores, err := GetAllStores()
+	if err != nil {
+		return err
+	}
+
+	for i := range stores {
+		store := &stores[i]
+		for {
+			delivery, err := store.claimWebhookDelivery(bson.M{})
+			if err != nil {
+				log.Printf("[webhook] store=%s: %v", store.ID.Hex(), err)
+				break
+			}
+			if delivery == nil {
+				break
+			}
+			store.sendWebhookDelivery(delivery)
+		}
+	}
+
+	return nil
+}
+
+// RetryWebhookDelivery puts a delivery back in the queue for an immediate new attempt.
+func (store *Store) RetryWebhookDelivery(deliveryID primitive.ObjectID) (*WebhookDelivery, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("webhook_delivery")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": deliveryID, "status": bson.M{"$ne": WebhookDeliveryStatusPending}},
+		bson.M{"$set": bson.M{
+			"status":          WebhookDeliveryStatusPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"updated_at":      now,
+		}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, errors.New("delivery not found or already pending")
+	}
+
+	store.attemptWebhookDelivery(deliveryID)
+
+	return store.FindWebhookDeliveryByID(&deliveryID)
+}
+
+func (store *Store) FindWebhookDeliveryByID(ID *primitive.ObjectID) (delivery *WebhookDelivery, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("webhook_delivery")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = collection.FindOne(ctx, bson.M{"_id": ID}).Decode(&delivery)
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (store *Store) SearchWebhookDelivery(w http.ResponseWriter, r *http.Request) (deliveries []WebhookDelivery, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+
+	if err = ParseObjectIDFilter(r, &criterias, "search[webhook_id]", "webhook_id"); err != nil {
+		return deliveries, criterias, err
+	}
+
+	for _, field := range []string{"event", "event_id", "status"} {
+		keys, ok := r.URL.Query()["search["+field+"]"]
+		if ok && len(keys[0]) >= 1 {
+			criterias.SearchBy[field] = keys[0]
+		}
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("webhook_delivery")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	keys, ok := r.URL.Query()["select"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.Select = ParseSelectString(keys[0])
+	}
+
+	if criterias.Select != nil {
+		findOptions.SetProjection(criterias.Select)
+	}
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return deliveries, criterias, errors.New("Error fetching webhook deliveries:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return deliveries, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		delivery := WebhookDelivery{}
+		err = cur.Decode(&delivery)
+		if err != nil {
+			return deliveries, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, criterias, nil
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+// ── SignWebhookPayload ───────────────────────────────────────────────────────
+
+func TestSignWebhookPayload_MatchesHMACOfTimestampAndBody(t *testing.T) {
+	body := []byte(`{"event":"order.created"}`)
+	mac := hmac.New(sha256.New, []byte("whsec_test_secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	if got := SignWebhookPayload("whsec_test_secret", 1700000000, body); got != want {
+		t.Errorf("SignWebhookPayload = %s, want %s", got, want)
+	}
+}
+
+func TestSignWebhookPayload_DependsOnSecretAndTimestamp(t *testing.T) {
+	body := []byte(`{}`)
+	base := SignWebhookPayload("secret-one-123456", 1, body)
+	if SignWebhookPayload("secret-two-123456", 1, body) == base {
+		t.Error("different secrets should give different signatures")
+	}
+	if SignWebhookPayload("secret-one-123456", 2, body) == base {
+		t.Error("different timestamps should give different signatures")
+	}
+}
+
+// ── WebhookRetryDelay ────────────────────────────────────────────────────────
+
+func TestWebhookRetryDelay_Doubles(t *testing.T) {
+	cases := map[int]time.Duration{
+		0: 30 * time.Second,
+		1: 30 * time.Second,
+		2: time.Minute,
+		3: 2 * time.Minute,
+		5: 8 * time.Minute,
+	}
+	for attempts, want := range cases {
+		if got := WebhookRetryDelay(attempts); got != want {
+			t.Errorf("WebhookRetryDelay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestWebhookRetryDelay_Capped(t *testing.T) {
+	if got := WebhookRetryDelay(50); got != 6*time.Hour {
+		t.Errorf("WebhookRetryDelay(50) = %v, want 6h", got)
+	}
+}
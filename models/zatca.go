@@ -7,17 +7,48 @@ import (
 	"encoding/xml"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/zatca"
 )
 
 type ComplianceCheck struct {
 	SimplifiedInvoice    bool `json:"simplified_invoice" bson:"simplified_invoice"`
 	SimplifiedCreditNote bool `json:"simplified_credit_note" bson:"simplified_credit_note"`
-	SimplifiedDebitNote  bool `json:"simplified_debit_note" bson:"simplified_debit_note`
+	SimplifiedDebitNote  bool `json:"simplified_debit_note" bson:"simplified_debit_note"`
 	StandardInvoice      bool `json:"standard_invoice" bson:"standard_invoice"`
 	StandardCreditNote   bool `json:"standard_credit_note" bson:"standard_credit_note"`
 	StandardDebitNote    bool `json:"standard_debit_note" bson:"standard_debit_note"`
 }
 
+// NewComplianceCheck maps the compliance documents an onboarding passed, keyed
+// as in zatca.ComplianceDocuments, to the store compliance check.
+func NewComplianceCheck(passed map[string]bool) ComplianceCheck {
+	return ComplianceCheck{
+		SimplifiedInvoice:    passed["simplified_invoice"],
+		SimplifiedCreditNote: passed["simplified_credit_note"],
+		SimplifiedDebitNote:  passed["simplified_debit_note"],
+		StandardInvoice:      passed["standard_invoice"],
+		StandardCreditNote:   passed["standard_credit_note"],
+		StandardDebitNote:    passed["standard_debit_note"],
+	}
+}
+
+// ValidateZatcaEnv returns a message when the store can't use its ZATCA environment:
+// the Sandbox when the server does not enable it, or when the store was on Production.
+func (store *Store) ValidateZatcaEnv(oldStore *Store) string {
+	if store.Zatca.Env != zatca.EnvSandbox {
+		return ""
+	}
+	if oldStore != nil && oldStore.Zatca.Env == zatca.EnvProduction {
+		return "A store on Production cannot be switched to Sandbox"
+	}
+	if !zatca.SandboxEnabled() {
+		return "The Sandbox environment is not enabled on this server"
+	}
+	return ""
+}
+
 // Invoice struct to hold XML data
 type Invoice struct {
 	XMLName xml.Name `xml:"Invoice"`
@@ -521,3 +552,53 @@ func GenerateInvoiceHash(inputString string) (string, error) {
 	// Convert Hash to Base64
 	return base64Str, nil
 }
+
+// SubmitToZatca signs the XML made by MakeXMLContent with the production CSID
+// of the store and reports it when it is simplified or clears it otherwise.
+// ClearedInvoice is the base64 of the signed invoice in both cases, so
+// SaveClearedInvoiceData reads it the same way.
+func (store *Store) SubmitToZatca(xmlContent string) (reportingResponse ZatcaReportingResponse) {
+	credentials := zatca.Credentials{
+		RequestID:           store.Zatca.ProductionRequestID,
+		BinarySecurityToken: store.Zatca.ProductionBinarySecurityToken,
+		Secret:              store.Zatca.ProductionSecret,
+	}
+
+	certificate, err := credentials.Certificate()
+	if err != nil {
+		reportingResponse.Error = err.Error()
+		return reportingResponse
+	}
+
+	signer, err := zatca.NewSigner(certificate, store.Zatca.PrivateKey)
+	if err != nil {
+		reportingResponse.Error = err.Error()
+		return reportingResponse
+	}
+
+	invoice, err := signer.Prepare([]byte(xmlContent), time.Now())
+	if err != nil {
+		reportingResponse.Error = err.Error()
+		return reportingResponse
+	}
+	reportingResponse.InvoiceHash = invoice.Hash
+	reportingResponse.IsSimplified = invoice.Simplified
+
+	result, err := zatca.NewClient(store.Zatca.Env).Submit(&credentials, invoice)
+	if err != nil {
+		reportingResponse.Error = err.Error()
+		return reportingResponse
+	}
+	if !result.Accepted() {
+		reportingResponse.Error = result.Errors()
+		return reportingResponse
+	}
+
+	reportingResponse.ReportingPassed = true
+	if invoice.Simplified {
+		reportingResponse.ClearedInvoice = base64.StdEncoding.EncodeToString(invoice.XML)
+	} else {
+		reportingResponse.ClearedInvoice = result.ClearedInvoice
+	}
+	return reportingResponse
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/pdfa"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// zatcaXMLFolders are the folders under zatca/<store id>/ the XML ZATCA
+// reported or cleared is kept in, for each document type.
+var zatcaXMLFolders = map[string]string{
+	ZatcaDocumentOrder:              "sales",
+	ZatcaDocumentSalesReturn:        "sales-returns",
+	ZatcaDocumentCustomerDeposit:    "receivables",
+	ZatcaDocumentCustomerWithdrawal: "payables",
+	ZatcaDocumentDebitNote:          "debit-notes",
+}
+
+// ZatcaXMLPath is the file SaveClearedInvoiceData keeps the signed XML of the document in.
+func ZatcaXMLPath(storeID *primitive.ObjectID, documentType, code string) string {
+	return "zatca/" + storeID.Hex() + "/" + zatcaXMLFolders[documentType] + "/xml/" + code + ".xml"
+}
+
+// ZatcaPDFA is what the PDF/A-3 print of a reported document carries besides the page.
+type ZatcaPDFA struct {
+	Code     string
+	Title    string
+	Store    string
+	SignedAt time.Time
+	XML      []byte
+}
+
+// FindZatcaPDFA finds the signed XML of a document ZATCA reported or cleared,
+// for its PDF/A-3 print.
+func (store *Store) FindZatcaPDFA(documentType string, documentID *primitive.ObjectID) (*ZatcaPDFA, error) {
+	var code, title string
+	var reporting ZatcaReporting
+
+	switch documentType {
+	case ZatcaDocumentOrder:
+		order, err := store.FindOrderByID(documentID, bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		code, reporting = order.Code, order.Zatca
+		title = "Tax Invoice"
+		if order.Zatca.IsSimplified {
+			title = "Simplified Tax Invoice"
+		}
+	case ZatcaDocumentSalesReturn:
+		salesReturn, err := store.FindSalesReturnByID(documentID, bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		code, reporting = salesReturn.Code, salesReturn.Zatca
+		title = "Credit Note"
+	case ZatcaDocumentCustomerDeposit:
+		deposit, err := store.FindCustomerDepositByID(documentID, bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		code, reporting = deposit.Code, deposit.Zatca
+		title = "Customer Deposit"
+	case ZatcaDocumentDebitNote:
+		debitNote, err := store.FindDebitNoteByID(documentID, bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		code, reporting = debitNote.Code, debitNote.Zatca
+		title = "Debit Note"
+	default:
+		return nil, errors.New("no PDF/A-3 print for " + documentType)
+	}
+
+	if !reporting.ReportingPassed {
+		return nil, errors.New(code + " is not reported to ZATCA yet")
+	}
+	xmlData, err := os.ReadFile(ZatcaXMLPath(&store.ID, documentType, code))
+	if err != nil {
+		return nil, errors.New("the signed XML of " + code + " is not found: " + err.Error())
+	}
+
+	document := &ZatcaPDFA{
+		Code:     code,
+		Title:    title + " " + code,
+		Store:    store.Name,
+		SignedAt: time.Now(),
+		XML:      xmlData,
+	}
+	if reporting.SigningTime != nil {
+		document.SignedAt = *reporting.SigningTime
+	}
+	return document, nil
+}
+
+// Convert makes the PDF of the document PDF/A-3b with its signed XML attached.
+func (document *ZatcaPDFA) Convert(pdf []byte) ([]byte, error) {
+	return pdfa.Convert(pdf, pdfa.Metadata{
+		Title:    document.Title,
+		Author:   document.Store,
+		Subject:  "ZATCA e-invoice " + document.Code,
+		Creator:  "Start POS",
+		Producer: "Start POS",
+	}, pdfa.Attachment{
+		Name:         document.Code + ".xml",
+		Description:  "Signed ZATCA XML of " + document.Code,
+		MIMEType:     "application/xml",
+		Relationship: pdfa.RelationshipAlternative,
+		Data:         document.XML,
+		ModDate:      document.SignedAt,
+	})
+}
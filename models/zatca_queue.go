@@ -0,0 +1,652 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sirinibin/startpos/backend/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ZatcaDocumentOrder              = "order"
+	ZatcaDocumentSalesReturn        = "sales_return"
+	ZatcaDocumentCustomerDeposit    = "customer_deposit"
+	ZatcaDocumentCustomerWithdrawal = "customer_withdrawal"
+	ZatcaDocumentDebitNote          = "debit_note"
+
+	ZatcaQueueStatusPending  = "pending"
+	ZatcaQueueStatusReported = "reported"
+
+	// ZatcaReportingDeadline is how long after its issue time a simplified invoice has to be reported
+	ZatcaReportingDeadline = 24 * time.Hour
+	// ZatcaDeadlineWarning is how close to the deadline an unreported invoice raises an alert
+	ZatcaDeadlineWarning = 6 * time.Hour
+	// ZatcaStuckAttempts is the number of failed retries after which an item raises an alert
+	ZatcaStuckAttempts = 5
+	// zatcaClaimLease keeps an item claimed by one worker while it is being reported
+	zatcaClaimLease = 5 * time.Minute
+)
+
+// ZatcaDocumentTypes are the documents reported to ZATCA, each with its own previous-hash chain.
+var ZatcaDocumentTypes = []string{
+	ZatcaDocumentOrder,
+	ZatcaDocumentSalesReturn,
+	ZatcaDocumentCustomerDeposit,
+	ZatcaDocumentCustomerWithdrawal,
+	ZatcaDocumentDebitNote,
+}
+
+// zatcaDocumentCollections are the collections of each document type.
+var zatcaDocumentCollections = map[string]string{
+	ZatcaDocumentOrder:              "order",
+	ZatcaDocumentSalesReturn:        "salesreturn",
+	ZatcaDocumentCustomerDeposit:    "customerdeposit",
+	ZatcaDocumentCustomerWithdrawal: "customerwithdrawal",
+	ZatcaDocumentDebitNote:          "debitnote",
+}
+
+// zatcaDocumentEvents are the socket events that refresh the lists of each document type.
+var zatcaDocumentEvents = map[string]string{
+	ZatcaDocumentOrder:              "sales_updated",
+	ZatcaDocumentSalesReturn:        "sales_return_updated",
+	ZatcaDocumentCustomerDeposit:    "receivable_updated",
+	ZatcaDocumentCustomerWithdrawal: "payable_updated",
+	ZatcaDocumentDebitNote:          "debit_note_updated",
+}
+
+// ZatcaQueueItem : a document whose reporting to ZATCA failed, retried by ProcessZatcaQueueForAllStores
+// until it is reported. Items of a document type are reported in invoice counter order, so that
+// every invoice gets the hash of the one before it.
+type ZatcaQueueItem struct {
+	ID                primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	DocumentType      string              `json:"document_type" bson:"document_type"`
+	DocumentID        primitive.ObjectID  `json:"document_id" bson:"document_id"`
+	DocumentCode      string              `json:"document_code" bson:"document_code"`
+	InvoiceCountValue int64               `json:"invoice_count_value" bson:"invoice_count_value"`
+	DocumentDate      *time.Time          `json:"document_date,omitempty" bson:"document_date,omitempty"`
+	IsSimplified      bool                `json:"is_simplified" bson:"is_simplified"`
+	Deadline          *time.Time          `json:"deadline,omitempty" bson:"deadline,omitempty"`
+	Status            string              `json:"status" bson:"status"`
+	Attempts          int                 `json:"attempts" bson:"attempts"`
+	NextAttemptAt     *time.Time          `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	LastAttemptAt     *time.Time          `json:"last_attempt_at,omitempty" bson:"last_attempt_at,omitempty"`
+	ReportedAt        *time.Time          `json:"reported_at,omitempty" bson:"reported_at,omitempty"`
+	Error             string              `json:"error,omitempty" bson:"error,omitempty"`
+	AlertedAt         *time.Time          `json:"alerted_at,omitempty" bson:"alerted_at,omitempty"`
+	HoursLeft         *float64            `json:"hours_left,omitempty" bson:"-"`
+	StoreID           *primitive.ObjectID `json:"store_id,omitempty" bson:"store_id,omitempty"`
+	CreatedAt         *time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt         *time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// ZatcaDeadlines is the dashboard of the unreported documents of a store.
+type ZatcaDeadlines struct {
+	Pending int64            `json:"pending"`
+	Overdue int64            `json:"overdue"`
+	DueSoon int64            `json:"due_soon"`
+	Stuck   int64            `json:"stuck"`
+	Items   []ZatcaQueueItem `json:"items"`
+}
+
+// ZatcaRetryDelay is the wait before the next attempt after `attempts` failed ones:
+// 1 minute doubling each time, capped at 1 hour so that a simplified invoice gets
+// many tries within its 24 hours.
+func ZatcaRetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := time.Minute
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= time.Hour {
+			return time.Hour
+		}
+	}
+	return delay
+}
+
+// ZatcaDeadlineOf is the reporting deadline of a document issued at date, nil for standard
+// invoices which are cleared before they are issued.
+func ZatcaDeadlineOf(date *time.Time, isSimplified bool) *time.Time {
+	if date == nil || !isSimplified {
+		return nil
+	}
+	deadline := date.Add(ZatcaReportingDeadline)
+	return &deadline
+}
+
+// NeedsAlert tells if the item is close to or past its deadline, or keeps failing.
+func (item *ZatcaQueueItem) NeedsAlert(now time.Time) bool {
+	if item.Status != ZatcaQueueStatusPending {
+		return false
+	}
+	if item.Attempts >= ZatcaStuckAttempts {
+		return true
+	}
+	return item.Deadline != nil && item.Deadline.Sub(now) <= ZatcaDeadlineWarning
+}
+
+// SortZatcaQueueItemsByDeadline puts the nearest deadline first and the items without a deadline
+// last, in invoice counter order.
+func SortZatcaQueueItemsByDeadline(items []ZatcaQueueItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].Deadline, items[j].Deadline
+		if a != nil && b != nil && !a.Equal(*b) {
+			return a.Before(*b)
+		}
+		if (a == nil) != (b == nil) {
+			return a != nil
+		}
+		return items[i].InvoiceCountValue < items[j].InvoiceCountValue
+	})
+}
+
+func (item *ZatcaQueueItem) setHoursLeft(now time.Time) {
+	if item.Deadline == nil {
+		return
+	}
+	hours := RoundTo2Decimals(item.Deadline.Sub(now).Hours())
+	item.HoursLeft = &hours
+}
+
+// queueZatcaReporting puts a document whose reporting failed in the queue of its store. A document
+// already waiting in the queue keeps its place and attempts; only the last error is updated.
+func queueZatcaReporting(storeID *primitive.ObjectID, item ZatcaQueueItem, errorMessage string) {
+	if storeID == nil || item.DocumentID.IsZero() {
+		// not saved yet: a new document that fails reporting is not created
+		return
+	}
+
+	collection := db.GetDB("store_" + storeID.Hex()).Collection("zatca_queue")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := collection.UpdateOne(ctx,
+		bson.M{
+			"document_type": item.DocumentType,
+			"document_id":   item.DocumentID,
+			"status":        ZatcaQueueStatusPending,
+		},
+		bson.M{
+			"$set": bson.M{
+				"document_code":       item.DocumentCode,
+				"invoice_count_value": item.InvoiceCountValue,
+				"document_date":       item.DocumentDate,
+				"is_simplified":       item.IsSimplified,
+				"deadline":            ZatcaDeadlineOf(item.DocumentDate, item.IsSimplified),
+				"error":               errorMessage,
+				"updated_at":          now,
+			},
+			"$setOnInsert": bson.M{
+				"attempts":        0,
+				"next_attempt_at": now.Add(ZatcaRetryDelay(1)),
+				"store_id":        storeID,
+				"created_at":      now,
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		log.Printf("[zatca-queue] store=%s %s=%s: error queueing: %v", storeID.Hex(), item.DocumentType, item.DocumentID.Hex(), err)
+	}
+}
+
+// resolveZatcaQueueItem marks the waiting item of a document reported.
+func resolveZatcaQueueItem(storeID *primitive.ObjectID, documentType string, documentID primitive.ObjectID) {
+	if storeID == nil || documentID.IsZero() {
+		return
+	}
+
+	collection := db.GetDB("store_" + storeID.Hex()).Collection("zatca_queue")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := collection.UpdateMany(ctx,
+		bson.M{
+			"document_type": documentType,
+			"document_id":   documentID,
+			"status":        ZatcaQueueStatusPending,
+		},
+		bson.M{
+			"$set":   bson.M{"status": ZatcaQueueStatusReported, "reported_at": now, "updated_at": now},
+			"$unset": bson.M{"next_attempt_at": ""},
+		},
+	)
+	if err != nil {
+		log.Printf("[zatca-queue] store=%s %s=%s: error resolving: %v", storeID.Hex(), documentType, documentID.Hex(), err)
+	}
+}
+
+// zatcaDocument is what the queue needs of a reportable document.
+type zatcaDocument interface {
+	ReportToZatca() error
+	Update() error
+}
+
+func (store *Store) findZatcaDocument(item *ZatcaQueueItem) (document zatcaDocument, reporting *ZatcaReporting, err error) {
+	switch item.DocumentType {
+	case ZatcaDocumentOrder:
+		order, err := store.FindOrderByID(&item.DocumentID, bson.M{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return order, &order.Zatca, nil
+	case ZatcaDocumentSalesReturn:
+		salesReturn, err := store.FindSalesReturnByID(&item.DocumentID, bson.M{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return salesReturn, &salesReturn.Zatca, nil
+	case ZatcaDocumentCustomerDeposit:
+		deposit, err := store.FindCustomerDepositByID(&item.DocumentID, bson.M{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return deposit, &deposit.Zatca, nil
+	case ZatcaDocumentCustomerWithdrawal:
+		withdrawal, err := store.FindCustomerWithdrawalByID(&item.DocumentID, bson.M{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return withdrawal, &withdrawal.Zatca, nil
+	case ZatcaDocumentDebitNote:
+		debitNote, err := store.FindDebitNoteByID(&item.DocumentID, bson.M{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return debitNote, &debitNote.Zatca, nil
+	}
+	return nil, nil, errors.New("unknown document type " + item.DocumentType)
+}
+
+// nextZatcaQueueItem claims the first pending item of the chain of documentType, if it is due.
+// Later items wait for it, even when they are due, to keep the hash chain in order.
+func (store *Store) nextZatcaQueueItem(documentType string) (*ZatcaQueueItem, error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("zatca_queue")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var head *ZatcaQueueItem
+	err := collection.FindOne(ctx,
+		bson.M{"document_type": documentType, "status": ZatcaQueueStatusPending},
+		options.FindOne().SetSort(bson.D{{Key: "invoice_count_value", Value: 1}, {Key: "document_date", Value: 1}}),
+	).Decode(&head)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var item *ZatcaQueueItem
+	err = collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": head.ID, "status": ZatcaQueueStatusPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"next_attempt_at": now.Add(zatcaClaimLease)}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&item)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return item, err
+}
+
+func (store *Store) saveZatcaQueueItem(item *ZatcaQueueItem) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("zatca_queue")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": item}
+	if item.NextAttemptAt == nil {
+		update["$unset"] = bson.M{"next_attempt_at": ""}
+	}
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": item.ID}, update)
+	if err != nil {
+		log.Printf("[zatca-queue] store=%s item=%s: error saving: %v", store.ID.Hex(), item.ID.Hex(), err)
+	}
+}
+
+// reportZatcaQueueItem reports the document of a claimed item and records the outcome.
+// It tells if the document is now reported.
+func (store *Store) reportZatcaQueueItem(item *ZatcaQueueItem) bool {
+	now := time.Now()
+	item.LastAttemptAt = &now
+	item.UpdatedAt = &now
+
+	document, reporting, err := store.findZatcaDocument(item)
+	if err == nil && !reporting.ReportingPassed {
+		err = document.ReportToZatca()
+		// the failure count and errors are kept on the document as well
+		if updateErr := document.Update(); err == nil && updateErr != nil {
+			err = updateErr
+		}
+	}
+
+	if err == nil {
+		item.Status = ZatcaQueueStatusReported
+		item.ReportedAt = &now
+		item.NextAttemptAt = nil
+		item.Error = ""
+		store.saveZatcaQueueItem(item)
+		return true
+	}
+
+	item.Attempts++
+	item.Error = err.Error()
+	next := now.Add(ZatcaRetryDelay(item.Attempts))
+	item.NextAttemptAt = &next
+	store.saveZatcaQueueItem(item)
+	return false
+}
+
+// ProcessZatcaQueue reports the due items of the store, chain by chain, stopping a chain at its
+// first failure. turn waits for the store's turn to report to ZATCA and returns the function
+// that ends it, so that the queue never reports alongside a request of a user.
+func (store *Store) ProcessZatcaQueue(turn func(storeID string) func()) error {
+	if store.Zatca.Phase != "2" || !store.Zatca.Connected {
+		return nil
+	}
+
+	for _, documentType := range ZatcaDocumentTypes {
+		for {
+			item, err := store.nextZatcaQueueItem(documentType)
+			if err != nil {
+				return err
+			}
+			if item == nil {
+				break
+			}
+
+			done := turn(store.ID.Hex())
+			reported := store.reportZatcaQueueItem(item)
+			done()
+			if !reported {
+				break
+			}
+			store.NotifyUsers(zatcaDocumentEvents[documentType])
+		}
+	}
+
+	return store.AlertZatcaQueue()
+}
+
+// AlertZatcaQueue notifies the users of the store once for every pending item that is close to
+// its deadline or keeps failing.
+func (store *Store) AlertZatcaQueue() error {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("zatca_queue")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	cur, err := collection.Find(ctx, bson.M{
+		"status":     ZatcaQueueStatusPending,
+		"alerted_at": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return err
+	}
+	items := []ZatcaQueueItem{}
+	if err := cur.All(ctx, &items); err != nil {
+		return err
+	}
+
+	ids := []primitive.ObjectID{}
+	for _, item := range items {
+		if !item.NeedsAlert(now) {
+			continue
+		}
+		ids = append(ids, item.ID)
+		log.Printf("[zatca-queue] store=%s %s %s: not reported after %d attempts: %s", store.ID.Hex(), item.DocumentType, item.DocumentCode, item.Attempts, item.Error)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err = collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"alerted_at": now}})
+	if err != nil {
+		return err
+	}
+
+	return store.NotifyUsers("zatca_queue_alert")
+}
+
+// ProcessZatcaQueueForAllStores retries the queued reporting of every store.
+// Called every minute by the gocron scheduler in main.go.
+func ProcessZatcaQueueForAllStores(turn func(storeID string) func()) error {
+	stores, err := GetAllStores()
+	if err != nil {
+		return err
+	}
+
+	for i := range stores {
+		if err := stores[i].ProcessZatcaQueue(turn); err != nil {
+			log.Printf("[zatca-queue] store=%s: %v", stores[i].ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
+
+// QueueUnreportedZatcaDocuments queues the documents of the store whose reporting failed
+// and that are not in the queue yet, such as the failures from before the queue existed.
+func (store *Store) QueueUnreportedZatcaDocuments() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, documentType := range ZatcaDocumentTypes {
+		collection := db.GetDB("store_" + store.ID.Hex()).Collection(zatcaDocumentCollections[documentType])
+		cur, err := collection.Find(ctx, bson.M{
+			"zatca.reporting_failed_count": bson.M{"$gt": 0},
+			"zatca.reporting_passed":       bson.M{"$ne": true},
+			"deleted":                      bson.M{"$ne": true},
+		}, options.Find().SetProjection(bson.M{
+			"code":                   1,
+			"invoice_count_value":    1,
+			"date":                   1,
+			"zatca.is_simplified":    1,
+			"zatca.reporting_errors": 1,
+		}))
+		if err != nil {
+			return err
+		}
+
+		documents := []struct {
+			ID                primitive.ObjectID `bson:"_id"`
+			Code              string             `bson:"code"`
+			InvoiceCountValue int64              `bson:"invoice_count_value"`
+			Date              *time.Time         `bson:"date"`
+			Zatca             ZatcaReporting     `bson:"zatca"`
+		}{}
+		if err := cur.All(ctx, &documents); err != nil {
+			return err
+		}
+
+		for _, document := range documents {
+			lastError := ""
+			if len(document.Zatca.ReportingErrors) > 0 {
+				lastError = document.Zatca.ReportingErrors[len(document.Zatca.ReportingErrors)-1]
+			}
+			queueZatcaReporting(&store.ID, ZatcaQueueItem{
+				DocumentType:      documentType,
+				DocumentID:        document.ID,
+				DocumentCode:      document.Code,
+				InvoiceCountValue: document.InvoiceCountValue,
+				DocumentDate:      document.Date,
+				IsSimplified:      document.Zatca.IsSimplified,
+			}, lastError)
+		}
+	}
+
+	return nil
+}
+
+// QueueUnreportedZatcaDocumentsForAllStores runs QueueUnreportedZatcaDocuments for the stores
+// reporting to ZATCA. Called once at startup from main.go.
+func QueueUnreportedZatcaDocumentsForAllStores() {
+	stores, err := GetAllStores()
+	if err != nil {
+		log.Printf("[zatca-queue] error finding stores: %v", err)
+		return
+	}
+
+	for i := range stores {
+		if stores[i].Zatca.Phase != "2" {
+			continue
+		}
+		if err := stores[i].QueueUnreportedZatcaDocuments(); err != nil {
+			log.Printf("[zatca-queue] store=%s: error queueing unreported documents: %v", stores[i].ID.Hex(), err)
+		}
+	}
+}
+
+// RetryZatcaQueueItem makes a pending item due now. It is still reported in its chain order.
+func (store *Store) RetryZatcaQueueItem(itemID primitive.ObjectID) error {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("zatca_queue")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": itemID, "status": ZatcaQueueStatusPending},
+		bson.M{"$set": bson.M{"next_attempt_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("item not found or already reported")
+	}
+	return nil
+}
+
+func (store *Store) FindZatcaQueueItemByID(ID *primitive.ObjectID) (item *ZatcaQueueItem, err error) {
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("zatca_queue")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = collection.FindOne(ctx, bson.M{"_id": ID}).Decode(&item)
+	if err != nil {
+		return nil, err
+	}
+	item.setHoursLeft(time.Now())
+	return item, nil
+}
+
+func (store *Store) SearchZatcaQueue(w http.ResponseWriter, r *http.Request) (items []ZatcaQueueItem, criterias SearchCriterias, err error) {
+	criterias = InitSearchCriterias()
+
+	for _, field := range []string{"document_type", "document_code", "status"} {
+		keys, ok := r.URL.Query()["search["+field+"]"]
+		if ok && len(keys[0]) >= 1 {
+			criterias.SearchBy[field] = keys[0]
+		}
+	}
+
+	keys, ok := r.URL.Query()["search[is_simplified]"]
+	if ok && len(keys[0]) >= 1 {
+		criterias.SearchBy["is_simplified"] = keys[0] == "1" || keys[0] == "true"
+	}
+
+	ParsePaginationAndSort(r, &criterias)
+
+	offset := (criterias.Page - 1) * criterias.Size
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("zatca_queue")
+	ctx := context.Background()
+	findOptions := options.Find()
+	findOptions.SetSkip(int64(offset))
+	findOptions.SetLimit(int64(criterias.Size))
+	findOptions.SetSort(criterias.SortBy)
+	findOptions.SetNoCursorTimeout(true)
+	findOptions.SetAllowDiskUse(true)
+
+	cur, err := collection.Find(ctx, criterias.SearchBy, findOptions)
+	if err != nil {
+		return items, criterias, errors.New("Error fetching zatca queue:" + err.Error())
+	}
+	if cur != nil {
+		defer cur.Close(ctx)
+	}
+
+	now := time.Now()
+	for i := 0; cur != nil && cur.Next(ctx); i++ {
+		err := cur.Err()
+		if err != nil {
+			return items, criterias, errors.New("Cursor error:" + err.Error())
+		}
+		item := ZatcaQueueItem{}
+		err = cur.Decode(&item)
+		if err != nil {
+			return items, criterias, errors.New("Cursor decode error:" + err.Error())
+		}
+		item.setHoursLeft(now)
+		items = append(items, item)
+	}
+
+	return items, criterias, nil
+}
+
+// GetZatcaDeadlines lists the pending items that are overdue, due within `hours` or keep
+// failing, the nearest deadline first, with the counts of the store's pending items.
+func (store *Store) GetZatcaDeadlines(r *http.Request) (deadlines ZatcaDeadlines, err error) {
+	window := ZatcaDeadlineWarning
+	keys, ok := r.URL.Query()["hours"]
+	if ok && len(keys[0]) >= 1 {
+		hours, err := strconv.ParseFloat(keys[0], 64)
+		if err != nil || hours < 0 {
+			return deadlines, errors.New("invalid hours")
+		}
+		window = time.Duration(hours * float64(time.Hour))
+	}
+
+	collection := db.GetDB("store_" + store.ID.Hex()).Collection("zatca_queue")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	pending := bson.M{"status": ZatcaQueueStatusPending}
+	counts := []struct {
+		count  *int64
+		filter bson.M
+	}{
+		{&deadlines.Pending, bson.M{}},
+		{&deadlines.Overdue, bson.M{"deadline": bson.M{"$lte": now}}},
+		{&deadlines.DueSoon, bson.M{"deadline": bson.M{"$gt": now, "$lte": now.Add(window)}}},
+		{&deadlines.Stuck, bson.M{"attempts": bson.M{"$gte": ZatcaStuckAttempts}}},
+	}
+	for _, c := range counts {
+		c.filter["status"] = ZatcaQueueStatusPending
+		if *c.count, err = collection.CountDocuments(ctx, c.filter); err != nil {
+			return deadlines, err
+		}
+	}
+
+	pending["$or"] = []bson.M{
+		{"deadline": bson.M{"$lte": now.Add(window)}},
+		{"attempts": bson.M{"$gte": ZatcaStuckAttempts}},
+	}
+	cur, err := collection.Find(ctx, pending)
+	if err != nil {
+		return deadlines, err
+	}
+	deadlines.Items = []ZatcaQueueItem{}
+	if err := cur.All(ctx, &deadlines.Items); err != nil {
+		return deadlines, err
+	}
+	SortZatcaQueueItemsByDeadline(deadlines.Items)
+	for i := range deadlines.Items {
+		deadlines.Items[i].setHoursLeft(now)
+	}
+
+	return deadlines, nil
+}
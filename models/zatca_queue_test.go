@@ -0,0 +1,105 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// ── ZatcaRetryDelay ──────────────────────────────────────────────────────────
+
+func TestZatcaRetryDelay_Doubles(t *testing.T) {
+	cases := map[int]time.Duration{
+		0: time.Minute,
+		1: time.Minute,
+		2: 2 * time.Minute,
+		3: 4 * time.Minute,
+		6: 32 * time.Minute,
+	}
+	for attempts, want := range cases {
+		if got := ZatcaRetryDelay(attempts); got != want {
+			t.Errorf("ZatcaRetryDelay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestZatcaRetryDelay_Capped(t *testing.T) {
+	if got := ZatcaRetryDelay(50); got != time.Hour {
+		t.Errorf("ZatcaRetryDelay(50) = %v, want 1h", got)
+	}
+}
+
+// ── ZatcaDeadlineOf ──────────────────────────────────────────────────────────
+
+func TestZatcaDeadlineOf_SimplifiedOnly(t *testing.T) {
+	issued := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	deadline := ZatcaDeadlineOf(&issued, true)
+	if deadline == nil || !deadline.Equal(issued.Add(24*time.Hour)) {
+		t.Errorf("simplified deadline = %v, want %v", deadline, issued.Add(24*time.Hour))
+	}
+	if ZatcaDeadlineOf(&issued, false) != nil {
+		t.Error("a standard invoice should have no reporting deadline")
+	}
+	if ZatcaDeadlineOf(nil, true) != nil {
+		t.Error("a document without date should have no reporting deadline")
+	}
+}
+
+// ── NeedsAlert ───────────────────────────────────────────────────────────────
+
+func TestZatcaQueueItemNeedsAlert(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	far := now.Add(10 * time.Hour)
+	near := now.Add(5 * time.Hour)
+	past := now.Add(-time.Hour)
+
+	cases := []struct {
+		name string
+		item ZatcaQueueItem
+		want bool
+	}{
+		{"deadline far", ZatcaQueueItem{Status: ZatcaQueueStatusPending, Deadline: &far}, false},
+		{"deadline near", ZatcaQueueItem{Status: ZatcaQueueStatusPending, Deadline: &near}, true},
+		{"overdue", ZatcaQueueItem{Status: ZatcaQueueStatusPending, Deadline: &past}, true},
+		{"no deadline", ZatcaQueueItem{Status: ZatcaQueueStatusPending, Attempts: 1}, false},
+		{"stuck", ZatcaQueueItem{Status: ZatcaQueueStatusPending, Attempts: ZatcaStuckAttempts}, true},
+		{"reported", ZatcaQueueItem{Status: ZatcaQueueStatusReported, Deadline: &past}, false},
+	}
+	for _, c := range cases {
+		if got := c.item.NeedsAlert(now); got != c.want {
+			t.Errorf("%s: NeedsAlert = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// ── SortZatcaQueueItemsByDeadline ────────────────────────────────────────────
+
+func TestSortZatcaQueueItemsByDeadline(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	early := now.Add(time.Hour)
+	late := now.Add(3 * time.Hour)
+
+	items := []ZatcaQueueItem{
+		{DocumentCode: "STD-2", InvoiceCountValue: 2},
+		{DocumentCode: "SIM-LATE", InvoiceCountValue: 9, Deadline: &late},
+		{DocumentCode: "STD-1", InvoiceCountValue: 1},
+		{DocumentCode: "SIM-EARLY-4", InvoiceCountValue: 4, Deadline: &early},
+		{DocumentCode: "SIM-EARLY-3", InvoiceCountValue: 3, Deadline: &early},
+	}
+	SortZatcaQueueItemsByDeadline(items)
+
+	want := []string{"SIM-EARLY-3", "SIM-EARLY-4", "SIM-LATE", "STD-1", "STD-2"}
+	for i, code := range want {
+		if items[i].DocumentCode != code {
+			t.Fatalf("order = %v, want %v", codesOf(items), want)
+		}
+	}
+}
+
+func codesOf(items []ZatcaQueueItem) []string {
+	codes := []string{}
+	for _, item := range items {
+		codes = append(codes, item.DocumentCode)
+	}
+	return codes
+}
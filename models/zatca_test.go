@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/sirinibin/startpos/backend/zatca"
+)
+
+func TestValidateZatcaEnv_SandboxNeedsServerSetting(t *testing.T) {
+	store := Store{}
+	store.Zatca.Env = zatca.EnvSandbox
+
+	t.Setenv("ZATCA_SANDBOX", "")
+	if message := store.ValidateZatcaEnv(nil); message == "" {
+		t.Error("Sandbox should be rejected when the server does not enable it")
+	}
+
+	t.Setenv("ZATCA_SANDBOX", "true")
+	if message := store.ValidateZatcaEnv(nil); message != "" {
+		t.Errorf("Sandbox enabled: message = %q, want none", message)
+	}
+
+	store.Zatca.Env = zatca.EnvProduction
+	t.Setenv("ZATCA_SANDBOX", "")
+	if message := store.ValidateZatcaEnv(nil); message != "" {
+		t.Errorf("Production: message = %q, want none", message)
+	}
+}
+
+func TestValidateZatcaEnv_ProductionCannotSwitchToSandbox(t *testing.T) {
+	t.Setenv("ZATCA_SANDBOX", "true")
+	oldStore := Store{}
+	oldStore.Zatca.Env = zatca.EnvProduction
+	store := Store{}
+	store.Zatca.Env = zatca.EnvSandbox
+
+	if message := store.ValidateZatcaEnv(&oldStore); message == "" {
+		t.Error("a Production store switched to Sandbox should be rejected")
+	}
+
+	oldStore.Zatca.Env = zatca.EnvSimulation
+	if message := store.ValidateZatcaEnv(&oldStore); message != "" {
+		t.Errorf("from Simulation: message = %q, want none", message)
+	}
+}
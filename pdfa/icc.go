@@ -0,0 +1,76 @@
+package pdfa
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// srgbProfile is a version 2 ICC display profile of sRGB, the output intent of
+// the documents: D50 adapted colorants and a 2.2 gamma tone curve.
+func srgbProfile() []byte {
+	s15Fixed16 := func(v float64) []byte {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(int32(math.Round(v*65536))))
+		return b
+	}
+	xyz := func(x, y, z float64) []byte {
+		data := []byte("XYZ \x00\x00\x00\x00")
+		data = append(data, s15Fixed16(x)...)
+		data = append(data, s15Fixed16(y)...)
+		return append(data, s15Fixed16(z)...)
+	}
+	description := func(text string) []byte {
+		data := []byte("desc\x00\x00\x00\x00")
+		data = binary.BigEndian.AppendUint32(data, uint32(len(text)+1))
+		data = append(data, text...)
+		data = append(data, 0)
+		// no Unicode and no ScriptCode description
+		data = append(data, make([]byte, 4+4+2+1+67)...)
+		return data
+	}
+	// gamma 2.2 as u8Fixed8
+	curve := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x01\x02\x33")
+
+	tags := []struct {
+		signature string
+		data      []byte
+	}{
+		{"desc", description("sRGB IEC61966-2.1")},
+		{"cprt", append([]byte("text\x00\x00\x00\x00"), "No copyright, use freely\x00"...)},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", curve},
+		{"gTRC", curve},
+		{"bTRC", curve},
+	}
+
+	offset := 128 + 4 + 12*len(tags)
+	table := binary.BigEndian.AppendUint32(nil, uint32(len(tags)))
+	var body []byte
+	for _, tag := range tags {
+		for len(tag.data)%4 != 0 {
+			tag.data = append(tag.data, 0)
+		}
+		table = append(table, tag.signature...)
+		table = binary.BigEndian.AppendUint32(table, uint32(offset+len(body)))
+		table = binary.BigEndian.AppendUint32(table, uint32(len(tag.data)))
+		body = append(body, tag.data...)
+	}
+
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(offset+len(body)))
+	copy(header[8:], []byte{2, 0x10, 0, 0}) // version 2.1
+	copy(header[12:], "mntrRGB XYZ ")
+	binary.BigEndian.PutUint16(header[24:], 2026) // creation date
+	binary.BigEndian.PutUint16(header[26:], 1)
+	binary.BigEndian.PutUint16(header[28:], 1)
+	copy(header[36:], "acsp")
+	copy(header[68:], s15Fixed16(0.9642)) // D50 illuminant
+	copy(header[72:], s15Fixed16(1.0))
+	copy(header[76:], s15Fixed16(0.8249))
+
+	profile := append(header, table...)
+	return append(profile, body...)
+}
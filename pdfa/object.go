@@ -0,0 +1,272 @@
+package pdfa
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var errSyntax = errors.New("pdfa: malformed PDF")
+
+// The update only reads what it changes: the trailer, the catalog and the
+// objects the catalog points to. Values are kept as the raw text they are
+// written in, and written back as they were.
+
+func isWhite(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// nextToken returns the raw text of the token at i, a whole array,
+// dictionary or string being one token, and the index after it.
+func nextToken(data []byte, i int) (string, int, error) {
+	for i < len(data) {
+		if isWhite(data[i]) {
+			i++
+		} else if data[i] == '%' {
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		} else {
+			break
+		}
+	}
+	if i >= len(data) {
+		return "", i, errSyntax
+	}
+
+	start := i
+	switch {
+	case bytes.HasPrefix(data[i:], []byte("<<")):
+		end, err := skipNested(data, i, "<<", ">>")
+		return string(data[start:end]), end, err
+	case data[i] == '[':
+		end, err := skipNested(data, i, "[", "]")
+		return string(data[start:end]), end, err
+	case data[i] == '(':
+		end, err := skipString(data, i)
+		return string(data[start:end]), end, err
+	case data[i] == '<':
+		end := bytes.IndexByte(data[i:], '>')
+		if end < 0 {
+			return "", i, errSyntax
+		}
+		return string(data[start : i+end+1]), i + end + 1, nil
+	case data[i] == ']' || data[i] == '>' || data[i] == ')':
+		return string(data[i : i+1]), i + 1, nil
+	}
+
+	i++
+	for i < len(data) && !isWhite(data[i]) && !isDelimiter(data[i]) {
+		i++
+	}
+	return string(data[start:i]), i, nil
+}
+
+// skipNested returns the index after the close matching the open at i.
+func skipNested(data []byte, i int, open, close string) (int, error) {
+	depth := 0
+	for i < len(data) {
+		switch {
+		case bytes.HasPrefix(data[i:], []byte(open)):
+			depth++
+			i += len(open)
+		case bytes.HasPrefix(data[i:], []byte(close)):
+			depth--
+			i += len(close)
+			if depth == 0 {
+				return i, nil
+			}
+		case data[i] == '(':
+			end, err := skipString(data, i)
+			if err != nil {
+				return i, err
+			}
+			i = end
+		case data[i] == '<' && open == "[":
+			// a hex string or a dictionary inside an array
+			if bytes.HasPrefix(data[i:], []byte("<<")) {
+				end, err := skipNested(data, i, "<<", ">>")
+				if err != nil {
+					return i, err
+				}
+				i = end
+				continue
+			}
+			end := bytes.IndexByte(data[i:], '>')
+			if end < 0 {
+				return i, errSyntax
+			}
+			i += end + 1
+		case data[i] == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return i, errSyntax
+}
+
+// skipString returns the index after the literal string at i.
+func skipString(data []byte, i int) (int, error) {
+	depth := 0
+	for i < len(data) {
+		switch data[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+		i++
+	}
+	return i, errSyntax
+}
+
+// entry is a key of a dictionary and the raw text of its value.
+type entry struct {
+	Key   string
+	Value string
+}
+
+// dict is a dictionary with its keys in the order they are written.
+type dict []entry
+
+func isInteger(token string) bool {
+	_, err := strconv.Atoi(token)
+	return err == nil
+}
+
+// parseDict reads the dictionary written in text. A reference "12 0 R" is one value.
+func parseDict(text string) (dict, error) {
+	data := []byte(strings.TrimSpace(text))
+	if !bytes.HasPrefix(data, []byte("<<")) || !bytes.HasSuffix(data, []byte(">>")) {
+		return nil, errSyntax
+	}
+	data = data[2 : len(data)-2]
+
+	var d dict
+	i := 0
+	for {
+		key, next, err := nextToken(data, i)
+		if err != nil {
+			// the end of the dictionary
+			return d, nil
+		}
+		if !strings.HasPrefix(key, "/") {
+			return nil, errSyntax
+		}
+		value, next, err := nextToken(data, next)
+		if err != nil {
+			return nil, errSyntax
+		}
+		if isInteger(value) {
+			generation, afterGeneration, err1 := nextToken(data, next)
+			r, afterR, err2 := nextToken(data, afterGeneration)
+			if err1 == nil && err2 == nil && isInteger(generation) && r == "R" {
+				value, next = value+" "+generation+" R", afterR
+			}
+		}
+		d = append(d, entry{key, value})
+		i = next
+	}
+}
+
+// Get is the value of the key, "" when it is not set.
+func (d dict) Get(key string) string {
+	for _, e := range d {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return ""
+}
+
+// Set replaces the value of the key or adds the key.
+func (d *dict) Set(key, value string) {
+	for i, e := range *d {
+		if e.Key == key {
+			(*d)[i].Value = value
+			return
+		}
+	}
+	*d = append(*d, entry{key, value})
+}
+
+func (d dict) String() string {
+	var b strings.Builder
+	b.WriteString("<<")
+	for _, e := range d {
+		b.WriteString(" " + e.Key + " " + e.Value)
+	}
+	b.WriteString(" >>")
+	return b.String()
+}
+
+// ref is an indirect reference.
+type ref struct {
+	Number     int
+	Generation int
+}
+
+func (r ref) String() string {
+	return fmt.Sprintf("%d %d R", r.Number, r.Generation)
+}
+
+var refPattern = regexp.MustCompile(`^(\d+)\s+(\d+)\s+R$`)
+
+func parseRef(value string) (ref, bool) {
+	m := refPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return ref{}, false
+	}
+	number, _ := strconv.Atoi(m[1])
+	generation, _ := strconv.Atoi(m[2])
+	return ref{number, generation}, true
+}
+
+// findObject returns the raw text of the latest definition of the object, the
+// part between "obj" and "endobj". Objects inside object streams are not found.
+func findObject(data []byte, r ref) (string, error) {
+	pattern := regexp.MustCompile(fmt.Sprintf(`(?:^|\s)%d\s+%d\s+obj\b`, r.Number, r.Generation))
+	matches := pattern.FindAllIndex(data, -1)
+	if len(matches) == 0 {
+		return "", fmt.Errorf("pdfa: object %d %d is not found, object streams are not supported", r.Number, r.Generation)
+	}
+	start := matches[len(matches)-1][1]
+	value, end, err := nextToken(data, start)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(value, "<<") {
+		// a stream is not rewritten, only dictionaries are
+		if after, _, err := nextToken(data, end); err == nil && after == "stream" {
+			return "", fmt.Errorf("pdfa: object %d %d is a stream", r.Number, r.Generation)
+		}
+	}
+	return value, nil
+}
+
+// findDict is the dictionary the value is or refers to.
+func findDict(data []byte, value string) (dict, error) {
+	if r, ok := parseRef(value); ok {
+		text, err := findObject(data, r)
+		if err != nil {
+			return nil, err
+		}
+		value = text
+	}
+	return parseDict(value)
+}
@@ -0,0 +1,464 @@
+// Package pdfa turns the PDFs headless Chrome prints into PDF/A-3b documents
+// with associated files, the form B2B customers archive tax invoices in with
+// the cleared XML attached.
+//
+// The PDF is not rewritten: an incremental update adds the XMP metadata, the
+// sRGB output intent, the embedded files and a new document information
+// dictionary, and redefines the catalog to point to them. Only PDFs with a
+// classic cross-reference table, as Chrome writes them, are supported.
+package pdfa
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Relationships of an associated file to the document, the values of AFRelationship.
+const (
+	// RelationshipAlternative is an equivalent representation of the document, as the cleared XML of an invoice is
+	RelationshipAlternative = "Alternative"
+	RelationshipData        = "Data"
+	RelationshipSource      = "Source"
+	RelationshipSupplement  = "Supplement"
+)
+
+// Attachment is a file embedded in the document as an associated file.
+type Attachment struct {
+	Name         string
+	Description  string
+	MIMEType     string
+	Relationship string
+	Data         []byte
+	ModDate      time.Time
+}
+
+// Metadata is the document information, written both to the XMP metadata and
+// the document information dictionary.
+type Metadata struct {
+	Title    string
+	Author   string
+	Subject  string
+	Keywords string
+	Creator  string
+	Producer string
+	// CreateDate is the creation time of the document, now when it is zero
+	CreateDate time.Time
+}
+
+var (
+	startXRefPattern = regexp.MustCompile(`startxref\s+(\d+)\s+%%EOF\s*$`)
+	idPattern        = regexp.MustCompile(`<[0-9A-Fa-f]*>`)
+)
+
+// Convert returns the PDF as a PDF/A-3b document with the attachments.
+func Convert(pdf []byte, metadata Metadata, attachments ...Attachment) ([]byte, error) {
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.")) {
+		return nil, errors.New("pdfa: not a PDF")
+	}
+
+	m := startXRefPattern.FindSubmatch(pdf)
+	if m == nil {
+		return nil, errors.New("pdfa: no startxref at the end of the PDF")
+	}
+	prev, _ := strconv.Atoi(string(m[1]))
+	if prev >= len(pdf) || !bytes.HasPrefix(pdf[prev:], []byte("xref")) {
+		return nil, errors.New("pdfa: the PDF has a cross-reference stream, only cross-reference tables are supported")
+	}
+
+	i := bytes.Index(pdf[prev:], []byte("trailer"))
+	if i < 0 {
+		return nil, errors.New("pdfa: the PDF has no trailer")
+	}
+	trailerText, _, err := nextToken(pdf, prev+i+len("trailer"))
+	if err != nil {
+		return nil, err
+	}
+	trailer, err := parseDict(trailerText)
+	if err != nil {
+		return nil, err
+	}
+	if trailer.Get("/Encrypt") != "" {
+		return nil, errors.New("pdfa: encrypted PDFs are not allowed in PDF/A")
+	}
+	size, err := strconv.Atoi(trailer.Get("/Size"))
+	if err != nil {
+		return nil, errors.New("pdfa: the trailer has no /Size")
+	}
+	rootRef, ok := parseRef(trailer.Get("/Root"))
+	if !ok {
+		return nil, errors.New("pdfa: the trailer has no /Root")
+	}
+	catalog, err := findDict(pdf, rootRef.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.CreateDate.IsZero() {
+		metadata.CreateDate = time.Now()
+	}
+
+	u := &update{base: pdf, next: size}
+
+	// the document information and the XMP metadata carry the same values
+	info := dict{}
+	for _, e := range []entry{
+		{"/Title", metadata.Title},
+		{"/Author", metadata.Author},
+		{"/Subject", metadata.Subject},
+		{"/Keywords", metadata.Keywords},
+		{"/Creator", metadata.Creator},
+		{"/Producer", metadata.Producer},
+	} {
+		if e.Value != "" {
+			info.Set(e.Key, textString(e.Value))
+		}
+	}
+	info.Set("/CreationDate", textString(pdfDate(metadata.CreateDate)))
+	info.Set("/ModDate", textString(pdfDate(metadata.CreateDate)))
+	infoRef := u.add(info.String())
+
+	xmp := []byte(xmpPacket(metadata))
+	metadataRef := u.add(fmt.Sprintf("<< /Type /Metadata /Subtype /XML /Length %d >>\nstream\n%s\nendstream", len(xmp), xmp))
+	catalog.Set("/Metadata", metadataRef.String())
+
+	profile := srgbProfile()
+	profileRef := u.add(fmt.Sprintf("<< /N 3 /Length %d >>\nstream\n%s\nendstream", len(profile), profile))
+	catalog.Set("/OutputIntents", fmt.Sprintf("[<< /Type /OutputIntent /S /GTS_PDFA1 /OutputConditionIdentifier (sRGB IEC61966-2.1) /Info (sRGB IEC61966-2.1) /RegistryName (http://www.color.org) /DestOutputProfile %s >>]", profileRef))
+
+	if len(attachments) > 0 {
+		var fileSpecs, names []string
+		for _, attachment := range attachments {
+			fileSpec, err := u.addAttachment(attachment)
+			if err != nil {
+				return nil, err
+			}
+			fileSpecs = append(fileSpecs, fileSpec.String())
+			names = append(names, textString(attachment.Name)+" "+fileSpec.String())
+		}
+		catalog.Set("/AF", "["+strings.Join(fileSpecs, " ")+"]")
+
+		if err := u.setEmbeddedFiles(&catalog, "[ "+strings.Join(names, " ")+" ]"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.printAnnotations(catalog.Get("/Pages")); err != nil {
+		return nil, err
+	}
+
+	u.set(rootRef, catalog.String())
+
+	// the first identifier stays that of the original document
+	id := "<" + randomHex() + ">"
+	firstID := id
+	if ids := idPattern.FindAllString(trailer.Get("/ID"), -1); len(ids) == 2 {
+		firstID = ids[0]
+	}
+	newTrailer := dict{
+		{"/Root", rootRef.String()},
+		{"/Info", infoRef.String()},
+		{"/ID", "[" + firstID + " " + id + "]"},
+		{"/Prev", strconv.Itoa(prev)},
+	}
+	return u.write(newTrailer), nil
+}
+
+// update is the incremental update being written.
+type update struct {
+	base    []byte
+	next    int
+	objects []object
+}
+
+type object struct {
+	ref  ref
+	body string
+}
+
+// add adds an object with a new number.
+func (u *update) add(body string) ref {
+	r := ref{u.next, 0}
+	u.next++
+	u.objects = append(u.objects, object{r, body})
+	return r
+}
+
+// set redefines an object of the document.
+func (u *update) set(r ref, body string) {
+	for i, o := range u.objects {
+		if o.ref == r {
+			u.objects[i].body = body
+			return
+		}
+	}
+	u.objects = append(u.objects, object{r, body})
+}
+
+// addAttachment adds the embedded file stream and the file specification of the attachment.
+func (u *update) addAttachment(attachment Attachment) (ref, error) {
+	if attachment.Name == "" {
+		return ref{}, errors.New("pdfa: an attachment has no name")
+	}
+	relationship := attachment.Relationship
+	if relationship == "" {
+		relationship = RelationshipData
+	}
+	mimeType := attachment.MIMEType
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	modDate := attachment.ModDate
+	if modDate.IsZero() {
+		modDate = time.Now()
+	}
+
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	if _, err := w.Write(attachment.Data); err != nil {
+		return ref{}, err
+	}
+	if err := w.Close(); err != nil {
+		return ref{}, err
+	}
+	checksum := md5.Sum(attachment.Data)
+
+	file := u.add(fmt.Sprintf("<< /Type /EmbeddedFile /Subtype %s /Filter /FlateDecode /Params << /ModDate %s /Size %d /CheckSum <%s> >> /Length %d >>\nstream\n%s\nendstream",
+		name(mimeType), textString(pdfDate(modDate)), len(attachment.Data), hex.EncodeToString(checksum[:]), compressed.Len(), compressed.Bytes()))
+
+	fileSpec := dict{
+		{"/Type", "/Filespec"},
+		{"/F", textString(attachment.Name)},
+		{"/UF", textString(attachment.Name)},
+		{"/EF", fmt.Sprintf("<< /F %s /UF %s >>", file, file)},
+		{"/AFRelationship", name(relationship)},
+	}
+	if attachment.Description != "" {
+		fileSpec.Set("/Desc", textString(attachment.Description))
+	}
+	return u.add(fileSpec.String()), nil
+}
+
+// setEmbeddedFiles sets the embedded files name tree of the catalog, keeping
+// the other name trees.
+func (u *update) setEmbeddedFiles(catalog *dict, names string) error {
+	embeddedFiles := "<< /Names " + names + " >>"
+	value := catalog.Get("/Names")
+	if value == "" {
+		catalog.Set("/Names", "<< /EmbeddedFiles "+embeddedFiles+" >>")
+		return nil
+	}
+
+	nameTrees, err := findDict(u.base, value)
+	if err != nil {
+		return err
+	}
+	nameTrees.Set("/EmbeddedFiles", embeddedFiles)
+	if r, ok := parseRef(value); ok {
+		u.set(r, nameTrees.String())
+	} else {
+		catalog.Set("/Names", nameTrees.String())
+	}
+	return nil
+}
+
+// printAnnotations sets the print flag of the annotations of the pages, which
+// PDF/A requires. Chrome writes the links of the page without flags.
+func (u *update) printAnnotations(pages string) error {
+	node, err := findDict(u.base, pages)
+	if err != nil {
+		return err
+	}
+	if kids := node.Get("/Kids"); kids != "" {
+		for _, kid := range refsOf(kids) {
+			if err := u.printAnnotations(kid.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	annots := node.Get("/Annots")
+	if annots == "" {
+		return nil
+	}
+	if r, ok := parseRef(annots); ok {
+		text, err := findObject(u.base, r)
+		if err != nil {
+			return err
+		}
+		annots = text
+	}
+	for _, r := range refsOf(annots) {
+		annotation, err := findDict(u.base, r.String())
+		if err != nil {
+			return err
+		}
+		flags, _ := strconv.Atoi(annotation.Get("/F"))
+		// print, and neither hidden nor invisible nor no-view
+		fixed := (flags | 4) &^ (1 | 2 | 32)
+		if fixed != flags || annotation.Get("/F") == "" {
+			annotation.Set("/F", strconv.Itoa(fixed))
+			u.set(r, annotation.String())
+		}
+	}
+	return nil
+}
+
+var refsPattern = regexp.MustCompile(`(\d+)\s+(\d+)\s+R`)
+
+// refsOf are the references of an array.
+func refsOf(array string) []ref {
+	var refs []ref
+	for _, m := range refsPattern.FindAllStringSubmatch(array, -1) {
+		number, _ := strconv.Atoi(m[1])
+		generation, _ := strconv.Atoi(m[2])
+		refs = append(refs, ref{number, generation})
+	}
+	return refs
+}
+
+// write appends the objects, the cross-reference section and the trailer to the document.
+func (u *update) write(trailer dict) []byte {
+	out := bytes.NewBuffer(append([]byte{}, u.base...))
+	if !bytes.HasSuffix(u.base, []byte("\n")) {
+		out.WriteByte('\n')
+	}
+
+	offsets := map[ref]int{}
+	size := u.next
+	for _, o := range u.objects {
+		offsets[o.ref] = out.Len()
+		fmt.Fprintf(out, "%d %d obj\n%s\nendobj\n", o.ref.Number, o.ref.Generation, o.body)
+		if o.ref.Number >= size {
+			size = o.ref.Number + 1
+		}
+	}
+
+	xref := out.Len()
+	out.WriteString("xref\n0 1\n0000000000 65535 f \n")
+	for _, o := range u.objects {
+		fmt.Fprintf(out, "%d 1\n%010d %05d n \n", o.ref.Number, offsets[o.ref], o.ref.Generation)
+	}
+	trailer.Set("/Size", strconv.Itoa(size))
+	fmt.Fprintf(out, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", trailer, xref)
+	return out.Bytes()
+}
+
+func randomHex() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// name writes a name object, escaping what is not a regular character.
+func name(value string) string {
+	var b strings.Builder
+	b.WriteByte('/')
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < '!' || c > '~' || c == '#' || isDelimiter(c) {
+			fmt.Fprintf(&b, "#%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// textString writes a text string: a literal string when it is ASCII, else
+// UTF-16BE with a byte order mark, as the names of Arabic stores need.
+func textString(value string) string {
+	ascii := true
+	for i := 0; i < len(value); i++ {
+		if value[i] < ' ' || value[i] > '~' {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(value) + ")"
+	}
+
+	b := []byte{0xfe, 0xff}
+	for _, unit := range utf16.Encode([]rune(value)) {
+		b = append(b, byte(unit>>8), byte(unit))
+	}
+	return "<" + strings.ToUpper(hex.EncodeToString(b)) + ">"
+}
+
+// pdfDate is the date as a PDF date string, D:YYYYMMDDHHmmSS+HH'mm'.
+func pdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	return fmt.Sprintf("D:%s%s%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
+
+func escapeXML(value string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
+
+// xmpPacket is the XMP metadata of the document with the PDF/A-3b identification.
+func xmpPacket(metadata Metadata) string {
+	date := metadata.CreateDate.Format(time.RFC3339)
+	var b strings.Builder
+	b.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/">` + "\n")
+	b.WriteString(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` + "\n")
+
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/">` + "\n")
+	b.WriteString("<pdfaid:part>3</pdfaid:part>\n<pdfaid:conformance>B</pdfaid:conformance>\n")
+	b.WriteString("</rdf:Description>\n")
+
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	b.WriteString("<dc:format>application/pdf</dc:format>\n")
+	if metadata.Title != "" {
+		b.WriteString(`<dc:title><rdf:Alt><rdf:li xml:lang="x-default">` + escapeXML(metadata.Title) + "</rdf:li></rdf:Alt></dc:title>\n")
+	}
+	if metadata.Author != "" {
+		b.WriteString("<dc:creator><rdf:Seq><rdf:li>" + escapeXML(metadata.Author) + "</rdf:li></rdf:Seq></dc:creator>\n")
+	}
+	if metadata.Subject != "" {
+		b.WriteString(`<dc:description><rdf:Alt><rdf:li xml:lang="x-default">` + escapeXML(metadata.Subject) + "</rdf:li></rdf:Alt></dc:description>\n")
+	}
+	b.WriteString("</rdf:Description>\n")
+
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/">` + "\n")
+	b.WriteString("<xmp:CreateDate>" + date + "</xmp:CreateDate>\n")
+	b.WriteString("<xmp:ModifyDate>" + date + "</xmp:ModifyDate>\n")
+	b.WriteString("<xmp:MetadataDate>" + date + "</xmp:MetadataDate>\n")
+	if metadata.Creator != "" {
+		b.WriteString("<xmp:CreatorTool>" + escapeXML(metadata.Creator) + "</xmp:CreatorTool>\n")
+	}
+	b.WriteString("</rdf:Description>\n")
+
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:pdf="http://ns.adobe.com/pdf/1.3/">` + "\n")
+	if metadata.Producer != "" {
+		b.WriteString("<pdf:Producer>" + escapeXML(metadata.Producer) + "</pdf:Producer>\n")
+	}
+	if metadata.Keywords != "" {
+		b.WriteString("<pdf:Keywords>" + escapeXML(metadata.Keywords) + "</pdf:Keywords>\n")
+	}
+	b.WriteString("</rdf:Description>\n")
+
+	b.WriteString("</rdf:RDF>\n</x:xmpmeta>\n")
+	// room for editors to update the metadata in place
+	b.WriteString(strings.Repeat(strings.Repeat(" ", 99)+"\n", 20))
+	b.WriteString(`<?xpacket end="w"?>`)
+	return b.String()
+}
@@ -0,0 +1,266 @@
+package pdfa
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPDF writes a one page PDF with a classic cross-reference table, the way
+// Chrome does, with the objects numbered from 1.
+func testPDF(trailerExtra string, objects ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := []int{}
+	for i, o := range objects {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R%s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailerExtra, xref)
+	return b.Bytes()
+}
+
+var chromePDF = testPDF(" /Info 6 0 R /ID [<0102><0304>]",
+	"<< /Type /Catalog /Pages 2 0 R /Names 7 0 R >>",
+	"<< /Type /Pages /Count 1 /Kids [3 0 R] >>",
+	"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R /Annots [5 0 R] >>",
+	"<< /Length 8 >>\nstream\nBT ET q\nendstream",
+	"<< /Type /Annot /Subtype /Link /Rect [0 0 10 10] /A << /S /URI /URI (https://example.com/a\\(b\\)c(d)) >> >>",
+	"<< /Producer (Skia/PDF m120) /Creator (Chromium) >>",
+	"<< /Dests << >> >>",
+)
+
+func mustDict(t *testing.T, pdf []byte, value string) dict {
+	t.Helper()
+	d, err := findDict(pdf, value)
+	if err != nil {
+		t.Fatalf("%s: %v", value, err)
+	}
+	return d
+}
+
+// streamOf is the decoded content of a stream object.
+func streamOf(t *testing.T, pdf []byte, r ref) []byte {
+	t.Helper()
+	i := regexp.MustCompile(fmt.Sprintf(`(?:^|\s)%d 0 obj\b`, r.Number)).FindIndex(pdf)
+	if i == nil {
+		t.Fatalf("no object %d", r.Number)
+	}
+	header, end, err := nextToken(pdf, i[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := parseDict(header)
+	length, _ := strconv.Atoi(d.Get("/Length"))
+	start := bytes.Index(pdf[end:], []byte("stream\n")) + end + len("stream\n")
+	data := pdf[start : start+length]
+	if !bytes.HasPrefix(pdf[start+length:], []byte("\nendstream")) {
+		t.Errorf("object %d: /Length %d does not end at endstream", r.Number, length)
+	}
+	if d.Get("/Filter") == "/FlateDecode" {
+		reader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, err = io.ReadAll(reader); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return data
+}
+
+// ── Convert ──────────────────────────────────────────────────────────────────
+
+func TestConvert_IncrementalUpdate(t *testing.T) {
+	invoiceXML := []byte(`<?xml version="1.0" encoding="UTF-8"?><Invoice>cleared</Invoice>`)
+	created := time.Date(2026, 7, 20, 12, 30, 0, 0, time.FixedZone("AST", 3*60*60))
+	out, err := Convert(chromePDF, Metadata{
+		Title:      "Tax Invoice S-INV-0001",
+		Author:     "متجر الاختبار",
+		Creator:    "Start POS",
+		Producer:   "Start POS",
+		CreateDate: created,
+	}, Attachment{
+		Name:         "S-INV-0001.xml",
+		Description:  "ZATCA cleared invoice",
+		MIMEType:     "application/xml",
+		Relationship: RelationshipAlternative,
+		Data:         invoiceXML,
+		ModDate:      created,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, chromePDF) {
+		t.Fatal("the original PDF is not kept as is")
+	}
+
+	// the new cross-reference section points at every object it lists
+	m := startXRefPattern.FindSubmatch(out)
+	xref, _ := strconv.Atoi(string(m[1]))
+	if xref <= len(chromePDF) || !bytes.HasPrefix(out[xref:], []byte("xref\n0 1\n0000000000 65535 f \n")) {
+		t.Fatalf("startxref = %d", xref)
+	}
+	entries := regexp.MustCompile(`(\d+) 1\n(\d{10}) 00000 n \n`).FindAllStringSubmatch(string(out[xref:]), -1)
+	for _, e := range entries {
+		offset, _ := strconv.Atoi(e[2])
+		if !bytes.HasPrefix(out[offset:], []byte(e[1]+" 0 obj\n")) {
+			t.Errorf("object %s: offset %d is not its definition", e[1], offset)
+		}
+	}
+
+	trailerText, _, _ := nextToken(out, xref+bytes.Index(out[xref:], []byte("trailer"))+len("trailer"))
+	trailer, _ := parseDict(trailerText)
+	if trailer.Get("/Prev") != string(startXRefPattern.FindSubmatch(chromePDF)[1]) || trailer.Get("/Root") != "1 0 R" {
+		t.Errorf("trailer = %s", trailer)
+	}
+	if !strings.HasPrefix(trailer.Get("/ID"), "[<0102> <") {
+		t.Errorf("/ID = %s, want the original first identifier", trailer.Get("/ID"))
+	}
+	if size, _ := strconv.Atoi(trailer.Get("/Size")); size != 8+len(entries)-3 {
+		// the catalog, the name trees and the link are redefined
+		t.Errorf("/Size = %d with %d entries", size, len(entries))
+	}
+
+	catalog := mustDict(t, out, "1 0 R")
+	for _, key := range []string{"/Metadata", "/OutputIntents", "/AF"} {
+		if catalog.Get(key) == "" {
+			t.Errorf("catalog has no %s: %s", key, catalog)
+		}
+	}
+	if catalog.Get("/Pages") != "2 0 R" || catalog.Get("/Names") != "7 0 R" {
+		t.Errorf("catalog = %s, want the pages and names kept", catalog)
+	}
+
+	xmp := string(streamOf(t, out, refsOf(catalog.Get("/Metadata"))[0]))
+	for _, want := range []string{"<pdfaid:part>3</pdfaid:part>", "<pdfaid:conformance>B</pdfaid:conformance>", "Tax Invoice S-INV-0001", "متجر الاختبار", "<xmp:CreateDate>2026-07-20T12:30:00+03:00</xmp:CreateDate>"} {
+		if !strings.Contains(xmp, want) {
+			t.Errorf("XMP has no %s", want)
+		}
+	}
+
+	info := mustDict(t, out, trailer.Get("/Info"))
+	if info.Get("/CreationDate") != "(D:20260720123000+03'00')" || info.Get("/Title") != "(Tax Invoice S-INV-0001)" || info.Get("/Producer") != "(Start POS)" {
+		t.Errorf("info = %s", info)
+	}
+	if info.Get("/Author") != textString("متجر الاختبار") || !strings.HasPrefix(info.Get("/Author"), "<FEFF") {
+		t.Errorf("author = %s, want UTF-16", info.Get("/Author"))
+	}
+
+	// the attachment is in the name tree and associated to the document
+	names := mustDict(t, out, "7 0 R")
+	if names.Get("/Dests") != "<< >>" {
+		t.Errorf("names = %s, want the destinations kept", names)
+	}
+	fileSpecRef := refsOf(catalog.Get("/AF"))[0]
+	if !strings.Contains(names.Get("/EmbeddedFiles"), "(S-INV-0001.xml) "+fileSpecRef.String()) {
+		t.Errorf("embedded files = %s", names.Get("/EmbeddedFiles"))
+	}
+	fileSpec := mustDict(t, out, fileSpecRef.String())
+	if fileSpec.Get("/AFRelationship") != "/Alternative" || fileSpec.Get("/UF") != "(S-INV-0001.xml)" {
+		t.Errorf("file specification = %s", fileSpec)
+	}
+	ef, _ := parseDict(fileSpec.Get("/EF"))
+	if got := streamOf(t, out, refsOf(ef.Get("/F"))[0]); !bytes.Equal(got, invoiceXML) {
+		t.Errorf("embedded file = %s", got)
+	}
+	if !bytes.Contains(out, []byte(ef.Get("/F")[:len(ef.Get("/F"))-2]+" obj\n<< /Type /EmbeddedFile /Subtype /application#2Fxml ")) {
+		t.Error("embedded file has no MIME type")
+	}
+
+	if link := mustDict(t, out, "5 0 R"); link.Get("/F") != "4" || !strings.Contains(link.Get("/A"), `(https://example.com/a\(b\)c(d))`) {
+		t.Errorf("link = %s, want the print flag", link)
+	}
+}
+
+func TestConvert_WithoutNamesOrAttachments(t *testing.T) {
+	pdf := testPDF("",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Count 0 /Kids [] >>",
+	)
+	out, err := Convert(pdf, Metadata{Title: "Receipt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := mustDict(t, out, "1 0 R")
+	if catalog.Get("/AF") != "" || catalog.Get("/Names") != "" || catalog.Get("/Metadata") == "" {
+		t.Errorf("catalog = %s", catalog)
+	}
+
+	out, err = Convert(pdf, Metadata{}, Attachment{Name: "a.xml", Data: []byte("<a/>")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, _ := parseDict(mustDict(t, out, "1 0 R").Get("/Names"))
+	if !strings.HasPrefix(names.Get("/EmbeddedFiles"), "<< /Names [ (a.xml) ") {
+		t.Errorf("names = %s", names)
+	}
+}
+
+func TestConvert_Unsupported(t *testing.T) {
+	encrypted := testPDF(" /Encrypt 3 0 R", "<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [] >>", "<< /Filter /Standard >>")
+	xrefStream := []byte("%PDF-1.5\n1 0 obj\n<< /Type /XRef >>\nstream\n\nendstream\nendobj\nstartxref\n9\n%%EOF\n")
+
+	for name, pdf := range map[string][]byte{
+		"not a PDF":   []byte("<html></html>"),
+		"encrypted":   encrypted,
+		"xref stream": xrefStream,
+		"truncated":   chromePDF[:len(chromePDF)-20],
+	} {
+		if _, err := Convert(pdf, Metadata{}); err == nil {
+			t.Errorf("%s: converted", name)
+		}
+	}
+}
+
+// ── Encoding ─────────────────────────────────────────────────────────────────
+
+func TestTextStringAndName(t *testing.T) {
+	cases := map[string]string{
+		"Invoice (1)": `(Invoice \(1\))`,
+		`a\b`:         `(a\\b)`,
+		"é":           "<FEFF00E9>",
+	}
+	for in, want := range cases {
+		if got := textString(in); got != want {
+			t.Errorf("textString(%q) = %s, want %s", in, got, want)
+		}
+	}
+	if got := name("application/xml"); got != "/application#2Fxml" {
+		t.Errorf("name = %s", got)
+	}
+	if got := pdfDate(time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("", -(4*60+30)*60))); got != "D:20260102030405-04'30'" {
+		t.Errorf("pdfDate = %s", got)
+	}
+}
+
+func TestSRGBProfile(t *testing.T) {
+	profile := srgbProfile()
+	if size := binary.BigEndian.Uint32(profile); int(size) != len(profile) {
+		t.Errorf("profile size = %d, want %d", size, len(profile))
+	}
+	if string(profile[36:40]) != "acsp" || string(profile[12:24]) != "mntrRGB XYZ " {
+		t.Errorf("profile header = %q", profile[:40])
+	}
+
+	count := int(binary.BigEndian.Uint32(profile[128:]))
+	for i := 0; i < count; i++ {
+		entry := profile[132+12*i:]
+		offset, length := binary.BigEndian.Uint32(entry[4:]), binary.BigEndian.Uint32(entry[8:])
+		if offset%4 != 0 || int(offset+length) > len(profile) {
+			t.Errorf("tag %s at %d+%d", entry[:4], offset, length)
+		}
+	}
+}
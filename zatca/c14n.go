@@ -0,0 +1,351 @@
+package zatca
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Element is a node of a parsed XML document. Prefixes are kept as written so
+// that the document can be canonicalised and written back unchanged.
+type Element struct {
+	Prefix   string
+	Local    string
+	Attrs    []Attr
+	Children []Node
+	Parent   *Element
+}
+
+// Attr is an attribute or a namespace declaration (Prefix "xmlns", or Local
+// "xmlns" for the default namespace).
+type Attr struct {
+	Prefix string
+	Local  string
+	Value  string
+}
+
+// Node is an *Element, a Text or a ProcInst.
+type Node interface{}
+
+type Text string
+
+type ProcInst struct {
+	Target string
+	Inst   string
+}
+
+// Parse reads an XML document. Comments, the XML declaration and the
+// whitespace around the root element are dropped, like canonical XML does.
+func Parse(data []byte) (*Element, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+
+	var root, current *Element
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			element := &Element{Prefix: t.Name.Space, Local: t.Name.Local, Parent: current}
+			for _, a := range t.Attr {
+				element.Attrs = append(element.Attrs, Attr{Prefix: a.Name.Space, Local: a.Name.Local, Value: a.Value})
+			}
+			if current == nil {
+				if root != nil {
+					return nil, errors.New("zatca: more than one root element")
+				}
+				root = element
+			} else {
+				current.Children = append(current.Children, element)
+			}
+			current = element
+		case xml.EndElement:
+			if current == nil {
+				return nil, errors.New("zatca: unexpected end element " + t.Name.Local)
+			}
+			current = current.Parent
+		case xml.CharData:
+			if current != nil {
+				current.appendText(string(t))
+			}
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				continue
+			}
+			if current != nil {
+				current.Children = append(current.Children, ProcInst{Target: t.Target, Inst: string(t.Inst)})
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("zatca: no root element")
+	}
+	return root, nil
+}
+
+func (e *Element) appendText(text string) {
+	if n := len(e.Children); n > 0 {
+		if previous, ok := e.Children[n-1].(Text); ok {
+			e.Children[n-1] = previous + Text(text)
+			return
+		}
+	}
+	e.Children = append(e.Children, Text(text))
+}
+
+// Name is the qualified name of the element as written.
+func (e *Element) Name() string {
+	if e.Prefix == "" {
+		return e.Local
+	}
+	return e.Prefix + ":" + e.Local
+}
+
+// Attr returns the value of an unprefixed attribute.
+func (e *Element) Attr(local string) string {
+	for _, a := range e.Attrs {
+		if a.Prefix == "" && a.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// SetAttr sets an unprefixed attribute.
+func (e *Element) SetAttr(local, value string) {
+	for i, a := range e.Attrs {
+		if a.Prefix == "" && a.Local == local {
+			e.Attrs[i].Value = value
+			return
+		}
+	}
+	e.Attrs = append(e.Attrs, Attr{Local: local, Value: value})
+}
+
+// Text is the concatenated text of the element and its descendants.
+func (e *Element) Text() string {
+	var b strings.Builder
+	for _, child := range e.Children {
+		switch c := child.(type) {
+		case Text:
+			b.WriteString(string(c))
+		case *Element:
+			b.WriteString(c.Text())
+		}
+	}
+	return b.String()
+}
+
+// SetText replaces the content of the element with text.
+func (e *Element) SetText(text string) {
+	e.Children = []Node{Text(text)}
+}
+
+// Child returns the first child element with the qualified name.
+func (e *Element) Child(name string) *Element {
+	for _, child := range e.Children {
+		if c, ok := child.(*Element); ok && c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Find follows a path of qualified child names, e.g.
+// "cac:AccountingSupplierParty/cac:Party/cac:PartyLegalEntity".
+func (e *Element) Find(path string) *Element {
+	current := e
+	for _, name := range strings.Split(path, "/") {
+		if current = current.Child(name); current == nil {
+			return nil
+		}
+	}
+	return current
+}
+
+// Descendant returns the first element with the qualified name in document order.
+func (e *Element) Descendant(name string) *Element {
+	for _, child := range e.Children {
+		if c, ok := child.(*Element); ok {
+			if c.Name() == name {
+				return c
+			}
+			if found := c.Descendant(name); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// Remove drops the child elements for which drop returns true, at any depth.
+func (e *Element) Remove(drop func(*Element) bool) {
+	children := e.Children[:0]
+	for _, child := range e.Children {
+		if c, ok := child.(*Element); ok {
+			if drop(c) {
+				continue
+			}
+			c.Remove(drop)
+		}
+		children = append(children, child)
+	}
+	e.Children = children
+}
+
+// Insert adds nodes to the children of e before index i.
+func (e *Element) Insert(i int, nodes ...Node) {
+	for _, node := range nodes {
+		if c, ok := node.(*Element); ok {
+			c.Parent = e
+		}
+	}
+	e.Children = append(e.Children[:i], append(nodes, e.Children[i:]...)...)
+}
+
+// Index is the position of child among the children of e, -1 when missing.
+func (e *Element) Index(child *Element) int {
+	for i, c := range e.Children {
+		if c == Node(child) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Canonicalize writes the element as Canonical XML 1.1 without comments.
+// The element is taken as the document element: the namespace declarations in
+// scope from its ancestors are not rendered.
+func (e *Element) Canonicalize() []byte {
+	var b bytes.Buffer
+	e.canonicalize(&b, map[string]string{"": ""})
+	return b.Bytes()
+}
+
+func (e *Element) canonicalize(b *bytes.Buffer, rendered map[string]string) {
+	var namespaces, attrs []Attr
+	scope, copied := rendered, false
+	for _, a := range e.Attrs {
+		if a.Prefix == "xmlns" || (a.Prefix == "" && a.Local == "xmlns") {
+			prefix := ""
+			if a.Prefix == "xmlns" {
+				prefix = a.Local
+			}
+			// A declaration the output ancestors already made is superfluous
+			if value, ok := scope[prefix]; ok && value == a.Value {
+				continue
+			}
+			if !copied {
+				scope, copied = make(map[string]string, len(rendered)+1), true
+				for k, v := range rendered {
+					scope[k] = v
+				}
+			}
+			scope[prefix] = a.Value
+			namespaces = append(namespaces, a)
+			continue
+		}
+		attrs = append(attrs, a)
+	}
+
+	sort.SliceStable(namespaces, func(i, j int) bool {
+		return namespaceDeclPrefix(namespaces[i]) < namespaceDeclPrefix(namespaces[j])
+	})
+	// Attributes sort by namespace URI then local name, unqualified ones first
+	sort.SliceStable(attrs, func(i, j int) bool {
+		ui, uj := attrNamespace(attrs[i], scope), attrNamespace(attrs[j], scope)
+		if ui != uj {
+			return ui < uj
+		}
+		return attrs[i].Local < attrs[j].Local
+	})
+
+	b.WriteByte('<')
+	b.WriteString(e.Name())
+	for _, a := range namespaces {
+		if a.Prefix == "" {
+			b.WriteString(` xmlns="`)
+		} else {
+			b.WriteString(" xmlns:" + a.Local + `="`)
+		}
+		writeEscaped(b, a.Value, true)
+		b.WriteByte('"')
+	}
+	for _, a := range attrs {
+		b.WriteByte(' ')
+		if a.Prefix != "" {
+			b.WriteString(a.Prefix + ":")
+		}
+		b.WriteString(a.Local + `="`)
+		writeEscaped(b, a.Value, true)
+		b.WriteByte('"')
+	}
+	b.WriteByte('>')
+
+	for _, child := range e.Children {
+		switch c := child.(type) {
+		case Text:
+			writeEscaped(b, string(c), false)
+		case *Element:
+			c.canonicalize(b, scope)
+		case ProcInst:
+			b.WriteString("<?" + c.Target)
+			if c.Inst != "" {
+				b.WriteString(" " + c.Inst)
+			}
+			b.WriteString("?>")
+		}
+	}
+
+	b.WriteString("</" + e.Name() + ">")
+}
+
+func namespaceDeclPrefix(a Attr) string {
+	if a.Prefix == "" {
+		return ""
+	}
+	return a.Local
+}
+
+func attrNamespace(a Attr, scope map[string]string) string {
+	if a.Prefix == "" {
+		return ""
+	}
+	if a.Prefix == "xml" {
+		return "http://www.w3.org/XML/1998/namespace"
+	}
+	return scope[a.Prefix]
+}
+
+func writeEscaped(b *bytes.Buffer, s string, attribute bool) {
+	for _, r := range s {
+		switch {
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>' && !attribute:
+			b.WriteString("&gt;")
+		case r == '"' && attribute:
+			b.WriteString("&quot;")
+		case r == '\t' && attribute:
+			b.WriteString("&#x9;")
+		case r == '\n' && attribute:
+			b.WriteString("&#xA;")
+		case r == '\r':
+			b.WriteString("&#xD;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
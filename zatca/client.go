@@ -0,0 +1,232 @@
+package zatca
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Environments, the values of Store.Zatca.Env.
+const (
+	EnvNonProduction = "NonProduction"
+	EnvSimulation    = "Simulation"
+	EnvProduction    = "Production"
+	// EnvSandbox is the in-process Sandbox, for development and the tests
+	EnvSandbox = "Sandbox"
+)
+
+// BaseURL is the Fatoora gateway of the environment.
+func BaseURL(env string) string {
+	path := "developer-portal"
+	switch env {
+	case EnvSimulation:
+		path = "simulation"
+	case EnvProduction:
+		path = "core"
+	case EnvSandbox:
+		return "https://sandbox.fatoora.invalid/e-invoicing/sandbox"
+	}
+	return "https://gw-fatoora.zatca.gov.sa/e-invoicing/" + path
+}
+
+// Credentials are a CSID as the API issues it. The token is the base64 of the
+// base64 certificate, and with the secret it is the basic auth of the API.
+type Credentials struct {
+	RequestID           int64  `json:"requestID"`
+	BinarySecurityToken string `json:"binarySecurityToken"`
+	Secret              string `json:"secret"`
+}
+
+// Certificate is the certificate text of the CSID, the X509Certificate of signed invoices.
+func (c Credentials) Certificate() (string, error) {
+	certificate, err := base64.StdEncoding.DecodeString(c.BinarySecurityToken)
+	if err != nil {
+		return "", errors.New("zatca: binarySecurityToken is not base64: " + err.Error())
+	}
+	return string(certificate), nil
+}
+
+// ValidationMessage is an info, warning or error of the validation results.
+type ValidationMessage struct {
+	Type     string `json:"type"`
+	Code     string `json:"code"`
+	Category string `json:"category"`
+	Message  string `json:"message"`
+	Status   string `json:"status"`
+}
+
+// Result is the answer of the reporting, clearance and compliance APIs.
+type Result struct {
+	ReportingStatus   string `json:"reportingStatus"`
+	ClearanceStatus   string `json:"clearanceStatus"`
+	ClearedInvoice    string `json:"clearedInvoice"`
+	ValidationResults struct {
+		InfoMessages    []ValidationMessage `json:"infoMessages"`
+		WarningMessages []ValidationMessage `json:"warningMessages"`
+		ErrorMessages   []ValidationMessage `json:"errorMessages"`
+		Status          string              `json:"status"`
+	} `json:"validationResults"`
+}
+
+// Accepted tells if ZATCA reported or cleared the invoice.
+func (r *Result) Accepted() bool {
+	return r.ReportingStatus == "REPORTED" || r.ClearanceStatus == "CLEARED"
+}
+
+// Errors is the text of the error messages of the validation.
+func (r *Result) Errors() string {
+	var messages []string
+	for _, m := range r.ValidationResults.ErrorMessages {
+		messages = append(messages, m.Code+": "+m.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Client calls the Fatoora API of one environment.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// Retries is how many times a request that could not reach the API is retried
+	Retries int
+}
+
+// NewClient is the client of the environment. That of the Sandbox
+// environment calls DefaultSandbox, or fails with ErrSandboxDisabled
+// when the server does not enable it.
+func NewClient(env string) *Client {
+	if env == EnvSandbox {
+		if !SandboxEnabled() {
+			return &Client{BaseURL: BaseURL(EnvSandbox), HTTPClient: &http.Client{Transport: sandboxDisabled{}}}
+		}
+		return DefaultSandbox.Client()
+	}
+	return &Client{
+		BaseURL:    BaseURL(env),
+		HTTPClient: &http.Client{Timeout: 60 * time.Second},
+		Retries:    2,
+	}
+}
+
+// APIError is a response other than 200 or 202.
+type APIError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("zatca: HTTP %d from %s: %s", e.StatusCode, e.URL, e.Body)
+}
+
+func (c *Client) post(path string, header http.Header, credentials *Credentials, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	url := c.BaseURL + path
+	var response *http.Response
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Accept", "application/json")
+		request.Header.Set("Accept-Language", "en")
+		request.Header.Set("Accept-Version", "V2")
+		request.Header.Set("Content-Type", "application/json")
+		for key, values := range header {
+			request.Header[key] = values
+		}
+		if credentials != nil {
+			request.SetBasicAuth(credentials.BinarySecurityToken, credentials.Secret)
+		}
+
+		response, err = c.HTTPClient.Do(request)
+		if err == nil {
+			break
+		}
+		// Only the failures to connect are retried, with 1s, 2s, 4s... between tries
+		if attempt >= c.Retries {
+			return err
+		}
+		time.Sleep(time.Duration(1<<attempt) * time.Second)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	// 202 is an invoice accepted with warnings
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusAccepted {
+		return &APIError{URL: url, StatusCode: response.StatusCode, Body: string(data)}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return errors.New("zatca: invalid response from " + url + ": " + err.Error())
+	}
+	return nil
+}
+
+// ComplianceCSID asks for the compliance CSID of a CSR with the OTP of the Fatoora portal.
+func (c *Client) ComplianceCSID(csr, otp string) (*Credentials, error) {
+	credentials := &Credentials{}
+	header := http.Header{"OTP": {otp}}
+	if err := c.post("/compliance", header, nil, map[string]string{"csr": csr}, credentials); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// ComplianceCheck submits a sample invoice with the compliance CSID.
+func (c *Client) ComplianceCheck(credentials *Credentials, invoice *Invoice) (*Result, error) {
+	return c.submit("/compliance/invoices", nil, credentials, invoice)
+}
+
+// ProductionCSID exchanges the compliance CSID, once its checks passed, for the production CSID.
+func (c *Client) ProductionCSID(compliance *Credentials) (*Credentials, error) {
+	credentials := &Credentials{}
+	body := map[string]int64{"compliance_request_id": compliance.RequestID}
+	if err := c.post("/production/csids", nil, compliance, body, credentials); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// Report sends a simplified invoice to the reporting API.
+func (c *Client) Report(credentials *Credentials, invoice *Invoice) (*Result, error) {
+	return c.submit("/invoices/reporting/single", nil, credentials, invoice)
+}
+
+// Clear sends a standard invoice to the clearance API; ZATCA signs it and
+// returns it in ClearedInvoice.
+func (c *Client) Clear(credentials *Credentials, invoice *Invoice) (*Result, error) {
+	return c.submit("/invoices/clearance/single", http.Header{"Clearance-Status": {"1"}}, credentials, invoice)
+}
+
+// Submit reports the invoice when it is simplified and clears it otherwise.
+func (c *Client) Submit(credentials *Credentials, invoice *Invoice) (*Result, error) {
+	if invoice.Simplified {
+		return c.Report(credentials, invoice)
+	}
+	return c.Clear(credentials, invoice)
+}
+
+func (c *Client) submit(path string, header http.Header, credentials *Credentials, invoice *Invoice) (*Result, error) {
+	body := map[string]string{
+		"invoiceHash": invoice.Hash,
+		"uuid":        invoice.UUID,
+		"invoice":     base64.StdEncoding.EncodeToString(invoice.XML),
+	}
+	result := &Result{}
+	if err := c.post(path, header, credentials, body, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,195 @@
+package zatca
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// InitialPIH is the previous invoice hash of the first invoice of a device,
+// the base64 of the hex SHA-256 of "0".
+const InitialPIH = "NWZlY2ViNjZmZmM4NmYzOGQ5NTI3ODZjNmQ2OTZjNzljMmRiYzIzOWRkNGU5MWI0NjcyOWQ3M2EyN2ZiNTdlOQ=="
+
+//go:embed resources/compliance_invoice.xml
+var complianceInvoiceTemplate []byte
+
+// ComplianceDocument is one of the sample documents the compliance CSID has
+// to get through before the production CSID is issued.
+type ComplianceDocument struct {
+	// Key is the field of the store compliance check it passes
+	Key             string
+	Prefix          string
+	TypeCode        string
+	Simplified      bool
+	InstructionNote string
+}
+
+// ComplianceDocuments are the documents of an onboarding for invoice type 1100, in order.
+var ComplianceDocuments = []ComplianceDocument{
+	{"standard_invoice", "STDSI", "388", false, ""},
+	{"standard_credit_note", "STDCN", "383", false, "InstructionNotes for Standard CreditNote"},
+	{"standard_debit_note", "STDDN", "381", false, "InstructionNotes for Standard DebitNote"},
+	{"simplified_invoice", "SIMSI", "388", true, ""},
+	{"simplified_credit_note", "SIMCN", "383", true, "InstructionNotes for Simplified CreditNote"},
+	{"simplified_debit_note", "SIMDN", "381", true, "InstructionNotes for Simplified DebitNote"},
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Invoice fills the sample invoice for the store: a new UUID, the issue time,
+// the counter and previous hash, the seller CR and VAT numbers and the invoice
+// code. Credit and debit notes keep the billing reference and get an
+// instruction note, invoices lose the billing reference.
+func (d ComplianceDocument) Invoice(icv int, pih, vat, crn, invoiceCode string, now time.Time) ([]byte, error) {
+	invoice, err := Parse(complianceInvoiceTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	now = now.In(riyadh())
+	issueDate := now.Format("2006-01-02")
+	set := func(path, value string) error {
+		element := invoice.Find(path)
+		if element == nil {
+			return errors.New("zatca: the compliance template has no " + path)
+		}
+		element.SetText(value)
+		return nil
+	}
+
+	typeName := "0100000"
+	if d.Simplified {
+		typeName = "0200000"
+	}
+
+	for path, value := range map[string]string{
+		"cbc:ID":              invoiceCode,
+		"cbc:UUID":            newUUID(),
+		"cbc:IssueDate":       issueDate,
+		"cbc:IssueTime":       now.Format("15:04:05"),
+		"cbc:InvoiceTypeCode": d.TypeCode,
+		"cac:BillingReference/cac:InvoiceDocumentReference/cbc:ID":               "Invoice Number: 354; Invoice Issue Date: " + issueDate,
+		"cac:AccountingSupplierParty/cac:Party/cac:PartyIdentification/cbc:ID":   crn,
+		"cac:AccountingSupplierParty/cac:Party/cac:PartyTaxScheme/cbc:CompanyID": vat,
+		"cac:Delivery/cbc:ActualDeliveryDate":                                    issueDate,
+	} {
+		if err := set(path, value); err != nil {
+			return nil, err
+		}
+	}
+	invoice.Child("cbc:InvoiceTypeCode").SetAttr("name", typeName)
+
+	for _, child := range invoice.Children {
+		reference, ok := child.(*Element)
+		if !ok || reference.Name() != "cac:AdditionalDocumentReference" {
+			continue
+		}
+		switch reference.Child("cbc:ID").Text() {
+		case "ICV":
+			reference.Child("cbc:UUID").SetText(strconv.Itoa(icv))
+		case "PIH":
+			reference.Find("cac:Attachment/cbc:EmbeddedDocumentBinaryObject").SetText(pih)
+		}
+	}
+
+	if d.Simplified {
+		if customer := invoice.Find("cac:AccountingCustomerParty/cac:Party/cac:PartyIdentification/cbc:ID"); customer != nil {
+			customer.SetText("CASH")
+			customer.SetAttr("schemeID", "OTH")
+		}
+	}
+
+	if d.InstructionNote != "" {
+		if paymentMeans := invoice.Child("cac:PaymentMeans"); paymentMeans != nil {
+			note := &Element{Prefix: "cbc", Local: "InstructionNote"}
+			note.SetText(d.InstructionNote)
+			paymentMeans.Insert(len(paymentMeans.Children), note)
+		}
+	} else {
+		invoice.Remove(func(e *Element) bool { return e.Name() == "cac:BillingReference" })
+	}
+
+	return []byte(xmlDeclaration + "\n" + string(invoice.Canonicalize())), nil
+}
+
+// OnboardingInput is what onboarding a store takes besides the CSR content.
+type OnboardingInput struct {
+	Env         string
+	OTP         string
+	CSR         CSRConfig
+	InvoiceCode string
+}
+
+// Onboarding is the outcome of onboarding a store. It is filled as far as the
+// onboarding got, so a failure still records the checks that passed.
+type Onboarding struct {
+	PrivateKey      string
+	CSR             string
+	Compliance      *Credentials
+	Production      *Credentials
+	ComplianceCheck map[string]bool
+}
+
+// Onboard does what the Fatoora SDK onboarding does: it makes a key and a CSR,
+// gets the compliance CSID with the OTP, passes the compliance checks with the
+// sample documents and gets the production CSID.
+func (c *Client) Onboard(input OnboardingInput) (*Onboarding, error) {
+	onboarding := &Onboarding{ComplianceCheck: map[string]bool{}}
+
+	key, err := GenerateKey(nil)
+	if err != nil {
+		return onboarding, err
+	}
+	onboarding.PrivateKey = MarshalPrivateKey(key)
+
+	if onboarding.CSR, err = CreateCSR(input.CSR, input.Env, key); err != nil {
+		return onboarding, err
+	}
+
+	if onboarding.Compliance, err = c.ComplianceCSID(onboarding.CSR, input.OTP); err != nil {
+		return onboarding, err
+	}
+	certificate, err := onboarding.Compliance.Certificate()
+	if err != nil {
+		return onboarding, err
+	}
+	signer, err := NewSigner(certificate, onboarding.PrivateKey)
+	if err != nil {
+		return onboarding, err
+	}
+
+	pih := InitialPIH
+	for i, document := range ComplianceDocuments {
+		data, err := document.Invoice(i+1, pih, input.CSR.VATNumber, input.CSR.CommonName, input.InvoiceCode, time.Now())
+		if err != nil {
+			return onboarding, err
+		}
+		invoice, err := signer.Prepare(data, time.Now())
+		if err != nil {
+			return onboarding, err
+		}
+		result, err := c.ComplianceCheck(onboarding.Compliance, invoice)
+		if err != nil {
+			return onboarding, err
+		}
+		if !result.Accepted() {
+			return onboarding, errors.New("zatca: compliance check of " + document.Prefix + " failed: " + result.Errors())
+		}
+		onboarding.ComplianceCheck[document.Key] = true
+		pih = invoice.Hash
+	}
+
+	if onboarding.Production, err = c.ProductionCSID(onboarding.Compliance); err != nil {
+		return onboarding, err
+	}
+	return onboarding, nil
+}
@@ -0,0 +1,161 @@
+package zatca
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+)
+
+var (
+	oidCertificateTemplateName = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2}
+	oidSubjectAltName          = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidExtensionRequest        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 14}
+	oidCountry                 = asn1.ObjectIdentifier{2, 5, 4, 6}
+	oidOrganization            = asn1.ObjectIdentifier{2, 5, 4, 10}
+	oidOrganizationalUnit      = asn1.ObjectIdentifier{2, 5, 4, 11}
+	oidSurname                 = asn1.ObjectIdentifier{2, 5, 4, 4}
+	oidUserID                  = asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 1}
+	oidTitle                   = asn1.ObjectIdentifier{2, 5, 4, 12}
+	oidRegisteredAddress       = asn1.ObjectIdentifier{2, 5, 4, 26}
+	oidBusinessCategory        = asn1.ObjectIdentifier{2, 5, 4, 15}
+)
+
+// CSRConfig is the content of the CSR a store is onboarded with, the fields of
+// the csr.properties file of the Fatoora SDK.
+type CSRConfig struct {
+	CommonName       string // the CR number
+	SerialNumber     string // 1-<solution>|2-<model>|3-<device UUID>
+	VATNumber        string
+	BranchName       string
+	OrganizationName string
+	CountryCode      string
+	InvoiceType      string // 1000 standard, 0100 simplified, 1100 both
+	Address          string
+	BusinessCategory string
+}
+
+type tbsCertificateRequest struct {
+	Version    int
+	Subject    asn1.RawValue
+	PublicKey  subjectPublicKeyInfo
+	Attributes []csrAttribute `asn1:"tag:0"`
+}
+
+type csrAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values [][]pkix.Extension `asn1:"set"`
+}
+
+type certificateRequest struct {
+	TBSCSR             tbsCertificateRequest
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+// certificateTemplate is the template the CSID is issued from in each environment.
+func certificateTemplate(env string) (string, error) {
+	switch env {
+	case EnvNonProduction, EnvSandbox:
+		return "TSTZATCA-Code-Signing", nil
+	case EnvSimulation:
+		return "PREZATCA-Code-Signing", nil
+	case EnvProduction:
+		return "ZATCA-Code-Signing", nil
+	}
+	return "", errors.New("zatca: unknown environment " + env)
+}
+
+func utf8Name(attributes ...pkix.AttributeTypeAndValue) ([]byte, error) {
+	var rdns pkix.RDNSequence
+	for _, attribute := range attributes {
+		value, _ := attribute.Value.(string)
+		if value == "" {
+			continue
+		}
+		encoded, err := asn1.MarshalWithParams(value, "utf8")
+		if err != nil {
+			return nil, err
+		}
+		rdns = append(rdns, []pkix.AttributeTypeAndValue{{Type: attribute.Type, Value: asn1.RawValue{FullBytes: encoded}}})
+	}
+	return asn1.Marshal(rdns)
+}
+
+// CreateCSR makes the certificate signing request for the compliance CSID and
+// returns it in the form the API takes: base64 of the PEM text.
+func CreateCSR(config CSRConfig, env string, key *PrivateKey) (string, error) {
+	template, err := certificateTemplate(env)
+	if err != nil {
+		return "", err
+	}
+
+	subject, err := utf8Name(
+		pkix.AttributeTypeAndValue{Type: oidCountry, Value: config.CountryCode},
+		pkix.AttributeTypeAndValue{Type: oidOrganizationalUnit, Value: config.BranchName},
+		pkix.AttributeTypeAndValue{Type: oidOrganization, Value: config.OrganizationName},
+		pkix.AttributeTypeAndValue{Type: oidCommonName, Value: config.CommonName},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	templateName, err := asn1.MarshalWithParams(template, "utf8")
+	if err != nil {
+		return "", err
+	}
+
+	directoryName, err := utf8Name(
+		pkix.AttributeTypeAndValue{Type: oidSurname, Value: config.SerialNumber},
+		pkix.AttributeTypeAndValue{Type: oidUserID, Value: config.VATNumber},
+		pkix.AttributeTypeAndValue{Type: oidTitle, Value: config.InvoiceType},
+		pkix.AttributeTypeAndValue{Type: oidRegisteredAddress, Value: config.Address},
+		pkix.AttributeTypeAndValue{Type: oidBusinessCategory, Value: config.BusinessCategory},
+	)
+	if err != nil {
+		return "", err
+	}
+	// GeneralNames with a single directoryName [4]
+	altName, err := asn1.Marshal([]asn1.RawValue{{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: directoryName}})
+	if err != nil {
+		return "", err
+	}
+
+	curve, _ := asn1.Marshal(oidCurveSecp256k1)
+	point := key.marshalPoint()
+	tbs := tbsCertificateRequest{
+		Subject: asn1.RawValue{FullBytes: subject},
+		PublicKey: subjectPublicKeyInfo{
+			Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: curve}},
+			PublicKey: asn1.BitString{Bytes: point, BitLength: len(point) * 8},
+		},
+		Attributes: []csrAttribute{{
+			Type: oidExtensionRequest,
+			Values: [][]pkix.Extension{{
+				{Id: oidCertificateTemplateName, Value: templateName},
+				{Id: oidSubjectAltName, Value: altName},
+			}},
+		}},
+	}
+	tbsDER, err := asn1.Marshal(tbs)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := key.Sign(tbsDER)
+	if err != nil {
+		return "", err
+	}
+	der, err := asn1.Marshal(certificateRequest{
+		TBSCSR:             tbs,
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSA},
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+	return base64.StdEncoding.EncodeToString(csrPEM), nil
+}
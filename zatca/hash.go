@@ -0,0 +1,57 @@
+package zatca
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// StripSignature removes what the invoice hash does not cover: the UBL
+// extensions holding the signature, the cac:Signature element and the QR code
+// reference, as the transforms of the invoiceSignedData reference say.
+func StripSignature(invoice *Element) {
+	invoice.Remove(func(e *Element) bool {
+		switch e.Local {
+		case "UBLExtensions", "Signature":
+			return true
+		case "AdditionalDocumentReference":
+			id := e.Child("cbc:ID")
+			return id != nil && strings.TrimSpace(id.Text()) == "QR"
+		}
+		return false
+	})
+}
+
+// CanonicalInvoice is the canonical form of the invoice without its signature,
+// the content the invoice hash is taken of.
+func CanonicalInvoice(data []byte) ([]byte, error) {
+	invoice, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	StripSignature(invoice)
+	return invoice.Canonicalize(), nil
+}
+
+// InvoiceHash is the base64 SHA-256 of the canonical invoice. It is the
+// DigestValue of the invoiceSignedData reference and the PIH of the next invoice.
+func InvoiceHash(data []byte) (string, error) {
+	canonical, err := CanonicalInvoice(data)
+	if err != nil {
+		return "", err
+	}
+	return canonicalHash(canonical), nil
+}
+
+func canonicalHash(canonical []byte) string {
+	hash := sha256.Sum256(canonical)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// hexDigest is the digest form ZATCA uses for the signed properties and the
+// certificate: base64 of the lowercase hex of the SHA-256.
+func hexDigest(data []byte) string {
+	hash := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString([]byte(hex.EncodeToString(hash[:])))
+}
@@ -0,0 +1,195 @@
+package zatca
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var (
+	oidPublicKeyECDSA   = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidCurveSecp256k1   = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+	oidSignatureECDSA   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidDomainComponent  = asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 25}
+	oidCommonName       = asn1.ObjectIdentifier{2, 5, 4, 3}
+	errUnsupportedCurve = errors.New("zatca: the key is not a secp256k1 key")
+)
+
+// The x509 package refuses secp256k1 keys, so the certificate and key
+// structures are decoded here with encoding/asn1 directly.
+
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type tbsCertificate struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           asn1.RawValue
+	Subject            asn1.RawValue
+	PublicKey          subjectPublicKeyInfo
+	UniqueID           asn1.BitString   `asn1:"optional,tag:1"`
+	SubjectUniqueID    asn1.BitString   `asn1:"optional,tag:2"`
+	Extensions         []pkix.Extension `asn1:"omitempty,optional,explicit,tag:3"`
+}
+
+type certificate struct {
+	TBSCertificate     tbsCertificate
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+// Certificate is the CSID certificate ZATCA issues for a store.
+type Certificate struct {
+	// Content is the base64 DER exactly as issued, the X509Certificate of the signature
+	Content      string
+	SerialNumber *big.Int
+	IssuerName   string
+	PublicKey    *PublicKey
+	// Signature is the DER ECDSA signature of the issuing CA over the certificate
+	Signature []byte
+}
+
+// ParseCertificate reads the certificate from its base64 DER, with or without
+// PEM armour. The binarySecurityToken of a CSID response decodes to this text.
+func ParseCertificate(content string) (*Certificate, error) {
+	content = strings.TrimSpace(content)
+	if block, _ := pem.Decode([]byte(content)); block != nil {
+		content = base64.StdEncoding.EncodeToString(block.Bytes)
+	}
+	der, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return nil, errors.New("zatca: certificate is not base64: " + err.Error())
+	}
+
+	var cert certificate
+	if rest, err := asn1.Unmarshal(der, &cert); err != nil {
+		return nil, errors.New("zatca: malformed certificate: " + err.Error())
+	} else if len(rest) > 0 {
+		return nil, errors.New("zatca: trailing data after certificate")
+	}
+
+	publicKey, err := parsePublicKeyInfo(cert.TBSCertificate.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var issuer pkix.RDNSequence
+	if _, err := asn1.Unmarshal(cert.TBSCertificate.Issuer.FullBytes, &issuer); err != nil {
+		return nil, errors.New("zatca: malformed certificate issuer: " + err.Error())
+	}
+
+	return &Certificate{
+		Content:      content,
+		SerialNumber: cert.TBSCertificate.SerialNumber,
+		IssuerName:   issuerName(issuer),
+		PublicKey:    publicKey,
+		Signature:    cert.SignatureValue.RightAlign(),
+	}, nil
+}
+
+// issuerName writes the issuer the way the ZATCA SDK does: the common name
+// first, then the domain components from the most specific one.
+func issuerName(rdns pkix.RDNSequence) string {
+	var commonNames, domainComponents []string
+	for _, rdn := range rdns {
+		for _, atv := range rdn {
+			value, _ := atv.Value.(string)
+			switch {
+			case atv.Type.Equal(oidCommonName):
+				commonNames = append(commonNames, "CN="+value)
+			case atv.Type.Equal(oidDomainComponent):
+				domainComponents = append([]string{"DC=" + value}, domainComponents...)
+			}
+		}
+	}
+	return strings.Join(append(commonNames, domainComponents...), ", ")
+}
+
+func parsePublicKeyInfo(info subjectPublicKeyInfo) (*PublicKey, error) {
+	if !info.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		return nil, errUnsupportedCurve
+	}
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &curve); err != nil || !curve.Equal(oidCurveSecp256k1) {
+		return nil, errUnsupportedCurve
+	}
+	return unmarshalPoint(info.PublicKey.RightAlign())
+}
+
+// MarshalPublicKey is the DER SubjectPublicKeyInfo of the key, tag 8 of the QR code.
+func MarshalPublicKey(pub *PublicKey) []byte {
+	curve, _ := asn1.Marshal(oidCurveSecp256k1)
+	point := pub.marshalPoint()
+	der, _ := asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: curve}},
+		PublicKey: asn1.BitString{Bytes: point, BitLength: len(point) * 8},
+	})
+	return der
+}
+
+// ParsePrivateKey reads the key as stored on the store: the base64 DER of a
+// SEC 1 "EC PRIVATE KEY", without PEM armour. PEM and PKCS #8 are accepted too.
+func ParsePrivateKey(content string) (*PrivateKey, error) {
+	content = strings.TrimSpace(content)
+	var der []byte
+	if block, _ := pem.Decode([]byte(content)); block != nil {
+		der = block.Bytes
+	} else {
+		var err error
+		if der, err = base64.StdEncoding.DecodeString(content); err != nil {
+			return nil, errors.New("zatca: private key is not base64: " + err.Error())
+		}
+	}
+
+	var wrapped pkcs8
+	if _, err := asn1.Unmarshal(der, &wrapped); err == nil && wrapped.Algo.Algorithm.Equal(oidPublicKeyECDSA) {
+		der = wrapped.PrivateKey
+	}
+
+	var key ecPrivateKey
+	if _, err := asn1.Unmarshal(der, &key); err != nil {
+		return nil, errors.New("zatca: malformed private key: " + err.Error())
+	}
+	if key.Version != 1 {
+		return nil, errors.New("zatca: unknown EC private key version")
+	}
+	if len(key.NamedCurveOID) > 0 && !key.NamedCurveOID.Equal(oidCurveSecp256k1) {
+		return nil, errUnsupportedCurve
+	}
+
+	return parseScalar(key.PrivateKey)
+}
+
+// MarshalPrivateKey is the base64 SEC 1 DER of the key, the form ParsePrivateKey reads.
+func MarshalPrivateKey(priv *PrivateKey) string {
+	point := priv.marshalPoint()
+	der, _ := asn1.Marshal(ecPrivateKey{
+		Version:       1,
+		PrivateKey:    priv.marshalScalar(),
+		NamedCurveOID: oidCurveSecp256k1,
+		PublicKey:     asn1.BitString{Bytes: point, BitLength: len(point) * 8},
+	})
+	return base64.StdEncoding.EncodeToString(der)
+}
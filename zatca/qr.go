@@ -0,0 +1,156 @@
+package zatca
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// QR code tags, in the order they are written.
+const (
+	QRSellerName = iota + 1
+	QRVATNumber
+	QRTimestamp
+	QRTotalWithVAT
+	QRVATTotal
+	QRInvoiceHash
+	QRSignature
+	QRPublicKey
+	QRCertificateSignature
+)
+
+var errQRTruncated = errors.New("zatca: truncated QR code")
+
+// QRCode is the content of the TLV QR code of a simplified invoice.
+type QRCode struct {
+	SellerName   string
+	VATNumber    string
+	Timestamp    string
+	TotalWithVAT string
+	VATTotal     string
+	InvoiceHash  string
+	Signature    string
+	PublicKey    []byte
+	// CertificateSignature is the signature of the CA over the CSID certificate
+	CertificateSignature []byte
+}
+
+// Encode writes the QR code as the base64 TLV the EmbeddedDocumentBinaryObject holds.
+func (qr *QRCode) Encode() (string, error) {
+	values := [][]byte{
+		[]byte(qr.SellerName),
+		[]byte(qr.VATNumber),
+		[]byte(qr.Timestamp),
+		[]byte(qr.TotalWithVAT),
+		[]byte(qr.VATTotal),
+		[]byte(qr.InvoiceHash),
+		[]byte(qr.Signature),
+		qr.PublicKey,
+		qr.CertificateSignature,
+	}
+
+	var data []byte
+	for i, value := range values {
+		if len(value) > 0xffff {
+			return "", fmt.Errorf("zatca: QR code tag %d is %d bytes long", i+1, len(value))
+		}
+		data = append(data, byte(i+1))
+		data = appendLength(data, len(value))
+		data = append(data, value...)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// appendLength writes a BER length, as the Fatoora SDK does: one byte up to
+// 127, else 0x81 or 0x82 and the length in one or two bytes.
+func appendLength(data []byte, n int) []byte {
+	switch {
+	case n < 0x80:
+		return append(data, byte(n))
+	case n <= 0xff:
+		return append(data, 0x81, byte(n))
+	default:
+		return append(data, 0x82, byte(n>>8), byte(n))
+	}
+}
+
+// DecodeQRCode reads a base64 TLV QR code.
+func DecodeQRCode(content string) (*QRCode, error) {
+	data, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return nil, errors.New("zatca: QR code is not base64: " + err.Error())
+	}
+
+	qr := &QRCode{}
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errQRTruncated
+		}
+		tag, length, header := data[0], int(data[1]), 2
+		if length > 0x80 {
+			// 0x81 or 0x82: the length follows in one or two bytes
+			header += length - 0x80
+			if length > 0x82 || len(data) < header {
+				return nil, errQRTruncated
+			}
+			length = 0
+			for _, b := range data[2:header] {
+				length = length<<8 | int(b)
+			}
+		}
+		if len(data) < header+length {
+			return nil, errQRTruncated
+		}
+		value := data[header : header+length]
+		data = data[header+length:]
+
+		switch tag {
+		case QRSellerName:
+			qr.SellerName = string(value)
+		case QRVATNumber:
+			qr.VATNumber = string(value)
+		case QRTimestamp:
+			qr.Timestamp = string(value)
+		case QRTotalWithVAT:
+			qr.TotalWithVAT = string(value)
+		case QRVATTotal:
+			qr.VATTotal = string(value)
+		case QRInvoiceHash:
+			qr.InvoiceHash = string(value)
+		case QRSignature:
+			qr.Signature = string(value)
+		case QRPublicKey:
+			qr.PublicKey = value
+		case QRCertificateSignature:
+			qr.CertificateSignature = value
+		}
+	}
+	return qr, nil
+}
+
+// InvoiceQRCode takes tags 1 to 5 from the invoice.
+func InvoiceQRCode(invoice *Element) (*QRCode, error) {
+	values := []string{
+		"cac:AccountingSupplierParty/cac:Party/cac:PartyLegalEntity/cbc:RegistrationName",
+		"cac:AccountingSupplierParty/cac:Party/cac:PartyTaxScheme/cbc:CompanyID",
+		"cbc:IssueDate",
+		"cbc:IssueTime",
+		"cac:LegalMonetaryTotal/cbc:PayableAmount",
+		"cac:TaxTotal/cbc:TaxAmount",
+	}
+	for i, path := range values {
+		element := invoice.Find(path)
+		if element == nil {
+			return nil, errors.New("zatca: the invoice has no " + path)
+		}
+		values[i] = element.Text()
+	}
+
+	return &QRCode{
+		SellerName:   values[0],
+		VATNumber:    values[1],
+		Timestamp:    values[2] + "T" + values[3],
+		TotalWithVAT: values[4],
+		VATTotal:     values[5],
+	}, nil
+}
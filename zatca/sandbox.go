@@ -0,0 +1,749 @@
+package zatca
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sandboxTemplate is the certificate template CSRs for the sandbox carry, that
+// of the developer portal.
+const sandboxTemplate = "TSTZATCA-Code-Signing"
+
+// DefaultSandbox is the sandbox the clients of the Sandbox environment call.
+var DefaultSandbox = NewSandbox()
+
+// ErrSandboxDisabled is the error of the Sandbox environment on a server that does not enable it.
+var ErrSandboxDisabled = errors.New("zatca: the Sandbox environment is not enabled on this server")
+
+// SandboxEnabled tells if the server lets stores use the Sandbox environment.
+// It is off unless ZATCA_SANDBOX=true, so a live store can't report to it by mistake.
+func SandboxEnabled() bool {
+	return os.Getenv("ZATCA_SANDBOX") == "true"
+}
+
+// sandboxDisabled is the transport of the Sandbox client when the Sandbox is not enabled.
+type sandboxDisabled struct{}
+
+func (sandboxDisabled) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, ErrSandboxDisabled
+}
+
+var (
+	sellerVATPattern = regexp.MustCompile(`^3[0-9]{13}3$`)
+	otpPattern       = regexp.MustCompile(`^[0-9]{6}$`)
+)
+
+// Sandbox is an in-process stand-in of the Fatoora API, the Sandbox
+// environment, so onboarding and reporting run without the ZATCA gateway in
+// development and in the integration tests.
+//
+// It issues CSIDs from its own CA, runs the compliance checks and validates the
+// invoices the way the gateway does: an invoice hash, UUID, signature, QR code,
+// seller VAT number or totals that do not hold, or the wrong API for the
+// invoice type, is an error (HTTP 400); a previous invoice hash it has not seen
+// and a simplified invoice reported after 24 hours are warnings (HTTP 202).
+// Cleared invoices come back stamped with its own certificate. Everything is
+// kept in memory.
+type Sandbox struct {
+	// OTP is the only OTP accepted when set, otherwise any 6 digits are
+	OTP string
+	// Now is the clock of the 24 hours reporting window, time.Now when nil
+	Now func() time.Time
+
+	mu        sync.Mutex
+	ca        *PrivateKey
+	issuer    []byte
+	stamp     *Signer
+	requestID int64
+	csids     map[string]*sandboxCSID
+	hashes    map[string]bool
+}
+
+// sandboxCSID is a CSID the sandbox issued, by its binarySecurityToken.
+type sandboxCSID struct {
+	Credentials
+	production bool
+	// invoiceType is the title of the CSR, e.g. 1100
+	invoiceType string
+	subject     []byte
+	publicKey   subjectPublicKeyInfo
+	extensions  []pkix.Extension
+	// passed are the keys of the compliance documents that passed
+	passed map[string]bool
+}
+
+// sandboxError is the body of a CSID request the sandbox refuses.
+type sandboxError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewSandbox is an empty sandbox. Its CA is made on the first request.
+func NewSandbox() *Sandbox {
+	return &Sandbox{
+		csids:  map[string]*sandboxCSID{},
+		hashes: map[string]bool{},
+	}
+}
+
+// Client is a client of the sandbox, without retries.
+func (sb *Sandbox) Client() *Client {
+	return &Client{
+		BaseURL:    BaseURL(EnvSandbox),
+		HTTPClient: &http.Client{Transport: sb, Timeout: 60 * time.Second},
+	}
+}
+
+func (sb *Sandbox) now() time.Time {
+	if sb.Now != nil {
+		return sb.Now()
+	}
+	return time.Now()
+}
+
+// setup makes the CA and the certificate cleared invoices are stamped with.
+// It is called with the lock held.
+func (sb *Sandbox) setup() error {
+	if sb.ca != nil {
+		return nil
+	}
+
+	ca, err := GenerateKey(nil)
+	if err != nil {
+		return err
+	}
+	issuer, err := utf8Name(
+		pkix.AttributeTypeAndValue{Type: oidDomainComponent, Value: "local"},
+		pkix.AttributeTypeAndValue{Type: oidDomainComponent, Value: "gov"},
+		pkix.AttributeTypeAndValue{Type: oidDomainComponent, Value: "sandbox"},
+		pkix.AttributeTypeAndValue{Type: oidCommonName, Value: "SandboxZATCA-SubCA-1"},
+	)
+	if err != nil {
+		return err
+	}
+	sb.ca, sb.issuer = ca, issuer
+
+	key, err := GenerateKey(nil)
+	if err != nil {
+		return err
+	}
+	subject, err := utf8Name(pkix.AttributeTypeAndValue{Type: oidCommonName, Value: "SandboxZATCA-Clearance"})
+	if err != nil {
+		return err
+	}
+	content, err := sb.issue(subject, publicKeyInfo(key), nil)
+	if err != nil {
+		return err
+	}
+	cert, err := ParseCertificate(content)
+	if err != nil {
+		return err
+	}
+	sb.stamp = &Signer{Certificate: cert, PrivateKey: key}
+	return nil
+}
+
+func publicKeyInfo(key *PrivateKey) subjectPublicKeyInfo {
+	curve, _ := asn1.Marshal(oidCurveSecp256k1)
+	point := key.marshalPoint()
+	return subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: curve}},
+		PublicKey: asn1.BitString{Bytes: point, BitLength: len(point) * 8},
+	}
+}
+
+// issue makes a certificate signed by the CA and returns its base64 DER.
+func (sb *Sandbox) issue(subject []byte, publicKey subjectPublicKeyInfo, extensions []pkix.Extension) (string, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return "", err
+	}
+	notBefore := sb.now().UTC().Truncate(time.Second)
+	validity, err := asn1.Marshal(struct{ NotBefore, NotAfter time.Time }{notBefore, notBefore.AddDate(5, 0, 0)})
+	if err != nil {
+		return "", err
+	}
+
+	tbs := tbsCertificate{
+		Version:            2,
+		SerialNumber:       serialNumber,
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSA},
+		Issuer:             asn1.RawValue{FullBytes: sb.issuer},
+		Validity:           asn1.RawValue{FullBytes: validity},
+		Subject:            asn1.RawValue{FullBytes: subject},
+		PublicKey:          publicKey,
+		Extensions:         extensions,
+	}
+	if tbs.Raw, err = asn1.Marshal(tbs); err != nil {
+		return "", err
+	}
+	signature, err := sb.ca.Sign(tbs.Raw)
+	if err != nil {
+		return "", err
+	}
+	der, err := asn1.Marshal(certificate{
+		TBSCertificate:     tbs,
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSA},
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(der), nil
+}
+
+// newCSID issues the credentials of a CSID for the certificate content.
+func (sb *Sandbox) newCSID(csid *sandboxCSID) (*Credentials, error) {
+	content, err := sb.issue(csid.subject, csid.publicKey, csid.extensions)
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	sb.requestID++
+	csid.Credentials = Credentials{
+		RequestID:           sb.requestID,
+		BinarySecurityToken: base64.StdEncoding.EncodeToString([]byte(content)),
+		Secret:              base64.StdEncoding.EncodeToString(secret),
+	}
+	sb.csids[csid.BinarySecurityToken] = csid
+	return &csid.Credentials, nil
+}
+
+// parseCSR reads the CSR as the compliance API takes it, base64 of the PEM
+// text, and checks its signature and certificate template.
+func parseCSR(content string) (*sandboxCSID, error) {
+	csrPEM, err := base64.StdEncoding.DecodeString(strings.TrimSpace(content))
+	if err != nil {
+		return nil, errors.New("the CSR is not base64 encoded")
+	}
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("the CSR is not a PEM certificate request")
+	}
+
+	var csr struct {
+		TBSCSR             asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		SignatureValue     asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(block.Bytes, &csr); err != nil {
+		return nil, errors.New("malformed CSR: " + err.Error())
+	}
+	var tbs tbsCertificateRequest
+	if _, err := asn1.Unmarshal(csr.TBSCSR.FullBytes, &tbs); err != nil {
+		return nil, errors.New("malformed CSR: " + err.Error())
+	}
+	publicKey, err := parsePublicKeyInfo(tbs.PublicKey)
+	if err != nil {
+		return nil, errors.New("the CSR key is not a secp256k1 key")
+	}
+	if !publicKey.Verify(csr.TBSCSR.FullBytes, csr.SignatureValue.RightAlign()) {
+		return nil, errors.New("the CSR signature is invalid")
+	}
+
+	csid := &sandboxCSID{subject: tbs.Subject.FullBytes, publicKey: tbs.PublicKey, passed: map[string]bool{}}
+	for _, attribute := range tbs.Attributes {
+		if !attribute.Type.Equal(oidExtensionRequest) || len(attribute.Values) == 0 {
+			continue
+		}
+		csid.extensions = attribute.Values[0]
+	}
+
+	var template string
+	for _, extension := range csid.extensions {
+		switch {
+		case extension.Id.Equal(oidCertificateTemplateName):
+			_, _ = asn1.Unmarshal(extension.Value, &template)
+		case extension.Id.Equal(oidSubjectAltName):
+			csid.invoiceType = csrInvoiceType(extension.Value)
+		}
+	}
+	if template != sandboxTemplate {
+		return nil, errors.New("the certificate template of the CSR is not " + sandboxTemplate)
+	}
+	if len(csid.invoiceType) != 4 || strings.Trim(csid.invoiceType, "01") != "" || csid.invoiceType[:2] == "00" {
+		return nil, errors.New("the invoice type (title) of the CSR is not 1000, 0100 or 1100")
+	}
+	return csid, nil
+}
+
+// csrInvoiceType is the title of the directory name of the subject alternative name.
+func csrInvoiceType(altName []byte) string {
+	var names []asn1.RawValue
+	if _, err := asn1.Unmarshal(altName, &names); err != nil {
+		return ""
+	}
+	for _, name := range names {
+		if name.Class != asn1.ClassContextSpecific || name.Tag != 4 {
+			continue
+		}
+		var rdns pkix.RDNSequence
+		if _, err := asn1.Unmarshal(name.Bytes, &rdns); err != nil {
+			return ""
+		}
+		for _, rdn := range rdns {
+			for _, atv := range rdn {
+				if value, ok := atv.Value.(string); ok && atv.Type.Equal(oidTitle) {
+					return value
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// ServeHTTP answers the Fatoora API paths under any base path, so the sandbox
+// can be mounted on a server as well as used as a transport.
+func (sb *Sandbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("Accept-Version") != "V2" {
+		writeJSON(w, http.StatusBadRequest, sandboxError{"Invalid-Version", "The Accept-Version header must be V2"})
+		return
+	}
+
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	if err := sb.setup(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, sandboxError{"Internal-Error", err.Error()})
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case strings.HasSuffix(path, "/compliance/invoices"):
+		sb.submission(w, r, "compliance")
+	case strings.HasSuffix(path, "/compliance"):
+		sb.complianceCSID(w, r)
+	case strings.HasSuffix(path, "/production/csids"):
+		sb.productionCSID(w, r)
+	case strings.HasSuffix(path, "/invoices/reporting/single"):
+		sb.submission(w, r, "reporting")
+	case strings.HasSuffix(path, "/invoices/clearance/single"):
+		sb.submission(w, r, "clearance")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// authenticate finds the CSID of the basic auth of the request.
+func (sb *Sandbox) authenticate(r *http.Request, production bool) *sandboxCSID {
+	token, secret, ok := r.BasicAuth()
+	if !ok {
+		return nil
+	}
+	csid := sb.csids[token]
+	if csid == nil || csid.Secret != secret || csid.production != production {
+		return nil
+	}
+	return csid
+}
+
+func (sb *Sandbox) complianceCSID(w http.ResponseWriter, r *http.Request) {
+	otp := r.Header.Get("OTP")
+	if values := r.Header["OTP"]; otp == "" && len(values) > 0 {
+		// as the client sets it, not canonicalised when the sandbox is the transport
+		otp = values[0]
+	}
+	if !otpPattern.MatchString(otp) || (sb.OTP != "" && otp != sb.OTP) {
+		writeJSON(w, http.StatusBadRequest, sandboxError{"Invalid-OTP", "The provided OTP is invalid or expired"})
+		return
+	}
+
+	var body struct {
+		CSR string `json:"csr"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.CSR == "" {
+		writeJSON(w, http.StatusBadRequest, sandboxError{"Missing-CSR", "The request has no CSR"})
+		return
+	}
+	csid, err := parseCSR(body.CSR)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, sandboxError{"Invalid-CSR", err.Error()})
+		return
+	}
+
+	credentials, err := sb.newCSID(csid)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, sandboxError{"Internal-Error", err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, credentials)
+}
+
+func (sb *Sandbox) productionCSID(w http.ResponseWriter, r *http.Request) {
+	compliance := sb.authenticate(r, false)
+	if compliance == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		ComplianceRequestID int64 `json:"compliance_request_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ComplianceRequestID != compliance.RequestID {
+		writeJSON(w, http.StatusBadRequest, sandboxError{"Invalid-Request-ID", "The compliance_request_id is not that of the compliance CSID"})
+		return
+	}
+
+	var missing []string
+	for _, document := range ComplianceDocuments {
+		wanted := compliance.invoiceType[0] == '1'
+		if document.Simplified {
+			wanted = compliance.invoiceType[1] == '1'
+		}
+		if wanted && !compliance.passed[document.Key] {
+			missing = append(missing, document.Key)
+		}
+	}
+	if len(missing) > 0 {
+		writeJSON(w, http.StatusBadRequest, sandboxError{"Missing-ComplianceSteps", "The compliance checks of " + strings.Join(missing, ", ") + " have not passed"})
+		return
+	}
+
+	credentials, err := sb.newCSID(&sandboxCSID{
+		production:  true,
+		invoiceType: compliance.invoiceType,
+		subject:     compliance.subject,
+		publicKey:   compliance.publicKey,
+		extensions:  compliance.extensions,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, sandboxError{"Internal-Error", err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, credentials)
+}
+
+// sandboxValidation collects the validation results of an invoice.
+type sandboxValidation struct {
+	Result
+}
+
+func (v *sandboxValidation) add(messageType, code, category, message string) {
+	m := ValidationMessage{Type: messageType, Code: code, Category: category, Message: message, Status: messageType}
+	switch messageType {
+	case "ERROR":
+		v.ValidationResults.ErrorMessages = append(v.ValidationResults.ErrorMessages, m)
+	case "WARNING":
+		v.ValidationResults.WarningMessages = append(v.ValidationResults.WarningMessages, m)
+	default:
+		m.Type, m.Status = "INFO", "PASS"
+		v.ValidationResults.InfoMessages = append(v.ValidationResults.InfoMessages, m)
+	}
+}
+
+func (v *sandboxValidation) fail(code, category, message string) {
+	v.add("ERROR", code, category, message)
+}
+
+func (v *sandboxValidation) warn(code, category, message string) {
+	v.add("WARNING", code, category, message)
+}
+
+// submission answers the compliance, reporting and clearance APIs.
+func (sb *Sandbox) submission(w http.ResponseWriter, r *http.Request, api string) {
+	csid := sb.authenticate(r, api != "compliance")
+	if csid == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		InvoiceHash string `json:"invoiceHash"`
+		UUID        string `json:"uuid"`
+		Invoice     string `json:"invoice"`
+	}
+	v := &sandboxValidation{}
+	var document *Element
+	var simplified bool
+	var hash string
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	data, decodeErr := base64.StdEncoding.DecodeString(body.Invoice)
+	if err == nil {
+		err = decodeErr
+	}
+	if err == nil {
+		document, err = Parse(data)
+	}
+	if err != nil {
+		v.fail("XSD_ZATCA_INVALID", "XSD validation", "The invoice is not base64 encoded UBL XML: "+err.Error())
+	} else {
+		v.add("INFO", "XSD_ZATCA_VALID", "XSD validation", "Complied with UBL 2.1 standards in line with ZATCA specifications")
+		simplified, hash = sb.validate(v, api, csid, document, data, body.InvoiceHash, body.UUID)
+	}
+
+	accepted := len(v.ValidationResults.ErrorMessages) == 0
+	status := http.StatusOK
+	v.ValidationResults.Status = "PASS"
+	if !accepted {
+		status = http.StatusBadRequest
+		v.ValidationResults.Status = "ERROR"
+	} else if len(v.ValidationResults.WarningMessages) > 0 {
+		status = http.StatusAccepted
+		v.ValidationResults.Status = "WARNING"
+	}
+
+	reporting := simplified
+	if api != "compliance" {
+		reporting = api == "reporting"
+	}
+	switch {
+	case reporting && accepted:
+		v.ReportingStatus = "REPORTED"
+	case reporting:
+		v.ReportingStatus = "NOT_REPORTED"
+	case accepted:
+		v.ClearanceStatus = "CLEARED"
+	default:
+		v.ClearanceStatus = "NOT_CLEARED"
+	}
+
+	if accepted {
+		sb.hashes[hash] = true
+		if api == "compliance" {
+			typeCode := document.Child("cbc:InvoiceTypeCode").Text()
+			for _, d := range ComplianceDocuments {
+				if d.TypeCode == typeCode && d.Simplified == simplified {
+					csid.passed[d.Key] = true
+				}
+			}
+		}
+		if api == "clearance" {
+			if v.ClearedInvoice, err = sb.clear(document, hash); err != nil {
+				writeJSON(w, http.StatusInternalServerError, sandboxError{"Internal-Error", err.Error()})
+				return
+			}
+		}
+	}
+	writeJSON(w, status, v.Result)
+}
+
+// validate checks the invoice and returns whether it is simplified and its hash.
+func (sb *Sandbox) validate(v *sandboxValidation, api string, csid *sandboxCSID, document *Element, data []byte, invoiceHash, uuid string) (bool, string) {
+	typeCode := document.Child("cbc:InvoiceTypeCode")
+	if typeCode == nil {
+		v.fail("BR-KSA-05", "KSA", "The invoice has no invoice type code (cbc:InvoiceTypeCode)")
+		return false, ""
+	}
+	simplified := strings.HasPrefix(typeCode.Attr("name"), "02")
+	allowed := csid.invoiceType[0] == '1'
+	if simplified {
+		allowed = csid.invoiceType[1] == '1'
+	}
+	switch {
+	case !allowed:
+		v.fail("Invalid-Invoice-Type", "INVOICE_TYPE", "The CSID is not issued for invoice type "+typeCode.Attr("name"))
+	case api == "reporting" && !simplified:
+		v.fail("Invalid-Invoice-Type", "INVOICE_TYPE", "A standard invoice must be sent to the clearance API, not reported")
+	case api == "clearance" && simplified:
+		v.fail("Invalid-Invoice-Type", "INVOICE_TYPE", "A simplified invoice must be sent to the reporting API, not cleared")
+	}
+
+	if element := document.Child("cbc:UUID"); element == nil || strings.TrimSpace(element.Text()) != uuid {
+		v.fail("invalid-uuid", "UUID", "The UUID of the request body does not match the cbc:UUID of the invoice")
+	}
+
+	hash, err := InvoiceHash(data)
+	if err != nil || hash != invoiceHash {
+		v.fail("invalid-invoice-hash", "INVOICE_HASHING_ERRORS", "The invoice hash API body does not match the (calculated) Hash of the XML")
+	}
+
+	if simplified {
+		sb.validateSignature(v, csid, document, hash)
+	}
+
+	vat := document.Find("cac:AccountingSupplierParty/cac:Party/cac:PartyTaxScheme/cbc:CompanyID")
+	if vat == nil || !sellerVATPattern.MatchString(strings.TrimSpace(vat.Text())) {
+		v.fail("BR-KSA-39", "KSA", `The seller VAT registration number (BT-31) must contain 15 digits, the first and the last digit being "3"`)
+	}
+
+	validateTotals(v, document)
+
+	if code := strings.TrimSpace(typeCode.Text()); code == "381" || code == "383" {
+		if reference := document.Find("cac:BillingReference/cac:InvoiceDocumentReference/cbc:ID"); reference == nil || strings.TrimSpace(reference.Text()) == "" {
+			v.fail("BR-KSA-56", "KSA", "A credit or debit note must reference the invoice it corrects (cac:BillingReference)")
+		}
+	}
+
+	pih := documentReference(document, "PIH")
+	if pih == nil || pih.Find("cac:Attachment/cbc:EmbeddedDocumentBinaryObject") == nil {
+		v.fail("BR-KSA-61", "KSA", "The invoice has no previous invoice hash (PIH) document reference")
+	} else if value := strings.TrimSpace(pih.Find("cac:Attachment/cbc:EmbeddedDocumentBinaryObject").Text()); value != InitialPIH && !sb.hashes[value] {
+		v.warn("BR-KSA-26", "KSA", "The previous invoice hash (PIH) is not the hash of an invoice submitted before")
+	}
+
+	if api == "reporting" && simplified {
+		issued, err := time.ParseInLocation("2006-01-02T15:04:05", textOf(document, "cbc:IssueDate")+"T"+textOf(document, "cbc:IssueTime"), riyadh())
+		if err != nil {
+			v.fail("BR-KSA-25", "KSA", "The invoice issue date and time are not valid")
+		} else if sb.now().Sub(issued) > 24*time.Hour {
+			v.warn("BR-KSA-98", "KSA", "The simplified invoice should be reported within 24 hours of issuing it")
+		}
+	}
+	return simplified, hash
+}
+
+// validateSignature checks the signature, the signing certificate and the QR
+// code of a simplified invoice against its hash.
+func (sb *Sandbox) validateSignature(v *sandboxValidation, csid *sandboxCSID, document *Element, hash string) {
+	signatureValue := document.Descendant("ds:SignatureValue")
+	certificateContent := document.Descendant("ds:X509Certificate")
+	digest := document.Descendant("ds:DigestValue")
+	if signatureValue == nil || certificateContent == nil || digest == nil {
+		v.fail("invalid-signature", "SIGNATURE_ERRORS", "A simplified invoice must be signed")
+		return
+	}
+
+	certificate, err := ParseCertificate(certificateContent.Text())
+	issued, _ := csid.Certificate()
+	if err != nil || certificate.Content != issued {
+		v.fail("invalid-signing-certificate", "CERTIFICATE_ERRORS", "The invoice is not signed with the certificate of the CSID")
+		return
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signatureValue.Text()))
+	hashBytes, _ := base64.StdEncoding.DecodeString(hash)
+	if err != nil || strings.TrimSpace(digest.Text()) != hash || !certificate.PublicKey.Verify(hashBytes, signature) {
+		v.fail("invalid-signature", "SIGNATURE_ERRORS", "The invoice signature does not verify against the invoice hash")
+	}
+
+	reference := documentReference(document, "QR")
+	if reference == nil || reference.Find("cac:Attachment/cbc:EmbeddedDocumentBinaryObject") == nil {
+		v.fail("QRCODE_INVALID", "QRCODE_VALIDATION", "A simplified invoice must have a QR code")
+		return
+	}
+	qr, err := DecodeQRCode(strings.TrimSpace(reference.Find("cac:Attachment/cbc:EmbeddedDocumentBinaryObject").Text()))
+	if err != nil {
+		v.fail("QRCODE_INVALID", "QRCODE_VALIDATION", "The QR code is not a base64 TLV: "+err.Error())
+		return
+	}
+	expected, err := InvoiceQRCode(document)
+	if err != nil ||
+		qr.SellerName != expected.SellerName || qr.VATNumber != expected.VATNumber ||
+		qr.TotalWithVAT != expected.TotalWithVAT || qr.VATTotal != expected.VATTotal ||
+		qr.InvoiceHash != hash || qr.Signature != strings.TrimSpace(signatureValue.Text()) ||
+		!bytes.Equal(qr.PublicKey, MarshalPublicKey(certificate.PublicKey)) {
+		v.fail("QRCODE_INVALID", "QRCODE_VALIDATION", "The QR code does not match the invoice, its hash or its signature")
+	}
+}
+
+// validateTotals checks BR-CO-15: the total with VAT is the total without VAT
+// plus the VAT.
+func validateTotals(v *sandboxValidation, document *Element) {
+	amount := func(path string) (float64, bool) {
+		value, err := strconv.ParseFloat(strings.TrimSpace(textOf(document, path)), 64)
+		return value, err == nil
+	}
+	withoutVAT, ok1 := amount("cac:LegalMonetaryTotal/cbc:TaxExclusiveAmount")
+	withVAT, ok2 := amount("cac:LegalMonetaryTotal/cbc:TaxInclusiveAmount")
+	vat, ok3 := amount("cac:TaxTotal/cbc:TaxAmount")
+	if !ok1 || !ok2 || !ok3 {
+		v.fail("BR-CO-15", "EN", "The invoice total amount without VAT (BT-109), the VAT total (BT-110) and the total with VAT (BT-112) are required")
+		return
+	}
+	if math.Abs(withoutVAT+vat-withVAT) >= 0.005 {
+		v.fail("BR-CO-15", "EN", fmt.Sprintf("Invoice total amount with VAT (BT-112) %.2f is not the total without VAT (BT-109) %.2f plus the VAT total (BT-110) %.2f", withVAT, withoutVAT, vat))
+	}
+}
+
+// clear stamps a standard invoice with the certificate of the sandbox, as
+// ZATCA does before returning the cleared invoice.
+func (sb *Sandbox) clear(document *Element, hash string) (string, error) {
+	StripSignature(document)
+	invoice := &Invoice{Hash: hash}
+	if err := sb.stamp.sign(document, string(document.Canonicalize()), invoice, sb.now()); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(invoice.XML), nil
+}
+
+// documentReference is the cac:AdditionalDocumentReference with the ID, e.g. PIH or QR.
+func documentReference(document *Element, id string) *Element {
+	for _, child := range document.Children {
+		reference, ok := child.(*Element)
+		if ok && reference.Name() == "cac:AdditionalDocumentReference" && textOf(reference, "cbc:ID") == id {
+			return reference
+		}
+	}
+	return nil
+}
+
+func textOf(element *Element, path string) string {
+	if found := element.Find(path); found != nil {
+		return strings.TrimSpace(found.Text())
+	}
+	return ""
+}
+
+// sandboxResponse records what ServeHTTP writes for RoundTrip.
+type sandboxResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *sandboxResponse) Header() http.Header { return r.header }
+
+func (r *sandboxResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *sandboxResponse) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(data)
+}
+
+// RoundTrip makes the sandbox the transport of an http.Client.
+func (sb *Sandbox) RoundTrip(request *http.Request) (*http.Response, error) {
+	recorder := &sandboxResponse{header: http.Header{}}
+	sb.ServeHTTP(recorder, request)
+	if request.Body != nil {
+		request.Body.Close()
+	}
+	recorder.WriteHeader(http.StatusOK)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorder.status, http.StatusText(recorder.status)),
+		StatusCode:    recorder.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorder.header,
+		Body:          io.NopCloser(&recorder.body),
+		ContentLength: int64(recorder.body.Len()),
+		Request:       request,
+	}, nil
+}
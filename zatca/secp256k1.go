@@ -0,0 +1,89 @@
+package zatca
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// ZATCA keys are on secp256k1, which the standard library does not implement.
+// The curve arithmetic and ECDSA are decred's constant time implementation;
+// this file only fits them to the SEC 1 and DER encodings ZATCA uses.
+
+// PublicKey is a point on secp256k1.
+type PublicKey struct {
+	point *secp256k1.PublicKey
+}
+
+// PrivateKey is a secp256k1 private key.
+type PrivateKey struct {
+	PublicKey
+	scalar *secp256k1.PrivateKey
+}
+
+// GenerateKey makes a new secp256k1 key.
+func GenerateKey(random io.Reader) (*PrivateKey, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+	key, err := secp256k1.GeneratePrivateKeyFromRand(random)
+	if err != nil {
+		return nil, err
+	}
+	return newPrivateKey(key), nil
+}
+
+func newPrivateKey(key *secp256k1.PrivateKey) *PrivateKey {
+	return &PrivateKey{PublicKey: PublicKey{point: key.PubKey()}, scalar: key}
+}
+
+// Sign signs message with ECDSA over SHA-256 and returns the ASN.1 DER signature
+// in the low S form, as libsecp256k1 produces.
+func (priv *PrivateKey) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	return ecdsa.Sign(priv.scalar, digest[:]).Serialize(), nil
+}
+
+// Verify checks an ASN.1 DER ECDSA signature of message over SHA-256.
+func (pub *PublicKey) Verify(message, signature []byte) bool {
+	sig, err := ecdsa.ParseDERSignature(signature)
+	if err != nil || pub.point == nil {
+		return false
+	}
+	digest := sha256.Sum256(message)
+	return sig.Verify(digest[:], pub.point)
+}
+
+// marshalPoint is the uncompressed SEC 1 encoding of the public key.
+func (pub *PublicKey) marshalPoint() []byte {
+	return pub.point.SerializeUncompressed()
+}
+
+func unmarshalPoint(data []byte) (*PublicKey, error) {
+	if len(data) != 65 || data[0] != 4 {
+		return nil, errors.New("zatca: only uncompressed secp256k1 public keys are supported")
+	}
+	point, err := secp256k1.ParsePubKey(data)
+	if err != nil {
+		return nil, errors.New("zatca: public key is not on secp256k1")
+	}
+	return &PublicKey{point: point}, nil
+}
+
+// parseScalar reads the big-endian private scalar of a SEC 1 key, which must be in [1, N-1].
+func parseScalar(data []byte) (*PrivateKey, error) {
+	var scalar secp256k1.ModNScalar
+	if len(data) > 32 || scalar.SetByteSlice(data) || scalar.IsZero() {
+		return nil, errors.New("zatca: invalid private key")
+	}
+	return newPrivateKey(secp256k1.NewPrivateKey(&scalar)), nil
+}
+
+// marshalScalar is the 32 byte big-endian private scalar.
+func (priv *PrivateKey) marshalScalar() []byte {
+	return priv.scalar.Serialize()
+}
@@ -0,0 +1,183 @@
+package zatca
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+const xmlDeclaration = `<?xml version="1.0" encoding="UTF-8"?>`
+
+var (
+	//go:embed resources/ubl_extensions.xml
+	ublExtensionsTemplate string
+
+	//go:embed resources/qr_signature.xml
+	qrSignatureTemplate string
+)
+
+// Invoice is an invoice ready for the reporting or clearance API.
+type Invoice struct {
+	UUID       string
+	Hash       string
+	Simplified bool
+	// XML is the invoice as submitted, signed when it is simplified
+	XML    []byte
+	QRCode string
+}
+
+// Signer signs invoices with the CSID of a store.
+type Signer struct {
+	Certificate *Certificate
+	PrivateKey  *PrivateKey
+}
+
+// NewSigner reads the certificate and the private key as they are stored on the store.
+func NewSigner(certificate, privateKey string) (*Signer, error) {
+	cert, err := ParseCertificate(certificate)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	// The key is not matched against the certificate: the developer portal
+	// issues the same test certificate whatever the CSR
+	return &Signer{Certificate: cert, PrivateKey: key}, nil
+}
+
+// riyadh is the time zone of the signing time; Saudi Arabia keeps no daylight saving.
+func riyadh() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Riyadh"); err == nil {
+		return loc
+	}
+	return time.FixedZone("AST", 3*60*60)
+}
+
+// Prepare canonicalises the invoice and computes its hash. A simplified
+// invoice (InvoiceTypeCode name 02xxxxx) is signed and gets its QR code; a
+// standard one goes to clearance unsigned, ZATCA signs it.
+func (s *Signer) Prepare(data []byte, signingTime time.Time) (*Invoice, error) {
+	document, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := &Invoice{}
+	if uuid := document.Child("cbc:UUID"); uuid != nil {
+		invoice.UUID = strings.TrimSpace(uuid.Text())
+	}
+	if invoice.UUID == "" {
+		return nil, errors.New("zatca: the invoice has no UUID")
+	}
+	if typeCode := document.Child("cbc:InvoiceTypeCode"); typeCode != nil {
+		invoice.Simplified = strings.HasPrefix(typeCode.Attr("name"), "02")
+	}
+
+	StripSignature(document)
+	canonical := document.Canonicalize()
+	hash := canonicalHash(canonical)
+	invoice.Hash = hash
+
+	if !invoice.Simplified {
+		invoice.XML = []byte(xmlDeclaration + "\n" + string(canonical))
+		return invoice, nil
+	}
+
+	if err := s.sign(document, string(canonical), invoice, signingTime); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// sign signs the canonical invoice, adds the signature and the QR code and
+// sets the XML and QR code of the invoice.
+func (s *Signer) sign(document *Element, canonical string, invoice *Invoice, signingTime time.Time) error {
+	if s == nil || s.Certificate == nil || s.PrivateKey == nil {
+		return errors.New("zatca: a simplified invoice needs the CSID certificate and private key")
+	}
+
+	hashBytes, _ := base64.StdEncoding.DecodeString(invoice.Hash)
+	signature, err := s.PrivateKey.Sign(hashBytes)
+	if err != nil {
+		return err
+	}
+	signatureValue := base64.StdEncoding.EncodeToString(signature)
+
+	qr, err := InvoiceQRCode(document)
+	if err != nil {
+		return err
+	}
+	qr.InvoiceHash = invoice.Hash
+	qr.Signature = signatureValue
+	qr.PublicKey = MarshalPublicKey(s.Certificate.PublicKey)
+	qr.CertificateSignature = s.Certificate.Signature
+	if invoice.QRCode, err = qr.Encode(); err != nil {
+		return err
+	}
+
+	signed, err := s.embedSignature(canonical, invoice.Hash, signatureValue, invoice.QRCode, signingTime)
+	if err != nil {
+		return err
+	}
+	invoice.XML = []byte(xmlDeclaration + "\n" + signed)
+	return nil
+}
+
+// embedSignature adds the UBL extensions with the XAdES signature as the first
+// child of the invoice, and the QR code reference and cac:Signature before the
+// supplier, the layout the Fatoora SDK writes.
+func (s *Signer) embedSignature(canonical, hash, signatureValue, qrCode string, signingTime time.Time) (string, error) {
+	timestamp := signingTime.In(riyadh()).Format("2006-01-02T15:04:05")
+	certDigest := hexDigest([]byte(s.Certificate.Content))
+	serialNumber := s.Certificate.SerialNumber.String()
+
+	extensions := strings.NewReplacer(
+		"INVOICE_HASH", hash,
+		"SIGNED_PROPERTIES", SignedPropertiesDigest(timestamp, certDigest, s.Certificate.IssuerName, serialNumber),
+		"SIGNATURE_VALUE", signatureValue,
+		"CERTIFICATE_CONTENT", s.Certificate.Content,
+		"SIGNATURE_TIMESTAMP", timestamp,
+		"PUBLICKEY_HASHING", certDigest,
+		"ISSUER_NAME", s.Certificate.IssuerName,
+		"SERIAL_NUMBER", serialNumber,
+	).Replace(ublExtensionsTemplate)
+
+	i := strings.Index(canonical, ">") + 1
+	canonical = canonical[:i] + extensions + canonical[i:]
+
+	i = strings.Index(canonical, "<cac:AccountingSupplierParty>")
+	if i < 0 {
+		return "", errors.New("zatca: the invoice has no cac:AccountingSupplierParty")
+	}
+	return canonical[:i] + strings.Replace(qrSignatureTemplate, "BASE64_QRCODE", qrCode, 1) + canonical[i:], nil
+}
+
+// SignedPropertiesDigest is the digest of the xades:SignedProperties element.
+// ZATCA validates it against this exact serialisation, the indentation included.
+func SignedPropertiesDigest(signingTime, certDigest, issuerName, serialNumber string) string {
+	const ds = `xmlns:ds="http://www.w3.org/2000/09/xmldsig#"`
+	indent := func(n int) string { return "\n" + strings.Repeat(" ", n) }
+
+	properties := `<xades:SignedProperties xmlns:xades="http://uri.etsi.org/01903/v1.3.2#" Id="xadesSignedProperties">` +
+		indent(36) + `<xades:SignedSignatureProperties>` +
+		indent(40) + `<xades:SigningTime>` + signingTime + `</xades:SigningTime>` +
+		indent(40) + `<xades:SigningCertificate>` +
+		indent(44) + `<xades:Cert>` +
+		indent(48) + `<xades:CertDigest>` +
+		indent(52) + `<ds:DigestMethod ` + ds + ` Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/>` +
+		indent(52) + `<ds:DigestValue ` + ds + `>` + certDigest + `</ds:DigestValue>` +
+		indent(48) + `</xades:CertDigest>` +
+		indent(48) + `<xades:IssuerSerial>` +
+		indent(52) + `<ds:X509IssuerName ` + ds + `>` + issuerName + `</ds:X509IssuerName>` +
+		indent(52) + `<ds:X509SerialNumber ` + ds + `>` + serialNumber + `</ds:X509SerialNumber>` +
+		indent(48) + `</xades:IssuerSerial>` +
+		indent(44) + `</xades:Cert>` +
+		indent(40) + `</xades:SigningCertificate>` +
+		indent(36) + `</xades:SignedSignatureProperties>` +
+		indent(32) + `</xades:SignedProperties>`
+	return hexDigest([]byte(properties))
+}